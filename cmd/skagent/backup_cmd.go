@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/biodoia/skagent/internal/backup"
+)
+
+func runBackup(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: skagent backup <create|verify|restore> [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		return runBackupCreate(args[1:])
+	case "verify":
+		return runBackupVerify(args[1:])
+	case "restore":
+		return runBackupRestore(args[1:])
+	default:
+		return fmt.Errorf("unknown backup command: %s", args[0])
+	}
+}
+
+func runBackupCreate(args []string) error {
+	fs := flag.NewFlagSet("backup create", flag.ContinueOnError)
+	output := fs.String("output", "", "archive path (default skagent-backup-<timestamp>.tar.gz)")
+	redactSecrets := fs.Bool("redact", false, "replace credentials in config files with "+backup.RedactedValue)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		*output = fmt.Sprintf("skagent-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+
+	sources, err := backup.DefaultSources()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	manifest, err := backup.Create(f, backup.CreateOptions{
+		Sources: sources,
+		Redact:  *redactSecrets,
+		Version: version,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*output)
+		return err
+	}
+
+	fmt.Printf("Backup written to %s\n", *output)
+	printManifestSummary(manifest)
+	return nil
+}
+
+func runBackupVerify(args []string) error {
+	fs := flag.NewFlagSet("backup verify", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: skagent backup verify <archive>")
+	}
+
+	manifest, err := backup.Verify(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: OK\n", fs.Arg(0))
+	printManifestSummary(manifest)
+	return nil
+}
+
+func runBackupRestore(args []string) error {
+	fs := flag.NewFlagSet("backup restore", flag.ContinueOnError)
+	force := fs.Bool("force", false, "overwrite existing files")
+	configOnly := fs.Bool("config-only", false, "restore only the configuration directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: skagent backup restore [--force] [--config-only] <archive>")
+	}
+
+	sources, err := backup.DefaultSources()
+	if err != nil {
+		return err
+	}
+	targets := make(map[backup.Component]string)
+	for _, src := range sources {
+		if *configOnly && src.Component != backup.ComponentConfig {
+			continue
+		}
+		targets[src.Component] = src.Root
+	}
+
+	manifest, err := backup.Restore(fs.Arg(0), backup.RestoreOptions{Targets: targets, Force: *force})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s\n", fs.Arg(0))
+	printManifestSummary(manifest)
+	if manifest.Redacted {
+		fmt.Println("Note: this backup was redacted; credentials missing from the existing config must be set again.")
+	}
+	return nil
+}
+
+func printManifestSummary(m *backup.Manifest) {
+	counts := make(map[backup.Component]int)
+	var size int64
+	for _, f := range m.Files {
+		counts[f.Component]++
+		size += f.Size
+	}
+	fmt.Printf("  created:  %s\n", m.CreatedAt.Local().Format(time.RFC3339))
+	if m.SkagentVersion != "" {
+		fmt.Printf("  version:  %s\n", m.SkagentVersion)
+	}
+	fmt.Printf("  files:    %d config, %d state (%d bytes)\n",
+		counts[backup.ComponentConfig], counts[backup.ComponentState], size)
+	fmt.Printf("  redacted: %t\n", m.Redacted)
+}
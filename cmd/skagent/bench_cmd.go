@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/biodoia/skagent/internal/bench"
+)
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	numAgents := fs.Int("agents", 16, "number of synthetic agents")
+	numTasks := fs.Int("tasks", 10000, "number of tasks to queue")
+	readers := fs.Int("readers", 2, "goroutines polling the registry while tasks run")
+	latency := fs.Duration("latency", time.Millisecond, "mock provider latency per task")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	verbose := fs.Bool("verbose", false, "keep registry logging on stderr")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The registry logs every assignment; on the terminal that would
+	// dominate the measurement
+	if !*verbose {
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := bench.Run(ctx, bench.Options{
+		Agents:  *numAgents,
+		Tasks:   *numTasks,
+		Readers: *readers,
+		Latency: *latency,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printBenchReport(report)
+	return nil
+}
+
+func printBenchReport(r *bench.Report) {
+	o := r.Options
+	fmt.Printf("Registry benchmark: %d agents, %d tasks, %d readers, %s provider latency\n\n",
+		o.Agents, o.Tasks, o.Readers, o.Latency)
+	fmt.Printf("  create:     %d tasks in %s (%.0f/s)\n", o.Tasks, r.CreateDuration.Round(time.Microsecond), r.CreatePerSec)
+	fmt.Printf("  run:        %d completed, %d failed in %s (%.0f tasks/s)\n",
+		r.Completed, r.Failed, r.RunDuration.Round(time.Microsecond), r.Throughput)
+	fmt.Println()
+	fmt.Printf("  %-10s %8s %10s %10s %10s %10s %10s\n", "latency", "count", "mean", "p50", "p90", "p99", "max")
+	for _, row := range []struct {
+		name string
+		l    bench.Latency
+	}{
+		{"assign", r.Assign},
+		{"complete", r.Complete},
+		{"read", r.Read},
+	} {
+		us := func(d time.Duration) time.Duration { return d.Round(time.Microsecond) }
+		fmt.Printf("  %-10s %8d %10s %10s %10s %10s %10s\n", row.name, row.l.Count,
+			us(row.l.Mean), us(row.l.P50), us(row.l.P90), us(row.l.P99), us(row.l.Max))
+	}
+	fmt.Println()
+	m := r.Memory
+	fmt.Printf("  heap:       %s -> %s (%d bytes retained per task)\n", formatBytes(m.HeapBefore), formatBytes(m.HeapAfter), m.BytesPerTask)
+	fmt.Printf("  allocated:  %s over %d GC cycles\n", formatBytes(m.TotalAlloc), m.NumGC)
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: skagent config <show|validate> [flags]")
+	}
+
+	switch args[0] {
+	case "show":
+		return runConfigShow(args[1:])
+	case "validate":
+		return runConfigValidate(args[1:])
+	default:
+		return fmt.Errorf("unknown config command: %s", args[0])
+	}
+}
+
+func configFlags(name string) (*flag.FlagSet, *config.LoadOptions) {
+	opts := &config.LoadOptions{}
+	fs := flag.NewFlagSet("config "+name, flag.ContinueOnError)
+	fs.StringVar(&opts.ConfigFile, "file", "", "config file (default ~/.config/skagent/config.json)")
+	fs.StringVar(&opts.Profile, "profile", "", "profile name (default $SKAGENT_PROFILE)")
+	fs.StringVar(&opts.ProjectDir, "project", "", "project directory to search for .skagent/config.json")
+	return fs, opts
+}
+
+func runConfigShow(args []string) error {
+	fs, opts := configFlags("show")
+	effective := fs.Bool("effective", false, "annotate every field with the layer that set it")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	showSecrets := fs.Bool("show-secrets", false, "print credentials unmasked")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	eff, err := config.LoadEffective(*opts)
+	if err != nil {
+		return err
+	}
+
+	display := func(path string) interface{} {
+		v := eff.Values[path]
+		if s, ok := v.(string); ok && !*showSecrets && config.IsSecretPath(path) {
+			return config.MaskSecret(s)
+		}
+		return v
+	}
+
+	if *asJSON {
+		type field struct {
+			Value  interface{}    `json:"value"`
+			Origin *config.Origin `json:"origin,omitempty"`
+		}
+		out := make(map[string]field, len(eff.Values))
+		for _, path := range eff.Paths() {
+			f := field{Value: display(path)}
+			if *effective {
+				origin := eff.OriginOf(path)
+				f.Origin = &origin
+			}
+			out[path] = f
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	if *effective {
+		fmt.Println("Layers (lowest to highest precedence):")
+		fmt.Println("  default")
+		for _, l := range eff.Layers {
+			if l.Detail != "" {
+				fmt.Printf("  %s (%s)\n", l.Source, l.Detail)
+			} else {
+				fmt.Printf("  %s\n", l.Source)
+			}
+		}
+		fmt.Println()
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, path := range eff.Paths() {
+		value, _ := json.Marshal(display(path))
+		if !*effective {
+			fmt.Fprintf(tw, "%s\t%s\n", path, value)
+			continue
+		}
+		origin := eff.OriginOf(path)
+		annotation := string(origin.Source)
+		if origin.Detail != "" {
+			annotation += ": " + origin.Detail
+		}
+		fmt.Fprintf(tw, "%s\t%s\t# %s\n", path, value, annotation)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if err := eff.Config.Validate(); err != nil {
+		fmt.Println()
+		printValidation(err)
+	}
+	return nil
+}
+
+func runConfigValidate(args []string) error {
+	fs, opts := configFlags("validate")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	eff, err := config.LoadEffective(*opts)
+	if err != nil {
+		return err
+	}
+	if err := eff.Config.Validate(); err != nil {
+		printValidation(err)
+		return errors.New("configuration is invalid")
+	}
+	fmt.Println("Configuration is valid")
+	return nil
+}
+
+func printValidation(err error) {
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		fmt.Printf("Validation error: %v\n", err)
+		return
+	}
+	fmt.Println("Validation problems:")
+	for _, p := range verr.Problems {
+		fmt.Printf("  - %s\n", p)
+	}
+}
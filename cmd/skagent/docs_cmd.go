@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/docs"
+)
+
+func runDocs(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: skagent docs <update|list> [flags]")
+	}
+
+	switch args[0] {
+	case "update":
+		return runDocsUpdate(args[1:])
+	case "list":
+		return runDocsList(args[1:])
+	default:
+		return fmt.Errorf("unknown docs command: %s", args[0])
+	}
+}
+
+func runDocsUpdate(args []string) error {
+	fs := flag.NewFlagSet("docs update", flag.ContinueOnError)
+	force := fs.Bool("force", false, "download every source even if unchanged")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	eff, err := config.LoadEffective(config.LoadOptions{})
+	if err != nil {
+		return err
+	}
+	cfg := eff.Config.Docs
+	if len(cfg.URLs) == 0 && cfg.GitRepo == "" {
+		return fmt.Errorf("no remote docs configured: set docs.urls or docs.git_repo")
+	}
+
+	cacheDir, err := docs.CacheDir()
+	if err != nil {
+		return err
+	}
+	syncer := docs.NewSyncer(cacheDir)
+	syncer.Force = *force
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	results, err := syncer.Sync(ctx, cfg)
+	failed := 0
+	for _, res := range results {
+		switch res.Status {
+		case docs.SyncFailed:
+			failed++
+			fmt.Printf("  failed        %s: %s\n", res.Source, res.Error)
+		default:
+			fmt.Printf("  %-13s %s (%s)\n", res.Status, res.Source, strings.Join(res.Files, ", "))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sources failed; cached copies were kept", failed, len(results))
+	}
+	fmt.Printf("Docs cache up to date in %s\n", cacheDir)
+	return nil
+}
+
+func runDocsList(args []string) error {
+	fs := flag.NewFlagSet("docs list", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	eff, err := config.LoadEffective(config.LoadOptions{})
+	if err != nil {
+		return err
+	}
+	loader := docs.ForConfig(eff.Config)
+	names, err := loader.ListDocs()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Source: %s\n", loader.Source())
+	for _, name := range names {
+		fmt.Printf("  %s\n", name)
+	}
+	return nil
+}
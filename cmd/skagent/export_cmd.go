@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/biodoia/skagent/internal/export"
+	"github.com/biodoia/skagent/pkg/client"
+)
+
+const exportUsage = `Usage: skagent export <dataset> [flags]
+
+Datasets:
+  tasks    One row per task: outcome, agent, model, score, queue and run time
+  agents   One row per agent with its lifetime stats
+  usage    One row per day and model: tasks finished, failures, run time
+
+Flags:
+`
+
+// runExport dumps a dataset of a running headless instance, read over its
+// REST API, as CSV or Parquet
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	baseURL := fs.String("url", os.Getenv("SKAGENT_URL"), "server URL, or unix:///path for a socket (default $SKAGENT_URL, then the configured API socket or address)")
+	apiKey := fs.String("api-key", os.Getenv("SKAGENT_API_KEY"), "API key (default $SKAGENT_API_KEY)")
+	since := fs.String("since", "", "only tasks created (usage: finished) since a duration ago such as 30d or 12h, or a date such as 2024-05-01")
+	formatName := fs.String("format", "csv", "csv or parquet")
+	output := fs.String("output", "", "file to write (default stdout for csv, skagent-<dataset>-<date>.parquet for parquet)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), exportUsage)
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		fs.Usage()
+		return fmt.Errorf("missing dataset")
+	}
+	dataset := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	format, err := export.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	from, err := export.ParseSince(*since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if *baseURL == "" {
+		*baseURL = defaultRemoteURL()
+	}
+	var opts []client.Option
+	if *apiKey != "" {
+		opts = append(opts, client.WithAPIKey(*apiKey))
+	}
+	c := client.New(*baseURL, opts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var table *export.Table
+	switch dataset {
+	case "tasks":
+		tasks, err := c.TasksSince(ctx, from)
+		if err != nil {
+			return err
+		}
+		agents, err := c.ListAgents(ctx)
+		if err != nil {
+			return err
+		}
+		table = export.Tasks(tasks, agents, from)
+	case "agents":
+		agents, err := c.ListAgents(ctx)
+		if err != nil {
+			return err
+		}
+		table = export.Agents(agents)
+	case "usage":
+		tasks, err := c.TasksSince(ctx, from)
+		if err != nil {
+			return err
+		}
+		table = export.Usage(tasks, from)
+	default:
+		return fmt.Errorf("unknown dataset %q; use tasks, agents or usage", dataset)
+	}
+
+	// Parquet is binary, so it goes to a file unless stdout is asked for
+	if *output == "" && format == export.FormatParquet {
+		*output = fmt.Sprintf("skagent-%s-%s.parquet", dataset, time.Now().Format("20060102"))
+	}
+	var w io.Writer = os.Stdout
+	if *output != "" && *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := export.Write(w, table, format); err != nil {
+		return err
+	}
+	if f, ok := w.(*os.File); ok && f != os.Stdout {
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d %s rows to %s\n", len(table.Rows), dataset, *output)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/dryrun"
+	"github.com/biodoia/skagent/internal/project"
+	"github.com/biodoia/skagent/internal/workspace"
+)
+
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	name := fs.String("name", "", "project name (default the directory name)")
+	force := fs.Bool("force", false, "overwrite files that already exist")
+	register := fs.Bool("register", false, "register the repository with the configured project manager")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: skagent init [--name NAME] [--force] [--register] [dir]")
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	res, err := workspace.Init(dir, workspace.Options{Name: *name, Force: *force})
+	if err != nil {
+		return err
+	}
+	created := "created"
+	if dryrun.Enabled() {
+		fmt.Printf("Dry run: would initialize skagent project %q in %s\n", res.Name, res.Root)
+		created = "would create"
+	} else {
+		fmt.Printf("Initialized skagent project %q in %s\n", res.Name, res.Root)
+	}
+	for _, path := range res.Created {
+		fmt.Printf("  %-8s %s\n", created, path)
+	}
+	for _, path := range res.Skipped {
+		fmt.Printf("  exists   %s\n", path)
+	}
+	if len(res.Skipped) > 0 && !*force {
+		fmt.Println("Existing files were kept; use --force to overwrite them.")
+	}
+
+	if *register {
+		return registerProject(res.Root)
+	}
+	return nil
+}
+
+// registerProject announces the workspace to the project manager and
+// stores the returned ID in .skagent/project.json
+func registerProject(root string) error {
+	eff, err := config.LoadEffective(config.LoadOptions{ProjectDir: root})
+	if err != nil {
+		return err
+	}
+	pm := eff.Config.Project
+	if pm.BaseURL == "" {
+		return fmt.Errorf("cannot register: project.base_url is not configured")
+	}
+
+	p, err := workspace.Load(root)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client := project.NewClient(pm.BaseURL, pm.APIKey)
+	registered, err := client.RegisterProject(ctx, project.ProjectRegistration{
+		Name:    p.Name,
+		RepoURL: p.RepoURL,
+		Path:    root,
+	})
+	if err != nil {
+		return err
+	}
+
+	if dryrun.Enabled() {
+		fmt.Println("Dry run: the project manager was not told about the project")
+		return nil
+	}
+	p.RemoteID = registered.ID
+	if err := workspace.Save(root, p); err != nil {
+		return err
+	}
+	fmt.Printf("Registered with project manager as %s\n", registered.ID)
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/dryrun"
+	"github.com/biodoia/skagent/internal/headless"
+	"github.com/biodoia/skagent/internal/i18n"
+	"github.com/biodoia/skagent/internal/redact"
+	"github.com/biodoia/skagent/internal/setup"
+	"github.com/biodoia/skagent/internal/tui"
+)
+
+// Set at build time via -ldflags (see Makefile)
+var (
+	version   = "dev"
+	buildTime = "unknown"
+	gitCommit = "unknown"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "skagent: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	// --dry-run before the command applies to all of them
+	if len(args) > 0 && args[0] == "--dry-run" {
+		dryrun.Enable()
+		args = args[1:]
+	}
+	setLocale()
+	if len(args) == 0 {
+		return runInteractive()
+	}
+
+	switch args[0] {
+	case "setup":
+		_, err := setup.Run()
+		return err
+	case "headless":
+		return runHeadless(args[1:])
+	case "config":
+		return runConfig(args[1:])
+	case "backup":
+		return runBackup(args[1:])
+	case "init":
+		return runInit(args[1:])
+	case "docs":
+		return runDocs(args[1:])
+	case "bench":
+		return runBench(args[1:])
+	case "remote":
+		return runRemote(args[1:])
+	case "export":
+		return runExport(args[1:])
+	case "mcp":
+		return runMCP(args[1:])
+	case "service":
+		return runService(args[1:])
+	case "version", "--version", "-v":
+		fmt.Printf("skagent %s (commit %s, built %s)\n", version, gitCommit, buildTime)
+		return nil
+	case "help", "--help", "-h":
+		printUsage()
+		return nil
+	default:
+		printUsage()
+		return errors.New(i18n.T("cli.unknown_command", args[0]))
+	}
+}
+
+func printUsage() {
+	fmt.Print(i18n.T("cli.usage"))
+}
+
+// setLocale selects the language of the TUI, the setup wizard and the
+// help from $SKAGENT_LOCALE, the configuration file and $LANG
+func setLocale() {
+	configured := ""
+	if cfg, err := config.Load(); err == nil && cfg != nil {
+		configured = cfg.Locale
+	}
+	i18n.SetLocale(i18n.Detect(configured))
+}
+
+func runInteractive() error {
+	if setup.NeedsSetup() {
+		if _, err := setup.Run(); err != nil {
+			return err
+		}
+	}
+
+	eff, err := config.LoadEffective(config.LoadOptions{})
+	if err != nil {
+		return err
+	}
+	if err := redact.Install(eff.Config); err != nil {
+		return err
+	}
+	if eff.Config.DryRun {
+		dryrun.Enable()
+	}
+	// The effective configuration may set the locale in a profile or the
+	// project overlay
+	i18n.SetLocale(i18n.Detect(eff.Config.Locale))
+	return tui.RunWithConfig(eff.Config)
+}
+
+func runHeadless(args []string) error {
+	fs := flag.NewFlagSet("headless", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to headless config file")
+	daemon := fs.Bool("daemon", false, "run without the interactive shell")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return headless.RunHeadless(*configPath, *daemon)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/dryrun"
+	"github.com/biodoia/skagent/internal/redact"
+	"github.com/biodoia/skagent/internal/server/mcp"
+)
+
+// runMCP serves MCP over stdin and stdout, for MCP hosts such as Claude
+// Desktop that launch their servers as subprocesses
+func runMCP(args []string) error {
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: skagent mcp")
+	}
+
+	// Standard output carries the protocol; logs go to standard error
+	log.SetOutput(os.Stderr)
+
+	eff, err := config.LoadEffective(config.LoadOptions{})
+	if err != nil {
+		return err
+	}
+	if err := redact.Install(eff.Config); err != nil {
+		return err
+	}
+	if eff.Config.DryRun {
+		dryrun.Enable()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	registry := agents.NewRegistry(ctx)
+	for _, ws := range eff.Config.Workspaces {
+		if _, err := registry.CreateWorkspace(ws.Name, ws.Description); err != nil {
+			return fmt.Errorf("failed to create workspace %s: %w", ws.Name, err)
+		}
+	}
+	server := mcp.NewServer(ctx, registry, eff.Config.MCP)
+	server.SetMaxBodySize(eff.Config.API.MaxBodySize)
+	server.SetSpecsDir(eff.Config.SpecKitPath)
+
+	if err := server.ServeStdio(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
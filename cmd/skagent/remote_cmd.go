@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/pkg/client"
+)
+
+// remote drives a running headless instance through its REST API
+type remote struct {
+	client *client.Client
+	json   bool
+	out    io.Writer
+}
+
+func runRemote(args []string) error {
+	fs := flag.NewFlagSet("remote", flag.ContinueOnError)
+	baseURL := fs.String("url", os.Getenv("SKAGENT_URL"), "server URL, or unix:///path for a socket (default $SKAGENT_URL, then the configured API socket or address)")
+	apiKey := fs.String("api-key", os.Getenv("SKAGENT_API_KEY"), "API key (default $SKAGENT_API_KEY)")
+	asJSON := fs.Bool("json", false, "print JSON instead of tables")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `Usage: skagent remote [flags] <command> [args]
+
+Commands:
+  agents                      List agents
+  tasks                       List tasks
+  task <id>                   Show a task, its history and its execution log
+  submit [flags] <text>       Submit a task
+  cancel <id> [reason]        Cancel a task
+  logs [flags]                Print server logs
+  chat [flags] <message>      Send a message to a session, streaming the reply
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing remote command")
+	}
+
+	if *baseURL == "" {
+		*baseURL = defaultRemoteURL()
+	}
+	var opts []client.Option
+	if *apiKey != "" {
+		opts = append(opts, client.WithAPIKey(*apiKey))
+	}
+	r := &remote{client: client.New(*baseURL, opts...), json: *asJSON, out: os.Stdout}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	var err error
+	switch cmd {
+	case "agents":
+		err = r.agents(ctx, rest)
+	case "tasks":
+		err = r.tasks(ctx, rest)
+	case "task":
+		err = r.task(ctx, rest)
+	case "submit":
+		err = r.submit(ctx, rest)
+	case "cancel":
+		err = r.cancel(ctx, rest)
+	case "logs":
+		err = r.logs(ctx, rest)
+	case "chat":
+		err = r.chat(ctx, rest)
+	default:
+		return fmt.Errorf("unknown remote command: %s", cmd)
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// defaultRemoteURL is the API address from the local configuration, so
+// that the command works unchanged on the machine running the daemon. The
+// API socket, when configured, is preferred to the TCP port.
+func defaultRemoteURL() string {
+	host, port, scheme := "localhost", 8080, "http"
+	if eff, err := config.LoadEffective(config.LoadOptions{}); err == nil {
+		api := eff.Config.API
+		if api.Socket != "" {
+			return "unix://" + api.Socket
+		}
+		if api.Host != "" && api.Host != "0.0.0.0" && api.Host != "::" {
+			host = api.Host
+		}
+		if api.Port > 0 {
+			port = api.Port
+		}
+		if api.TLS.CertFile != "" {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+func (r *remote) agents(ctx context.Context, args []string) error {
+	if err := noArgs("agents", args); err != nil {
+		return err
+	}
+	agents, err := r.client.ListAgents(ctx)
+	if err != nil {
+		return err
+	}
+	if r.json {
+		return r.printJSON(agents)
+	}
+
+	tw := r.table("ID", "NAME", "TYPE", "STATUS", "LOAD", "QUEUED", "COMPLETED", "FAILED", "CURRENT TASK")
+	for _, a := range agents {
+		current := "-"
+		if a.CurrentTask != nil {
+			current = a.CurrentTask.ID
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n", a.ID, a.Name, a.Type, a.Status, a.Load,
+			a.QueueDepth, a.Stats.TasksCompleted, a.Stats.TasksFailed, current)
+	}
+	return tw.Flush()
+}
+
+func (r *remote) tasks(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("remote tasks", flag.ContinueOnError)
+	status := fs.String("status", "", "only tasks in this status")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := noArgs("tasks", fs.Args()); err != nil {
+		return err
+	}
+	tasks, err := r.client.ListTasks(ctx)
+	if err != nil {
+		return err
+	}
+	if *status != "" {
+		kept := tasks[:0]
+		for _, t := range tasks {
+			if string(t.Status) == *status {
+				kept = append(kept, t)
+			}
+		}
+		tasks = kept
+	}
+	if r.json {
+		return r.printJSON(tasks)
+	}
+
+	tw := r.table("ID", "STATUS", "PRIORITY", "AGENT", "CREATED", "TITLE")
+	for _, t := range tasks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Status, priorityName(int(t.Priority)),
+			orDash(t.AssignedTo), t.CreatedAt.Local().Format("2006-01-02 15:04:05"), truncate(t.Title, 60))
+	}
+	return tw.Flush()
+}
+
+func (r *remote) task(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: skagent remote task <id>")
+	}
+	task, err := r.client.GetTask(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	history, err := r.client.TaskHistory(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	// Servers without task logs answer 503; the task is shown without
+	entries, err := r.client.TaskLog(ctx, task.ID)
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusServiceUnavailable {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if r.json {
+		return r.printJSON(map[string]interface{}{"task": task, "history": history, "log": entries})
+	}
+	r.printTask(task)
+	if len(history) > 0 {
+		fmt.Fprintln(r.out, "\nHistory:")
+		tw := r.table("  TIME", "EVENT", "FROM", "TO", "AGENT", "REASON")
+		for _, tr := range history {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", tr.Time.Local().Format("15:04:05"), tr.Event,
+				orDash(string(tr.From)), tr.To, orDash(tr.AgentID), tr.Reason)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		fmt.Fprintln(r.out, "\nLog:")
+		for _, e := range entries {
+			fmt.Fprintf(r.out, "  %s\n", tasklog.Format(e))
+		}
+	}
+	return nil
+}
+
+func (r *remote) submit(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("remote submit", flag.ContinueOnError)
+	priority := fs.String("priority", "medium", "low, medium, high or urgent")
+	agentID := fs.String("agent", "", "assign the task to this agent")
+	wait := fs.Bool("wait", false, "wait for the task to finish")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	text := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if text == "" {
+		return fmt.Errorf("usage: skagent remote submit [flags] <text>")
+	}
+	p, err := parsePriority(*priority)
+	if err != nil {
+		return err
+	}
+
+	task, err := r.client.SubmitTask(ctx, client.SubmitTaskRequest{Task: text, Priority: p, AgentID: *agentID})
+	if err != nil {
+		return err
+	}
+	if *wait {
+		if !r.json {
+			fmt.Fprintf(os.Stderr, "Submitted %s, waiting for it to finish...\n", task.ID)
+		}
+		if task, err = r.client.WaitForTask(ctx, task.ID); err != nil {
+			return err
+		}
+	}
+	if r.json {
+		return r.printJSON(task)
+	}
+	r.printTask(task)
+	if *wait && task.Status != client.TaskCompleted {
+		return fmt.Errorf("task %s %s", task.ID, task.Status)
+	}
+	return nil
+}
+
+func (r *remote) cancel(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: skagent remote cancel <id> [reason]")
+	}
+	if err := r.client.CancelTask(ctx, args[0], strings.Join(args[1:], " ")); err != nil {
+		return err
+	}
+	if r.json {
+		return r.printJSON(map[string]string{"id": args[0], "status": string(client.TaskCancelled)})
+	}
+	fmt.Fprintf(r.out, "Cancelled %s\n", args[0])
+	return nil
+}
+
+func (r *remote) logs(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("remote logs", flag.ContinueOnError)
+	var q client.LogQuery
+	fs.StringVar(&q.Level, "level", "", "lowest level shown: debug, info, warn or error")
+	fs.StringVar(&q.Component, "component", "", "only entries from this component")
+	fs.StringVar(&q.Since, "since", "", "RFC 3339 time or duration, e.g. 15m")
+	fs.IntVar(&q.Limit, "limit", 0, "only the most recent entries")
+	follow := fs.Bool("f", false, "keep printing new entries")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := noArgs("logs", fs.Args()); err != nil {
+		return err
+	}
+
+	// Log lines are printed as they come in; in JSON mode one object per
+	// line, so that the output can be piped to jq while following
+	print := func(e client.LogEntry) error {
+		if r.json {
+			return json.NewEncoder(r.out).Encode(e)
+		}
+		_, err := fmt.Fprintf(r.out, "%s %-5s [%s] %s\n", e.Timestamp.Local().Format("2006-01-02 15:04:05.000"),
+			strings.ToUpper(e.Level.String()), e.Component, e.Message)
+		return err
+	}
+	if *follow {
+		return r.client.FollowLogs(ctx, q, print)
+	}
+	entries, err := r.client.Logs(ctx, q)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := print(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *remote) chat(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("remote chat", flag.ContinueOnError)
+	sessionID := fs.String("session", "", "continue this session instead of starting one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	message := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if message == "" {
+		return fmt.Errorf("usage: skagent remote chat [--session id] <message>")
+	}
+
+	if *sessionID == "" {
+		session, err := r.client.CreateSession(ctx, truncate(message, 60))
+		if err != nil {
+			return err
+		}
+		*sessionID = session.ID
+		if !r.json {
+			fmt.Fprintf(os.Stderr, "Session %s\n", session.ID)
+		}
+	}
+
+	var onDelta func(string) error
+	if !r.json {
+		onDelta = func(delta string) error {
+			_, err := io.WriteString(r.out, delta)
+			return err
+		}
+	}
+	response, err := r.client.Chat(ctx, *sessionID, message, onDelta)
+	if err != nil {
+		if !r.json {
+			fmt.Fprintln(r.out)
+		}
+		return err
+	}
+	if r.json {
+		return r.printJSON(map[string]string{"session_id": *sessionID, "response": response})
+	}
+	if !strings.HasSuffix(response, "\n") {
+		fmt.Fprintln(r.out)
+	}
+	return nil
+}
+
+func (r *remote) printTask(t *client.Task) {
+	tw := tabwriter.NewWriter(r.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", t.ID)
+	fmt.Fprintf(tw, "Title:\t%s\n", t.Title)
+	fmt.Fprintf(tw, "Status:\t%s\n", t.Status)
+	fmt.Fprintf(tw, "Priority:\t%s\n", priorityName(int(t.Priority)))
+	fmt.Fprintf(tw, "Agent:\t%s\n", orDash(t.AssignedTo))
+	fmt.Fprintf(tw, "Created:\t%s\n", t.CreatedAt.Local().Format(time.RFC3339))
+	if t.CompletedAt != nil {
+		fmt.Fprintf(tw, "Finished:\t%s\n", t.CompletedAt.Local().Format(time.RFC3339))
+	}
+	tw.Flush()
+	if t.Result != nil {
+		if t.Result.Error != "" {
+			fmt.Fprintf(r.out, "\nError: %s\n", t.Result.Error)
+		}
+		if t.Result.Output != "" {
+			fmt.Fprintf(r.out, "\n%s\n", strings.TrimRight(t.Result.Output, "\n"))
+		}
+	}
+}
+
+func (r *remote) table(headers ...string) *tabwriter.Writer {
+	tw := tabwriter.NewWriter(r.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	return tw
+}
+
+func (r *remote) printJSON(v interface{}) error {
+	enc := json.NewEncoder(r.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func noArgs(cmd string, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("remote %s takes no arguments", cmd)
+	}
+	return nil
+}
+
+var priorityNames = []string{"low", "medium", "high", "urgent"}
+
+func priorityName(p int) string {
+	if p >= 0 && p < len(priorityNames) {
+		return priorityNames[p]
+	}
+	return strconv.Itoa(p)
+}
+
+func parsePriority(s string) (int, error) {
+	for i, name := range priorityNames {
+		if strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(priorityNames) {
+		return n, nil
+	}
+	return 0, fmt.Errorf("unknown priority %q: use low, medium, high or urgent", s)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
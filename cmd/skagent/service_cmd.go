@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/headless"
+	"github.com/biodoia/skagent/internal/service"
+)
+
+// runService installs, removes or runs the headless daemon as a Windows
+// service
+func runService(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: skagent service <install|uninstall|run> [flags]")
+	}
+
+	switch args[0] {
+	case "install":
+		return runServiceInstall(args[1:])
+	case "uninstall":
+		return runServiceUninstall(args[1:])
+	case "run":
+		return runServiceRun(args[1:])
+	default:
+		return fmt.Errorf("unknown service command: %s", args[0])
+	}
+}
+
+func runServiceInstall(args []string) error {
+	fs := flag.NewFlagSet("service install", flag.ContinueOnError)
+	name := fs.String("name", service.DefaultName, "service name")
+	configPath := fs.String("config", "", "path to headless config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The service manager starts the service in another directory
+	runArgs := []string{"service", "run", "--name", *name}
+	if *configPath != "" {
+		abs, err := filepath.Abs(*configPath)
+		if err != nil {
+			return err
+		}
+		runArgs = append(runArgs, "--config", abs)
+	}
+	if err := service.Install(*name, runArgs...); err != nil {
+		return err
+	}
+	fmt.Printf("Service %s installed; start it with: sc start %s\n", *name, *name)
+	return nil
+}
+
+func runServiceUninstall(args []string) error {
+	fs := flag.NewFlagSet("service uninstall", flag.ContinueOnError)
+	name := fs.String("name", service.DefaultName, "service name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := service.Uninstall(*name); err != nil {
+		return err
+	}
+	fmt.Printf("Service %s removed\n", *name)
+	return nil
+}
+
+// runServiceRun is what the service manager starts. The service has no
+// console, so logs go to service.log in the data directory.
+func runServiceRun(args []string) error {
+	fs := flag.NewFlagSet("service run", flag.ContinueOnError)
+	name := fs.String("name", service.DefaultName, "service name")
+	configPath := fs.String("config", "", "path to headless config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	isService, err := service.IsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		return fmt.Errorf("skagent service run is started by the service manager; use skagent headless --daemon to run in the foreground")
+	}
+
+	dir, err := config.DataDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(filepath.Join(dir, "service.log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	log.SetOutput(logFile)
+
+	mode, err := headless.NewHeadless(*configPath)
+	if err != nil {
+		log.Printf("Failed to start: %v", err)
+		return err
+	}
+	return service.Run(*name, mode.Start, func() { mode.RequestShutdown("service stop") })
+}
@@ -0,0 +1,105 @@
+package agents
+
+import (
+	"time"
+)
+
+// Archive is a store that keeps tasks which finished long ago apart from
+// the live ones, so that the registry can drop them from memory while
+// they stay readable
+type Archive interface {
+	Store
+	// ArchiveTasks moves tasks from the stored ones to the archive
+	// atomically
+	ArchiveTasks(tasks []*Task) error
+	// ArchivedTasks returns a page of the archived tasks of a workspace,
+	// the most recently finished first
+	ArchivedTasks(workspace string, limit, offset int) ([]*Task, error)
+	// ArchivedTask returns an archived task, or nil when there is none
+	ArchivedTask(id string) (*Task, error)
+}
+
+// ErrNoArchive is returned when the registry's store keeps no archive, as
+// with the memory driver
+var ErrNoArchive = &AgentError{message: "the registry storage keeps no archive"}
+
+// FinishedAt returns when a finished task finished
+func (t *Task) FinishedAt() time.Time {
+	if t.CompletedAt != nil {
+		return *t.CompletedAt
+	}
+	return t.UpdatedAt
+}
+
+// Compact moves the completed, failed and cancelled tasks that finished
+// before cutoff to the store's archive and drops them, with their routing
+// decisions, from memory. Their history is kept. It returns how many tasks
+// were archived.
+func (r *Registry) Compact(cutoff time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.unlock()
+	var old []*Task
+	for _, t := range r.tasks {
+		if t.Status.Finished() && t.FinishedAt().Before(cutoff) {
+			old = append(old, t)
+		}
+	}
+	if len(old) == 0 {
+		return 0, nil
+	}
+	archive, ok := r.store.(Archive)
+	if !ok {
+		return 0, ErrNoArchive
+	}
+	// Pending writes go first, so none puts an archived task back
+	if err := r.flush(); err != nil {
+		return 0, err
+	}
+	if err := archive.ArchiveTasks(old); err != nil {
+		return 0, err
+	}
+	for _, t := range old {
+		delete(r.tasks, t.ID)
+		delete(r.routing, t.ID)
+	}
+	r.changed()
+	r.logger.Printf("Archived %d tasks finished before %s", len(old), cutoff.Format(time.RFC3339))
+	return len(old), nil
+}
+
+// ArchivedTasks returns a page of the archived tasks of a workspace, the
+// most recently finished first
+func (r *Registry) ArchivedTasks(workspace string, limit, offset int) ([]*Task, error) {
+	archive, err := r.archive()
+	if err != nil {
+		return nil, err
+	}
+	return archive.ArchivedTasks(workspace, limit, offset)
+}
+
+// ArchivedTask returns an archived task, or ErrTaskNotFound
+func (r *Registry) ArchivedTask(id string) (*Task, error) {
+	archive, err := r.archive()
+	if err != nil {
+		return nil, err
+	}
+	task, err := archive.ArchivedTask(id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+// archive returns the store's archive, if it keeps one
+func (r *Registry) archive() (Archive, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	archive, ok := r.store.(Archive)
+	if !ok {
+		return nil, ErrNoArchive
+	}
+	return archive, nil
+}
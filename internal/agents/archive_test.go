@@ -0,0 +1,106 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// archiveStore is a memStore with an archive
+type archiveStore struct {
+	memStore
+	archived []*Task
+}
+
+func (s *archiveStore) ArchiveTasks(tasks []*Task) error {
+	s.archived = append(s.archived, tasks...)
+	return nil
+}
+
+func (s *archiveStore) ArchivedTasks(workspace string, limit, offset int) ([]*Task, error) {
+	return s.archived, nil
+}
+
+func (s *archiveStore) ArchivedTask(id string) (*Task, error) {
+	for _, t := range s.archived {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestCompactArchivesFinishedTasks(t *testing.T) {
+	r := NewRegistry(context.Background())
+	if _, err := r.Compact(time.Now()); err != nil {
+		t.Fatalf("compacting nothing without an archive: %v", err)
+	}
+	r.CreateTask(&Task{Title: "memory"})
+	r.CancelTask(r.ListTasks()[0].ID, Cause{})
+	if _, err := r.Compact(time.Now().Add(time.Second)); !errors.Is(err, ErrNoArchive) {
+		t.Fatalf("err = %v, want ErrNoArchive", err)
+	}
+
+	r = NewRegistry(context.Background())
+	store := &archiveStore{}
+	if err := r.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+	agent, _ := r.CreateAgent("a", "coder", nil)
+	done := r.CreateTask(&Task{Title: "done"})
+	if err := r.AssignTask(done.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CompleteTask(done.ID, &TaskResult{Success: true}); err != nil {
+		t.Fatal(err)
+	}
+	pending := r.CreateTask(&Task{Title: "pending"})
+	cutoff := time.Now().Add(time.Second)
+	recent := r.CreateTask(&Task{Title: "recent"})
+	if err := r.CancelTask(recent.ID, Cause{}); err != nil {
+		t.Fatal(err)
+	}
+	later := cutoff.Add(time.Hour)
+	r.mu.Lock()
+	r.tasks[recent.ID].CompletedAt = &later
+	r.mu.Unlock()
+
+	n, err := r.Compact(cutoff)
+	if err != nil || n != 1 {
+		t.Fatalf("archived %d, %v", n, err)
+	}
+	if len(store.archived) != 1 || store.archived[0].ID != done.ID {
+		t.Fatalf("archive = %+v", store.archived)
+	}
+	if _, ok := r.GetTask(done.ID); ok {
+		t.Error("the archived task is still in the registry")
+	}
+	if _, ok := r.GetTask(pending.ID); !ok {
+		t.Error("the pending task was archived")
+	}
+	if _, ok := r.GetTask(recent.ID); !ok {
+		t.Error("the task cancelled after the cutoff was archived")
+	}
+	if len(r.ListTasks()) != 2 {
+		t.Errorf("list = %d tasks", len(r.ListTasks()))
+	}
+
+	// Archived tasks stay readable, with their history
+	if task, err := r.ArchivedTask(done.ID); err != nil || task.Status != TaskStatusCompleted {
+		t.Fatalf("archived task = %+v, %v", task, err)
+	}
+	if _, err := r.ArchivedTask(pending.ID); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("err = %v, want ErrTaskNotFound", err)
+	}
+	if history, ok := r.TaskHistory(done.ID); !ok || len(history) == 0 {
+		t.Error("the history of the archived task was dropped")
+	}
+	// Archiving is not a deletion the next write would repeat
+	last := store.batches[len(store.batches)-1]
+	for _, id := range last.DeletedTasks {
+		if id == done.ID {
+			t.Error("the archived task was deleted from the store")
+		}
+	}
+}
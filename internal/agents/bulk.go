@@ -0,0 +1,356 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BulkAction is the kind of change a bulk operation makes
+type BulkAction string
+
+const (
+	BulkCreate BulkAction = "create"
+	BulkUpdate BulkAction = "update"
+	BulkDelete BulkAction = "delete"
+)
+
+// ErrInvalidOperation marks a bulk operation that is malformed
+var ErrInvalidOperation = errors.New("invalid operation")
+
+// OpError reports the operation that stopped a bulk batch
+type OpError struct {
+	Index int
+	Field string // offending field, if any
+	Err   error
+}
+
+func (e *OpError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("operation %d: %s: %v", e.Index, e.Field, e.Err)
+	}
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+func invalidOp(index int, field, message string) *OpError {
+	return &OpError{Index: index, Field: field, Err: fmt.Errorf("%w: %s", ErrInvalidOperation, message)}
+}
+
+// AgentOp is one entry of a bulk agent batch. For updates only the fields
+// that are set are changed.
+type AgentOp struct {
+	Op           BulkAction             `json:"op"`
+	ID           string                 `json:"id,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	Type         AgentType              `json:"type,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	Labels       []string               `json:"labels,omitempty"`
+	Capabilities []string               `json:"capabilities,omitempty"`
+	Config       map[string]interface{} `json:"config,omitempty"`
+	// Version, when set on an update or delete, is the version the client
+	// read; the batch is rejected if the agent has changed since
+	Version int64 `json:"version,omitempty"`
+}
+
+// TaskOp is one entry of a bulk task batch. For updates only the fields
+// that are set are changed.
+type TaskOp struct {
+	Op          BulkAction    `json:"op"`
+	ID          string        `json:"id,omitempty"`
+	Title       string        `json:"title,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Priority    *TaskPriority `json:"priority,omitempty"`
+	Labels      []string      `json:"labels,omitempty"`
+	ProjectID   string        `json:"project_id,omitempty"`
+	// AcceptanceCriteria replace the task's on update
+	AcceptanceCriteria []string `json:"acceptance_criteria,omitempty"`
+	// Source and Meta are set on the tasks a batch creates by callers
+	// inside the daemon; Source defaults to "api"
+	Source string            `json:"-"`
+	Meta   map[string]string `json:"-"`
+}
+
+// BulkResult is the outcome of one operation of an applied batch
+type BulkResult struct {
+	Index int        `json:"index"`
+	Op    BulkAction `json:"op"`
+	ID    string     `json:"id"`
+	Agent *AgentView `json:"agent,omitempty"`
+	Task  *Task      `json:"task,omitempty"`
+}
+
+// ApplyAgentOps applies a batch of agent operations atomically: every
+// operation is validated against the registry as it would be after the
+// preceding ones, and nothing is changed unless all of them are valid.
+func (r *Registry) ApplyAgentOps(ops []AgentOp) ([]BulkResult, error) {
+	return r.ApplyAgentOpsIn("", ops)
+}
+
+// ApplyAgentOpsIn is ApplyAgentOps limited to one workspace: the agents it
+// creates belong to it, and agents of other workspaces are not found. An
+// empty workspace spans them all and creates agents in the default one.
+func (r *Registry) ApplyAgentOpsIn(workspace string, ops []AgentOp) ([]BulkResult, error) {
+	r.mu.Lock()
+	defer r.unlock()
+	if !r.hasWorkspace(workspace) {
+		return nil, ErrWorkspaceNotFound
+	}
+
+	// exists tracks IDs created or deleted earlier in the batch
+	exists := make(map[string]bool)
+	present := func(id string) bool {
+		if v, ok := exists[id]; ok {
+			return v
+		}
+		agent, ok := r.agents[id]
+		return ok && inWorkspace(agent.Workspace, workspace)
+	}
+	// taken also counts the IDs of other workspaces, which stay unique
+	taken := func(id string) bool {
+		if v, ok := exists[id]; ok {
+			return v
+		}
+		_, ok := r.agents[id]
+		return ok
+	}
+
+	ids := make([]string, len(ops))
+	for i, op := range ops {
+		switch op.Op {
+		case BulkCreate:
+			if op.Name == "" {
+				return nil, invalidOp(i, "name", "is required")
+			}
+			if op.Type == "" {
+				return nil, invalidOp(i, "type", "is required")
+			}
+			id := op.ID
+			if id == "" {
+				id = uuid.New().String()
+			} else if taken(id) {
+				return nil, &OpError{Index: i, Field: "id", Err: ErrAgentExists}
+			}
+			exists[id] = true
+			ids[i] = id
+		case BulkUpdate, BulkDelete:
+			if op.ID == "" {
+				return nil, invalidOp(i, "id", "is required")
+			}
+			if !present(op.ID) {
+				return nil, &OpError{Index: i, Field: "id", Err: ErrAgentNotFound}
+			}
+			if agent, ok := r.agents[op.ID]; ok && op.Version != 0 && agent.Version != op.Version {
+				return nil, &OpError{Index: i, Field: "version", Err: ErrVersionConflict}
+			}
+			if op.Op == BulkDelete {
+				if agent, ok := r.agents[op.ID]; ok && len(agent.Running)+len(agent.Queue) > 0 {
+					return nil, &OpError{Index: i, Field: "id", Err: ErrAgentBusy}
+				}
+				exists[op.ID] = false
+			}
+			ids[i] = op.ID
+		default:
+			return nil, invalidOp(i, "op", fmt.Sprintf("unknown op %q", op.Op))
+		}
+	}
+
+	results := make([]BulkResult, len(ops))
+	for i, op := range ops {
+		res := BulkResult{Index: i, Op: op.Op, ID: ids[i]}
+		switch op.Op {
+		case BulkCreate:
+			agent := newAgent(op.Name, string(op.Type), op.Config)
+			agent.ID = ids[i]
+			agent.Workspace = workspace
+			if op.Description != "" {
+				agent.Description = op.Description
+			}
+			agent.Labels = op.Labels
+			agent.Capabilities = op.Capabilities
+			r.addAgent(agent)
+			view := agent.View()
+			res.Agent = &view
+		case BulkUpdate:
+			agent := r.editAgent(r.agents[op.ID])
+			if op.Name != "" {
+				agent.Name = op.Name
+			}
+			if op.Type != "" {
+				agent.Type = op.Type
+			}
+			if op.Description != "" {
+				agent.Description = op.Description
+			}
+			if op.Labels != nil {
+				agent.Labels = op.Labels
+			}
+			if op.Capabilities != nil {
+				agent.Capabilities = op.Capabilities
+			}
+			applyAgentConfig(agent, op.Config)
+			agent.UpdatedAt = time.Now()
+			view := agent.View()
+			res.Agent = &view
+			r.emitAgent(EventAgentUpdated, agent)
+		case BulkDelete:
+			r.emitAgent(EventAgentDeleted, r.agents[op.ID])
+			delete(r.agents, op.ID)
+			r.markAgent(op.ID)
+		}
+		results[i] = res
+	}
+
+	r.changed()
+	r.logger.Printf("Applied %d bulk agent operations", len(ops))
+	return results, nil
+}
+
+// ApplyTaskOps applies a batch of task operations atomically, with the
+// same all-or-nothing semantics as ApplyAgentOps. Tasks that are running
+// cannot be deleted, and no tasks are created while draining.
+func (r *Registry) ApplyTaskOps(ops []TaskOp) ([]BulkResult, error) {
+	return r.ApplyTaskOpsBy(ops, Cause{Actor: "api"})
+}
+
+// ApplyTaskOpsBy is ApplyTaskOps recording who made the changes
+func (r *Registry) ApplyTaskOpsBy(ops []TaskOp, c Cause) ([]BulkResult, error) {
+	return r.ApplyTaskOpsIn("", ops, c)
+}
+
+// ApplyTaskOpsIn is ApplyTaskOpsBy limited to one workspace, as
+// ApplyAgentOpsIn is for agents
+func (r *Registry) ApplyTaskOpsIn(workspace string, ops []TaskOp, c Cause) ([]BulkResult, error) {
+	r.mu.Lock()
+	defer r.unlock()
+	if !r.hasWorkspace(workspace) {
+		return nil, ErrWorkspaceNotFound
+	}
+
+	exists := make(map[string]bool)
+	present := func(id string) bool {
+		if v, ok := exists[id]; ok {
+			return v
+		}
+		task, ok := r.tasks[id]
+		return ok && inWorkspace(task.Workspace, workspace)
+	}
+	// taken also counts the IDs of other workspaces, which stay unique
+	taken := func(id string) bool {
+		if v, ok := exists[id]; ok {
+			return v
+		}
+		_, ok := r.tasks[id]
+		return ok
+	}
+
+	ids := make([]string, len(ops))
+	for i, op := range ops {
+		if op.Priority != nil && (*op.Priority < PriorityLow || *op.Priority > PriorityUrgent) {
+			return nil, invalidOp(i, "priority", "must be between 0 and 3")
+		}
+		switch op.Op {
+		case BulkCreate:
+			if r.draining {
+				return nil, &OpError{Index: i, Err: ErrDraining}
+			}
+			if op.Title == "" {
+				return nil, invalidOp(i, "title", "is required")
+			}
+			id := op.ID
+			if id == "" {
+				id = uuid.New().String()
+			} else if taken(id) {
+				return nil, &OpError{Index: i, Field: "id", Err: ErrTaskExists}
+			}
+			exists[id] = true
+			ids[i] = id
+		case BulkUpdate, BulkDelete:
+			if op.ID == "" {
+				return nil, invalidOp(i, "id", "is required")
+			}
+			if !present(op.ID) {
+				return nil, &OpError{Index: i, Field: "id", Err: ErrTaskNotFound}
+			}
+			if op.Op == BulkDelete {
+				if task, ok := r.tasks[op.ID]; ok &&
+					(task.Status == TaskStatusInProgress || task.Status == TaskStatusQueued) {
+					return nil, &OpError{Index: i, Field: "id", Err: ErrTaskActive}
+				}
+				exists[op.ID] = false
+			}
+			ids[i] = op.ID
+		default:
+			return nil, invalidOp(i, "op", fmt.Sprintf("unknown op %q", op.Op))
+		}
+	}
+
+	results := make([]BulkResult, len(ops))
+	for i, op := range ops {
+		res := BulkResult{Index: i, Op: op.Op, ID: ids[i]}
+		switch op.Op {
+		case BulkCreate:
+			task := &Task{
+				ID:          ids[i],
+				Title:       op.Title,
+				Description: op.Description,
+				Labels:      op.Labels,
+				ProjectID:   op.ProjectID,
+				Workspace:   workspace,
+				Source:      op.Source,
+				Meta:        op.Meta,
+
+				AcceptanceCriteria: op.AcceptanceCriteria,
+			}
+			if task.Source == "" {
+				task.Source = "api"
+			}
+			if op.Priority != nil {
+				task.Priority = *op.Priority
+			}
+			r.addTask(task, c)
+			res.Task = task
+		case BulkUpdate:
+			task := r.editTask(r.tasks[op.ID])
+			if op.Title != "" {
+				task.Title = op.Title
+			}
+			if op.Description != "" {
+				task.Description = op.Description
+			}
+			if op.Priority != nil {
+				task.Priority = *op.Priority
+				r.reprioritize(task)
+			}
+			if op.Labels != nil {
+				task.Labels = op.Labels
+			}
+			if op.ProjectID != "" {
+				task.ProjectID = op.ProjectID
+			}
+			if op.AcceptanceCriteria != nil {
+				task.AcceptanceCriteria = op.AcceptanceCriteria
+			}
+			task.UpdatedAt = time.Now()
+			res.Task = task.Clone()
+			r.emitTask(EventTaskUpdated, task)
+		case BulkDelete:
+			task := r.tasks[op.ID]
+			r.recordTransition(EventTaskDeleted, task, task.Status, c)
+			r.emitTask(EventTaskDeleted, task)
+			delete(r.tasks, op.ID)
+			r.markTask(op.ID)
+			delete(r.routing, op.ID)
+		}
+		results[i] = res
+	}
+
+	r.changed()
+	r.logger.Printf("Applied %d bulk task operations", len(ops))
+	return results, nil
+}
@@ -0,0 +1,76 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestApplyAgentOpsIsAtomic(t *testing.T) {
+	r := NewRegistry(context.Background())
+	existing, _ := r.CreateAgent("existing", "coder", nil)
+
+	_, err := r.ApplyAgentOps([]AgentOp{
+		{Op: BulkCreate, Name: "a", Type: AgentTypeCoder},
+		{Op: BulkUpdate, ID: existing.ID, Name: "renamed"},
+		{Op: BulkDelete, ID: "missing"},
+	})
+	var opErr *OpError
+	if !errors.As(err, &opErr) || opErr.Index != 2 || !errors.Is(err, ErrAgentNotFound) {
+		t.Fatalf("err = %v, want not-found at operation 2", err)
+	}
+	if got := len(r.ListAgents()); got != 1 {
+		t.Fatalf("failed batch changed the registry: %d agents", got)
+	}
+	if existing.Name != "existing" {
+		t.Fatalf("failed batch applied an update: name %q", existing.Name)
+	}
+
+	results, err := r.ApplyAgentOps([]AgentOp{
+		{Op: BulkCreate, ID: "new", Name: "a", Type: AgentTypeCoder},
+		{Op: BulkUpdate, ID: "new", Labels: []string{"go"}},
+		{Op: BulkDelete, ID: existing.ID},
+	})
+	if err != nil {
+		t.Fatalf("ApplyAgentOps: %v", err)
+	}
+	if len(results) != 3 || results[0].ID != "new" {
+		t.Fatalf("results = %+v", results)
+	}
+	agent, ok := r.GetAgent("new")
+	if !ok || len(agent.Labels) != 1 {
+		t.Fatalf("created agent = %+v", agent)
+	}
+	if _, ok := r.GetAgent(existing.ID); ok {
+		t.Fatal("deleted agent is still registered")
+	}
+}
+
+func TestApplyTaskOpsValidation(t *testing.T) {
+	r := NewRegistry(context.Background())
+	bad := TaskPriority(9)
+
+	cases := []struct {
+		name string
+		ops  []TaskOp
+		want error
+	}{
+		{"missing title", []TaskOp{{Op: BulkCreate}}, ErrInvalidOperation},
+		{"bad priority", []TaskOp{{Op: BulkCreate, Title: "t", Priority: &bad}}, ErrInvalidOperation},
+		{"duplicate id", []TaskOp{{Op: BulkCreate, ID: "x", Title: "t"}, {Op: BulkCreate, ID: "x", Title: "u"}}, ErrTaskExists},
+		{"unknown op", []TaskOp{{Op: "merge", ID: "x"}}, ErrInvalidOperation},
+	}
+	for _, tc := range cases {
+		if _, err := r.ApplyTaskOps(tc.ops); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+	if n := len(r.ListTasks()); n != 0 {
+		t.Fatalf("rejected batches created %d tasks", n)
+	}
+
+	r.BeginDrain()
+	if _, err := r.ApplyTaskOps([]TaskOp{{Op: BulkCreate, Title: "t"}}); !errors.Is(err, ErrDraining) {
+		t.Fatalf("create while draining: err = %v", err)
+	}
+}
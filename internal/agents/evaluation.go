@@ -0,0 +1,100 @@
+package agents
+
+import (
+	"strings"
+	"time"
+)
+
+// ErrTaskNotCompleted is returned when evaluating or revising a task that
+// has no successful result
+var ErrTaskNotCompleted = &AgentError{message: "task has not completed successfully"}
+
+// Evaluation scores a task's result against its acceptance criteria
+type Evaluation struct {
+	// Score is from 0 to 100
+	Score int `json:"score"`
+	// Passed is set when the score reaches the threshold and every
+	// criterion is met
+	Passed    bool              `json:"passed"`
+	Threshold int               `json:"threshold"`
+	Summary   string            `json:"summary,omitempty"`
+	Criteria  []CriterionResult `json:"criteria,omitempty"`
+	// Feedback tells the agent what to change when the result is sent
+	// back for revision
+	Feedback string `json:"feedback,omitempty"`
+	// Model is the evaluator's model
+	Model       string    `json:"model,omitempty"`
+	EvaluatedAt time.Time `json:"evaluated_at"`
+}
+
+// CriterionResult tells whether a result meets one acceptance criterion
+type CriterionResult struct {
+	Criterion string `json:"criterion"`
+	Met       bool   `json:"met"`
+	Comment   string `json:"comment,omitempty"`
+}
+
+// SetTaskEvaluation records the evaluation of a completed task's result
+func (r *Registry) SetTaskEvaluation(taskID string, e *Evaluation) error {
+	r.mu.Lock()
+	defer r.unlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if task.Status != TaskStatusCompleted || task.Result == nil || !task.Result.Success {
+		return ErrTaskNotCompleted
+	}
+	task = r.editTask(task)
+	eval := *e
+	task.Result.Evaluation = &eval
+	task.UpdatedAt = time.Now()
+	r.logger.Printf("Evaluated task %s: score %d", taskID, e.Score)
+	r.emitTask(EventTaskEvaluated, task)
+	return nil
+}
+
+// ReviseTask sends a completed task back for another attempt, with
+// feedback on what to change. It goes back to the agent that did it when
+// one of that agent's slots is free, and waits for auto-assignment
+// otherwise. The result
+// of the attempt that was sent back stays on the task until the next one
+// completes.
+func (r *Registry) ReviseTask(taskID, feedback string, c Cause) error {
+	r.mu.Lock()
+	defer r.unlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if task.Status != TaskStatusCompleted || task.Result == nil || !task.Result.Success {
+		return ErrTaskNotCompleted
+	}
+	if r.draining {
+		return ErrDraining
+	}
+
+	from := task.Status
+	task = r.editTask(task)
+	task.Status = TaskStatusPending
+	task.Revision++
+	task.Feedback = strings.TrimSpace(feedback)
+	task.StartedAt = nil
+	task.CompletedAt = nil
+	task.UpdatedAt = time.Now()
+	if c.Reason == "" {
+		c.Reason = task.Feedback
+	}
+	r.recordTransition(EventTaskRevised, task, from, c)
+	r.logger.Printf("Sent task %s back for revision %d", taskID, task.Revision)
+	r.emitTask(EventTaskRevised, task)
+
+	if agent, ok := r.agents[task.AssignedTo]; ok && agent.Accepts(TaskStatusInProgress) {
+		if task, agent, err := r.claim(task, agent.ID, TaskStatusInProgress); err == nil {
+			r.assign(task, agent, TaskStatusInProgress, Cause{Actor: c.Actor, Reason: "revision by the agent that did the task"})
+		}
+	}
+	return nil
+}
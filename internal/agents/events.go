@@ -0,0 +1,143 @@
+package agents
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType names a change in the registry
+type EventType string
+
+const (
+	EventAgentCreated  EventType = "agent.created"
+	EventAgentUpdated  EventType = "agent.updated"
+	EventAgentDeleted  EventType = "agent.deleted"
+	EventAgentStarted  EventType = "agent.started"
+	EventAgentStopped  EventType = "agent.stopped"
+	EventAgentError    EventType = "agent.error" // a task failed on the agent
+	EventTaskCreated   EventType = "task.created"
+	EventTaskUpdated   EventType = "task.updated"
+	EventTaskDeleted   EventType = "task.deleted"
+	EventTaskAssigned  EventType = "task.assigned"
+	EventTaskStarted   EventType = "task.started" // a queued task took a free slot of its agent
+	EventTaskCompleted EventType = "task.completed"
+	EventTaskFailed    EventType = "task.failed"
+	EventTaskCancelled EventType = "task.cancelled"
+	EventTaskEvaluated EventType = "task.evaluated" // a result was scored
+	EventTaskRevised   EventType = "task.revised"   // a result was sent back
+	EventTaskRetried   EventType = "task.retried"   // a failed run is to be tried again
+)
+
+// EventTypes lists every event the registry emits
+var EventTypes = []EventType{
+	EventAgentCreated, EventAgentUpdated, EventAgentDeleted,
+	EventAgentStarted, EventAgentStopped, EventAgentError,
+	EventTaskCreated, EventTaskUpdated, EventTaskDeleted,
+	EventTaskAssigned, EventTaskStarted, EventTaskCompleted,
+	EventTaskFailed, EventTaskCancelled, EventTaskEvaluated,
+	EventTaskRevised, EventTaskRetried,
+}
+
+// Event describes one change. Data holds a snapshot of the agent and/or
+// task taken when the change happened.
+type Event struct {
+	Type    EventType `json:"type"`
+	Time    time.Time `json:"time"`
+	AgentID string    `json:"agent_id,omitempty"`
+	TaskID  string    `json:"task_id,omitempty"`
+	// Workspace is the workspace of the agent or task
+	Workspace string                 `json:"workspace,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Final reports whether a task.failed event ends its task: false when the
+// failed run will be retried. Other events are final.
+func (e Event) Final() bool {
+	final, ok := e.Data["final"].(bool)
+	return !ok || final
+}
+
+// eventHub fans registry events out to subscribers
+type eventHub struct {
+	mu      sync.Mutex
+	subs    map[int]chan Event
+	nextSub int
+}
+
+// Subscribe registers for events from now on. Events are dropped for a
+// subscriber whose buffer is full. The returned cancel function must be
+// called to release the subscription.
+func (r *Registry) Subscribe(buffer int) (<-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	ch := make(chan Event, buffer)
+
+	h := &r.events
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = make(map[int]chan Event)
+	}
+	id := h.nextSub
+	h.nextSub++
+	h.subs[id] = ch
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, id)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// emit delivers an event to every subscriber without blocking
+func (r *Registry) emit(e Event) {
+	e.Time = time.Now()
+	h := &r.events
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// emitAgent emits an agent event; the caller holds r.mu
+func (r *Registry) emitAgent(t EventType, agent *Agent) {
+	r.emit(Event{
+		Type:      t,
+		AgentID:   agent.ID,
+		Workspace: workspaceOf(agent.Workspace),
+		Data:      map[string]interface{}{"agent": agentSnapshot(agent)},
+	})
+}
+
+// emitTask emits a task event; the caller holds r.mu
+func (r *Registry) emitTask(t EventType, task *Task) {
+	r.emit(Event{
+		Type:      t,
+		AgentID:   task.AssignedTo,
+		TaskID:    task.ID,
+		Workspace: workspaceOf(task.Workspace),
+		Data:      map[string]interface{}{"task": *task.Clone()},
+	})
+}
+
+// agentSnapshot copies the fields of an agent that events carry
+func agentSnapshot(agent *Agent) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           agent.ID,
+		"name":         agent.Name,
+		"type":         agent.Type,
+		"status":       agent.Status,
+		"workspace":    workspaceOf(agent.Workspace),
+		"labels":       append([]string(nil), agent.Labels...),
+		"capabilities": append([]string(nil), agent.Capabilities...),
+	}
+}
@@ -0,0 +1,126 @@
+package agents
+
+import (
+	"time"
+)
+
+// Cause says who made a change to a task and why, for its history
+type Cause struct {
+	// Actor is who made the change: an API key name, "api",
+	// "auto-assign", "project-manager" or "system"
+	Actor string `json:"actor"`
+	// Reason explains the change, such as why an agent was chosen or why
+	// a task failed
+	Reason string `json:"reason,omitempty"`
+}
+
+// systemCause is recorded for changes made without a Cause
+var systemCause = Cause{Actor: "system"}
+
+// Transition is one change of a task's state. Transitions are appended to
+// the registry's history and never modified, so that the history tells
+// how a task reached its current state even after it is deleted.
+type Transition struct {
+	// Seq orders every transition of every task; it starts at 1
+	Seq    int64     `json:"seq"`
+	TaskID string    `json:"task_id"`
+	Time   time.Time `json:"time"`
+	// Event is the registry event the transition produced
+	Event EventType  `json:"event"`
+	From  TaskStatus `json:"from,omitempty"`
+	To    TaskStatus `json:"to,omitempty"`
+	// AgentID is the agent the task is assigned to after the change
+	AgentID string `json:"agent_id,omitempty"`
+	// Workspace is the task's, kept so that the history of a deleted task
+	// stays scoped to it
+	Workspace string `json:"workspace"`
+	Cause
+}
+
+// history is the append-only log of task transitions, indexed by task
+type history struct {
+	log    []Transition
+	byTask map[string][]int // positions in log
+}
+
+// recordTransition appends a transition of task, whose status was from
+// before the change; the caller holds r.mu
+func (r *Registry) recordTransition(event EventType, task *Task, from TaskStatus, c Cause) {
+	if c.Actor == "" {
+		c.Actor = systemCause.Actor
+	}
+	h := &r.history
+	if h.byTask == nil {
+		h.byTask = make(map[string][]int)
+	}
+	t := Transition{
+		Seq:       int64(len(h.log)) + 1,
+		TaskID:    task.ID,
+		Time:      time.Now(),
+		Event:     event,
+		From:      from,
+		To:        task.Status,
+		AgentID:   task.AssignedTo,
+		Cause:     c,
+		Workspace: workspaceOf(task.Workspace),
+	}
+	if event == EventTaskDeleted {
+		t.To = ""
+	}
+	h.byTask[task.ID] = append(h.byTask[task.ID], len(h.log))
+	h.log = append(h.log, t)
+}
+
+// TaskHistory returns the transitions of a task, oldest first. The
+// history of a deleted task is kept; ok is false for an unknown task.
+func (r *Registry) TaskHistory(taskID string) (transitions []Transition, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	positions, ok := r.history.byTask[taskID]
+	if !ok {
+		return nil, false
+	}
+	transitions = make([]Transition, len(positions))
+	for i, p := range positions {
+		transitions[i] = r.history.log[p]
+	}
+	return transitions, true
+}
+
+// TransitionsSince returns up to limit transitions of any task with a
+// sequence number above seq, oldest first, so that a consumer can catch up
+// from the last transition it saw. A limit of 0 returns them all.
+func (r *Registry) TransitionsSince(seq int64, limit int) []Transition {
+	return r.TransitionsSinceIn(seq, limit, "")
+}
+
+// TransitionsSinceIn is TransitionsSince for the tasks of one workspace;
+// an empty workspace means every task
+func (r *Registry) TransitionsSinceIn(seq int64, limit int, workspace string) []Transition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if seq < 0 {
+		seq = 0
+	}
+	log := r.history.log
+	if seq >= int64(len(log)) {
+		return []Transition{}
+	}
+	rest := log[seq:]
+	if workspace == "" {
+		if limit > 0 && len(rest) > limit {
+			rest = rest[:limit]
+		}
+		return append([]Transition(nil), rest...)
+	}
+	out := []Transition{}
+	for _, t := range rest {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		if t.Workspace == workspace {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTaskHistory(t *testing.T) {
+	r := NewRegistry(context.Background())
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+
+	task := r.CreateTaskBy(&Task{Title: "build"}, Cause{Actor: "alice"})
+	if err := r.AssignTaskBy(task.ID, agent.ID, Cause{Actor: "alice", Reason: "knows the code"}); err != nil {
+		t.Fatal(err)
+	}
+	r.CompleteTask(task.ID, &TaskResult{Success: false, Error: "tests failed"})
+
+	history, ok := r.TaskHistory(task.ID)
+	if !ok || len(history) != 3 {
+		t.Fatalf("history = %+v", history)
+	}
+	want := []struct {
+		event    EventType
+		from, to TaskStatus
+		actor    string
+		reason   string
+	}{
+		{EventTaskCreated, "", TaskStatusPending, "alice", ""},
+		{EventTaskAssigned, TaskStatusPending, TaskStatusInProgress, "alice", "knows the code"},
+		{EventTaskFailed, TaskStatusInProgress, TaskStatusCompleted, "system", "tests failed"},
+	}
+	for i, w := range want {
+		got := history[i]
+		if got.Event != w.event || got.From != w.from || got.To != w.to || got.Actor != w.actor || got.Reason != w.reason {
+			t.Errorf("transition %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if history[1].AgentID != agent.ID {
+		t.Errorf("assignment recorded agent %q, want %q", history[1].AgentID, agent.ID)
+	}
+
+	// Cancelling frees the agent; the history outlives the task
+	other := r.CreateTask(&Task{Title: "docs", Source: "api"})
+	if err := r.AssignTask(other.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CancelTask(other.ID, Cause{Actor: "bob", Reason: "duplicate"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CancelTask(other.ID, Cause{Actor: "bob"}); !errors.Is(err, ErrTaskFinished) {
+		t.Errorf("second cancel = %v, want ErrTaskFinished", err)
+	}
+	if a, _ := r.GetAgent(agent.ID); a.Status != StatusIdle || a.CurrentTask != nil {
+		t.Errorf("agent after cancel: status %s, current task %v", a.Status, a.CurrentTask)
+	}
+	if _, err := r.ApplyTaskOps([]TaskOp{{Op: BulkDelete, ID: other.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	history, ok = r.TaskHistory(other.ID)
+	if !ok || len(history) != 4 || history[0].Actor != "api" || history[2].Reason != "duplicate" ||
+		history[3].Event != EventTaskDeleted || history[3].From != TaskStatusCancelled {
+		t.Fatalf("history of the deleted task = %+v", history)
+	}
+
+	all := r.TransitionsSince(0, 0)
+	if len(all) != 7 {
+		t.Fatalf("%d transitions in all, want 7", len(all))
+	}
+	for i, tr := range all {
+		if tr.Seq != int64(i+1) {
+			t.Fatalf("transition %d has seq %d", i, tr.Seq)
+		}
+	}
+	if page := r.TransitionsSince(5, 1); len(page) != 1 || page[0].Seq != 6 {
+		t.Fatalf("TransitionsSince(5, 1) = %+v", page)
+	}
+}
@@ -0,0 +1,19 @@
+package agents
+
+import "github.com/biodoia/skagent/internal/metrics"
+
+var (
+	tasksCreated = metrics.Default.NewCounter("skagent_tasks_created_total",
+		"Tasks added to the registry.")
+	tasksFinished = metrics.Default.NewCounter("skagent_tasks_finished_total",
+		"Tasks that finished, by outcome.", "outcome")
+)
+
+// recordFinished counts a finished task by the success of its result
+func recordFinished(result *TaskResult) {
+	if result != nil && !result.Success {
+		tasksFinished.Inc("failure")
+		return
+	}
+	tasksFinished.Inc("success")
+}
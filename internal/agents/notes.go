@@ -0,0 +1,119 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Metadata keys holding an agent's notes
+const (
+	// MetaNotes is the current text of the notes
+	MetaNotes = "notes"
+	// MetaNotesHistory is the JSON list of the notes' revisions, oldest
+	// first, the current one included
+	MetaNotesHistory = "notes_history"
+)
+
+const (
+	// maxNotesSize bounds the text of an agent's notes, in bytes
+	maxNotesSize = 16 << 10
+	// maxNoteRevisions bounds the revisions kept in the history
+	maxNoteRevisions = 20
+)
+
+// NoteRevision is one version of an agent's notes
+type NoteRevision struct {
+	Text string `json:"text"`
+	// Author wrote this version: an agent, an API key name or "api"
+	Author string    `json:"author,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Notes are the freeform notes attached to an agent, such as its current
+// blockers or the quirks of its environment
+type Notes struct {
+	Text      string     `json:"text"`
+	UpdatedBy string     `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// History lists the earlier versions, newest first
+	History []NoteRevision `json:"history"`
+}
+
+// AgentNotes returns the notes of an agent, with their history
+func AgentNotes(a *Agent) Notes {
+	notes := Notes{Text: a.Meta[MetaNotes], History: []NoteRevision{}}
+	revisions := noteRevisions(a.Meta)
+	if len(revisions) == 0 {
+		return notes
+	}
+	last := revisions[len(revisions)-1]
+	notes.UpdatedBy, notes.UpdatedAt = last.Author, &last.At
+	for i := len(revisions) - 2; i >= 0; i-- {
+		notes.History = append(notes.History, revisions[i])
+	}
+	return notes
+}
+
+// noteRevisions decodes the history kept in an agent's metadata; a
+// history that cannot be read is dropped
+func noteRevisions(meta map[string]string) []NoteRevision {
+	var revisions []NoteRevision
+	if raw := meta[MetaNotesHistory]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &revisions); err != nil {
+			return nil
+		}
+	}
+	return revisions
+}
+
+// SetAgentNotes replaces the notes of an agent and returns a copy of the
+// agent. The previous text stays in the history, which keeps the last
+// maxNoteRevisions versions; an empty text clears the notes. Notes are
+// not settings, so they can change while the agent is working. version
+// works as in UpdateAgentIf.
+func (r *Registry) SetAgentNotes(agentID string, version int64, text, author string) (*Agent, error) {
+	if len(text) > maxNotesSize {
+		return nil, invalidField("text", fmt.Sprintf("must be at most %d bytes", maxNotesSize))
+	}
+
+	r.mu.Lock()
+	defer r.unlock()
+
+	agent, ok := r.agents[agentID]
+	if !ok {
+		return nil, ErrAgentNotFound
+	}
+	if version != 0 && agent.Version != version {
+		return nil, ErrVersionConflict
+	}
+	if agent.Meta[MetaNotes] == text {
+		return agent.Clone(), nil
+	}
+
+	now := time.Now()
+	revisions := append(noteRevisions(agent.Meta), NoteRevision{Text: text, Author: author, At: now})
+	if len(revisions) > maxNoteRevisions {
+		revisions = revisions[len(revisions)-maxNoteRevisions:]
+	}
+	history, err := json.Marshal(revisions)
+	if err != nil {
+		return nil, err
+	}
+
+	agent = r.editAgent(agent)
+	if agent.Meta == nil {
+		agent.Meta = make(map[string]string)
+	}
+	if text == "" {
+		delete(agent.Meta, MetaNotes)
+	} else {
+		agent.Meta[MetaNotes] = text
+	}
+	agent.Meta[MetaNotesHistory] = string(history)
+	agent.UpdatedAt = now
+
+	r.logger.Printf("Updated the notes of agent %s", agentID)
+	r.emitAgent(EventAgentUpdated, agent)
+	return agent.Clone(), nil
+}
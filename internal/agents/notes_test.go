@@ -0,0 +1,64 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetAgentNotes(t *testing.T) {
+	r := NewRegistry(context.Background())
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+	if notes := AgentNotes(agent); notes.Text != "" || notes.UpdatedAt != nil || len(notes.History) != 0 {
+		t.Fatalf("notes of a new agent: %+v", notes)
+	}
+
+	updated, err := r.SetAgentNotes(agent.ID, agent.Version, "blocked on the staging database", "alice")
+	if err != nil {
+		t.Fatalf("SetAgentNotes() = %v", err)
+	}
+	if updated.Version != agent.Version+1 || updated.Meta[MetaNotes] != "blocked on the staging database" {
+		t.Fatalf("notes not applied: %+v", updated)
+	}
+	if _, err := r.SetAgentNotes(agent.ID, agent.Version, "stale", "bob"); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("err = %v, want ErrVersionConflict", err)
+	}
+
+	// Notes change while the agent works
+	task := r.CreateTask(&Task{Title: "work"})
+	if err := r.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	updated, err = r.SetAgentNotes(agent.ID, 0, "needs GOFLAGS=-mod=mod", "coder")
+	if err != nil {
+		t.Fatalf("notes of a busy agent: %v", err)
+	}
+	notes := AgentNotes(updated)
+	if notes.Text != "needs GOFLAGS=-mod=mod" || notes.UpdatedBy != "coder" || notes.UpdatedAt == nil ||
+		len(notes.History) != 1 || notes.History[0].Author != "alice" {
+		t.Fatalf("notes: %+v", notes)
+	}
+
+	// The same text is not a new version
+	if again, _ := r.SetAgentNotes(agent.ID, 0, "needs GOFLAGS=-mod=mod", "coder"); again.Version != updated.Version {
+		t.Fatalf("version = %d, want %d", again.Version, updated.Version)
+	}
+
+	for i := 0; i < maxNoteRevisions+5; i++ {
+		r.SetAgentNotes(agent.ID, 0, strings.Repeat("x", i+1), "coder")
+	}
+	cleared, _ := r.SetAgentNotes(agent.ID, 0, "", "alice")
+	notes = AgentNotes(cleared)
+	if _, ok := cleared.Meta[MetaNotes]; ok || notes.Text != "" || len(notes.History) != maxNoteRevisions-1 {
+		t.Fatalf("cleared notes: text %q, %d revisions", notes.Text, len(notes.History))
+	}
+
+	var fe *FieldError
+	if _, err := r.SetAgentNotes(agent.ID, 0, strings.Repeat("x", maxNotesSize+1), "alice"); !errors.As(err, &fe) || fe.Field != "text" {
+		t.Fatalf("err = %v, want too long", err)
+	}
+	if _, err := r.SetAgentNotes("missing", 0, "x", "alice"); !errors.Is(err, ErrAgentNotFound) {
+		t.Fatalf("err = %v, want ErrAgentNotFound", err)
+	}
+}
@@ -0,0 +1,203 @@
+package agents
+
+import (
+	"sort"
+	"time"
+)
+
+// defaultQueueSize bounds the queue of agents without a queue_size
+const defaultQueueSize = 10
+
+// QueuedTask is a task waiting in an agent's queue for one of its slots
+type QueuedTask struct {
+	ID       string       `json:"id"`
+	Priority TaskPriority `json:"priority"`
+	QueuedAt time.Time    `json:"queued_at"`
+}
+
+// Slots is how many tasks the agent runs at once: Config.MaxConcurrent,
+// at least 1
+func (a *Agent) Slots() int {
+	if a.Config.MaxConcurrent < 1 {
+		return 1
+	}
+	return a.Config.MaxConcurrent
+}
+
+// QueueSize bounds the agent's queue: Config.QueueSize, or 10
+func (a *Agent) QueueSize() int {
+	if a.Config.QueueSize < 1 {
+		return defaultQueueSize
+	}
+	return a.Config.QueueSize
+}
+
+// Accepts reports whether the agent can take a task with status: in
+// progress when one of its slots is free, queued when its queue has room.
+// Paused, failed and offline agents take nothing.
+func (a *Agent) Accepts(status TaskStatus) bool {
+	if a.Status != StatusIdle && a.Status != StatusWorking {
+		return false
+	}
+	switch status {
+	case TaskStatusInProgress:
+		return len(a.Running) < a.Slots()
+	case TaskStatusQueued:
+		return len(a.Queue) < a.QueueSize()
+	}
+	return false
+}
+
+// Hold records task on the agent as its status says: a task in progress
+// takes a slot, a queued one waits behind the queued tasks of higher
+// priority and those of the same priority queued before it
+func (a *Agent) Hold(task *Task) {
+	queuedAt := task.UpdatedAt
+	for _, q := range a.Queue {
+		if q.ID == task.ID {
+			queuedAt = q.QueuedAt
+		}
+	}
+	a.drop(task.ID)
+	switch task.Status {
+	case TaskStatusInProgress:
+		a.Running = append(a.Running, task.ID)
+		if a.CurrentTask == nil {
+			a.CurrentTask = task
+		}
+	case TaskStatusQueued:
+		a.Queue = append(a.Queue, QueuedTask{ID: task.ID, Priority: task.Priority, QueuedAt: queuedAt})
+		sort.SliceStable(a.Queue, func(i, j int) bool {
+			if a.Queue[i].Priority != a.Queue[j].Priority {
+				return a.Queue[i].Priority > a.Queue[j].Priority
+			}
+			return a.Queue[i].QueuedAt.Before(a.Queue[j].QueuedAt)
+		})
+	}
+	a.settle()
+}
+
+// holds reports whether a task is in the agent's slots or queue
+func (a *Agent) holds(taskID string) bool {
+	for _, id := range a.Running {
+		if id == taskID {
+			return true
+		}
+	}
+	for _, q := range a.Queue {
+		if q.ID == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// drop takes a task off the agent's slots and queue
+func (a *Agent) drop(taskID string) {
+	for i, id := range a.Running {
+		if id == taskID {
+			a.Running = append(a.Running[:i:i], a.Running[i+1:]...)
+		}
+	}
+	for i, q := range a.Queue {
+		if q.ID == taskID {
+			a.Queue = append(a.Queue[:i:i], a.Queue[i+1:]...)
+		}
+	}
+	if a.CurrentTask != nil && a.CurrentTask.ID == taskID {
+		a.CurrentTask = nil
+	}
+	a.settle()
+}
+
+// settle brings the status and load of the agent in line with what it
+// holds: it is working while a task runs, and its load is the share of
+// its slots and queue in use
+func (a *Agent) settle() {
+	if a.Status == StatusIdle || a.Status == StatusWorking {
+		a.Status = StatusIdle
+		if len(a.Running) > 0 {
+			a.Status = StatusWorking
+		}
+	}
+	a.Load = (len(a.Running) + len(a.Queue)) * 100 / (a.Slots() + a.QueueSize())
+}
+
+// release takes a task that finished, or left the agent, off the agent it
+// was assigned to, then starts the queued tasks the freed slot allows;
+// the caller holds r.mu
+func (r *Registry) release(agentID, taskID string) {
+	agent, ok := r.agents[agentID]
+	if !ok || !agent.holds(taskID) {
+		return
+	}
+	agent = r.editAgent(agent)
+	agent.drop(taskID)
+	if agent.CurrentTask == nil && len(agent.Running) > 0 {
+		agent.CurrentTask = r.tasks[agent.Running[0]]
+	}
+	agent.UpdatedAt = time.Now()
+	r.startQueued(agent)
+}
+
+// reprioritize moves a queued task whose priority changed to its new place
+// in its agent's queue; the caller holds r.mu
+func (r *Registry) reprioritize(task *Task) {
+	agent, ok := r.agents[task.AssignedTo]
+	if !ok || task.Status != TaskStatusQueued || !agent.holds(task.ID) {
+		return
+	}
+	agent = r.editAgent(agent)
+	agent.Hold(task)
+}
+
+// startQueued moves the first tasks of an agent's queue to its free
+// slots; the caller holds r.mu
+func (r *Registry) startQueued(agent *Agent) {
+	for len(agent.Queue) > 0 && agent.Accepts(TaskStatusInProgress) {
+		next := agent.Queue[0]
+		task, ok := r.tasks[next.ID]
+		if !ok || task.AssignedTo != agent.ID || task.Status != TaskStatusQueued {
+			// Moved on since it was queued
+			agent = r.editAgent(agent)
+			agent.drop(next.ID)
+			continue
+		}
+		task, claimed, err := r.claim(task, agent.ID, TaskStatusInProgress)
+		if err != nil {
+			r.logger.Printf("WARN: Starting task %s on agent %s: %v", next.ID, agent.ID, err)
+			return
+		}
+		agent = claimed
+		now := time.Now()
+		task = r.editTask(task)
+		task.Status = TaskStatusInProgress
+		task.Owner = r.instance
+		task.StartedAt = &now
+		task.UpdatedAt = now
+		r.recordTransition(EventTaskStarted, task, TaskStatusQueued, Cause{Actor: systemCause.Actor, Reason: "next in the agent's queue"})
+		agent = r.editAgent(agent)
+		agent.Hold(task)
+		agent.UpdatedAt = now
+		r.logger.Printf("Started queued task %s on agent %s", task.ID, agent.ID)
+		r.emitTask(EventTaskStarted, task)
+	}
+}
+
+// unqueue sends the queued tasks of a deleted agent back to pending; the
+// caller holds r.mu
+func (r *Registry) unqueue(agent *Agent) {
+	c := Cause{Actor: systemCause.Actor, Reason: "the agent was deleted"}
+	for _, q := range agent.Queue {
+		task, ok := r.tasks[q.ID]
+		if !ok || task.AssignedTo != agent.ID || task.Status != TaskStatusQueued {
+			continue
+		}
+		task = r.editTask(task)
+		task.Status = TaskStatusPending
+		task.AssignedTo = ""
+		task.UpdatedAt = time.Now()
+		r.recordTransition(EventTaskUpdated, task, TaskStatusQueued, c)
+		r.emitTask(EventTaskUpdated, task)
+	}
+}
@@ -0,0 +1,65 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAgentQueue(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(ctx)
+	agent, _ := r.CreateAgent("a", "coder", map[string]interface{}{"max_concurrent": 2.0, "queue_size": 2.0})
+	events, unsubscribe := r.Subscribe(64)
+	defer unsubscribe()
+
+	var tasks []*Task
+	for _, p := range []TaskPriority{PriorityMedium, PriorityMedium, PriorityLow, PriorityUrgent} {
+		task := r.CreateTask(&Task{Title: "t", Priority: p})
+		if err := r.AssignTask(task.ID, agent.ID); err != nil {
+			t.Fatal(err)
+		}
+		tasks = append(tasks, task)
+	}
+	// Two tasks take the slots, the others wait by priority
+	a, _ := r.GetAgent(agent.ID)
+	if len(a.Running) != 2 || len(a.Queue) != 2 || a.Queue[0].ID != tasks[3].ID || a.Status != StatusWorking || a.Load != 100 {
+		t.Fatalf("agent = %+v", a)
+	}
+	if got, _ := r.GetTask(tasks[2].ID); got.Status != TaskStatusQueued || got.StartedAt != nil {
+		t.Fatalf("queued task = %+v", got)
+	}
+	extra := r.CreateTask(&Task{Title: "extra"})
+	if err := r.AssignTask(extra.ID, agent.ID); !errors.Is(err, ErrAgentBusy) {
+		t.Fatalf("err = %v with the queue full", err)
+	}
+	// Auto-assignment leaves the queue to explicit assignments
+	if n := r.AutoAssign(ctx); n != 0 {
+		t.Fatalf("auto-assigned %d", n)
+	}
+
+	// A finished task frees a slot for the most urgent queued one
+	if err := r.CompleteTask(tasks[0].ID, &TaskResult{Success: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.GetTask(tasks[3].ID); got.Status != TaskStatusInProgress || got.StartedAt == nil {
+		t.Fatalf("urgent task = %+v", got)
+	}
+	started := false
+	for len(events) > 0 {
+		if ev := <-events; ev.Type == EventTaskStarted && ev.TaskID == tasks[3].ID {
+			started = true
+		}
+	}
+	if !started {
+		t.Error("no task.started event")
+	}
+
+	// A cancelled queued task leaves the queue
+	if err := r.CancelTask(tasks[2].ID, Cause{Actor: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := r.GetAgentView(agent.ID); v.QueueDepth != 0 || len(v.Running) != 2 || v.Load != 50 {
+		t.Fatalf("view = %+v", v)
+	}
+}
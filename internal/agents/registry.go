@@ -3,9 +3,16 @@ package agents
 import (
 	"context"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/biodoia/skagent/internal/artifacts"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
 	"github.com/google/uuid"
 )
 
@@ -41,14 +48,21 @@ type Agent struct {
 	Description  string            `json:"description,omitempty"`
 	Labels       []string          `json:"labels,omitempty"`
 	Capabilities []string          `json:"capabilities,omitempty"`
-	Load         int               `json:"load,omitempty"` // 0-100
+	Workspace    string            `json:"workspace"`
+	Load         int               `json:"load,omitempty"` // 0-100, share of the slots and queue in use
 	Config       AgentConfig       `json:"config"`
 	Stats        AgentStats        `json:"stats"`
-	CurrentTask  *Task             `json:"current_task,omitempty"`
+	CurrentTask  *Task             `json:"current_task,omitempty"` // the first of Running
+	// Running are the tasks in progress on the agent, at most Slots
+	Running []string `json:"running,omitempty"`
+	// Queue are the tasks assigned to the agent that wait for a slot, in
+	// the order they will start: by priority, then oldest first
+	Queue []QueuedTask `json:"queue,omitempty"`
 	CreatedAt    time.Time         `json:"created_at"`
 	UpdatedAt    time.Time         `json:"updated_at"`
 	Meta         map[string]string `json:"meta,omitempty"`
-	mu           sync.RWMutex
+	// Version counts the changes made to the agent, starting at 1
+	Version int64 `json:"version"`
 }
 
 // AgentConfig holds agent-specific configuration
@@ -57,6 +71,7 @@ type AgentConfig struct {
 	Model          string   `json:"model,omitempty"`
 	SystemPrompt   string   `json:"system_prompt,omitempty"`
 	MaxConcurrent  int      `json:"max_concurrent"`
+	QueueSize      int      `json:"queue_size,omitempty"` // 0 uses 10
 	Timeout        int      `json:"timeout_seconds"`
 	AutoAssign     bool     `json:"auto_assign"`
 	PreferredTasks []string `json:"preferred_tasks,omitempty"`
@@ -80,11 +95,22 @@ type Task struct {
 	Priority    TaskPriority      `json:"priority"`
 	Status      TaskStatus        `json:"status"`
 	AssignedTo  string            `json:"assigned_to,omitempty"`
+	Owner       string            `json:"owner,omitempty"` // registry instance that claimed the assignment; runs only there
 	Labels      []string          `json:"labels,omitempty"`
+	AgentTypes  []string          `json:"agent_types,omitempty"` // types of agent that may take the task; any when empty
 	ProjectID   string            `json:"project_id,omitempty"`
+	Workspace   string            `json:"workspace"`
 	ExternalID  string            `json:"external_id,omitempty"` // ID from project manager
 	Source      string            `json:"source,omitempty"`      // linear, github, jira
 	Result      *TaskResult       `json:"result,omitempty"`
+	Artifacts   []artifacts.Artifact `json:"artifacts,omitempty"` // uploaded through the artifact store
+	CallbackURL string            `json:"callback_url,omitempty"` // receives the result when the task finishes
+	AcceptanceCriteria []string   `json:"acceptance_criteria,omitempty"` // what a result must do to be accepted
+	Revision    int               `json:"revision,omitempty"`  // times the task was sent back for revision
+	Feedback    string            `json:"feedback,omitempty"`  // what to change, when sent back
+	Retry       *config.RetryPolicy `json:"retry,omitempty"`   // overrides the configured retry policy
+	Attempts    []Attempt         `json:"attempts,omitempty"` // failed runs that were retried
+	RetryAt     *time.Time        `json:"retry_at,omitempty"` // when a retried task may be assigned again
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
 	StartedAt   *time.Time        `json:"started_at,omitempty"`
@@ -120,6 +146,9 @@ type TaskResult struct {
 	Output    string    `json:"output,omitempty"`
 	Error     string    `json:"error,omitempty"`
 	Artifacts []string  `json:"artifacts,omitempty"` // file paths, URLs, etc.
+	Model     string    `json:"model,omitempty"`     // model that produced the result
+	Lessons   []string  `json:"lessons,omitempty"`   // what the agent learned, for similar tasks
+	Evaluation *Evaluation `json:"evaluation,omitempty"` // quality score given after completion
 	Duration  int64     `json:"duration_ms"`
 	Timestamp time.Time `json:"timestamp"`
 }
@@ -130,6 +159,42 @@ type Registry struct {
 	tasks  map[string]*Task
 	mu     sync.RWMutex
 	ctx    context.Context
+	logger *log.Logger
+	// instance tells this registry's assignments from those of others
+	// sharing its store
+	instance string
+
+	// draining is set during shutdown; no new work is assigned
+	draining bool
+	// retries tells the failed runs that will be retried, if set
+	retries RetryDecider
+	
+	// agentList and taskList are the copy-on-write snapshots served to
+	// readers; nil until the first read after a change
+	agentList atomic.Pointer[[]*Agent]
+	taskList  atomic.Pointer[[]*Task]
+	
+	events eventHub
+	
+	// workspaces partition agents and tasks; DefaultWorkspace always
+	// exists
+	workspaces map[string]*Workspace
+	
+	// history records every task transition
+	history history
+	
+	// routing holds the decision that assigned each task, by task ID
+	routing map[string]*RoutingDecision
+	
+	// store persists agents, tasks and workspaces when set; dirty marks
+	// what the current operation changed
+	store Store
+	dirty dirty
+	
+	// tx is the transaction of the current operation on a shared store,
+	// begun by its first edit; txErr is why it could not be begun or read
+	tx    StoreTx
+	txErr error
 }
 
 // NewRegistry creates a new agent registry
@@ -137,52 +202,80 @@ func NewRegistry(ctx context.Context) *Registry {
 	return &Registry{
 		agents: make(map[string]*Agent),
 		tasks:  make(map[string]*Task),
-		ctx:    ctx,
+		workspaces: map[string]*Workspace{
+			DefaultWorkspace: {Name: DefaultWorkspace, Description: "Agents and tasks created without a workspace", CreatedAt: time.Now()},
+		},
+		ctx:      ctx,
+		logger:   logging.New("registry", "[REGISTRY] ", log.Writer()),
+		instance: uuid.New().String(),
 	}
 }
 
+// Instance returns the ID this registry stamps on the tasks it assigns
+func (r *Registry) Instance() string {
+	return r.instance
+}
+
+// SetInstance replaces the ID stamped on the tasks the registry assigns,
+// so that a restarted process owns the runs it had claimed; call it before
+// the registry assigns any
+func (r *Registry) SetInstance(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instance = id
+}
+
+// Owns reports whether this registry assigned task, so that its runs are
+// this process's to make rather than another's sharing the store
+func (r *Registry) Owns(task *Task) bool {
+	return task.Owner == r.instance
+}
+
 // RegisterAgent adds a new agent to the registry
 func (r *Registry) RegisterAgent(agent *Agent) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-	
+	defer r.unlock()
+	r.addAgent(agent)
+}
+
+// addAgent inserts a copy of an agent after filling in its ID, timestamps
+// and status; the caller holds r.mu
+func (r *Registry) addAgent(agent *Agent) {
 	if agent.ID == "" {
 		agent.ID = uuid.New().String()
 	}
 	agent.CreatedAt = time.Now()
 	agent.UpdatedAt = time.Now()
 	agent.Status = StatusIdle
-	
-	r.agents[agent.ID] = agent
+	agent.Workspace = workspaceOf(agent.Workspace)
+	agent.Version = 1
+	
+	r.agents[agent.ID] = agent.Clone()
+	r.markAgent(agent.ID)
+	r.changed()
+	r.logger.Printf("Registered agent %s (%s, type %s)", agent.ID, agent.Name, agent.Type)
+	r.emitAgent(EventAgentCreated, agent)
 }
 
-// GetAgent returns an agent by ID
+// GetAgent returns a copy of an agent by ID
 func (r *Registry) GetAgent(id string) (*Agent, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	agent, ok := r.agents[id]
-	return agent, ok
+	return agent.Clone(), ok
 }
 
-// ListAgents returns all registered agents
+// ListAgents returns all registered agents. The agents are a snapshot
+// shared with other readers and must not be modified.
 func (r *Registry) ListAgents() []*Agent {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
-	agents := make([]*Agent, 0, len(r.agents))
-	for _, a := range r.agents {
-		agents = append(agents, a)
-	}
-	return agents
+	return append([]*Agent(nil), r.agentsView()...)
 }
 
-// GetAgentsByType returns agents of a specific type
+// GetAgentsByType returns agents of a specific type, from the same
+// snapshot as ListAgents
 func (r *Registry) GetAgentsByType(agentType AgentType) []*Agent {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
 	var agents []*Agent
-	for _, a := range r.agents {
+	for _, a := range r.agentsView() {
 		if a.Type == agentType {
 			agents = append(agents, a)
 		}
@@ -190,13 +283,11 @@ func (r *Registry) GetAgentsByType(agentType AgentType) []*Agent {
 	return agents
 }
 
-// GetIdleAgents returns all idle agents
+// GetIdleAgents returns all idle agents, from the same snapshot as
+// ListAgents
 func (r *Registry) GetIdleAgents() []*Agent {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
 	var agents []*Agent
-	for _, a := range r.agents {
+	for _, a := range r.agentsView() {
 		if a.Status == StatusIdle {
 			agents = append(agents, a)
 		}
@@ -204,60 +295,89 @@ func (r *Registry) GetIdleAgents() []*Agent {
 	return agents
 }
 
-// CreateTask creates a new task
+// CreateTask stores a copy of task, filling in the ID, timestamps and
+// status of task itself, and returns task. The history credits the task's
+// source with its creation.
 func (r *Registry) CreateTask(task *Task) *Task {
+	return r.CreateTaskBy(task, Cause{Actor: task.Source})
+}
+
+// CreateTaskBy is CreateTask recording who created the task
+func (r *Registry) CreateTaskBy(task *Task, c Cause) *Task {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-	
+	defer r.unlock()
+	r.addTask(task, c)
+	return task
+}
+
+// addTask inserts a copy of a pending task after filling in its ID,
+// timestamps and status; the caller holds r.mu
+func (r *Registry) addTask(task *Task, c Cause) {
 	if task.ID == "" {
 		task.ID = uuid.New().String()
 	}
 	task.CreatedAt = time.Now()
 	task.UpdatedAt = time.Now()
 	task.Status = TaskStatusPending
-	
-	r.tasks[task.ID] = task
-	return task
+	task.Workspace = workspaceOf(task.Workspace)
+	
+	r.tasks[task.ID] = task.Clone()
+	r.markTask(task.ID)
+	r.changed()
+	tasksCreated.Inc()
+	r.recordTransition(EventTaskCreated, task, "", c)
+	r.emitTask(EventTaskCreated, task)
 }
 
-// GetTask returns a task by ID
+// GetTask returns a copy of a task by ID
 func (r *Registry) GetTask(id string) (*Task, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	task, ok := r.tasks[id]
-	return task, ok
+	return task.Clone(), ok
 }
 
-// ListTasks returns all tasks
+// ListTasks returns all tasks. The tasks are a snapshot shared with other
+// readers and must not be modified.
 func (r *Registry) ListTasks() []*Task {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
-	tasks := make([]*Task, 0, len(r.tasks))
-	for _, t := range r.tasks {
-		tasks = append(tasks, t)
-	}
-	return tasks
+	return append([]*Task(nil), r.tasksView()...)
 }
 
-// GetPendingTasks returns all pending tasks
+// GetPendingTasks returns all pending tasks, from the same snapshot as
+// ListTasks, in queue order
 func (r *Registry) GetPendingTasks() []*Task {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
 	var tasks []*Task
-	for _, t := range r.tasks {
+	for _, t := range r.tasksView() {
 		if t.Status == TaskStatusPending || t.Status == TaskStatusQueued {
 			tasks = append(tasks, t)
 		}
 	}
+	sortQueue(tasks)
+	return tasks
+}
+
+// TasksByExternalID returns the tasks of a source carrying an external ID,
+// oldest first, from the same snapshot as ListTasks
+func (r *Registry) TasksByExternalID(source, externalID string) []*Task {
+	var tasks []*Task
+	for _, t := range r.tasksView() {
+		if t.Source == source && t.ExternalID == externalID {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].CreatedAt.Before(tasks[j].CreatedAt) })
 	return tasks
 }
 
 // AssignTask assigns a task to an agent
 func (r *Registry) AssignTask(taskID, agentID string) error {
+	return r.AssignTaskBy(taskID, agentID, systemCause)
+}
+
+// AssignTaskBy is AssignTask recording who chose the agent and why
+func (r *Registry) AssignTaskBy(taskID, agentID string, c Cause) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	defer r.unlock()
 	
 	task, ok := r.tasks[taskID]
 	if !ok {
@@ -269,110 +389,360 @@ func (r *Registry) AssignTask(taskID, agentID string) error {
 		return ErrAgentNotFound
 	}
 	
-	if agent.Status != StatusIdle {
-		return ErrAgentBusy
+	if workspaceOf(agent.Workspace) != workspaceOf(task.Workspace) {
+		return ErrWorkspaceMismatch
+	}
+	
+	// The task starts in a free slot of the agent, or waits in its queue
+	status := TaskStatusInProgress
+	if !agent.Accepts(status) {
+		status = TaskStatusQueued
+		if !agent.Accepts(status) {
+			return ErrAgentBusy
+		}
+	}
+	
+	if r.draining {
+		return ErrDraining
 	}
 	
+	task, agent, err := r.claim(task, agentID, status)
+	if err != nil {
+		return err
+	}
+	r.assign(task, agent, status, c)
+	return nil
+}
+
+// assign gives a task to an agent, in progress or queued as status says;
+// the caller holds r.mu and has checked that the agent accepts it
+func (r *Registry) assign(task *Task, agent *Agent, status TaskStatus, c Cause) {
+	taskID, agentID := task.ID, agent.ID
+	from, previous := task.Status, task.AssignedTo
+	task = r.editTask(task)
 	task.AssignedTo = agentID
-	task.Status = TaskStatusInProgress
+	task.Owner = r.instance
+	task.Status = status
+	task.RetryAt = nil
 	now := time.Now()
-	task.StartedAt = &now
+	if status == TaskStatusInProgress {
+		task.StartedAt = &now
+	}
 	task.UpdatedAt = now
+	r.recordTransition(EventTaskAssigned, task, from, c)
+	r.recordRouting(task, agentID, nil, c)
 	
-	agent.Status = StatusWorking
-	agent.CurrentTask = task
+	agent = r.editAgent(agent)
+	agent.Hold(task)
 	agent.UpdatedAt = now
 	
+	if status == TaskStatusQueued {
+		r.logger.Printf("Queued task %s for agent %s", taskID, agentID)
+	} else {
+		r.logger.Printf("Assigned task %s to agent %s", taskID, agentID)
+	}
+	r.emitTask(EventTaskAssigned, task)
+	if previous != "" && previous != agentID {
+		r.release(previous, taskID)
+	}
+}
+
+// AddTaskArtifact records a stored artifact on its task
+func (r *Registry) AddTaskArtifact(taskID string, a artifacts.Artifact) error {
+	r.mu.Lock()
+	defer r.unlock()
+	
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	task = r.editTask(task)
+	task.Artifacts = append(task.Artifacts, a)
+	task.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetTaskMeta merges meta into a task's metadata; an empty value removes
+// the key
+func (r *Registry) SetTaskMeta(taskID string, meta map[string]string) error {
+	r.mu.Lock()
+	defer r.unlock()
+	
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	task = r.editTask(task)
+	if task.Meta == nil {
+		task.Meta = make(map[string]string, len(meta))
+	}
+	for k, v := range meta {
+		if v == "" {
+			delete(task.Meta, k)
+		} else {
+			task.Meta[k] = v
+		}
+	}
+	task.UpdatedAt = time.Now()
+	r.emitTask(EventTaskUpdated, task)
 	return nil
 }
 
 // CompleteTask marks a task as completed
 func (r *Registry) CompleteTask(taskID string, result *TaskResult) error {
+	return r.CompleteTaskBy(taskID, result, systemCause)
+}
+
+// CompleteTaskBy is CompleteTask recording who reported the result. A
+// failed result's error becomes the reason when c gives none.
+func (r *Registry) CompleteTaskBy(taskID string, result *TaskResult, c Cause) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	defer r.unlock()
 	
 	task, ok := r.tasks[taskID]
 	if !ok {
 		return ErrTaskNotFound
 	}
-	
+	r.complete(task, result, c)
+	return nil
+}
+
+// complete records the result of a task and frees its agent; the caller
+// holds r.mu
+func (r *Registry) complete(task *Task, result *TaskResult, c Cause) {
+	taskID := task.ID
 	now := time.Now()
+	from := task.Status
+	task = r.editTask(task)
 	task.Status = TaskStatusCompleted
 	task.CompletedAt = &now
 	task.UpdatedAt = now
 	task.Result = result
+	recordFinished(result)
+	event := EventTaskCompleted
+	if result != nil && !result.Success {
+		event = EventTaskFailed
+		if c.Reason == "" {
+			c.Reason = result.Error
+		}
+	}
+	r.recordTransition(event, task, from, c)
 	
 	// Update agent stats
 	if task.AssignedTo != "" {
 		if agent, ok := r.agents[task.AssignedTo]; ok {
-			agent.Status = StatusIdle
-			agent.CurrentTask = nil
-			agent.Stats.TasksCompleted++
+			agent = r.editAgent(agent)
+			if event == EventTaskFailed {
+				agent.Stats.TasksFailed++
+			} else {
+				agent.Stats.TasksCompleted++
+			}
 			agent.Stats.LastActive = now
 			if result != nil {
 				agent.Stats.TotalTime += result.Duration
-				agent.Stats.AvgTime = agent.Stats.TotalTime / int64(agent.Stats.TasksCompleted)
-				if agent.Stats.TasksCompleted > 0 {
-					agent.Stats.SuccessRate = float64(agent.Stats.TasksCompleted) / 
-						float64(agent.Stats.TasksCompleted+agent.Stats.TasksFailed)
-				}
 			}
+			// Failed runs take time too, so both kinds are averaged
+			finished := agent.Stats.TasksCompleted + agent.Stats.TasksFailed
+			agent.Stats.AvgTime = agent.Stats.TotalTime / int64(finished)
+			agent.Stats.SuccessRate = float64(agent.Stats.TasksCompleted) / float64(finished)
 			agent.UpdatedAt = now
 		}
 	}
 	
+	r.logger.Printf("Completed task %s", taskID)
+	if event == EventTaskFailed {
+		r.emit(Event{
+			Type:      EventTaskFailed,
+			AgentID:   task.AssignedTo,
+			TaskID:    taskID,
+			Workspace: workspaceOf(task.Workspace),
+			Data:      map[string]interface{}{"task": *task.Clone(), "final": !r.retried(task)},
+		})
+		if agent, ok := r.agents[task.AssignedTo]; ok {
+			r.emit(Event{
+				Type:    EventAgentError,
+				AgentID: agent.ID,
+				TaskID:  taskID,
+				Data:    map[string]interface{}{"agent": agentSnapshot(agent), "error": result.Error},
+			})
+		}
+	} else {
+		r.emitTask(EventTaskCompleted, task)
+	}
+	r.release(task.AssignedTo, taskID)
+}
+
+// CancelTask stops a task that has not finished, freeing the agent
+// working on it
+func (r *Registry) CancelTask(taskID string, c Cause) error {
+	r.mu.Lock()
+	defer r.unlock()
+	
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	switch task.Status {
+	case TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled:
+		return ErrTaskFinished
+	}
+	
+	now := time.Now()
+	from := task.Status
+	task = r.editTask(task)
+	task.Status = TaskStatusCancelled
+	task.CompletedAt = &now
+	task.UpdatedAt = now
+	r.recordTransition(EventTaskCancelled, task, from, c)
+	
+	r.logger.Printf("Cancelled task %s", taskID)
+	r.emitTask(EventTaskCancelled, task)
+	r.release(task.AssignedTo, taskID)
 	return nil
 }
 
-// AutoAssign finds and assigns idle agents to pending tasks
+// AutoAssign gives pending tasks, in queue order, to the free slots of
+// agents, where they start at once. Agents' queues are left to explicit
+// assignments, so that pending tasks go to the agents free first.
 func (r *Registry) AutoAssign(ctx context.Context) (assigned int) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	defer r.unlock()
+	
+	if r.draining {
+		return 0
+	}
 	
+	now := time.Now()
+	var pending []*Task
 	for _, task := range r.tasks {
-		if task.Status != TaskStatusPending {
+		if task.Status == TaskStatusPending && task.due(now) {
+			pending = append(pending, task)
+		}
+	}
+	sortQueue(pending)
+	
+	free := 0
+	for _, agent := range r.agents {
+		if agent.Config.AutoAssign && agent.Accepts(TaskStatusInProgress) {
+			free += agent.Slots() - len(agent.Running)
+		}
+	}
+	
+	// Each task goes to the best scoring eligible agent; see rankAgents
+	for _, task := range pending {
+		if free == 0 {
+			break
+		}
+		candidates := r.rankAgents(task)
+		if len(candidates) == 0 || !candidates[0].Eligible {
 			continue
 		}
-		
-		// Find matching idle agent
-		for _, agent := range r.agents {
-			if agent.Status != StatusIdle || !agent.Config.AutoAssign {
-				continue
-			}
-			
-			// Check if agent handles this type of task
-			if matchesLabels(agent.Labels, task.Labels) {
-				now := time.Now()
-				task.AssignedTo = agent.ID
-				task.Status = TaskStatusQueued
-				task.UpdatedAt = now
-				
-				agent.Status = StatusWorking
-				agent.CurrentTask = task
-				agent.UpdatedAt = now
-				assigned++
-				break
-			}
+		c := Cause{Actor: "auto-assign", Reason: autoAssignReason(candidates[0])}
+		agentID := candidates[0].AgentID
+		claimed, agent, err := r.claim(task, agentID, TaskStatusInProgress)
+		if err != nil {
+			// Another instance sharing the store took the task or the
+			// agent; the next round works from what it synced
+			r.logger.Printf("WARN: Claiming task %s for agent %s: %v", task.ID, agentID, err)
+			break
 		}
+		task = claimed
+		
+		now := time.Now()
+		task = r.editTask(task)
+		task.AssignedTo = agent.ID
+		task.Owner = r.instance
+		task.Status = TaskStatusInProgress
+		task.StartedAt = &now
+		task.RetryAt = nil
+		task.UpdatedAt = now
+		r.recordTransition(EventTaskAssigned, task, TaskStatusPending, c)
+		r.recordRouting(task, agent.ID, candidates, c)
+		
+		agent = r.editAgent(agent)
+		agent.Hold(task)
+		agent.UpdatedAt = now
+		free--
+		assigned++
+		r.logger.Printf("Auto-assigned task %s to agent %s", task.ID, agent.ID)
+		r.emitTask(EventTaskAssigned, task)
 	}
 	
 	return assigned
 }
 
-// matchesLabels checks if agent can handle task based on labels
-func matchesLabels(agentLabels, taskLabels []string) bool {
-	if len(agentLabels) == 0 {
-		return true // Agent handles any task
+// BeginDrain stops the registry from assigning new tasks so that in-flight
+// work can finish before shutdown
+func (r *Registry) BeginDrain() {
+	r.mu.Lock()
+	defer r.unlock()
+	
+	if !r.draining {
+		r.draining = true
+		r.logger.Printf("Draining: no new tasks will be assigned")
 	}
+}
+
+// Draining reports whether BeginDrain has been called
+func (r *Registry) Draining() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.draining
+}
+
+// InFlightTasks returns the number of tasks queued on or running in agents
+func (r *Registry) InFlightTasks() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	
-	for _, al := range agentLabels {
-		for _, tl := range taskLabels {
-			if al == tl {
-				return true
-			}
+	var n int
+	for _, t := range r.tasks {
+		if t.Status == TaskStatusInProgress || t.Status == TaskStatusQueued {
+			n++
+		}
+	}
+	return n
+}
+
+// Drain begins draining and waits until no tasks are in flight or ctx is
+// done
+func (r *Registry) Drain(ctx context.Context) error {
+	r.BeginDrain()
+	
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	
+	for {
+		n := r.InFlightTasks()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d tasks still in flight: %w", n, ctx.Err())
+		case <-ticker.C:
 		}
 	}
-	return false
+}
+
+// sortQueue orders tasks as they are taken from the queue: by priority,
+// then oldest first
+func sortQueue(tasks []*Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Priority != tasks[j].Priority {
+			return tasks[i].Priority > tasks[j].Priority
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+}
+
+// autoAssignReason explains why AutoAssign gave a task to the best
+// candidate
+func autoAssignReason(best RoutingCandidate) string {
+	if len(best.Rules) == 0 {
+		return fmt.Sprintf("idle agent %s scored %d", best.Name, best.Score)
+	}
+	return fmt.Sprintf("idle agent %s scored %d (%s)", best.Name, best.Score, strings.Join(best.Rules, ", "))
 }
 
 // DefaultAgents creates the default set of agents
@@ -431,9 +801,15 @@ func DefaultAgents() []*Agent {
 
 // Errors
 var (
-	ErrAgentNotFound = &AgentError{message: "agent not found"}
-	ErrTaskNotFound  = &AgentError{message: "task not found"}
-	ErrAgentBusy     = &AgentError{message: "agent is busy"}
+	ErrAgentNotFound   = &AgentError{message: "agent not found"}
+	ErrTaskNotFound    = &AgentError{message: "task not found"}
+	ErrAgentBusy       = &AgentError{message: "agent is busy"}
+	ErrDraining        = &AgentError{message: "registry is draining for shutdown"}
+	ErrAgentExists     = &AgentError{message: "agent already exists"}
+	ErrTaskExists      = &AgentError{message: "task already exists"}
+	ErrTaskActive      = &AgentError{message: "task is in progress"}
+	ErrTaskFinished    = &AgentError{message: "task has already finished"}
+	ErrVersionConflict = &AgentError{message: "agent was changed since the given version"}
 )
 
 type AgentError struct {
@@ -444,15 +820,14 @@ func (e *AgentError) Error() string {
 	return e.message
 }
 
-// GetStats returns statistics about the registry
+// GetStats returns statistics about the registry, computed from the list
+// snapshots
 func (r *Registry) GetStats() map[string]interface{} {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
+	agents, tasks := r.agentsView(), r.tasksView()
 	var totalTasks, completedTasks, failedTasks int
 	var activeAgents, idleAgents int
 	
-	for _, agent := range r.agents {
+	for _, agent := range agents {
 		switch agent.Status {
 		case StatusIdle:
 			idleAgents++
@@ -461,7 +836,7 @@ func (r *Registry) GetStats() map[string]interface{} {
 		}
 	}
 	
-	for _, task := range r.tasks {
+	for _, task := range tasks {
 		totalTasks++
 		switch task.Status {
 		case TaskStatusCompleted:
@@ -472,7 +847,7 @@ func (r *Registry) GetStats() map[string]interface{} {
 	}
 	
 	return map[string]interface{}{
-		"total_agents":   len(r.agents),
+		"total_agents":   len(agents),
 		"active_agents":  activeAgents,
 		"idle_agents":    idleAgents,
 		"total_tasks":    totalTasks,
@@ -481,38 +856,78 @@ func (r *Registry) GetStats() map[string]interface{} {
 	}
 }
 
+// StatusCounts returns the number of agents and tasks in each status
+func (r *Registry) StatusCounts() (map[AgentStatus]int, map[TaskStatus]int) {
+	agentCounts := make(map[AgentStatus]int)
+	for _, agent := range r.agentsView() {
+		agentCounts[agent.Status]++
+	}
+	taskCounts := make(map[TaskStatus]int)
+	for _, task := range r.tasksView() {
+		taskCounts[task.Status]++
+	}
+	return agentCounts, taskCounts
+}
+
 // StartAgent starts a specific agent
 func (r *Registry) StartAgent(agentID string) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	defer r.unlock()
 	
 	agent, ok := r.agents[agentID]
 	if !ok {
 		return ErrAgentNotFound
 	}
 	
+	agent = r.editAgent(agent)
 	agent.Status = StatusIdle
+	agent.settle()
 	agent.UpdatedAt = time.Now()
+	r.logger.Printf("Started agent %s", agentID)
+	r.emitAgent(EventAgentStarted, agent)
+	r.startQueued(agent)
 	return nil
 }
 
 // StopAgent stops a specific agent
 func (r *Registry) StopAgent(agentID string) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	defer r.unlock()
 	
 	agent, ok := r.agents[agentID]
 	if !ok {
 		return ErrAgentNotFound
 	}
 	
+	agent = r.editAgent(agent)
 	agent.Status = StatusOffline
 	agent.UpdatedAt = time.Now()
+	r.logger.Printf("Stopped agent %s", agentID)
+	r.emitAgent(EventAgentStopped, agent)
 	return nil
 }
 
-// CreateAgent creates a new agent with given parameters
+// CreateAgent creates a new agent with given parameters and returns a
+// copy of it
 func (r *Registry) CreateAgent(name, agentType string, config map[string]interface{}) (*Agent, error) {
+	return r.CreateAgentIn(DefaultWorkspace, name, agentType, config)
+}
+
+// CreateAgentIn is CreateAgent for an agent of workspace, which must exist
+func (r *Registry) CreateAgentIn(workspace, name, agentType string, config map[string]interface{}) (*Agent, error) {
+	agent := newAgent(name, agentType, config)
+	agent.Workspace = workspace
+	r.mu.Lock()
+	defer r.unlock()
+	if !r.hasWorkspace(workspace) {
+		return nil, ErrWorkspaceNotFound
+	}
+	r.addAgent(agent)
+	return agent, nil
+}
+
+// newAgent builds an agent with default settings and config overrides
+func newAgent(name, agentType string, config map[string]interface{}) *Agent {
 	agent := &Agent{
 		Name:        name,
 		Type:        AgentType(agentType),
@@ -527,24 +942,48 @@ func (r *Registry) CreateAgent(name, agentType string, config map[string]interfa
 		},
 	}
 	
-	// Apply config overrides
+	applyAgentConfig(agent, config)
+	return agent
+}
+
+// applyAgentConfig applies the config overrides understood by the API
+func applyAgentConfig(agent *Agent, config map[string]interface{}) {
 	if cfg, ok := config["auto_assign"].(bool); ok {
 		agent.Config.AutoAssign = cfg
 	}
-	
-	r.RegisterAgent(agent)
-	return agent, nil
+	if n, ok := config["max_concurrent"].(float64); ok && n >= 1 && n <= maxAgentConcurrency {
+		agent.Config.MaxConcurrent = int(n)
+	}
+	if n, ok := config["queue_size"].(float64); ok && n >= 1 && n <= maxAgentQueue {
+		agent.Config.QueueSize = int(n)
+	}
 }
 
 // DeleteAgent removes an agent from the registry
 func (r *Registry) DeleteAgent(agentID string) error {
+	return r.DeleteAgentIf(agentID, 0)
+}
+
+// DeleteAgentIf removes an agent if it is still at version, returning
+// ErrVersionConflict otherwise; a version of 0 removes it whatever its
+// version
+func (r *Registry) DeleteAgentIf(agentID string, version int64) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	defer r.unlock()
 	
-	if _, ok := r.agents[agentID]; !ok {
+	agent, ok := r.agents[agentID]
+	if !ok {
 		return ErrAgentNotFound
 	}
+	if version != 0 && agent.Version != version {
+		return ErrVersionConflict
+	}
 	
 	delete(r.agents, agentID)
+	r.markAgent(agentID)
+	r.changed()
+	r.logger.Printf("Deleted agent %s", agentID)
+	r.emitAgent(EventAgentDeleted, agent)
+	r.unqueue(agent)
 	return nil
 }
@@ -0,0 +1,55 @@
+package agents
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAutoAssignByPriority(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(ctx)
+	r.RegisterAgent(&Agent{Name: "worker", Status: StatusIdle, Config: AgentConfig{AutoAssign: true}})
+
+	r.CreateTask(&Task{Title: "old low", Priority: PriorityLow})
+	r.CreateTask(&Task{Title: "medium", Priority: PriorityMedium})
+	urgent := r.CreateTask(&Task{Title: "urgent", Priority: PriorityUrgent})
+
+	pending := r.GetPendingTasks()
+	if len(pending) != 3 || pending[0].ID != urgent.ID || pending[2].Title != "old low" {
+		t.Fatalf("queue order: %v, %v, %v", pending[0].Title, pending[1].Title, pending[2].Title)
+	}
+	if n := r.AutoAssign(ctx); n != 1 {
+		t.Fatalf("assigned %d tasks", n)
+	}
+	if task, _ := r.GetTask(urgent.ID); task.Status != TaskStatusInProgress {
+		t.Fatalf("the urgent task is %s, not assigned first", task.Status)
+	}
+}
+
+func TestCompleteCountsFailures(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(ctx)
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+	results := []*TaskResult{
+		{Success: true, Duration: 3000},
+		{Error: "compile error", Duration: 1000},
+		{Success: true, Duration: 2000},
+		{Error: "tests failed", Duration: 2000},
+	}
+	for _, result := range results {
+		task := r.CreateTask(&Task{Title: "Build"})
+		if err := r.AssignTask(task.ID, agent.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CompleteTask(task.ID, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, _ := r.GetAgent(agent.ID)
+	want := AgentStats{TasksCompleted: 2, TasksFailed: 2, TotalTime: 8000, AvgTime: 2000, SuccessRate: 0.5}
+	got.Stats.LastActive = time.Time{}
+	if got.Stats != want {
+		t.Fatalf("stats = %+v, want %+v", got.Stats, want)
+	}
+}
@@ -0,0 +1,104 @@
+package agents
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErrTaskNotFailed is returned when retrying a task whose last run did not
+// fail
+var ErrTaskNotFailed = &AgentError{message: "task has not failed"}
+
+// Attempt is a failed run of a task that was retried
+type Attempt struct {
+	// Number counts the runs of the task, starting at 1
+	Number  int    `json:"number"`
+	AgentID string `json:"agent_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Class is the kind of failure, as the retry policy classified it
+	Class      string     `json:"class,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// RetryAt is when the next run could start
+	RetryAt time.Time `json:"retry_at"`
+}
+
+// RetryDecider tells whether a task whose run an agent of agentType just
+// failed will be retried. It is called with the registry locked, so it must
+// not call the registry.
+type RetryDecider func(task *Task, agentType AgentType) bool
+
+// SetRetryDecider sets what tells the failures that will be retried, whose
+// task.failed events are not final, from those that end their task.
+// Without one, every failure is final.
+func (r *Registry) SetRetryDecider(d RetryDecider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries = d
+}
+
+// retried reports whether the failed task will be retried; the caller
+// holds r.mu
+func (r *Registry) retried(task *Task) bool {
+	if r.retries == nil || r.draining {
+		return false
+	}
+	var agentType AgentType
+	if agent, ok := r.agents[task.AssignedTo]; ok {
+		agentType = agent.Type
+	}
+	return r.retries(task, agentType)
+}
+
+// RetryTask sends a task whose run failed back to pending for another
+// attempt, which auto-assignment starts once at has passed. The failed run
+// joins the task's attempts with the class of its error, and its result is
+// cleared.
+func (r *Registry) RetryTask(taskID string, at time.Time, class string, c Cause) error {
+	r.mu.Lock()
+	defer r.unlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if !task.Status.Finished() || task.Status == TaskStatusCancelled || task.Result == nil || task.Result.Success {
+		return ErrTaskNotFailed
+	}
+	if r.draining {
+		return ErrDraining
+	}
+
+	from := task.Status
+	task = r.editTask(task)
+	attempt := Attempt{
+		Number:     len(task.Attempts) + 1,
+		AgentID:    task.AssignedTo,
+		Error:      task.Result.Error,
+		Class:      class,
+		StartedAt:  task.StartedAt,
+		FinishedAt: task.CompletedAt,
+		RetryAt:    at,
+	}
+	task.Attempts = append(task.Attempts, attempt)
+	task.Status = TaskStatusPending
+	task.AssignedTo = ""
+	task.Result = nil
+	task.StartedAt = nil
+	task.CompletedAt = nil
+	task.RetryAt = &at
+	task.UpdatedAt = time.Now()
+	if c.Reason == "" {
+		c.Reason = fmt.Sprintf("attempt %d failed (%s): %s", attempt.Number, class, attempt.Error)
+	}
+	r.recordTransition(EventTaskRetried, task, from, c)
+	r.logger.Printf("Retrying task %s after attempt %d at %s", taskID, attempt.Number, at.Format(time.RFC3339))
+	r.emitTask(EventTaskRetried, task)
+	return nil
+}
+
+// due reports whether a pending task may be assigned, its retry time, if
+// any, having passed
+func (t *Task) due(now time.Time) bool {
+	return t.RetryAt == nil || !t.RetryAt.After(now)
+}
@@ -0,0 +1,45 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryTaskWaitsForItsTime(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(ctx)
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+	task := r.CreateTask(&Task{Title: "Fetch the feed"})
+	r.AutoAssign(ctx)
+
+	if err := r.RetryTask(task.ID, time.Now(), "transient", systemCause); !errors.Is(err, ErrTaskNotFailed) {
+		t.Fatalf("retrying a running task: err = %v", err)
+	}
+	r.CompleteTask(task.ID, &TaskResult{Error: "connection reset"})
+	at := time.Now().Add(time.Hour)
+	if err := r.RetryTask(task.ID, at, "transient", systemCause); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := r.GetTask(task.ID)
+	if got.Status != TaskStatusPending || got.AssignedTo != "" || got.Result != nil ||
+		len(got.Attempts) != 1 || got.Attempts[0].AgentID != agent.ID || got.Attempts[0].Error != "connection reset" {
+		t.Fatalf("retried task = %+v", got)
+	}
+	if n := r.AutoAssign(ctx); n != 0 {
+		t.Fatalf("assigned %d tasks before their retry time", n)
+	}
+
+	// Once due, the task is assigned and its retry time cleared
+	r.mu.Lock()
+	past := time.Now().Add(-time.Second)
+	r.editTask(r.tasks[task.ID]).RetryAt = &past
+	r.mu.Unlock()
+	if n := r.AutoAssign(ctx); n != 1 {
+		t.Fatalf("assigned %d tasks, want the due one", n)
+	}
+	if got, _ := r.GetTask(task.ID); got.Status != TaskStatusInProgress || got.RetryAt != nil || len(got.Attempts) != 1 {
+		t.Fatalf("reassigned task = %+v", got)
+	}
+}
@@ -0,0 +1,209 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Routing weights: an agent scores matchWeight for each label it shares
+// with the task and preferredWeight for each task label in its preferred
+// tasks, and loses a point for every loadStep of reported load. Agents
+// without labels accept any task but score nothing for labels, so
+// specialists win over generalists. A task that names agent types only
+// goes to agents of those types.
+const (
+	matchWeight     = 10
+	preferredWeight = 5
+	loadStep        = 10
+)
+
+// RoutingCandidate is how one agent of the task's workspace fared when the
+// task was routed
+type RoutingCandidate struct {
+	AgentID string      `json:"agent_id"`
+	Name    string      `json:"name"`
+	Status  AgentStatus `json:"status"`
+	Load    int         `json:"load"`
+	// Eligible is set when the agent could take the task
+	Eligible bool `json:"eligible"`
+	// Rejected says why an agent that is not eligible could not take it
+	Rejected string `json:"rejected,omitempty"`
+	// Rules lists what the agent matched: "label:<name>",
+	// "preferred:<name>", "type:<type>" for a task that names agent types,
+	// or "any-task" for an agent without labels
+	Rules []string `json:"rules,omitempty"`
+	// Factors break Score down into "labels", "preferred" and "load"
+	Factors map[string]int `json:"factors,omitempty"`
+	Score   int            `json:"score"`
+	Chosen  bool           `json:"chosen,omitempty"`
+}
+
+// RoutingDecision explains why a task was, or would be, assigned to an
+// agent
+type RoutingDecision struct {
+	TaskID string    `json:"task_id"`
+	Time   time.Time `json:"time"`
+	// AgentID is the agent the task went to; empty in a preview that found
+	// no eligible agent
+	AgentID string `json:"agent_id,omitempty"`
+	// Preview is set for a task not assigned yet: the decision is what
+	// auto-assign would do now
+	Preview bool `json:"preview,omitempty"`
+	// Blocked says why auto-assign would not run at all
+	Blocked string `json:"blocked,omitempty"`
+	// Candidates are the agents considered, eligible ones first by score.
+	// A task assigned before the daemon started has none.
+	Candidates []RoutingCandidate `json:"candidates"`
+	Cause
+}
+
+// Routing explains how a task was assigned, or how auto-assign would
+// assign it now if it is pending. Decisions are kept in memory for the
+// tasks that still exist; a recorded decision is shared with other readers
+// and must not be modified.
+func (r *Registry) Routing(taskID string) (*RoutingDecision, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return nil, false
+	}
+	if task.Status == TaskStatusPending {
+		d := &RoutingDecision{
+			TaskID:     taskID,
+			Time:       time.Now(),
+			Preview:    true,
+			Candidates: r.rankAgents(task),
+			Cause:      Cause{Actor: "auto-assign"},
+		}
+		if r.draining {
+			d.Blocked = ErrDraining.Error()
+		} else if len(d.Candidates) > 0 && d.Candidates[0].Eligible {
+			d.Candidates[0].Chosen = true
+			d.AgentID = d.Candidates[0].AgentID
+		}
+		return d, true
+	}
+	if d, ok := r.routing[taskID]; ok && d.AgentID == task.AssignedTo {
+		return d, true
+	}
+
+	// Assigned before the decisions were recorded: the history still says
+	// who chose the agent
+	d := &RoutingDecision{TaskID: taskID, AgentID: task.AssignedTo, Candidates: []RoutingCandidate{}}
+	for _, i := range r.history.byTask[taskID] {
+		if t := r.history.log[i]; t.Event == EventTaskAssigned {
+			d.Time, d.Cause = t.Time, t.Cause
+		}
+	}
+	return d, true
+}
+
+// recordRouting keeps the decision that assigned task to agentID, ranking
+// the candidates as they were; the caller holds r.mu
+func (r *Registry) recordRouting(task *Task, agentID string, candidates []RoutingCandidate, c Cause) {
+	if candidates == nil {
+		candidates = r.rankAgents(task)
+	}
+	for i := range candidates {
+		candidates[i].Chosen = candidates[i].AgentID == agentID
+	}
+	if r.routing == nil {
+		r.routing = make(map[string]*RoutingDecision)
+	}
+	r.routing[task.ID] = &RoutingDecision{
+		TaskID:     task.ID,
+		Time:       time.Now(),
+		AgentID:    agentID,
+		Candidates: candidates,
+		Cause:      c,
+	}
+}
+
+// rankAgents scores the agents of the task's workspace, eligible ones
+// first from the best; the caller holds r.mu
+func (r *Registry) rankAgents(task *Task) []RoutingCandidate {
+	ws := workspaceOf(task.Workspace)
+	candidates := make([]RoutingCandidate, 0, len(r.agents))
+	for _, agent := range r.agents {
+		if workspaceOf(agent.Workspace) == ws {
+			candidates = append(candidates, evaluate(agent, task))
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.AgentID < b.AgentID
+	})
+	return candidates
+}
+
+// evaluate scores an agent of the task's workspace for the task
+func evaluate(agent *Agent, task *Task) RoutingCandidate {
+	c := RoutingCandidate{
+		AgentID: agent.ID,
+		Name:    agent.Name,
+		Status:  agent.Status,
+		Load:    agent.Load,
+	}
+
+	var matched, preferred int
+	if contains(task.AgentTypes, string(agent.Type)) {
+		c.Rules = append(c.Rules, "type:"+string(agent.Type))
+	}
+	if len(agent.Labels) == 0 {
+		c.Rules = append(c.Rules, "any-task")
+	}
+	for _, tl := range task.Labels {
+		if contains(agent.Labels, tl) {
+			c.Rules = append(c.Rules, "label:"+tl)
+			matched++
+		}
+		if contains(agent.Config.PreferredTasks, tl) {
+			c.Rules = append(c.Rules, "preferred:"+tl)
+			preferred++
+		}
+	}
+	c.Factors = map[string]int{
+		"labels":    matched * matchWeight,
+		"preferred": preferred * preferredWeight,
+		"load":      -agent.Load / loadStep,
+	}
+	c.Score = c.Factors["labels"] + c.Factors["preferred"] + c.Factors["load"]
+
+	switch {
+	case agent.Status != StatusIdle && agent.Status != StatusWorking:
+		c.Rejected = fmt.Sprintf("agent is %s", agent.Status)
+	case !agent.Accepts(TaskStatusInProgress):
+		c.Rejected = fmt.Sprintf("all %d slots are busy", agent.Slots())
+	case !agent.Config.AutoAssign:
+		c.Rejected = "auto_assign is off"
+	case len(task.AgentTypes) > 0 && !contains(task.AgentTypes, string(agent.Type)):
+		c.Rejected = fmt.Sprintf("the task needs a %s agent", strings.Join(task.AgentTypes, " or "))
+	case len(agent.Labels) > 0 && matched == 0:
+		c.Rejected = fmt.Sprintf("no label in common: agent has %s", strings.Join(agent.Labels, ", "))
+	default:
+		c.Eligible = true
+	}
+	return c
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
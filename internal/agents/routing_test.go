@@ -0,0 +1,110 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAutoAssignPrefersBestScore(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(ctx)
+	auto := AgentConfig{AutoAssign: true}
+	r.RegisterAgent(&Agent{ID: "any", Name: "generalist", Config: auto})
+	r.RegisterAgent(&Agent{ID: "loaded", Name: "coder", Labels: []string{"code"}, Load: 80, Config: auto})
+	r.RegisterAgent(&Agent{ID: "code", Name: "coder", Labels: []string{"code"}, Config: AgentConfig{AutoAssign: true, PreferredTasks: []string{"fix"}}})
+	r.RegisterAgent(&Agent{ID: "docs", Name: "documenter", Labels: []string{"docs"}, Config: auto})
+	r.RegisterAgent(&Agent{ID: "manual", Name: "manual", Labels: []string{"code"}})
+	r.RegisterAgent(&Agent{ID: "other", Name: "elsewhere", Workspace: "team-b", Config: auto})
+
+	task := r.CreateTask(&Task{Title: "fix the parser", Labels: []string{"code", "fix"}})
+
+	preview, ok := r.Routing(task.ID)
+	if !ok || !preview.Preview || preview.AgentID != "code" {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if n := r.AutoAssign(ctx); n != 1 {
+		t.Fatalf("assigned %d tasks", n)
+	}
+	d, ok := r.Routing(task.ID)
+	if !ok || d.Preview || d.AgentID != "code" || d.Actor != "auto-assign" || !strings.Contains(d.Reason, "scored 15") {
+		t.Fatalf("decision = %+v", d)
+	}
+
+	// Eligible agents first by score, the others after
+	var order []string
+	byID := map[string]RoutingCandidate{}
+	for _, c := range d.Candidates {
+		order = append(order, c.AgentID)
+		byID[c.AgentID] = c
+	}
+	if got := strings.Join(order, " "); got != "code loaded any manual docs" {
+		t.Errorf("candidates = %s", got)
+	}
+	if c := byID["code"]; !c.Chosen || c.Score != 15 || strings.Join(c.Rules, " ") != "label:code preferred:fix" {
+		t.Errorf("chosen = %+v", c)
+	}
+	if c := byID["loaded"]; c.Score != 2 || c.Factors["load"] != -8 {
+		t.Errorf("loaded agent = %+v", c)
+	}
+	if c := byID["docs"]; c.Eligible || !strings.Contains(c.Rejected, "no label in common") {
+		t.Errorf("docs = %+v", c)
+	}
+	if c := byID["manual"]; c.Eligible || c.Rejected != "auto_assign is off" {
+		t.Errorf("manual = %+v", c)
+	}
+	if _, ok := byID["other"]; ok {
+		t.Error("agents of other workspaces must not be candidates")
+	}
+}
+
+func TestAutoAssignHonorsAgentTypes(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(ctx)
+	auto := AgentConfig{AutoAssign: true}
+	r.RegisterAgent(&Agent{ID: "rev", Name: "reviewer", Type: AgentTypeReviewer, Labels: []string{"code"}, Config: auto})
+	r.RegisterAgent(&Agent{ID: "cod", Name: "coder", Type: AgentTypeCoder, Config: auto})
+
+	task := r.CreateTask(&Task{Title: "write tests", Labels: []string{"code"}, AgentTypes: []string{"coder"}})
+	if n := r.AutoAssign(ctx); n != 1 {
+		t.Fatalf("assigned %d tasks", n)
+	}
+	d, _ := r.Routing(task.ID)
+	if d.AgentID != "cod" {
+		t.Fatalf("decision = %+v", d)
+	}
+	for _, c := range d.Candidates {
+		switch c.AgentID {
+		case "cod":
+			if strings.Join(c.Rules, " ") != "type:coder any-task" {
+				t.Errorf("coder = %+v", c)
+			}
+		case "rev":
+			if c.Eligible || c.Rejected != "the task needs a coder agent" {
+				t.Errorf("reviewer = %+v", c)
+			}
+		}
+	}
+}
+
+func TestRoutingOfManualAssignment(t *testing.T) {
+	r := NewRegistry(context.Background())
+	r.RegisterAgent(&Agent{ID: "a", Name: "a"})
+	task := r.CreateTask(&Task{Title: "t"})
+	if err := r.AssignTaskBy(task.ID, "a", Cause{Actor: "ci", Reason: "picked"}); err != nil {
+		t.Fatal(err)
+	}
+	d, ok := r.Routing(task.ID)
+	if !ok || d.AgentID != "a" || d.Actor != "ci" || len(d.Candidates) != 1 || !d.Candidates[0].Chosen {
+		t.Fatalf("decision = %+v", d)
+	}
+
+	r.BeginDrain()
+	pending := r.CreateTask(&Task{Title: "later"})
+	if d, _ := r.Routing(pending.ID); d.Blocked == "" || d.AgentID != "" {
+		t.Errorf("draining preview = %+v", d)
+	}
+	if _, ok := r.Routing("missing"); ok {
+		t.Error("unknown task")
+	}
+}
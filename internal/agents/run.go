@@ -0,0 +1,24 @@
+package agents
+
+// ErrTaskNotAssigned is returned when an agent finishes a task that is no
+// longer its own, because it was cancelled, reassigned or finished
+// meanwhile
+var ErrTaskNotAssigned = &AgentError{message: "task is not assigned to the agent"}
+
+// FinishTask is CompleteTaskBy for the agent running the task: the result
+// is dropped with ErrTaskNotAssigned unless the task is still in progress
+// on agentID
+func (r *Registry) FinishTask(taskID, agentID string, result *TaskResult, c Cause) error {
+	r.mu.Lock()
+	defer r.unlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if task.Status != TaskStatusInProgress || task.AssignedTo != agentID {
+		return ErrTaskNotAssigned
+	}
+	r.complete(task, result, c)
+	return nil
+}
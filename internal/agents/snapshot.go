@@ -0,0 +1,164 @@
+package agents
+
+import "github.com/biodoia/skagent/internal/artifacts"
+
+// The agents and tasks in the registry maps are never modified once
+// stored: a change replaces the entry with an edited copy (editAgent,
+// editTask). Readers can therefore keep what they were given without
+// racing the writers. GetAgent and GetTask return private clones; the
+// list operations share a snapshot of the stored pointers, built on the
+// first read after a change and then served without taking the registry
+// lock, so that a fleet of API clients polling the registry does not
+// contend with the writers.
+
+// Clone returns a deep copy of the agent, including its current task
+func (a *Agent) Clone() *Agent {
+	if a == nil {
+		return nil
+	}
+	c := *a
+	c.Labels = cloneStrings(a.Labels)
+	c.Capabilities = cloneStrings(a.Capabilities)
+	c.Config.PreferredTasks = cloneStrings(a.Config.PreferredTasks)
+	c.Meta = cloneMeta(a.Meta)
+	c.CurrentTask = a.CurrentTask.Clone()
+	c.Running = cloneStrings(a.Running)
+	c.Queue = append([]QueuedTask(nil), a.Queue...)
+	return &c
+}
+
+// Clone returns a deep copy of the task
+func (t *Task) Clone() *Task {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	c.Labels = cloneStrings(t.Labels)
+	c.AgentTypes = cloneStrings(t.AgentTypes)
+	c.AcceptanceCriteria = cloneStrings(t.AcceptanceCriteria)
+	c.Artifacts = append([]artifacts.Artifact(nil), t.Artifacts...)
+	c.Meta = cloneMeta(t.Meta)
+	if t.Result != nil {
+		result := *t.Result
+		result.Artifacts = cloneStrings(t.Result.Artifacts)
+		result.Lessons = cloneStrings(t.Result.Lessons)
+		if e := t.Result.Evaluation; e != nil {
+			eval := *e
+			eval.Criteria = append([]CriterionResult(nil), e.Criteria...)
+			result.Evaluation = &eval
+		}
+		c.Result = &result
+	}
+	if t.StartedAt != nil {
+		started := *t.StartedAt
+		c.StartedAt = &started
+	}
+	if t.CompletedAt != nil {
+		completed := *t.CompletedAt
+		c.CompletedAt = &completed
+	}
+	if t.Retry != nil {
+		policy := *t.Retry
+		policy.RetryOn = cloneStrings(t.Retry.RetryOn)
+		c.Retry = &policy
+	}
+	c.Attempts = append([]Attempt(nil), t.Attempts...)
+	if t.RetryAt != nil {
+		retryAt := *t.RetryAt
+		c.RetryAt = &retryAt
+	}
+	return &c
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+func cloneMeta(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// changed drops the list snapshots; every mutation calls it while holding
+// r.mu
+func (r *Registry) changed() {
+	r.agentList.Store(nil)
+	r.taskList.Store(nil)
+}
+
+// editAgent replaces a stored agent with a copy at the next version and
+// returns the copy for the caller to change; the caller holds r.mu. With a
+// shared store the copy is made of the stored row, so that the change goes
+// on top of what other registries did to the agent.
+func (r *Registry) editAgent(agent *Agent) *Agent {
+	if stored := r.storedAgent(agent.ID); stored != nil {
+		agent = stored
+	}
+	c := agent.Clone()
+	c.Version++
+	r.agents[c.ID] = c
+	r.markAgent(c.ID)
+	r.changed()
+	return c
+}
+
+// editTask replaces a stored task with a copy and returns the copy for the
+// caller to change; the caller holds r.mu. With a shared store the copy is
+// made of the stored row, as with editAgent.
+func (r *Registry) editTask(task *Task) *Task {
+	if stored := r.storedTask(task.ID); stored != nil {
+		task = stored
+	}
+	c := task.Clone()
+	r.tasks[c.ID] = c
+	r.markTask(c.ID)
+	r.changed()
+	return c
+}
+
+// agentsView returns the shared, read-only snapshot of every agent
+func (r *Registry) agentsView() []*Agent {
+	if list := r.agentList.Load(); list != nil {
+		return *list
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if list := r.agentList.Load(); list != nil {
+		return *list
+	}
+	list := make([]*Agent, 0, len(r.agents))
+	for _, a := range r.agents {
+		list = append(list, a)
+	}
+	// Stored under the read lock, so that no change can slip in between
+	// building the snapshot and publishing it
+	r.agentList.Store(&list)
+	return list
+}
+
+// tasksView returns the shared, read-only snapshot of every task
+func (r *Registry) tasksView() []*Task {
+	if list := r.taskList.Load(); list != nil {
+		return *list
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if list := r.taskList.Load(); list != nil {
+		return *list
+	}
+	list := make([]*Task, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		list = append(list, t)
+	}
+	r.taskList.Store(&list)
+	return list
+}
@@ -0,0 +1,87 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestReadersGetCopies(t *testing.T) {
+	r := NewRegistry(context.Background())
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+	task := r.CreateTask(&Task{Title: "work", Labels: []string{"code"}})
+
+	got, _ := r.GetTask(task.ID)
+	got.Title = "changed"
+	got.Labels[0] = "changed"
+	if again, _ := r.GetTask(task.ID); again.Title != "work" || again.Labels[0] != "code" {
+		t.Fatalf("GetTask returned the registry's task: %+v", again)
+	}
+
+	before := r.ListAgents()
+	if err := r.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if before[0].Status != StatusIdle {
+		t.Fatal("a listed agent changed after the list was taken")
+	}
+	if after := r.ListAgents(); after[0].Status != StatusWorking || after[0].CurrentTask.ID != task.ID {
+		t.Fatalf("list after assignment: %+v", after[0])
+	}
+}
+
+// TestConcurrentReadsAndWrites is meant for go test -race: readers encode
+// what they get while the tasks are being worked on
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	r := NewRegistry(context.Background())
+	r.logger.SetOutput(io.Discard)
+	var agentIDs []string
+	for i := 0; i < 4; i++ {
+		a, _ := r.CreateAgent("worker", "coder", nil)
+		agentIDs = append(agentIDs, a.ID)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				json.Marshal(r.ListTasks())
+				json.Marshal(r.ListAgents())
+				r.GetStats()
+			}
+		}()
+	}
+
+	var workers sync.WaitGroup
+	for _, id := range agentIDs {
+		workers.Add(1)
+		go func(agentID string) {
+			defer workers.Done()
+			for i := 0; i < 50; i++ {
+				task := r.CreateTask(&Task{Title: "job"})
+				if err := r.AssignTask(task.ID, agentID); err != nil {
+					t.Error(err)
+					return
+				}
+				r.CompleteTask(task.ID, &TaskResult{Success: true})
+			}
+		}(id)
+	}
+	workers.Wait()
+	close(stop)
+	wg.Wait()
+
+	if stats := r.GetStats(); stats["completed_tasks"] != 200 {
+		t.Fatalf("stats: %v", stats)
+	}
+}
@@ -0,0 +1,400 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Store persists the registry's agents, tasks and workspaces so that they
+// survive a restart. The registry loads them when the store is attached
+// with UseStore, then writes every change through to it before releasing
+// its lock, so the store never lags behind what readers have seen.
+type Store interface {
+	// Load returns everything stored
+	Load() (*State, error)
+	// Save applies a batch of changes atomically
+	Save(Changes) error
+	// Close releases the store
+	Close() error
+}
+
+// SharedStore is a store that several registries use at once, such as a
+// database shared by headless instances. Each registry claims an
+// assignment in the store before making it, so that two of them never
+// give a task to two agents or an agent more tasks than it has room for,
+// and syncs to see what the others changed.
+type SharedStore interface {
+	Store
+	// Claim assigns task to agentID in the store, moving it to status, if
+	// the stored task still has the status and assignee of task and the
+	// stored agent accepts a task with that status; otherwise it returns
+	// ErrClaimed. It returns the task and agent as the claim stored them.
+	Claim(task *Task, agentID string, status TaskStatus) (*Task, *Agent, error)
+	// Begin starts a transaction in which the rows read stay locked until
+	// it commits or rolls back
+	Begin() (StoreTx, error)
+}
+
+// StoreTx is a transaction on a shared store. The registry reads in it the
+// stored row of each agent and task an operation edits, makes the edit on
+// that row and writes it back in the same transaction, so that what other
+// registries stored since the last sync is kept rather than overwritten by
+// a stale copy.
+type StoreTx interface {
+	// Agent returns the stored agent with id, locked, or nil if there is none
+	Agent(id string) (*Agent, error)
+	// Task returns the stored task with id, locked, or nil if there is none
+	Task(id string) (*Task, error)
+	// Save applies a batch of changes
+	Save(Changes) error
+	// Commit ends the transaction, keeping its writes
+	Commit() error
+	// Rollback ends the transaction, dropping its writes
+	Rollback() error
+}
+
+// ErrClaimed is returned when another registry sharing the store assigned
+// the task, or gave the agent other work, first
+var ErrClaimed = &AgentError{message: "task or agent was claimed by another instance"}
+
+// State is the content of a store
+type State struct {
+	Agents     []*Agent
+	Tasks      []*Task
+	Workspaces []*Workspace
+}
+
+// Changes are the writes of one registry operation: the agents, tasks and
+// workspaces to store, replacing any stored ones with the same ID, and the
+// IDs of those to delete
+type Changes struct {
+	State
+	DeletedAgents     []string
+	DeletedTasks      []string
+	DeletedWorkspaces []string
+}
+
+// Empty reports whether there is nothing to write
+func (c Changes) Empty() bool {
+	return len(c.Agents)+len(c.Tasks)+len(c.Workspaces)+
+		len(c.DeletedAgents)+len(c.DeletedTasks)+len(c.DeletedWorkspaces) == 0
+}
+
+// dirty is what changed under r.mu since the last write to the store, by
+// ID; whether an entry was stored or deleted is read from the maps
+type dirty struct {
+	agents, tasks, workspaces map[string]struct{}
+}
+
+// markAgent, markTask and markWorkspace record a change to write through;
+// the caller holds r.mu
+func (r *Registry) markAgent(id string)       { r.mark(&r.dirty.agents, id) }
+func (r *Registry) markTask(id string)        { r.mark(&r.dirty.tasks, id) }
+func (r *Registry) markWorkspace(name string) { r.mark(&r.dirty.workspaces, name) }
+
+func (r *Registry) mark(set *map[string]struct{}, id string) {
+	if r.store == nil {
+		return
+	}
+	if *set == nil {
+		*set = make(map[string]struct{})
+	}
+	(*set)[id] = struct{}{}
+}
+
+// UseStore loads what store holds into the registry and writes every later
+// change through to it. Stored entries replace those with the same ID.
+// Work that was running when the previous process stopped is requeued: its
+// tasks go back to pending, for auto-assignment, and the queued tasks of
+// its agents take their slots. A shared store is left as it is, since
+// other registries may be running that work.
+func (r *Registry) UseStore(store Store) error {
+	state, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("loading the registry: %w", err)
+	}
+	r.mu.Lock()
+	defer r.unlock()
+	r.store = store
+	for _, ws := range state.Workspaces {
+		r.workspaces[ws.Name] = ws
+	}
+	for _, a := range state.Agents {
+		r.agents[a.ID] = a
+	}
+	_, shared := store.(SharedStore)
+	now := time.Now()
+	for _, t := range state.Tasks {
+		r.tasks[t.ID] = t
+		if shared {
+			continue
+		}
+		// Queued tasks stay in the queue that holds them
+		agent := r.agents[t.AssignedTo]
+		if t.Status == TaskStatusInProgress || (t.Status == TaskStatusQueued && (agent == nil || !agent.holds(t.ID))) {
+			t = r.editTask(t)
+			t.Status = TaskStatusPending
+			t.AssignedTo = ""
+			t.StartedAt = nil
+			t.UpdatedAt = now
+		}
+	}
+	for _, a := range state.Agents {
+		if shared || (len(a.Running) == 0 && a.CurrentTask == nil && a.Status != StatusWorking) {
+			continue
+		}
+		a = r.editAgent(a)
+		a.Running = nil
+		a.CurrentTask = nil
+		a.settle()
+		a.UpdatedAt = now
+		r.startQueued(a)
+	}
+	r.changed()
+	r.logger.Printf("Loaded %d agents, %d tasks and %d workspaces from the store",
+		len(state.Agents), len(state.Tasks), len(state.Workspaces))
+	return nil
+}
+
+// CloseStore closes the store, after which changes are kept in memory only
+func (r *Registry) CloseStore() error {
+	r.mu.Lock()
+	store := r.store
+	r.store = nil
+	r.mu.Unlock()
+	if store == nil {
+		return nil
+	}
+	return store.Close()
+}
+
+// unlock writes the changes made under r.mu through to the store, then
+// releases the lock
+func (r *Registry) unlock() {
+	defer r.mu.Unlock()
+	if err := r.flush(); err != nil {
+		r.logger.Printf("ERROR: Persisting the registry: %v", err)
+	}
+}
+
+// flush writes the marked changes to the store; the caller holds r.mu.
+// Changes that fail to be written stay marked and go with the next flush.
+func (r *Registry) flush() error {
+	if r.store == nil {
+		return nil
+	}
+	if shared, ok := r.store.(SharedStore); ok {
+		return r.commit(shared)
+	}
+	changes := r.pendingChanges()
+	if changes.Empty() {
+		return nil
+	}
+	if err := r.store.Save(changes); err != nil {
+		return err
+	}
+	r.dirty = dirty{}
+	return nil
+}
+
+// commit is flush for a shared store: it writes the marked changes in the
+// transaction that read the rows they were made on, or in a new one when
+// the operation only created or deleted entries. Changes that fail to be
+// written are dropped and the registry reloads, since writing them later,
+// without the rows locked, would overwrite what other registries stored.
+func (r *Registry) commit(shared SharedStore) error {
+	tx, err := r.tx, r.txErr
+	changes := r.pendingChanges()
+	r.tx, r.txErr, r.dirty = nil, nil, dirty{}
+	if tx == nil && err == nil {
+		if changes.Empty() {
+			return nil
+		}
+		if tx, err = shared.Begin(); err != nil {
+			return r.discard(err)
+		}
+	}
+	if err == nil {
+		err = tx.Save(changes)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if tx != nil {
+			tx.Rollback()
+		}
+		return r.discard(err)
+	}
+	return nil
+}
+
+// discard reloads the registry after its changes failed to be written to a
+// shared store, and returns err; the caller holds r.mu
+func (r *Registry) discard(err error) error {
+	if lerr := r.load(); lerr != nil {
+		r.logger.Printf("ERROR: Reloading the registry: %v", lerr)
+	}
+	return fmt.Errorf("%w (the changes were dropped)", err)
+}
+
+// storedAgent and storedTask return the stored row of an entry the current
+// operation is about to edit for the first time, locked until its changes
+// are written, or nil when the store is not shared, the entry was already
+// edited or created, or it is not stored; the caller holds r.mu
+func (r *Registry) storedAgent(id string) *Agent {
+	if _, ok := r.dirty.agents[id]; ok {
+		return nil
+	}
+	tx := r.begin()
+	if tx == nil {
+		return nil
+	}
+	a, err := tx.Agent(id)
+	if err != nil {
+		r.txErr = fmt.Errorf("reading agent %s: %w", id, err)
+	}
+	return a
+}
+
+func (r *Registry) storedTask(id string) *Task {
+	if _, ok := r.dirty.tasks[id]; ok {
+		return nil
+	}
+	tx := r.begin()
+	if tx == nil {
+		return nil
+	}
+	t, err := tx.Task(id)
+	if err != nil {
+		r.txErr = fmt.Errorf("reading task %s: %w", id, err)
+	}
+	return t
+}
+
+// begin returns the transaction of the current operation on a shared
+// store, beginning it if needed, or nil when there is none; the caller
+// holds r.mu. A failure is kept for the flush, which drops the changes.
+func (r *Registry) begin() StoreTx {
+	if r.tx != nil || r.txErr != nil {
+		return r.tx
+	}
+	shared, ok := r.store.(SharedStore)
+	if !ok {
+		return nil
+	}
+	tx, err := shared.Begin()
+	if err != nil {
+		r.txErr = err
+		return nil
+	}
+	r.tx = tx
+	return tx
+}
+
+// claim reserves the assignment of task to agentID in a shared store
+// before the registry makes it, and returns the task and agent to make it
+// on; the caller holds r.mu. When another registry got there first, this
+// one syncs to see what it did.
+func (r *Registry) claim(task *Task, agentID string, status TaskStatus) (*Task, *Agent, error) {
+	shared, ok := r.store.(SharedStore)
+	if !ok {
+		return task, r.agents[agentID], nil
+	}
+	// The stored task must be as this registry sees it, changes made
+	// earlier in the same operation included
+	if err := r.flush(); err != nil {
+		return nil, nil, err
+	}
+	stored, agent, err := shared.Claim(task, agentID, status)
+	if errors.Is(err, ErrClaimed) {
+		if err := r.sync(); err != nil {
+			r.logger.Printf("ERROR: Syncing with the store: %v", err)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// The stored rows hold what other registries changed since the last
+	// sync, such as the other tasks of the agent; the assignment is made
+	// on them so that writing them back keeps those changes. It is left
+	// undone on the task, for the caller to make and record.
+	stored = stored.Clone()
+	stored.Status, stored.AssignedTo = task.Status, task.AssignedTo
+	r.tasks[stored.ID] = stored
+	r.agents[agent.ID] = agent
+	r.changed()
+	return stored, agent, nil
+}
+
+// Sync reloads the registry from a store shared with other registries, to
+// see the agents, tasks and workspaces they created, changed or deleted.
+// Changes of this registry are written first, so the store has them all.
+func (r *Registry) Sync() error {
+	r.mu.Lock()
+	defer r.unlock()
+	return r.sync()
+}
+
+// sync is Sync for a caller holding r.mu
+func (r *Registry) sync() error {
+	if r.store == nil {
+		return nil
+	}
+	if err := r.flush(); err != nil {
+		return err
+	}
+	return r.load()
+}
+
+// load replaces the registry's entries with those stored; the caller holds
+// r.mu and has written its changes
+func (r *Registry) load() error {
+	state, err := r.store.Load()
+	if err != nil {
+		return err
+	}
+	r.agents = make(map[string]*Agent, len(state.Agents))
+	for _, a := range state.Agents {
+		r.agents[a.ID] = a
+	}
+	r.tasks = make(map[string]*Task, len(state.Tasks))
+	for _, t := range state.Tasks {
+		r.tasks[t.ID] = t
+	}
+	r.workspaces = map[string]*Workspace{DefaultWorkspace: r.workspaces[DefaultWorkspace]}
+	for _, ws := range state.Workspaces {
+		r.workspaces[ws.Name] = ws
+	}
+	r.changed()
+	return nil
+}
+
+// pendingChanges turns the marked IDs into the writes that bring the store
+// up to date; the caller holds r.mu
+func (r *Registry) pendingChanges() Changes {
+	var c Changes
+	for id := range r.dirty.agents {
+		if a, ok := r.agents[id]; ok {
+			c.Agents = append(c.Agents, a)
+		} else {
+			c.DeletedAgents = append(c.DeletedAgents, id)
+		}
+	}
+	for id := range r.dirty.tasks {
+		if t, ok := r.tasks[id]; ok {
+			c.Tasks = append(c.Tasks, t)
+		} else {
+			c.DeletedTasks = append(c.DeletedTasks, id)
+		}
+	}
+	for name := range r.dirty.workspaces {
+		if ws, ok := r.workspaces[name]; ok {
+			c.Workspaces = append(c.Workspaces, ws)
+		} else {
+			c.DeletedWorkspaces = append(c.DeletedWorkspaces, name)
+		}
+	}
+	return c
+}
@@ -0,0 +1,327 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// memStore records the batches saved, and fails while fail is set
+type memStore struct {
+	state   State
+	batches []Changes
+	fail    bool
+}
+
+func (s *memStore) Load() (*State, error) { return &s.state, nil }
+func (s *memStore) Close() error          { return nil }
+func (s *memStore) Save(c Changes) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	s.batches = append(s.batches, c)
+	return nil
+}
+
+func TestStoreWritesThrough(t *testing.T) {
+	r := NewRegistry(context.Background())
+	store := &memStore{}
+	if err := r.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	agent, _ := r.CreateAgent("a", "coder", nil)
+	task := r.CreateTask(&Task{Title: "t"})
+	if err := r.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.batches) != 3 {
+		t.Fatalf("%d batches, want one per operation", len(store.batches))
+	}
+	// Assigning writes the task and the agent together, as they are after
+	// the change
+	assigned := store.batches[2]
+	if len(assigned.Tasks) != 1 || assigned.Tasks[0].Status != TaskStatusInProgress ||
+		len(assigned.Agents) != 1 || assigned.Agents[0].Status != StatusWorking {
+		t.Fatalf("assign batch = %+v", assigned)
+	}
+
+	// A failed write is retried with the next one
+	store.fail = true
+	if err := r.CompleteTask(task.ID, &TaskResult{Success: true}); err != nil {
+		t.Fatal(err)
+	}
+	store.fail = false
+	if err := r.DeleteAgent(agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	last := store.batches[len(store.batches)-1]
+	if len(last.Tasks) != 1 || last.Tasks[0].Status != TaskStatusCompleted || len(last.DeletedAgents) != 1 || len(last.Agents) != 0 {
+		t.Fatalf("batch after a failure = %+v", last)
+	}
+
+	// Reads write nothing
+	n := len(store.batches)
+	r.ListTasks()
+	r.GetStats()
+	if len(store.batches) != n {
+		t.Error("reads wrote to the store")
+	}
+}
+
+func TestUseStoreRequeuesRunningWork(t *testing.T) {
+	task := &Task{ID: "t1", Title: "t", Status: TaskStatusInProgress, AssignedTo: "a1", Workspace: DefaultWorkspace}
+	store := &memStore{state: State{
+		Agents: []*Agent{{ID: "a1", Name: "a", Status: StatusWorking, CurrentTask: task, Workspace: DefaultWorkspace, Config: AgentConfig{AutoAssign: true}}},
+		Tasks:  []*Task{task},
+	}}
+	r := NewRegistry(context.Background())
+	if err := r.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.GetTask("t1"); got.Status != TaskStatusPending || got.AssignedTo != "" {
+		t.Fatalf("task = %+v", got)
+	}
+	if a, _ := r.GetAgent("a1"); a.Status != StatusIdle || a.CurrentTask != nil {
+		t.Fatalf("agent = %+v", a)
+	}
+	if len(store.batches) != 1 || len(store.batches[0].Tasks) != 1 || len(store.batches[0].Agents) != 1 {
+		t.Fatalf("batches = %+v", store.batches)
+	}
+	// The requeued task is assigned again
+	if n := r.AutoAssign(context.Background()); n != 1 {
+		t.Fatalf("assigned %d", n)
+	}
+}
+
+// sharedStore is a memStore shared with other registries: it applies the
+// batches saved and claims as a database would, and refuses claims while
+// taken is set
+type sharedStore struct {
+	memStore
+	claims []string
+	taken  bool
+}
+
+func (s *sharedStore) Load() (*State, error) {
+	return &State{
+		Agents:     append([]*Agent(nil), s.state.Agents...),
+		Tasks:      append([]*Task(nil), s.state.Tasks...),
+		Workspaces: append([]*Workspace(nil), s.state.Workspaces...),
+	}, nil
+}
+
+func (s *sharedStore) Save(c Changes) error {
+	if err := s.memStore.Save(c); err != nil {
+		return err
+	}
+	for _, a := range c.Agents {
+		s.putAgent(a)
+	}
+	for _, t := range c.Tasks {
+		s.putTask(t)
+	}
+	s.state.Agents = slices.DeleteFunc(s.state.Agents, func(a *Agent) bool { return slices.Contains(c.DeletedAgents, a.ID) })
+	s.state.Tasks = slices.DeleteFunc(s.state.Tasks, func(t *Task) bool { return slices.Contains(c.DeletedTasks, t.ID) })
+	return nil
+}
+
+func (s *sharedStore) putAgent(a *Agent) {
+	if i := slices.IndexFunc(s.state.Agents, func(b *Agent) bool { return b.ID == a.ID }); i >= 0 {
+		s.state.Agents[i] = a
+		return
+	}
+	s.state.Agents = append(s.state.Agents, a)
+}
+
+func (s *sharedStore) putTask(t *Task) {
+	if i := slices.IndexFunc(s.state.Tasks, func(u *Task) bool { return u.ID == t.ID }); i >= 0 {
+		s.state.Tasks[i] = t
+		return
+	}
+	s.state.Tasks = append(s.state.Tasks, t)
+}
+
+func (s *sharedStore) Claim(task *Task, agentID string, status TaskStatus) (*Task, *Agent, error) {
+	i := slices.IndexFunc(s.state.Tasks, func(t *Task) bool { return t.ID == task.ID })
+	j := slices.IndexFunc(s.state.Agents, func(a *Agent) bool { return a.ID == agentID })
+	if s.taken || i < 0 || j < 0 {
+		return nil, nil, ErrClaimed
+	}
+	stored, agent := s.state.Tasks[i].Clone(), s.state.Agents[j].Clone()
+	if stored.Status != task.Status || stored.AssignedTo != task.AssignedTo || !agent.Accepts(status) {
+		return nil, nil, ErrClaimed
+	}
+	stored.Status = status
+	stored.AssignedTo = agentID
+	agent.Hold(stored)
+	agent.Version++
+	s.putTask(stored)
+	s.putAgent(agent)
+	s.claims = append(s.claims, task.ID+" "+agentID+" "+string(status))
+	return stored, agent, nil
+}
+
+// Begin returns a transaction that reads the stored rows and applies its
+// writes at once; nothing else runs while a test's registry holds it
+func (s *sharedStore) Begin() (StoreTx, error) { return sharedTx{s}, nil }
+
+type sharedTx struct{ s *sharedStore }
+
+func (tx sharedTx) Agent(id string) (*Agent, error) {
+	if i := slices.IndexFunc(tx.s.state.Agents, func(a *Agent) bool { return a.ID == id }); i >= 0 {
+		return tx.s.state.Agents[i].Clone(), nil
+	}
+	return nil, nil
+}
+
+func (tx sharedTx) Task(id string) (*Task, error) {
+	if i := slices.IndexFunc(tx.s.state.Tasks, func(t *Task) bool { return t.ID == id }); i >= 0 {
+		return tx.s.state.Tasks[i].Clone(), nil
+	}
+	return nil, nil
+}
+
+func (tx sharedTx) Save(c Changes) error { return tx.s.Save(c) }
+func (tx sharedTx) Commit() error        { return nil }
+func (tx sharedTx) Rollback() error      { return nil }
+
+func TestSharedStoreClaimsAssignments(t *testing.T) {
+	running := &Task{ID: "t0", Title: "elsewhere", Status: TaskStatusInProgress, AssignedTo: "other", Workspace: DefaultWorkspace}
+	store := &sharedStore{memStore: memStore{state: State{Tasks: []*Task{running}}}}
+	r := NewRegistry(context.Background())
+	if err := r.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+	// Work running in another instance is left alone
+	if got, _ := r.GetTask("t0"); got.Status != TaskStatusInProgress || len(store.batches) != 0 {
+		t.Fatalf("task = %+v, batches = %d", got, len(store.batches))
+	}
+
+	agent, _ := r.CreateAgent("a", "coder", nil)
+	task := r.CreateTask(&Task{Title: "t"})
+	if err := r.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.claims) != 1 || store.claims[0] != task.ID+" "+agent.ID+" in_progress" {
+		t.Fatalf("claims = %v", store.claims)
+	}
+	if err := r.CompleteTask(task.ID, &TaskResult{Success: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance took the next task: the assignment fails and the
+	// registry syncs to what the store holds
+	next := r.CreateTask(&Task{Title: "next"})
+	stolen := *next
+	stolen.Status, stolen.AssignedTo = TaskStatusInProgress, "other"
+	store.state = State{Agents: []*Agent{r.agents[agent.ID]}, Tasks: []*Task{&stolen}}
+	store.taken = true
+	if err := r.AssignTask(next.ID, agent.ID); !errors.Is(err, ErrClaimed) {
+		t.Fatalf("err = %v", err)
+	}
+	if got, _ := r.GetTask(next.ID); got.AssignedTo != "other" {
+		t.Fatalf("task after the sync = %+v", got)
+	}
+	if _, ok := r.GetTask(task.ID); ok {
+		t.Error("a task deleted from the store survived the sync")
+	}
+	if n := r.AutoAssign(context.Background()); n != 0 {
+		t.Fatalf("auto-assigned %d", n)
+	}
+}
+
+func TestSharedStoreKeepsTheChangesOfOtherRegistries(t *testing.T) {
+	ctx := context.Background()
+	store := &sharedStore{}
+	a, b := NewRegistry(ctx), NewRegistry(ctx)
+	for _, r := range []*Registry{a, b} {
+		if err := r.UseStore(store); err != nil {
+			t.Fatal(err)
+		}
+	}
+	agent, _ := a.CreateAgent("coder", "coder", map[string]interface{}{"max_concurrent": 2.0})
+	second := a.CreateTask(&Task{Title: "second"})
+	if err := b.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown to b, a starts a task on the agent and edits the other one
+	first := a.CreateTask(&Task{Title: "first"})
+	if err := a.AssignTask(first.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetTaskMeta(second.ID, map[string]string{"pr": "42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// b then gives the other task to the agent's free slot, from its
+	// stale copies
+	if n := b.AutoAssign(ctx); n != 1 {
+		t.Fatalf("b auto-assigned %d tasks", n)
+	}
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := a.GetAgent(agent.ID)
+	if !slices.Contains(stored.Running, first.ID) || !slices.Contains(stored.Running, second.ID) {
+		t.Errorf("agent runs %v, want both tasks", stored.Running)
+	}
+	task, _ := a.GetTask(second.ID)
+	if task.Status != TaskStatusInProgress || task.Meta["pr"] != "42" {
+		t.Errorf("task = %+v, want it running with a's edit", task)
+	}
+}
+
+func TestSharedStoreCompletionsKeepTheOtherTasksOfTheAgent(t *testing.T) {
+	ctx := context.Background()
+	store := &sharedStore{}
+	a, b := NewRegistry(ctx), NewRegistry(ctx)
+	for _, r := range []*Registry{a, b} {
+		if err := r.UseStore(store); err != nil {
+			t.Fatal(err)
+		}
+	}
+	agent, _ := a.CreateAgent("coder", "coder", map[string]interface{}{"max_concurrent": 2.0})
+	if err := b.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each registry starts a task on the agent; a does not see b's
+	first := a.CreateTask(&Task{Title: "first"})
+	if err := a.AssignTask(first.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	second := b.CreateTask(&Task{Title: "second"})
+	if err := b.AssignTask(second.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	claimed, _ := b.GetAgent(agent.ID)
+	storedAgent := func() *Agent {
+		i := slices.IndexFunc(store.state.Agents, func(s *Agent) bool { return s.ID == agent.ID })
+		return store.state.Agents[i]
+	}
+
+	// a completes its task from a copy of the agent that runs only it
+	if err := a.CompleteTask(first.ID, &TaskResult{Success: true}); err != nil {
+		t.Fatal(err)
+	}
+	stored := storedAgent()
+	if !slices.Equal(stored.Running, []string{second.ID}) {
+		t.Errorf("agent runs %v, want only %s", stored.Running, second.ID)
+	}
+	if stored.Version <= claimed.Version {
+		t.Errorf("version = %d, want past %d", stored.Version, claimed.Version)
+	}
+
+	// b completes its task from a copy that still runs a's, and counts no
+	// completion
+	if err := b.CompleteTask(second.ID, &TaskResult{Success: true}); err != nil {
+		t.Fatal(err)
+	}
+	stored = storedAgent()
+	if len(stored.Running) != 0 || stored.Stats.TasksCompleted != 2 {
+		t.Errorf("agent runs %v with %d completions, want none and 2", stored.Running, stored.Stats.TasksCompleted)
+	}
+}
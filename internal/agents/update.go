@@ -0,0 +1,162 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// maxAgentConcurrency bounds AgentConfig.MaxConcurrent
+const maxAgentConcurrency = 64
+
+// maxAgentQueue bounds AgentConfig.QueueSize
+const maxAgentQueue = 1000
+
+// AgentUpdate lists the changes to make to an agent. Nil fields are left
+// unchanged; an empty, non-nil list clears labels or capabilities.
+type AgentUpdate struct {
+	Name          *string  `json:"name,omitempty"`
+	Description   *string  `json:"description,omitempty"`
+	Labels        []string `json:"labels,omitempty"`
+	Capabilities  []string `json:"capabilities,omitempty"`
+	Provider      *string  `json:"provider,omitempty"`
+	Model         *string  `json:"model,omitempty"`
+	MaxConcurrent *int     `json:"max_concurrent,omitempty"`
+	QueueSize     *int     `json:"queue_size,omitempty"`
+	AutoAssign    *bool    `json:"auto_assign,omitempty"`
+	// Force applies the update even while the agent is working on a task
+	Force bool `json:"force,omitempty"`
+}
+
+// FieldError reports the field of an update that failed validation; it
+// matches ErrInvalidOperation
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func (e *FieldError) Unwrap() error {
+	return ErrInvalidOperation
+}
+
+func invalidField(field, message string) *FieldError {
+	return &FieldError{Field: field, Message: message}
+}
+
+// Validate checks the update on its own, without looking at the agent,
+// and returns every invalid field
+func (u *AgentUpdate) Validate() []*FieldError {
+	var errs []*FieldError
+	if u.Name != nil && strings.TrimSpace(*u.Name) == "" {
+		errs = append(errs, invalidField("name", "must not be empty"))
+	}
+	if err := checkTags("labels", u.Labels); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkTags("capabilities", u.Capabilities); err != nil {
+		errs = append(errs, err)
+	}
+	if u.Provider != nil && *u.Provider != "" && !config.Provider(*u.Provider).Valid() {
+		errs = append(errs, invalidField("provider", fmt.Sprintf("unknown provider %q", *u.Provider)))
+	}
+	if u.Model != nil && *u.Model != "" && strings.TrimSpace(*u.Model) != *u.Model {
+		errs = append(errs, invalidField("model", "must not have surrounding spaces"))
+	}
+	if u.MaxConcurrent != nil && (*u.MaxConcurrent < 1 || *u.MaxConcurrent > maxAgentConcurrency) {
+		errs = append(errs, invalidField("max_concurrent", fmt.Sprintf("must be between 1 and %d", maxAgentConcurrency)))
+	}
+	if u.QueueSize != nil && (*u.QueueSize < 1 || *u.QueueSize > maxAgentQueue) {
+		errs = append(errs, invalidField("queue_size", fmt.Sprintf("must be between 1 and %d", maxAgentQueue)))
+	}
+	return errs
+}
+
+// checkTags rejects empty and duplicate labels or capabilities
+func checkTags(field string, tags []string) *FieldError {
+	seen := make(map[string]bool, len(tags))
+	for i, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return invalidField(fmt.Sprintf("%s[%d]", field, i), "must not be empty")
+		}
+		if seen[tag] {
+			return invalidField(fmt.Sprintf("%s[%d]", field, i), fmt.Sprintf("duplicate %q", tag))
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+// UpdateAgent validates and applies an update and returns a copy of the
+// updated agent. An agent that is working on
+// a task is only changed when the update is forced; the running task keeps
+// the settings it started with.
+func (r *Registry) UpdateAgent(agentID string, u AgentUpdate) (*Agent, error) {
+	return r.UpdateAgentIf(agentID, 0, u)
+}
+
+// UpdateAgentIf is UpdateAgent for a client that read the agent at
+// version: it returns ErrVersionConflict when the agent changed since. A
+// version of 0 updates it whatever its version.
+func (r *Registry) UpdateAgentIf(agentID string, version int64, u AgentUpdate) (*Agent, error) {
+	if errs := u.Validate(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	r.mu.Lock()
+	defer r.unlock()
+
+	agent, ok := r.agents[agentID]
+	if !ok {
+		return nil, ErrAgentNotFound
+	}
+	if version != 0 && agent.Version != version {
+		return nil, ErrVersionConflict
+	}
+	if agent.CurrentTask != nil && !u.Force {
+		return nil, fmt.Errorf("%w: working on task %s; set force to update anyway", ErrAgentBusy, agent.CurrentTask.ID)
+	}
+
+	agent = r.editAgent(agent)
+	if u.Name != nil {
+		agent.Name = strings.TrimSpace(*u.Name)
+	}
+	if u.Description != nil {
+		agent.Description = *u.Description
+	}
+	if u.Labels != nil {
+		agent.Labels = append([]string{}, u.Labels...)
+	}
+	if u.Capabilities != nil {
+		agent.Capabilities = append([]string{}, u.Capabilities...)
+	}
+	if u.Provider != nil {
+		agent.Config.Provider = *u.Provider
+	}
+	if u.Model != nil {
+		agent.Config.Model = *u.Model
+	}
+	if u.MaxConcurrent != nil {
+		agent.Config.MaxConcurrent = *u.MaxConcurrent
+	}
+	if u.QueueSize != nil {
+		agent.Config.QueueSize = *u.QueueSize
+	}
+	if u.AutoAssign != nil {
+		agent.Config.AutoAssign = *u.AutoAssign
+	}
+	agent.UpdatedAt = time.Now()
+	// More slots start queued tasks; a smaller queue keeps the tasks it
+	// already holds
+	agent.settle()
+	r.startQueued(agent)
+
+	r.logger.Printf("Updated agent %s", agentID)
+	r.emitAgent(EventAgentUpdated, agent)
+	return agent.Clone(), nil
+}
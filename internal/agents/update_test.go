@@ -0,0 +1,97 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUpdateAgent(t *testing.T) {
+	r := NewRegistry(context.Background())
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+
+	name, provider, max, auto := "renamed", "openrouter", 4, false
+	updated, err := r.UpdateAgent(agent.ID, AgentUpdate{
+		Name:          &name,
+		Labels:        []string{"go", "api"},
+		Provider:      &provider,
+		MaxConcurrent: &max,
+		AutoAssign:    &auto,
+	})
+	if err != nil {
+		t.Fatalf("UpdateAgent() = %v", err)
+	}
+	if updated.Name != name || len(updated.Labels) != 2 || updated.Config.Provider != provider ||
+		updated.Config.MaxConcurrent != max || updated.Config.AutoAssign {
+		t.Fatalf("update not applied: %+v", updated)
+	}
+
+	bad, zero := "nope", 0
+	_, err = r.UpdateAgent(agent.ID, AgentUpdate{Provider: &bad, MaxConcurrent: &zero})
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "provider" || !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("err = %v, want invalid provider", err)
+	}
+	if errs := (&AgentUpdate{Provider: &bad, MaxConcurrent: &zero}).Validate(); len(errs) != 2 {
+		t.Fatalf("Validate() = %v, want 2 errors", errs)
+	}
+
+	if _, err := r.UpdateAgent("missing", AgentUpdate{Name: &name}); !errors.Is(err, ErrAgentNotFound) {
+		t.Fatalf("err = %v, want ErrAgentNotFound", err)
+	}
+}
+
+func TestUpdateAgentMidTask(t *testing.T) {
+	r := NewRegistry(context.Background())
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+	task := r.CreateTask(&Task{Title: "work"})
+	if err := r.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "renamed"
+	if _, err := r.UpdateAgent(agent.ID, AgentUpdate{Name: &name}); !errors.Is(err, ErrAgentBusy) {
+		t.Fatalf("err = %v, want ErrAgentBusy", err)
+	}
+	if _, err := r.UpdateAgent(agent.ID, AgentUpdate{Name: &name, Force: true}); err != nil {
+		t.Fatalf("forced update: %v", err)
+	}
+	if got, _ := r.GetAgent(agent.ID); got.Name != name {
+		t.Fatalf("name = %q, want %q", got.Name, name)
+	}
+}
+
+func TestUpdateAgentIfVersion(t *testing.T) {
+	r := NewRegistry(context.Background())
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+	if agent.Version != 1 {
+		t.Fatalf("new agent at version %d, want 1", agent.Version)
+	}
+
+	name := "first"
+	updated, err := r.UpdateAgentIf(agent.ID, 1, AgentUpdate{Name: &name})
+	if err != nil {
+		t.Fatalf("UpdateAgentIf: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("version = %d, want 2", updated.Version)
+	}
+
+	// A second writer that read version 1 loses
+	stale := "second"
+	if _, err := r.UpdateAgentIf(agent.ID, 1, AgentUpdate{Name: &stale}); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("stale update: %v", err)
+	}
+	if got, _ := r.GetAgent(agent.ID); got.Name != name {
+		t.Fatalf("stale update applied: name %q", got.Name)
+	}
+	if err := r.DeleteAgentIf(agent.ID, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("stale delete: %v", err)
+	}
+	if _, err := r.ApplyAgentOps([]AgentOp{{Op: BulkUpdate, ID: agent.ID, Name: "bulk", Version: 1}}); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("stale bulk update: %v", err)
+	}
+	if err := r.DeleteAgentIf(agent.ID, 2); err != nil {
+		t.Fatalf("DeleteAgentIf: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package agents
+
+import (
+	"sort"
+	"time"
+)
+
+// AgentView is how the REST and MCP APIs present an agent: a detached
+// copy that summarizes the current task instead of embedding it
+type AgentView struct {
+	ID           string      `json:"id"`
+	Name         string      `json:"name"`
+	Type         AgentType   `json:"type"`
+	Status       AgentStatus `json:"status"`
+	Description  string      `json:"description,omitempty"`
+	Labels       []string    `json:"labels,omitempty"`
+	Capabilities []string    `json:"capabilities,omitempty"`
+	Workspace    string      `json:"workspace"`
+	Load         int         `json:"load,omitempty"`
+	Config       AgentConfig `json:"config"`
+	Stats        AgentStats  `json:"stats"`
+	CurrentTask  *TaskRef    `json:"current_task,omitempty"`
+	// Running are the tasks in progress on the agent, and Queue those
+	// waiting for one of its slots, next first
+	Running    []string          `json:"running,omitempty"`
+	Queue      []QueuedTask      `json:"queue,omitempty"`
+	QueueDepth int               `json:"queue_depth"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Meta       map[string]string `json:"meta,omitempty"`
+	Version    int64             `json:"version"`
+}
+
+// TaskRef identifies a task from another resource
+type TaskRef struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Status    TaskStatus `json:"status"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+// View returns the API representation of the agent
+func (a *Agent) View() AgentView {
+	c := a.Clone()
+	v := AgentView{
+		ID:           c.ID,
+		Name:         c.Name,
+		Type:         c.Type,
+		Status:       c.Status,
+		Description:  c.Description,
+		Labels:       c.Labels,
+		Capabilities: c.Capabilities,
+		Workspace:    c.Workspace,
+		Load:         c.Load,
+		Config:       c.Config,
+		Stats:        c.Stats,
+		Running:      c.Running,
+		Queue:        c.Queue,
+		QueueDepth:   len(c.Queue),
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		Meta:         c.Meta,
+		Version:      c.Version,
+	}
+	if t := c.CurrentTask; t != nil {
+		v.CurrentTask = &TaskRef{ID: t.ID, Title: t.Title, Status: t.Status, StartedAt: t.StartedAt}
+	}
+	return v
+}
+
+// GetAgentView returns the view of an agent, taken under the registry lock
+func (r *Registry) GetAgentView(id string) (AgentView, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	agent, ok := r.agents[id]
+	if !ok {
+		return AgentView{}, false
+	}
+	return agent.View(), true
+}
+
+// ListAgentViews returns the views of every agent, oldest first
+func (r *Registry) ListAgentViews() []AgentView {
+	list := r.agentsView()
+	views := make([]AgentView, len(list))
+	for i, a := range list {
+		views[i] = a.View()
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if !views[i].CreatedAt.Equal(views[j].CreatedAt) {
+			return views[i].CreatedAt.Before(views[j].CreatedAt)
+		}
+		return views[i].ID < views[j].ID
+	})
+	return views
+}
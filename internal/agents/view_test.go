@@ -0,0 +1,34 @@
+package agents
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAgentViews(t *testing.T) {
+	r := NewRegistry(context.Background())
+	first, _ := r.CreateAgent("first", "coder", nil)
+	second, _ := r.CreateAgent("second", "reviewer", nil)
+	task := r.CreateTask(&Task{Title: "review", Description: "a long description"})
+	if err := r.AssignTask(task.ID, second.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	views := r.ListAgentViews()
+	if len(views) != 2 || views[0].ID != first.ID || views[1].ID != second.ID {
+		t.Fatalf("views are not oldest first: %+v", views)
+	}
+
+	v, ok := r.GetAgentView(second.ID)
+	if !ok {
+		t.Fatal("view not found")
+	}
+	if ct := v.CurrentTask; ct == nil || ct.ID != task.ID || ct.Title != "review" || ct.Status != TaskStatusInProgress || ct.StartedAt == nil {
+		t.Fatalf("current task: %+v", v.CurrentTask)
+	}
+
+	v.Labels = append(v.Labels, "mutated")
+	if again, _ := r.GetAgentView(second.ID); len(again.Labels) != 0 {
+		t.Fatal("a view shares state with the registry")
+	}
+}
@@ -0,0 +1,55 @@
+package agents
+
+import (
+	"context"
+	"time"
+)
+
+// waitPoll is how often WaitTask looks at the task again, in case an event
+// it waited for was dropped
+const waitPoll = time.Second
+
+// Finished reports whether a task in this status will not run again
+func (s TaskStatus) Finished() bool {
+	switch s {
+	case TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// WaitTask blocks until a task finishes or ctx is done, and returns the
+// task as it was last seen. It returns ctx's error with the unfinished
+// task when ctx ends first, and ErrTaskNotFound when the task does not
+// exist or is deleted while waiting.
+func (r *Registry) WaitTask(ctx context.Context, id string) (*Task, error) {
+	// Subscribe before the first look so no change is missed in between
+	events, cancel := r.Subscribe(16)
+	defer cancel()
+	poll := time.NewTicker(waitPoll)
+	defer poll.Stop()
+
+	for {
+		task, ok := r.GetTask(id)
+		if !ok {
+			return nil, ErrTaskNotFound
+		}
+		if task.Status.Finished() {
+			return task, nil
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return task, ctx.Err()
+			case <-poll.C:
+				break wait
+			case e := <-events:
+				if e.TaskID == id {
+					break wait
+				}
+			}
+		}
+	}
+}
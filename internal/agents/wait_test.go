@@ -0,0 +1,37 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitTask(t *testing.T) {
+	r := NewRegistry(context.Background())
+	agent, _ := r.CreateAgent("a", "coder", nil)
+	task := r.CreateTask(&Task{Title: "build"})
+	if err := r.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatalf("AssignTask: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	got, err := r.WaitTask(ctx, task.ID)
+	if !errors.Is(err, context.DeadlineExceeded) || got == nil || got.Status.Finished() {
+		t.Fatalf("WaitTask on a running task = %v, %v", got, err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.CompleteTask(task.ID, &TaskResult{Success: true, Output: "ok"})
+	}()
+	got, err = r.WaitTask(context.Background(), task.ID)
+	if err != nil || got.Status != TaskStatusCompleted || got.Result == nil || got.Result.Output != "ok" {
+		t.Fatalf("WaitTask = %+v, %v", got, err)
+	}
+
+	if _, err := r.WaitTask(context.Background(), "missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("WaitTask on a missing task: %v", err)
+	}
+}
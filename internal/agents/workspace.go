@@ -0,0 +1,137 @@
+package agents
+
+import (
+	"regexp"
+	"sort"
+	"time"
+)
+
+// DefaultWorkspace holds the agents and tasks created without a workspace
+const DefaultWorkspace = "default"
+
+// Workspace errors
+var (
+	ErrWorkspaceNotFound = &AgentError{message: "workspace not found"}
+	ErrWorkspaceExists   = &AgentError{message: "workspace already exists"}
+	ErrWorkspaceNotEmpty = &AgentError{message: "workspace still has agents or tasks"}
+	ErrWorkspaceDefault  = &AgentError{message: "the default workspace cannot be deleted"}
+	ErrWorkspaceInvalid  = &AgentError{message: "workspace names are 1-63 lowercase letters, digits, '-' or '_'"}
+	ErrWorkspaceMismatch = &AgentError{message: "agent and task are in different workspaces"}
+)
+
+// Workspace groups the agents, tasks and sessions of one team. Agents only
+// work on tasks of their own workspace.
+type Workspace struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Agents and Tasks count what the workspace holds when it is read
+	Agents int `json:"agents"`
+	Tasks  int `json:"tasks"`
+}
+
+var workspaceName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidWorkspaceName reports whether name may name a workspace
+func ValidWorkspaceName(name string) bool {
+	return workspaceName.MatchString(name)
+}
+
+// workspaceOf is the workspace of a resource; those created without one
+// belong to the default workspace
+func workspaceOf(name string) string {
+	if name == "" {
+		return DefaultWorkspace
+	}
+	return name
+}
+
+// inWorkspace reports whether a resource of workspace ws is in scope, the
+// workspace an operation is limited to; an empty scope spans them all
+func inWorkspace(ws, scope string) bool {
+	return scope == "" || workspaceOf(ws) == scope
+}
+
+// CreateWorkspace adds a workspace
+func (r *Registry) CreateWorkspace(name, description string) (*Workspace, error) {
+	if !ValidWorkspaceName(name) {
+		return nil, ErrWorkspaceInvalid
+	}
+	r.mu.Lock()
+	defer r.unlock()
+	if _, ok := r.workspaces[name]; ok {
+		return nil, ErrWorkspaceExists
+	}
+	ws := &Workspace{Name: name, Description: description, CreatedAt: time.Now()}
+	r.workspaces[name] = ws
+	r.markWorkspace(name)
+	r.logger.Printf("Created workspace %s", name)
+	c := *ws
+	return &c, nil
+}
+
+// GetWorkspace returns a workspace with its counts
+func (r *Registry) GetWorkspace(name string) (*Workspace, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ws, ok := r.workspaces[workspaceOf(name)]
+	if !ok {
+		return nil, false
+	}
+	return r.countWorkspace(ws), true
+}
+
+// ListWorkspaces returns every workspace with its counts, by name
+func (r *Registry) ListWorkspaces() []*Workspace {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*Workspace, 0, len(r.workspaces))
+	for _, ws := range r.workspaces {
+		out = append(out, r.countWorkspace(ws))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// countWorkspace returns a copy of ws with its counts; the caller holds
+// r.mu
+func (r *Registry) countWorkspace(ws *Workspace) *Workspace {
+	c := *ws
+	for _, a := range r.agents {
+		if workspaceOf(a.Workspace) == ws.Name {
+			c.Agents++
+		}
+	}
+	for _, t := range r.tasks {
+		if workspaceOf(t.Workspace) == ws.Name {
+			c.Tasks++
+		}
+	}
+	return &c
+}
+
+// DeleteWorkspace removes an empty workspace other than the default one
+func (r *Registry) DeleteWorkspace(name string) error {
+	if name == DefaultWorkspace {
+		return ErrWorkspaceDefault
+	}
+	r.mu.Lock()
+	defer r.unlock()
+	ws, ok := r.workspaces[name]
+	if !ok {
+		return ErrWorkspaceNotFound
+	}
+	if c := r.countWorkspace(ws); c.Agents > 0 || c.Tasks > 0 {
+		return ErrWorkspaceNotEmpty
+	}
+	delete(r.workspaces, name)
+	r.markWorkspace(name)
+	r.logger.Printf("Deleted workspace %s", name)
+	return nil
+}
+
+// hasWorkspace reports whether a workspace exists; the caller holds r.mu
+func (r *Registry) hasWorkspace(name string) bool {
+	_, ok := r.workspaces[workspaceOf(name)]
+	return ok
+}
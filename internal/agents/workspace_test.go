@@ -0,0 +1,70 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWorkspacesScopeAssignment(t *testing.T) {
+	r := NewRegistry(context.Background())
+	if _, err := r.CreateWorkspace("team-a", "Team A"); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	if _, err := r.CreateWorkspace("team-a", ""); !errors.Is(err, ErrWorkspaceExists) {
+		t.Fatalf("duplicate workspace: %v", err)
+	}
+	if _, err := r.CreateWorkspace("Team B", ""); !errors.Is(err, ErrWorkspaceInvalid) {
+		t.Fatalf("invalid name: %v", err)
+	}
+	if _, err := r.CreateAgentIn("missing", "a", "coder", nil); !errors.Is(err, ErrWorkspaceNotFound) {
+		t.Fatalf("agent in unknown workspace: %v", err)
+	}
+
+	agentA, _ := r.CreateAgentIn("team-a", "a", "coder", nil)
+	agentDefault, _ := r.CreateAgent("d", "coder", nil)
+	if agentDefault.Workspace != DefaultWorkspace {
+		t.Fatalf("agent workspace = %q, want default", agentDefault.Workspace)
+	}
+
+	task := r.CreateTask(&Task{Title: "fix", Workspace: "team-a"})
+	if err := r.AssignTask(task.ID, agentDefault.ID); !errors.Is(err, ErrWorkspaceMismatch) {
+		t.Fatalf("cross-workspace assignment: %v", err)
+	}
+	if err := r.AssignTask(task.ID, agentA.ID); err != nil {
+		t.Fatalf("AssignTask: %v", err)
+	}
+
+	ws, _ := r.GetWorkspace("team-a")
+	if ws.Agents != 1 || ws.Tasks != 1 {
+		t.Fatalf("counts = %d agents, %d tasks", ws.Agents, ws.Tasks)
+	}
+	if err := r.DeleteWorkspace("team-a"); !errors.Is(err, ErrWorkspaceNotEmpty) {
+		t.Fatalf("delete non-empty workspace: %v", err)
+	}
+	if err := r.DeleteWorkspace(DefaultWorkspace); !errors.Is(err, ErrWorkspaceDefault) {
+		t.Fatalf("delete default workspace: %v", err)
+	}
+	if got := len(r.TransitionsSinceIn(0, 100, DefaultWorkspace)); got != 0 {
+		t.Fatalf("default workspace sees %d transitions of team-a", got)
+	}
+}
+
+func TestAutoAssignStaysInWorkspace(t *testing.T) {
+	r := NewRegistry(context.Background())
+	r.CreateWorkspace("team-a", "")
+	r.CreateAgent("d", "coder", nil)
+	task := r.CreateTask(&Task{Title: "fix", Workspace: "team-a"})
+
+	r.AutoAssign(context.Background())
+	got, _ := r.GetTask(task.ID)
+	if got.AssignedTo != "" {
+		t.Fatalf("task of team-a assigned to %s in the default workspace", got.AssignedTo)
+	}
+
+	agentA, _ := r.CreateAgentIn("team-a", "a", "coder", nil)
+	r.AutoAssign(context.Background())
+	if got, _ := r.GetTask(task.ID); got.AssignedTo != agentA.ID {
+		t.Fatalf("task assigned to %q, want %s", got.AssignedTo, agentA.ID)
+	}
+}
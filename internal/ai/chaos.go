@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"context"
+	"time"
+
+	"github.com/biodoia/skagent/internal/chaos"
+)
+
+// faultyProvider injects the faults of a chaos.Injector into the calls of
+// the provider it wraps
+type faultyProvider struct {
+	Provider
+	faults *chaos.Injector
+}
+
+// WithFaults returns p with the faults of inj injected into its
+// completions: errors, 429s, latency and truncated output
+func WithFaults(p Provider, inj *chaos.Injector) Provider {
+	if f, ok := p.(*faultyProvider); ok {
+		p = f.Provider
+	}
+	return &faultyProvider{Provider: p, faults: inj}
+}
+
+// Complete implements Provider
+func (p *faultyProvider) Complete(ctx context.Context, messages []Message, systemPrompt string) (string, error) {
+	plan, err := p.begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	response, err := p.Provider.Complete(ctx, messages, systemPrompt)
+	if err == nil && plan.Truncate {
+		response = chaos.Truncate(response)
+	}
+	return response, err
+}
+
+// Stream implements StreamingProvider. A truncated completion is
+// delivered as a single delta.
+func (p *faultyProvider) Stream(ctx context.Context, messages []Message, systemPrompt string, onDelta func(string) error) (string, error) {
+	plan, err := p.begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !plan.Truncate {
+		return CompleteStream(ctx, p.Provider, messages, systemPrompt, onDelta)
+	}
+	response, err := p.Provider.Complete(ctx, messages, systemPrompt)
+	if err != nil {
+		return "", err
+	}
+	response = chaos.Truncate(response)
+	return response, onDelta(response)
+}
+
+// begin waits out the injected latency and fails the call when the plan
+// says so
+func (p *faultyProvider) begin(ctx context.Context) (chaos.Plan, error) {
+	name := p.Name()
+	plan := p.faults.Plan(name)
+	if err := plan.Wait(ctx); err != nil {
+		return plan, err
+	}
+	switch plan.Fail {
+	case chaos.FaultError:
+		return plan, p.faults.Fail(name)
+	case chaos.FaultRateLimit:
+		reset := time.Now().Add(time.Second)
+		return plan, &RateLimitError{
+			Provider: name,
+			Limit:    RateLimit{Reset: &reset},
+			Message:  "injected by chaos mode",
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,45 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/chaos"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestWithFaults(t *testing.T) {
+	ctx := context.Background()
+	mock := NewMockProvider("abcdef")
+
+	failing := WithFaults(mock, chaos.New("provider", config.FaultConfig{ErrorRate: 1}, 1))
+	var injected *chaos.Error
+	if _, err := failing.Complete(ctx, nil, ""); !errors.As(err, &injected) {
+		t.Errorf("error fault: %v", err)
+	}
+
+	limited := WithFaults(mock, chaos.New("provider", config.FaultConfig{RateLimitRate: 1}, 1))
+	if _, err := limited.Complete(ctx, nil, ""); err == nil {
+		t.Error("expected a rate limit")
+	} else if rl, ok := AsRateLimit(err); !ok || rl.Provider != "mock" {
+		t.Errorf("rate limit fault: %v", err)
+	}
+
+	truncating := WithFaults(mock, chaos.New("provider", config.FaultConfig{TruncateRate: 1}, 1))
+	var deltas []string
+	got, err := CompleteStream(ctx, truncating, []Message{{Role: "user", Content: "hi"}}, "", func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil || got != "abc" || len(deltas) != 1 || deltas[0] != "abc" {
+		t.Errorf("truncated stream = %q, %v, deltas %q", got, err, deltas)
+	}
+
+	if checked, err := Check(ctx, truncating); !checked || err != nil {
+		t.Errorf("Check should reach the wrapped provider: %v %v", checked, err)
+	}
+	if truncating.Name() != "mock" {
+		t.Errorf("Name = %s", truncating.Name())
+	}
+}
@@ -7,39 +7,9 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/biodoia/skagent/internal/docs"
 )
 
-// SpecKitDocs contains the embedded documentation for SpecKit
-const SpecKitDocs = `
-# GitHub Spec-Kit Commands
-
-## Core Commands
-- /speckit.constitution: Establish governance principles
-- /speckit.specify: Define requirements and specifications
-- /speckit.plan: Create technical implementation plan
-- /speckit.tasks: Generate actionable task list
-- /speckit.implement: Execute implementation
-
-## Optional Commands
-- /speckit.clarify: Refine under-specified areas
-- /speckit.analyze: Validate consistency
-- /speckit.checklist: Verify requirements quality
-
-## Workflow
-1. SPECIFY -> Define what and why
-2. PLAN -> Technical blueprint
-3. TASKS -> Atomic work items
-4. IMPLEMENT -> Build with TDD
-
-## The Nine Articles (Constitution)
-1. Library-First: Features as standalone libraries
-2. CLI Mandate: All functionality via CLI
-3. Test-First: No implementation without tests
-4. Simplicity: Max 3 projects per implementation
-5. Anti-Abstraction: Use frameworks directly
-6. Integration-First: Test in realistic environments
-`
-
 // SystemPrompt is the core system prompt for the AI agent
 const SystemPrompt = `You are an expert AI agent for spec-driven development using GitHub Spec-Kit.
 
@@ -130,7 +100,7 @@ func (c *Client) Complete(ctx context.Context, prompt string) (string, error) {
 		Model:     c.config.Model,
 		MaxTokens: c.config.MaxTokens,
 		System: []anthropic.TextBlockParam{
-			{Text: fmt.Sprintf(SystemPrompt, SpecKitDocs)},
+			{Text: fmt.Sprintf(SystemPrompt, docs.Embedded())},
 		},
 		Messages: c.history,
 	})
@@ -150,6 +120,7 @@ func (c *Client) Complete(ctx context.Context, prompt string) (string, error) {
 	// Add assistant response to history
 	c.history = append(c.history, message.ToParam())
 	c.simpleHistory = append(c.simpleHistory, Message{Role: "assistant", Content: response})
+	RecordFinish(ctx, string(message.StopReason))
 
 	return response, nil
 }
@@ -170,7 +141,7 @@ func (c *Client) CompleteWithTools(ctx context.Context, prompt string, tools []T
 		Model:     c.config.Model,
 		MaxTokens: c.config.MaxTokens,
 		System: []anthropic.TextBlockParam{
-			{Text: fmt.Sprintf(SystemPrompt, SpecKitDocs)},
+			{Text: fmt.Sprintf(SystemPrompt, docs.Embedded())},
 		},
 		Messages: c.history,
 		Tools:    apiTools,
@@ -200,6 +171,7 @@ func (c *Client) CompleteWithTools(ctx context.Context, prompt string, tools []T
 	// Add assistant response to history
 	c.history = append(c.history, message.ToParam())
 	c.simpleHistory = append(c.simpleHistory, Message{Role: "assistant", Content: response})
+	RecordFinish(ctx, string(message.StopReason))
 
 	return response, toolCalls, nil
 }
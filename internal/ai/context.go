@@ -0,0 +1,32 @@
+package ai
+
+import "context"
+
+type providerKey struct{}
+
+// offered is a provider attached to a context by the caller of the code
+// run with it
+type offered struct {
+	provider Provider
+	prefer   bool
+}
+
+// WithProvider attaches p to ctx, as the model the caller offers, such as
+// the one of an MCP host. With prefer set it is used over the provider of
+// the code run with ctx; otherwise only where that code has none.
+func WithProvider(ctx context.Context, p Provider, prefer bool) context.Context {
+	return context.WithValue(ctx, providerKey{}, offered{provider: p, prefer: p != nil && prefer})
+}
+
+// ProviderFor returns the provider to use under ctx: the one attached
+// with WithProvider when it is preferred or own is nil, else own
+func ProviderFor(ctx context.Context, own Provider) Provider {
+	o, ok := ctx.Value(providerKey{}).(offered)
+	if !ok || o.provider == nil {
+		return own
+	}
+	if o.prefer || own == nil {
+		return o.provider
+	}
+	return own
+}
@@ -0,0 +1,60 @@
+package ai
+
+import "context"
+
+// Reasons a provider ends a completion with
+const (
+	// FinishStop is a complete reply
+	FinishStop = "stop"
+	// FinishLength is a reply cut off by the output token limit
+	FinishLength = "length"
+	// FinishContentFilter is a reply withheld or cut off by the
+	// provider's moderation
+	FinishContentFilter = "content_filter"
+)
+
+// Finish is why a provider ended a completion, filled by the providers
+// called with a context from WithFinish. Providers that cannot tell, such
+// as the CLI ones, leave it empty.
+type Finish struct {
+	Reason string
+}
+
+// Truncated reports whether the reply was cut off by the token limit, so
+// that asking the model to continue completes it
+func (f *Finish) Truncated() bool { return f.Reason == FinishLength }
+
+// Partial reports whether the reply is not the whole answer
+func (f *Finish) Partial() bool {
+	return f.Reason == FinishLength || f.Reason == FinishContentFilter
+}
+
+type finishKey struct{}
+
+// WithFinish returns a context under which providers record in f why they
+// ended their completion
+func WithFinish(ctx context.Context, f *Finish) context.Context {
+	return context.WithValue(ctx, finishKey{}, f)
+}
+
+// RecordFinish records the finish reason a provider's API reported, in
+// the Finish attached to ctx if any
+func RecordFinish(ctx context.Context, reason string) {
+	if f, ok := ctx.Value(finishKey{}).(*Finish); ok && f != nil {
+		f.Reason = normalizeFinish(reason)
+	}
+}
+
+// normalizeFinish maps the finish reasons of the APIs providers speak,
+// OpenAI's, Anthropic's and MCP sampling's, to the Finish constants
+func normalizeFinish(reason string) string {
+	switch reason {
+	case "length", "max_tokens", "maxTokens":
+		return FinishLength
+	case "content_filter", "safety", "refusal":
+		return FinishContentFilter
+	case "stop", "end_turn", "endTurn", "stop_sequence", "stopSequence", "tool_calls", "tool_use":
+		return FinishStop
+	}
+	return reason
+}
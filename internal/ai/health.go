@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// Checker is a Provider that can tell whether it is usable without running
+// a completion, as readiness probes need
+type Checker interface {
+	Provider
+	// Check returns an error when the provider cannot serve completions
+	Check(ctx context.Context) error
+}
+
+// Check verifies p when it is a Checker; checked is false for providers
+// that have no way to tell
+func Check(ctx context.Context, p Provider) (checked bool, err error) {
+	if f, ok := p.(*faultyProvider); ok {
+		p = f.Provider
+	}
+	c, ok := p.(Checker)
+	if !ok {
+		return false, nil
+	}
+	return true, c.Check(ctx)
+}
+
+// Check implements Checker
+func (p *OpenRouterProvider) Check(ctx context.Context) error {
+	return checkModels(ctx, p.baseURL, p.apiKey)
+}
+
+// Check implements Checker
+func (p *GenericOpenAIProvider) Check(ctx context.Context) error {
+	return checkModels(ctx, p.baseURL, p.apiKey)
+}
+
+// Check implements Checker: the CLI must be installed
+func (p *CLIProvider) Check(ctx context.Context) error {
+	return checkCommand(p.command)
+}
+
+// Check implements Checker: the claude CLI must be installed
+func (p *ClaudeMaxProvider) Check(ctx context.Context) error {
+	return checkCommand("claude")
+}
+
+// checkModels lists the models of an OpenAI-compatible API, which every
+// such API serves cheaply and which fails on a bad key
+func checkModels(ctx context.Context, baseURL, apiKey string) error {
+	if baseURL == "" {
+		return fmt.Errorf("no base URL configured")
+	}
+	if apiKey == "" {
+		return fmt.Errorf("no API key configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(baseURL, "/")+"/models", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("API key rejected (status %d)", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("API error %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func checkCommand(command string) error {
+	if _, err := exec.LookPath(command); err != nil {
+		return fmt.Errorf("%s not found in PATH", command)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockCall is one completion request seen by a MockProvider
+type MockCall struct {
+	Messages     []Message
+	SystemPrompt string
+	// Model is the model WithModel switched the provider to, if any
+	Model string
+}
+
+// MockProvider is a StreamingProvider that answers from a script, for
+// tests and benchmarks. Replies are returned in order; once they run out,
+// the provider echoes the last message. Respond, when set, takes
+// precedence.
+type MockProvider struct {
+	// Respond computes the reply to a request
+	Respond func(messages []Message, systemPrompt string) (string, error)
+	// Latency delays every reply, as a remote model would
+	Latency time.Duration
+	// Forget skips recording requests, for long runs
+	Forget bool
+	// Unreachable, when set, is what Check reports
+	Unreachable error
+	// Finishes are the finish reasons of the replies, in order, for
+	// testing truncated completions; once they run out replies stop
+	// normally
+	Finishes []string
+
+	mu      sync.Mutex
+	replies []string
+	calls   []MockCall
+}
+
+// NewMockProvider returns a provider that answers with replies in order
+func NewMockProvider(replies ...string) *MockProvider {
+	return &MockProvider{replies: replies}
+}
+
+// Name implements Provider
+func (p *MockProvider) Name() string { return "mock" }
+
+// Check implements Checker
+func (p *MockProvider) Check(ctx context.Context) error { return p.Unreachable }
+
+// Complete implements Provider
+func (p *MockProvider) Complete(ctx context.Context, messages []Message, systemPrompt string) (string, error) {
+	return p.complete(ctx, "", messages, systemPrompt)
+}
+
+func (p *MockProvider) complete(ctx context.Context, model string, messages []Message, systemPrompt string) (string, error) {
+	if p.Latency > 0 {
+		timer := time.NewTimer(p.Latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	if !p.Forget {
+		p.calls = append(p.calls, MockCall{
+			Messages:     append([]Message(nil), messages...),
+			SystemPrompt: systemPrompt,
+			Model:        model,
+		})
+	}
+	respond := p.Respond
+	var reply string
+	scripted := len(p.replies) > 0
+	if scripted {
+		reply, p.replies = p.replies[0], p.replies[1:]
+	}
+	finish := FinishStop
+	if len(p.Finishes) > 0 {
+		finish, p.Finishes = p.Finishes[0], p.Finishes[1:]
+	}
+	p.mu.Unlock()
+	RecordFinish(ctx, finish)
+
+	switch {
+	case respond != nil:
+		return respond(messages, systemPrompt)
+	case scripted:
+		return reply, nil
+	case len(messages) == 0:
+		return "", nil
+	default:
+		return "echo: " + messages[len(messages)-1].Content, nil
+	}
+}
+
+// Stream implements StreamingProvider, delivering the reply one word at a
+// time
+func (p *MockProvider) Stream(ctx context.Context, messages []Message, systemPrompt string, onDelta func(string) error) (string, error) {
+	return p.stream(ctx, "", messages, systemPrompt, onDelta)
+}
+
+func (p *MockProvider) stream(ctx context.Context, model string, messages []Message, systemPrompt string, onDelta func(string) error) (string, error) {
+	reply, err := p.complete(ctx, model, messages, systemPrompt)
+	if err != nil {
+		return "", err
+	}
+	for _, word := range strings.SplitAfter(reply, " ") {
+		if word == "" {
+			continue
+		}
+		if err := onDelta(word); err != nil {
+			return reply, err
+		}
+	}
+	return reply, nil
+}
+
+// Calls returns the requests received so far
+func (p *MockProvider) Calls() []MockCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]MockCall(nil), p.calls...)
+}
+
+// mockModel is a MockProvider switched to a model by WithModel. It shares
+// the mock's script and calls, which record the model.
+type mockModel struct {
+	*MockProvider
+	model string
+}
+
+// Complete implements Provider
+func (m *mockModel) Complete(ctx context.Context, messages []Message, systemPrompt string) (string, error) {
+	return m.complete(ctx, m.model, messages, systemPrompt)
+}
+
+// Stream implements StreamingProvider
+func (m *mockModel) Stream(ctx context.Context, messages []Message, systemPrompt string, onDelta func(string) error) (string, error) {
+	return m.stream(ctx, m.model, messages, systemPrompt, onDelta)
+}
@@ -0,0 +1,28 @@
+package ai
+
+// WithModel returns a copy of p that requests the given model, for
+// providers that serve several models behind one API. The second result
+// is false, and p is returned unchanged, for providers that do not.
+func WithModel(p Provider, model string) (Provider, bool) {
+	switch p := p.(type) {
+	case *faultyProvider:
+		inner, ok := WithModel(p.Provider, model)
+		if !ok {
+			return p, false
+		}
+		return &faultyProvider{Provider: inner, faults: p.faults}, true
+	case *MockProvider:
+		return &mockModel{MockProvider: p, model: model}, true
+	case *mockModel:
+		return &mockModel{MockProvider: p.MockProvider, model: model}, true
+	case *OpenRouterProvider:
+		c := *p
+		c.model = model
+		return &c, true
+	case *GenericOpenAIProvider:
+		c := *p
+		c.model = model
+		return &c, true
+	}
+	return p, false
+}
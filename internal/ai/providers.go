@@ -93,7 +93,7 @@ func (p *OpenRouterProvider) Complete(ctx context.Context, messages []Message, s
 	}
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return "", apiError(p.Name(), resp, body)
 	}
 
 	var result struct {
@@ -101,6 +101,7 @@ func (p *OpenRouterProvider) Complete(ctx context.Context, messages []Message, s
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 		Error *struct {
 			Message string `json:"message"`
@@ -119,6 +120,7 @@ func (p *OpenRouterProvider) Complete(ctx context.Context, messages []Message, s
 		return "", fmt.Errorf("no response from model")
 	}
 
+	RecordFinish(ctx, result.Choices[0].FinishReason)
 	return result.Choices[0].Message.Content, nil
 }
 
@@ -193,7 +195,7 @@ func (p *GenericOpenAIProvider) Complete(ctx context.Context, messages []Message
 	}
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		return "", apiError(p.Name(), resp, body)
 	}
 
 	var result struct {
@@ -201,6 +203,7 @@ func (p *GenericOpenAIProvider) Complete(ctx context.Context, messages []Message
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 	}
 
@@ -212,6 +215,7 @@ func (p *GenericOpenAIProvider) Complete(ctx context.Context, messages []Message
 		return "", fmt.Errorf("no response from model")
 	}
 
+	RecordFinish(ctx, result.Choices[0].FinishReason)
 	return result.Choices[0].Message.Content, nil
 }
 
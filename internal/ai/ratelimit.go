@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is a provider's request quota, as reported when it refuses a
+// request with 429 Too Many Requests. Fields the provider does not report
+// are zero.
+type RateLimit struct {
+	// Limit is the number of requests allowed per window
+	Limit int `json:"limit,omitempty"`
+	// Remaining is the number of requests left in the window
+	Remaining int `json:"remaining"`
+	// Reset is when the quota refills or the provider accepts requests again
+	Reset *time.Time `json:"reset,omitempty"`
+}
+
+// RetryAfter returns how long to wait before retrying, or 0 when the
+// provider gave no reset time or it has passed
+func (l RateLimit) RetryAfter(now time.Time) time.Duration {
+	if l.Reset == nil || !l.Reset.After(now) {
+		return 0
+	}
+	return l.Reset.Sub(now)
+}
+
+// String describes the quota, e.g. "0/20 requests left, resets in 42s"
+func (l RateLimit) String() string {
+	var parts []string
+	if l.Limit > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d requests left", l.Remaining, l.Limit))
+	}
+	if wait := l.RetryAfter(time.Now()); wait > 0 {
+		parts = append(parts, "resets in "+wait.Round(time.Second).String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// RateLimitError is returned by providers when the API answers 429
+type RateLimitError struct {
+	Provider string
+	Limit    RateLimit
+	// Message is the provider's explanation, when it sent one
+	Message string
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("%s rate limit reached (429)", e.Provider)
+	if s := e.Limit.String(); s != "" {
+		msg += ": " + s
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// AsRateLimit returns the rate limit error wrapped in err, if any
+func AsRateLimit(err error) (*RateLimitError, bool) {
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		return rl, true
+	}
+	return nil, false
+}
+
+// apiError turns a non-200 chat completion response into an error; 429s
+// become a *RateLimitError
+func apiError(provider string, resp *http.Response, body []byte) error {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	// OpenRouter repeats the upstream's rate limit headers in the error
+	// body; the response's own headers take precedence
+	var payload struct {
+		Error struct {
+			Message  string `json:"message"`
+			Metadata struct {
+				Headers map[string]string `json:"headers"`
+			} `json:"metadata"`
+		} `json:"error"`
+	}
+	json.Unmarshal(body, &payload)
+
+	header := http.Header{}
+	for k, v := range payload.Error.Metadata.Headers {
+		header.Set(k, v)
+	}
+	for k, v := range resp.Header {
+		header[k] = v
+	}
+
+	message := payload.Error.Message
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	return &RateLimitError{
+		Provider: provider,
+		Limit:    ParseRateLimit(header, time.Now()),
+		Message:  message,
+	}
+}
+
+// ParseRateLimit reads the quota from response headers. It understands
+// X-RateLimit-Limit/Remaining/Reset (the reset as a Unix time in seconds
+// or milliseconds, or a delay), the OpenAI-style *-Requests variants and
+// Retry-After, which wins over the reset when both are present.
+func ParseRateLimit(h http.Header, now time.Time) RateLimit {
+	var l RateLimit
+	l.Limit, _ = strconv.Atoi(firstHeader(h, "X-RateLimit-Limit", "X-RateLimit-Limit-Requests"))
+	l.Remaining, _ = strconv.Atoi(firstHeader(h, "X-RateLimit-Remaining", "X-RateLimit-Remaining-Requests"))
+	if reset, ok := parseReset(firstHeader(h, "X-RateLimit-Reset", "X-RateLimit-Reset-Requests"), now); ok {
+		l.Reset = &reset
+	}
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			reset := now.Add(time.Duration(secs) * time.Second)
+			l.Reset = &reset
+		} else if t, err := http.ParseTime(v); err == nil {
+			l.Reset = &t
+		}
+	}
+	return l
+}
+
+func firstHeader(h http.Header, names ...string) string {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseReset reads a reset header: a Go-style delay such as "6m0s", a
+// Unix time in milliseconds or seconds, or a number of seconds to wait
+func parseReset(v string, now time.Time) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	if n, err := strconv.ParseFloat(v, 64); err == nil {
+		switch {
+		case n > 1e12:
+			return time.UnixMilli(int64(n)), true
+		case n > 1e9:
+			return time.Unix(int64(n), 0), true
+		default:
+			return now.Add(time.Duration(n * float64(time.Second))), true
+		}
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(d), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StreamingProvider is a Provider that can deliver a completion while it
+// is being generated
+type StreamingProvider interface {
+	Provider
+	// Stream calls onDelta with each piece of text as it arrives and
+	// returns the whole completion. An error from onDelta stops the stream.
+	Stream(ctx context.Context, messages []Message, systemPrompt string, onDelta func(string) error) (string, error)
+}
+
+// CompleteStream streams from p when it supports streaming; other
+// providers deliver their whole completion as a single delta
+func CompleteStream(ctx context.Context, p Provider, messages []Message, systemPrompt string, onDelta func(string) error) (string, error) {
+	if sp, ok := p.(StreamingProvider); ok {
+		return sp.Stream(ctx, messages, systemPrompt, onDelta)
+	}
+	response, err := p.Complete(ctx, messages, systemPrompt)
+	if err != nil {
+		return "", err
+	}
+	if err := onDelta(response); err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+// Stream implements StreamingProvider
+func (p *OpenRouterProvider) Stream(ctx context.Context, messages []Message, systemPrompt string, onDelta func(string) error) (string, error) {
+	return streamChatCompletion(ctx, p.Name(), p.baseURL+"/chat/completions", p.model, messages, systemPrompt, map[string]string{
+		"Authorization": "Bearer " + p.apiKey,
+		"HTTP-Referer":  "https://github.com/biodoia/skagent",
+		"X-Title":       "SkAgent",
+	}, onDelta)
+}
+
+// Stream implements StreamingProvider
+func (p *GenericOpenAIProvider) Stream(ctx context.Context, messages []Message, systemPrompt string, onDelta func(string) error) (string, error) {
+	return streamChatCompletion(ctx, p.Name(), p.baseURL+"/chat/completions", p.model, messages, systemPrompt, map[string]string{
+		"Authorization": "Bearer " + p.apiKey,
+	}, onDelta)
+}
+
+// streamChatCompletion runs an OpenAI-compatible chat completion with
+// "stream": true and reads the server-sent events it answers with
+func streamChatCompletion(ctx context.Context, provider, url, model string, messages []Message, systemPrompt string, headers map[string]string, onDelta func(string) error) (string, error) {
+	var reqMessages []map[string]string
+	if systemPrompt != "" {
+		reqMessages = append(reqMessages, map[string]string{
+			"role":    "system",
+			"content": systemPrompt,
+		})
+	}
+	for _, msg := range messages {
+		reqMessages = append(reqMessages, map[string]string{
+			"role":    msg.Role,
+			"content": msg.Content,
+		})
+	}
+
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model":    model,
+		"messages": reqMessages,
+		"stream":   true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return "", apiError(provider, resp, body)
+	}
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			// Comments (OpenRouter sends ": PROCESSING"), event names, blanks
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return full.String(), fmt.Errorf("malformed stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return full.String(), fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		// The reason comes with the last chunk, which may carry text too
+		if reason := chunk.Choices[0].FinishReason; reason != "" {
+			RecordFinish(ctx, reason)
+		}
+		if chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		full.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return full.String(), err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return full.String(), err
+	}
+	if full.Len() == 0 {
+		return "", fmt.Errorf("no response from model")
+	}
+	return full.String(), nil
+}
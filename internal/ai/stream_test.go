@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestGenericOpenAIStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": PROCESSING\n\n")
+		for _, piece := range []string{"Hel", "lo", "!"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", piece)
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"length\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := NewGenericOpenAIProvider("test", config.ProviderConfig{BaseURL: srv.URL}, "m")
+	var deltas []string
+	var finish Finish
+	full, err := CompleteStream(WithFinish(context.Background(), &finish), p, []Message{{Role: "user", Content: "hi"}}, "", func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full != "Hello!" || strings.Join(deltas, "|") != "Hel|lo|!" {
+		t.Fatalf("got %q from deltas %q", full, deltas)
+	}
+	if !finish.Truncated() {
+		t.Errorf("finish = %+v, want the length reason of the last chunk", finish)
+	}
+}
+
+func TestRateLimitError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "12")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"Rate limit exceeded: free-models-per-min","metadata":{"headers":{"X-RateLimit-Limit":"20","X-RateLimit-Remaining":"0","X-RateLimit-Reset":"1741305600000"}}}}`)
+	}))
+	defer srv.Close()
+
+	p := NewOpenRouterProvider(config.ProviderConfig{BaseURL: srv.URL, APIKey: "k"})
+	for name, call := range map[string]func() error{
+		"complete": func() error {
+			_, err := p.Complete(context.Background(), []Message{{Role: "user", Content: "hi"}}, "")
+			return err
+		},
+		"stream": func() error {
+			_, err := p.Stream(context.Background(), []Message{{Role: "user", Content: "hi"}}, "", func(string) error { return nil })
+			return err
+		},
+	} {
+		err := call()
+		rl, ok := AsRateLimit(err)
+		if !ok {
+			t.Fatalf("%s: err = %v, want a rate limit error", name, err)
+		}
+		if rl.Limit.Limit != 20 || rl.Limit.Remaining != 0 || rl.Message != "Rate limit exceeded: free-models-per-min" {
+			t.Errorf("%s: got %+v", name, rl)
+		}
+		// Retry-After wins over the reset time in the body
+		if wait := rl.Limit.RetryAfter(time.Now()); wait <= 10*time.Second || wait > 12*time.Second {
+			t.Errorf("%s: retry after %v, want about 12s", name, wait)
+		}
+		if !strings.Contains(err.Error(), "429") {
+			t.Errorf("%s: %q does not mention 429, so retry would not catch it", name, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Package artifacts stores the files that agents produce for tasks, such
+// as build outputs, reports and patches.
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxSize is the largest artifact accepted when no limit is set
+const DefaultMaxSize int64 = 32 << 20
+
+var (
+	// ErrNotFound is returned for an unknown artifact ID
+	ErrNotFound = errors.New("artifact not found")
+	// ErrTooLarge is returned when content exceeds the store's size limit
+	ErrTooLarge = errors.New("artifact too large")
+)
+
+// Artifact describes a stored file; it is what tasks record
+type Artifact struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"task_id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store keeps artifact content and metadata. LocalStore is the built-in
+// implementation; an object store can be plugged in behind the same
+// interface.
+type Store interface {
+	// Put stores the content of r, returning ErrTooLarge when it exceeds
+	// the size limit
+	Put(taskID, name, contentType string, r io.Reader) (*Artifact, error)
+	// Open returns an artifact's metadata and content; the caller closes
+	// the reader
+	Open(id string) (*Artifact, io.ReadCloser, error)
+	// Delete removes an artifact
+	Delete(id string) error
+}
+
+// LocalStore keeps each artifact in its own directory holding the content
+// ("data") and its metadata ("meta.json")
+type LocalStore struct {
+	dir     string
+	maxSize int64
+}
+
+// NewLocalStore creates the directory if needed. A maxSize of 0 uses
+// DefaultMaxSize.
+func NewLocalStore(dir string, maxSize int64) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating artifact directory: %w", err)
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &LocalStore{dir: dir, maxSize: maxSize}, nil
+}
+
+// MaxSize returns the largest artifact the store accepts
+func (s *LocalStore) MaxSize() int64 { return s.maxSize }
+
+// path returns the directory of an artifact. IDs are UUIDs, which also
+// keeps them from escaping the store directory.
+func (s *LocalStore) path(id string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dir, id), nil
+}
+
+// Put implements Store
+func (s *LocalStore) Put(taskID, name, contentType string, r io.Reader) (*Artifact, error) {
+	name = CleanName(name)
+	if name == "" {
+		return nil, fmt.Errorf("artifact name is required")
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	a := &Artifact{
+		ID:          uuid.New().String(),
+		TaskID:      taskID,
+		Name:        name,
+		ContentType: contentType,
+		CreatedAt:   time.Now(),
+	}
+
+	// Write into a temporary directory and rename it into place, so that
+	// readers never see a partial artifact
+	tmp, err := os.MkdirTemp(s.dir, ".upload-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	f, err := os.OpenFile(filepath.Join(tmp, "data"), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, hash), io.LimitReader(r, s.maxSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n > s.maxSize {
+		return nil, ErrTooLarge
+	}
+	a.Size = n
+	a.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	meta, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "meta.json"), meta, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, a.ID)); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Open implements Store
+func (s *LocalStore) Open(id string) (*Artifact, io.ReadCloser, error) {
+	a, err := s.Stat(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, _ := s.path(id)
+	f, err := os.Open(filepath.Join(dir, "data"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	return a, f, nil
+}
+
+// Stat returns an artifact's metadata without opening its content
+func (s *LocalStore) Stat(id string) (*Artifact, error) {
+	dir, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var a Artifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("reading artifact %s: %w", id, err)
+	}
+	return &a, nil
+}
+
+// List returns the metadata of every artifact of a task, oldest first
+func (s *LocalStore) List(taskID string) ([]*Artifact, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var list []*Artifact
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		a, err := s.Stat(e.Name())
+		if err != nil || a.TaskID != taskID {
+			continue
+		}
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+// Delete implements Store
+func (s *LocalStore) Delete(id string) error {
+	dir, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return os.RemoveAll(dir)
+}
+
+// CleanName reduces an uploaded file name to its base name, so that it is
+// safe to offer back in a Content-Disposition header
+func CleanName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base(strings.TrimSpace(name))
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,52 @@
+package artifacts
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := store.Put("task-1", "../../etc/report.txt", "", strings.NewReader("all green"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "report.txt" || a.Size != 9 || a.ContentType != "application/octet-stream" {
+		t.Fatalf("unexpected metadata %+v", a)
+	}
+
+	got, content, err := store.Open(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(content)
+	content.Close()
+	if string(data) != "all green" || got.SHA256 != a.SHA256 {
+		t.Fatalf("read back %q, %+v", data, got)
+	}
+
+	if _, err := store.Put("task-1", "big.bin", "", strings.NewReader(strings.Repeat("x", 17))); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("oversized upload: got %v", err)
+	}
+	if list, _ := store.List("task-1"); len(list) != 1 {
+		t.Fatalf("listed %d artifacts, want 1", len(list))
+	}
+
+	for _, id := range []string{"../" + a.ID, "missing"} {
+		if _, _, err := store.Open(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q): got %v", id, err)
+		}
+	}
+	if err := store.Delete(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Stat(a.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted artifact still readable: %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+// Package audit keeps an append-only record of who changed what and when:
+// every mutating API request, tool call and shutdown, one JSON object per
+// line.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Record after Close
+var ErrClosed = errors.New("audit log closed")
+
+// DefaultLimit is how many entries Query returns when the filter sets none
+const DefaultLimit = 100
+
+// memoryCapacity bounds the entries kept by a log without a file
+const memoryCapacity = 10000
+
+// Entry is one audited operation
+type Entry struct {
+	// Seq orders the entries of a log; it starts at 1
+	Seq  int64     `json:"seq"`
+	Time time.Time `json:"time"`
+	// Source is where the operation came from: "rest", "mcp" or "signal"
+	Source string `json:"source"`
+	// Actor is the API key name, "anonymous" without authentication, or
+	// "system"
+	Actor string `json:"actor"`
+	Role  string `json:"role,omitempty"`
+	// Action is "create", "update", "delete", "execute", "shutdown" or
+	// the operation a route names, such as "start" or "reload"
+	Action     string `json:"action"`
+	Resource   string `json:"resource"`
+	ResourceID string `json:"resource_id,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Path       string `json:"path,omitempty"`
+	// Status is the HTTP status of the response; 0 for operations that
+	// had none
+	Status     int    `json:"status,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	Details    string `json:"details,omitempty"`
+}
+
+// Failed reports whether the operation was refused or went wrong
+func (e *Entry) Failed() bool {
+	return e.Status >= 400
+}
+
+// Filter selects entries; zero fields match everything
+type Filter struct {
+	Actor      string
+	Action     string
+	Resource   string
+	ResourceID string
+	Source     string
+	Since      time.Time
+	Until      time.Time
+	// AfterSeq skips the entries up to and including this sequence number
+	AfterSeq int64
+	// Failed, when set, keeps only failed (true) or successful (false)
+	// operations
+	Failed *bool
+	// Limit keeps the most recent matching entries; 0 uses DefaultLimit
+	Limit int
+}
+
+// Matches reports whether e passes the filter
+func (f *Filter) Matches(e *Entry) bool {
+	switch {
+	case f.Actor != "" && e.Actor != f.Actor,
+		f.Action != "" && e.Action != f.Action,
+		f.Resource != "" && e.Resource != f.Resource,
+		f.ResourceID != "" && e.ResourceID != f.ResourceID,
+		f.Source != "" && e.Source != f.Source,
+		!f.Since.IsZero() && e.Time.Before(f.Since),
+		!f.Until.IsZero() && e.Time.After(f.Until),
+		e.Seq <= f.AfterSeq,
+		f.Failed != nil && e.Failed() != *f.Failed:
+		return false
+	}
+	return true
+}
+
+// Log appends entries to a JSONL file. It is safe for concurrent use.
+type Log struct {
+	path string
+
+	mu     sync.Mutex
+	file   *os.File
+	seq    int64
+	closed bool
+	// memory holds the entries of a log without a file
+	memory []Entry
+}
+
+// Open opens the log at path for appending, creating it if needed. An
+// empty path keeps the most recent entries in memory only.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path}
+	if path == "" {
+		return l, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	// Continue the sequence of the entries already in the file
+	err = scan(f, func(e *Entry) bool {
+		l.seq = e.Seq
+		return true
+	})
+	if err == nil {
+		err = endLine(f)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	l.file = f
+	return l, nil
+}
+
+// endLine terminates a torn last line, so that the next entry starts on a
+// line of its own
+func endLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] != '\n' {
+		_, err = f.Write([]byte{'\n'})
+	}
+	return err
+}
+
+// Record appends e, setting its sequence number and, when it is zero, its
+// time
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	e.Seq = l.seq + 1
+	if l.file == nil {
+		l.memory = append(l.memory, e)
+		if len(l.memory) > memoryCapacity {
+			l.memory = append(l.memory[:0:0], l.memory[len(l.memory)-memoryCapacity:]...)
+		}
+		l.seq = e.Seq
+		return nil
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	l.seq = e.Seq
+	return nil
+}
+
+// Query returns the most recent entries matching f, oldest first
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	var out []Entry
+	keep := func(e *Entry) bool {
+		if f.Matches(e) {
+			out = append(out, *e)
+			// Trim as we go, so that a long log is never held whole
+			if len(out) >= 2*limit {
+				out = append(out[:0], out[len(out)-limit:]...)
+			}
+		}
+		return true
+	}
+
+	l.mu.Lock()
+	if l.file == nil {
+		for i := range l.memory {
+			keep(&l.memory[i])
+		}
+		l.mu.Unlock()
+	} else {
+		// Read through a separate handle, so that writers only wait for
+		// the file to be opened
+		l.mu.Unlock()
+		file, err := os.Open(l.path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if err := scan(file, keep); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return out, nil
+}
+
+// Close flushes and closes the file; later records fail with ErrClosed
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// scan calls fn with each entry of r until it returns false. Lines that
+// do not parse, such as one torn by a crash mid-write, are skipped.
+func scan(r io.Reader, fn func(*Entry) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		var e Entry
+		if len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &e) == nil {
+			if !fn(&e) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
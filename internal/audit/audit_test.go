@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLogPersistsAndFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Record(Entry{Source: "rest", Actor: "ci", Action: "create", Resource: "agents", ResourceID: "a1", Status: 201})
+	l.Record(Entry{Source: "mcp", Actor: "bot", Action: "execute", Resource: "tools", ResourceID: "list_agents", Status: 403})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Record(Entry{Action: "late"}); err != ErrClosed {
+		t.Errorf("record after close: %v", err)
+	}
+
+	// A crash left half a line behind
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	f.WriteString(`{"seq":3,"actor":"to`)
+	f.Close()
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := l.Record(Entry{Source: "signal", Actor: "system", Action: "shutdown", Resource: "system"}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[2].Seq != 3 || all[2].Action != "shutdown" {
+		t.Fatalf("entries = %+v", all)
+	}
+
+	failed := true
+	cases := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"actor", Filter{Actor: "ci"}, 1},
+		{"failed", Filter{Failed: &failed}, 1},
+		{"after", Filter{AfterSeq: 1}, 2},
+		{"limit keeps the newest", Filter{Limit: 1}, 1},
+		{"until", Filter{Until: time.Now().Add(-time.Hour)}, 0},
+	}
+	for _, c := range cases {
+		got, _ := l.Query(c.filter)
+		if len(got) != c.want {
+			t.Errorf("%s: %d entries, want %d", c.name, len(got), c.want)
+		}
+	}
+	if got, _ := l.Query(Filter{Limit: 1}); got[0].Seq != 3 {
+		t.Errorf("limit kept seq %d", got[0].Seq)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	params := map[string]string{"agentID": "a1", "taskID": "t1", "toolName": "github", "lessonID": "l1"}
+	param := func(k string) string { return params[k] }
+	cases := []struct {
+		method, pattern              string
+		action, resource, resourceID string
+	}{
+		{"POST", "/api/v1/agents/", "create", "agents", ""},
+		{"PUT", "/api/v1/agents/{agentID}", "update", "agents", "a1"},
+		{"DELETE", "/tasks/{taskID}", "delete", "tasks", "t1"},
+		{"POST", "/api/v1/agents/{agentID}/start", "start", "agents", "a1"},
+		{"POST", "/api/v1/tasks/bulk", "bulk", "tasks", ""},
+		{"POST", "/api/v1/tools/{toolName}/execute", "execute", "tools", "github"},
+		{"POST", "/tools/{toolName}/call", "execute", "tools", "github"},
+		{"POST", "/api/v1/system/shutdown", "shutdown", "system", ""},
+		{"POST", "/api/v1/system/config/reload", "reload", "system", ""},
+		{"DELETE", "/api/v1/agents/{agentID}/lessons/{lessonID}", "delete", "lessons", "l1"},
+	}
+	for _, c := range cases {
+		action, resource, id := Describe(c.method, c.pattern, param)
+		if action != c.action || resource != c.resource || id != c.resourceID {
+			t.Errorf("%s %s = %s %s %s, want %s %s %s", c.method, c.pattern, action, resource, id, c.action, c.resource, c.resourceID)
+		}
+	}
+}
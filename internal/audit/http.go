@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/server/requestid"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// actionAliases gives operations that are the same under different route
+// names one action
+var actionAliases = map[string]string{
+	"call": "execute",
+}
+
+// Middleware records every request that may change state, that is every
+// method but GET, HEAD and OPTIONS, once its response is written. It runs
+// inside the router, after authentication, so that the route and the
+// caller are known; requests that match no route are not recorded.
+func Middleware(l *Log, source string, logger *log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			rctx := chi.RouteContext(r.Context())
+			if rctx == nil || rctx.RoutePattern() == "" {
+				return
+			}
+			action, resource, id := Describe(r.Method, rctx.RoutePattern(), rctx.URLParam)
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			e := Entry{
+				Source:     source,
+				Actor:      "anonymous",
+				Action:     action,
+				Resource:   resource,
+				ResourceID: id,
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Status:     status,
+				RequestID:  requestid.FromContext(r.Context()),
+				RemoteAddr: r.RemoteAddr,
+			}
+			if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+				e.Actor, e.Role = p.Name, string(p.Role)
+			}
+			if err := l.Record(e); err != nil && logger != nil {
+				logger.Printf("ERROR: Failed to record %s %s in the audit log: %v", r.Method, r.URL.Path, err)
+			}
+		})
+	}
+}
+
+// Describe names the operation a request performs from its method and
+// route pattern. The resource is the innermost collection the route
+// addresses and id the parameter that follows it. POST creates when the
+// route ends at a collection and otherwise performs the operation the
+// route ends with, such as "start" in /agents/{agentID}/start or
+// "lessons" in /agents/{agentID}/lessons.
+func Describe(method, pattern string, param func(string) string) (action, resource, id string) {
+	pattern = strings.TrimPrefix(pattern, "/api/v1")
+	var segments []string
+	for _, s := range strings.Split(pattern, "/") {
+		if s != "" && s != "*" {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) == 0 {
+		return strings.ToLower(method), "", ""
+	}
+
+	isParam := func(s string) bool { return strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") }
+	resource = segments[0]
+	for i, s := range segments {
+		if isParam(s) {
+			continue
+		}
+		if i+1 < len(segments) && isParam(segments[i+1]) {
+			resource = s
+			id = param(strings.Trim(segments[i+1], "{}"))
+		}
+	}
+
+	last := segments[len(segments)-1]
+	switch {
+	case method == http.MethodDelete:
+		action = "delete"
+	case method == http.MethodPut || method == http.MethodPatch:
+		action = "update"
+	case method == http.MethodPost && (len(segments) == 1 || last == resource || isParam(last)):
+		action = "create"
+	case isParam(last):
+		action = strings.ToLower(method)
+	default:
+		action = last
+	}
+	if alias, ok := actionAliases[action]; ok {
+		action = alias
+	}
+	return action, resource, id
+}
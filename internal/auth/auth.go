@@ -0,0 +1,232 @@
+// Package auth implements API key authentication and role-based access
+// control for the REST and MCP servers.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+)
+
+// Role names a set of permissions granted to an API key
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+// Permission is a "resource:action" pair checked before an operation runs
+type Permission string
+
+const (
+	PermAgentsRead      Permission = "agents:read"
+	PermAgentsWrite     Permission = "agents:write"
+	PermAgentsControl   Permission = "agents:control"
+	PermTasksRead       Permission = "tasks:read"
+	PermTasksWrite      Permission = "tasks:write"
+	PermToolsRead       Permission = "tools:read"
+	PermToolsExecute    Permission = "tools:execute"
+	PermSessionsRead    Permission = "sessions:read"
+	PermSessionsWrite   Permission = "sessions:write"
+	PermProjectRead     Permission = "project:read"
+	PermProjectWrite    Permission = "project:write"
+	PermWebhooksRead    Permission = "webhooks:read"
+	PermWebhooksWrite   Permission = "webhooks:write"
+	PermWorkspacesRead  Permission = "workspaces:read"
+	PermWorkspacesWrite Permission = "workspaces:write"
+	PermSystemRead      Permission = "system:read"
+	PermSystemAdmin     Permission = "system:admin"
+)
+
+// DefaultRoles are the built-in role definitions. Entries in the auth.roles
+// config section replace or extend them.
+var DefaultRoles = map[Role][]Permission{
+	RoleViewer: {
+		"agents:read", "tasks:read", "tools:read", "sessions:read", "project:read", "system:read",
+		"workspaces:read",
+	},
+	RoleOperator: {
+		"agents:read", "agents:write", "agents:control",
+		"tasks:read", "tasks:write",
+		"tools:read", "tools:execute",
+		"sessions:read", "sessions:write",
+		"project:read", "project:write",
+		"webhooks:read", "webhooks:write",
+		"workspaces:read",
+		"system:read",
+	},
+	RoleAdmin: {"*"},
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+	// Workspaces are the workspaces the key is bound to; none means all
+	Workspaces []string `json:"workspaces,omitempty"`
+}
+
+// Bound reports whether the principal is limited to some workspaces
+func (p Principal) Bound() bool {
+	return len(p.Workspaces) > 0
+}
+
+// CanAccess reports whether the principal may use workspace
+func (p Principal) CanAccess(workspace string) bool {
+	if !p.Bound() {
+		return true
+	}
+	for _, ws := range p.Workspaces {
+		if ws == workspace {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccess reports whether the caller of ctx may use workspace. Without
+// a principal, as when authentication is off, every workspace is open.
+func CanAccess(ctx context.Context, workspace string) bool {
+	p, ok := PrincipalFromContext(ctx)
+	return !ok || p.CanAccess(workspace)
+}
+
+type principalKey struct{}
+
+// WithPrincipal attaches a principal to a context
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal attached to a context
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+type apiKey struct {
+	name       string
+	token      []byte
+	role       Role
+	workspaces []string
+}
+
+// Authorizer resolves API keys to principals and checks permissions
+type Authorizer struct {
+	keys   []apiKey
+	roles  map[Role][]Permission
+	logger *log.Logger
+}
+
+// New builds an authorizer from the auth config section
+func New(cfg config.AuthConfig) (*Authorizer, error) {
+	a := &Authorizer{
+		roles:  make(map[Role][]Permission),
+		logger: logging.New("audit", "[AUDIT] ", log.Writer()),
+	}
+	for role, perms := range DefaultRoles {
+		a.roles[role] = perms
+	}
+	for name, perms := range cfg.Roles {
+		list := make([]Permission, len(perms))
+		for i, p := range perms {
+			list[i] = Permission(p)
+		}
+		a.roles[Role(name)] = list
+	}
+
+	names := make([]string, 0, len(cfg.Keys))
+	for name := range cfg.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key := cfg.Keys[name]
+		if key.Token == "" {
+			return nil, fmt.Errorf("auth key %q has no token", name)
+		}
+		role := Role(key.Role)
+		if _, ok := a.roles[role]; !ok {
+			return nil, fmt.Errorf("auth key %q has unknown role %q", name, key.Role)
+		}
+		a.keys = append(a.keys, apiKey{name: name, token: []byte(key.Token), role: role, workspaces: key.Workspaces})
+	}
+	return a, nil
+}
+
+// Authenticate resolves a token to the principal it belongs to
+func (a *Authorizer) Authenticate(token string) (Principal, bool) {
+	if token == "" {
+		return Principal{}, false
+	}
+	// Compare against every key so timing does not reveal which one matched
+	var found *apiKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(a.keys[i].token, []byte(token)) == 1 {
+			found = &a.keys[i]
+		}
+	}
+	if found == nil {
+		return Principal{}, false
+	}
+	return Principal{Name: found.name, Role: found.role, Workspaces: found.workspaces}, true
+}
+
+// Allowed reports whether a role grants a permission. Grants may use
+// wildcards: "*" for everything or "agents:*" for every agent action.
+func (a *Authorizer) Allowed(role Role, perm Permission) bool {
+	resource := strings.SplitN(string(perm), ":", 2)[0]
+	for _, grant := range a.roles[role] {
+		if grant == "*" || grant == perm || string(grant) == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Audit records an authorization decision. Denials are always logged;
+// allowed calls are logged only for permissions that change state.
+func (a *Authorizer) Audit(p Principal, perm Permission, allowed bool, target string) {
+	if allowed {
+		if strings.HasSuffix(string(perm), ":read") {
+			return
+		}
+		a.logger.Printf("INFO: allow key=%s role=%s perm=%s target=%s", p.Name, p.Role, perm, target)
+		return
+	}
+	a.logger.Printf("WARN: deny key=%s role=%s perm=%s target=%s", p.Name, p.Role, perm, target)
+}
+
+// AuditUnauthenticated records a request rejected for a missing or
+// unknown API key
+func (a *Authorizer) AuditUnauthenticated(target string) {
+	a.logger.Printf("WARN: deny unauthenticated target=%s", target)
+}
+
+// Check combines Allowed and Audit for a single operation
+func (a *Authorizer) Check(p Principal, perm Permission, target string) bool {
+	allowed := a.Allowed(p.Role, perm)
+	a.Audit(p, perm, allowed, target)
+	return allowed
+}
+
+// TokenFromRequest extracts an API key from the Authorization bearer
+// header or the X-API-Key header
+func TokenFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+			return strings.TrimSpace(h[7:])
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestAuthorizer_Roles(t *testing.T) {
+	a, err := New(config.AuthConfig{
+		Keys: map[string]config.APIKeyConfig{
+			"dashboard": {Token: "view-token", Role: "viewer"},
+			"ci":        {Token: "ops-token", Role: "operator"},
+			"oncall":    {Token: "root-token", Role: "admin"},
+			"bot":       {Token: "bot-token", Role: "tasker"},
+		},
+		Roles: map[string][]string{"tasker": {"tasks:*"}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		token string
+		perm  Permission
+		want  bool
+	}{
+		{"view-token", PermAgentsRead, true},
+		{"view-token", PermAgentsControl, false},
+		{"view-token", PermToolsExecute, false},
+		{"ops-token", PermAgentsControl, true},
+		{"ops-token", PermToolsExecute, true},
+		{"ops-token", PermSystemAdmin, false},
+		{"root-token", PermSystemAdmin, true},
+		{"bot-token", PermTasksWrite, true},
+		{"bot-token", PermAgentsRead, false},
+	}
+	for _, tt := range tests {
+		p, ok := a.Authenticate(tt.token)
+		if !ok {
+			t.Fatalf("Authenticate(%q) failed", tt.token)
+		}
+		if got := a.Allowed(p.Role, tt.perm); got != tt.want {
+			t.Errorf("%s (%s) Allowed(%s) = %v, want %v", p.Name, p.Role, tt.perm, got, tt.want)
+		}
+	}
+
+	if _, ok := a.Authenticate("wrong"); ok {
+		t.Error("Authenticate accepted an unknown token")
+	}
+}
+
+func TestNew_RejectsUnknownRole(t *testing.T) {
+	_, err := New(config.AuthConfig{
+		Keys: map[string]config.APIKeyConfig{"x": {Token: "t", Role: "superuser"}},
+	})
+	if err == nil {
+		t.Error("New() accepted an undefined role")
+	}
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer abc")
+	if got := TokenFromRequest(r); got != "abc" {
+		t.Errorf("bearer token = %q", got)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-API-Key", "def")
+	if got := TokenFromRequest(r); got != "def" {
+		t.Errorf("X-API-Key token = %q", got)
+	}
+}
@@ -0,0 +1,461 @@
+// Package backup creates and restores archives of skagent configuration
+// and persisted state.
+//
+// An archive is a gzipped tar file. Every file is stored under a directory
+// named after its component ("config" or "state") and the archive ends
+// with MANIFEST.json, which records a SHA-256 checksum for each file.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// FormatVersion is the archive layout version written to the manifest
+const FormatVersion = 1
+
+// ManifestName is the name of the manifest entry, always the last one
+const ManifestName = "MANIFEST.json"
+
+// RedactedValue replaces credentials in a redacted backup
+const RedactedValue = "[REDACTED]"
+
+// Component groups the files of an archive by where they are restored
+type Component string
+
+const (
+	// ComponentConfig is the configuration directory (~/.config/skagent):
+	// config, profiles, headless settings and themes
+	ComponentConfig Component = "config"
+	// ComponentState is the data directory: registry snapshots, sessions,
+	// artifacts and project manager state
+	ComponentState Component = "state"
+)
+
+// Source is a directory backed up as one component
+type Source struct {
+	Component Component
+	Root      string
+}
+
+// FileEntry describes one file in an archive
+type FileEntry struct {
+	Component Component `json:"component"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	Mode      uint32    `json:"mode"`
+	SHA256    string    `json:"sha256"`
+	Redacted  bool      `json:"redacted,omitempty"`
+}
+
+// Manifest describes the contents of an archive
+type Manifest struct {
+	FormatVersion  int         `json:"format_version"`
+	CreatedAt      time.Time   `json:"created_at"`
+	SkagentVersion string      `json:"skagent_version,omitempty"`
+	Redacted       bool        `json:"redacted"`
+	Files          []FileEntry `json:"files"`
+}
+
+// DefaultSources returns the configuration and data directories
+func DefaultSources() ([]Source, error) {
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return []Source{
+		{Component: ComponentConfig, Root: configDir},
+		{Component: ComponentState, Root: dataDir},
+	}, nil
+}
+
+// CreateOptions controls Create
+type CreateOptions struct {
+	Sources []Source
+	// Redact replaces credentials in JSON config files with RedactedValue
+	Redact bool
+	// Version is recorded in the manifest
+	Version string
+}
+
+// Create writes an archive of every regular file under the sources to w.
+// Missing source directories are skipped.
+func Create(w io.Writer, opts CreateOptions) (*Manifest, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := &Manifest{
+		FormatVersion:  FormatVersion,
+		CreatedAt:      time.Now().UTC(),
+		SkagentVersion: opts.Version,
+		Redacted:       opts.Redact,
+		Files:          []FileEntry{},
+	}
+
+	for _, src := range opts.Sources {
+		if err := addSource(tw, manifest, src, opts.Redact); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, ManifestName, 0o644, data); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func addSource(tw *tar.Writer, m *Manifest, src Source, redactSecrets bool) error {
+	if _, err := os.Stat(src.Root); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return filepath.WalkDir(src.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src.Root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		entry := FileEntry{
+			Component: src.Component,
+			Path:      rel,
+			Mode:      uint32(info.Mode().Perm()),
+		}
+		if redactSecrets && src.Component == ComponentConfig && strings.HasSuffix(rel, ".json") {
+			if redacted, changed, err := redactJSON(data); err == nil && changed {
+				data = redacted
+				entry.Redacted = true
+			}
+		}
+
+		sum := sha256.Sum256(data)
+		entry.SHA256 = hex.EncodeToString(sum[:])
+		entry.Size = int64(len(data))
+
+		if err := writeEntry(tw, path.Join(string(src.Component), rel), info.Mode().Perm(), data); err != nil {
+			return err
+		}
+		m.Files = append(m.Files, entry)
+		return nil
+	})
+}
+
+func writeEntry(tw *tar.Writer, name string, mode fs.FileMode, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(mode),
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// redactJSON replaces credential values in a JSON document
+func redactJSON(data []byte) ([]byte, bool, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false, err
+	}
+	changed := walkSecrets(doc, "", func(string, string) (string, bool) {
+		return RedactedValue, true
+	})
+	if !changed {
+		return data, false, nil
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	return out, true, err
+}
+
+// walkSecrets calls fn for every non-empty string stored under a secret
+// key and replaces it when fn returns true
+func walkSecrets(v interface{}, prefix string, fn func(path, value string) (string, bool)) bool {
+	changed := false
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			p := k
+			if prefix != "" {
+				p = prefix + "." + k
+			}
+			if s, ok := child.(string); ok && s != "" && config.IsSecretPath(p) {
+				if repl, ok := fn(p, s); ok {
+					node[k] = repl
+					changed = true
+				}
+				continue
+			}
+			if walkSecrets(child, p, fn) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, child := range node {
+			if walkSecrets(child, fmt.Sprintf("%s[%d]", prefix, i), fn) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// Verify reads an archive and checks every file against the manifest
+func Verify(archive string) (*Manifest, error) {
+	sums := make(map[string]string)
+	var manifest *Manifest
+
+	err := walkArchive(archive, func(name string, r io.Reader) error {
+		if name == ManifestName {
+			manifest = &Manifest{}
+			return json.NewDecoder(r).Decode(manifest)
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("archive has no %s", ManifestName)
+	}
+	if manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("archive format %d is newer than supported format %d", manifest.FormatVersion, FormatVersion)
+	}
+
+	var problems []string
+	for _, f := range manifest.Files {
+		name := path.Join(string(f.Component), f.Path)
+		got, ok := sums[name]
+		switch {
+		case !ok:
+			problems = append(problems, name+": missing")
+		case got != f.SHA256:
+			problems = append(problems, name+": checksum mismatch")
+		}
+		delete(sums, name)
+	}
+	for name := range sums {
+		problems = append(problems, name+": not listed in manifest")
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return manifest, fmt.Errorf("archive failed verification: %s", strings.Join(problems, "; "))
+	}
+	return manifest, nil
+}
+
+// RestoreOptions controls Restore
+type RestoreOptions struct {
+	// Targets maps each component to the directory it is restored into;
+	// components without a target are skipped
+	Targets map[Component]string
+	// Force overwrites existing files
+	Force bool
+}
+
+// Restore verifies an archive and writes its files to the target
+// directories. Nothing is written if verification fails or, without
+// Force, if any file already exists. Credentials in a redacted backup are
+// taken from the existing file when present and left empty otherwise.
+func Restore(archive string, opts RestoreOptions) (*Manifest, error) {
+	manifest, err := Verify(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]FileEntry, len(manifest.Files))
+	var conflicts []string
+	for _, f := range manifest.Files {
+		root, ok := opts.Targets[f.Component]
+		if !ok {
+			continue
+		}
+		dest, err := safeJoin(root, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(dest); err == nil && !opts.Force {
+			conflicts = append(conflicts, dest)
+		}
+		entries[path.Join(string(f.Component), f.Path)] = f
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("refusing to overwrite %d existing files (use --force): %s",
+			len(conflicts), strings.Join(conflicts, ", "))
+	}
+
+	err = walkArchive(archive, func(name string, r io.Reader) error {
+		f, ok := entries[name]
+		if !ok {
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		dest, _ := safeJoin(opts.Targets[f.Component], f.Path)
+		if f.Redacted {
+			data = restoreSecrets(data, dest)
+		}
+		return writeFileAtomic(dest, data, fs.FileMode(f.Mode))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// restoreSecrets fills redacted credentials from the file being replaced
+func restoreSecrets(data []byte, dest string) []byte {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return data
+	}
+
+	existing := make(map[string]interface{})
+	if old, err := os.ReadFile(dest); err == nil {
+		var oldDoc interface{}
+		if json.Unmarshal(old, &oldDoc) == nil {
+			walkSecrets(oldDoc, "", func(p, v string) (string, bool) {
+				existing[p] = v
+				return "", false
+			})
+		}
+	}
+
+	walkSecrets(doc, "", func(p, v string) (string, bool) {
+		if v != RedactedValue {
+			return "", false
+		}
+		if old, ok := existing[p].(string); ok {
+			return old, true
+		}
+		return "", true
+	})
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+func walkArchive(archive string, fn func(name string, r io.Reader) error) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(hdr.Name, tr); err != nil {
+			return fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+	}
+}
+
+// safeJoin joins an archive path to a root, rejecting paths that escape it
+func safeJoin(root, rel string) (string, error) {
+	clean := path.Clean("/" + rel)
+	if clean == "/" || rel != strings.TrimPrefix(clean, "/") {
+		return "", fmt.Errorf("unsafe path in archive: %q", rel)
+	}
+	return filepath.Join(root, filepath.FromSlash(clean)), nil
+}
+
+func writeFileAtomic(dest string, data []byte, mode fs.FileMode) error {
+	if mode == 0 {
+		mode = 0o600
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
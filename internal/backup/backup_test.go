@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createArchive(t *testing.T, sources []Source, redact bool) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Create(f, CreateOptions{Sources: sources, Redact: redact}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	f.Close()
+	return archive
+}
+
+func TestCreateVerifyRestore(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "config.json"), `{"theme":"dark"}`)
+	writeFile(t, filepath.Join(src, "profiles", "work.json"), `{"api":{"port":9000}}`)
+
+	archive := createArchive(t, []Source{
+		{Component: ComponentConfig, Root: src},
+		{Component: ComponentState, Root: filepath.Join(src, "missing")},
+	}, false)
+
+	m, err := Verify(archive)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if len(m.Files) != 2 {
+		t.Fatalf("manifest lists %d files, want 2", len(m.Files))
+	}
+
+	dst := t.TempDir()
+	targets := map[Component]string{ComponentConfig: dst}
+	if _, err := Restore(archive, RestoreOptions{Targets: targets}); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	got, _ := os.ReadFile(filepath.Join(dst, "profiles", "work.json"))
+	if string(got) != `{"api":{"port":9000}}` {
+		t.Errorf("restored profile = %q", got)
+	}
+
+	if _, err := Restore(archive, RestoreOptions{Targets: targets}); err == nil {
+		t.Error("Restore() overwrote existing files without Force")
+	}
+	if _, err := Restore(archive, RestoreOptions{Targets: targets, Force: true}); err != nil {
+		t.Errorf("Restore(Force) error = %v", err)
+	}
+}
+
+func TestRedactedBackupKeepsExistingSecrets(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "config.json"),
+		`{"providers":{"openrouter":{"api_key":"sk-live-123456"}},"project":{"api_key":"pm-key-999"}}`)
+
+	archive := createArchive(t, []Source{{Component: ComponentConfig, Root: src}}, true)
+
+	dst := t.TempDir()
+	writeFile(t, filepath.Join(dst, "config.json"), `{"providers":{"openrouter":{"api_key":"sk-new-654321"}}}`)
+
+	if _, err := Restore(archive, RestoreOptions{Targets: map[Component]string{ComponentConfig: dst}, Force: true}); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	got, _ := os.ReadFile(filepath.Join(dst, "config.json"))
+	s := string(got)
+	if strings.Contains(s, "sk-live-123456") || strings.Contains(s, "pm-key-999") || strings.Contains(s, RedactedValue) {
+		t.Errorf("restored config leaked or kept placeholder: %s", s)
+	}
+	if !strings.Contains(s, "sk-new-654321") {
+		t.Errorf("restored config lost existing secret: %s", s)
+	}
+}
+
+func TestSafeJoinRejectsEscapes(t *testing.T) {
+	for _, p := range []string{"../etc/passwd", "/abs", "a/../../b", ""} {
+		if _, err := safeJoin("/root", p); err == nil {
+			t.Errorf("safeJoin(%q) accepted an unsafe path", p)
+		}
+	}
+}
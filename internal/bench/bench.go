@@ -0,0 +1,269 @@
+// Package bench drives the agent registry with synthetic agents and tasks
+// to measure how it behaves under load.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+)
+
+// Options sizes a benchmark run
+type Options struct {
+	// Agents is the number of synthetic agents; each works one task at a time
+	Agents int
+	// Tasks is the number of tasks queued
+	Tasks int
+	// Readers poll the registry (ListTasks, GetStats) while tasks run, as
+	// API clients and the TUI do
+	Readers int
+	// Latency is how long each mock completion takes
+	Latency time.Duration
+}
+
+// Latency summarizes a set of timings
+type Latency struct {
+	Count int           `json:"count"`
+	Mean  time.Duration `json:"mean_ns"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// Memory is the heap growth over a run
+type Memory struct {
+	HeapBefore   uint64 `json:"heap_before_bytes"`
+	HeapAfter    uint64 `json:"heap_after_bytes"`
+	TotalAlloc   uint64 `json:"total_alloc_bytes"`
+	BytesPerTask uint64 `json:"bytes_per_task"`
+	NumGC        uint32 `json:"num_gc"`
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Options Options `json:"options"`
+
+	// CreateDuration is how long queueing every task took
+	CreateDuration time.Duration `json:"create_duration_ns"`
+	CreatePerSec   float64       `json:"create_per_sec"`
+
+	// RunDuration is how long the agents took to work through the queue
+	RunDuration time.Duration `json:"run_duration_ns"`
+	// Throughput is completed tasks per second
+	Throughput float64 `json:"throughput_per_sec"`
+	Completed  int     `json:"completed"`
+	Failed     int     `json:"failed"`
+
+	// Assign and Complete time the registry calls, including the wait for
+	// the registry mutex
+	Assign   Latency `json:"assign"`
+	Complete Latency `json:"complete"`
+	// Read times the readers' calls
+	Read      Latency `json:"read"`
+	ReadCount int64   `json:"read_count"`
+
+	Memory Memory `json:"memory"`
+}
+
+// Validate reports options that cannot run
+func (o Options) Validate() error {
+	switch {
+	case o.Agents < 1:
+		return fmt.Errorf("agents must be at least 1")
+	case o.Tasks < 1:
+		return fmt.Errorf("tasks must be at least 1")
+	case o.Readers < 0:
+		return fmt.Errorf("readers must not be negative")
+	case o.Latency < 0:
+		return fmt.Errorf("latency must not be negative")
+	}
+	return nil
+}
+
+// Run creates the agents and tasks in a fresh registry, then has every
+// agent take tasks off a shared queue until it is empty
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	report := &Report{Options: opts}
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	registry := agents.NewRegistry(ctx)
+	provider := &ai.MockProvider{Latency: opts.Latency, Forget: true}
+
+	agentIDs := make([]string, opts.Agents)
+	for i := range agentIDs {
+		agent, err := registry.CreateAgent(fmt.Sprintf("bench-%d", i), string(agents.AgentTypeCoder), nil)
+		if err != nil {
+			return nil, err
+		}
+		agentIDs[i] = agent.ID
+	}
+
+	// Queue the tasks from as many goroutines as there are agents, so that
+	// creation contends for the mutex the way API clients would
+	queue := make(chan string, opts.Tasks)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Agents; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < opts.Tasks; i += opts.Agents {
+				task := registry.CreateTask(&agents.Task{
+					Title:    fmt.Sprintf("bench task %d", i),
+					Priority: agents.TaskPriority(i % 4),
+				})
+				queue <- task.ID
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(queue)
+	report.CreateDuration = time.Since(start)
+	report.CreatePerSec = perSecond(opts.Tasks, report.CreateDuration)
+
+	// Readers run until the workers finish
+	readCtx, stopReaders := context.WithCancel(ctx)
+	defer stopReaders()
+	var readers sync.WaitGroup
+	var readCount int64
+	readTimes := make([][]time.Duration, opts.Readers)
+	for r := 0; r < opts.Readers; r++ {
+		readers.Add(1)
+		go func(r int) {
+			defer readers.Done()
+			for i := 0; readCtx.Err() == nil; i++ {
+				t := time.Now()
+				if i%2 == 0 {
+					registry.ListTasks()
+				} else {
+					registry.GetStats()
+				}
+				readTimes[r] = append(readTimes[r], time.Since(t))
+				atomic.AddInt64(&readCount, 1)
+			}
+		}(r)
+	}
+
+	assignTimes := make([][]time.Duration, opts.Agents)
+	completeTimes := make([][]time.Duration, opts.Agents)
+	var failed int64
+	start = time.Now()
+	for w, agentID := range agentIDs {
+		wg.Add(1)
+		go func(w int, agentID string) {
+			defer wg.Done()
+			for taskID := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+
+				t := time.Now()
+				err := registry.AssignTask(taskID, agentID)
+				assignTimes[w] = append(assignTimes[w], time.Since(t))
+				if err != nil {
+					atomic.AddInt64(&failed, 1)
+					continue
+				}
+
+				taskStart := time.Now()
+				output, err := provider.Complete(ctx, []ai.Message{{Role: "user", Content: taskID}}, "")
+				result := &agents.TaskResult{
+					Success:   err == nil,
+					Output:    output,
+					Duration:  time.Since(taskStart).Milliseconds(),
+					Timestamp: time.Now(),
+				}
+				if err != nil {
+					result.Error = err.Error()
+					atomic.AddInt64(&failed, 1)
+				}
+
+				t = time.Now()
+				registry.CompleteTask(taskID, result)
+				completeTimes[w] = append(completeTimes[w], time.Since(t))
+			}
+		}(w, agentID)
+	}
+	wg.Wait()
+	report.RunDuration = time.Since(start)
+	stopReaders()
+	readers.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report.Assign = summarize(assignTimes)
+	report.Complete = summarize(completeTimes)
+	report.Read = summarize(readTimes)
+	report.ReadCount = readCount
+	report.Failed = int(failed)
+	report.Completed = report.Complete.Count - report.Failed
+	report.Throughput = perSecond(report.Complete.Count, report.RunDuration)
+
+	// Measure what the registry retains, not the timings or the garbage
+	assignTimes, completeTimes, readTimes = nil, nil, nil
+	runtime.GC()
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	report.Memory = Memory{
+		HeapBefore: before.HeapAlloc,
+		HeapAfter:  after.HeapAlloc,
+		TotalAlloc: after.TotalAlloc - before.TotalAlloc,
+		NumGC:      after.NumGC - before.NumGC,
+	}
+	if after.HeapAlloc > before.HeapAlloc {
+		report.Memory.BytesPerTask = (after.HeapAlloc - before.HeapAlloc) / uint64(opts.Tasks)
+	}
+	runtime.KeepAlive(registry)
+	return report, nil
+}
+
+func perSecond(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+// summarize merges per-goroutine timings into percentiles
+func summarize(groups [][]time.Duration) Latency {
+	var all []time.Duration
+	for _, g := range groups {
+		all = append(all, g...)
+	}
+	if len(all) == 0 {
+		return Latency{}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	var total time.Duration
+	for _, d := range all {
+		total += d
+	}
+	at := func(p float64) time.Duration {
+		return all[int(p*float64(len(all)-1))]
+	}
+	return Latency{
+		Count: len(all),
+		Mean:  total / time.Duration(len(all)),
+		P50:   at(0.50),
+		P90:   at(0.90),
+		P99:   at(0.99),
+		Max:   all[len(all)-1],
+	}
+}
@@ -0,0 +1,36 @@
+package bench
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	report, err := Run(context.Background(), Options{Agents: 4, Tasks: 200, Readers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Completed != 200 || report.Failed != 0 {
+		t.Fatalf("completed %d, failed %d", report.Completed, report.Failed)
+	}
+	if report.Assign.Count != 200 || report.Assign.P50 > report.Assign.Max {
+		t.Fatalf("assign latency %+v", report.Assign)
+	}
+	if report.Throughput <= 0 || report.ReadCount == 0 {
+		t.Fatalf("throughput %.1f, reads %d", report.Throughput, report.ReadCount)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, opts := range []Options{
+		{Agents: 0, Tasks: 1},
+		{Agents: 1, Tasks: 0},
+		{Agents: 1, Tasks: 1, Readers: -1},
+		{Agents: 1, Tasks: 1, Latency: -time.Second},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
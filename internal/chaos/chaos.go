@@ -0,0 +1,159 @@
+// Package chaos injects faults into AI provider and tool calls: errors,
+// rate limits, latency and truncated output, at rates set in the chaos
+// section of the configuration. It exists to exercise retries, failover
+// and guardrails in staging, and must stay off in production.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/metrics"
+)
+
+// Fault is a kind of injected fault
+type Fault string
+
+const (
+	FaultError     Fault = "error"
+	FaultRateLimit Fault = "rate_limit"
+	FaultLatency   Fault = "latency"
+	FaultTruncate  Fault = "truncate"
+)
+
+var faultsInjected = metrics.Default.NewCounter("skagent_chaos_faults_total",
+	"Faults injected by chaos mode, by component and fault.", "component", "fault")
+
+// Error is the error of a call failed on purpose. Its message carries a 503
+// so that retry policies treat it as a temporary failure.
+type Error struct {
+	Component string
+	Name      string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("chaos: injected failure of %s %s (503 Service Unavailable)", e.Component, e.Name)
+}
+
+// Plan is what an Injector decided for one call
+type Plan struct {
+	// Fail is FaultError or FaultRateLimit when the call must fail
+	Fail Fault
+	// Delay is added before the call
+	Delay time.Duration
+	// Truncate cuts the output of a successful call
+	Truncate bool
+}
+
+// Injector decides the faults of the calls of one component, such as
+// "provider" or "tool"
+type Injector struct {
+	component string
+	cfg       config.FaultConfig
+	logger    *log.Logger
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// New returns an injector for component with the rates of cfg. A zero
+// seed seeds from the clock.
+func New(component string, cfg config.FaultConfig, seed int64) *Injector {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{
+		component: component,
+		cfg:       cfg,
+		logger:    logging.New("chaos", "[CHAOS] ", log.Writer()),
+		rnd:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Plan draws the faults of a call to name. Names outside the configured
+// ones get no faults.
+func (i *Injector) Plan(name string) Plan {
+	var p Plan
+	if !i.targets(name) {
+		return p
+	}
+
+	i.mu.Lock()
+	fail, latency, truncate := i.rnd.Float64(), i.rnd.Float64(), i.rnd.Float64()
+	if latency < i.cfg.LatencyRate && i.cfg.LatencyMS > 0 {
+		p.Delay = time.Duration(i.rnd.Int63n(int64(i.cfg.LatencyMS))+1) * time.Millisecond
+	}
+	i.mu.Unlock()
+
+	switch {
+	case fail < i.cfg.ErrorRate:
+		p.Fail = FaultError
+	case fail < i.cfg.ErrorRate+i.cfg.RateLimitRate:
+		p.Fail = FaultRateLimit
+	}
+	p.Truncate = p.Fail == "" && truncate < i.cfg.TruncateRate
+
+	if p.Delay > 0 {
+		i.record(name, FaultLatency, p.Delay.String())
+	}
+	if p.Fail != "" {
+		i.record(name, p.Fail, "")
+	}
+	if p.Truncate {
+		i.record(name, FaultTruncate, "")
+	}
+	return p
+}
+
+// Fail returns the error of an injected FaultError
+func (i *Injector) Fail(name string) error {
+	return &Error{Component: i.component, Name: name}
+}
+
+func (i *Injector) targets(name string) bool {
+	if len(i.cfg.Only) == 0 {
+		return true
+	}
+	for _, n := range i.cfg.Only {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Injector) record(name string, f Fault, detail string) {
+	faultsInjected.Inc(i.component, string(f))
+	if detail != "" {
+		i.logger.Printf("Injecting %s (%s) into %s %s", f, detail, i.component, name)
+	} else {
+		i.logger.Printf("Injecting %s into %s %s", f, i.component, name)
+	}
+}
+
+// Wait sleeps for the plan's delay, or until ctx is done
+func (p Plan) Wait(ctx context.Context) error {
+	if p.Delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(p.Delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Truncate cuts s in half, at a rune boundary
+func Truncate(s string) string {
+	r := []rune(s)
+	return string(r[:len(r)/2])
+}
@@ -0,0 +1,51 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestPlan(t *testing.T) {
+	always := New("tool", config.FaultConfig{ErrorRate: 1, LatencyRate: 1, LatencyMS: 5, TruncateRate: 1}, 1)
+	p := always.Plan("git")
+	if p.Fail != FaultError || p.Delay <= 0 || p.Delay > 5*time.Millisecond || p.Truncate {
+		t.Errorf("plan = %+v; a failed call is never truncated", p)
+	}
+	if err := p.Wait(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	limited := New("provider", config.FaultConfig{RateLimitRate: 1}, 1)
+	if p := limited.Plan("mock"); p.Fail != FaultRateLimit {
+		t.Errorf("rate limit plan = %+v", p)
+	}
+
+	never := New("tool", config.FaultConfig{}, 1)
+	if p := never.Plan("git"); p != (Plan{}) {
+		t.Errorf("no faults configured: %+v", p)
+	}
+
+	only := New("tool", config.FaultConfig{ErrorRate: 1, Only: []string{"github"}}, 1)
+	if only.Plan("git").Fail != "" || only.Plan("github").Fail != FaultError {
+		t.Error("only should limit the faults to the named tools")
+	}
+}
+
+func TestPlanIsReproducible(t *testing.T) {
+	cfg := config.FaultConfig{ErrorRate: 0.3, TruncateRate: 0.3, LatencyRate: 0.5, LatencyMS: 100}
+	a, b := New("tool", cfg, 42), New("tool", cfg, 42)
+	for i := 0; i < 50; i++ {
+		if pa, pb := a.Plan("git"), b.Plan("git"); pa != pb {
+			t.Fatalf("call %d: %+v != %+v", i, pa, pb)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("àèìòù!"); got != "àèì" {
+		t.Errorf("Truncate = %q", got)
+	}
+}
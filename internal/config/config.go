@@ -2,8 +2,20 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/i18n"
+	"github.com/biodoia/skagent/internal/retry"
+	"github.com/biodoia/skagent/internal/server/unixsock"
 )
 
 // Provider represents an AI provider type
@@ -21,6 +33,16 @@ const (
 	ProviderLocal        Provider = "local"
 )
 
+// Valid reports whether p names a provider skagent supports
+func (p Provider) Valid() bool {
+	switch p {
+	case ProviderOpenRouter, ProviderClaudeMax, ProviderGeminiCLI, ProviderCodex, ProviderMinimax,
+		ProviderKimi, ProviderGLM, ProviderDeepSeek, ProviderLocal:
+		return true
+	}
+	return false
+}
+
 // FreeModel represents a free model available on OpenRouter
 type FreeModel struct {
 	ID            string `json:"id"`
@@ -67,6 +89,16 @@ var OpenRouterFreeModels = []FreeModel{
 	{ID: "cognitivecomputations/dolphin-mistral-24b-venice-edition:free", Name: "Venice Uncensored", ContextLength: 32768, Provider: "CogComp", Description: "Uncensored model"},
 }
 
+// ContextLength returns the context window of a known model, or 0
+func ContextLength(model string) int {
+	for _, m := range OpenRouterFreeModels {
+		if m.ID == model {
+			return m.ContextLength
+		}
+	}
+	return 0
+}
+
 // ProviderConfig holds configuration for a specific provider
 type ProviderConfig struct {
 	Enabled   bool              `json:"enabled"`
@@ -86,6 +118,59 @@ type APIConfig struct {
 	RateLimit    int    `json:"rate_limit"`
 	ReadTimeout  int    `json:"read_timeout"`
 	WriteTimeout int    `json:"write_timeout"`
+	TLS          TLSConfig `json:"tls"`
+	CORS         CORSConfig `json:"cors"`
+	// IdempotencyTTL is how long, in seconds, responses to POST requests
+	// carrying an Idempotency-Key are kept for replay
+	IdempotencyTTL int `json:"idempotency_ttl"`
+	// MaxArtifactSize is the largest artifact upload, in bytes; 0 uses
+	// the default of 32 MiB
+	MaxArtifactSize int64 `json:"max_artifact_size,omitempty"`
+	// MaxBodySize is the largest request body of the REST and MCP servers,
+	// in bytes, after decompression; 0 uses the default of 1 MiB. Artifact
+	// uploads are bounded by MaxArtifactSize instead.
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+	// CallbackSecret signs the results posted to task callback URLs; when
+	// empty, callbacks are sent unsigned
+	CallbackSecret string `json:"callback_secret,omitempty"`
+	// Socket is the path of a Unix domain socket served besides the TCP
+	// port, always without TLS; empty disables it
+	Socket string `json:"socket,omitempty"`
+	// SocketMode is the octal file mode of Socket, deciding which local
+	// users may connect; empty means "0600", the owner only
+	SocketMode string `json:"socket_mode,omitempty"`
+}
+
+// CORSConfig controls cross-origin access when EnableCORS is set
+type CORSConfig struct {
+	// AllowedOrigins lists exact origins ("https://app.example.com"),
+	// subdomain wildcards ("https://*.example.com") or "*" for any origin
+	AllowedOrigins []string `json:"allowed_origins"`
+	// AllowedHeaders are the request headers browsers may send
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
+	// AllowCredentials lets browsers send cookies and Authorization headers
+	AllowCredentials bool `json:"allow_credentials"`
+	// MaxAge is how long, in seconds, browsers may cache a preflight result
+	MaxAge int `json:"max_age,omitempty"`
+}
+
+// TLSConfig enables HTTPS and, with ClientCAFile, client certificate
+// verification (mTLS)
+type TLSConfig struct {
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	// ClientCAFile is a PEM bundle of CAs trusted to sign client certificates
+	ClientCAFile string `json:"client_ca_file,omitempty"`
+	// ClientAuth is "none", "request", "verify_if_given" or "require";
+	// it defaults to "require" when ClientCAFile is set
+	ClientAuth string `json:"client_auth,omitempty"`
+	// MinVersion is "1.2" (default) or "1.3"
+	MinVersion string `json:"min_version,omitempty"`
+}
+
+// Enabled reports whether a certificate has been configured
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
 }
 
 // MCPConfig holds MCP server configuration
@@ -93,6 +178,455 @@ type MCPConfig struct {
 	Host       string `json:"host"`
 	Port       int    `json:"port"`
 	EnableAuth bool   `json:"enable_auth"`
+	// Socket and SocketMode work as in APIConfig
+	Socket     string `json:"socket,omitempty"`
+	SocketMode string `json:"socket_mode,omitempty"`
+	// Token is a shared secret MCP clients may present, with enable_auth,
+	// besides the keys of the auth section; the REST API does not accept it
+	Token string `json:"token,omitempty"`
+	// TokenRole is the role Token grants: "viewer", the default, lists and
+	// reads; "operator" also creates tasks and executes tools
+	TokenRole string `json:"token_role,omitempty"`
+	// Sampling decides when tools ask the model of an MCP host that grants
+	// sampling, through sampling/createMessage: "prefer", the default,
+	// over the configured provider; "fallback" only when there is no
+	// provider; "off" never
+	Sampling string `json:"sampling,omitempty"`
+	// SamplingMaxTokens bounds the completions asked of the host; 0 means
+	// DefaultSamplingMaxTokens
+	SamplingMaxTokens int `json:"sampling_max_tokens,omitempty"`
+	// SessionRateLimit bounds the requests of each client session, per
+	// minute; 0 means no limit
+	SessionRateLimit int `json:"session_rate_limit,omitempty"`
+	// Transports are the HTTP transports served on the port:
+	// "streamable-http", "sse" and "websocket". By default the first two,
+	// for clients that hold event streams open.
+	Transports []string `json:"transports,omitempty"`
+	// WebSocketOrigins are the origins of web pages, besides the server's
+	// own host, whose scripts may open the WebSocket transport, as
+	// "https://app.example.com"; path.Match patterns are allowed.
+	// Browsers do not apply CORS to WebSocket handshakes, so the server
+	// checks the origin itself.
+	WebSocketOrigins []string `json:"websocket_origins,omitempty"`
+	// Views restrict the tools and resources of the sessions they match,
+	// so that a lightly trusted host cannot reach fleet control. A session
+	// takes the first view that matches it when it initializes.
+	Views []MCPViewConfig `json:"views,omitempty"`
+	// DefaultView names the view of the sessions no view matches; empty
+	// leaves them every tool and resource
+	DefaultView string `json:"default_view,omitempty"`
+}
+
+// MCPViewConfig is a subset of the MCP server's tools and resources. The
+// match fields are lists of path.Match patterns; a view matches the
+// sessions that fit one pattern of every non-empty list. Hosts declare
+// their client name themselves and can claim any, so a host is restricted
+// by a view matching the principal of its own API key.
+type MCPViewConfig struct {
+	Name string `json:"name"`
+	// Clients match the clientInfo.name of initialize, as "cursor*"
+	Clients []string `json:"clients,omitempty"`
+	// Principals match the name of the API key of the session
+	Principals []string `json:"principals,omitempty"`
+	// Transports match stdio, streamable-http, sse or websocket, or http
+	// for the /tools and /agents routes
+	Transports []string `json:"transports,omitempty"`
+	// Tools match the tools the view exposes; empty exposes all
+	Tools []string `json:"tools,omitempty"`
+	// DenyTools match tools hidden even when Tools exposes them
+	DenyTools []string `json:"deny_tools,omitempty"`
+	// ResourceRoots are the URI prefixes of the resources the view
+	// exposes, as "skagent://specs/"; empty exposes all
+	ResourceRoots []string `json:"resource_roots,omitempty"`
+}
+
+// HTTP transports of MCPConfig.Transports
+const (
+	MCPTransportStreamable = "streamable-http"
+	MCPTransportSSE        = "sse"
+	MCPTransportWebSocket  = "websocket"
+)
+
+// DefaultMCPTransports are served when mcp.transports is not set
+var DefaultMCPTransports = []string{MCPTransportStreamable, MCPTransportSSE}
+
+// Sampling modes of MCPConfig.Sampling
+const (
+	SamplingPrefer   = "prefer"
+	SamplingFallback = "fallback"
+	SamplingOff      = "off"
+)
+
+// DefaultSamplingMaxTokens bounds the completions asked of an MCP host
+// when mcp.sampling_max_tokens is not set
+const DefaultSamplingMaxTokens = 4096
+
+// MCPTokenKey names the key of MCPConfig.Token in MCPAuth and in the audit
+// log
+const MCPTokenKey = "mcp.token"
+
+// MCPAuth returns the auth section the MCP server authenticates with: the
+// configured keys plus, when set, mcp.token
+func (c *Config) MCPAuth() AuthConfig {
+	a := c.Auth
+	if c.MCP.Token == "" {
+		return a
+	}
+	role := c.MCP.TokenRole
+	if role == "" {
+		role = "viewer"
+	}
+	a.Keys = make(map[string]APIKeyConfig, len(c.Auth.Keys)+1)
+	for name, key := range c.Auth.Keys {
+		a.Keys[name] = key
+	}
+	a.Keys[MCPTokenKey] = APIKeyConfig{Token: c.MCP.Token, Role: role}
+	return a
+}
+
+// MCPServerConfig is an external MCP server whose tools agents may use.
+// Exactly one of Command, for a server speaking MCP over stdin and stdout,
+// and URL, for one reached over HTTP+SSE, is set.
+type MCPServerConfig struct {
+	// Name identifies the server in logs; lowercase letters, digits, "-"
+	// and "_"
+	Name    string            `json:"name"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	// Env is added to the environment of Command
+	Env map[string]string `json:"env,omitempty"`
+	// URL is the SSE endpoint, e.g. "http://localhost:3000/sse"
+	URL string `json:"url,omitempty"`
+	// Headers are sent with every HTTP request, e.g. Authorization
+	Headers map[string]string `json:"headers,omitempty"`
+	// Timeout bounds connecting and each call, in seconds; 0 means 60
+	Timeout  int  `json:"timeout_seconds,omitempty"`
+	// Disabled leaves the server off at startup; it can be enabled at
+	// runtime
+	Disabled bool `json:"disabled,omitempty"`
+	// Namespace prefixes the names of the server's tools, as in
+	// "fs.read_file"; it defaults to Name
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ToolNamespace returns the prefix of the server's tool names
+func (s MCPServerConfig) ToolNamespace() string {
+	if s.Namespace != "" {
+		return s.Namespace
+	}
+	return s.Name
+}
+
+// AuthConfig holds API keys and role definitions used when api.enable_auth
+// or mcp.enable_auth is set
+type AuthConfig struct {
+	// Keys maps a key name to its token and role
+	Keys map[string]APIKeyConfig `json:"keys,omitempty"`
+	// Roles adds or overrides role definitions as lists of permissions,
+	// e.g. "agents:read", "tools:*" or "*"
+	Roles map[string][]string `json:"roles,omitempty"`
+}
+
+// APIKeyConfig is a single API key and the role it grants
+type APIKeyConfig struct {
+	Token string `json:"token"`
+	Role  string `json:"role"`
+	// Workspaces binds the key to these workspaces, the first being where
+	// its requests go by default; an unbound key reaches every workspace
+	Workspaces []string `json:"workspaces,omitempty"`
+}
+
+// WorkspaceConfig declares a workspace that exists from startup, in
+// addition to the default one
+type WorkspaceConfig struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Dir is the checkout the tools of the workspace's agents and sessions
+	// work in; empty shares the tools of the default workspace
+	Dir string `json:"dir,omitempty"`
+}
+
+// WorkspaceDir returns the checkout of a declared workspace, or "" when
+// the workspace has none of its own
+func (c *Config) WorkspaceDir(name string) string {
+	for _, ws := range c.Workspaces {
+		if ws.Name == name {
+			return ws.Dir
+		}
+	}
+	return ""
+}
+
+// workspaceName is the form of workspace names
+var workspaceName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// RedactionConfig controls scrubbing of secrets from logs, stored session
+// messages, tool outputs and API responses
+type RedactionConfig struct {
+	Enabled bool `json:"enabled"`
+	// Patterns are extra regular expressions whose matches are redacted
+	Patterns []string `json:"patterns,omitempty"`
+	// Replacement defaults to "[REDACTED]"
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// DocsConfig lists remote SpecKit documentation fetched by
+// `skagent docs update` and cached under the config directory
+type DocsConfig struct {
+	// URLs point at individual markdown files
+	URLs []string `json:"urls,omitempty"`
+	// GitRepo is a repository whose markdown files under GitPath are used
+	GitRepo string `json:"git_repo,omitempty"`
+	// GitRef is the branch or tag to fetch (default: the remote HEAD)
+	GitRef string `json:"git_ref,omitempty"`
+	// GitPath is the directory inside the repository (default "docs")
+	GitPath string `json:"git_path,omitempty"`
+}
+
+// ModelPolicyConfig controls per-task model selection: each task starts
+// on the cheapest tier expected to handle it and moves up a tier when it
+// fails
+type ModelPolicyConfig struct {
+	Enabled bool `json:"enabled"`
+	// Tiers lists the models to choose from; empty uses the built-in
+	// ladder of free OpenRouter models
+	Tiers []ModelTier `json:"tiers,omitempty"`
+	// MaxEscalations bounds how many stronger tiers a failing task tries
+	MaxEscalations int `json:"max_escalations"`
+}
+
+// AuditConfig controls the append-only record of mutating API requests,
+// tool calls and shutdowns
+type AuditConfig struct {
+	Enabled bool `json:"enabled"`
+	// Path is the JSONL file; empty uses audit.jsonl in the data directory
+	Path string `json:"path,omitempty"`
+}
+
+// ConstitutionConfig controls the checks of generated plans and task
+// batches against the project constitution
+type ConstitutionConfig struct {
+	Enabled bool `json:"enabled"`
+	// Enforce rejects task batches that break a rule; otherwise the
+	// violations are only reported
+	Enforce bool `json:"enforce"`
+	// Rules names the rules to check; empty checks every built-in rule
+	Rules []string `json:"rules,omitempty"`
+	// MaxProjects is the Simplicity limit; 0 uses 3
+	MaxProjects int `json:"max_projects,omitempty"`
+}
+
+// PullRequestConfig controls turning the changes of coder agents into
+// GitHub pull requests
+type PullRequestConfig struct {
+	Enabled bool `json:"enabled"`
+	// Auto opens a pull request whenever a coder agent completes a task
+	// whose workspace has changes; otherwise they are opened on request
+	Auto bool `json:"auto"`
+	// Workspace is the git checkout used for tasks that name none in
+	// meta.workspace
+	Workspace string `json:"workspace,omitempty"`
+	// Base is the branch pull requests target; empty uses the branch
+	// checked out in the workspace
+	Base string `json:"base,omitempty"`
+	// Remote is where branches are pushed; empty uses origin
+	Remote string `json:"remote,omitempty"`
+	// BranchPrefix starts the name of every branch; empty uses skagent/
+	BranchPrefix string `json:"branch_prefix,omitempty"`
+	Draft        bool   `json:"draft"`
+	// PublicURL is the address of this server as seen from GitHub, used
+	// to link pull requests to their task, e.g. https://skagent.example.com
+	PublicURL string `json:"public_url,omitempty"`
+}
+
+// SnapshotConfig controls the git checkpoints taken of a coder agent's
+// workspace before it changes anything, so that a failed run can be
+// rolled back
+type SnapshotConfig struct {
+	Enabled bool `json:"enabled"`
+	// Auto takes a checkpoint whenever a task is assigned to a coder
+	// agent; otherwise they are taken on request
+	Auto bool `json:"auto"`
+	// Workspace is the git checkout used for tasks that name none in
+	// meta.workspace; empty uses pull_requests.workspace
+	Workspace string `json:"workspace,omitempty"`
+}
+
+// Storage drivers
+const (
+	StorageMemory = "memory"
+	StorageSQLite = "sqlite"
+	StoragePostgres = "postgres"
+)
+
+// StorageConfig selects where the registry keeps agents, tasks and
+// workspaces. In memory they are lost on restart; in PostgreSQL several
+// headless instances can share them.
+type StorageConfig struct {
+	// Driver is memory, sqlite or postgres; empty is memory
+	Driver string `json:"driver,omitempty"`
+	// Path is the SQLite database; empty uses registry.db in the data
+	// directory
+	Path string `json:"path,omitempty"`
+	// DSN is the PostgreSQL connection string, as a URL or key=value pairs
+	DSN string `json:"dsn,omitempty"`
+	// SyncInterval is how often, in seconds, an instance reloads a shared
+	// database to see what the others changed; 0 is every 5 seconds
+	SyncInterval int `json:"sync_interval,omitempty"`
+	// ArchiveAfter is how many days after finishing completed, failed and
+	// cancelled tasks move to the archive of the database, which keeps
+	// them readable with ?archived=true; 0 keeps them in the registry
+	ArchiveAfter int `json:"archive_after,omitempty"`
+}
+
+// ReviewConfig controls the review of GitHub pull requests: the GitHub
+// webhook delivers pull_request events, which become review tasks for
+// reviewer agents whose findings are posted back as review comments
+type ReviewConfig struct {
+	Enabled bool `json:"enabled"`
+	// WebhookSecret is the secret configured on the GitHub webhook; every
+	// delivery must carry its X-Hub-Signature-256
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+	// Repositories limits reviews to these owner/name repositories; empty
+	// reviews every repository that delivers events
+	Repositories []string `json:"repositories,omitempty"`
+	// Drafts also reviews draft pull requests
+	Drafts bool `json:"drafts"`
+	// CommentOnly posts every review as a comment rather than approving
+	// or requesting changes, e.g. when the token belongs to the author
+	CommentOnly bool `json:"comment_only"`
+	// MaxDiffSize bounds the diff sent to the model, in bytes; 0 uses the
+	// default of 200 KiB. Larger diffs get the static checks only.
+	MaxDiffSize int `json:"max_diff_size"`
+}
+
+// RunnerConfig controls the agent runtime, which runs the tasks assigned
+// to agents: it prompts the agent's model with the task, lets it call
+// tools and records what it answers as the task's result
+type RunnerConfig struct {
+	Enabled bool `json:"enabled"`
+	// AgentTypes are the types of agent whose tasks are run; empty runs
+	// the tasks of every agent
+	AgentTypes []string `json:"agent_types,omitempty"`
+	// MaxConcurrent bounds the tasks running at once; 0 uses 4
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// MaxSteps bounds the tool calls of a run; 0 uses 8
+	MaxSteps int `json:"max_steps,omitempty"`
+	// Timeout is how long a run may take, in seconds, for agents without
+	// a timeout of their own; 0 uses 600
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// RetryConfig controls the retrying of failed tasks: a task whose run
+// fails with an error of a class its policy retries goes back to pending,
+// for auto-assignment after a backoff, until it has run max_attempts times.
+// A task's own policy comes first, then that of its agent's type, then the
+// default one.
+type RetryConfig struct {
+	Enabled bool `json:"enabled"`
+	// RetryPolicy is the default policy
+	RetryPolicy
+	// AgentTypes are the policies of the tasks failed by each type of
+	// agent
+	AgentTypes map[string]RetryPolicy `json:"agent_types,omitempty"`
+}
+
+// RetryPolicy says which failures of a task are retried, how many times
+// and after how long
+type RetryPolicy struct {
+	// MaxAttempts bounds the runs of a task, the first one included; 0
+	// uses 3, and 1 never retries
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// Backoff is the wait before the first retry, in seconds; 0 uses 30
+	Backoff int `json:"backoff,omitempty"`
+	// MaxBackoff bounds the wait, in seconds; 0 uses 600
+	MaxBackoff int `json:"max_backoff,omitempty"`
+	// Multiplier grows the wait at each retry; 0 uses 2
+	Multiplier float64 `json:"multiplier,omitempty"`
+	// RetryOn are the classes of error retried: timeout, rate_limit,
+	// transient or permanent; empty retries all but permanent
+	RetryOn []string `json:"retry_on,omitempty"`
+}
+
+// EvaluationConfig controls the scoring of completed tasks: an evaluator
+// model checks each result against the task's acceptance criteria and may
+// send a low-scoring one back to the agent for revision
+type EvaluationConfig struct {
+	Enabled bool `json:"enabled"`
+	// Model is the evaluator's model, which should differ from the one the
+	// agents use; empty evaluates with the default model
+	Model string `json:"model,omitempty"`
+	// Threshold is the score, from 0 to 100, a result needs to pass
+	Threshold int `json:"threshold"`
+	// MaxRevisions bounds how many times a task is sent back; 0 only
+	// records the scores
+	MaxRevisions int `json:"max_revisions"`
+}
+
+// DigestConfig controls the daily digest of fleet activity: what tasks
+// finished or failed, the notable errors and the models used, summed up by
+// a cheap model
+type DigestConfig struct {
+	Enabled bool `json:"enabled"`
+	// At is the local time of day the digest is built, as "15:04"
+	At string `json:"at"`
+	// Model writes the summary and should be a cheap one; empty uses the
+	// cheapest model_policy tier when the policy is enabled, else the
+	// default model
+	Model string `json:"model,omitempty"`
+	// MaxErrors bounds the notable errors listed
+	MaxErrors int `json:"max_errors"`
+}
+
+// ChaosConfig injects faults into AI providers and tools, so that the
+// retry, failover and guardrail code can be exercised in staging. It must
+// stay off in production.
+type ChaosConfig struct {
+	Enabled bool `json:"enabled"`
+	// Seed makes the sequence of faults reproducible; 0 seeds from the
+	// clock
+	Seed     int64       `json:"seed,omitempty"`
+	Provider FaultConfig `json:"provider"`
+	Tools    FaultConfig `json:"tools"`
+}
+
+// FaultConfig sets how often each fault hits a call, as a fraction from 0
+// to 1
+type FaultConfig struct {
+	// ErrorRate fails calls with a 503 error
+	ErrorRate float64 `json:"error_rate,omitempty"`
+	// RateLimitRate fails provider calls as a 429; tools ignore it
+	RateLimitRate float64 `json:"rate_limit_rate,omitempty"`
+	// LatencyRate delays calls by up to LatencyMS milliseconds
+	LatencyRate float64 `json:"latency_rate,omitempty"`
+	LatencyMS   int     `json:"latency_ms,omitempty"`
+	// TruncateRate cuts the output of successful calls in half
+	TruncateRate float64 `json:"truncate_rate,omitempty"`
+	// Only limits the faults to these provider or tool names; empty hits
+	// every one
+	Only []string `json:"only,omitempty"`
+}
+
+// LessonsConfig controls the lessons agents keep from finished tasks and
+// the ones added to the prompts of similar tasks
+type LessonsConfig struct {
+	Enabled bool `json:"enabled"`
+	// TopK is how many lessons a prompt gets
+	TopK int `json:"top_k"`
+	// MaxPerAgent bounds the lessons kept per agent; the least helpful go
+	// first. 0 keeps every lesson.
+	MaxPerAgent int `json:"max_per_agent"`
+}
+
+// ModelTier is one step of the model ladder
+type ModelTier struct {
+	Model string `json:"model"`
+	// Cost is the relative cost of a request, e.g. its share of a free
+	// tier's quota; tiers are tried cheapest first
+	Cost float64 `json:"cost"`
+	// MaxComplexity is the highest estimated task complexity, from 0 to 1,
+	// the model is expected to handle
+	MaxComplexity float64 `json:"max_complexity"`
 }
 
 // HeadlessConfig holds headless mode configuration
@@ -123,6 +657,29 @@ type ProjectConfig struct {
 	BaseURL     string `json:"base_url,omitempty"`
 	AutoAssign  bool   `json:"auto_assign"`
 	PollInterval int   `json:"poll_interval"`
+	// WebhookPort is where project manager events are received; 0 uses
+	// 8082 and a negative port disables the webhook server
+	WebhookPort int `json:"webhook_port,omitempty"`
+	// WebhookWorkers process the received events, each task's in order on
+	// one worker; 0 uses 4
+	WebhookWorkers int `json:"webhook_workers,omitempty"`
+	// WebhookQueueSize is how many events may wait for a worker before
+	// deliveries are refused with 503; 0 uses 1000
+	WebhookQueueSize int `json:"webhook_queue_size,omitempty"`
+	// WebhookRate is the most events processed a second; 0 means no limit
+	WebhookRate int `json:"webhook_rate,omitempty"`
+	// MaxPendingTasks pauses pulling tasks while more than this many
+	// wait for an agent in the registry; 0 never pauses
+	MaxPendingTasks int `json:"max_pending_tasks"`
+	// ImportTasks creates a registry task for every todo task of the
+	// project manager, for agents to take from the registry's queue
+	ImportTasks bool `json:"import_tasks"`
+	// PriorityMap maps project manager priorities, in any case, to low,
+	// medium, high or urgent, over the built-in mapping
+	PriorityMap map[string]string `json:"priority_map,omitempty"`
+	// DueSoonHours raises imported tasks due within this many hours one
+	// priority level, and overdue ones to urgent; 0 disables it
+	DueSoonHours int `json:"due_soon_hours"`
 }
 
 // Config holds the complete application configuration
@@ -133,14 +690,38 @@ type Config struct {
 	SpecKitPath     string                    `json:"speckit_path,omitempty"`
 	GitHubUser      string                    `json:"github_user,omitempty"`
 	Autonomous      bool                      `json:"autonomous_default"`
+	// DryRun logs what mutating tools would do instead of doing it
+	DryRun          bool                      `json:"dry_run,omitempty"`
 	ThemeName       string                    `json:"theme"`
+	// Locale is the language of the TUI, the setup wizard and the help
+	// ("en", "it"); empty follows $LANG
+	Locale          string                    `json:"locale,omitempty"`
 	
 	// New configuration sections
 	API        APIConfig        `json:"api"`
+	Storage    StorageConfig    `json:"storage"`
 	MCP        MCPConfig        `json:"mcp"`
 	Headless   HeadlessConfig   `json:"headless"`
 	Theme      ThemeConfig      `json:"theme_settings"`
 	Project    ProjectConfig    `json:"project"`
+	Auth       AuthConfig       `json:"auth"`
+	Redaction  RedactionConfig  `json:"redaction"`
+	Docs       DocsConfig       `json:"docs"`
+	ModelPolicy ModelPolicyConfig `json:"model_policy"`
+	Lessons    LessonsConfig    `json:"lessons"`
+	Audit      AuditConfig      `json:"audit"`
+	Constitution ConstitutionConfig `json:"constitution"`
+	PullRequests PullRequestConfig  `json:"pull_requests"`
+	Snapshots  SnapshotConfig   `json:"snapshots"`
+	Review     ReviewConfig     `json:"review"`
+	Evaluation EvaluationConfig `json:"evaluation"`
+	Runner     RunnerConfig     `json:"runner"`
+	Retry      RetryConfig      `json:"retry"`
+	Digest     DigestConfig     `json:"digest"`
+	Workspaces []WorkspaceConfig `json:"workspaces,omitempty"`
+	Chaos      ChaosConfig      `json:"chaos"`
+	// MCPServers are the external MCP servers whose tools agents use
+	MCPServers []MCPServerConfig `json:"mcp_servers,omitempty"`
 	
 	// First run tracking
 	FirstRun   bool             `json:"first_run"`
@@ -183,6 +764,11 @@ func DefaultConfig() *Config {
 			RateLimit:    100,
 			ReadTimeout:  30,
 			WriteTimeout: 30,
+			CORS: CORSConfig{
+				AllowedOrigins: []string{"*"},
+				MaxAge:         600,
+			},
+			IdempotencyTTL: 86400,
 		},
 		
 		// MCP configuration
@@ -220,17 +806,86 @@ func DefaultConfig() *Config {
 			BaseURL:     "",
 			AutoAssign:  false,
 			PollInterval: 30,
+			MaxPendingTasks: 100,
+			DueSoonHours: 48,
+		},
+		
+		// Secrets redaction
+		Redaction: RedactionConfig{
+			Enabled: true,
+		},
+		
+		ModelPolicy: ModelPolicyConfig{
+			MaxEscalations: 2,
+		},
+		
+		Lessons: LessonsConfig{
+			TopK:        3,
+			MaxPerAgent: 200,
+		},
+		
+		Evaluation: EvaluationConfig{
+			Threshold: 70,
+		},
+		
+		Digest: DigestConfig{
+			At:        "07:00",
+			MaxErrors: 5,
+		},
+		
+		Audit: AuditConfig{
+			Enabled: true,
 		},
 	}
 }
 
+// ConfigDir returns the directory holding skagent configuration:
+// ~/.config/skagent, or %AppData%\skagent on Windows
+func ConfigDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "skagent"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "skagent"), nil
+}
+
+// DataDir returns the directory for persisted runtime state such as
+// registry snapshots, sessions and artifacts. SKAGENT_DATA_DIR overrides
+// the default of $XDG_DATA_HOME/skagent or ~/.local/share/skagent, and
+// %LocalAppData%\skagent on Windows.
+func DataDir() (string, error) {
+	if dir := os.Getenv("SKAGENT_DATA_DIR"); dir != "" {
+		return dir, nil
+	}
+	if runtime.GOOS == "windows" {
+		if local := os.Getenv("LocalAppData"); local != "" {
+			return filepath.Join(local, "skagent"), nil
+		}
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "skagent"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "skagent"), nil
+}
+
 // ConfigPath returns the path to the config file
 func ConfigPath() (string, error) {
-	home, err := os.UserHomeDir()
+	dir, err := ConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(home, ".config", "skagent", "config.json"), nil
+	return filepath.Join(dir, "config.json"), nil
 }
 
 // Load loads configuration from disk
@@ -354,3 +1009,402 @@ func (c *Config) GetHeadlessConfig() HeadlessConfig {
 func (c *Config) GetProjectConfig() ProjectConfig {
 	return c.Project
 }
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the configuration for inconsistent or missing values
+func (c *Config) Validate() error {
+	var problems []string
+
+	provider, ok := c.Providers[c.DefaultProvider]
+	switch {
+	case c.DefaultProvider == "":
+		problems = append(problems, "default_provider is not set")
+	case !ok:
+		problems = append(problems, fmt.Sprintf("default_provider %q has no entry in providers", c.DefaultProvider))
+	case provider.AuthType == "api_key" && provider.APIKey == "":
+		problems = append(problems, fmt.Sprintf("providers.%s.api_key is required", c.DefaultProvider))
+	}
+
+	checkPort := func(name string, port int) {
+		if port < 0 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("%s must be between 0 and 65535", name))
+		}
+	}
+	checkPort("api.port", c.API.Port)
+	checkPort("mcp.port", c.MCP.Port)
+	if c.API.Port > 0 && c.API.Port == c.MCP.Port && c.API.Host == c.MCP.Host {
+		problems = append(problems, "api.port and mcp.port must differ")
+	}
+	checkSocket := func(name, mode string) {
+		if _, err := unixsock.ParseMode(mode); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.socket_mode: %v", name, err))
+		}
+	}
+	checkSocket("api", c.API.SocketMode)
+	checkSocket("mcp", c.MCP.SocketMode)
+	if c.API.Socket != "" && filepath.Clean(c.API.Socket) == filepath.Clean(c.MCP.Socket) {
+		problems = append(problems, "api.socket and mcp.socket must differ")
+	}
+
+	if c.Locale != "" {
+		if _, ok := i18n.Parse(c.Locale); !ok {
+			var names []string
+			for _, l := range i18n.Locales() {
+				names = append(names, string(l))
+			}
+			problems = append(problems, fmt.Sprintf("locale %q is not one of %s", c.Locale, strings.Join(names, ", ")))
+		}
+	}
+
+	switch c.Headless.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("headless.log_level %q is not one of debug, info, warn, error", c.Headless.LogLevel))
+	}
+
+	if c.API.EnableAuth && len(c.Auth.Keys) == 0 {
+		problems = append(problems, "auth.keys must define at least one key when api.enable_auth is set")
+	}
+	if c.MCP.EnableAuth && len(c.Auth.Keys) == 0 && c.MCP.Token == "" {
+		problems = append(problems, "auth.keys or mcp.token must define a key when mcp.enable_auth is set")
+	}
+	if c.MCP.Token != "" && !c.MCP.EnableAuth {
+		problems = append(problems, "mcp.token is set but mcp.enable_auth is off")
+	}
+	roleDefined := func(role string) bool {
+		switch role {
+		case "viewer", "operator", "admin":
+			return true
+		}
+		_, ok := c.Auth.Roles[role]
+		return ok
+	}
+	if c.MCP.TokenRole != "" && !roleDefined(c.MCP.TokenRole) {
+		problems = append(problems, fmt.Sprintf("mcp.token_role %q is not defined", c.MCP.TokenRole))
+	}
+	switch c.MCP.Sampling {
+	case "", SamplingPrefer, SamplingFallback, SamplingOff:
+	default:
+		problems = append(problems, fmt.Sprintf("mcp.sampling %q is not one of prefer, fallback, off", c.MCP.Sampling))
+	}
+	if c.MCP.SamplingMaxTokens < 0 {
+		problems = append(problems, "mcp.sampling_max_tokens must not be negative")
+	}
+	if c.MCP.SessionRateLimit < 0 {
+		problems = append(problems, "mcp.session_rate_limit must not be negative")
+	}
+	for i, t := range c.MCP.Transports {
+		switch t {
+		case MCPTransportStreamable, MCPTransportSSE, MCPTransportWebSocket:
+		default:
+			problems = append(problems, fmt.Sprintf("mcp.transports[%d] %q is not one of streamable-http, sse, websocket", i, t))
+		}
+	}
+	seenViews := make(map[string]bool, len(c.MCP.Views))
+	for i, v := range c.MCP.Views {
+		name := fmt.Sprintf("mcp.views[%d]", i)
+		switch {
+		case !workspaceName.MatchString(v.Name):
+			problems = append(problems, fmt.Sprintf("%s.name %q must be lowercase letters, digits, \"-\" or \"_\"", name, v.Name))
+		case seenViews[v.Name]:
+			problems = append(problems, fmt.Sprintf("%s.name %q is used twice", name, v.Name))
+		}
+		seenViews[v.Name] = true
+		for _, list := range []struct {
+			field    string
+			patterns []string
+		}{
+			{"clients", v.Clients}, {"principals", v.Principals}, {"transports", v.Transports},
+			{"tools", v.Tools}, {"deny_tools", v.DenyTools},
+		} {
+			for _, p := range list.patterns {
+				if _, err := path.Match(p, ""); err != nil {
+					problems = append(problems, fmt.Sprintf("%s.%s pattern %q is malformed", name, list.field, p))
+				}
+			}
+		}
+	}
+	if c.MCP.DefaultView != "" && !seenViews[c.MCP.DefaultView] {
+		problems = append(problems, fmt.Sprintf("mcp.default_view %q is not one of mcp.views", c.MCP.DefaultView))
+	}
+	for name, key := range c.Auth.Keys {
+		if key.Token == "" {
+			problems = append(problems, fmt.Sprintf("auth.keys.%s.token is required", name))
+		}
+		if !roleDefined(key.Role) {
+			problems = append(problems, fmt.Sprintf("auth.keys.%s.role %q is not defined", name, key.Role))
+		}
+		for i, ws := range key.Workspaces {
+			if !workspaceName.MatchString(ws) {
+				problems = append(problems, fmt.Sprintf("auth.keys.%s.workspaces[%d] %q is not a valid workspace name", name, i, ws))
+			}
+		}
+	}
+	switch c.Storage.Driver {
+	case "", StorageMemory, StorageSQLite:
+	case StoragePostgres:
+		if c.Storage.DSN == "" {
+			problems = append(problems, "storage.dsn is required by the postgres driver")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("storage.driver %q is not one of memory, sqlite, postgres", c.Storage.Driver))
+	}
+	if c.Storage.SyncInterval < 0 {
+		problems = append(problems, "storage.sync_interval must not be negative")
+	}
+	switch {
+	case c.Storage.ArchiveAfter < 0:
+		problems = append(problems, "storage.archive_after must not be negative")
+	case c.Storage.ArchiveAfter > 0 && (c.Storage.Driver == "" || c.Storage.Driver == StorageMemory):
+		problems = append(problems, "storage.archive_after needs the sqlite or postgres driver")
+	}
+	seenWorkspaces := make(map[string]bool)
+	for i, ws := range c.Workspaces {
+		switch {
+		case !workspaceName.MatchString(ws.Name):
+			problems = append(problems, fmt.Sprintf("workspaces[%d].name %q must be 1-63 lowercase letters, digits, '-' or '_'", i, ws.Name))
+		case ws.Name == "default" || seenWorkspaces[ws.Name]:
+			problems = append(problems, fmt.Sprintf("workspaces[%d].name %q is declared twice", i, ws.Name))
+		}
+		if ws.Dir != "" && !filepath.IsAbs(ws.Dir) {
+			problems = append(problems, fmt.Sprintf("workspaces[%d].dir %q must be an absolute path", i, ws.Dir))
+		}
+		seenWorkspaces[ws.Name] = true
+	}
+
+	if tls := c.API.TLS; tls.Enabled() || tls.ClientCAFile != "" {
+		if tls.CertFile == "" || tls.KeyFile == "" {
+			problems = append(problems, "api.tls.cert_file and api.tls.key_file must be set together")
+		}
+		for _, f := range []struct{ name, path string }{
+			{"cert_file", tls.CertFile}, {"key_file", tls.KeyFile}, {"client_ca_file", tls.ClientCAFile},
+		} {
+			if f.path == "" {
+				continue
+			}
+			if _, err := os.Stat(f.path); err != nil {
+				problems = append(problems, fmt.Sprintf("api.tls.%s: %v", f.name, err))
+			}
+		}
+		switch tls.ClientAuth {
+		case "", "none", "request", "verify_if_given", "require":
+		default:
+			problems = append(problems, fmt.Sprintf("api.tls.client_auth %q is not one of none, request, verify_if_given, require", tls.ClientAuth))
+		}
+		if (tls.ClientAuth == "verify_if_given" || tls.ClientAuth == "require") && tls.ClientCAFile == "" {
+			problems = append(problems, "api.tls.client_ca_file is required to verify client certificates")
+		}
+		switch tls.MinVersion {
+		case "", "1.2", "1.3":
+		default:
+			problems = append(problems, fmt.Sprintf("api.tls.min_version %q is not 1.2 or 1.3", tls.MinVersion))
+		}
+	}
+
+	if c.API.EnableCORS && c.API.CORS.AllowCredentials {
+		for _, origin := range c.API.CORS.AllowedOrigins {
+			if origin == "*" {
+				problems = append(problems, "api.cors.allow_credentials cannot be combined with allowed origin \"*\"")
+				break
+			}
+		}
+	}
+
+	for i, u := range c.Docs.URLs {
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			problems = append(problems, fmt.Sprintf("docs.urls[%d] is not an http(s) URL", i))
+		} else if !strings.HasSuffix(parsed.Path, ".md") {
+			problems = append(problems, fmt.Sprintf("docs.urls[%d] must point at a .md file", i))
+		}
+	}
+
+	if c.Lessons.TopK < 0 {
+		problems = append(problems, "lessons.top_k must not be negative")
+	}
+	if c.Lessons.MaxPerAgent < 0 {
+		problems = append(problems, "lessons.max_per_agent must not be negative")
+	}
+	if u := c.PullRequests.PublicURL; u != "" {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			problems = append(problems, "pull_requests.public_url is not an http(s) URL")
+		}
+	}
+	if c.Review.Enabled && c.Review.WebhookSecret == "" {
+		problems = append(problems, "review.webhook_secret is required when review is enabled")
+	}
+	if c.Review.MaxDiffSize < 0 {
+		problems = append(problems, "review.max_diff_size must not be negative")
+	}
+	for i, repo := range c.Review.Repositories {
+		if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			problems = append(problems, fmt.Sprintf("review.repositories[%d] is not owner/name", i))
+		}
+	}
+	if c.Evaluation.Threshold < 0 || c.Evaluation.Threshold > 100 {
+		problems = append(problems, "evaluation.threshold must be between 0 and 100")
+	}
+	if c.Evaluation.MaxRevisions < 0 {
+		problems = append(problems, "evaluation.max_revisions must not be negative")
+	}
+	if c.Digest.At != "" {
+		if _, err := time.Parse("15:04", c.Digest.At); err != nil {
+			problems = append(problems, fmt.Sprintf("digest.at %q is not a time of day such as 07:00", c.Digest.At))
+		}
+	}
+	if c.Digest.MaxErrors < 0 {
+		problems = append(problems, "digest.max_errors must not be negative")
+	}
+	if c.Constitution.MaxProjects < 0 {
+		problems = append(problems, "constitution.max_projects must not be negative")
+	}
+	if c.Runner.MaxConcurrent < 0 || c.Runner.MaxSteps < 0 || c.Runner.Timeout < 0 {
+		problems = append(problems, "runner.max_concurrent, max_steps and timeout must not be negative")
+	}
+	problems = append(problems, c.Retry.RetryPolicy.problems("retry")...)
+	for name, p := range c.Retry.AgentTypes {
+		problems = append(problems, p.problems("retry.agent_types."+name)...)
+	}
+	if c.ModelPolicy.MaxEscalations < 0 {
+		problems = append(problems, "model_policy.max_escalations must not be negative")
+	}
+	for i, tier := range c.ModelPolicy.Tiers {
+		if tier.Model == "" {
+			problems = append(problems, fmt.Sprintf("model_policy.tiers[%d].model is required", i))
+		}
+		if tier.Cost < 0 {
+			problems = append(problems, fmt.Sprintf("model_policy.tiers[%d].cost must not be negative", i))
+		}
+		if tier.MaxComplexity < 0 || tier.MaxComplexity > 1 {
+			problems = append(problems, fmt.Sprintf("model_policy.tiers[%d].max_complexity must be between 0 and 1", i))
+		}
+	}
+
+	if c.API.IdempotencyTTL < 0 {
+		problems = append(problems, "api.idempotency_ttl must not be negative")
+	}
+	if c.API.MaxArtifactSize < 0 {
+		problems = append(problems, "api.max_artifact_size must not be negative")
+	}
+	if c.API.MaxBodySize < 0 {
+		problems = append(problems, "api.max_body_size must not be negative")
+	}
+
+	seenServers := make(map[string]bool, len(c.MCPServers))
+	seenNamespaces := make(map[string]string, len(c.MCPServers))
+	for i, srv := range c.MCPServers {
+		name := fmt.Sprintf("mcp_servers[%d]", i)
+		switch {
+		case !workspaceName.MatchString(srv.Name):
+			problems = append(problems, fmt.Sprintf("%s.name %q must be lowercase letters, digits, \"-\" or \"_\"", name, srv.Name))
+		case seenServers[srv.Name]:
+			problems = append(problems, fmt.Sprintf("%s.name %q is used twice", name, srv.Name))
+		}
+		seenServers[srv.Name] = true
+		if srv.Namespace != "" && !workspaceName.MatchString(srv.Namespace) {
+			problems = append(problems, fmt.Sprintf("%s.namespace %q must be lowercase letters, digits, \"-\" or \"_\"", name, srv.Namespace))
+		} else if other, ok := seenNamespaces[srv.ToolNamespace()]; ok && srv.Namespace != "" {
+			problems = append(problems, fmt.Sprintf("%s.namespace %q is already the namespace of %s", name, srv.ToolNamespace(), other))
+		}
+		if _, ok := seenNamespaces[srv.ToolNamespace()]; !ok {
+			seenNamespaces[srv.ToolNamespace()] = name
+		}
+		if (srv.Command == "") == (srv.URL == "") {
+			problems = append(problems, fmt.Sprintf("%s must set exactly one of command and url", name))
+		}
+		if srv.URL != "" {
+			if u, err := url.Parse(srv.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				problems = append(problems, fmt.Sprintf("%s.url must be an http or https URL", name))
+			}
+		}
+		if srv.Timeout < 0 {
+			problems = append(problems, fmt.Sprintf("%s.timeout_seconds must not be negative", name))
+		}
+	}
+
+	checkFaults := func(name string, f FaultConfig) {
+		rates := []struct {
+			field string
+			rate  float64
+		}{
+			{"error_rate", f.ErrorRate},
+			{"rate_limit_rate", f.RateLimitRate},
+			{"latency_rate", f.LatencyRate},
+			{"truncate_rate", f.TruncateRate},
+		}
+		for _, r := range rates {
+			if r.rate < 0 || r.rate > 1 {
+				problems = append(problems, fmt.Sprintf("%s.%s must be between 0 and 1", name, r.field))
+			}
+		}
+		if f.ErrorRate+f.RateLimitRate > 1 {
+			problems = append(problems, fmt.Sprintf("%s.error_rate and rate_limit_rate must not add up to more than 1", name))
+		}
+		if f.LatencyMS < 0 || (f.LatencyRate > 0 && f.LatencyMS == 0) {
+			problems = append(problems, fmt.Sprintf("%s.latency_ms must be positive when latency_rate is set", name))
+		}
+	}
+	checkFaults("chaos.provider", c.Chaos.Provider)
+	checkFaults("chaos.tools", c.Chaos.Tools)
+
+	for i, p := range c.Redaction.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			problems = append(problems, fmt.Sprintf("redaction.patterns[%d] is not a valid regular expression: %v", i, err))
+		}
+	}
+
+	if c.Project.Enabled && (c.Project.BaseURL == "" || c.Project.APIKey == "") {
+		problems = append(problems, "project.base_url and project.api_key are required when project is enabled")
+	}
+	for name, level := range c.Project.PriorityMap {
+		switch strings.ToLower(level) {
+		case "low", "medium", "high", "urgent":
+		default:
+			problems = append(problems, fmt.Sprintf("project.priority_map[%q] must be low, medium, high or urgent, not %q", name, level))
+		}
+	}
+	if c.Project.DueSoonHours < 0 {
+		problems = append(problems, "project.due_soon_hours must not be negative")
+	}
+	if c.Project.WebhookWorkers < 0 || c.Project.WebhookQueueSize < 0 || c.Project.WebhookRate < 0 {
+		problems = append(problems, "project.webhook_workers, webhook_queue_size and webhook_rate must not be negative")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Validate checks a retry policy, such as one given to a task
+func (p RetryPolicy) Validate() error {
+	if problems := p.problems("retry"); len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// problems lists what is wrong with a retry policy found at path
+func (p RetryPolicy) problems(path string) []string {
+	var problems []string
+	if p.MaxAttempts < 0 || p.Backoff < 0 || p.MaxBackoff < 0 {
+		problems = append(problems, path+".max_attempts, backoff and max_backoff must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		problems = append(problems, path+".multiplier must be at least 1")
+	}
+	for i, class := range p.RetryOn {
+		if !slices.Contains(retry.Classes, retry.Class(class)) {
+			problems = append(problems, fmt.Sprintf("%s.retry_on[%d] %q is not timeout, rate_limit, transient or permanent", path, i, class))
+		}
+	}
+	return problems
+}
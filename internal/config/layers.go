@@ -0,0 +1,387 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source identifies the configuration layer a value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceProfile Source = "profile"
+	SourceProject Source = "project"
+)
+
+// ProjectDirName is the per-project directory holding the local overlay
+const ProjectDirName = ".skagent"
+
+// Origin records where an effective configuration value was set
+type Origin struct {
+	Source Source `json:"source"`
+	Detail string `json:"detail,omitempty"` // file path or env variable name
+}
+
+// Effective is the merged configuration together with per-field provenance
+type Effective struct {
+	Config     *Config
+	Values     map[string]interface{} // flattened dotted path -> value
+	Provenance map[string]Origin      // flattened dotted path -> origin
+	Layers     []Origin               // layers that contributed, in order
+}
+
+// LoadOptions controls how the effective configuration is assembled
+type LoadOptions struct {
+	ConfigFile string // defaults to ConfigPath()
+	Profile    string // defaults to $SKAGENT_PROFILE
+	ProjectDir string // directory to search upwards from; defaults to cwd
+}
+
+// envBinding maps an environment variable to a config path
+type envBinding struct {
+	name    string
+	path    string
+	numeric bool
+	boolean bool
+}
+
+// envBindings lists the environment variables understood by the env layer
+var envBindings = []envBinding{
+	{name: "SKAGENT_DEFAULT_PROVIDER", path: "default_provider"},
+	{name: "SKAGENT_SPECKIT_PATH", path: "speckit_path"},
+	{name: "SKAGENT_THEME", path: "theme"},
+	{name: "SKAGENT_LOCALE", path: "locale"},
+	{name: "SKAGENT_AUTONOMOUS", path: "autonomous_default", boolean: true},
+	{name: "SKAGENT_DRY_RUN", path: "dry_run", boolean: true},
+	{name: "SKAGENT_API_HOST", path: "api.host"},
+	{name: "SKAGENT_API_PORT", path: "api.port", numeric: true},
+	{name: "SKAGENT_API_TLS_CERT", path: "api.tls.cert_file"},
+	{name: "SKAGENT_API_TLS_KEY", path: "api.tls.key_file"},
+	{name: "SKAGENT_API_TLS_CLIENT_CA", path: "api.tls.client_ca_file"},
+	{name: "SKAGENT_API_CALLBACK_SECRET", path: "api.callback_secret"},
+	{name: "SKAGENT_API_SOCKET", path: "api.socket"},
+	{name: "SKAGENT_REVIEW_WEBHOOK_SECRET", path: "review.webhook_secret"},
+	{name: "SKAGENT_MCP_HOST", path: "mcp.host"},
+	{name: "SKAGENT_MCP_PORT", path: "mcp.port", numeric: true},
+	{name: "SKAGENT_MCP_SOCKET", path: "mcp.socket"},
+	{name: "SKAGENT_MCP_TOKEN", path: "mcp.token"},
+	{name: "SKAGENT_LOG_LEVEL", path: "headless.log_level"},
+	{name: "SKAGENT_STORAGE_DSN", path: "storage.dsn"},
+	{name: "SKAGENT_PROJECT_URL", path: "project.base_url"},
+	{name: "SKAGENT_PROJECT_API_KEY", path: "project.api_key"},
+	{name: "SKAGENT_PROJECT_MAX_PENDING_TASKS", path: "project.max_pending_tasks", numeric: true},
+	{name: "SKAGENT_PROJECT_IMPORT_TASKS", path: "project.import_tasks", boolean: true},
+	{name: "OPENROUTER_API_KEY", path: "providers.openrouter.api_key"},
+	{name: "DEEPSEEK_API_KEY", path: "providers.deepseek.api_key"},
+	{name: "MOONSHOT_API_KEY", path: "providers.kimi.api_key"},
+	{name: "GLM_API_KEY", path: "providers.glm.api_key"},
+	{name: "MINIMAX_API_KEY", path: "providers.minimax.api_key"},
+}
+
+// LoadEffective merges defaults, the config file, environment variables,
+// the selected profile and the project overlay, in that order, recording
+// which layer set every field
+func LoadEffective(opts LoadOptions) (*Effective, error) {
+	eff := &Effective{
+		Values:     make(map[string]interface{}),
+		Provenance: make(map[string]Origin),
+	}
+
+	merged, err := toMap(DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	eff.record(merged, "", Origin{Source: SourceDefault})
+
+	// Config file
+	path := opts.ConfigFile
+	if path == "" {
+		if path, err = ConfigPath(); err != nil {
+			return nil, err
+		}
+	}
+	if err := eff.applyFile(merged, path, SourceFile); err != nil {
+		return nil, err
+	}
+
+	// Environment
+	envLayer := make(map[string]interface{})
+	for _, b := range envBindings {
+		raw, ok := os.LookupEnv(b.name)
+		if !ok || raw == "" {
+			continue
+		}
+		value, err := b.parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		setPath(envLayer, b.path, value)
+		eff.Provenance[b.path] = Origin{Source: SourceEnv, Detail: b.name}
+	}
+	if len(envLayer) > 0 {
+		mergeMaps(merged, envLayer)
+		eff.Layers = append(eff.Layers, Origin{Source: SourceEnv})
+	}
+
+	// Profile
+	profile := opts.Profile
+	if profile == "" {
+		profile = os.Getenv("SKAGENT_PROFILE")
+	}
+	if profile != "" {
+		profilePath, err := ProfilePath(profile)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(profilePath); err != nil {
+			return nil, fmt.Errorf("profile %q not found at %s", profile, profilePath)
+		}
+		if err := eff.applyFile(merged, profilePath, SourceProfile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Project overlay
+	if projectFile := FindProjectConfig(opts.ProjectDir); projectFile != "" {
+		if err := eff.applyFile(merged, projectFile, SourceProject); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode merged config: %w", err)
+	}
+	eff.Config = &cfg
+
+	flatten(merged, "", eff.Values)
+	return eff, nil
+}
+
+// ProfilePath returns the path of a named configuration profile
+func ProfilePath(name string) (string, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "profiles", name+".json"), nil
+}
+
+// FindProjectConfig walks up from dir looking for .skagent/config.json
+func FindProjectConfig(dir string) string {
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return ""
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, ProjectDirName, "config.json")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Paths returns the flattened field paths in sorted order
+func (e *Effective) Paths() []string {
+	paths := make([]string, 0, len(e.Values))
+	for p := range e.Values {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// OriginOf returns the origin of a field, falling back to its closest parent
+func (e *Effective) OriginOf(path string) Origin {
+	for p := path; p != ""; {
+		if o, ok := e.Provenance[p]; ok {
+			return o
+		}
+		i := strings.LastIndex(p, ".")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	return Origin{Source: SourceDefault}
+}
+
+func (e *Effective) applyFile(merged map[string]interface{}, path string, source Source) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && source == SourceFile {
+			return nil
+		}
+		return err
+	}
+	var layer map[string]interface{}
+	if err := json.Unmarshal(data, &layer); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	origin := Origin{Source: source, Detail: path}
+	e.record(layer, "", origin)
+	e.Layers = append(e.Layers, origin)
+	mergeMaps(merged, layer)
+	return nil
+}
+
+// record marks every leaf of layer as originating from origin
+func (e *Effective) record(layer map[string]interface{}, prefix string, origin Origin) {
+	for k, v := range layer {
+		path := joinPath(prefix, k)
+		if child, ok := v.(map[string]interface{}); ok && len(child) > 0 {
+			e.record(child, path, origin)
+			continue
+		}
+		e.Provenance[path] = origin
+	}
+}
+
+func (b envBinding) parse(raw string) (interface{}, error) {
+	switch {
+	case b.numeric:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected a number, got %q", b.name, raw)
+		}
+		return n, nil
+	case b.boolean:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected a boolean, got %q", b.name, raw)
+		}
+		return v, nil
+	default:
+		return raw, nil
+	}
+}
+
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// mergeMaps deep-merges src into dst; non-map values in src replace dst
+func mergeMaps(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcChild, srcIsMap := v.(map[string]interface{})
+		dstChild, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstChild, srcChild)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+func setPath(m map[string]interface{}, path string, value interface{}) {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := m[p].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			m[p] = child
+		}
+		m = child
+	}
+	m[parts[len(parts)-1]] = value
+}
+
+func flatten(m map[string]interface{}, prefix string, out map[string]interface{}) {
+	for k, v := range m {
+		path := joinPath(prefix, k)
+		if child, ok := v.(map[string]interface{}); ok && len(child) > 0 {
+			flatten(child, path, out)
+			continue
+		}
+		out[path] = v
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// Diff returns the flattened paths, such as "api.rate_limit", whose values
+// differ between two configurations, in sorted order
+func Diff(a, b *Config) ([]string, error) {
+	am, err := toMap(a)
+	if err != nil {
+		return nil, err
+	}
+	bm, err := toMap(b)
+	if err != nil {
+		return nil, err
+	}
+	av := make(map[string]interface{})
+	bv := make(map[string]interface{})
+	flatten(am, "", av)
+	flatten(bm, "", bv)
+
+	var changed []string
+	for path, v := range av {
+		if w, ok := bv[path]; !ok || !reflect.DeepEqual(v, w) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range bv {
+		if _, ok := av[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// IsSecretPath reports whether a flattened config path holds a credential
+func IsSecretPath(path string) bool {
+	return strings.HasSuffix(path, "api_key") || strings.HasSuffix(path, "token") ||
+		strings.HasSuffix(path, "secret") || strings.HasSuffix(path, "password") ||
+		// Connection strings may carry a password
+		strings.HasSuffix(path, "dsn")
+}
+
+// MaskSecret hides all but the last four characters of a credential
+func MaskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 4 {
+		return "****"
+	}
+	return "****" + value[len(value)-4:]
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadEffective_Provenance(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SKAGENT_PROFILE", "")
+	t.Setenv("SKAGENT_API_PORT", "9090")
+
+	cfgFile := filepath.Join(home, "config.json")
+	writeFile(t, cfgFile, `{"api": {"host": "0.0.0.0"}, "providers": {"openrouter": {"model": "file-model"}}}`)
+
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, ".skagent", "config.json"), `{"providers": {"openrouter": {"model": "project-model"}}}`)
+
+	eff, err := LoadEffective(LoadOptions{ConfigFile: cfgFile, ProjectDir: filepath.Join(project, "sub")})
+	if err != nil {
+		t.Fatalf("LoadEffective: %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		source Source
+	}{
+		{"api.host", SourceFile},
+		{"api.port", SourceEnv},
+		{"providers.openrouter.model", SourceProject},
+		{"mcp.port", SourceDefault},
+	}
+	for _, tt := range tests {
+		if got := eff.OriginOf(tt.path).Source; got != tt.source {
+			t.Errorf("OriginOf(%q) = %s, want %s", tt.path, got, tt.source)
+		}
+	}
+
+	if eff.Config.API.Port != 9090 {
+		t.Errorf("API.Port = %d, want 9090", eff.Config.API.Port)
+	}
+	if got := eff.Config.Providers[ProviderOpenRouter].Model; got != "project-model" {
+		t.Errorf("openrouter model = %q, want project-model", got)
+	}
+	if !eff.Config.Providers[ProviderOpenRouter].Enabled {
+		t.Error("deep merge dropped providers.openrouter.enabled")
+	}
+}
+
+func TestLoadEffective_MissingProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := LoadEffective(LoadOptions{Profile: "nope", ProjectDir: t.TempDir()}); err == nil {
+		t.Error("expected error for missing profile")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected missing OpenRouter key to be reported")
+	}
+
+	p := cfg.Providers[ProviderOpenRouter]
+	p.APIKey = "sk-or-test"
+	cfg.Providers[ProviderOpenRouter] = p
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+
+	cfg.Headless.LogLevel = "loud"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected invalid log level to be reported")
+	}
+	cfg.Headless.LogLevel = "info"
+
+	cfg.Chaos.Provider = FaultConfig{ErrorRate: 0.7, RateLimitRate: 0.5, LatencyRate: 0.1}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "add up to more than 1") || !strings.Contains(err.Error(), "chaos.provider.latency_ms") {
+		t.Errorf("expected the chaos rates to be reported, got %v", err)
+	}
+	cfg.Chaos.Provider = FaultConfig{}
+
+	cfg.MCPServers = []MCPServerConfig{
+		{Name: "fs", Command: "mcp-fs"},
+		{Name: "fs", URL: "ftp://example.com/sse"},
+		{Name: "Browser", Command: "mcp-browser", URL: "http://localhost:3000/sse"},
+		{Name: "files", Command: "mcp-files", Namespace: "fs"},
+		{Name: "web", Command: "mcp-web", Namespace: "Web"},
+	}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "used twice") || !strings.Contains(err.Error(), "http") || !strings.Contains(err.Error(), "exactly one") ||
+		!strings.Contains(err.Error(), `namespace "fs" is already the namespace of mcp_servers[0]`) || !strings.Contains(err.Error(), `namespace "Web"`) {
+		t.Errorf("expected the MCP server problems to be reported, got %v", err)
+	}
+	cfg.MCPServers = nil
+
+	cfg.MCP.Sampling, cfg.MCP.SamplingMaxTokens, cfg.MCP.SessionRateLimit = "always", -1, -1
+	cfg.MCP.Transports = []string{MCPTransportWebSocket, "grpc"}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "mcp.sampling \"always\"") || !strings.Contains(err.Error(), "mcp.sampling_max_tokens") ||
+		!strings.Contains(err.Error(), "mcp.session_rate_limit") || !strings.Contains(err.Error(), "mcp.transports[1] \"grpc\"") {
+		t.Errorf("expected the sampling, session and transport settings to be reported, got %v", err)
+	}
+	cfg.MCP.Sampling, cfg.MCP.SamplingMaxTokens, cfg.MCP.SessionRateLimit = SamplingFallback, 0, 0
+	cfg.MCP.Transports = nil
+
+	cfg.Digest.At, cfg.Digest.MaxErrors = "7am", -1
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "digest.at \"7am\"") || !strings.Contains(err.Error(), "digest.max_errors") {
+		t.Errorf("expected the digest settings to be reported, got %v", err)
+	}
+	cfg.Digest.At, cfg.Digest.MaxErrors = "07:00", 5
+
+	cfg.Storage.Driver = "mysql"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `storage.driver "mysql"`) {
+		t.Errorf("expected the storage driver to be reported, got %v", err)
+	}
+	cfg.Storage.Driver, cfg.Storage.SyncInterval = StoragePostgres, -1
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "storage.dsn") || !strings.Contains(err.Error(), "storage.sync_interval") {
+		t.Errorf("expected the postgres settings to be reported, got %v", err)
+	}
+	cfg.Storage.Driver, cfg.Storage.SyncInterval = StorageSQLite, 0
+
+	cfg.MCP.Views = []MCPViewConfig{{Name: "chat", Tools: []string{"get_["}}}
+	cfg.MCP.DefaultView = "ide"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "mcp.views[0].tools") || !strings.Contains(err.Error(), `mcp.default_view "ide"`) {
+		t.Errorf("expected the MCP views to be reported, got %v", err)
+	}
+	cfg.MCP.Views, cfg.MCP.DefaultView = nil, ""
+
+	cfg.API.Socket, cfg.MCP.Socket = "/run/skagent.sock", "/run/skagent.sock"
+	cfg.API.SocketMode = "rw-rw----"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "api.socket_mode") || !strings.Contains(err.Error(), "must differ") {
+		t.Errorf("expected the socket problems to be reported, got %v", err)
+	}
+}
+
+func TestMCPAuth(t *testing.T) {
+	cfg := DefaultConfig()
+	p := cfg.Providers[ProviderOpenRouter]
+	p.APIKey = "sk-or-test"
+	cfg.Providers[ProviderOpenRouter] = p
+	cfg.Auth.Keys = map[string]APIKeyConfig{"ci": {Token: "ci-token", Role: "operator"}}
+
+	cfg.MCP.Token = "shared"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "mcp.enable_auth is off") {
+		t.Errorf("expected a token without enable_auth to be reported, got %v", err)
+	}
+	cfg.MCP.EnableAuth = true
+	cfg.MCP.TokenRole = "superuser"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "mcp.token_role") {
+		t.Errorf("expected the undefined role to be reported, got %v", err)
+	}
+	cfg.MCP.TokenRole = ""
+	cfg.Auth.Keys = nil
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("mcp.token alone should satisfy mcp.enable_auth: %v", err)
+	}
+
+	cfg.Auth.Keys = map[string]APIKeyConfig{"ci": {Token: "ci-token", Role: "operator"}}
+	a := cfg.MCPAuth()
+	if len(a.Keys) != 2 || a.Keys[MCPTokenKey].Token != "shared" || a.Keys[MCPTokenKey].Role != "viewer" {
+		t.Errorf("MCPAuth keys = %+v", a.Keys)
+	}
+	if _, ok := cfg.Auth.Keys[MCPTokenKey]; ok {
+		t.Error("MCPAuth must not modify auth.keys")
+	}
+}
@@ -0,0 +1,398 @@
+// Package constitution checks generated plans against the project
+// constitution, the SpecKit articles such as Test-First, so that a plan
+// breaking them is reported before its tasks are created.
+package constitution
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// DefaultMaxProjects is the Simplicity limit when the configuration sets
+// none
+const DefaultMaxProjects = 3
+
+// Task is one step of a plan
+type Task struct {
+	// ID is the SpecKit task ID, such as "T004", or the position of the
+	// task in a batch when it has none
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Section string   `json:"section,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+	// DependsOn lists the IDs of the tasks this one refers to
+	DependsOn []string `json:"depends_on,omitempty"`
+	ProjectID string   `json:"project_id,omitempty"`
+	// Source is where the task came from, such as "operations[2]" for a
+	// task of a batch
+	Source string `json:"source,omitempty"`
+}
+
+// Plan is an ordered list of tasks
+type Plan struct {
+	Tasks []Task `json:"tasks"`
+}
+
+// Task returns the task with the given ID
+func (p *Plan) Task(id string) (Task, bool) {
+	for _, t := range p.Tasks {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Task{}, false
+}
+
+// Violation is a rule a plan breaks
+type Violation struct {
+	Rule    string `json:"rule"`
+	Article string `json:"article"`
+	// TaskID is the offending task; empty when the plan as a whole breaks
+	// the rule
+	TaskID  string `json:"task_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of checking a plan
+type Report struct {
+	Rules      []string    `json:"rules"`
+	Tasks      int         `json:"tasks"`
+	Violations []Violation `json:"violations"`
+	// Enforced tells whether a plan with violations is rejected
+	Enforced bool `json:"enforced"`
+}
+
+// OK reports whether the plan breaks no rule
+func (r *Report) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// Rejected reports whether the plan breaks a rule that is enforced
+func (r *Report) Rejected() bool {
+	return r.Enforced && !r.OK()
+}
+
+// rule checks one article of the constitution
+type rule struct {
+	article string
+	check   func(p *Plan, c *Checker) []Violation
+}
+
+// Rules are the built-in rules, by name
+var rules = map[string]rule{
+	"library-first":     {article: "I. Library-First", check: checkLibraryFirst},
+	"cli-mandate":       {article: "II. CLI Mandate", check: checkCLIMandate},
+	"test-first":        {article: "III. Test-First", check: checkTestFirst},
+	"simplicity":        {article: "IV. Simplicity", check: checkSimplicity},
+	"integration-first": {article: "VI. Integration-First", check: checkIntegrationFirst},
+}
+
+// RuleNames returns the names of the built-in rules, sorted
+func RuleNames() []string {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Checker validates plans against the configured rules. It holds no
+// mutable state and is safe for concurrent use.
+type Checker struct {
+	rules       []string
+	enforce     bool
+	maxProjects int
+}
+
+// NewChecker returns a checker for the rules cfg names, or every built-in
+// rule when it names none
+func NewChecker(cfg config.ConstitutionConfig) (*Checker, error) {
+	c := &Checker{enforce: cfg.Enforce, maxProjects: cfg.MaxProjects}
+	if c.maxProjects <= 0 {
+		c.maxProjects = DefaultMaxProjects
+	}
+	if len(cfg.Rules) == 0 {
+		c.rules = RuleNames()
+		return c, nil
+	}
+	for _, name := range cfg.Rules {
+		if _, ok := rules[name]; !ok {
+			return nil, fmt.Errorf("unknown constitution rule %q (known: %s)", name, strings.Join(RuleNames(), ", "))
+		}
+		c.rules = append(c.rules, name)
+	}
+	return c, nil
+}
+
+// Rules returns the names of the rules the checker applies
+func (c *Checker) Rules() []string {
+	return append([]string(nil), c.rules...)
+}
+
+// Enforced reports whether plans that break a rule are rejected
+func (c *Checker) Enforced() bool {
+	return c.enforce
+}
+
+// Check validates a plan
+func (c *Checker) Check(p Plan) Report {
+	report := Report{Rules: c.Rules(), Tasks: len(p.Tasks), Violations: []Violation{}, Enforced: c.enforce}
+	if len(p.Tasks) == 0 {
+		return report
+	}
+	for _, name := range c.rules {
+		r := rules[name]
+		for _, v := range r.check(&p, c) {
+			v.Rule, v.Article = name, r.article
+			report.Violations = append(report.Violations, v)
+		}
+	}
+	return report
+}
+
+// taskIDPattern matches SpecKit task IDs
+var taskIDPattern = regexp.MustCompile(`\bT\d{3,}\b`)
+
+// checklistItem matches a markdown task line: "- [ ] T001 [P] ..."
+var checklistItem = regexp.MustCompile(`^\s*[-*]\s*\[[ xX]\]\s*(.*)$`)
+
+// ParseTasks reads the tasks of a SpecKit tasks.md, or of any reply that
+// lists tasks as a markdown checklist. The heading above a task is its
+// section.
+func ParseTasks(markdown string) Plan {
+	var p Plan
+	section := ""
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			section = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			continue
+		}
+		m := checklistItem.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		t := parseTask(m[1], len(p.Tasks))
+		t.Section = section
+		p.Tasks = append(p.Tasks, t)
+	}
+	return p
+}
+
+// FromTaskOps builds a plan from the tasks a bulk batch creates. Titles
+// and descriptions are read like the lines of a tasks.md, so that
+// SpecKit IDs and the references between tasks are kept.
+func FromTaskOps(ops []agents.TaskOp) Plan {
+	var p Plan
+	for i, op := range ops {
+		if op.Op != agents.BulkCreate {
+			continue
+		}
+		t := parseTask(strings.TrimSpace(op.Title+"\n"+op.Description), i)
+		t.Source = fmt.Sprintf("operations[%d]", i)
+		if strings.HasPrefix(t.ID, "#") {
+			t.ID = t.Source
+		}
+		t.Labels = op.Labels
+		t.ProjectID = op.ProjectID
+		p.Tasks = append(p.Tasks, t)
+	}
+	return p
+}
+
+// parseTask reads "T003 [P] Implement the user model (depends on T002)"
+func parseTask(text string, index int) Task {
+	t := Task{ID: fmt.Sprintf("#%d", index+1)}
+	if fields := strings.Fields(text); len(fields) > 0 {
+		if id := strings.Trim(fields[0], "*:"); id != "" && taskIDPattern.FindString(id) == id {
+			t.ID = id
+			text = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), fields[0]))
+		}
+	}
+	t.Title = strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	for _, ref := range taskIDPattern.FindAllString(text, -1) {
+		if ref != t.ID && !contains(t.DependsOn, ref) {
+			t.DependsOn = append(t.DependsOn, ref)
+		}
+	}
+	return t
+}
+
+// text is everything that describes a task, lower-cased
+func (t *Task) text() string {
+	return strings.ToLower(t.Title + " " + t.Section + " " + strings.Join(t.Labels, " "))
+}
+
+var (
+	testWords        = regexp.MustCompile(`\b(tests?|testing|tdd|e2e)\b`)
+	integrationWords = regexp.MustCompile(`\b(integration|contract|e2e|end-to-end)\b`)
+	cliWords         = regexp.MustCompile(`\b(cli|command|subcommand)\b`)
+)
+
+// isTest reports whether the task writes or runs tests
+func (t *Task) isTest() bool {
+	return testWords.MatchString(t.text())
+}
+
+// isImplementation reports whether the task writes production code
+func (t *Task) isImplementation() bool {
+	if t.isTest() {
+		return false
+	}
+	title := strings.ToLower(t.Title)
+	for _, verb := range []string{"implement", "build "} {
+		if strings.HasPrefix(title, verb) {
+			return true
+		}
+	}
+	for _, l := range t.Labels {
+		if strings.EqualFold(l, "implementation") || strings.EqualFold(l, "implement") {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(t.Section), "implementation") || strings.Contains(strings.ToLower(t.Section), "core")
+}
+
+// checkTestFirst requires every implementation task to refer to a test
+// task of the plan
+func checkTestFirst(p *Plan, _ *Checker) []Violation {
+	tests := make(map[string]bool)
+	for i := range p.Tasks {
+		if p.Tasks[i].isTest() {
+			tests[p.Tasks[i].ID] = true
+		}
+	}
+	var out []Violation
+	for i := range p.Tasks {
+		t := &p.Tasks[i]
+		if !t.isImplementation() {
+			continue
+		}
+		covered := false
+		for _, dep := range t.DependsOn {
+			if tests[dep] {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			out = append(out, Violation{TaskID: t.ID, Message: fmt.Sprintf("implementation task %q does not refer to a test task", t.Title)})
+		}
+	}
+	return out
+}
+
+// checkIntegrationFirst requires a plan with tests to test in a realistic
+// environment, with contract, integration or end-to-end tests
+func checkIntegrationFirst(p *Plan, _ *Checker) []Violation {
+	hasTests := false
+	for i := range p.Tasks {
+		t := &p.Tasks[i]
+		if !t.isTest() {
+			continue
+		}
+		hasTests = true
+		if integrationWords.MatchString(t.text()) {
+			return nil
+		}
+	}
+	if !hasTests {
+		return nil
+	}
+	return []Violation{{Message: "the plan has only unit tests; add a contract, integration or end-to-end test task"}}
+}
+
+// checkCLIMandate requires a plan that implements something to expose it
+// through a CLI task
+func checkCLIMandate(p *Plan, _ *Checker) []Violation {
+	implements := false
+	for i := range p.Tasks {
+		t := &p.Tasks[i]
+		if cliWords.MatchString(t.text()) {
+			return nil
+		}
+		implements = implements || t.isImplementation()
+	}
+	if !implements {
+		return nil
+	}
+	return []Violation{{Message: "no task exposes the feature through the CLI"}}
+}
+
+// checkLibraryFirst flags features implemented straight into an
+// application entry point rather than a library
+func checkLibraryFirst(p *Plan, _ *Checker) []Violation {
+	var out []Violation
+	for i := range p.Tasks {
+		t := &p.Tasks[i]
+		if !t.isImplementation() {
+			continue
+		}
+		for _, path := range paths(t.Title) {
+			base := path[strings.LastIndex(path, "/")+1:]
+			if strings.HasPrefix(base, "main.") || strings.HasPrefix(base, "app.") {
+				out = append(out, Violation{TaskID: t.ID, Message: fmt.Sprintf("%s implements the feature in %s instead of a library", t.ID, path)})
+				break
+			}
+		}
+	}
+	return out
+}
+
+// sharedRoots are top-level directories that do not make a project of
+// their own
+var sharedRoots = map[string]bool{
+	"src": true, "tests": true, "test": true, "docs": true, "spec": true, "specs": true,
+	"scripts": true, "cmd": true, "internal": true, "pkg": true, "lib": true,
+}
+
+// checkSimplicity limits the projects a plan touches: the distinct
+// project IDs of its tasks, or else the top-level directories, such as
+// backend/ and frontend/, of the files it names
+func checkSimplicity(p *Plan, c *Checker) []Violation {
+	projects := make(map[string]bool)
+	for i := range p.Tasks {
+		t := &p.Tasks[i]
+		if t.ProjectID != "" {
+			projects[t.ProjectID] = true
+			continue
+		}
+		for _, path := range paths(t.Title) {
+			root := strings.SplitN(path, "/", 2)[0]
+			if !sharedRoots[root] {
+				projects[root] = true
+			}
+		}
+	}
+	if len(projects) <= c.maxProjects {
+		return nil
+	}
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return []Violation{{Message: fmt.Sprintf("the plan spans %d projects (%s); at most %d are allowed", len(names), strings.Join(names, ", "), c.maxProjects)}}
+}
+
+// pathPattern matches relative file paths such as src/models/user.py
+var pathPattern = regexp.MustCompile(`\b[\w.-]+(?:/[\w.-]+)+\.\w+\b`)
+
+func paths(text string) []string {
+	return pathPattern.FindAllString(text, -1)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
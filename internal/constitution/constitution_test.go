@@ -0,0 +1,74 @@
+package constitution
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+const tasksMD = `# Tasks: User accounts
+
+## Phase 3.2: Tests First (TDD)
+- [ ] T004 [P] Contract test POST /users in tests/contract/test_users_post.py
+- [ ] T005 [P] Unit tests for password hashing in tests/unit/test_hash.py
+
+## Phase 3.3: Core Implementation
+- [ ] T006 Implement the user model in backend/src/models/user.py (makes T004 pass)
+- [ ] T007 Implement password hashing in backend/src/services/hash.py
+- [ ] T008 Add the users command to the CLI in backend/src/cli/users.py (T004)
+`
+
+func TestCheckTasksMD(t *testing.T) {
+	plan := ParseTasks(tasksMD)
+	if len(plan.Tasks) != 5 {
+		t.Fatalf("parsed %d tasks: %+v", len(plan.Tasks), plan.Tasks)
+	}
+	if got := plan.Tasks[2]; got.ID != "T006" || got.Section != "Phase 3.3: Core Implementation" || len(got.DependsOn) != 1 || got.DependsOn[0] != "T004" {
+		t.Errorf("T006 = %+v", got)
+	}
+
+	c, err := NewChecker(config.ConstitutionConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := c.Check(plan)
+	if len(report.Violations) != 1 {
+		t.Fatalf("violations = %+v", report.Violations)
+	}
+	if v := report.Violations[0]; v.Rule != "test-first" || v.TaskID != "T007" || v.Article != "III. Test-First" {
+		t.Errorf("violation = %+v", v)
+	}
+	if report.Rejected() {
+		t.Error("a report is rejected only when the rules are enforced")
+	}
+
+	fixed := strings.Replace(tasksMD, "hash.py\n- [ ] T008", "hash.py after T005\n- [ ] T008", 1)
+	if report := c.Check(ParseTasks(fixed)); !report.OK() {
+		t.Errorf("fixed plan: %+v", report.Violations)
+	}
+}
+
+func TestCheckTaskOps(t *testing.T) {
+	c, err := NewChecker(config.ConstitutionConfig{Enforce: true, Rules: []string{"simplicity", "integration-first"}, MaxProjects: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := FromTaskOps([]agents.TaskOp{
+		{Op: agents.BulkCreate, Title: "Write unit tests for the parser", ProjectID: "api"},
+		{Op: agents.BulkDelete, ID: "old"},
+		{Op: agents.BulkCreate, Title: "Implement the parser", ProjectID: "web"},
+	})
+	if len(plan.Tasks) != 2 || plan.Tasks[1].ID != "operations[2]" {
+		t.Fatalf("plan = %+v", plan.Tasks)
+	}
+	report := c.Check(plan)
+	if !report.Rejected() || len(report.Violations) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	if _, err := NewChecker(config.ConstitutionConfig{Rules: []string{"test-last"}}); err == nil {
+		t.Error("unknown rule accepted")
+	}
+}
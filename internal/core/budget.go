@@ -0,0 +1,67 @@
+package core
+
+import (
+	"github.com/biodoia/skagent/internal/docs"
+)
+
+// ContextBudget reports how the prompt of a request was assembled, in
+// tokens estimated at four bytes each, so that users can see what fills
+// the model's context window and tune it
+type ContextBudget struct {
+	Model string `json:"model,omitempty"`
+	// ContextWindow is the model's context window, 0 when unknown
+	ContextWindow int `json:"context_window,omitempty"`
+	// SystemPromptTokens is the base system prompt with the mode
+	// instructions, without the docs and lessons counted apart
+	SystemPromptTokens int `json:"system_prompt_tokens"`
+	// DocsTokens is the SpecKit docs kept in the system prompt, out of
+	// DocsAvailableTokens, within DocsBudgetTokens when the window is
+	// known
+	DocsTokens          int `json:"docs_tokens"`
+	DocsAvailableTokens int `json:"docs_available_tokens"`
+	DocsBudgetTokens    int `json:"docs_budget_tokens,omitempty"`
+	// LessonsTokens is what the session's agent learned from similar tasks
+	LessonsTokens int `json:"lessons_tokens"`
+	// HistoryTokens is the earlier messages of the session
+	HistoryTokens   int `json:"history_tokens"`
+	HistoryMessages int `json:"history_messages"`
+	// InputTokens is the new message
+	InputTokens int `json:"input_tokens"`
+	// ToolOutputsTrimmed counts the tool outputs recorded in the history
+	// that were left out of the prompt, and ToolOutputTokens their size
+	ToolOutputsTrimmed int `json:"tool_outputs_trimmed"`
+	ToolOutputTokens   int `json:"tool_output_tokens"`
+	// TotalTokens is the whole prompt, and WindowUsed its share of the
+	// context window when that is known
+	TotalTokens int     `json:"total_tokens"`
+	WindowUsed  float64 `json:"window_used,omitempty"`
+}
+
+// countHistory adds the messages sent to the model, all but the last of
+// which are history, and the tool outputs left out of them
+func (b *ContextBudget) countHistory(messages []Message) {
+	for i, msg := range messages {
+		tokens := docs.EstimateTokens(msg.Content)
+		if i == len(messages)-1 {
+			b.InputTokens = tokens
+		} else {
+			b.HistoryTokens += tokens
+			b.HistoryMessages++
+		}
+		// Only the content of a message is sent, not its tool calls
+		for _, call := range msg.ToolCalls {
+			if call.Output != "" {
+				b.ToolOutputsTrimmed++
+				b.ToolOutputTokens += docs.EstimateTokens(call.Output)
+			}
+		}
+	}
+}
+
+// total sums the prompt up and its share of the window
+func (b *ContextBudget) total() {
+	b.TotalTokens = b.SystemPromptTokens + b.DocsTokens + b.LessonsTokens + b.HistoryTokens + b.InputTokens
+	if b.ContextWindow > 0 {
+		b.WindowUsed = float64(int(float64(b.TotalTokens)/float64(b.ContextWindow)*1000+0.5)) / 1000
+	}
+}
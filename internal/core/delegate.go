@@ -0,0 +1,183 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/constitution"
+	"github.com/google/uuid"
+)
+
+// Metadata keys of the tasks created from a session's plan
+const (
+	// TaskSource is the source of the tasks
+	TaskSource = "session"
+	// MetaSession and MetaMessage name the session and the message that
+	// held the plan
+	MetaSession = "session"
+	MetaMessage = "message"
+	// MetaPlanTask is the ID of the task in the plan, such as "T003"
+	MetaPlanTask = "plan_task"
+)
+
+var (
+	// ErrNoPlan is returned when a session has no message listing tasks
+	ErrNoPlan = NewError("the session has no plan: no message lists tasks as a markdown checklist")
+	// ErrPlanDelegated is returned when the tasks of a plan were created
+	// before
+	ErrPlanDelegated = NewError("the plan was already converted into tasks")
+	// ErrPlanRejected is returned when the plan breaks an enforced rule of
+	// the constitution
+	ErrPlanRejected = NewError("the plan breaks the constitution")
+	// ErrPlanPartial is returned when the message holding the plan was cut
+	// off, so the plan may miss its last steps
+	ErrPlanPartial = NewError("the plan's message is partial: the reply was cut off before its end")
+)
+
+// DelegateOptions tune the tasks created from a plan; every field is
+// optional
+type DelegateOptions struct {
+	// MessageID is the message holding the plan. By default it is the last
+	// assistant message that lists tasks.
+	MessageID string `json:"message_id,omitempty"`
+	// Labels are added to every task, for routing
+	Labels   []string             `json:"labels,omitempty"`
+	Priority *agents.TaskPriority `json:"priority,omitempty"`
+	// Force creates the tasks again for a plan already converted
+	Force bool `json:"force,omitempty"`
+	// AllowPartial creates the tasks of a plan whose message was cut off
+	AllowPartial bool `json:"allow_partial,omitempty"`
+}
+
+// Delegation is a plan converted into tasks
+type Delegation struct {
+	SessionID string            `json:"session_id"`
+	MessageID string            `json:"message_id"`
+	Plan      constitution.Plan `json:"plan"`
+	// Tasks are the tasks created, in the order of the plan
+	Tasks []*agents.Task `json:"tasks"`
+	// Constitution is the check of the plan, when a checker is set
+	Constitution *constitution.Report `json:"constitution,omitempty"`
+}
+
+// TasksFromSession creates a registry task for each step of the plan
+// agreed in a session: the checklist of its last assistant message that
+// has one, or of opts.MessageID. The tasks are created at once in the
+// session's workspace, or not at all. A plan that breaks an enforced rule
+// of the constitution returns ErrPlanRejected with the report in the
+// delegation; one in a partial reply returns ErrPlanPartial unless
+// opts.AllowPartial is set.
+func (e *Engine) TasksFromSession(sessionID string, opts DelegateOptions, c agents.Cause) (*Delegation, error) {
+	session, ok := e.SessionSnapshot(sessionID)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	msg, plan, err := findPlan(session.Messages, opts.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Force && e.delegated(sessionID, msg.ID) {
+		return nil, ErrPlanDelegated
+	}
+	if msg.Metadata.Partial && !opts.AllowPartial {
+		return nil, ErrPlanPartial
+	}
+
+	d := &Delegation{SessionID: sessionID, MessageID: msg.ID, Plan: plan}
+	ops := PlanTaskOps(plan, opts)
+	for i := range ops {
+		ops[i].Source = TaskSource
+		ops[i].ProjectID = session.Metadata.ProjectID
+		ops[i].Meta[MetaSession] = sessionID
+		ops[i].Meta[MetaMessage] = msg.ID
+	}
+	if e.constitution != nil {
+		report := e.constitution.Check(constitution.FromTaskOps(ops))
+		d.Constitution = &report
+		if report.Rejected() {
+			return d, ErrPlanRejected
+		}
+	}
+
+	results, err := e.agentRegistry.ApplyTaskOpsIn(session.Workspace, ops, c)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		d.Tasks = append(d.Tasks, r.Task)
+	}
+	e.logger.Printf("Created %d tasks from the plan of session %s", len(d.Tasks), sessionID)
+	return d, nil
+}
+
+// PlanTaskOps turns the steps of a plan into task creations. Tasks keep
+// the SpecKit ID of their step in the title, and a step that refers to
+// others lists the tasks created for them in its description.
+func PlanTaskOps(plan constitution.Plan, opts DelegateOptions) []agents.TaskOp {
+	ids := make(map[string]string, len(plan.Tasks))
+	for _, t := range plan.Tasks {
+		ids[t.ID] = uuid.New().String()
+	}
+
+	ops := make([]agents.TaskOp, 0, len(plan.Tasks))
+	for _, t := range plan.Tasks {
+		title := t.Title
+		if !strings.HasPrefix(t.ID, "#") {
+			title = t.ID + " " + title
+		}
+		var desc []string
+		if t.Section != "" {
+			desc = append(desc, "Section: "+t.Section)
+		}
+		for _, dep := range t.DependsOn {
+			if id, ok := ids[dep]; ok {
+				desc = append(desc, fmt.Sprintf("Depends on %s (task %s)", dep, id))
+			}
+		}
+		op := agents.TaskOp{
+			Op:          agents.BulkCreate,
+			ID:          ids[t.ID],
+			Title:       title,
+			Description: strings.Join(desc, "\n"),
+			Priority:    opts.Priority,
+			Labels:      append(append([]string(nil), opts.Labels...), t.Labels...),
+			Meta:        map[string]string{MetaPlanTask: t.ID},
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// findPlan finds the message holding the plan: messageID, or the last
+// assistant message listing tasks
+func findPlan(messages []Message, messageID string) (Message, constitution.Plan, error) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if messageID != "" {
+			if msg.ID != messageID {
+				continue
+			}
+		} else if msg.Role != "assistant" {
+			continue
+		}
+		if plan := constitution.ParseTasks(msg.Content); len(plan.Tasks) > 0 {
+			return msg, plan, nil
+		}
+		if messageID != "" {
+			break
+		}
+	}
+	return Message{}, constitution.Plan{}, ErrNoPlan
+}
+
+// delegated reports whether tasks were created from a message of a
+// session
+func (e *Engine) delegated(sessionID, messageID string) bool {
+	for _, t := range e.agentRegistry.ListTasks() {
+		if t.Source == TaskSource && t.Meta[MetaSession] == sessionID && t.Meta[MetaMessage] == messageID {
+			return true
+		}
+	}
+	return false
+}
@@ -3,14 +3,23 @@ package core
 import (
 	"context"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/biodoia/skagent/internal/ai"
 	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/chaos"
 	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/constitution"
+	"github.com/biodoia/skagent/internal/docs"
+	"github.com/biodoia/skagent/internal/lessons"
+	"github.com/biodoia/skagent/internal/logging"
 	"github.com/biodoia/skagent/internal/project"
+	"github.com/biodoia/skagent/internal/redact"
 	"github.com/biodoia/skagent/internal/tools"
 )
 
@@ -19,15 +28,43 @@ type Engine struct {
 	config         *config.Config
 	provider       ai.Provider
 	tools          *tools.ToolManager
+	// workspaceTools are the tools of workspaces with a checkout of their
+	// own, by workspace; see ToolsFor
+	workspaceTools map[string]*scopedTools
 	agentRegistry  *agents.Registry
 	projectManager *project.Manager
 	sessions       map[string]*Session
+	// processing holds the sessions with a message in progress, so that
+	// each session takes one message at a time
+	processing     map[string]bool
+	docSections    []docs.Section
+	lessons        *lessons.Store
+	constitution   *constitution.Checker
+	logger         *log.Logger
 	mu             sync.RWMutex
+
+	// providerMu guards provider, which a config reload can replace, and
+	// providerErr, why there is none
+	providerMu  sync.RWMutex
+	providerErr error
+	// providerFaults, set in chaos mode, is injected into every provider
+	providerFaults *chaos.Injector
+
+	// The last provider check, reused for providerCheckTTL
+	checkMu      sync.Mutex
+	checkedAt    time.Time
+	checkErr     error
+	checkChecked bool
 }
 
+// providerCheckTTL is how long a provider check is reused, so that
+// readiness probes do not call the provider's API on every request
+const providerCheckTTL = 30 * time.Second
+
 // Session represents a conversation session
 type Session struct {
 	ID        string       `json:"id"`
+	Workspace string       `json:"workspace"`
 	CreatedAt time.Time    `json:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at"`
 	Messages  []Message    `json:"messages"`
@@ -68,27 +105,54 @@ type MsgMeta struct {
 	Model    string `json:"model,omitempty"`
 	Tokens   int    `json:"tokens,omitempty"`
 	Duration int64  `json:"duration_ms,omitempty"`
+	// Partial marks a reply that is not the whole answer, see
+	// ProcessResult.Partial
+	Partial bool `json:"partial,omitempty"`
 }
 
-// NewEngine creates a new engine instance
-func NewEngine(ctx context.Context, cfg *config.Config, agentRegistry *agents.Registry) (*Engine, error) {
+// NewEngine creates a new engine instance with the provider cfg selects.
+// When the provider cannot be created the engine starts degraded: it
+// serves sessions, tools and everything else, but completions fail with
+// ErrNoProvider until SetProvider gives it one.
+func NewEngine(ctx context.Context, cfg *config.Config, agentRegistry *agents.Registry) *Engine {
 	provider, err := ai.CreateProvider(cfg)
+	engine := NewEngineWithProvider(ctx, cfg, agentRegistry, provider)
 	if err != nil {
-		return nil, err
+		engine.providerErr = fmt.Errorf("%w: %v", ErrNoProvider, err)
+		engine.logger.Printf("WARNING: starting without an AI provider, completions are unavailable: %v", err)
 	}
+	return engine
+}
 
+// NewEngineWithProvider creates an engine that completes with provider
+// instead of the one cfg selects
+func NewEngineWithProvider(ctx context.Context, cfg *config.Config, agentRegistry *agents.Registry, provider ai.Provider) *Engine {
 	tm := tools.NewToolManager()
 	tm.AddTool(tools.NewSpecKitTool(""))
 	tm.AddTool(tools.NewGitHubTool(""))
+	tm.AddTool(tools.NewGitTool(""))
 	tm.AddTool(tools.NewWebSearchTool())
 
 	engine := &Engine{
 		config:        cfg,
 		provider:      provider,
 		tools:         tm,
+		workspaceTools: make(map[string]*scopedTools),
 		agentRegistry: agentRegistry,
 		sessions:      make(map[string]*Session),
+		processing:    make(map[string]bool),
+		logger:        logging.New("engine", "[ENGINE] ", log.Writer()),
 	}
+	if cfg.Chaos.Enabled {
+		engine.providerFaults = chaos.New("provider", cfg.Chaos.Provider, cfg.Chaos.Seed)
+		if provider != nil {
+			engine.provider = ai.WithFaults(provider, engine.providerFaults)
+		}
+		tm.SetFaults(chaos.New("tool", cfg.Chaos.Tools, cfg.Chaos.Seed))
+		engine.logger.Printf("WARNING: chaos mode is on; provider and tool calls fail, slow down and truncate on purpose")
+	}
+	engine.docSections = engine.loadSpecKitDocs()
+	tm.AddTool(tools.NewDiffReviewTool(engine.Provider, 0))
 
 	// Initialize project manager if enabled
 	if cfg.IsProjectEnabled() {
@@ -97,13 +161,23 @@ func NewEngine(ctx context.Context, cfg *config.Config, agentRegistry *agents.Re
 		engine.projectManager = projectManager
 	}
 
-	return engine, nil
+	return engine
 }
 
-// CreateSession creates a new conversation session
+// CreateSession creates a new conversation session in the default
+// workspace
 func (e *Engine) CreateSession() *Session {
+	return e.CreateSessionIn(agents.DefaultWorkspace)
+}
+
+// CreateSessionIn creates a new conversation session in a workspace
+func (e *Engine) CreateSessionIn(workspace string) *Session {
+	if workspace == "" {
+		workspace = agents.DefaultWorkspace
+	}
 	session := &Session{
 		ID:        uuid.New().String(),
+		Workspace: workspace,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		Messages:  []Message{},
@@ -114,6 +188,7 @@ func (e *Engine) CreateSession() *Session {
 	e.sessions[session.ID] = session
 	e.mu.Unlock()
 
+	e.logger.Printf("Created session %s", session.ID)
 	return session
 }
 
@@ -137,6 +212,69 @@ func (e *Engine) ListSessions() []*Session {
 	return sessions
 }
 
+// SessionSnapshot returns a copy of a session that is safe to read while
+// the session is in use
+func (e *Engine) SessionSnapshot(id string) (Session, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	session, ok := e.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	return session.copy(), true
+}
+
+// SessionSnapshots returns copies of all sessions, oldest first
+func (e *Engine) SessionSnapshots() []Session {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	sessions := make([]Session, 0, len(e.sessions))
+	for _, s := range e.sessions {
+		sessions = append(sessions, s.copy())
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
+// UpdateSessionMeta replaces the metadata of a session
+func (e *Engine) UpdateSessionMeta(id string, meta SessionMeta) (Session, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	session, ok := e.sessions[id]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	session.Metadata = meta
+	session.UpdatedAt = time.Now()
+	return session.copy(), nil
+}
+
+// copy returns a copy that shares no slices or maps with s; the caller
+// holds the engine lock
+func (s *Session) copy() Session {
+	c := *s
+	c.Messages = append([]Message(nil), s.Messages...)
+	c.Metadata.Tags = append([]string(nil), s.Metadata.Tags...)
+	if s.Metadata.Custom != nil {
+		c.Metadata.Custom = make(map[string]string, len(s.Metadata.Custom))
+		for k, v := range s.Metadata.Custom {
+			c.Metadata.Custom[k] = v
+		}
+	}
+	return c
+}
+
+// appendMessage adds a message to a session under the engine lock
+func (e *Engine) appendMessage(session *Session, msg Message) {
+	e.mu.Lock()
+	session.Messages = append(session.Messages, msg)
+	session.UpdatedAt = msg.Timestamp
+	e.mu.Unlock()
+}
+
 // DeleteSession removes a session
 func (e *Engine) DeleteSession(id string) bool {
 	e.mu.Lock()
@@ -156,13 +294,58 @@ type ProcessResult struct {
 	Error      error      `json:"-"`
 	TokensUsed int        `json:"tokens_used,omitempty"`
 	Duration   int64      `json:"duration_ms"`
+	// RateLimit is the provider's quota when it refused the request with 429
+	RateLimit  *ai.RateLimit `json:"rate_limit,omitempty"`
+	// Constitution is the check of the task list the reply proposes, if
+	// it proposes one and a constitution checker is set
+	Constitution *constitution.Report `json:"constitution,omitempty"`
+	// Budget is how the prompt was assembled, also when the completion
+	// fails
+	Budget *ContextBudget `json:"context_budget,omitempty"`
+	// FinishReason is why the provider ended the reply, when it tells:
+	// stop, length or content_filter
+	FinishReason string `json:"finish_reason,omitempty"`
+	// Continuations is the number of follow-ups that asked the model to
+	// go on with a reply cut off by its token limit
+	Continuations int `json:"continuations,omitempty"`
+	// Partial marks a reply that is not the whole answer: still cut off
+	// after maxContinuations follow-ups, or withheld by the provider's
+	// moderation
+	Partial bool `json:"partial,omitempty"`
 }
 
 // Process handles a user message in a session
 func (e *Engine) Process(ctx context.Context, sessionID, input string) (*ProcessResult, error) {
-	session, ok := e.GetSession(sessionID)
-	if !ok {
-		return nil, ErrSessionNotFound
+	return e.ProcessStream(ctx, sessionID, input, false, nil)
+}
+
+// ProcessStream handles a user message like Process, passing the reply to
+// onDelta piece by piece as the provider generates it. Providers that
+// cannot stream deliver the whole reply at once. A nil onDelta waits for
+// the full completion; autonomous selects the ProcessAutonomous prompt.
+// A session takes one message at a time: while one is processed, others
+// fail with ErrSessionBusy.
+func (e *Engine) ProcessStream(ctx context.Context, sessionID, input string, autonomous bool, onDelta func(string) error) (*ProcessResult, error) {
+	session, release, err := e.acquireSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	// Without a provider the message is not even recorded, so that the
+	// session reads the same once one is configured
+	provider := e.Provider()
+	if provider == nil {
+		err := e.ProviderError()
+		return &ProcessResult{Error: err}, err
+	}
+
+	if autonomous {
+		e.mu.Lock()
+		session.Metadata.Autonomous = true
+		e.mu.Unlock()
+
+		// Enhanced prompt for autonomous mode
+		input = buildAutonomousPrompt(input)
 	}
 
 	start := time.Now()
@@ -171,13 +354,13 @@ func (e *Engine) Process(ctx context.Context, sessionID, input string) (*Process
 	userMsg := Message{
 		ID:        uuid.New().String(),
 		Role:      "user",
-		Content:   input,
+		Content:   redact.String(input),
 		Timestamp: time.Now(),
 	}
-	session.Messages = append(session.Messages, userMsg)
-	session.UpdatedAt = time.Now()
+	e.appendMessage(session, userMsg)
 
 	// Convert to AI messages
+	e.mu.RLock()
 	aiMessages := make([]ai.Message, len(session.Messages))
 	for i, msg := range session.Messages {
 		aiMessages[i] = ai.Message{
@@ -187,56 +370,197 @@ func (e *Engine) Process(ctx context.Context, sessionID, input string) (*Process
 	}
 
 	// Get system prompt
-	systemPrompt := e.buildSystemPrompt(session)
+	systemPrompt, budget := e.buildSystemPrompt(session)
+	budget.countHistory(session.Messages)
+	e.mu.RUnlock()
+	budget.total()
 
 	// Call AI provider
-	response, err := e.provider.Complete(ctx, aiMessages, systemPrompt)
+	response, finish, continuations, err := e.complete(ctx, provider, aiMessages, systemPrompt, onDelta)
 	if err != nil {
-		return &ProcessResult{Error: err}, err
+		e.logger.Printf("ERROR: Completion failed for session %s: %v", sessionID, err)
+		result := &ProcessResult{Error: err, Budget: &budget}
+		if rl, ok := ai.AsRateLimit(err); ok {
+			result.RateLimit = &rl.Limit
+		}
+		return result, err
 	}
 
 	// Add assistant message
 	assistantMsg := Message{
 		ID:        uuid.New().String(),
 		Role:      "assistant",
-		Content:   response,
+		Content:   redact.String(response),
 		Timestamp: time.Now(),
 		Metadata: MsgMeta{
 			Duration: time.Since(start).Milliseconds(),
+			Partial:  finish.Partial(),
 		},
 	}
-	session.Messages = append(session.Messages, assistantMsg)
-	session.UpdatedAt = time.Now()
+	e.appendMessage(session, assistantMsg)
+
+	result := &ProcessResult{
+		Response:      response,
+		Duration:      time.Since(start).Milliseconds(),
+		Budget:        &budget,
+		FinishReason:  finish.Reason,
+		Continuations: continuations,
+		Partial:       finish.Partial(),
+	}
+	if result.Partial {
+		e.logger.Printf("Reply in session %s is partial (%s)", sessionID, finish.Reason)
+	}
+	if e.constitution != nil {
+		if plan := constitution.ParseTasks(response); len(plan.Tasks) > 0 {
+			report := e.constitution.Check(plan)
+			if !report.OK() {
+				e.logger.Printf("Plan proposed in session %s breaks %d constitution rule(s)", sessionID, len(report.Violations))
+			}
+			result.Constitution = &report
+		}
+	}
+	return result, nil
+}
+
+// maxContinuations bounds the follow-ups asking the model to go on with a
+// reply cut off by its token limit
+const maxContinuations = 2
+
+// continuePrompt is the follow-up to a reply cut off by the token limit
+const continuePrompt = "Your reply was cut off by the output limit. Continue exactly where it stopped, without repeating anything or adding a preamble."
+
+// complete asks the provider for the reply to messages. While the provider
+// reports the reply cut off by its token limit, it asks the model to go on
+// with it, up to maxContinuations times, and joins the pieces; onDelta sees
+// them as one reply. A follow-up that fails leaves the reply partial
+// rather than failing it.
+func (e *Engine) complete(ctx context.Context, provider ai.Provider, messages []ai.Message, systemPrompt string, onDelta func(string) error) (string, ai.Finish, int, error) {
+	var reply strings.Builder
+	var finish ai.Finish
+	history := messages
+	for n := 0; ; n++ {
+		finish = ai.Finish{}
+		callCtx := ai.WithFinish(ctx, &finish)
+		callStart := time.Now()
+		var part string
+		var err error
+		if onDelta != nil {
+			part, err = ai.CompleteStream(callCtx, provider, history, systemPrompt, onDelta)
+		} else {
+			part, err = provider.Complete(callCtx, history, systemPrompt)
+		}
+		recordProviderCall(provider.Name(), time.Since(callStart), err)
+		if err != nil {
+			if n == 0 || ctx.Err() != nil {
+				return "", finish, n, err
+			}
+			e.logger.Printf("ERROR: Continuation %d of a truncated reply failed: %v", n, err)
+			return reply.String(), ai.Finish{Reason: ai.FinishLength}, n - 1, nil
+		}
+		reply.WriteString(part)
+		if !finish.Truncated() || part == "" || n == maxContinuations {
+			return reply.String(), finish, n, nil
+		}
+		history = append(messages[:len(messages):len(messages)],
+			ai.Message{Role: "assistant", Content: reply.String()},
+			ai.Message{Role: "user", Content: continuePrompt})
+	}
+}
 
-	return &ProcessResult{
-		Response: response,
-		Duration: time.Since(start).Milliseconds(),
+// acquireSession reserves a session for one message. A session already
+// processing one returns ErrSessionBusy rather than waiting, since the
+// reply to the first message would otherwise land after the second.
+func (e *Engine) acquireSession(id string) (*Session, func(), error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	session, ok := e.sessions[id]
+	if !ok {
+		return nil, nil, ErrSessionNotFound
+	}
+	if e.processing[id] {
+		return nil, nil, ErrSessionBusy
+	}
+	e.processing[id] = true
+	return session, func() {
+		e.mu.Lock()
+		delete(e.processing, id)
+		e.mu.Unlock()
 	}, nil
 }
 
 // ProcessAutonomous handles autonomous mode processing
 func (e *Engine) ProcessAutonomous(ctx context.Context, sessionID, input string) (*ProcessResult, error) {
-	session, ok := e.GetSession(sessionID)
-	if !ok {
-		return nil, ErrSessionNotFound
+	return e.ProcessStream(ctx, sessionID, input, true, nil)
+}
+
+// docsShare is the fraction of a model's context window the SpecKit docs
+// may take in the system prompt
+const docsShare = 8
+
+// autonomousInstructions end the system prompt of autonomous sessions
+const autonomousInstructions = "\n\nYou are in AUTONOMOUS mode. Be proactive and thorough. Execute tasks without asking for confirmation."
+
+// buildSystemPrompt returns the system prompt of a session and the budget
+// of its parts
+func (e *Engine) buildSystemPrompt(session *Session) (string, ContextBudget) {
+	model := e.config.GetActiveProvider().Model
+	budget := ContextBudget{Model: model, ContextWindow: config.ContextLength(model)}
+	budget.DocsBudgetTokens = budget.ContextWindow / docsShare
+	for _, s := range e.docSections {
+		budget.DocsAvailableTokens += s.Tokens
 	}
 
-	session.Metadata.Autonomous = true
+	docsText := e.selectDocs(session)
+	budget.DocsTokens = docs.EstimateTokens(docsText)
+	budget.SystemPromptTokens = docs.EstimateTokens(ai.SystemPrompt)
+	prompt := ai.SystemPrompt + "\n\n" + docsText
+
+	// Sessions run on behalf of an agent get what it learned from similar
+	// tasks
+	if e.lessons != nil && session.Metadata.AgentID != "" {
+		ranked := e.lessons.Relevant(session.Metadata.AgentID, latestUserMessage(session), session.Metadata.Tags, 0)
+		if text := lessons.Prompt(ranked); text != "" {
+			prompt += "\n\n" + text
+			budget.LessonsTokens = docs.EstimateTokens(text)
+		}
+	}
 
-	// Enhanced prompt for autonomous mode
-	enhancedInput := buildAutonomousPrompt(input)
+	if session.Metadata.Autonomous {
+		prompt += autonomousInstructions
+		budget.SystemPromptTokens += docs.EstimateTokens(autonomousInstructions)
+	}
 
-	return e.Process(ctx, sessionID, enhancedInput)
+	return prompt, budget
 }
 
-func (e *Engine) buildSystemPrompt(session *Session) string {
-	prompt := ai.SystemPrompt + "\n\n" + ai.SpecKitDocs
+// selectDocs returns the SpecKit docs for the system prompt. For models
+// with a known context window only the sections most relevant to the
+// latest user message are kept, within 1/docsShare of the window.
+func (e *Engine) selectDocs(session *Session) string {
+	budget := config.ContextLength(e.config.GetActiveProvider().Model) / docsShare
+	return docs.Select(e.docSections, latestUserMessage(session), budget)
+}
 
-	if session.Metadata.Autonomous {
-		prompt += "\n\nYou are in AUTONOMOUS mode. Be proactive and thorough. Execute tasks without asking for confirmation."
+func latestUserMessage(session *Session) string {
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		if session.Messages[i].Role == "user" {
+			return session.Messages[i].Content
+		}
 	}
+	return ""
+}
 
-	return prompt
+// loadSpecKitDocs reads the SpecKit docs, letting the remote docs cache and
+// SpecKitPath override the embedded copy, and falls back to the embedded
+// docs on error
+func (e *Engine) loadSpecKitDocs() []docs.Section {
+	loader := docs.ForConfig(e.config)
+	sections, err := loader.Sections()
+	if err != nil {
+		e.logger.Printf("WARN: Failed to load SpecKit docs from %s, using embedded copy: %v", loader.Source(), err)
+		sections, _ = docs.NewDocLoader("").Sections()
+	}
+	return sections
 }
 
 func buildAutonomousPrompt(input string) string {
@@ -253,16 +577,98 @@ Include:
 Be proactive and start working immediately.`
 }
 
+// SetLessons adds the relevant lessons of a session's agent to its system
+// prompt
+func (e *Engine) SetLessons(store *lessons.Store) {
+	e.lessons = store
+}
+
+// SetConstitution checks the task lists replies propose against the
+// constitution rules of c
+func (e *Engine) SetConstitution(c *constitution.Checker) {
+	e.constitution = c
+}
+
 // Tools returns the tool manager
 func (e *Engine) Tools() *tools.ToolManager {
 	return e.tools
 }
 
+// scopedTools is the tool manager made for a workspace's checkout
+type scopedTools struct {
+	dir   string
+	tools *tools.ToolManager
+}
+
+// ToolsFor returns the tools of the agents and MCP callers of a workspace.
+// A workspace declared with a dir gets a manager of its own, made on first
+// use, whose git tool works in that checkout and which shares the
+// stateless tools with Tools; other workspaces use Tools.
+func (e *Engine) ToolsFor(workspace string) *tools.ToolManager {
+	dir := e.config.WorkspaceDir(workspace)
+	if dir == "" {
+		return e.tools
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if s, ok := e.workspaceTools[workspace]; ok && s.dir == dir {
+		return s.tools
+	}
+	s := &scopedTools{dir: dir, tools: e.tools.Scoped(tools.NewGitTool(dir))}
+	e.workspaceTools[workspace] = s
+	e.logger.Printf("Scoped the tools of workspace %s to %s", workspace, dir)
+	return s.tools
+}
+
 // Provider returns the AI provider
 func (e *Engine) Provider() ai.Provider {
+	e.providerMu.RLock()
+	defer e.providerMu.RUnlock()
 	return e.provider
 }
 
+// SetProvider replaces the AI provider, bringing a degraded engine back.
+// Completions already running finish with the old one.
+func (e *Engine) SetProvider(provider ai.Provider) {
+	if e.providerFaults != nil {
+		provider = ai.WithFaults(provider, e.providerFaults)
+	}
+	e.providerMu.Lock()
+	degraded := e.provider == nil
+	e.provider = provider
+	e.providerErr = nil
+	e.providerMu.Unlock()
+
+	e.checkMu.Lock()
+	e.checkedAt = time.Time{}
+	e.checkMu.Unlock()
+	if degraded {
+		e.logger.Printf("Provider %s configured, completions are available again", provider.Name())
+		return
+	}
+	e.logger.Printf("Provider changed to %s", provider.Name())
+}
+
+// ProviderError returns why the engine has no provider, wrapping
+// ErrNoProvider, or nil when it has one
+func (e *Engine) ProviderError() error {
+	e.providerMu.RLock()
+	defer e.providerMu.RUnlock()
+	switch {
+	case e.provider != nil:
+		return nil
+	case e.providerErr != nil:
+		return e.providerErr
+	default:
+		return ErrNoProvider
+	}
+}
+
+// Degraded reports whether the engine runs without a provider
+func (e *Engine) Degraded() bool {
+	return e.Provider() == nil
+}
+
 // Config returns the configuration
 func (e *Engine) Config() *config.Config {
 	return e.config
@@ -271,6 +677,12 @@ func (e *Engine) Config() *config.Config {
 // Errors
 var (
 	ErrSessionNotFound = NewError("session not found")
+	// ErrSessionBusy is returned for a message sent to a session that is
+	// still processing the previous one
+	ErrSessionBusy = NewError("session is already processing a message")
+	// ErrNoProvider is returned by completions while the engine has no
+	// provider
+	ErrNoProvider = NewError("no AI provider configured")
 )
 
 // Error represents an engine error
@@ -288,7 +700,25 @@ func (e *Error) Error() string {
 
 // IsHealthy returns true if the engine is healthy
 func (e *Engine) IsHealthy() bool {
-	return e.provider != nil && e.tools != nil
+	return e.Provider() != nil && e.tools != nil
+}
+
+// CheckProvider reports whether the provider can serve completions;
+// checked is false for providers that cannot tell. Results are cached for
+// providerCheckTTL.
+func (e *Engine) CheckProvider(ctx context.Context) (checked bool, err error) {
+	provider := e.Provider()
+	if provider == nil {
+		return true, e.ProviderError()
+	}
+	e.checkMu.Lock()
+	defer e.checkMu.Unlock()
+	if !e.checkedAt.IsZero() && time.Since(e.checkedAt) < providerCheckTTL {
+		return e.checkChecked, e.checkErr
+	}
+	e.checkChecked, e.checkErr = ai.Check(ctx, provider)
+	e.checkedAt = time.Now()
+	return e.checkChecked, e.checkErr
 }
 
 // GetStatus returns the current status of the engine
@@ -296,16 +726,26 @@ func (e *Engine) GetStatus() map[string]interface{} {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	
-	return map[string]interface{}{
+	status := map[string]interface{}{
 		"status":    "running",
 		"sessions":  len(e.sessions),
 		"healthy":   e.IsHealthy(),
 		"timestamp": time.Now(),
 	}
+	if err := e.ProviderError(); err != nil {
+		status["status"] = "degraded"
+		status["provider_error"] = err.Error()
+	}
+	return status
 }
 
 // Start initializes the engine
 func (e *Engine) Start() error {
+	if provider := e.Provider(); provider != nil {
+		e.logger.Printf("Engine started with provider %s", provider.Name())
+	} else {
+		e.logger.Printf("WARN: Engine started degraded: %v", e.ProviderError())
+	}
 	// Start project manager if enabled
 	if e.projectManager != nil {
 		if err := e.projectManager.Start(); err != nil {
@@ -0,0 +1,23 @@
+package core
+
+import (
+	"time"
+
+	"github.com/biodoia/skagent/internal/metrics"
+)
+
+var (
+	providerRequests = metrics.Default.NewCounter("skagent_provider_requests_total",
+		"AI provider completion calls, by provider and outcome.", "provider", "outcome")
+	providerDuration = metrics.Default.NewHistogram("skagent_provider_request_duration_seconds",
+		"Latency of AI provider completion calls.", metrics.ProviderBuckets, "provider")
+)
+
+func recordProviderCall(provider string, d time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	providerRequests.Inc(provider, outcome)
+	providerDuration.Observe(d.Seconds(), provider)
+}
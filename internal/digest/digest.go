@@ -0,0 +1,457 @@
+// Package digest builds the daily digest of fleet activity: the tasks
+// created, finished and failed over the last day, the errors that came up
+// most, the agents that did the work and the models they used, summed up
+// in a few sentences by a cheap model. The digest is built every day at a
+// set time, delivered to the webhooks that subscribe to report.daily and
+// kept for GET /reports/daily.
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+)
+
+// EventDaily is the event the digest is delivered as
+const EventDaily agents.EventType = "report.daily"
+
+// Period is how far back a digest looks
+const Period = 24 * time.Hour
+
+const (
+	// maxSamples bounds the task IDs listed for an error
+	maxSamples = 3
+	// maxErrorText bounds the text of an error in the digest, in bytes
+	maxErrorText = 200
+	// summaryTimeout bounds the model's summary
+	summaryTimeout = 2 * time.Minute
+)
+
+// Report is the digest of one period
+type Report struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Tasks       TaskStats `json:"tasks"`
+	// Errors are the errors failed tasks ended with, the most frequent
+	// first
+	Errors []ErrorCount `json:"errors"`
+	// Agents are the agents that finished tasks, the busiest first
+	Agents []AgentActivity `json:"agents"`
+	// Usage is the work done by each model, the most used first
+	Usage []ModelUsage `json:"usage"`
+	// Cost sums the relative costs of the model_policy tiers used
+	Cost float64 `json:"cost"`
+	// Summary sums the period up in plain words: written by the model, or
+	// from the numbers when it fails
+	Summary      string `json:"summary"`
+	SummaryModel string `json:"summary_model,omitempty"`
+	SummaryError string `json:"summary_error,omitempty"`
+}
+
+// TaskStats counts the tasks of a period
+type TaskStats struct {
+	Created   int `json:"created"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+	// FailureRate is the share of finished tasks that failed
+	FailureRate float64 `json:"failure_rate"`
+	// Pending are the tasks still waiting or running at the end of the
+	// period
+	Pending int `json:"pending"`
+}
+
+// ErrorCount is an error shared by failed tasks
+type ErrorCount struct {
+	Error string   `json:"error"`
+	Count int      `json:"count"`
+	Tasks []string `json:"tasks"`
+}
+
+// AgentActivity is the work of one agent
+type AgentActivity struct {
+	AgentID   string `json:"agent_id"`
+	Name      string `json:"name,omitempty"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+}
+
+// ModelUsage is the work of one model
+type ModelUsage struct {
+	Model      string  `json:"model"`
+	Tasks      int     `json:"tasks"`
+	DurationMS int64   `json:"duration_ms"`
+	Cost       float64 `json:"cost"`
+}
+
+// Build computes the digest of the tasks for the period that ends at to.
+// costs gives the relative cost of a task run by a model; models it does
+// not list cost nothing. The summary is left to the caller.
+func Build(tasks []*agents.Task, names map[string]string, costs map[string]float64, to time.Time, maxErrors int) Report {
+	from := to.Add(-Period)
+	r := Report{From: from, To: to, GeneratedAt: time.Now(), Errors: []ErrorCount{}, Agents: []AgentActivity{}, Usage: []ModelUsage{}}
+	in := func(t *time.Time) bool {
+		return t != nil && t.After(from) && !t.After(to)
+	}
+
+	errs := make(map[string]*ErrorCount)
+	byAgent := make(map[string]*AgentActivity)
+	byModel := make(map[string]*ModelUsage)
+	for _, t := range tasks {
+		if created := t.CreatedAt; in(&created) {
+			r.Tasks.Created++
+		}
+		if !in(t.CompletedAt) {
+			if !t.CreatedAt.After(to) && (t.CompletedAt == nil || t.CompletedAt.After(to)) {
+				r.Tasks.Pending++
+			}
+			continue
+		}
+
+		// The registry records a failure as a completed task with a failed
+		// result
+		failed := t.Status == agents.TaskStatusFailed ||
+			(t.Status == agents.TaskStatusCompleted && t.Result != nil && !t.Result.Success)
+		switch {
+		case failed:
+			r.Tasks.Failed++
+			text := "unknown error"
+			if t.Result != nil && t.Result.Error != "" {
+				text = errorText(t.Result.Error)
+			}
+			key := normalize(text)
+			e, ok := errs[key]
+			if !ok {
+				e = &ErrorCount{Error: text}
+				errs[key] = e
+			}
+			e.Count++
+			if len(e.Tasks) < maxSamples {
+				e.Tasks = append(e.Tasks, t.ID)
+			}
+		case t.Status == agents.TaskStatusCompleted:
+			r.Tasks.Completed++
+		case t.Status == agents.TaskStatusCancelled:
+			r.Tasks.Cancelled++
+			continue
+		default:
+			continue
+		}
+
+		if t.AssignedTo != "" {
+			a, ok := byAgent[t.AssignedTo]
+			if !ok {
+				a = &AgentActivity{AgentID: t.AssignedTo, Name: names[t.AssignedTo]}
+				byAgent[t.AssignedTo] = a
+			}
+			if failed {
+				a.Failed++
+			} else {
+				a.Completed++
+			}
+		}
+		if t.Result != nil && t.Result.Model != "" {
+			m, ok := byModel[t.Result.Model]
+			if !ok {
+				m = &ModelUsage{Model: t.Result.Model}
+				byModel[t.Result.Model] = m
+			}
+			m.Tasks++
+			m.DurationMS += t.Result.Duration
+			m.Cost += costs[t.Result.Model]
+			r.Cost += costs[t.Result.Model]
+		}
+	}
+	if finished := r.Tasks.Completed + r.Tasks.Failed; finished > 0 {
+		r.Tasks.FailureRate = float64(int(float64(r.Tasks.Failed)/float64(finished)*1000+0.5)) / 1000
+	}
+
+	for _, e := range errs {
+		r.Errors = append(r.Errors, *e)
+	}
+	sort.Slice(r.Errors, func(i, j int) bool {
+		if r.Errors[i].Count != r.Errors[j].Count {
+			return r.Errors[i].Count > r.Errors[j].Count
+		}
+		return r.Errors[i].Error < r.Errors[j].Error
+	})
+	if maxErrors > 0 && len(r.Errors) > maxErrors {
+		r.Errors = r.Errors[:maxErrors]
+	}
+	for _, a := range byAgent {
+		r.Agents = append(r.Agents, *a)
+	}
+	sort.Slice(r.Agents, func(i, j int) bool {
+		ti, tj := r.Agents[i].Completed+r.Agents[i].Failed, r.Agents[j].Completed+r.Agents[j].Failed
+		if ti != tj {
+			return ti > tj
+		}
+		return r.Agents[i].AgentID < r.Agents[j].AgentID
+	})
+	for _, m := range byModel {
+		r.Usage = append(r.Usage, *m)
+	}
+	sort.Slice(r.Usage, func(i, j int) bool {
+		if r.Usage[i].Tasks != r.Usage[j].Tasks {
+			return r.Usage[i].Tasks > r.Usage[j].Tasks
+		}
+		return r.Usage[i].Model < r.Usage[j].Model
+	})
+	r.Summary = r.plainSummary()
+	return r
+}
+
+// errorText is the first line of an error, bounded to maxErrorText
+func errorText(err string) string {
+	text, _, _ := strings.Cut(strings.TrimSpace(err), "\n")
+	if len(text) > maxErrorText {
+		text = text[:maxErrorText] + "..."
+	}
+	return text
+}
+
+// variable matches the parts of an error that differ between occurrences
+// of the same problem: IDs and numbers
+var variable = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f-]{27}|\d+`)
+
+// normalize groups errors that differ only by IDs and numbers
+func normalize(text string) string {
+	return variable.ReplaceAllString(strings.ToLower(text), "#")
+}
+
+// plainSummary sums the report up without a model
+func (r *Report) plainSummary() string {
+	s := fmt.Sprintf("In the last 24 hours %d tasks were created, %d completed, %d failed and %d cancelled; %d are still open.",
+		r.Tasks.Created, r.Tasks.Completed, r.Tasks.Failed, r.Tasks.Cancelled, r.Tasks.Pending)
+	if len(r.Errors) > 0 {
+		s += fmt.Sprintf(" The most frequent error (%d tasks) was: %s", r.Errors[0].Count, r.Errors[0].Error)
+	}
+	return s
+}
+
+// Digest builds the daily digest on schedule and keeps the latest
+type Digest struct {
+	cfg      config.DigestConfig
+	registry *agents.Registry
+	provider func() ai.Provider
+	path     string
+	logger   *log.Logger
+
+	mu     sync.Mutex
+	costs  map[string]float64
+	model  string
+	notify func(context.Context, agents.Event)
+	latest *Report
+}
+
+// New returns a digest of registry's tasks summed up by the model provider
+// returns, switched to cfg.Model when the provider serves several. The
+// latest digest is kept in path, when set, across restarts.
+func New(cfg config.DigestConfig, registry *agents.Registry, provider func() ai.Provider, path string) *Digest {
+	d := &Digest{
+		cfg:      cfg,
+		registry: registry,
+		provider: provider,
+		path:     path,
+		model:    cfg.Model,
+		costs:    map[string]float64{},
+		logger:   logging.New("digest", "[DIGEST] ", log.Writer()),
+	}
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			var r Report
+			if err := json.Unmarshal(data, &r); err != nil {
+				d.logger.Printf("WARN: Ignoring the saved digest: %v", err)
+			} else {
+				d.latest = &r
+			}
+		}
+	}
+	return d
+}
+
+// SetTiers prices the models of the model_policy tiers, cheapest first.
+// Without a configured model the summary uses the cheapest of them.
+func (d *Digest) SetTiers(tiers []config.ModelTier) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.costs = make(map[string]float64, len(tiers))
+	for _, t := range tiers {
+		d.costs[t.Model] = t.Cost
+	}
+	if d.cfg.Model == "" && len(tiers) > 0 {
+		d.model = tiers[0].Model
+	}
+}
+
+// SetNotify sets where each scheduled digest is delivered, such as the
+// webhooks
+func (d *Digest) SetNotify(notify func(context.Context, agents.Event)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notify = notify
+}
+
+// Latest returns the last digest built, or nil
+func (d *Digest) Latest() *Report {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.latest == nil {
+		return nil
+	}
+	r := *d.latest
+	return &r
+}
+
+// Generate builds the digest of the 24 hours before now, has the model
+// sum it up and keeps it as the latest
+func (d *Digest) Generate(ctx context.Context) *Report {
+	d.mu.Lock()
+	costs := d.costs
+	d.mu.Unlock()
+
+	names := make(map[string]string)
+	for _, a := range d.registry.ListAgents() {
+		names[a.ID] = a.Name
+	}
+	r := Build(d.registry.ListTasks(), names, costs, time.Now(), d.cfg.MaxErrors)
+	d.summarize(ctx, &r)
+
+	d.mu.Lock()
+	d.latest = &r
+	d.mu.Unlock()
+	if err := d.save(&r); err != nil {
+		d.logger.Printf("ERROR: Failed to save the digest: %v", err)
+	}
+	return &r
+}
+
+// summaryPrompt asks the model for the summary
+const summaryPrompt = `You write the daily digest of a fleet of AI agents for the team that runs it. The user sends the numbers of the last 24 hours as JSON.
+In three to five plain sentences, say how the day went, point out the errors and agents that need attention and anything unusual in the usage. Do not list every number and do not use markdown.`
+
+// summarize has the model write the summary, keeping the plain one when
+// there is no model or it fails
+func (d *Digest) summarize(ctx context.Context, r *Report) {
+	provider := d.summaryModel()
+	if provider == nil {
+		return
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		r.SummaryError = err.Error()
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, summaryTimeout)
+	defer cancel()
+	reply, err := provider.Complete(ctx, []ai.Message{{Role: "user", Content: string(data)}}, summaryPrompt)
+	if err != nil {
+		r.SummaryError = err.Error()
+		d.logger.Printf("WARN: The model could not sum the digest up: %v", err)
+		return
+	}
+	if reply = strings.TrimSpace(reply); reply != "" {
+		r.Summary = reply
+		r.SummaryModel = provider.Name()
+		if d.model != "" {
+			r.SummaryModel += " " + d.model
+		}
+	}
+}
+
+// summaryModel is the provider to sum up with, or nil when there is none
+func (d *Digest) summaryModel() ai.Provider {
+	if d.provider == nil {
+		return nil
+	}
+	p := d.provider()
+	d.mu.Lock()
+	model := d.model
+	d.mu.Unlock()
+	if p == nil || model == "" {
+		return p
+	}
+	if m, ok := ai.WithModel(p, model); ok {
+		return m
+	}
+	return p
+}
+
+// save writes the digest to d.path
+func (d *Digest) save(r *Report) error {
+	if d.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0o755); err != nil {
+		return err
+	}
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path)
+}
+
+// Run builds and delivers the digest every day at the configured time
+// until ctx is done
+func (d *Digest) Run(ctx context.Context) {
+	at := d.cfg.At
+	if at == "" {
+		at = "07:00"
+	}
+	for {
+		next, err := Next(time.Now(), at)
+		if err != nil {
+			d.logger.Printf("WARN: Not scheduling the digest: %v", err)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		r := d.Generate(ctx)
+		d.logger.Printf("Daily digest: %d completed, %d failed", r.Tasks.Completed, r.Tasks.Failed)
+		d.mu.Lock()
+		notify := d.notify
+		d.mu.Unlock()
+		if notify != nil {
+			notify(ctx, agents.Event{Type: EventDaily, Time: r.GeneratedAt, Data: map[string]interface{}{"report": r}})
+		}
+	}
+}
+
+// Next returns the first time after now at the time of day at, as
+// "15:04", in now's location
+func Next(now time.Time, at string) (time.Time, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time of day %q", at)
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
@@ -0,0 +1,119 @@
+package digest
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration) *time.Time {
+		t := now.Add(-ago)
+		return &t
+	}
+	done := func(id, agent, model string, ago time.Duration, err string) *agents.Task {
+		return &agents.Task{
+			ID: id, AssignedTo: agent, Status: agents.TaskStatusCompleted,
+			CreatedAt: *at(ago + time.Hour), CompletedAt: at(ago),
+			Result: &agents.TaskResult{Success: err == "", Error: err, Model: model, Duration: 1000},
+		}
+	}
+	tasks := []*agents.Task{
+		done("t1", "a1", "cheap", time.Hour, ""),
+		done("t2", "a1", "cheap", 2*time.Hour, ""),
+		done("t3", "a2", "strong", 3*time.Hour, "timeout after 30s calling 10.0.0.1"),
+		done("t4", "a2", "strong", 4*time.Hour, "timeout after 45s calling 10.0.0.2\nstack..."),
+		done("t5", "a1", "cheap", 5*time.Hour, "tests failed"),
+		// Finished before the period
+		done("old", "a1", "cheap", 30*time.Hour, "tests failed"),
+		{ID: "t6", Status: agents.TaskStatusCancelled, CreatedAt: *at(3 * time.Hour), CompletedAt: at(2 * time.Hour)},
+		{ID: "t7", Status: agents.TaskStatusPending, CreatedAt: *at(time.Hour)},
+		{ID: "t8", Status: agents.TaskStatusInProgress, CreatedAt: *at(48 * time.Hour)},
+	}
+
+	r := Build(tasks, map[string]string{"a1": "coder"}, map[string]float64{"cheap": 1, "strong": 8}, now, 5)
+	want := TaskStats{Created: 7, Completed: 2, Failed: 3, Cancelled: 1, FailureRate: 0.6, Pending: 2}
+	if r.Tasks != want {
+		t.Errorf("tasks = %+v, want %+v", r.Tasks, want)
+	}
+	if len(r.Errors) != 2 || r.Errors[0].Count != 2 || !strings.HasPrefix(r.Errors[0].Error, "timeout after 30s") ||
+		len(r.Errors[0].Tasks) != 2 || strings.Contains(r.Errors[0].Error, "stack") {
+		t.Errorf("errors = %+v", r.Errors)
+	}
+	if len(r.Agents) != 2 || r.Agents[0].AgentID != "a1" || r.Agents[0].Name != "coder" ||
+		r.Agents[0].Completed != 2 || r.Agents[0].Failed != 1 || r.Agents[1].Failed != 2 {
+		t.Errorf("agents = %+v", r.Agents)
+	}
+	if len(r.Usage) != 2 || r.Usage[0].Model != "cheap" || r.Usage[0].Tasks != 3 || r.Usage[0].DurationMS != 3000 || r.Cost != 19 {
+		t.Errorf("usage = %+v, cost %v", r.Usage, r.Cost)
+	}
+	if !strings.Contains(r.Summary, "3 failed") {
+		t.Errorf("summary = %q", r.Summary)
+	}
+
+	if r := Build(tasks, nil, nil, now, 1); len(r.Errors) != 1 {
+		t.Errorf("max errors: %+v", r.Errors)
+	}
+}
+
+func TestNext(t *testing.T) {
+	now := time.Date(2026, 3, 10, 8, 30, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"09:00": time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+		"08:30": time.Date(2026, 3, 11, 8, 30, 0, 0, time.UTC),
+		"07:00": time.Date(2026, 3, 11, 7, 0, 0, 0, time.UTC),
+	}
+	for at, want := range cases {
+		if got, err := Next(now, at); err != nil || !got.Equal(want) {
+			t.Errorf("Next(%s) = %v, %v; want %v", at, got, err, want)
+		}
+	}
+	if _, err := Next(now, "7am"); err == nil {
+		t.Error("Next accepted 7am")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("coder", "coder", nil)
+	task := registry.CreateTask(&agents.Task{Title: "fix"})
+	if err := registry.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	registry.CompleteTaskBy(task.ID, &agents.TaskResult{Success: false, Error: "boom", Model: "cheap"}, agents.Cause{Actor: "test"})
+
+	mock := ai.NewMockProvider("A quiet day; one task failed with boom.")
+	path := filepath.Join(t.TempDir(), "digest.json")
+	d := New(config.DigestConfig{MaxErrors: 5}, registry, func() ai.Provider { return mock }, path)
+	d.SetTiers([]config.ModelTier{{Model: "cheap", Cost: 1}, {Model: "strong", Cost: 8}})
+	if d.Latest() != nil {
+		t.Fatal("a digest before the first run")
+	}
+
+	r := d.Generate(ctx)
+	if r.Tasks.Failed != 1 || r.Cost != 1 || r.Summary != "A quiet day; one task failed with boom." || r.SummaryModel != "mock cheap" {
+		t.Fatalf("report = %+v", r)
+	}
+	if calls := mock.Calls(); len(calls) != 1 || !strings.Contains(calls[0].Messages[0].Content, `"boom"`) {
+		t.Errorf("prompt: %+v", calls)
+	}
+
+	// The latest digest survives a restart
+	if again := New(config.DigestConfig{}, registry, nil, path).Latest(); again == nil || again.Summary != r.Summary {
+		t.Errorf("reloaded digest = %+v", again)
+	}
+
+	// Without a model the summary comes from the numbers
+	plain := New(config.DigestConfig{}, registry, nil, "").Generate(ctx)
+	if !strings.Contains(plain.Summary, "1 failed") || plain.SummaryModel != "" {
+		t.Errorf("plain summary = %+v", plain)
+	}
+}
@@ -1,71 +1,156 @@
 package docs
 
 import (
+	"embed"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/biodoia/skagent/internal/config"
 )
 
-// DocLoader loads documentation from the filesystem
+// embedded holds the canonical SpecKit documentation shipped in the binary
+//
+//go:embed speckit/*.md
+var embedded embed.FS
+
+// DocLoader loads SpecKit documentation. Files from the embedded set are
+// used unless the remote cache or the docs directory provides a file with
+// the same name; extra files in either are added. The docs directory takes
+// precedence over the cache.
 type DocLoader struct {
 	docsPath string
+	cacheDir string
 }
 
-// NewDocLoader creates a new documentation loader
+// NewDocLoader creates a new documentation loader. An empty docsPath, or
+// one that does not exist, selects the embedded documentation only.
 func NewDocLoader(docsPath string) *DocLoader {
 	return &DocLoader{
 		docsPath: docsPath,
 	}
 }
 
-// LoadSpecKitDocs loads all markdown files from the docs directory
+// ForConfig returns the loader used by the engine and the TUI: embedded
+// docs, then the remote cache, then SpecKitPath
+func ForConfig(cfg *config.Config) *DocLoader {
+	d := NewDocLoader("")
+	if cfg != nil {
+		d.docsPath = cfg.SpecKitPath
+	}
+	if dir, err := CacheDir(); err == nil {
+		d.cacheDir = dir
+	}
+	return d
+}
+
+// WithCacheDir layers the remote documentation cache in dir between the
+// embedded docs and the docs directory
+func (d *DocLoader) WithCacheDir(dir string) *DocLoader {
+	d.cacheDir = dir
+	return d
+}
+
+// Embedded returns the built-in SpecKit documentation
+func Embedded() string {
+	content, _ := NewDocLoader("").LoadSpecKitDocs()
+	return content
+}
+
+// Source describes where documentation is read from
+func (d *DocLoader) Source() string {
+	dirs := d.dirs()
+	if len(dirs) == 0 {
+		return "embedded"
+	}
+	return strings.Join(dirs, ", ") + " (over embedded)"
+}
+
+// dirs returns the existing documentation directories, highest precedence
+// first
+func (d *DocLoader) dirs() []string {
+	var dirs []string
+	for _, dir := range []string{d.docsPath, d.cacheDir} {
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// LoadSpecKitDocs concatenates all documentation files in name order
 func (d *DocLoader) LoadSpecKitDocs() (string, error) {
-	// Use os.ReadDir instead of deprecated ioutil.ReadDir
-	entries, err := os.ReadDir(d.docsPath)
+	names, err := d.ListDocs()
 	if err != nil {
-		return "", fmt.Errorf("failed to read docs directory: %w", err)
+		return "", err
 	}
 
 	var content strings.Builder
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		if filepath.Ext(entry.Name()) == ".md" {
-			data, err := os.ReadFile(filepath.Join(d.docsPath, entry.Name()))
-			if err != nil {
-				return "", fmt.Errorf("failed to read file %s: %w", entry.Name(), err)
-			}
-			content.Write(data)
-			content.WriteString("\n\n")
+	for _, name := range names {
+		data, err := d.LoadFile(name)
+		if err != nil {
+			return "", err
 		}
+		content.WriteString(data)
+		content.WriteString("\n\n")
 	}
 
 	return content.String(), nil
 }
 
-// LoadFile loads a single documentation file
+// LoadFile loads a single documentation file, preferring the docs directory
 func (d *DocLoader) LoadFile(filename string) (string, error) {
-	data, err := os.ReadFile(filepath.Join(d.docsPath, filename))
+	for _, dir := range d.dirs() {
+		data, err := os.ReadFile(filepath.Join(dir, filename))
+		if err == nil {
+			return string(data), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read file %s: %w", filename, err)
+		}
+	}
+
+	data, err := embedded.ReadFile("speckit/" + filename)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file %s: %w", filename, err)
 	}
 	return string(data), nil
 }
 
-// ListDocs returns a list of available documentation files
+// ListDocs returns the names of the available documentation files
 func (d *DocLoader) ListDocs() ([]string, error) {
-	entries, err := os.ReadDir(d.docsPath)
+	seen := make(map[string]bool)
+
+	entries, err := fs.ReadDir(embedded, "speckit")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read docs directory: %w", err)
+		return nil, fmt.Errorf("failed to read embedded docs: %w", err)
 	}
-
-	var docs []string
 	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".md" {
-			docs = append(docs, entry.Name())
+		seen[entry.Name()] = true
+	}
+
+	for _, dir := range d.dirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read docs directory: %w", err)
 		}
+		for _, entry := range entries {
+			if !entry.IsDir() && filepath.Ext(entry.Name()) == ".md" {
+				seen[entry.Name()] = true
+			}
+		}
+	}
+
+	docs := make([]string, 0, len(seen))
+	for name := range seen {
+		docs = append(docs, name)
 	}
+	sort.Strings(docs)
 	return docs, nil
 }
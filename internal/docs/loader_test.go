@@ -0,0 +1,53 @@
+package docs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedDocs(t *testing.T) {
+	content := Embedded()
+	for _, want := range []string{"/speckit.specify", "Workflow", "Constitution"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("embedded docs lack %q", want)
+		}
+	}
+	if src := NewDocLoader(filepath.Join(t.TempDir(), "missing")).Source(); src != "embedded" {
+		t.Errorf("Source() for a missing directory = %q", src)
+	}
+}
+
+func TestOverrideDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "constitution.md"), []byte("# Local rules"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "extra.md"), []byte("# Extra"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewDocLoader(dir)
+	names, err := loader.ListDocs()
+	if err != nil {
+		t.Fatalf("ListDocs: %v", err)
+	}
+	if got := strings.Join(names, ","); got != "commands.md,constitution.md,extra.md,workflow.md" {
+		t.Fatalf("ListDocs = %s", got)
+	}
+
+	content, err := loader.LoadSpecKitDocs()
+	if err != nil {
+		t.Fatalf("LoadSpecKitDocs: %v", err)
+	}
+	if !strings.Contains(content, "# Local rules") || strings.Contains(content, "Nine Articles") {
+		t.Error("override file did not replace the embedded one")
+	}
+	if !strings.Contains(content, "/speckit.plan") || !strings.Contains(content, "# Extra") {
+		t.Error("embedded or extra docs missing from the merged set")
+	}
+}
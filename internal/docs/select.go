@@ -0,0 +1,175 @@
+package docs
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Section is a heading and the text below it, up to the next heading
+type Section struct {
+	File    string `json:"file"`
+	Heading string `json:"heading,omitempty"`
+	Text    string `json:"text"` // includes the heading line
+	Tokens  int    `json:"tokens"`
+}
+
+// EstimateTokens approximates the token count of s at four bytes per token
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// Sections splits every documentation file at its markdown headings
+func (d *DocLoader) Sections() ([]Section, error) {
+	names, err := d.ListDocs()
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []Section
+	for _, name := range names {
+		content, err := d.LoadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, splitSections(name, content)...)
+	}
+	return sections, nil
+}
+
+func splitSections(file, content string) []Section {
+	var sections []Section
+	var cur strings.Builder
+	heading := ""
+	flush := func() {
+		text := strings.TrimSpace(cur.String())
+		if text != "" {
+			sections = append(sections, Section{File: file, Heading: heading, Text: text, Tokens: EstimateTokens(text)})
+		}
+		cur.Reset()
+	}
+
+	inFence := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(trimmed, "#") {
+			flush()
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		}
+		cur.WriteString(line)
+	}
+	flush()
+	return sections
+}
+
+// SelectRelevant returns the documentation sections most relevant to query
+// that fit in tokenBudget, in their original order. A budget of zero or
+// less, or one that fits everything, returns all documentation.
+func (d *DocLoader) SelectRelevant(query string, tokenBudget int) (string, error) {
+	sections, err := d.Sections()
+	if err != nil {
+		return "", err
+	}
+	return Select(sections, query, tokenBudget), nil
+}
+
+// Select ranks sections by keyword overlap with query and keeps the best
+// ones that fit in tokenBudget. Sections matching no query term fill any
+// room that is left, in document order.
+func Select(sections []Section, query string, tokenBudget int) string {
+	total := 0
+	for _, s := range sections {
+		total += s.Tokens
+	}
+	if tokenBudget <= 0 || total <= tokenBudget {
+		return joinSections(sections, nil)
+	}
+
+	terms := keywords(query)
+	scores := make([]float64, len(sections))
+	for i, s := range sections {
+		scores[i] = score(s, terms)
+	}
+
+	order := make([]int, len(sections))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	keep := make([]bool, len(sections))
+	used := 0
+	for _, i := range order {
+		if used+sections[i].Tokens > tokenBudget {
+			continue
+		}
+		keep[i] = true
+		used += sections[i].Tokens
+	}
+	return joinSections(sections, keep)
+}
+
+func joinSections(sections []Section, keep []bool) string {
+	var b strings.Builder
+	for i, s := range sections {
+		if keep != nil && !keep[i] {
+			continue
+		}
+		b.WriteString(s.Text)
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+// score weighs query terms found in the heading three times as much as
+// terms found in the body, dampened by section length
+func score(s Section, terms map[string]bool) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	var hits float64
+	for _, w := range words(s.Heading) {
+		if terms[w] {
+			hits += 3
+		}
+	}
+	body := words(s.Text)
+	for _, w := range body {
+		if terms[w] {
+			hits++
+		}
+	}
+	if hits == 0 {
+		return 0
+	}
+	return hits / math.Log2(float64(len(body))+2)
+}
+
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true, "this": true,
+	"from": true, "are": true, "was": true, "you": true, "your": true, "can": true,
+	"how": true, "what": true, "should": true, "into": true, "all": true, "use": true,
+}
+
+// keywords returns the distinct significant words of a query
+func keywords(query string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, w := range words(query) {
+		if len(w) >= 3 && !stopwords[w] {
+			terms[w] = true
+		}
+	}
+	return terms
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,60 @@
+package docs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitSections(t *testing.T) {
+	content := "intro\n# One\nfirst\n```\n# not a heading\n```\n## Two\nsecond\n"
+	sections := splitSections("a.md", content)
+	if len(sections) != 3 {
+		t.Fatalf("got %d sections: %+v", len(sections), sections)
+	}
+	if sections[1].Heading != "One" || !strings.Contains(sections[1].Text, "# not a heading") {
+		t.Errorf("fenced heading split the section: %+v", sections[1])
+	}
+	if sections[2].Heading != "Two" {
+		t.Errorf("third heading = %q", sections[2].Heading)
+	}
+}
+
+func TestSelectWithinBudget(t *testing.T) {
+	mk := func(heading, text string) Section {
+		full := "## " + heading + "\n" + text
+		return Section{File: "x.md", Heading: heading, Text: full, Tokens: EstimateTokens(full)}
+	}
+	sections := []Section{
+		mk("Testing", strings.Repeat("unit tests and coverage ", 10)),
+		mk("Deployment", strings.Repeat("docker images and kubernetes ", 10)),
+		mk("Planning", strings.Repeat("architecture plan decisions ", 10)),
+	}
+
+	all := Select(sections, "anything", 0)
+	if strings.Count(all, "## ") != 3 {
+		t.Fatal("a zero budget did not return everything")
+	}
+
+	out := Select(sections, "how do I deploy with kubernetes?", sections[1].Tokens+10)
+	if !strings.Contains(out, "## Deployment") || strings.Contains(out, "## Testing") || strings.Contains(out, "## Planning") {
+		t.Fatalf("unexpected selection:\n%s", out)
+	}
+
+	out = Select(sections, "kubernetes plan", sections[1].Tokens+sections[2].Tokens)
+	if i, j := strings.Index(out, "## Deployment"), strings.Index(out, "## Planning"); i < 0 || j < 0 || i > j {
+		t.Fatalf("selection lost document order:\n%s", out)
+	}
+}
+
+func TestSelectRelevantEmbedded(t *testing.T) {
+	out, err := NewDocLoader("").SelectRelevant("which /speckit.plan command", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "/speckit.plan") {
+		t.Fatalf("command reference not selected:\n%s", out)
+	}
+	if EstimateTokens(out) > 110 {
+		t.Fatalf("selection exceeds budget: %d tokens", EstimateTokens(out))
+	}
+}
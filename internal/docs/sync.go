@@ -0,0 +1,320 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// indexFile records the validators of every cached source
+const indexFile = "index.json"
+
+// maxDocSize bounds a single downloaded documentation file
+const maxDocSize = 4 << 20
+
+// CacheDir returns the directory holding remotely fetched documentation
+func CacheDir() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "docs"), nil
+}
+
+// SyncStatus is the outcome of refreshing one source
+type SyncStatus string
+
+const (
+	SyncUpdated     SyncStatus = "updated"
+	SyncNotModified SyncStatus = "not_modified"
+	SyncFailed      SyncStatus = "failed"
+)
+
+// SyncResult reports what happened to one configured source
+type SyncResult struct {
+	Source string     `json:"source"`
+	Status SyncStatus `json:"status"`
+	Files  []string   `json:"files,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// cacheEntry holds the validators of a cached source
+type cacheEntry struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Revision     string    `json:"revision,omitempty"` // git commit
+	Files        []string  `json:"files"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// Syncer refreshes the documentation cache from remote sources
+type Syncer struct {
+	CacheDir   string
+	HTTPClient *http.Client
+	// Force ignores stored validators and downloads everything again
+	Force bool
+}
+
+// NewSyncer creates a syncer writing to cacheDir
+func NewSyncer(cacheDir string) *Syncer {
+	return &Syncer{
+		CacheDir:   cacheDir,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Sync refreshes every source in cfg. A failing source is reported in its
+// result and keeps its previously cached files.
+func (s *Syncer) Sync(ctx context.Context, cfg config.DocsConfig) ([]SyncResult, error) {
+	if err := os.MkdirAll(s.CacheDir, 0o755); err != nil {
+		return nil, err
+	}
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SyncResult
+	for _, u := range cfg.URLs {
+		results = append(results, s.syncURL(ctx, u, index))
+	}
+	if cfg.GitRepo != "" {
+		results = append(results, s.syncGit(ctx, cfg, index))
+	}
+
+	if err := s.saveIndex(index); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (s *Syncer) syncURL(ctx context.Context, rawURL string, index map[string]*cacheEntry) SyncResult {
+	res := SyncResult{Source: rawURL}
+	fail := func(err error) SyncResult {
+		res.Status = SyncFailed
+		res.Error = err.Error()
+		return res
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fail(err)
+	}
+	name := path.Base(u.Path)
+	if filepath.Ext(name) != ".md" {
+		return fail(fmt.Errorf("%s does not name a .md file", rawURL))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fail(err)
+	}
+	prev := index[rawURL]
+	if prev != nil && !s.Force && s.cached(prev.Files) {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		res.Status = SyncNotModified
+		res.Files = prev.Files
+		return res
+	case http.StatusOK:
+	default:
+		return fail(fmt.Errorf("GET %s: %s", rawURL, resp.Status))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDocSize+1))
+	if err != nil {
+		return fail(err)
+	}
+	if len(data) > maxDocSize {
+		return fail(fmt.Errorf("%s is larger than %d bytes", rawURL, maxDocSize))
+	}
+	if err := writeFileAtomic(filepath.Join(s.CacheDir, name), data); err != nil {
+		return fail(err)
+	}
+
+	index[rawURL] = &cacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Files:        []string{name},
+		FetchedAt:    time.Now(),
+	}
+	res.Status = SyncUpdated
+	res.Files = []string{name}
+	return res
+}
+
+// syncGit copies the markdown files of a repository directory into the
+// cache. The remote commit plays the role of an ETag: nothing is cloned
+// when it has not changed.
+func (s *Syncer) syncGit(ctx context.Context, cfg config.DocsConfig, index map[string]*cacheEntry) SyncResult {
+	ref := cfg.GitRef
+	if ref == "" {
+		ref = "HEAD"
+	}
+	dir := cfg.GitPath
+	if dir == "" {
+		dir = "docs"
+	}
+	key := "git:" + cfg.GitRepo + "#" + ref + ":" + dir
+	res := SyncResult{Source: key}
+	fail := func(err error) SyncResult {
+		res.Status = SyncFailed
+		res.Error = err.Error()
+		return res
+	}
+
+	out, err := exec.CommandContext(ctx, "git", "ls-remote", cfg.GitRepo, ref).Output()
+	if err != nil {
+		return fail(fmt.Errorf("git ls-remote %s: %w", cfg.GitRepo, err))
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return fail(fmt.Errorf("ref %s not found in %s", ref, cfg.GitRepo))
+	}
+	revision := fields[0]
+
+	prev := index[key]
+	if prev != nil && !s.Force && prev.Revision == revision && s.cached(prev.Files) {
+		res.Status = SyncNotModified
+		res.Files = prev.Files
+		return res
+	}
+
+	tmp, err := os.MkdirTemp("", "skagent-docs-")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if cfg.GitRef != "" {
+		args = append(args, "--branch", cfg.GitRef)
+	}
+	args = append(args, cfg.GitRepo, tmp)
+	if out, err := exec.CommandContext(ctx, "git", args...).CombinedOutput(); err != nil {
+		return fail(fmt.Errorf("git clone %s: %w: %s", cfg.GitRepo, err, strings.TrimSpace(string(out))))
+	}
+
+	src := filepath.Join(tmp, filepath.FromSlash(dir))
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fail(fmt.Errorf("%s in %s: %w", dir, cfg.GitRepo, err))
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(src, entry.Name()))
+		if err != nil {
+			return fail(err)
+		}
+		if err := writeFileAtomic(filepath.Join(s.CacheDir, entry.Name()), data); err != nil {
+			return fail(err)
+		}
+		files = append(files, entry.Name())
+	}
+
+	// Drop files the repository no longer has
+	if prev != nil {
+		for _, old := range prev.Files {
+			if !contains(files, old) {
+				os.Remove(filepath.Join(s.CacheDir, old))
+			}
+		}
+	}
+
+	index[key] = &cacheEntry{Revision: revision, Files: files, FetchedAt: time.Now()}
+	res.Status = SyncUpdated
+	res.Files = files
+	return res
+}
+
+// cached reports whether every file of a previous sync is still on disk
+func (s *Syncer) cached(files []string) bool {
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(s.CacheDir, f)); err != nil {
+			return false
+		}
+	}
+	return len(files) > 0
+}
+
+func (s *Syncer) loadIndex() (map[string]*cacheEntry, error) {
+	index := make(map[string]*cacheEntry)
+	data, err := os.ReadFile(filepath.Join(s.CacheDir, indexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		// A corrupt index only costs a full refresh
+		return make(map[string]*cacheEntry), nil
+	}
+	return index, nil
+}
+
+func (s *Syncer) saveIndex(index map[string]*cacheEntry) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.CacheDir, indexFile), data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
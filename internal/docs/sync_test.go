@@ -0,0 +1,80 @@
+package docs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestSyncURLWithETag(t *testing.T) {
+	body := "# Remote commands"
+	etag := `"v1"`
+	var hits, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	cache := t.TempDir()
+	cfg := config.DocsConfig{URLs: []string{srv.URL + "/docs/commands.md"}}
+	syncer := NewSyncer(cache)
+
+	results, err := syncer.Sync(context.Background(), cfg)
+	if err != nil || len(results) != 1 || results[0].Status != SyncUpdated {
+		t.Fatalf("first sync: %+v, %v", results, err)
+	}
+
+	results, err = syncer.Sync(context.Background(), cfg)
+	if err != nil || results[0].Status != SyncNotModified || notModified != 1 {
+		t.Fatalf("second sync: %+v, %v (304s: %d)", results, err, notModified)
+	}
+
+	body, etag = "# Remote commands v2", `"v2"`
+	if results, _ = syncer.Sync(context.Background(), cfg); results[0].Status != SyncUpdated {
+		t.Fatalf("changed source: %+v", results)
+	}
+
+	content, err := NewDocLoader("").WithCacheDir(cache).LoadSpecKitDocs()
+	if err != nil {
+		t.Fatalf("LoadSpecKitDocs: %v", err)
+	}
+	if !strings.Contains(content, "v2") || strings.Contains(content, "/speckit.specify") {
+		t.Error("cached file did not replace the embedded commands.md")
+	}
+	if hits != 3 {
+		t.Errorf("server hits = %d, want 3", hits)
+	}
+}
+
+func TestSyncKeepsCacheOnFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cache := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cache, "extra.md"), []byte("# Kept"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := NewSyncer(cache).Sync(context.Background(), config.DocsConfig{URLs: []string{srv.URL + "/extra.md"}})
+	if err != nil || results[0].Status != SyncFailed {
+		t.Fatalf("sync: %+v, %v", results, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(cache, "extra.md")); string(data) != "# Kept" {
+		t.Fatalf("cached file changed: %q", data)
+	}
+}
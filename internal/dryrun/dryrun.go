@@ -0,0 +1,170 @@
+// Package dryrun simulates a run without side effects. While dry-run mode
+// is on, tools that change the world outside skagent (git commits and
+// pushes, gh, spec-kit, project manager updates, project files) log the
+// action they would take and skip it, so what an autonomous run would do
+// can be audited before granting it real permissions.
+package dryrun
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/redact"
+)
+
+// DefaultCapacity is how many skipped actions a recorder keeps when no
+// capacity is given
+const DefaultCapacity = 1000
+
+// Action is a change dry-run mode kept from happening
+type Action struct {
+	Seq       int64     `json:"seq"`
+	Time      time.Time `json:"time"`
+	Component string    `json:"component"`
+	Action    string    `json:"action"`
+}
+
+// Recorder logs skipped actions and keeps the most recent of them
+type Recorder struct {
+	logger *log.Logger
+
+	mu       sync.Mutex
+	actions  []Action
+	capacity int
+	seq      int64
+}
+
+// New returns a recorder keeping the last capacity actions; 0 uses
+// DefaultCapacity
+func New(capacity int) *Recorder {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Recorder{
+		logger:   logging.New("dryrun", "[DRY-RUN] ", log.Writer()),
+		capacity: capacity,
+	}
+}
+
+// Record logs an action that was skipped
+func (r *Recorder) Record(component, action string) Action {
+	action = redact.String(action)
+	r.mu.Lock()
+	r.seq++
+	a := Action{Seq: r.seq, Time: time.Now(), Component: component, Action: action}
+	r.actions = append(r.actions, a)
+	if len(r.actions) > r.capacity {
+		r.actions = append(r.actions[:0], r.actions[len(r.actions)-r.capacity:]...)
+	}
+	r.mu.Unlock()
+
+	r.logger.Printf("%s: would %s", component, action)
+	return a
+}
+
+// Actions returns the recorded actions, oldest first
+func (r *Recorder) Actions() []Action {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Action(nil), r.actions...)
+}
+
+var defaultRecorder atomic.Pointer[Recorder]
+
+// SetDefault turns dry-run mode on for the whole process, recording into
+// r; a nil recorder turns it off
+func SetDefault(r *Recorder) {
+	defaultRecorder.Store(r)
+}
+
+// Enable turns dry-run mode on with a new recorder, unless it already is
+func Enable() {
+	defaultRecorder.CompareAndSwap(nil, New(0))
+}
+
+// Enabled reports whether dry-run mode is on
+func Enabled() bool {
+	return defaultRecorder.Load() != nil
+}
+
+// Skip reports whether dry-run mode is on, recording the action when it
+// is; callers then return without taking it
+func Skip(component, format string, args ...interface{}) bool {
+	r := defaultRecorder.Load()
+	if r == nil {
+		return false
+	}
+	r.Record(component, fmt.Sprintf(format, args...))
+	return true
+}
+
+// Actions returns the actions skipped so far, or nil when dry-run mode is
+// off
+func Actions() []Action {
+	r := defaultRecorder.Load()
+	if r == nil {
+		return nil
+	}
+	return r.Actions()
+}
+
+// Command formats a command line as it would be typed in a shell
+func Command(name string, args ...string) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, name)
+	for _, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"$`\\|&;<>*?") {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Transport wraps base, nil for http.DefaultTransport, so that requests
+// other than GET, HEAD and OPTIONS are recorded instead of sent while
+// dry-run mode is on. They get an empty 200 JSON response.
+func Transport(component string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{component: component, base: base}
+}
+
+type transport struct {
+	component string
+	base      http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return t.base.RoundTrip(req)
+	}
+	u := *req.URL
+	u.User = nil
+	if !Skip(t.component, "send %s %s", req.Method, u.String()) {
+		return t.base.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader("{}")),
+		ContentLength: 2,
+		Request:       req,
+	}, nil
+}
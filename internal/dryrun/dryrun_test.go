@@ -0,0 +1,65 @@
+package dryrun
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSkipAndTransport(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: Transport("project", nil)}
+
+	if Skip("git", "run git push") {
+		t.Fatal("Skip is true with dry-run mode off")
+	}
+	resp, err := client.Post(server.URL+"/tasks", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if hits.Load() != 1 {
+		t.Fatalf("POST with dry-run mode off reached the server %d times", hits.Load())
+	}
+
+	SetDefault(New(2))
+	defer SetDefault(nil)
+
+	resp, err = client.Post(server.URL+"/tasks", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if hits.Load() != 1 || resp.StatusCode != http.StatusOK || string(body) != "{}" {
+		t.Fatalf("dry-run POST: %d hits, %d %s", hits.Load(), resp.StatusCode, body)
+	}
+	resp, err = client.Get(server.URL + "/tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if hits.Load() != 2 {
+		t.Fatalf("GET did not reach the server in a dry run")
+	}
+
+	if !Skip("git", "run %s", Command("git", "commit", "-m", "it's done")) {
+		t.Fatal("Skip is false with dry-run mode on")
+	}
+	Skip("git", "run git push")
+	actions := Actions()
+	if len(actions) != 2 || actions[0].Seq != 2 {
+		t.Fatalf("kept %+v, want the last 2", actions)
+	}
+	if want := `run git commit -m 'it'\''s done'`; actions[0].Action != want {
+		t.Errorf("action = %s, want %s", actions[0].Action, want)
+	}
+}
@@ -0,0 +1,281 @@
+// Package evaluation scores the results of completed tasks. An evaluator
+// model, which should differ from the one the agents use, checks each
+// result against the task's acceptance criteria; the score is recorded on
+// the result, and a result that falls short can be sent back to the agent
+// for revision with feedback on what to change.
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/tasklog"
+)
+
+// MetaEvaluate set to "false" on a task leaves its results unscored
+const MetaEvaluate = "evaluate"
+
+// maxOutput bounds the part of a result's output sent to the evaluator,
+// in bytes
+const maxOutput = 32 << 10
+
+// ErrNoModel is returned when there is no model to evaluate with
+var ErrNoModel = errors.New("no model is configured to evaluate with")
+
+// cause is recorded on the revisions the evaluator asks for
+var cause = agents.Cause{Actor: "evaluator"}
+
+// source names the evaluator in task logs
+const source = "evaluator"
+
+// Evaluator scores task results
+type Evaluator struct {
+	cfg      config.EvaluationConfig
+	registry *agents.Registry
+	provider func() ai.Provider
+	logger   *log.Logger
+
+	// warnModel logs once that the provider cannot switch models
+	warnModel sync.Once
+}
+
+// New returns an evaluator asking the model provider returns, switched to
+// the configured model when the provider serves several
+func New(cfg config.EvaluationConfig, registry *agents.Registry, provider func() ai.Provider) *Evaluator {
+	return &Evaluator{
+		cfg:      cfg,
+		registry: registry,
+		provider: provider,
+		logger:   logging.New("evaluation", "[EVAL] ", log.Writer()),
+	}
+}
+
+// model is the provider to evaluate with, or nil when there is none
+func (e *Evaluator) model() ai.Provider {
+	if e.provider == nil {
+		return nil
+	}
+	p := e.provider()
+	if p == nil || e.cfg.Model == "" {
+		return p
+	}
+	if m, ok := ai.WithModel(p, e.cfg.Model); ok {
+		return m
+	}
+	e.warnModel.Do(func() {
+		e.logger.Printf("WARN: Provider %s cannot switch to %s; evaluating with its own model", p.Name(), e.cfg.Model)
+	})
+	return p
+}
+
+// Run evaluates every task completed successfully, and sends back those
+// that fall short while revisions are left, until ctx is done or events
+// is closed
+func (e *Evaluator) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.Type != agents.EventTaskCompleted {
+				continue
+			}
+			task, ok := ev.Data["task"].(agents.Task)
+			if !ok || task.Result == nil || !task.Result.Success || task.Meta[MetaEvaluate] == "false" {
+				continue
+			}
+			go e.evaluate(ctx, task.ID)
+		}
+	}
+}
+
+// evaluate scores a task and sends it back when it falls short
+func (e *Evaluator) evaluate(ctx context.Context, taskID string) {
+	eval, err := e.Evaluate(ctx, taskID)
+	switch {
+	case errors.Is(err, agents.ErrTaskNotCompleted), errors.Is(err, agents.ErrTaskNotFound):
+		return
+	case err != nil:
+		e.logger.Printf("ERROR: Failed to evaluate task %s: %v", taskID, err)
+		return
+	case eval.Passed:
+		return
+	}
+
+	task, ok := e.registry.GetTask(taskID)
+	if !ok || task.Revision >= e.cfg.MaxRevisions {
+		return
+	}
+	c := cause
+	c.Reason = fmt.Sprintf("score %d is below %d", eval.Score, eval.Threshold)
+	if err := e.registry.ReviseTask(taskID, eval.Feedback, c); err != nil && !errors.Is(err, agents.ErrTaskNotCompleted) {
+		e.logger.Printf("ERROR: Failed to send task %s back for revision: %v", taskID, err)
+	}
+}
+
+// evaluatorPrompt asks the model for an evaluation in the shape of answer
+const evaluatorPrompt = `You are a strict reviewer of the work of AI agents. The user sends a task, its acceptance criteria and the result an agent produced.
+Judge only whether the result does what the task asks and meets every criterion; do not reward length or confidence.
+Answer with JSON only, no prose around it:
+{"score": <0-100>,
+ "summary": "<one or two sentences on the result's quality>",
+ "criteria": [{"criterion": "<criterion as given>", "met": true | false, "comment": "<why>"}],
+ "feedback": "<what the agent must change, addressed to the agent; empty if nothing>"}`
+
+// answer is the evaluator model's reply
+type answer struct {
+	Score    float64                  `json:"score"`
+	Summary  string                   `json:"summary"`
+	Criteria []agents.CriterionResult `json:"criteria"`
+	Feedback string                   `json:"feedback"`
+}
+
+// Evaluate scores a completed task's result and records the evaluation on
+// it
+func (e *Evaluator) Evaluate(ctx context.Context, taskID string) (*agents.Evaluation, error) {
+	task, ok := e.registry.GetTask(taskID)
+	if !ok {
+		return nil, agents.ErrTaskNotFound
+	}
+	if task.Status != agents.TaskStatusCompleted || task.Result == nil || !task.Result.Success {
+		return nil, agents.ErrTaskNotCompleted
+	}
+	provider := e.model()
+	if provider == nil {
+		return nil, ErrNoModel
+	}
+
+	prompt := Prompt(task)
+	tasklog.Record(task.ID, tasklog.KindPrompt, source, "%s", prompt)
+	reply, err := provider.Complete(ctx, []ai.Message{{Role: "user", Content: prompt}}, evaluatorPrompt)
+	if err != nil {
+		tasklog.Record(task.ID, tasklog.KindError, source, "Evaluation failed: %v", err)
+		return nil, err
+	}
+	tasklog.Record(task.ID, tasklog.KindResponse, source, "%s", reply)
+	a, err := parseAnswer(reply)
+	if err != nil {
+		tasklog.Record(task.ID, tasklog.KindError, source, "%v", err)
+		return nil, err
+	}
+
+	eval := score(a, task.AcceptanceCriteria, e.cfg.Threshold)
+	eval.Model = provider.Name()
+	if e.cfg.Model != "" {
+		eval.Model += " " + e.cfg.Model
+	}
+	if err := e.registry.SetTaskEvaluation(taskID, eval); err != nil {
+		return nil, err
+	}
+	return eval, nil
+}
+
+// Prompt is what the evaluator is told about a task: what it asks, its
+// criteria, any earlier feedback and the result
+func Prompt(task *agents.Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Task\n\n%s\n", task.Title)
+	if d := strings.TrimSpace(task.Description); d != "" {
+		fmt.Fprintf(&b, "\n%s\n", d)
+	}
+	b.WriteString("\n## Acceptance criteria\n\n")
+	if len(task.AcceptanceCriteria) == 0 {
+		b.WriteString("None were given; judge whether the result fully does what the task asks.\n")
+	}
+	for _, c := range task.AcceptanceCriteria {
+		fmt.Fprintf(&b, "- %s\n", c)
+	}
+	if task.Feedback != "" {
+		fmt.Fprintf(&b, "\n## Feedback on the previous attempt\n\n%s\n", task.Feedback)
+	}
+	output := task.Result.Output
+	if len(output) > maxOutput {
+		output = output[:maxOutput] + "\n[output truncated]"
+	}
+	fmt.Fprintf(&b, "\n## Result\n\n%s\n", output)
+	if len(task.Result.Artifacts) > 0 {
+		fmt.Fprintf(&b, "\nArtifacts: %s\n", strings.Join(task.Result.Artifacts, ", "))
+	}
+	return b.String()
+}
+
+func parseAnswer(reply string) (*answer, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("the evaluator did not answer with JSON")
+	}
+	var a answer
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &a); err != nil {
+		return nil, fmt.Errorf("the evaluator's answer is not valid JSON: %w", err)
+	}
+	return &a, nil
+}
+
+// score turns the evaluator's answer into an evaluation. Every criterion
+// of the task is reported, those the model left out as not met, and the
+// result passes when it reaches threshold and meets all of them.
+func score(a *answer, criteria []string, threshold int) *agents.Evaluation {
+	points := int(a.Score + 0.5)
+	if points < 0 {
+		points = 0
+	} else if points > 100 {
+		points = 100
+	}
+	eval := &agents.Evaluation{
+		Score:       points,
+		Threshold:   threshold,
+		Summary:     strings.TrimSpace(a.Summary),
+		Feedback:    strings.TrimSpace(a.Feedback),
+		EvaluatedAt: time.Now(),
+	}
+
+	judged := make(map[string]agents.CriterionResult, len(a.Criteria))
+	for _, c := range a.Criteria {
+		judged[normalize(c.Criterion)] = c
+	}
+	met := true
+	for _, c := range criteria {
+		r, ok := judged[normalize(c)]
+		if !ok {
+			r = agents.CriterionResult{Comment: "not judged by the evaluator"}
+		}
+		r.Criterion = c
+		met = met && r.Met
+		eval.Criteria = append(eval.Criteria, r)
+	}
+	eval.Passed = met && points >= threshold
+
+	if !eval.Passed && eval.Feedback == "" {
+		var missed []string
+		for _, r := range eval.Criteria {
+			if !r.Met {
+				missed = append(missed, r.Criterion)
+			}
+		}
+		if len(missed) > 0 {
+			eval.Feedback = "Meet these acceptance criteria: " + strings.Join(missed, "; ")
+		} else {
+			eval.Feedback = eval.Summary
+		}
+	}
+	return eval
+}
+
+// normalize matches criteria the model quoted with different case or
+// spacing
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
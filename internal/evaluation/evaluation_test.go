@@ -0,0 +1,109 @@
+package evaluation
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func completedTask(t *testing.T, r *agents.Registry, criteria ...string) (*agents.Task, *agents.Agent) {
+	t.Helper()
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+	task := r.CreateTask(&agents.Task{Title: "Add a login page", AcceptanceCriteria: criteria})
+	if err := r.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CompleteTask(task.ID, &agents.TaskResult{Success: true, Output: "added login.html"}); err != nil {
+		t.Fatal(err)
+	}
+	return task, agent
+}
+
+func TestEvaluateRecordsScore(t *testing.T) {
+	r := agents.NewRegistry(context.Background())
+	task, _ := completedTask(t, r, "Has a password field", "Links to sign-up")
+	model := ai.NewMockProvider(`Here you go: {"score": 85.4, "summary": "Solid.", "criteria": [
+		{"criterion": "has a PASSWORD field", "met": true},
+		{"criterion": "Links to sign-up", "met": true, "comment": "footer link"}]}`)
+	e := New(config.EvaluationConfig{Threshold: 70}, r, func() ai.Provider { return model })
+
+	eval, err := e.Evaluate(context.Background(), task.ID)
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if eval.Score != 85 || !eval.Passed || len(eval.Criteria) != 2 || eval.Criteria[0].Criterion != "Has a password field" {
+		t.Fatalf("evaluation = %+v", eval)
+	}
+	got, _ := r.GetTask(task.ID)
+	if got.Result.Evaluation == nil || got.Result.Evaluation.Score != 85 {
+		t.Fatalf("evaluation not recorded: %+v", got.Result)
+	}
+	prompt := model.Calls()[0].Messages[0].Content
+	if !strings.Contains(prompt, "- Links to sign-up") || !strings.Contains(prompt, "added login.html") {
+		t.Errorf("prompt misses the criteria or result:\n%s", prompt)
+	}
+}
+
+func TestScoreFailsUnjudgedCriteria(t *testing.T) {
+	eval := score(&answer{Score: 95}, []string{"Has tests"}, 70)
+	if eval.Passed || eval.Criteria[0].Met {
+		t.Fatalf("passed with a criterion the model did not judge: %+v", eval)
+	}
+	if !strings.Contains(eval.Feedback, "Has tests") {
+		t.Errorf("feedback = %q, want the missed criterion", eval.Feedback)
+	}
+	if eval := score(&answer{Score: 140}, nil, 70); eval.Score != 100 || !eval.Passed {
+		t.Errorf("score not clamped: %+v", eval)
+	}
+}
+
+func TestRunSendsWeakResultsBack(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := agents.NewRegistry(ctx)
+	events, unsubscribe := r.Subscribe(64)
+	defer unsubscribe()
+	weak := `{"score": 40, "summary": "Incomplete.", "feedback": "Add the sign-up link."}`
+	model := ai.NewMockProvider(weak, weak)
+	e := New(config.EvaluationConfig{Threshold: 70, MaxRevisions: 1}, r, func() ai.Provider { return model })
+	go e.Run(ctx, events)
+
+	task, agent := completedTask(t, r)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got, _ := r.GetTask(task.ID)
+		if got.Revision == 1 {
+			if got.Status != agents.TaskStatusInProgress || got.AssignedTo != agent.ID || got.Feedback != "Add the sign-up link." {
+				t.Fatalf("revised task = %+v", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task not sent back: %+v", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The second attempt is scored but not sent back again
+	if err := r.CompleteTask(task.ID, &agents.TaskResult{Success: true, Output: "still no link"}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		got, _ := r.GetTask(task.ID)
+		if got.Result.Evaluation != nil {
+			if got.Status != agents.TaskStatusCompleted || got.Revision != 1 {
+				t.Fatalf("task after the last revision = %+v", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("second attempt not evaluated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
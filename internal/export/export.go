@@ -0,0 +1,368 @@
+// Package export writes the fleet's history as tables for analysis in BI
+// tools: one row per task with its outcome and timings, one per agent with
+// its stats, and the daily usage of each model. Tables are written as CSV
+// or as Parquet files readable by pandas, DuckDB, Spark and the like.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// Format is the file format of an export
+type Format string
+
+// Formats
+const (
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat reads a format name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatCSV, FormatParquet:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown export format %q; use csv or parquet", name)
+}
+
+// Kind is the type of a column's values
+type Kind int
+
+// Column kinds, and the Go type of their values
+const (
+	KindString Kind = iota // string
+	KindInt                // int64
+	KindFloat              // float64
+	KindBool               // bool
+	KindTime               // time.Time, stored to the millisecond in UTC
+)
+
+// Column is a named, typed column
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// Table is a dataset to export. A nil value is a missing one: an empty
+// CSV field or a Parquet null.
+type Table struct {
+	Name    string
+	Columns []Column
+	Rows    [][]interface{}
+}
+
+// Write writes the table to w in the format f
+func Write(w io.Writer, t *Table, f Format) error {
+	switch f {
+	case FormatCSV:
+		return WriteCSV(w, t)
+	case FormatParquet:
+		return WriteParquet(w, t)
+	}
+	return fmt.Errorf("unknown export format %q", f)
+}
+
+// WriteCSV writes the table as CSV with a header row. Times are RFC 3339
+// in UTC.
+func WriteCSV(w io.Writer, t *Table) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Columns))
+	for _, row := range t.Rows {
+		for i := range t.Columns {
+			record[i] = csvValue(row[i])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// TaskColumns are the columns of Tasks
+var TaskColumns = []Column{
+	{"id", KindString},
+	{"title", KindString},
+	{"status", KindString},
+	{"success", KindBool},
+	{"priority", KindInt},
+	{"agent_id", KindString},
+	{"agent_name", KindString},
+	{"agent_type", KindString},
+	{"workspace", KindString},
+	{"source", KindString},
+	{"external_id", KindString},
+	{"labels", KindString},
+	{"model", KindString},
+	{"revision", KindInt},
+	{"attempts", KindInt},
+	{"score", KindInt},
+	{"error", KindString},
+	{"created_at", KindTime},
+	{"started_at", KindTime},
+	{"completed_at", KindTime},
+	{"queue_ms", KindInt},
+	{"duration_ms", KindInt},
+}
+
+// Tasks is a row per task created since since, oldest first: its outcome,
+// who ran it with which model, and how long it waited and ran. agents names
+// the agents by ID; tasks of agents it lacks keep only the ID. Labels are
+// joined with ";".
+func Tasks(tasks []agents.Task, agentList []agents.AgentView, since time.Time) *Table {
+	byID := make(map[string]agents.AgentView, len(agentList))
+	for _, a := range agentList {
+		byID[a.ID] = a
+	}
+	t := &Table{Name: "tasks", Columns: TaskColumns}
+	for _, task := range sinceSorted(tasks, since) {
+		var success, model, score, errText, duration interface{}
+		if r := task.Result; r != nil && finished(task.Status) {
+			success = r.Success
+			model = str(r.Model)
+			errText = str(r.Error)
+			duration = r.Duration
+			if r.Evaluation != nil {
+				score = int64(r.Evaluation.Score)
+			}
+		}
+		var agentName, agentType interface{}
+		if a, ok := byID[task.AssignedTo]; ok {
+			agentName, agentType = a.Name, string(a.Type)
+		}
+		var queue interface{}
+		if task.StartedAt != nil {
+			queue = task.StartedAt.Sub(task.CreatedAt).Milliseconds()
+		}
+		t.Rows = append(t.Rows, []interface{}{
+			task.ID,
+			task.Title,
+			string(task.Status),
+			success,
+			int64(task.Priority),
+			str(task.AssignedTo),
+			agentName,
+			agentType,
+			task.Workspace,
+			str(task.Source),
+			str(task.ExternalID),
+			str(strings.Join(task.Labels, ";")),
+			model,
+			int64(task.Revision),
+			int64(len(task.Attempts) + 1),
+			score,
+			errText,
+			task.CreatedAt,
+			timePtr(task.StartedAt),
+			timePtr(task.CompletedAt),
+			queue,
+			duration,
+		})
+	}
+	return t
+}
+
+// AgentColumns are the columns of Agents
+var AgentColumns = []Column{
+	{"id", KindString},
+	{"name", KindString},
+	{"type", KindString},
+	{"status", KindString},
+	{"workspace", KindString},
+	{"labels", KindString},
+	{"tasks_completed", KindInt},
+	{"tasks_failed", KindInt},
+	{"success_rate", KindFloat},
+	{"total_time_ms", KindInt},
+	{"avg_time_ms", KindInt},
+	{"last_active", KindTime},
+	{"created_at", KindTime},
+}
+
+// Agents is a row per agent with its lifetime stats
+func Agents(agentList []agents.AgentView) *Table {
+	list := append([]agents.AgentView(nil), agentList...)
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	t := &Table{Name: "agents", Columns: AgentColumns}
+	for _, a := range list {
+		var lastActive interface{}
+		if !a.Stats.LastActive.IsZero() {
+			lastActive = a.Stats.LastActive
+		}
+		t.Rows = append(t.Rows, []interface{}{
+			a.ID,
+			a.Name,
+			string(a.Type),
+			string(a.Status),
+			a.Workspace,
+			str(strings.Join(a.Labels, ";")),
+			int64(a.Stats.TasksCompleted),
+			int64(a.Stats.TasksFailed),
+			a.Stats.SuccessRate,
+			a.Stats.TotalTime,
+			a.Stats.AvgTime,
+			lastActive,
+			a.CreatedAt,
+		})
+	}
+	return t
+}
+
+// UsageColumns are the columns of Usage
+var UsageColumns = []Column{
+	{"date", KindString},
+	{"model", KindString},
+	{"tasks", KindInt},
+	{"failed", KindInt},
+	{"duration_ms", KindInt},
+	{"avg_duration_ms", KindInt},
+}
+
+// Usage is a row per day and model with the tasks the model finished that
+// day, in UTC, since since. Tasks finished without a model, as CLI
+// providers leave them, are counted under "unknown".
+func Usage(tasks []agents.Task, since time.Time) *Table {
+	type key struct{ date, model string }
+	type usage struct{ tasks, failed, duration int64 }
+	byKey := make(map[key]*usage)
+	for _, task := range tasks {
+		r := task.Result
+		if r == nil || task.CompletedAt == nil || task.CompletedAt.Before(since) || !finished(task.Status) {
+			continue
+		}
+		k := key{task.CompletedAt.UTC().Format("2006-01-02"), r.Model}
+		if k.model == "" {
+			k.model = "unknown"
+		}
+		u, ok := byKey[k]
+		if !ok {
+			u = &usage{}
+			byKey[k] = u
+		}
+		u.tasks++
+		if !r.Success {
+			u.failed++
+		}
+		u.duration += r.Duration
+	}
+	keys := make([]key, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].date != keys[j].date {
+			return keys[i].date < keys[j].date
+		}
+		return keys[i].model < keys[j].model
+	})
+	t := &Table{Name: "usage", Columns: UsageColumns}
+	for _, k := range keys {
+		u := byKey[k]
+		t.Rows = append(t.Rows, []interface{}{k.date, k.model, u.tasks, u.failed, u.duration, u.duration / u.tasks})
+	}
+	return t
+}
+
+// sinceSorted returns the tasks created since since, oldest first
+func sinceSorted(tasks []agents.Task, since time.Time) []agents.Task {
+	out := make([]agents.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if !t.CreatedAt.Before(since) {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// finished reports whether a task in status has its final result; a task
+// sent back for revision keeps the result of its previous attempt
+func finished(status agents.TaskStatus) bool {
+	switch status {
+	case agents.TaskStatusCompleted, agents.TaskStatusFailed:
+		return true
+	}
+	return false
+}
+
+// str is s, or nil when it is empty
+func str(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func timePtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// ParseSince reads how far back an export goes: a Go duration such as
+// 12h, a number of days such as 30d, or an RFC 3339 date or time
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q; use a duration such as 30d or 12h, or a date such as 2024-05-01", s)
+}
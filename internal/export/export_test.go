@@ -0,0 +1,384 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func fixture() ([]agents.Task, []agents.AgentView, time.Time) {
+	now := time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	tasks := []agents.Task{
+		{
+			ID: "t2", Title: "Fix, then \"ship\"", Status: agents.TaskStatusCompleted, AssignedTo: "a1",
+			Priority: agents.PriorityHigh, Workspace: "default", Labels: []string{"go", "api"},
+			CreatedAt: now.Add(-3 * time.Hour), StartedAt: at(-170 * time.Minute), CompletedAt: at(-2 * time.Hour),
+			Result: &agents.TaskResult{Success: true, Model: "gpt-4o", Duration: 600000,
+				Evaluation: &agents.Evaluation{Score: 85}},
+		},
+		{
+			ID: "t3", Title: "Flaky", Status: agents.TaskStatusFailed, AssignedTo: "gone",
+			Workspace: "default", CreatedAt: now.Add(-time.Hour), StartedAt: at(-time.Hour), CompletedAt: at(-30 * time.Minute),
+			Result: &agents.TaskResult{Success: false, Error: "boom", Model: "gpt-4o", Duration: 1000},
+		},
+		{ID: "t1", Title: "Old", Status: agents.TaskStatusCompleted, CreatedAt: now.AddDate(0, 0, -40)},
+		{ID: "t4", Title: "Waiting", Status: agents.TaskStatusPending, Workspace: "default", CreatedAt: now.Add(-time.Minute)},
+	}
+	agentList := []agents.AgentView{{
+		ID: "a1", Name: "coder-1", Type: agents.AgentTypeCoder, Status: agents.StatusIdle, Workspace: "default",
+		Stats:     agents.AgentStats{TasksCompleted: 4, TasksFailed: 1, SuccessRate: 0.8, TotalTime: 5000, AvgTime: 1000},
+		CreatedAt: now.AddDate(0, -1, 0),
+	}}
+	return tasks, agentList, now
+}
+
+func TestTasksCSV(t *testing.T) {
+	tasks, agentList, now := fixture()
+	since, err := ParseSince("30d", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, Tasks(tasks, agentList, since), FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("%d records, want a header and 3 tasks:\n%v", len(records), records)
+	}
+	row := func(r []string) map[string]string {
+		m := make(map[string]string)
+		for i, name := range records[0] {
+			m[name] = r[i]
+		}
+		return m
+	}
+	first := row(records[1])
+	want := map[string]string{
+		"id": "t2", "title": `Fix, then "ship"`, "success": "true", "priority": "2",
+		"agent_name": "coder-1", "agent_type": "coder", "labels": "go;api", "model": "gpt-4o",
+		"score": "85", "queue_ms": "600000", "duration_ms": "600000",
+		"created_at": "2024-05-20T09:00:00Z", "error": "",
+	}
+	for k, v := range want {
+		if first[k] != v {
+			t.Errorf("%s = %q, want %q", k, first[k], v)
+		}
+	}
+	if failed := row(records[2]); failed["id"] != "t3" || failed["success"] != "false" || failed["error"] != "boom" || failed["agent_name"] != "" {
+		t.Errorf("failed task: %v", failed)
+	}
+	if pending := row(records[3]); pending["success"] != "" || pending["completed_at"] != "" || pending["queue_ms"] != "" {
+		t.Errorf("pending task: %v", pending)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	tasks, _, now := fixture()
+	u := Usage(tasks, now.AddDate(0, 0, -1))
+	if len(u.Rows) != 1 {
+		t.Fatalf("rows: %v", u.Rows)
+	}
+	want := []interface{}{"2024-05-20", "gpt-4o", int64(2), int64(1), int64(601000), int64(300500)}
+	for i, v := range want {
+		if u.Rows[0][i] != v {
+			t.Fatalf("%s = %v, want %v", u.Columns[i].Name, u.Rows[0][i], v)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC)
+	for in, want := range map[string]time.Time{
+		"":           {},
+		"7d":         now.AddDate(0, 0, -7),
+		"90m":        now.Add(-90 * time.Minute),
+		"2024-05-01": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		got, err := ParseSince(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseSince(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseSince("last week", now); err == nil {
+		t.Error("ParseSince accepted garbage")
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("ParseFormat accepted xlsx")
+	}
+}
+
+func TestParquet(t *testing.T) {
+	tasks, agentList, now := fixture()
+	table := Tasks(tasks, agentList, now.AddDate(0, 0, -30))
+	var buf bytes.Buffer
+	if err := Write(&buf, table, FormatParquet); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatal("missing magic")
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &compactReader{buf: data[len(data)-8-size : len(data)-8]}
+	meta := footer.readStruct()
+	if footer.pos != len(footer.buf) {
+		t.Fatalf("footer decoded %d of %d bytes", footer.pos, len(footer.buf))
+	}
+
+	if meta[3] != int64(3) {
+		t.Fatalf("num_rows = %v", meta[3])
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != len(TaskColumns)+1 {
+		t.Fatalf("%d schema elements", len(schema))
+	}
+	for i, c := range TaskColumns {
+		el := schema[i+1].(map[int16]interface{})
+		if el[4] != c.Name {
+			t.Fatalf("schema element %d is %v, want %s", i+1, el[4], c.Name)
+		}
+	}
+	columns := meta[4].([]interface{})[0].(map[int16]interface{})[1].([]interface{})
+
+	// Read columns back from their pages
+	read := func(name string) []interface{} {
+		for i, c := range TaskColumns {
+			if c.Name != name {
+				continue
+			}
+			cm := columns[i].(map[int16]interface{})[3].(map[int16]interface{})
+			page := &compactReader{buf: data, pos: int(cm[9].(int64))}
+			header := page.readStruct()
+			body := data[page.pos : page.pos+int(header[3].(int32))]
+			n := int(header[5].(map[int16]interface{})[1].(int32))
+			return decodePage(t, body, n, c.Kind)
+		}
+		t.Fatalf("no column %s", name)
+		return nil
+	}
+	if ids := read("id"); len(ids) != 3 || ids[0] != "t2" || ids[1] != "t3" || ids[2] != "t4" {
+		t.Fatalf("ids: %v", ids)
+	}
+	if d := read("duration_ms"); d[0] != int64(600000) || d[1] != int64(1000) || d[2] != nil {
+		t.Fatalf("durations: %v", d)
+	}
+	if s := read("success"); s[0] != true || s[1] != false || s[2] != nil {
+		t.Fatalf("success: %v", s)
+	}
+	if c := read("created_at"); c[0] != tasks[0].CreatedAt.UnixMilli() {
+		t.Fatalf("created_at: %v", c)
+	}
+}
+
+// decodePage reads a page of an optional column with plain encoding
+func decodePage(t *testing.T, body []byte, n int, kind Kind) []interface{} {
+	t.Helper()
+	size := int(binary.LittleEndian.Uint32(body))
+	levels := body[4 : 4+size]
+	values := body[4+size:]
+	var defined []bool
+	for len(levels) > 0 {
+		header, k := binary.Uvarint(levels)
+		if header&1 != 0 {
+			t.Fatal("bit-packed run")
+		}
+		for i := uint64(0); i < header>>1; i++ {
+			defined = append(defined, levels[k] == 1)
+		}
+		levels = levels[k+1:]
+	}
+	if len(defined) != n {
+		t.Fatalf("%d levels for %d values", len(defined), n)
+	}
+	out := make([]interface{}, n)
+	bit := 0
+	for i, ok := range defined {
+		if !ok {
+			continue
+		}
+		switch kind {
+		case KindString:
+			l := int(binary.LittleEndian.Uint32(values))
+			out[i] = string(values[4 : 4+l])
+			values = values[4+l:]
+		case KindInt, KindTime:
+			out[i] = int64(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case KindBool:
+			out[i] = values[bit/8]&(1<<(bit%8)) != 0
+			bit++
+		}
+	}
+	return out
+}
+
+// compactReader decodes Thrift compact structs into maps by field ID
+type compactReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *compactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) readStruct() map[int16]interface{} {
+	out := make(map[int16]interface{})
+	var last int16
+	for {
+		b := r.buf[r.pos]
+		r.pos++
+		if b == 0 {
+			return out
+		}
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		last = id
+		out[id] = r.readValue(b & 0x0f)
+	}
+}
+
+func (r *compactReader) readValue(typ byte) interface{} {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case compactI32:
+		return int32(r.zigzag())
+	case compactI64:
+		return r.zigzag()
+	case compactBinary:
+		n := int(r.uvarint())
+		s := string(r.buf[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case compactList:
+		b := r.buf[r.pos]
+		r.pos++
+		n := int(b >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = r.readValue(b & 0x0f)
+		}
+		return list
+	case compactStruct:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("unknown compact type %d", typ))
+}
+
+// spec assembles a byte string from bytes and raw strings
+func spec(parts ...interface{}) []byte {
+	var out []byte
+	for _, p := range parts {
+		switch p := p.(type) {
+		case int:
+			out = append(out, byte(p))
+		case string:
+			out = append(out, p...)
+		}
+	}
+	return out
+}
+
+// TestParquetGolden checks the writer byte for byte against a file worked
+// out by hand from parquet.thrift and the encodings document of
+// apache/parquet-format, so that the test does not share the writer's
+// reading of the format.
+func TestParquetGolden(t *testing.T) {
+	table := &Table{
+		Name:    "golden",
+		Columns: []Column{{"name", KindString}, {"n", KindInt}, {"ok", KindBool}, {"at", KindTime}},
+		Rows: [][]interface{}{
+			{"a", int64(1), true, time.UnixMilli(1000)},
+			{nil, nil, false, nil},
+		},
+	}
+	// PageHeader{type: DATA_PAGE, uncompressed_page_size and
+	// compressed_page_size: size, data_page_header: DataPageHeader{
+	// num_values: 2, encoding: PLAIN, definition_level_encoding and
+	// repetition_level_encoding: RLE}}, sizes zigzag encoded
+	pageHeader := func(size int) []byte {
+		return spec(0x15, 0x00, 0x15, size, 0x15, size, 0x2c, 0x15, 0x04, 0x15, 0x00, 0x15, 0x06, 0x15, 0x06, 0x00, 0x00)
+	}
+	// Definition levels of a value then a null: the 4-byte length, then
+	// two RLE runs of one, header 1<<1 and a 1-byte value
+	valueThenNull := spec(0x04, 0, 0, 0, 0x02, 0x01, 0x02, 0x00)
+	// ColumnMetaData of one chunk, inside its ColumnChunk: file_offset,
+	// then type, encodings [PLAIN, RLE], path_in_schema, codec
+	// UNCOMPRESSED, num_values 2, both total sizes and data_page_offset
+	chunk := func(offset []byte, physical int, name string, size int) []byte {
+		return spec(0x26, string(offset), 0x1c,
+			0x15, physical, 0x19, 0x25, 0x00, 0x06, 0x19, 0x18, len(name), name,
+			0x15, 0x00, 0x16, 0x04, 0x16, size, 0x16, size, 0x26, string(offset), 0x00, 0x00)
+	}
+
+	want := bytes.Join([][]byte{
+		spec("PAR1"),
+		// name at 4: 17 + 13 bytes, "a" as a 4-byte length and its bytes
+		pageHeader(0x1a), valueThenNull, spec(0x01, 0, 0, 0, "a"),
+		// n at 34: 17 + 16 bytes, 1 as a little-endian INT64
+		pageHeader(0x20), valueThenNull, spec(1, 0, 0, 0, 0, 0, 0, 0),
+		// ok at 67: 17 + 7 bytes, one run of two values, bit-packed true
+		// then false
+		pageHeader(0x0e), spec(0x02, 0, 0, 0, 0x04, 0x01), spec(0x01),
+		// at at 91: 17 + 16 bytes, 1000 milliseconds
+		pageHeader(0x20), valueThenNull, spec(0xe8, 0x03, 0, 0, 0, 0, 0, 0),
+
+		// FileMetaData: version 1, then the schema, a list of 5 structs
+		spec(0x15, 0x02, 0x19, 0x5c),
+		// the root: name "schema", num_children 4
+		spec(0x48, 6, "schema", 0x15, 0x08, 0x00),
+		// type, repetition_type OPTIONAL, name and converted_type: UTF8
+		// BYTE_ARRAY, INT64, BOOLEAN, TIMESTAMP_MILLIS INT64
+		spec(0x15, 0x0c, 0x25, 0x02, 0x18, 4, "name", 0x25, 0x00, 0x00),
+		spec(0x15, 0x04, 0x25, 0x02, 0x18, 1, "n", 0x00),
+		spec(0x15, 0x00, 0x25, 0x02, 0x18, 2, "ok", 0x00),
+		spec(0x15, 0x04, 0x25, 0x02, 0x18, 2, "at", 0x25, 0x12, 0x00),
+		// num_rows 2, and row_groups, a list of one RowGroup whose columns
+		// are a list of 4 ColumnChunks
+		spec(0x16, 0x04, 0x19, 0x1c, 0x19, 0x4c),
+		chunk(spec(0x08), 0x0c, "name", 0x3c),
+		chunk(spec(0x44), 0x04, "n", 0x42),
+		chunk(spec(0x86, 0x01), 0x00, "ok", 0x30),
+		chunk(spec(0xb6, 0x01), 0x04, "at", 0x42),
+		// the RowGroup's total_byte_size 120 and num_rows 2
+		spec(0x16, 0xf0, 0x01, 0x16, 0x04, 0x00),
+		// created_by
+		spec(0x28, 14, "skagent export", 0x00),
+	}, nil)
+	// The footer's length, as the pages end at 124
+	want = binary.LittleEndian.AppendUint32(want, uint32(len(want)-124))
+	want = append(want, "PAR1"...)
+
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, table); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		t.Fatalf("file differs from the golden one:\ngot  % x\nwant % x", got, want)
+	}
+}
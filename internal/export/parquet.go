@@ -0,0 +1,330 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// The Parquet writer covers what the tables need: a flat schema of
+// optional columns, one row group, one uncompressed data page per column
+// with plain encoding, and the file metadata in Thrift's compact protocol.
+// See https://github.com/apache/parquet-format.
+
+// parquetMagic starts and ends a Parquet file
+const parquetMagic = "PAR1"
+
+// Parquet physical types
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// Parquet converted types, the logical type of a column
+const (
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+)
+
+// Other enums of the format
+const (
+	repetitionOptional = 1
+	encodingPlain      = 0
+	encodingRLE        = 3
+	codecUncompressed  = 0
+	pageData           = 0
+)
+
+// parquetCreatedBy names the writer in the file metadata
+const parquetCreatedBy = "skagent export"
+
+// WriteParquet writes the table as a Parquet file. Strings are UTF-8 byte
+// arrays, integers INT64, floats DOUBLE and times INT64 milliseconds since
+// the epoch, in UTC; every column is optional.
+func WriteParquet(w io.Writer, t *Table) error {
+	var buf bytes.Buffer
+	buf.WriteString(parquetMagic)
+
+	chunks := make([]columnChunk, len(t.Columns))
+	for i, c := range t.Columns {
+		offset := int64(buf.Len())
+		page, err := dataPage(t, i)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", c.Name, err)
+		}
+		var header compactWriter
+		header.beginStruct()
+		header.i32Field(1, pageData)
+		header.i32Field(2, int32(len(page)))
+		header.i32Field(3, int32(len(page)))
+		header.structField(5)
+		header.i32Field(1, int32(len(t.Rows)))
+		header.i32Field(2, encodingPlain)
+		header.i32Field(3, encodingRLE)
+		header.i32Field(4, encodingRLE)
+		header.endStruct()
+		header.endStruct()
+		buf.Write(header.bytes())
+		buf.Write(page)
+		chunks[i] = columnChunk{offset: offset, size: int64(buf.Len()) - offset}
+	}
+
+	footer := fileMetaData(t, chunks)
+	buf.Write(footer)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(footer)))
+	buf.WriteString(parquetMagic)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// columnChunk is where a column's page was written
+type columnChunk struct {
+	offset, size int64
+}
+
+// dataPage encodes column i of the table: the definition levels, which
+// mark the nulls, then the plain-encoded values that are not null
+func dataPage(t *Table, i int) ([]byte, error) {
+	kind := t.Columns[i].Kind
+	levels := make([]bool, len(t.Rows))
+	var values bytes.Buffer
+	var bits []bool
+	for r, row := range t.Rows {
+		v := row[i]
+		if v == nil {
+			continue
+		}
+		levels[r] = true
+		switch kind {
+		case KindString:
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("row %d: %T is not a string", r, v)
+			}
+			binary.Write(&values, binary.LittleEndian, uint32(len(s)))
+			values.WriteString(s)
+		case KindInt:
+			n, ok := v.(int64)
+			if !ok {
+				return nil, fmt.Errorf("row %d: %T is not an int64", r, v)
+			}
+			binary.Write(&values, binary.LittleEndian, n)
+		case KindFloat:
+			f, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("row %d: %T is not a float64", r, v)
+			}
+			binary.Write(&values, binary.LittleEndian, math.Float64bits(f))
+		case KindBool:
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("row %d: %T is not a bool", r, v)
+			}
+			bits = append(bits, b)
+		case KindTime:
+			tm, ok := v.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("row %d: %T is not a time", r, v)
+			}
+			binary.Write(&values, binary.LittleEndian, tm.UnixMilli())
+		}
+	}
+	if kind == KindBool {
+		// Booleans are bit-packed, the first value in the lowest bit
+		packed := make([]byte, (len(bits)+7)/8)
+		for j, b := range bits {
+			if b {
+				packed[j/8] |= 1 << (j % 8)
+			}
+		}
+		values.Write(packed)
+	}
+
+	var page bytes.Buffer
+	encoded := definitionLevels(levels)
+	binary.Write(&page, binary.LittleEndian, uint32(len(encoded)))
+	page.Write(encoded)
+	page.Write(values.Bytes())
+	return page.Bytes(), nil
+}
+
+// definitionLevels encodes the levels of an optional column, 1 for a value
+// and 0 for a null, as runs of the RLE/bit-packing hybrid with a bit width
+// of 1
+func definitionLevels(levels []bool) []byte {
+	var out []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		if levels[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i = j
+	}
+	return out
+}
+
+// fileMetaData encodes the footer: the schema, and one row group holding
+// the column chunks
+func fileMetaData(t *Table, chunks []columnChunk) []byte {
+	var m compactWriter
+	m.beginStruct()
+	m.i32Field(1, 1)
+
+	m.listField(2, compactStruct, len(t.Columns)+1)
+	m.beginStruct() // the root of the schema
+	m.stringField(4, "schema")
+	m.i32Field(5, int32(len(t.Columns)))
+	m.endStruct()
+	for _, c := range t.Columns {
+		physical, converted := parquetType(c.Kind)
+		m.beginStruct()
+		m.i32Field(1, physical)
+		m.i32Field(3, repetitionOptional)
+		m.stringField(4, c.Name)
+		if converted >= 0 {
+			m.i32Field(6, converted)
+		}
+		m.endStruct()
+	}
+
+	m.i64Field(3, int64(len(t.Rows)))
+
+	var total int64
+	for _, c := range chunks {
+		total += c.size
+	}
+	m.listField(4, compactStruct, 1)
+	m.beginStruct()
+	m.listField(1, compactStruct, len(t.Columns))
+	for i, c := range t.Columns {
+		physical, _ := parquetType(c.Kind)
+		m.beginStruct()
+		m.i64Field(2, chunks[i].offset)
+		m.structField(3)
+		m.i32Field(1, physical)
+		m.listField(2, compactI32, 2)
+		m.writeVarint(encodingPlain)
+		m.writeVarint(encodingRLE)
+		m.listField(3, compactBinary, 1)
+		m.writeString(c.Name)
+		m.i32Field(4, codecUncompressed)
+		m.i64Field(5, int64(len(t.Rows)))
+		m.i64Field(6, chunks[i].size)
+		m.i64Field(7, chunks[i].size)
+		m.i64Field(9, chunks[i].offset)
+		m.endStruct()
+		m.endStruct()
+	}
+	m.i64Field(2, total)
+	m.i64Field(3, int64(len(t.Rows)))
+	m.endStruct()
+
+	m.stringField(6, parquetCreatedBy)
+	m.endStruct()
+	return m.bytes()
+}
+
+// parquetType maps a column kind to its physical and converted types; -1
+// is no converted type
+func parquetType(k Kind) (physical, converted int32) {
+	switch k {
+	case KindInt:
+		return parquetInt64, -1
+	case KindFloat:
+		return parquetDouble, -1
+	case KindBool:
+		return parquetBoolean, -1
+	case KindTime:
+		return parquetInt64, convertedTimestampMillis
+	}
+	return parquetByteArray, convertedUTF8
+}
+
+// Thrift compact protocol types
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter encodes Thrift structs with the compact protocol, in which
+// a field header holds the difference from the previous field ID
+type compactWriter struct {
+	buf  []byte
+	last []int16 // the last field ID of each open struct
+}
+
+func (c *compactWriter) bytes() []byte { return c.buf }
+
+func (c *compactWriter) beginStruct() { c.last = append(c.last, 0) }
+
+func (c *compactWriter) endStruct() {
+	c.buf = append(c.buf, 0) // stop
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compactWriter) fieldHeader(id int16, typ byte) {
+	last := &c.last[len(c.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.buf = append(c.buf, byte(delta)<<4|typ)
+	} else {
+		c.buf = append(c.buf, typ)
+		c.writeVarint(int64(id))
+	}
+	*last = id
+}
+
+// writeVarint writes a zigzag varint, as i16, i32 and i64 are encoded
+func (c *compactWriter) writeVarint(n int64) {
+	c.buf = binary.AppendUvarint(c.buf, uint64(n<<1)^uint64(n>>63))
+}
+
+func (c *compactWriter) writeString(s string) {
+	c.buf = binary.AppendUvarint(c.buf, uint64(len(s)))
+	c.buf = append(c.buf, s...)
+}
+
+func (c *compactWriter) i32Field(id int16, v int32) {
+	c.fieldHeader(id, compactI32)
+	c.writeVarint(int64(v))
+}
+
+func (c *compactWriter) i64Field(id int16, v int64) {
+	c.fieldHeader(id, compactI64)
+	c.writeVarint(v)
+}
+
+func (c *compactWriter) stringField(id int16, s string) {
+	c.fieldHeader(id, compactBinary)
+	c.writeString(s)
+}
+
+// structField starts a struct field; close it with endStruct
+func (c *compactWriter) structField(id int16) {
+	c.fieldHeader(id, compactStruct)
+	c.beginStruct()
+}
+
+// listField starts a list field of n elements, which follow
+func (c *compactWriter) listField(id int16, elem byte, n int) {
+	c.fieldHeader(id, compactList)
+	if n < 15 {
+		c.buf = append(c.buf, byte(n)<<4|elem)
+	} else {
+		c.buf = append(c.buf, 0xf0|elem)
+		c.buf = binary.AppendUvarint(c.buf, uint64(n))
+	}
+}
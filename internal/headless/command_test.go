@@ -0,0 +1,30 @@
+package headless
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/validate"
+)
+
+func TestParseCommand(t *testing.T) {
+	cmd, err := ParseCommand([]byte(`{"type":"agent","command":"start","agent_id":"a1"}`))
+	if err != nil || cmd.Type != "agent" || cmd.AgentID != "a1" {
+		t.Fatalf("valid command: %+v %v", cmd, err)
+	}
+
+	_, err = ParseCommand([]byte(`{"type":"shell","command":"","callback_url":"nope"}`))
+	var errs validate.Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("invalid command: %v", err)
+	}
+	for i, field := range []string{"type", "command", "callback_url"} {
+		if errs[i].Field != field {
+			t.Errorf("error %d names %s, want %s", i, errs[i].Field, field)
+		}
+	}
+
+	if _, err := ParseCommand([]byte(`{"type":"system","command":"status","extra":1}`)); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
@@ -1,25 +1,55 @@
 package headless
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/artifacts"
+	"github.com/biodoia/skagent/internal/audit"
+	"github.com/biodoia/skagent/internal/auth"
 	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/dryrun"
+	"github.com/biodoia/skagent/internal/constitution"
 	"github.com/biodoia/skagent/internal/core"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/mcpclient"
+	"github.com/biodoia/skagent/internal/digest"
+	"github.com/biodoia/skagent/internal/evaluation"
+	"github.com/biodoia/skagent/internal/lessons"
+	"github.com/biodoia/skagent/internal/modelpolicy"
+	"github.com/biodoia/skagent/internal/project"
+	"github.com/biodoia/skagent/internal/pullrequest"
+	"github.com/biodoia/skagent/internal/snapshot"
+	"github.com/biodoia/skagent/internal/redact"
+	"github.com/biodoia/skagent/internal/runner"
+	"github.com/biodoia/skagent/internal/review"
 	"github.com/biodoia/skagent/internal/server/mcp"
 	"github.com/biodoia/skagent/internal/server/rest"
+	"github.com/biodoia/skagent/internal/server/unixsock"
+	"github.com/biodoia/skagent/internal/shutdown"
+	"github.com/biodoia/skagent/internal/storage"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/taskretry"
+	"github.com/biodoia/skagent/internal/tools"
+	"github.com/biodoia/skagent/internal/validate"
+	"github.com/biodoia/skagent/internal/webhooks"
+	"github.com/google/uuid"
 )
 
 type HeadlessMode struct {
@@ -32,16 +62,44 @@ type HeadlessMode struct {
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
 	logger       *log.Logger
+	shutdown     *shutdown.Coordinator
+	callbacks    *webhooks.CallbackDispatcher
+	audit        *audit.Log
+	
+	// Config reload state: the file to re-read, the configuration in
+	// effect, and whether the provider came from it rather than the caller
+	reloadMu     sync.Mutex
+	configPath   string
+	active       *config.Config
+	ownsProvider bool
+	// providers are the runner's non-default providers, recreated from
+	// the active configuration after a reload; nil without a runner
+	providers *runner.ProviderCache
 }
 
 type Command struct {
 	ID          string                 `json:"id"`
-	Type        string                 `json:"type"`
-	Command     string                 `json:"command"`
+	Type        string                 `json:"type" validate:"required,oneof=agent tool system"`
+	Command     string                 `json:"command" validate:"required,max=100"`
 	Params      map[string]interface{} `json:"params"`
-	AgentID     string                 `json:"agent_id,omitempty"`
-	Timeout     time.Duration          `json:"timeout,omitempty"`
-	CallbackURL string                 `json:"callback_url,omitempty"`
+	AgentID     string                 `json:"agent_id,omitempty" validate:"max=100"`
+	Timeout     time.Duration          `json:"timeout,omitempty" validate:"min=0"`
+	CallbackURL string                 `json:"callback_url,omitempty" validate:"url"`
+}
+
+// ParseCommand decodes a JSON command and checks it with the rules the
+// REST API applies to its requests
+func ParseCommand(data []byte) (Command, error) {
+	var cmd Command
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cmd); err != nil {
+		return Command{}, fmt.Errorf("invalid command: %w", err)
+	}
+	if errs := validate.Struct(&cmd); len(errs) > 0 {
+		return Command{}, errs
+	}
+	return cmd, nil
 }
 
 type CommandResult struct {
@@ -49,37 +107,352 @@ type CommandResult struct {
 	Status    string                 `json:"status"`
 	Result    map[string]interface{} `json:"result"`
 	Error     string                 `json:"error,omitempty"`
+	// Invalid lists the fields of a command that failed validation
+	Invalid   validate.Errors        `json:"invalid,omitempty"`
 	Duration  time.Duration          `json:"duration"`
 	Timestamp time.Time              `json:"timestamp"`
 }
 
 func NewHeadless(configPath string) (*HeadlessMode, error) {
 	// Load configuration
+	if configPath == "" {
+		configPath = getDefaultConfigPath()
+	}
 	config, err := loadHeadlessConfig(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 	
+	h, err := New(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	h.configPath = configPath
+	return h, nil
+}
+
+// New builds the headless stack from a loaded configuration. A nil
+// provider uses the one the configuration selects; tests pass a fake.
+func New(config *config.Config, provider ai.Provider) (*HeadlessMode, error) {
+	// Scrub configured secrets from logs, sessions and tool output
+	if err := redact.Install(config); err != nil {
+		return nil, fmt.Errorf("failed to configure redaction: %w", err)
+	}
+	
 	// Set up context
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	// Create logger
-	logger := log.New(os.Stdout, "[HEADLESS] ", log.LstdFlags|log.Lmsgprefix)
+	logger := logging.New("headless", "[HEADLESS] ", os.Stdout)
+	if config.DryRun {
+		dryrun.Enable()
+	}
+	if dryrun.Enabled() {
+		logger.Printf("Dry run: mutating tools will log their actions instead of taking them")
+	}
 	
 	// Initialize agent registry
 	agentRegistry := agents.NewRegistry(ctx)
+	store, err := storage.Open(config.Storage)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open the registry storage: %w", err)
+	}
+	if _, shared := store.(agents.SharedStore); shared {
+		// A restart resumes the runs this instance had claimed
+		if id, err := instanceID(); err != nil {
+			logger.Printf("WARN: Keeping a new instance ID: %v", err)
+		} else {
+			agentRegistry.SetInstance(id)
+		}
+	}
+	if store != nil {
+		if err := agentRegistry.UseStore(store); err != nil {
+			store.Close()
+			cancel()
+			return nil, err
+		}
+	}
+	// Other instances sharing the database change it too
+	if _, shared := store.(agents.SharedStore); shared {
+		go syncRegistry(ctx, agentRegistry, config.Storage.SyncInterval, logger)
+	}
+	if config.Storage.ArchiveAfter > 0 {
+		go compactRegistry(ctx, agentRegistry, config.Storage.ArchiveAfter, logger)
+	}
+	for _, ws := range config.Workspaces {
+		// Workspaces loaded from the storage already exist
+		if _, ok := agentRegistry.GetWorkspace(ws.Name); ok {
+			continue
+		}
+		if _, err := agentRegistry.CreateWorkspace(ws.Name, ws.Description); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to create workspace %s: %w", ws.Name, err)
+		}
+	}
 	
 	// Initialize core components
-	engine, err := core.NewEngine(ctx, config, agentRegistry)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create engine: %w", err)
+	// Without a usable provider the engine starts degraded, so that the
+	// servers still come up; a config reload can supply one later
+	ownsProvider := provider == nil
+	var engine *core.Engine
+	if ownsProvider {
+		engine = core.NewEngine(ctx, config, agentRegistry)
+	} else {
+		engine = core.NewEngineWithProvider(ctx, config, agentRegistry, provider)
 	}
 	
 	// Initialize servers
-	mcpServer := mcp.NewServer(ctx, agentRegistry)
+	mcpServer := mcp.NewServer(ctx, agentRegistry, config.MCP)
 	restServer := rest.NewServer(ctx, config.API.Port, config.API.Host, engine, agentRegistry)
+	restServer.SetTLS(config.API.TLS)
+	restServer.SetCORS(config.API.EnableCORS, config.API.CORS)
+	restServer.SetIdempotencyTTL(time.Duration(config.API.IdempotencyTTL) * time.Second)
+	restServer.SetRateLimit(config.API.RateLimit)
+	restServer.SetMaxBodySize(config.API.MaxBodySize)
+	mcpServer.SetMaxBodySize(config.API.MaxBodySize)
+	mcpServer.SetSessions(engine)
+	mcpServer.SetSpecsDir(config.SpecKitPath)
+	mcpServer.AddToolManager(engine.Tools())
+	mcpServer.SetWorkspaceTools(engine.ToolsFor)
+	if err := setSockets(config, restServer, mcpServer); err != nil {
+		cancel()
+		return nil, err
+	}
+	restServer.SetTimeouts(time.Duration(config.API.ReadTimeout)*time.Second, time.Duration(config.API.WriteTimeout)*time.Second)
+	if store, err := newArtifactStore(config); err != nil {
+		logger.Printf("WARN: Artifact store disabled: %v", err)
+	} else {
+		restServer.SetArtifactStore(store)
+	}
 	
-	return &HeadlessMode{
+	// Deliver registry events to the registered webhooks, and results to
+	// task callback URLs
+	webhookManager, callbacks, err := newDeliveries(config)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	events, unsubscribe := agentRegistry.Subscribe(1024)
+	go webhookManager.Run(ctx, events)
+	callbackEvents, unsubscribeCallbacks := agentRegistry.Subscribe(1024)
+	go callbacks.Run(ctx, callbackEvents)
+	restServer.SetWebhookManager(webhookManager)
+	restServer.SetCallbackDispatcher(callbacks)
+	stopWebhooks := func(ctx context.Context, force bool) error {
+		unsubscribe()
+		unsubscribeCallbacks()
+		if force {
+			return nil
+		}
+		if err := webhookManager.Wait(ctx); err != nil {
+			return err
+		}
+		return callbacks.Wait(ctx)
+	}
+	
+	// Keep the execution log of every task
+	if taskLog, err := newTaskLog(); err != nil {
+		logger.Printf("WARN: Task logs disabled: %v", err)
+	} else {
+		tasklog.SetDefault(taskLog)
+		logEvents, unsubscribeLog := agentRegistry.Subscribe(1024)
+		go func() {
+			defer unsubscribeLog()
+			taskLog.Run(ctx, logEvents)
+		}()
+		restServer.SetTaskLog(taskLog)
+	}
+	
+	// Learn from finished tasks which models handle which kinds of work
+	var policy *modelpolicy.Policy
+	if config.ModelPolicy.Enabled {
+		policy = modelpolicy.NewPolicy(config.ModelPolicy)
+		policyEvents, unsubscribePolicy := agentRegistry.Subscribe(1024)
+		go func() {
+			defer unsubscribePolicy()
+			policy.Run(ctx, policyEvents)
+		}()
+		restServer.SetModelPolicy(policy)
+	}
+	
+	// Keep what agents learn from finished tasks for the prompts of
+	// similar ones
+	var lessonStore *lessons.Store
+	if config.Lessons.Enabled {
+		lessonStore, err = newLessonStore(config)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load lessons: %w", err)
+		}
+		lessonEvents, unsubscribeLessons := agentRegistry.Subscribe(1024)
+		go func() {
+			defer unsubscribeLessons()
+			lessonStore.Run(ctx, lessonEvents)
+		}()
+		engine.SetLessons(lessonStore)
+		restServer.SetLessons(lessonStore)
+	}
+	
+	// Check plans and task batches against the project constitution
+	if config.Constitution.Enabled {
+		checker, err := constitution.NewChecker(config.Constitution)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("invalid constitution config: %w", err)
+		}
+		engine.SetConstitution(checker)
+		restServer.SetConstitution(checker)
+	}
+	
+	// Turn the changes of coder agents into pull requests
+	if config.PullRequests.Enabled {
+		var linker pullrequest.Linker
+		if config.IsProjectEnabled() {
+			linker = project.NewClient(config.Project.BaseURL, config.Project.APIKey)
+		}
+		workflow := pullrequest.New(config.PullRequests, agentRegistry, linker)
+		prEvents, unsubscribePRs := agentRegistry.Subscribe(1024)
+		go func() {
+			defer unsubscribePRs()
+			workflow.Run(ctx, prEvents)
+		}()
+		restServer.SetPullRequests(workflow)
+	}
+
+	// Checkpoint the workspaces of coder agents so their runs can be
+	// rolled back
+	if config.Snapshots.Enabled {
+		snapshotConfig := config.Snapshots
+		if snapshotConfig.Workspace == "" {
+			snapshotConfig.Workspace = config.PullRequests.Workspace
+		}
+		snapshots := snapshot.New(snapshotConfig, agentRegistry)
+		snapshotEvents, unsubscribeSnapshots := agentRegistry.Subscribe(1024)
+		go func() {
+			defer unsubscribeSnapshots()
+			snapshots.Run(ctx, snapshotEvents)
+		}()
+		restServer.SetSnapshots(snapshots)
+	}
+	
+	// Run the tasks assigned to agents with their models and the tools
+	var runnerProviders *runner.ProviderCache
+	if config.Runner.Enabled {
+		runnerProviders = runner.NewProviderCache(config, engine.Provider)
+		taskRunner := runner.New(config.Runner, agentRegistry, engine.ToolsFor, runnerProviders.Provider)
+		if lessonStore != nil {
+			taskRunner.SetLessons(lessonStore)
+		}
+		if policy != nil {
+			taskRunner.SetModelPolicy(policy)
+		}
+		runnerEvents, unsubscribeRunner := agentRegistry.Subscribe(1024)
+		go func() {
+			defer unsubscribeRunner()
+			taskRunner.Run(ctx, runnerEvents)
+		}()
+		if pm := engine.GetProjectManager(); pm != nil {
+			pm.SetExecutor(func(ctx context.Context, task *project.Task, agent *agents.Agent) (string, error) {
+				result := taskRunner.Execute(ctx, &agents.Task{
+					ID: task.ID, Title: task.Title, Description: task.Description, Labels: task.Labels,
+				}, agent)
+				if !result.Success {
+					return "", fmt.Errorf("%s", result.Error)
+				}
+				return result.Output, nil
+			})
+		}
+	}
+	
+	// Score the results of completed tasks and send weak ones back
+	if config.Evaluation.Enabled {
+		evaluator := evaluation.New(config.Evaluation, agentRegistry, engine.Provider)
+		evalEvents, unsubscribeEvals := agentRegistry.Subscribe(1024)
+		go func() {
+			defer unsubscribeEvals()
+			evaluator.Run(ctx, evalEvents)
+		}()
+		restServer.SetEvaluator(evaluator)
+	}
+	
+	// Send the tasks that fail back for another attempt, as their retry
+	// policies allow
+	if config.Retry.Enabled {
+		retrier := taskretry.New(config.Retry, agentRegistry)
+		retryEvents, unsubscribeRetries := agentRegistry.Subscribe(1024)
+		go func() {
+			defer unsubscribeRetries()
+			retrier.Run(ctx, retryEvents)
+		}()
+	}
+	
+	// Sum up the day's activity every morning, for the webhooks and
+	// GET /reports/daily
+	if config.Digest.Enabled {
+		d := digest.New(config.Digest, agentRegistry, engine.Provider, digestPath())
+		if config.ModelPolicy.Enabled {
+			d.SetTiers(modelpolicy.NewPolicy(config.ModelPolicy).Tiers())
+		}
+		d.SetNotify(webhookManager.Publish)
+		go d.Run(ctx)
+		restServer.SetDigest(d)
+	}
+	
+	// Review the pull requests GitHub reports with the reviewer agents
+	if config.Review.Enabled {
+		reviewer := tools.NewDiffReviewTool(engine.Provider, config.Review.MaxDiffSize)
+		pipeline := review.New(config.Review, agentRegistry, tools.NewGitHubTool(""), reviewer)
+		reviewEvents, unsubscribeReviews := agentRegistry.Subscribe(1024)
+		go func() {
+			defer unsubscribeReviews()
+			pipeline.Run(ctx, reviewEvents)
+		}()
+		restServer.SetReview(pipeline)
+	}
+	
+	// Record who changed what
+	var auditLog *audit.Log
+	if config.Audit.Enabled {
+		if auditLog, err = openAuditLog(config); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		restServer.SetAuditLog(auditLog)
+		mcpServer.SetAuditLog(auditLog)
+	}
+	
+	// Enable role-based access control
+	if config.API.EnableAuth {
+		authz, err := auth.New(config.Auth)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to configure auth: %w", err)
+		}
+		restServer.SetAuthorizer(authz)
+	}
+	if config.MCP.EnableAuth {
+		// The MCP server also accepts mcp.token
+		authz, err := auth.New(config.MCPAuth())
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to configure MCP auth: %w", err)
+		}
+		mcpServer.SetAuthorizer(authz)
+	}
+	
+	active, err := cloneConfig(config)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	
+	// Tools of external MCP servers join the engine's, and so are
+	// re-exported by the MCP server too
+	mcpClients := mcpclient.ConnectAll(ctx, config.MCPServers, engine.Tools())
+	restServer.SetMCPClients(mcpClients)
+	
+	h := &HeadlessMode{
 		engine:        engine,
 		agentRegistry: agentRegistry,
 		mcpServer:     mcpServer,
@@ -88,7 +461,171 @@ func NewHeadless(configPath string) (*HeadlessMode, error) {
 		ctx:           ctx,
 		cancel:        cancel,
 		logger:        logger,
-	}, nil
+		shutdown:      newShutdownCoordinator(config, agentRegistry, stopWebhooks, engine, mcpClients, mcpServer, restServer, auditLog),
+		callbacks:     callbacks,
+		audit:         auditLog,
+		active:        active,
+		ownsProvider:  ownsProvider,
+		providers:     runnerProviders,
+	}
+	restServer.SetShutdownCoordinator(h.shutdown)
+	restServer.SetConfigReloader(h)
+	
+	return h, nil
+}
+
+// Engine returns the core engine
+func (h *HeadlessMode) Engine() *core.Engine {
+	return h.engine
+}
+
+// Registry returns the agent registry
+func (h *HeadlessMode) Registry() *agents.Registry {
+	return h.agentRegistry
+}
+
+// RESTHandler returns the REST API routes, for serving them without
+// binding the configured port
+func (h *HeadlessMode) RESTHandler() http.Handler {
+	return h.restServer.Handler()
+}
+
+// instanceID returns the ID of this instance among those sharing the
+// registry storage, kept in the data directory across restarts
+func instanceID() (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dataDir, "instance-id")
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	id := uuid.New().String()
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(id+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// newTaskLog keeps task logs in the data directory
+func newTaskLog() (*tasklog.Store, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return tasklog.New(filepath.Join(dataDir, "tasklogs"), 0)
+}
+
+// newArtifactStore keeps artifacts in the data directory, next to the
+// other runtime state
+func newArtifactStore(cfg *config.Config) (*artifacts.LocalStore, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return artifacts.NewLocalStore(filepath.Join(dataDir, "artifacts"), cfg.API.MaxArtifactSize)
+}
+
+// digestPath keeps the latest daily digest in the data directory; without
+// one the digest is only kept in memory
+func digestPath() string {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dataDir, "digest.json")
+}
+
+// newLessonStore loads the lessons from the data directory
+func newLessonStore(cfg *config.Config) (*lessons.Store, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return lessons.NewStore(filepath.Join(dataDir, "lessons.json"), cfg.Lessons)
+}
+
+// openAuditLog opens the configured audit log, by default in the data
+// directory
+func openAuditLog(cfg *config.Config) (*audit.Log, error) {
+	path := cfg.Audit.Path
+	if path == "" {
+		dataDir, err := config.DataDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dataDir, "audit.jsonl")
+	}
+	return audit.Open(path)
+}
+
+// recordSignal adds a shutdown or reload requested by a signal to the
+// audit log
+func (h *HeadlessMode) recordSignal(action string, sig os.Signal, details string) {
+	if h.audit == nil {
+		return
+	}
+	err := h.audit.Record(audit.Entry{
+		Source:   "signal",
+		Actor:    "system",
+		Action:   action,
+		Resource: "system",
+		Details:  strings.TrimSpace(sig.String() + " " + details),
+	})
+	if err != nil {
+		h.logger.Printf("ERROR: Failed to record %s in the audit log: %v", action, err)
+	}
+}
+
+// newDeliveries loads the webhook subscriptions from the data directory
+// and keeps the callback dead-letter log next to them
+func newDeliveries(cfg *config.Config) (*webhooks.Manager, *webhooks.CallbackDispatcher, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	manager, err := webhooks.NewManager(filepath.Join(dataDir, "webhooks.json"), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	callbacks := webhooks.NewCallbackDispatcher(cfg.API.CallbackSecret, filepath.Join(dataDir, "callbacks-dead-letter.jsonl"), nil)
+	return manager, callbacks, nil
+}
+
+// newShutdownCoordinator drains in-flight tasks, then flushes webhook and
+// callback deliveries and stops the engine, the external MCP servers, the MCP
+// server and the REST server in that order
+func newShutdownCoordinator(cfg *config.Config, registry *agents.Registry, hooks shutdown.StopFunc, engine *core.Engine, mcpClients *mcpclient.Manager, mcpServer *mcp.Server, restServer *rest.APIServer, auditLog *audit.Log) *shutdown.Coordinator {
+	c := shutdown.New()
+	if cfg.Headless.Timeout > 0 {
+		c.DrainTimeout = time.Duration(cfg.Headless.Timeout) * time.Second
+	}
+	
+	c.SetDrain(registry.Drain)
+	c.Add("webhooks", hooks)
+	c.Add("engine", func(ctx context.Context, force bool) error {
+		return engine.Stop()
+	})
+	c.Add("mcp clients", mcpClients.Close)
+	c.Add("mcp server", mcpServer.Shutdown)
+	c.Add("rest server", restServer.Shutdown)
+	if auditLog != nil {
+		c.Add("audit log", func(ctx context.Context, force bool) error {
+			return auditLog.Close()
+		})
+	}
+	c.Add("registry storage", func(ctx context.Context, force bool) error {
+		return registry.CloseStore()
+	})
+	return c
 }
 
 func (h *HeadlessMode) Start() error {
@@ -96,7 +633,7 @@ func (h *HeadlessMode) Start() error {
 	
 	// Set up signal handling
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, shutdownSignals...)
 	
 	// Create PID file if configured
 	if h.config.Headless.PidFile != "" {
@@ -121,7 +658,7 @@ func (h *HeadlessMode) Start() error {
 	go func() {
 		defer h.wg.Done()
 		if err := h.engine.Start(); err != nil {
-			h.logger.Printf("Engine error: %v", err)
+			h.logger.Printf("ERROR: Engine error: %v", err)
 		}
 	}()
 	
@@ -130,7 +667,7 @@ func (h *HeadlessMode) Start() error {
 	go func() {
 		defer h.wg.Done()
 		if err := h.mcpServer.Start(); err != nil {
-			h.logger.Printf("MCP server error: %v", err)
+			h.logger.Printf("ERROR: MCP server error: %v", err)
 		}
 	}()
 	
@@ -139,7 +676,7 @@ func (h *HeadlessMode) Start() error {
 	go func() {
 		defer h.wg.Done()
 		if err := h.restServer.Start(); err != nil {
-			h.logger.Printf("REST server error: %v", err)
+			h.logger.Printf("ERROR: REST server error: %v", err)
 		}
 	}()
 	
@@ -150,34 +687,52 @@ func (h *HeadlessMode) Start() error {
 	
 	h.logger.Println("Headless mode started successfully")
 	
-	// Wait for shutdown signal
-	<-sigChan
-	
-	h.logger.Println("Received shutdown signal, stopping services...")
-	h.Stop()
-	
-	return nil
+	// Wait for a shutdown signal or a shutdown requested through the API.
+	// A second signal forces the shutdown.
+	for {
+		select {
+		case sig := <-sigChan:
+			if isReload(sig) {
+				if _, err := h.ReloadConfig(); err != nil {
+					h.logger.Printf("ERROR: Config reload failed: %v", err)
+					h.recordSignal("reload", sig, "failed: "+err.Error())
+				} else {
+					h.recordSignal("reload", sig, "")
+				}
+				continue
+			}
+			if h.shutdown.Trigger(shutdown.Options{Reason: "signal " + sig.String()}) {
+				h.logger.Printf("Received %s, stopping services (repeat to force)...", sig)
+				h.recordSignal("shutdown", sig, "")
+			} else {
+				h.logger.Printf("Received %s again, forcing shutdown", sig)
+				h.recordSignal("shutdown", sig, "forced")
+				h.shutdown.Trigger(shutdown.Options{Force: true, Reason: "signal " + sig.String()})
+			}
+		case <-h.shutdown.Done():
+			return h.finish()
+		}
+	}
+}
+
+// RequestShutdown makes Start stop the services and return, as a signal
+// does
+func (h *HeadlessMode) RequestShutdown(reason string) {
+	h.shutdown.Trigger(shutdown.Options{Reason: reason})
 }
 
 func (h *HeadlessMode) Stop() error {
 	h.logger.Println("Stopping headless mode...")
 	
+	h.shutdown.Shutdown(shutdown.Options{Reason: "stop"})
+	return h.finish()
+}
+
+// finish releases the remaining resources once the shutdown coordinator
+// has stopped every service
+func (h *HeadlessMode) finish() error {
 	h.cancel()
 	
-	// Stop servers
-	if h.restServer != nil {
-		h.restServer.Stop()
-	}
-	
-	if h.mcpServer != nil {
-		h.mcpServer.Stop()
-	}
-	
-	// Stop engine
-	if h.engine != nil {
-		h.engine.Stop()
-	}
-	
 	// Wait for all goroutines to finish
 	done := make(chan struct{})
 	go func() {
@@ -189,10 +744,10 @@ func (h *HeadlessMode) Stop() error {
 	case <-done:
 		h.logger.Println("All services stopped successfully")
 	case <-time.After(30 * time.Second):
-		h.logger.Println("Timeout waiting for services to stop")
+		h.logger.Println("WARN: Timeout waiting for services to stop")
 	}
 	
-	return nil
+	return h.shutdown.Err()
 }
 
 func (h *HeadlessMode) ExecuteCommand(cmd Command) CommandResult {
@@ -209,6 +764,14 @@ func (h *HeadlessMode) ExecuteCommand(cmd Command) CommandResult {
 		timeout = time.Duration(h.config.Headless.Timeout) * time.Second
 	}
 	
+	if errs := validate.Struct(&cmd); len(errs) > 0 {
+		result.Status = "error"
+		result.Error = errs.Error()
+		result.Invalid = errs
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	
 	ctx, cancel := context.WithTimeout(h.ctx, timeout)
 	defer cancel()
 	
@@ -236,7 +799,7 @@ func (h *HeadlessMode) executeAgentCommand(ctx context.Context, cmd Command) Com
 	
 	switch cmd.Command {
 	case "list":
-		agents := h.agentRegistry.ListAgents()
+		agents := h.agentRegistry.ListAgentViews()
 		return CommandResult{
 			ID:        cmd.ID,
 			Status:    "success",
@@ -381,13 +944,39 @@ func (h *HeadlessMode) startDefaultAgents() {
 	// For now, it's a placeholder
 }
 
+// setSockets configures the Unix domain sockets of the servers
+func setSockets(cfg *config.Config, restServer *rest.APIServer, mcpServer *mcp.Server) error {
+	if cfg.API.Socket != "" {
+		mode, err := unixsock.ParseMode(cfg.API.SocketMode)
+		if err != nil {
+			return fmt.Errorf("api.socket_mode: %w", err)
+		}
+		restServer.SetSocket(cfg.API.Socket, mode)
+	}
+	if cfg.MCP.Socket != "" {
+		mode, err := unixsock.ParseMode(cfg.MCP.SocketMode)
+		if err != nil {
+			return fmt.Errorf("mcp.socket_mode: %w", err)
+		}
+		mcpServer.SetSocket(cfg.MCP.Socket, mode)
+	}
+	return nil
+}
+
 func (h *HeadlessMode) createPidFile() error {
 	dir := filepath.Dir(h.config.Headless.PidFile)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 	
+	// A PID file left by a process that is gone is replaced
 	pid := os.Getpid()
+	if data, err := os.ReadFile(h.config.Headless.PidFile); err == nil {
+		var old int
+		if _, err := fmt.Sscanf(string(data), "%d", &old); err == nil && old != pid && processAlive(old) {
+			return fmt.Errorf("skagent is already running with PID %d (%s)", old, h.config.Headless.PidFile)
+		}
+	}
 	return os.WriteFile(h.config.Headless.PidFile, []byte(fmt.Sprintf("%d\n", pid)), 0644)
 }
 
@@ -416,8 +1005,8 @@ func loadHeadlessConfig(configPath string) (*config.Config, error) {
 }
 
 func getDefaultConfigPath() string {
-	if home, err := os.UserHomeDir(); err == nil {
-		return filepath.Join(home, ".config", "skagent", "headless.json")
+	if dir, err := config.ConfigDir(); err == nil {
+		return filepath.Join(dir, "headless.json")
 	}
 	return "headless.json"
 }
@@ -461,20 +1050,19 @@ func RunHeadless(configPath string, daemon bool) error {
 }
 
 func runInteractiveHeadless(mode *HeadlessMode) error {
-	fmt.Println("Headless mode interactive shell. Type 'help' for commands.")
+	fmt.Println("Headless mode interactive shell. Type 'help' for commands, or a JSON command.")
 	
+	lines := bufio.NewScanner(os.Stdin)
 	for {
 		fmt.Print("skagent> ")
-		var input string
-		if _, err := fmt.Scanln(&input); err != nil {
-			if err == io.EOF {
-				break
+		if !lines.Scan() {
+			if err := lines.Err(); err != nil {
+				fmt.Printf("Error reading input: %v\n", err)
 			}
-			fmt.Printf("Error reading input: %v\n", err)
-			continue
+			break
 		}
 		
-		input = strings.TrimSpace(input)
+		input := strings.TrimSpace(lines.Text())
 		if input == "" {
 			continue
 		}
@@ -483,17 +1071,69 @@ func runInteractiveHeadless(mode *HeadlessMode) error {
 			break
 		}
 		
-		// Process command
+		// Process command: a JSON object is a full command, anything
+		// else a system command
 		cmd := Command{
 			ID:          fmt.Sprintf("cmd-%d", time.Now().Unix()),
 			Type:        "system",
 			Command:     input,
 			Timeout:     10 * time.Second,
 		}
+		if strings.HasPrefix(input, "{") {
+			parsed, err := ParseCommand([]byte(input))
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			if parsed.ID == "" {
+				parsed.ID = cmd.ID
+			}
+			cmd = parsed
+		}
 		
 		result := mode.ExecuteCommand(cmd)
 		fmt.Printf("Result: %+v\n", result)
 	}
 	
 	return mode.Stop()
-}
\ No newline at end of file
+}
+
+// syncRegistry reloads the registry from its shared store every interval
+// seconds, 5 when unset, until ctx is done
+func syncRegistry(ctx context.Context, registry *agents.Registry, interval int, logger *log.Logger) {
+	if interval <= 0 {
+		interval = 5
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := registry.Sync(); err != nil {
+				logger.Printf("ERROR: Syncing the registry with the store: %v", err)
+			}
+		}
+	}
+}
+
+// compactInterval is how often compactRegistry looks for tasks to archive
+const compactInterval = time.Hour
+
+// compactRegistry archives the tasks that finished more than days ago, at
+// start and then every compactInterval, until ctx is done
+func compactRegistry(ctx context.Context, registry *agents.Registry, days int, logger *log.Logger) {
+	ticker := time.NewTicker(compactInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := registry.Compact(time.Now().AddDate(0, 0, -days)); err != nil {
+			logger.Printf("ERROR: Archiving finished tasks: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package headless_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/project"
+	"github.com/biodoia/skagent/internal/testutil"
+)
+
+func TestRESTConversation(t *testing.T) {
+	provider := ai.NewMockProvider("Here is the plan.")
+	stack := testutil.StartHeadless(t, testutil.StackOptions{Provider: provider})
+
+	resp := stack.Do(http.MethodPost, "/api/v1/sessions", map[string]string{"title": "plan"})
+	if resp.Status != http.StatusCreated {
+		t.Fatalf("create session: status %d: %v", resp.Status, resp.Body)
+	}
+	id := resp.Data()["session"].(map[string]interface{})["id"].(string)
+
+	resp = stack.Do(http.MethodPost, "/api/v1/sessions/"+id+"/messages", map[string]string{"content": "plan a todo app"})
+	if resp.Status != http.StatusOK || resp.Data()["response"] != "Here is the plan." {
+		t.Fatalf("message: status %d: %v", resp.Status, resp.Body)
+	}
+	calls := provider.Calls()
+	if len(calls) != 1 || calls[0].Messages[len(calls[0].Messages)-1].Content != "plan a todo app" {
+		t.Fatalf("provider saw %+v", calls)
+	}
+
+	resp = stack.Do(http.MethodPost, "/api/v1/agents/bulk", map[string]interface{}{
+		"operations": []agents.AgentOp{{Op: "create", Name: "coder", Type: agents.AgentTypeCoder}},
+	})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("bulk create: status %d: %v", resp.Status, resp.Body)
+	}
+	if n := len(stack.Registry.ListAgents()); n != 1 {
+		t.Fatalf("registry has %d agents, want 1", n)
+	}
+}
+
+func TestProjectManagerAssignment(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the agent runtime to run the task")
+	}
+
+	pm := testutil.NewFakePM(t)
+	pm.AddTask(project.Task{ID: "PM-1", Title: "write code for the login form"})
+	pm.AddTask(project.Task{ID: "PM-2", Title: "update the changelog", Status: "done"})
+
+	cfg := config.DefaultConfig()
+	cfg.Project.AutoAssign = true
+	cfg.Runner.Enabled = true
+	coder := &agents.Agent{Name: "coder", Type: agents.AgentTypeCoder, Capabilities: []string{"code"}}
+	provider := ai.NewMockProvider("Wrote the login form.")
+	testutil.StartHeadless(t, testutil.StackOptions{Config: cfg, PM: pm, Provider: provider, Agents: []*agents.Agent{coder}})
+
+	testutil.Eventually(t, 10*time.Second, func() bool {
+		task, _ := pm.Task("PM-1")
+		return task.Status == "done"
+	}, "PM-1 was never completed")
+
+	assignments := pm.Assignments()
+	if len(assignments) != 1 || assignments[0].TaskID != "PM-1" || assignments[0].AgentID != coder.ID {
+		t.Fatalf("assignments: %+v", assignments)
+	}
+	if calls := provider.Calls(); len(calls) != 1 || !strings.Contains(calls[0].Messages[0].Content, "write code for the login form") {
+		t.Fatalf("provider saw %+v", calls)
+	}
+	if task, _ := pm.Task("PM-2"); task.Assignee != "" {
+		t.Fatalf("finished task PM-2 was assigned to %s", task.Assignee)
+	}
+}
+
+func TestProjectManagerBackpressure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for project manager polls")
+	}
+
+	pm := testutil.NewFakePM(t)
+	cfg := config.DefaultConfig()
+	cfg.Project.PollInterval = 1
+	cfg.Project.MaxPendingTasks = 1
+	stack := testutil.StartHeadless(t, testutil.StackOptions{Config: cfg, PM: pm})
+	testutil.Eventually(t, 5*time.Second, func() bool { return pm.Polls() == 1 }, "the project manager was never polled")
+
+	// Without agents the tasks stay pending, over the threshold
+	stack.Registry.CreateTask(&agents.Task{Title: "one"})
+	stack.Registry.CreateTask(&agents.Task{Title: "two"})
+	resp := stack.Do(http.MethodGet, "/api/v1/status", nil)
+	bp := resp.Data()["backpressure"].(map[string]interface{})
+	if bp["active"] != true || bp["queue_depth"] != float64(2) || bp["threshold"] != float64(1) {
+		t.Fatalf("backpressure: %v", bp)
+	}
+
+	time.Sleep(2500 * time.Millisecond)
+	if n := pm.Polls(); n != 1 {
+		t.Fatalf("polled %d times under backpressure", n)
+	}
+	bp = stack.Do(http.MethodGet, "/api/v1/status", nil).Data()["backpressure"].(map[string]interface{})
+	if bp["skipped_polls"].(float64) < 1 {
+		t.Fatalf("no skipped polls recorded: %v", bp)
+	}
+
+	for _, task := range stack.Registry.GetPendingTasks() {
+		stack.Registry.CancelTask(task.ID, agents.Cause{})
+	}
+	testutil.Eventually(t, 5*time.Second, func() bool { return pm.Polls() > 1 }, "polling did not resume")
+}
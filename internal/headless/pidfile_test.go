@@ -0,0 +1,39 @@
+package headless
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestCreatePidFile(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Headless.PidFile = filepath.Join(t.TempDir(), "run", "skagent.pid")
+	h := &HeadlessMode{config: cfg}
+
+	// A process that has exited leaves a stale PID file
+	done := exec.Command(os.Args[0], "-test.run=^$")
+	if err := done.Run(); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(cfg.Headless.PidFile), 0o755)
+	os.WriteFile(cfg.Headless.PidFile, []byte(strconv.Itoa(done.Process.Pid)+"\n"), 0o644)
+	if err := h.createPidFile(); err != nil {
+		t.Fatalf("stale PID file: %v", err)
+	}
+	data, _ := os.ReadFile(cfg.Headless.PidFile)
+	if strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("PID file holds %q", data)
+	}
+
+	// A running process, here the parent, keeps it
+	os.WriteFile(cfg.Headless.PidFile, []byte(strconv.Itoa(os.Getppid())+"\n"), 0o644)
+	if err := h.createPidFile(); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("live PID file: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package headless
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/redact"
+	"github.com/biodoia/skagent/internal/server/rest"
+)
+
+// hotReloadable lists the config paths ReloadConfig applies to the
+// running daemon; entries ending in "." match every path below them
+var hotReloadable = []string{
+	"default_provider",
+	"providers.",
+	"api.rate_limit",
+	"api.write_timeout",
+	"api.callback_secret",
+	"redaction.",
+}
+
+func isHotReloadable(path string) bool {
+	for _, p := range hotReloadable {
+		if path == p || (strings.HasSuffix(p, ".") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReloadConfig re-reads the configuration file and applies the provider,
+// rate limit, request timeout and redaction settings without a restart.
+// Other changes are reported as needing one. An invalid file changes
+// nothing.
+func (h *HeadlessMode) ReloadConfig() (*rest.ReloadResult, error) {
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+
+	if h.configPath == "" {
+		return nil, fmt.Errorf("the daemon was not started from a configuration file")
+	}
+	cfg, err := loadHeadlessConfig(h.configPath)
+	if err != nil {
+		return nil, &config.ValidationError{Problems: []string{err.Error()}}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	changed, err := config.Diff(h.active, cfg)
+	if err != nil {
+		return nil, err
+	}
+	result := &rest.ReloadResult{
+		Changed:         changed,
+		Applied:         []string{},
+		RestartRequired: []string{},
+	}
+
+	// Build everything that can fail before applying anything. A degraded
+	// engine gets a new try at its provider on every reload, and stays
+	// degraded without failing the reload if it still cannot have one.
+	var provider ai.Provider
+	degraded := h.engine.Degraded()
+	providerChanged := cfg.DefaultProvider != h.active.DefaultProvider ||
+		!reflect.DeepEqual(cfg.GetActiveProvider(), h.active.GetActiveProvider())
+	if (providerChanged || degraded) && h.ownsProvider {
+		if provider, err = ai.CreateProvider(cfg); err != nil {
+			if !degraded {
+				return nil, &config.ValidationError{Problems: []string{err.Error()}}
+			}
+			result.ProviderError = err.Error()
+			h.logger.Printf("WARN: Still no AI provider after the reload: %v", err)
+		}
+	}
+	if err := redact.Install(cfg); err != nil {
+		return nil, &config.ValidationError{Problems: []string{err.Error()}}
+	}
+
+	if provider != nil {
+		h.engine.SetProvider(provider)
+		result.ProviderReplaced = true
+	}
+	h.restServer.SetRateLimit(cfg.API.RateLimit)
+	h.restServer.SetTimeouts(time.Duration(h.active.API.ReadTimeout)*time.Second,
+		time.Duration(cfg.API.WriteTimeout)*time.Second)
+	h.callbacks.SetSecret(cfg.API.CallbackSecret)
+
+	for _, path := range changed {
+		providerPath := path == "default_provider" || strings.HasPrefix(path, "providers.")
+		if isHotReloadable(path) && (!providerPath || h.ownsProvider) {
+			result.Applied = append(result.Applied, path)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, path)
+		}
+	}
+
+	// The active configuration takes the applied values only, so that
+	// changes needing a restart keep being reported
+	if h.ownsProvider {
+		h.active.DefaultProvider = cfg.DefaultProvider
+		h.active.Providers = cfg.Providers
+	}
+	h.active.API.RateLimit = cfg.API.RateLimit
+	h.active.API.WriteTimeout = cfg.API.WriteTimeout
+	h.active.API.CallbackSecret = cfg.API.CallbackSecret
+	h.active.Redaction = cfg.Redaction
+	if h.providers != nil {
+		h.providers.SetConfig(h.active)
+	}
+
+	if p := h.engine.Provider(); p != nil {
+		result.Provider = p.Name()
+	}
+	h.logger.Printf("Configuration reloaded: %d applied, %d need a restart", len(result.Applied), len(result.RestartRequired))
+	return result, nil
+}
+
+// cloneConfig deep-copies a configuration
+func cloneConfig(cfg *config.Config) (*config.Config, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var clone config.Config
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
@@ -0,0 +1,152 @@
+package headless
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestReloadConfig(t *testing.T) {
+	t.Setenv("SKAGENT_DATA_DIR", t.TempDir())
+	path := filepath.Join(t.TempDir(), "headless.json")
+	write := func(mutate func(*config.Config)) {
+		t.Helper()
+		cfg := config.DefaultConfig()
+		p := cfg.Providers[config.ProviderOpenRouter]
+		p.APIKey = "key-one"
+		cfg.Providers[config.ProviderOpenRouter] = p
+		mutate(cfg)
+		data, _ := json.Marshal(cfg)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(func(*config.Config) {})
+
+	h, err := NewHeadless(path)
+	if err != nil {
+		t.Fatalf("NewHeadless: %v", err)
+	}
+	t.Cleanup(func() { h.Stop() })
+	handler := h.RESTHandler()
+	do := func(method, path string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+	before := h.Engine().Provider()
+
+	write(func(cfg *config.Config) {
+		p := cfg.Providers[config.ProviderOpenRouter]
+		p.APIKey = "key-two"
+		cfg.Providers[config.ProviderOpenRouter] = p
+		cfg.API.RateLimit = 2
+		cfg.API.Port = 9999
+	})
+	code, body := do(http.MethodPost, "/api/v1/system/config/reload")
+	if code != http.StatusOK {
+		t.Fatalf("reload = %d %v", code, body)
+	}
+	reload := body["data"].(map[string]interface{})["reload"].(map[string]interface{})
+	if !contains(reload["applied"], "providers.openrouter.api_key") || !contains(reload["applied"], "api.rate_limit") {
+		t.Errorf("applied = %v", reload["applied"])
+	}
+	if !contains(reload["restart_required"], "api.port") {
+		t.Errorf("restart_required = %v", reload["restart_required"])
+	}
+	if reload["provider_replaced"] != true || h.Engine().Provider() == before {
+		t.Errorf("provider was not replaced: %v", reload)
+	}
+
+	// The client's bucket is capped at the new limit of two requests
+	for i := 0; i < 2; i++ {
+		if code, _ := do(http.MethodGet, "/api/v1/agents"); code != http.StatusOK {
+			t.Fatalf("request %d after reload = %d", i+1, code)
+		}
+	}
+	if code, _ := do(http.MethodGet, "/api/v1/agents"); code != http.StatusTooManyRequests {
+		t.Fatalf("request over the new rate limit = %d, want 429", code)
+	}
+
+	// An invalid file is rejected and leaves the running config alone
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h.restServer.SetRateLimit(0)
+	if code, body := do(http.MethodPost, "/api/v1/system/config/reload"); code != http.StatusBadRequest {
+		t.Fatalf("reload of an invalid file = %d %v", code, body)
+	}
+	if h.active.API.RateLimit != 2 {
+		t.Errorf("active rate limit = %d after a failed reload, want 2", h.active.API.RateLimit)
+	}
+}
+
+func contains(list interface{}, want string) bool {
+	items, _ := list.([]interface{})
+	for _, item := range items {
+		if item == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReloadConfig_Degraded(t *testing.T) {
+	t.Setenv("SKAGENT_DATA_DIR", t.TempDir())
+	path := filepath.Join(t.TempDir(), "headless.json")
+	write := func(key string) {
+		t.Helper()
+		cfg := config.DefaultConfig()
+		p := cfg.Providers[config.ProviderOpenRouter]
+		p.APIKey = key
+		cfg.Providers[config.ProviderOpenRouter] = p
+		data, _ := json.Marshal(cfg)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("")
+
+	// Without an API key the daemon starts, degraded
+	h, err := NewHeadless(path)
+	if err != nil {
+		t.Fatalf("NewHeadless without a provider: %v", err)
+	}
+	t.Cleanup(func() { h.Stop() })
+	if !h.Engine().Degraded() {
+		t.Fatal("the engine should be degraded")
+	}
+	handler := h.RESTHandler()
+	do := func(method, path string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+	if code, _ := do(http.MethodGet, "/api/v1/agents"); code != http.StatusOK {
+		t.Fatalf("management API while degraded = %d", code)
+	}
+
+	// A file still without a key is rejected and leaves it degraded
+	if code, body := do(http.MethodPost, "/api/v1/system/config/reload"); code != http.StatusBadRequest || !h.Engine().Degraded() {
+		t.Fatalf("reload without a key = %d %v", code, body)
+	}
+
+	write("key-one")
+	code, body := do(http.MethodPost, "/api/v1/system/config/reload")
+	reload, _ := body["data"].(map[string]interface{})["reload"].(map[string]interface{})
+	if code != http.StatusOK || reload["provider_replaced"] != true || reload["provider"] != "OpenRouter" {
+		t.Fatalf("reload with a key = %d %v", code, body)
+	}
+	if h.Engine().Degraded() || h.Engine().ProviderError() != nil {
+		t.Error("the engine should have recovered")
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package headless
+
+import (
+	"os"
+	"syscall"
+)
+
+// shutdownSignals are the signals Start listens to: SIGINT and SIGTERM
+// stop the daemon, SIGHUP reloads the configuration
+var shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// isReload reports whether sig asks for a configuration reload
+func isReload(sig os.Signal) bool {
+	return sig == syscall.SIGHUP
+}
+
+// processAlive reports whether a process with the given PID is running
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Signal 0 checks for the process without signalling it; EPERM means
+	// it exists under another user
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
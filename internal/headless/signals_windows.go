@@ -0,0 +1,40 @@
+//go:build windows
+
+package headless
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// shutdownSignals are the signals Start listens to. Windows has no SIGHUP:
+// Ctrl+C arrives as os.Interrupt and closing the console, logging off or
+// shutting down as SIGTERM. Reload the configuration through the API.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// isReload reports whether sig asks for a configuration reload; no signal
+// does on Windows
+func isReload(sig os.Signal) bool {
+	return false
+}
+
+// stillActive is the exit code GetExitCodeProcess reports for a running
+// process
+const stillActive = 259
+
+// processAlive reports whether a process with the given PID is running
+func processAlive(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// Access denied still means the process exists
+		return err == windows.ERROR_ACCESS_DENIED
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
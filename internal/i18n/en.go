@@ -0,0 +1,191 @@
+package i18n
+
+// en is the English catalog, the reference every other catalog follows
+var en = map[string]string{
+	// Command-line help
+	"cli.usage": `Usage: skagent [--dry-run] [command] [flags]
+
+Commands:
+  (none)        Start the interactive TUI
+  setup         Run the setup wizard
+  init          Create the .skagent directory and specs/ layout in a project
+  headless      Run the headless daemon (REST + MCP servers)
+  config        Inspect and validate configuration
+  backup        Create, verify and restore backups of config and state
+  docs          Update and list the SpecKit documentation
+  bench         Load-test the agent registry with synthetic agents and tasks
+  remote        Drive a running headless instance over its API
+  export        Export tasks, agent stats and model usage as CSV or Parquet
+  mcp           Serve MCP over stdin/stdout for hosts that launch skagent
+  service       Install, remove or run the headless daemon as a Windows service
+  version       Print version information
+  help          Show this help
+
+Global flags:
+  --dry-run     Log what git, gh, spec-kit, project manager updates and
+                project file writes would do instead of doing it
+
+The language follows $SKAGENT_LOCALE, the "locale" setting or $LANG
+(available: en, it).
+`,
+	"cli.unknown_command": "unknown command: %s",
+
+	// Setup wizard
+	"wizard.title":               "🚀 SkAgent Setup Wizard",
+	"wizard.welcome.title":       "Welcome to SkAgent!",
+	"wizard.welcome.intro":       "SkAgent is an AI-powered spec-driven development assistant.\nLet's configure your AI provider.\n\n",
+	"wizard.welcome.options":     "Available options:\n",
+	"wizard.welcome.openrouter":  "• OpenRouter - 35+ FREE models (no cost!)\n",
+	"wizard.welcome.claude":      "• Claude Max - Use your subscription\n",
+	"wizard.welcome.cli":         "• Gemini/Codex CLI - Free tiers available\n",
+	"wizard.welcome.others":      "• Kimi, GLM, DeepSeek, Minimax - Free/cheap options\n",
+	"wizard.welcome.help":        "Press Enter to continue, Ctrl+C to quit",
+	"wizard.provider.list":       "Select AI Provider",
+	"wizard.provider.step":       "Step 1: Select AI Provider",
+	"wizard.provider.help":       "↑/↓ to navigate, Enter to select, Esc to go back",
+	"wizard.provider.openrouter": "🌐 OpenRouter (Free Models)",
+	"wizard.desc.openrouter":     "35+ free models including Qwen Coder, DeepSeek R1, Llama 3.3 70B",
+	"wizard.desc.claude":         "Use your Claude Max subscription - no API costs",
+	"wizard.desc.gemini":         "Use Google's Gemini via CLI - free tier available",
+	"wizard.desc.codex":          "Use OpenAI Codex via CLI",
+	"wizard.desc.kimi":           "Moonshot AI's Kimi model - free tier available",
+	"wizard.desc.glm":            "Zhipu's GLM-4 - Chinese-optimized with free tier",
+	"wizard.desc.deepseek":       "DeepSeek models - very affordable",
+	"wizard.desc.minimax":        "Minimax models with coding focus",
+	"wizard.configure.step":      "Step 2: Configure Provider",
+	"wizard.configure.help":      "Enter to continue, Esc to go back",
+	"wizard.key.placeholder":     "Enter your API key...",
+	"wizard.key.free":            "%s API Key (get free at %s):",
+	"wizard.key.site":            "%s API Key (get at %s):",
+	"wizard.key.plain":           "%s API Key:",
+	"wizard.claude.login":        "Starting Claude Max OAuth login...",
+	"wizard.gemini.login":        "Run 'gemini auth login' first, then press Enter",
+	"wizard.model.list":          "Select Model",
+	"wizard.model.step":          "Step 3: Select Model",
+	"wizard.model.recommended":   "Recommended models are marked with ⭐",
+	"wizard.model.help":          "↑/↓ to navigate, Enter to select, / to filter",
+	"wizard.model.description":   "%s | %dk context | %s",
+	"wizard.test.step":           "Step 4: Testing Connection",
+	"wizard.test.testing":        "Testing connection...",
+	"wizard.test.saved":          "✓ Configuration saved!",
+	"wizard.test.provider":       "Provider: %s",
+	"wizard.test.model":          "Model: %s",
+	"wizard.test.help":           "Press Enter to finish",
+	"wizard.error":               "Error: %v",
+	"wizard.complete.title":      "🎉 Setup Complete!",
+	"wizard.complete.body":       "Your configuration has been saved.\n\nYou can now run skagent to start using the assistant.\nUse /help in the app to see available commands.\n\n",
+	"wizard.complete.help":       "Press Enter or Ctrl+C to exit",
+
+	// TUI
+	"tui.placeholder":         "Describe your project idea... (type /help for commands)",
+	"tui.initializing":        "Initializing...",
+	"tui.welcome":             "Welcome! Describe your project idea or type /help for commands.\n",
+	"tui.role.user":           "You: ",
+	"tui.role.assistant":      "Agent: ",
+	"tui.role.system":         "System: ",
+	"tui.role.error":          "Error: ",
+	"tui.error":               "Error: %v",
+	"tui.tool_error":          "Tool %s error: %v",
+	"tui.no_provider":         "⚠ No AI provider configured",
+	"tui.no_provider.hint":    "Run 'skagent setup' to configure an AI provider.",
+	"tui.no_provider.error":   "no AI provider configured",
+	"tui.status":              "Model: %s | Messages: %d | /help for commands",
+	"tui.thinking":            "Thinking...",
+	"tui.rate_limited":        "Rate limited",
+	"tui.rate_limited.detail": "Rate limited: %s",
+	"tui.auto.enabled":        "Autonomous mode enabled",
+	"tui.auto.disabled":       "Autonomous mode disabled",
+	"tui.provider.unknown":    "unknown",
+	"tui.provider.current":    "Current provider: %s\nModel: %s",
+	"tui.models.title":        "Available free models on OpenRouter:\n\n",
+	"tui.command.unknown":     "Unknown command: %s\nType /help for available commands",
+	"tui.task.usage":          "usage: /task <id>",
+	"tui.delegate.no_plan":    "no plan to delegate: no reply lists tasks as a markdown checklist (\"- [ ] ...\")",
+	"tui.delegate.error":      "Creating the tasks: %v",
+	"tui.delegate.created":    "Created %d tasks on the daemon:",
+	"tui.rollback.usage":      "usage: /rollback <task-id>",
+	"tui.rollback.error":      "Rolling back the task: %v",
+	"tui.rollback.done":       "Rolled the workspace of task %s back to %s on %s (%s)",
+	"tui.template.list":       "Task templates (* marks required parameters):",
+	"tui.template.default":    "default: %s",
+	"tui.template.usage":      "usage: /template <name> param=value ...",
+	"tui.template.unknown":    "Unknown template %q; type /template to list them",
+	"tui.template.error":      "Creating the task from the template: %v",
+	"tui.template.created":    "Created task %s on the daemon: %s",
+	"tui.task.fetch_error":    "Fetching the task: %v",
+	"tui.task.heading":        "Task %s",
+	"tui.task.title":          "Title:",
+	"tui.task.status":         "Status:",
+	"tui.task.agent":          "Agent:",
+	"tui.task.revision":       "Revision:",
+	"tui.task.attempt":        "Attempt:",
+	"tui.task.error":          "Error:",
+	"tui.task.score":          "Score:",
+	"tui.task.score.value":    "%d of %d",
+	"tui.task.log":            "Execution log:",
+	"tui.task.log.empty":      "(empty)",
+	"tui.follow.usage":        "usage: /follow <agent-id>, or /follow alone to stop",
+	"tui.follow.started":      "Following agent %s; /follow or Esc stops",
+	"tui.follow.stopped":      "Stopped following agent %s",
+	"tui.follow.ended":        "The stream of agent %s ended",
+	"tui.follow.error":        "Following agent %s: %v",
+	"tui.theme.error":         "Theme: %v",
+	"tui.theme.unknown":       "Theme: unknown theme %q, using %s",
+	"tui.theme.loaded":        "loaded %s",
+	"tui.theme.removed":       "removed %s",
+	"tui.theme.changes":       "Themes %s",
+	"tui.theme.list":          "Available themes:\n\n",
+	"tui.theme.custom":        " (custom)",
+	"tui.theme.dir":           "\nCustom themes are read from %s",
+	"tui.theme.not_found":     "theme not found: %s",
+	"tui.theme.set":           "Theme set to %s",
+	"tui.help": `
+╭─────────────────────────────────────────────╮
+│           SkAgent Commands                   │
+╰─────────────────────────────────────────────╯
+
+  /auto      Toggle autonomous mode
+  /provider  Show current AI provider
+  /models    List available free models
+  /theme     List themes, or /theme <name> to switch
+             /theme preview [name] shows styles and contrast
+  /task <id> Show a task of the running daemon and its
+             execution log
+  /delegate [label...]
+             Create tasks on the daemon from the last
+             plan, with the given labels
+  /rollback <task-id>
+             Restore a task's workspace to the snapshot
+             taken before its agent ran
+  /template [name param=value...]
+             List task templates, or create a task on
+             the daemon from one
+  /follow <agent-id>
+             Live-tail an agent of the daemon: its
+             tasks, prompts, tool calls and results
+  /clear     Clear conversation
+  /help      Show this help
+  /quit      Exit application
+
+╭─────────────────────────────────────────────╮
+│           SpecKit Workflow                   │
+╰─────────────────────────────────────────────╯
+
+In autonomous mode, the agent will:
+1. Analyze your project idea
+2. Search for best practices
+3. Generate SpecKit specifications
+4. Create technical plans
+5. Break down into tasks
+
+Just describe your project idea to get started!
+
+╭─────────────────────────────────────────────╮
+│           Keyboard Shortcuts                 │
+╰─────────────────────────────────────────────╯
+
+  Enter      Send message
+  Ctrl+C     Exit
+  Esc        Cancel/Exit
+  ↑/↓        Scroll messages`,
+}
@@ -0,0 +1,115 @@
+// Package i18n translates the user-facing strings of the TUI, the setup
+// wizard and the command-line help. Messages live in per-locale catalogs
+// keyed by stable dotted names; a key missing from a catalog falls back to
+// English, and a key missing from English is shown as is.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Locale identifies a message catalog
+type Locale string
+
+const (
+	English Locale = "en"
+	Italian Locale = "it"
+)
+
+// DefaultLocale is used when nothing selects a supported locale
+const DefaultLocale = English
+
+// catalogs maps each supported locale to its messages
+var catalogs = map[Locale]map[string]string{
+	English: en,
+	Italian: it,
+}
+
+// current is the locale T translates to
+var current atomic.Value
+
+func init() {
+	current.Store(DefaultLocale)
+}
+
+// Locales lists the supported locales
+func Locales() []Locale {
+	list := make([]Locale, 0, len(catalogs))
+	for l := range catalogs {
+		list = append(list, l)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// Parse maps a locale name such as "it", "it_IT.UTF-8" or "en-US" to a
+// supported locale
+func Parse(name string) (Locale, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	lang, _, _ := strings.Cut(strings.ReplaceAll(name, "-", "_"), "_")
+	if _, ok := catalogs[Locale(lang)]; ok {
+		return Locale(lang), true
+	}
+	return "", false
+}
+
+// Detect picks the locale from $SKAGENT_LOCALE, then configured (the
+// locale of the configuration file), then the usual $LC_ALL,
+// $LC_MESSAGES and $LANG. Unsupported values are skipped; English is the
+// fallback.
+func Detect(configured string) Locale {
+	candidates := []string{
+		os.Getenv("SKAGENT_LOCALE"),
+		configured,
+		os.Getenv("LC_ALL"),
+		os.Getenv("LC_MESSAGES"),
+		os.Getenv("LANG"),
+	}
+	for _, c := range candidates {
+		if l, ok := Parse(c); ok {
+			return l
+		}
+	}
+	return DefaultLocale
+}
+
+// SetLocale selects the locale T translates to; unsupported locales
+// select English
+func SetLocale(l Locale) {
+	if _, ok := catalogs[l]; !ok {
+		l = DefaultLocale
+	}
+	current.Store(l)
+}
+
+// Current returns the selected locale
+func Current() Locale {
+	return current.Load().(Locale)
+}
+
+// T translates key to the selected locale and, given args, formats it
+// with fmt.Sprintf
+func T(key string, args ...interface{}) string {
+	return Translate(Current(), key, args...)
+}
+
+// Translate is T for an explicit locale
+func Translate(l Locale, key string, args ...interface{}) string {
+	msg, ok := catalogs[l][key]
+	if !ok {
+		if msg, ok = en[key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,74 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var verb = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// TestCatalogsMatch checks that every catalog has the keys of the English
+// one and takes the same format arguments
+func TestCatalogsMatch(t *testing.T) {
+	for _, l := range Locales() {
+		catalog := catalogs[l]
+		for key, msg := range en {
+			translated, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing %s", l, key)
+				continue
+			}
+			if want, got := verb.FindAllString(msg, -1), verb.FindAllString(translated, -1); !slices.Equal(want, got) {
+				t.Errorf("%s: %s takes %v, want %v", l, key, got, want)
+			}
+		}
+		for key := range catalog {
+			if _, ok := en[key]; !ok {
+				t.Errorf("%s: %s is not in the English catalog", l, key)
+			}
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, env := range []string{"SKAGENT_LOCALE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		t.Setenv(env, "")
+	}
+	if got := Detect(""); got != English {
+		t.Errorf("no locale: %s", got)
+	}
+	t.Setenv("LANG", "it_IT.UTF-8")
+	if got := Detect(""); got != Italian {
+		t.Errorf("LANG=it_IT.UTF-8: %s", got)
+	}
+	if got := Detect("en-US"); got != English {
+		t.Errorf("configured en-US: %s", got)
+	}
+	t.Setenv("LC_ALL", "C")
+	if got := Detect("fr"); got != Italian {
+		t.Errorf("unsupported locales should be skipped: %s", got)
+	}
+	t.Setenv("SKAGENT_LOCALE", "it")
+	if got := Detect("en"); got != Italian {
+		t.Errorf("SKAGENT_LOCALE should win: %s", got)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate(Italian, "tui.status", "m", 2); got != "Modello: m | Messaggi: 2 | /help per i comandi" {
+		t.Errorf("Italian: %q", got)
+	}
+	if got := Translate(Locale("fr"), "tui.thinking"); got != "Thinking..." {
+		t.Errorf("fallback: %q", got)
+	}
+	if got := Translate(English, "no.such.key"); got != "no.such.key" {
+		t.Errorf("unknown key: %q", got)
+	}
+
+	defer SetLocale(Current())
+	SetLocale(Italian)
+	if got := T("tui.thinking"); got != "Sto pensando..." {
+		t.Errorf("T: %q", got)
+	}
+}
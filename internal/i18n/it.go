@@ -0,0 +1,191 @@
+package i18n
+
+// it is the Italian catalog
+var it = map[string]string{
+	// Command-line help
+	"cli.usage": `Uso: skagent [--dry-run] [comando] [opzioni]
+
+Comandi:
+  (nessuno)     Avvia la TUI interattiva
+  setup         Avvia la procedura guidata di configurazione
+  init          Crea la directory .skagent e la struttura specs/ in un progetto
+  headless      Avvia il demone headless (server REST + MCP)
+  config        Ispeziona e valida la configurazione
+  backup        Crea, verifica e ripristina i backup di configurazione e stato
+  docs          Aggiorna ed elenca la documentazione SpecKit
+  bench         Mette sotto carico il registro con agenti e task sintetici
+  remote        Controlla un'istanza headless in esecuzione tramite la sua API
+  export        Esporta task, statistiche degli agenti e uso dei modelli in CSV o Parquet
+  mcp           Serve MCP su stdin/stdout per gli host che avviano skagent
+  service       Installa, rimuove o avvia il demone headless come servizio Windows
+  version       Mostra le informazioni sulla versione
+  help          Mostra questo aiuto
+
+Opzioni globali:
+  --dry-run     Registra cosa farebbero git, gh, spec-kit, gli aggiornamenti
+                del project manager e le scritture di file invece di farlo
+
+La lingua segue $SKAGENT_LOCALE, l'impostazione "locale" o $LANG
+(disponibili: en, it).
+`,
+	"cli.unknown_command": "comando sconosciuto: %s",
+
+	// Setup wizard
+	"wizard.title":               "🚀 Configurazione guidata di SkAgent",
+	"wizard.welcome.title":       "Benvenuto in SkAgent!",
+	"wizard.welcome.intro":       "SkAgent è un assistente allo sviluppo guidato dalle specifiche, basato sull'AI.\nConfiguriamo il tuo provider AI.\n\n",
+	"wizard.welcome.options":     "Opzioni disponibili:\n",
+	"wizard.welcome.openrouter":  "• OpenRouter - oltre 35 modelli GRATUITI (nessun costo!)\n",
+	"wizard.welcome.claude":      "• Claude Max - Usa il tuo abbonamento\n",
+	"wizard.welcome.cli":         "• Gemini/Codex CLI - Piani gratuiti disponibili\n",
+	"wizard.welcome.others":      "• Kimi, GLM, DeepSeek, Minimax - Opzioni gratuite o economiche\n",
+	"wizard.welcome.help":        "Premi Invio per continuare, Ctrl+C per uscire",
+	"wizard.provider.list":       "Seleziona il provider AI",
+	"wizard.provider.step":       "Passo 1: Seleziona il provider AI",
+	"wizard.provider.help":       "↑/↓ per spostarti, Invio per selezionare, Esc per tornare indietro",
+	"wizard.provider.openrouter": "🌐 OpenRouter (modelli gratuiti)",
+	"wizard.desc.openrouter":     "Oltre 35 modelli gratuiti tra cui Qwen Coder, DeepSeek R1, Llama 3.3 70B",
+	"wizard.desc.claude":         "Usa il tuo abbonamento Claude Max - nessun costo di API",
+	"wizard.desc.gemini":         "Usa Gemini di Google tramite CLI - piano gratuito disponibile",
+	"wizard.desc.codex":          "Usa OpenAI Codex tramite CLI",
+	"wizard.desc.kimi":           "Il modello Kimi di Moonshot AI - piano gratuito disponibile",
+	"wizard.desc.glm":            "GLM-4 di Zhipu - ottimizzato per il cinese, con piano gratuito",
+	"wizard.desc.deepseek":       "Modelli DeepSeek - molto economici",
+	"wizard.desc.minimax":        "Modelli Minimax orientati al codice",
+	"wizard.configure.step":      "Passo 2: Configura il provider",
+	"wizard.configure.help":      "Invio per continuare, Esc per tornare indietro",
+	"wizard.key.placeholder":     "Inserisci la tua chiave API...",
+	"wizard.key.free":            "Chiave API di %s (gratuita su %s):",
+	"wizard.key.site":            "Chiave API di %s (da %s):",
+	"wizard.key.plain":           "Chiave API di %s:",
+	"wizard.claude.login":        "Avvio del login OAuth di Claude Max...",
+	"wizard.gemini.login":        "Esegui prima 'gemini auth login', poi premi Invio",
+	"wizard.model.list":          "Seleziona il modello",
+	"wizard.model.step":          "Passo 3: Seleziona il modello",
+	"wizard.model.recommended":   "I modelli consigliati sono indicati con ⭐",
+	"wizard.model.help":          "↑/↓ per spostarti, Invio per selezionare, / per filtrare",
+	"wizard.model.description":   "%s | contesto %dk | %s",
+	"wizard.test.step":           "Passo 4: Verifica della connessione",
+	"wizard.test.testing":        "Verifica della connessione...",
+	"wizard.test.saved":          "✓ Configurazione salvata!",
+	"wizard.test.provider":       "Provider: %s",
+	"wizard.test.model":          "Modello: %s",
+	"wizard.test.help":           "Premi Invio per terminare",
+	"wizard.error":               "Errore: %v",
+	"wizard.complete.title":      "🎉 Configurazione completata!",
+	"wizard.complete.body":       "La configurazione è stata salvata.\n\nOra puoi avviare skagent e usare l'assistente.\nNell'applicazione usa /help per vedere i comandi disponibili.\n\n",
+	"wizard.complete.help":       "Premi Invio o Ctrl+C per uscire",
+
+	// TUI
+	"tui.placeholder":         "Descrivi la tua idea di progetto... (/help per i comandi)",
+	"tui.initializing":        "Avvio...",
+	"tui.welcome":             "Benvenuto! Descrivi la tua idea di progetto o digita /help per i comandi.\n",
+	"tui.role.user":           "Tu: ",
+	"tui.role.assistant":      "Agente: ",
+	"tui.role.system":         "Sistema: ",
+	"tui.role.error":          "Errore: ",
+	"tui.error":               "Errore: %v",
+	"tui.tool_error":          "Errore dello strumento %s: %v",
+	"tui.no_provider":         "⚠ Nessun provider AI configurato",
+	"tui.no_provider.hint":    "Esegui 'skagent setup' per configurare un provider AI.",
+	"tui.no_provider.error":   "nessun provider AI configurato",
+	"tui.status":              "Modello: %s | Messaggi: %d | /help per i comandi",
+	"tui.thinking":            "Sto pensando...",
+	"tui.rate_limited":        "Limite di richieste raggiunto",
+	"tui.rate_limited.detail": "Limite di richieste raggiunto: %s",
+	"tui.auto.enabled":        "Modalità autonoma attivata",
+	"tui.auto.disabled":       "Modalità autonoma disattivata",
+	"tui.provider.unknown":    "sconosciuto",
+	"tui.provider.current":    "Provider attuale: %s\nModello: %s",
+	"tui.models.title":        "Modelli gratuiti disponibili su OpenRouter:\n\n",
+	"tui.command.unknown":     "Comando sconosciuto: %s\nDigita /help per i comandi disponibili",
+	"tui.task.usage":          "uso: /task <id>",
+	"tui.delegate.no_plan":    "nessun piano da delegare: nessuna risposta elenca task come checklist markdown (\"- [ ] ...\")",
+	"tui.delegate.error":      "Creazione dei task: %v",
+	"tui.delegate.created":    "Creati %d task sul demone:",
+	"tui.rollback.usage":      "uso: /rollback <id-task>",
+	"tui.rollback.error":      "Rollback del task: %v",
+	"tui.rollback.done":       "Workspace del task %s riportato a %s su %s (%s)",
+	"tui.template.list":       "Modelli di task (* indica i parametri obbligatori):",
+	"tui.template.default":    "predefinito: %s",
+	"tui.template.usage":      "uso: /template <nome> parametro=valore ...",
+	"tui.template.unknown":    "Modello %q sconosciuto; digita /template per elencarli",
+	"tui.template.error":      "Creazione del task dal modello: %v",
+	"tui.template.created":    "Creato sul demone il task %s: %s",
+	"tui.task.fetch_error":    "Lettura del task: %v",
+	"tui.task.heading":        "Task %s",
+	"tui.task.title":          "Titolo:",
+	"tui.task.status":         "Stato:",
+	"tui.task.agent":          "Agente:",
+	"tui.task.revision":       "Revisione:",
+	"tui.task.attempt":        "Tentativo:",
+	"tui.task.error":          "Errore:",
+	"tui.task.score":          "Punteggio:",
+	"tui.task.score.value":    "%d su %d",
+	"tui.task.log":            "Log di esecuzione:",
+	"tui.task.log.empty":      "(vuoto)",
+	"tui.follow.usage":        "uso: /follow <id-agente>, oppure /follow da solo per smettere",
+	"tui.follow.started":      "Segui l'agente %s; /follow o Esc per smettere",
+	"tui.follow.stopped":      "Non segui più l'agente %s",
+	"tui.follow.ended":        "Lo stream dell'agente %s è terminato",
+	"tui.follow.error":        "Errore seguendo l'agente %s: %v",
+	"tui.theme.error":         "Tema: %v",
+	"tui.theme.unknown":       "Tema: tema sconosciuto %q, uso %s",
+	"tui.theme.loaded":        "caricati %s",
+	"tui.theme.removed":       "rimossi %s",
+	"tui.theme.changes":       "Temi %s",
+	"tui.theme.list":          "Temi disponibili:\n\n",
+	"tui.theme.custom":        " (personalizzato)",
+	"tui.theme.dir":           "\nI temi personalizzati vengono letti da %s",
+	"tui.theme.not_found":     "tema non trovato: %s",
+	"tui.theme.set":           "Tema impostato: %s",
+	"tui.help": `
+╭─────────────────────────────────────────────╮
+│           Comandi di SkAgent                 │
+╰─────────────────────────────────────────────╯
+
+  /auto      Attiva o disattiva la modalità autonoma
+  /provider  Mostra il provider AI attuale
+  /models    Elenca i modelli gratuiti disponibili
+  /theme     Elenca i temi, o /theme <nome> per cambiarlo
+             /theme preview [nome] mostra stili e contrasto
+  /task <id> Mostra un task del demone in esecuzione e il
+             suo log di esecuzione
+  /delegate [etichetta...]
+             Crea sul demone i task dell'ultimo piano,
+             con le etichette indicate
+  /rollback <id-task>
+             Riporta il workspace di un task allo
+             snapshot preso prima del suo agente
+  /template [nome parametro=valore...]
+             Elenca i modelli di task, o crea sul
+             demone un task da uno di essi
+  /follow <id-agente>
+             Segue dal vivo un agente del demone: task,
+             prompt, chiamate ai tool e risultati
+  /clear     Cancella la conversazione
+  /help      Mostra questo aiuto
+  /quit      Esci dall'applicazione
+
+╭─────────────────────────────────────────────╮
+│           Flusso di lavoro SpecKit           │
+╰─────────────────────────────────────────────╯
+
+In modalità autonoma l'agente:
+1. Analizza la tua idea di progetto
+2. Cerca le migliori pratiche
+3. Genera le specifiche SpecKit
+4. Crea i piani tecnici
+5. Suddivide il lavoro in task
+
+Descrivi la tua idea di progetto per iniziare!
+
+╭─────────────────────────────────────────────╮
+│           Scorciatoie da tastiera            │
+╰─────────────────────────────────────────────╯
+
+  Invio      Invia il messaggio
+  Ctrl+C     Esci
+  Esc        Annulla/Esci
+  ↑/↓        Scorri i messaggi`,
+}
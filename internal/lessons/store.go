@@ -0,0 +1,504 @@
+// Package lessons keeps what agents learned from finished tasks, what
+// worked and what went wrong, and picks the lessons relevant to a new task
+// so that they can be added to its prompt.
+package lessons
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/redact"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned for an unknown lesson ID
+var ErrNotFound = errors.New("lesson not found")
+
+// maxLessonLength bounds the text of a lesson, in bytes
+const maxLessonLength = 500
+
+// duplicateOverlap is the share of words two lessons of an agent must have
+// in common for the second to reinforce the first instead of being added
+const duplicateOverlap = 0.8
+
+// Kind tells a lesson to repeat from one to avoid
+type Kind string
+
+const (
+	KindSuccess Kind = "success"
+	KindPitfall Kind = "pitfall"
+)
+
+// Lesson is something an agent learned from a task
+type Lesson struct {
+	ID      string `json:"id"`
+	AgentID string `json:"agent_id"`
+	// TaskID is the task the lesson was learned from, if any
+	TaskID    string   `json:"task_id,omitempty"`
+	Kind      Kind     `json:"kind"`
+	Text      string   `json:"text"`
+	Labels    []string `json:"labels,omitempty"`
+	ProjectID string   `json:"project_id,omitempty"`
+	// Uses counts the finished tasks the lesson was given to, and Helped
+	// those of them that succeeded
+	Uses      int       `json:"uses"`
+	Helped    int       `json:"helped"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Ranked is a lesson with how relevant it is to a task
+type Ranked struct {
+	Lesson
+	Score float64 `json:"score"`
+}
+
+// Store keeps the lessons of every agent. It is safe for concurrent use.
+type Store struct {
+	path        string
+	topK        int
+	maxPerAgent int
+	logger      *log.Logger
+
+	mu      sync.RWMutex
+	lessons map[string][]*Lesson // by agent ID
+	// given records the lessons handed out for each unfinished task, so
+	// that its outcome can be credited to them
+	given map[string]given
+}
+
+// given is the lessons of one agent handed out for a task
+type given struct {
+	agentID string
+	ids     []string
+}
+
+// NewStore loads the lessons saved at path, which may not exist yet. An
+// empty path keeps lessons in memory only.
+func NewStore(path string, cfg config.LessonsConfig) (*Store, error) {
+	s := &Store{
+		path:        path,
+		topK:        cfg.TopK,
+		maxPerAgent: cfg.MaxPerAgent,
+		logger:      logging.New("lessons", "[LESSONS] ", log.Writer()),
+		lessons:     make(map[string][]*Lesson),
+		given:       make(map[string]given),
+	}
+	if s.topK <= 0 {
+		s.topK = 3
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved []*Lesson
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	for _, l := range saved {
+		s.lessons[l.AgentID] = append(s.lessons[l.AgentID], l)
+	}
+	return s, nil
+}
+
+// TopK is how many lessons a prompt gets
+func (s *Store) TopK() int {
+	return s.topK
+}
+
+// save writes the lessons; the caller holds s.mu
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	var all []*Lesson
+	for _, list := range s.lessons {
+		all = append(all, list...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt.Before(all[j].CreatedAt) })
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Add records a lesson for l.AgentID. A lesson that says much the same as
+// one the agent already has refreshes that one instead, which is returned.
+func (s *Store) Add(l Lesson) (*Lesson, error) {
+	l.Text = clip(redact.String(strings.TrimSpace(l.Text)))
+	if l.AgentID == "" || l.Text == "" {
+		return nil, fmt.Errorf("a lesson needs an agent and a text")
+	}
+	if l.Kind == "" {
+		l.Kind = KindSuccess
+	}
+	if l.Kind != KindSuccess && l.Kind != KindPitfall {
+		return nil, fmt.Errorf("unknown lesson kind %q", l.Kind)
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	words := keywords(l.Text)
+	for _, existing := range s.lessons[l.AgentID] {
+		if existing.Kind == l.Kind && overlap(words, keywords(existing.Text)) >= duplicateOverlap {
+			existing.UpdatedAt = now
+			if err := s.save(); err != nil {
+				return nil, err
+			}
+			c := *existing
+			return &c, nil
+		}
+	}
+
+	l.ID = uuid.New().String()
+	l.Uses, l.Helped = 0, 0
+	l.CreatedAt, l.UpdatedAt = now, now
+	l.Labels = append([]string(nil), l.Labels...)
+	list := append(s.lessons[l.AgentID], &l)
+	if s.maxPerAgent > 0 && len(list) > s.maxPerAgent {
+		list = evict(list, len(list)-s.maxPerAgent)
+	}
+	s.lessons[l.AgentID] = list
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	c := l
+	return &c, nil
+}
+
+// evict drops the n lessons that helped least, the oldest first among
+// equals
+func evict(list []*Lesson, n int) []*Lesson {
+	order := append([]*Lesson(nil), list...)
+	sort.SliceStable(order, func(i, j int) bool {
+		wi, wj := order[i].weight(), order[j].weight()
+		if wi != wj {
+			return wi < wj
+		}
+		return order[i].UpdatedAt.Before(order[j].UpdatedAt)
+	})
+	drop := make(map[*Lesson]bool, n)
+	for _, l := range order[:n] {
+		drop[l] = true
+	}
+	kept := list[:0]
+	for _, l := range list {
+		if !drop[l] {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// List returns an agent's lessons, newest first
+func (s *Store) List(agentID string) []Lesson {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := s.lessons[agentID]
+	out := make([]Lesson, len(list))
+	for i, l := range list {
+		out[len(list)-1-i] = *l
+	}
+	return out
+}
+
+// Delete removes a lesson of an agent
+func (s *Store) Delete(agentID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.lessons[agentID]
+	for i, l := range list {
+		if l.ID != id {
+			continue
+		}
+		s.lessons[agentID] = append(list[:i:i], list[i+1:]...)
+		if err := s.save(); err != nil {
+			s.lessons[agentID] = list
+			return err
+		}
+		return nil
+	}
+	return ErrNotFound
+}
+
+// Relevant returns up to k of an agent's lessons that share words or
+// labels with query and labels, best first. Lessons that went with
+// successful tasks rank higher. A k of zero or less uses the store's TopK.
+func (s *Store) Relevant(agentID, query string, labels []string, k int) []Ranked {
+	if k <= 0 {
+		k = s.topK
+	}
+	words := keywords(query)
+	for _, l := range labels {
+		for w := range keywords(l) {
+			words[w] = true
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ranked []Ranked
+	for _, l := range s.lessons[agentID] {
+		score := overlap(keywords(l.Text), words)
+		for _, label := range l.Labels {
+			if containsFold(labels, label) {
+				score += 0.5
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		ranked = append(ranked, Ranked{Lesson: *l, Score: score * l.weight()})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+	return ranked
+}
+
+// ForTask returns the lessons for a task's prompt from the agent it is
+// assigned to, or agentID when it is not empty, and remembers them so
+// that the task's outcome is credited to them
+func (s *Store) ForTask(task *agents.Task, agentID string) []Ranked {
+	if agentID == "" {
+		agentID = task.AssignedTo
+	}
+	ranked := s.Relevant(agentID, task.Title+"\n"+task.Description, task.Labels, 0)
+	if len(ranked) == 0 {
+		return nil
+	}
+	ids := make([]string, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.ID
+	}
+	s.mu.Lock()
+	s.given[task.ID] = given{agentID: agentID, ids: ids}
+	s.mu.Unlock()
+	return ranked
+}
+
+// Learn credits the outcome of a finished task to the lessons it was
+// given and records what it taught its agent: the lessons the agent
+// reported with its result or, without any, one distilled from the result
+func (s *Store) Learn(task *agents.Task) {
+	if task.Result == nil {
+		return
+	}
+
+	s.mu.Lock()
+	g, ok := s.given[task.ID]
+	delete(s.given, task.ID)
+	if ok {
+		for _, l := range s.lessons[g.agentID] {
+			for _, id := range g.ids {
+				if l.ID == id {
+					l.Uses++
+					if task.Result.Success {
+						l.Helped++
+					}
+				}
+			}
+		}
+		if err := s.save(); err != nil {
+			s.logger.Printf("ERROR: Failed to save lessons: %v", err)
+		}
+	}
+	s.mu.Unlock()
+
+	if task.AssignedTo == "" {
+		return
+	}
+	kind := KindSuccess
+	if !task.Result.Success {
+		kind = KindPitfall
+	}
+	texts := task.Result.Lessons
+	if len(texts) == 0 {
+		if text := Distill(task); text != "" {
+			texts = []string{text}
+		}
+	}
+	for _, text := range texts {
+		_, err := s.Add(Lesson{
+			AgentID:   task.AssignedTo,
+			TaskID:    task.ID,
+			Kind:      kind,
+			Text:      text,
+			Labels:    task.Labels,
+			ProjectID: task.ProjectID,
+		})
+		if err != nil {
+			s.logger.Printf("ERROR: Failed to record lesson from task %s: %v", task.ID, err)
+		}
+	}
+}
+
+// Distill sums up a finished task in one lesson: the error of a failed
+// task, or the start of a successful task's output. It returns "" when
+// there is nothing worth keeping.
+func Distill(task *agents.Task) string {
+	if task.Result == nil {
+		return ""
+	}
+	if !task.Result.Success {
+		reason := firstLine(task.Result.Error)
+		if reason == "" {
+			return ""
+		}
+		return fmt.Sprintf("%q failed: %s", task.Title, reason)
+	}
+	summary := firstLine(task.Result.Output)
+	if summary == "" {
+		return ""
+	}
+	return fmt.Sprintf("%q succeeded: %s", task.Title, summary)
+}
+
+// Run learns from the tasks finishing in events, such as a registry
+// subscription, until the channel is closed or ctx is done. Failed runs
+// that will be retried teach nothing yet.
+func (s *Store) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			switch e.Type {
+			case agents.EventTaskCompleted, agents.EventTaskFailed:
+				if task, ok := e.Data["task"].(agents.Task); ok && e.Final() {
+					s.Learn(&task)
+				}
+			case agents.EventTaskCancelled:
+				if task, ok := e.Data["task"].(agents.Task); ok {
+					s.mu.Lock()
+					delete(s.given, task.ID)
+					s.mu.Unlock()
+				}
+			}
+		}
+	}
+}
+
+// Prompt formats lessons for a system prompt, or returns "" for none
+func Prompt(ranked []Ranked) string {
+	if len(ranked) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Lessons from your past tasks:\n")
+	for _, r := range ranked {
+		switch r.Kind {
+		case KindPitfall:
+			b.WriteString("- Avoid: ")
+		default:
+			b.WriteString("- Worked: ")
+		}
+		b.WriteString(r.Text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// weight favours lessons that went with successful tasks; an unused
+// lesson weighs 1
+func (l *Lesson) weight() float64 {
+	return 2 * float64(l.Helped+1) / float64(l.Uses+2)
+}
+
+// stopWords are left out of the words compared between lessons and tasks
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true,
+	"that": true, "this": true, "into": true, "when": true, "are": true,
+	"not": true, "all": true, "its": true, "was": true, "failed": true,
+	"succeeded": true,
+}
+
+// keywords returns the distinct words of s long enough to tell texts apart
+func keywords(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) >= 3 && !stopWords[w] {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+// overlap is the share of a's words found in b
+func overlap(a, b map[string]bool) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a))
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return clip(s)
+}
+
+// clip shortens s to maxLessonLength bytes on a rune boundary
+func clip(s string) string {
+	if len(s) <= maxLessonLength {
+		return s
+	}
+	cut := maxLessonLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
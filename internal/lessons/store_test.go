@@ -0,0 +1,86 @@
+package lessons
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestLearnAndRank(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lessons.json")
+	s, err := NewStore(path, config.LessonsConfig{TopK: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Learn(&agents.Task{
+		ID: "t1", Title: "Fix flaky migration test", AssignedTo: "a1", Labels: []string{"database"},
+		Result: &agents.TaskResult{Success: false, Error: "migration lock held by another connection\nstack..."},
+	})
+	s.Learn(&agents.Task{
+		ID: "t2", Title: "Add index to users table", AssignedTo: "a1",
+		Result: &agents.TaskResult{Success: true, Lessons: []string{
+			"Run migrations inside a transaction so that a failed migration leaves no lock behind",
+			"Regenerate the schema snapshot after changing a migration",
+		}},
+	})
+	// Much the same lesson again reinforces the first one
+	s.Learn(&agents.Task{
+		ID: "t3", Title: "Another", AssignedTo: "a1",
+		Result: &agents.TaskResult{Success: true, Lessons: []string{
+			"Run migrations inside a transaction so that a failed migration leaves no lock",
+		}},
+	})
+
+	if list := s.List("a1"); len(list) != 3 || list[2].Kind != KindPitfall || !strings.Contains(list[2].Text, "migration lock held") {
+		t.Fatalf("lessons = %+v", list)
+	}
+
+	task := &agents.Task{ID: "t4", Title: "Write a migration for orders", Labels: []string{"database"}, AssignedTo: "a1"}
+	ranked := s.ForTask(task, "")
+	if len(ranked) != 2 || ranked[0].Kind != KindPitfall {
+		t.Fatalf("ranked = %+v", ranked)
+	}
+	prompt := Prompt(ranked)
+	if !strings.HasPrefix(prompt, "Lessons from your past tasks:\n- Avoid: ") || strings.Count(prompt, "\n- ") != 2 {
+		t.Errorf("prompt = %q", prompt)
+	}
+
+	// The task succeeds: the lessons it was given are credited
+	task.Result = &agents.TaskResult{Success: true}
+	s.Learn(task)
+	reloaded, err := NewStore(path, config.LessonsConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	helped := 0
+	for _, l := range reloaded.List("a1") {
+		if l.Uses == 1 && l.Helped == 1 {
+			helped++
+		}
+	}
+	if helped != 2 {
+		t.Errorf("%d lessons credited, want 2", helped)
+	}
+	if got := reloaded.Relevant("a2", "migration", nil, 0); len(got) != 0 {
+		t.Errorf("lessons leaked across agents: %+v", got)
+	}
+}
+
+func TestEvictsLeastHelpful(t *testing.T) {
+	s, _ := NewStore("", config.LessonsConfig{MaxPerAgent: 2})
+	first, _ := s.Add(Lesson{AgentID: "a", Text: "alpha bravo charlie"})
+	s.mu.Lock()
+	s.lessons["a"][0].Uses, s.lessons["a"][0].Helped = 4, 4
+	s.mu.Unlock()
+	s.Add(Lesson{AgentID: "a", Text: "delta echo foxtrot"})
+	s.Add(Lesson{AgentID: "a", Text: "golf hotel india"})
+
+	list := s.List("a")
+	if len(list) != 2 || list[1].ID != first.ID || list[0].Text != "golf hotel india" {
+		t.Fatalf("kept %+v", list)
+	}
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/biodoia/skagent/internal/redact"
+)
+
+// New returns a standard logger that writes to out and captures every
+// line into the default ring under the given component name
+func New(component, prefix string, out io.Writer) *log.Logger {
+	return NewWithRing(Default(), component, prefix, out)
+}
+
+// NewWithRing is like New but captures into a specific ring
+func NewWithRing(ring *Ring, component, prefix string, out io.Writer) *log.Logger {
+	w := &ringWriter{ring: ring, component: component, prefix: prefix, out: out}
+	return log.New(w, prefix, log.LstdFlags|log.Lmsgprefix)
+}
+
+// ringWriter tees formatted log lines to an output and the ring, redacting
+// secrets on the way
+type ringWriter struct {
+	ring      *Ring
+	component string
+	prefix    string
+	out       io.Writer
+	mu        sync.Mutex
+}
+
+func (w *ringWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	clean := []byte(redact.String(string(p)))
+	if w.out != nil {
+		if _, err := w.out.Write(clean); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, line := range bytes.Split(bytes.TrimRight(clean, "\n"), []byte("\n")) {
+		msg := string(line)
+		if w.prefix != "" {
+			if i := strings.Index(msg, w.prefix); i >= 0 {
+				msg = msg[i+len(w.prefix):]
+			}
+		}
+		level, msg := classify(msg)
+		w.ring.Append(Entry{Level: level, Component: w.component, Message: msg})
+	}
+	return len(p), nil
+}
+
+// classify determines the level of a message from the "DEBUG:", "INFO:",
+// "WARN:" or "ERROR:" marker its call site put in front of it; unmarked
+// messages are at info level, whatever words they contain
+func classify(msg string) (Level, string) {
+	upper := strings.ToUpper(msg)
+	for _, marker := range []struct {
+		tag   string
+		level Level
+	}{
+		{"DEBUG:", LevelDebug},
+		{"INFO:", LevelInfo},
+		{"WARN:", LevelWarn},
+		{"WARNING:", LevelWarn},
+		{"ERROR:", LevelError},
+	} {
+		if strings.HasPrefix(upper, marker.tag) {
+			return marker.level, strings.TrimSpace(msg[len(marker.tag):])
+		}
+	}
+	return LevelInfo, msg
+}
@@ -0,0 +1,222 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of entries kept by the default ring
+const DefaultCapacity = 2000
+
+// Level is the severity of a log entry
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) to a Level
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level: %q", s)
+	}
+}
+
+func (l Level) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+func (l *Level) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	parsed, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// Entry is a single captured log line
+type Entry struct {
+	Seq       uint64    `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+	Level     Level     `json:"level"`
+	Component string    `json:"component"`
+	Message   string    `json:"message"`
+}
+
+// Filter selects entries from a Ring
+type Filter struct {
+	MinLevel  Level
+	Since     time.Time
+	Component string
+	AfterSeq  uint64
+	Limit     int // most recent N matches; 0 means all
+}
+
+// Match reports whether an entry satisfies the filter
+func (f Filter) Match(e Entry) bool {
+	if e.Level < f.MinLevel {
+		return false
+	}
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if f.Component != "" && !strings.EqualFold(e.Component, f.Component) {
+		return false
+	}
+	return e.Seq > f.AfterSeq
+}
+
+// Ring is a fixed-size in-memory log buffer with live subscribers
+type Ring struct {
+	mu      sync.RWMutex
+	entries []Entry
+	next    int
+	full    bool
+	seq     uint64
+	subs    map[int]chan Entry
+	nextSub int
+}
+
+// NewRing creates a ring holding at most capacity entries
+func NewRing(capacity int) *Ring {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Ring{
+		entries: make([]Entry, capacity),
+		subs:    make(map[int]chan Entry),
+	}
+}
+
+var defaultRing = NewRing(DefaultCapacity)
+
+// Default returns the process-wide ring shared by all component loggers
+func Default() *Ring {
+	return defaultRing
+}
+
+// Append stores an entry, assigning its sequence number, and fans it out
+// to subscribers. Slow subscribers drop entries rather than block logging.
+func (r *Ring) Append(e Entry) Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	e.Seq = r.seq
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+
+	for _, ch := range r.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return e
+}
+
+// Query returns the buffered entries matching f, oldest first
+func (r *Ring) Query(f Filter) []Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []Entry
+	r.each(func(e Entry) {
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	})
+
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[len(out)-f.Limit:]
+	}
+	return out
+}
+
+// Len returns the number of buffered entries
+func (r *Ring) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.full {
+		return len(r.entries)
+	}
+	return r.next
+}
+
+// Subscribe registers for entries appended from now on. The returned
+// cancel function must be called to release the subscription.
+func (r *Ring) Subscribe(buffer int) (<-chan Entry, func()) {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	ch := make(chan Entry, buffer)
+
+	r.mu.Lock()
+	id := r.nextSub
+	r.nextSub++
+	r.subs[id] = ch
+	r.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.subs, id)
+			r.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// each visits buffered entries oldest first; callers must hold r.mu
+func (r *Ring) each(fn func(Entry)) {
+	if r.full {
+		for _, e := range r.entries[r.next:] {
+			fn(e)
+		}
+	}
+	for _, e := range r.entries[:r.next] {
+		fn(e)
+	}
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRing_WrapsAndFilters(t *testing.T) {
+	r := NewRing(3)
+	r.Append(Entry{Level: LevelInfo, Component: "api", Message: "one"})
+	r.Append(Entry{Level: LevelError, Component: "mcp", Message: "two"})
+	r.Append(Entry{Level: LevelDebug, Component: "api", Message: "three"})
+	r.Append(Entry{Level: LevelWarn, Component: "api", Message: "four"})
+
+	all := r.Query(Filter{})
+	if len(all) != 3 || all[0].Message != "two" || all[2].Message != "four" {
+		t.Fatalf("Query() = %+v, want two..four oldest first", all)
+	}
+
+	if got := r.Query(Filter{MinLevel: LevelWarn}); len(got) != 2 {
+		t.Errorf("MinLevel warn matched %d entries, want 2", len(got))
+	}
+	if got := r.Query(Filter{Component: "API"}); len(got) != 2 {
+		t.Errorf("Component api matched %d entries, want 2", len(got))
+	}
+	if got := r.Query(Filter{Limit: 1}); len(got) != 1 || got[0].Message != "four" {
+		t.Errorf("Limit 1 = %+v, want most recent entry", got)
+	}
+	if got := r.Query(Filter{Since: time.Now().Add(time.Hour)}); len(got) != 0 {
+		t.Errorf("Since in the future matched %d entries", len(got))
+	}
+}
+
+func TestRing_Subscribe(t *testing.T) {
+	r := NewRing(10)
+	ch, cancel := r.Subscribe(1)
+	defer cancel()
+
+	r.Append(Entry{Message: "hello"})
+	select {
+	case e := <-ch:
+		if e.Message != "hello" || e.Seq != 1 {
+			t.Errorf("got %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber did not receive entry")
+	}
+}
+
+func TestLogger_CapturesComponentAndLevel(t *testing.T) {
+	r := NewRing(10)
+	var out bytes.Buffer
+	logger := NewWithRing(r, "engine", "[ENGINE] ", &out)
+
+	logger.Printf("Started")
+	logger.Printf("ERROR: Completion failed: boom")
+	logger.Printf("DEBUG: details")
+	logger.Printf("Retrying after a failed attempt")
+
+	entries := r.Query(Filter{})
+	if len(entries) != 4 {
+		t.Fatalf("captured %d entries, want 4", len(entries))
+	}
+	want := []struct {
+		level Level
+		msg   string
+	}{
+		{LevelInfo, "Started"},
+		{LevelError, "Completion failed: boom"},
+		{LevelDebug, "details"},
+		// The level is the call site's, not guessed from the words
+		{LevelInfo, "Retrying after a failed attempt"},
+	}
+	for i, w := range want {
+		if entries[i].Level != w.level || entries[i].Message != w.msg || entries[i].Component != "engine" {
+			t.Errorf("entry %d = %+v, want %s %q", i, entries[i], w.level, w.msg)
+		}
+	}
+	if out.Len() == 0 {
+		t.Error("logger did not write to its output")
+	}
+}
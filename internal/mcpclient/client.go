@@ -0,0 +1,379 @@
+// Package mcpclient connects to external MCP servers, over stdio or
+// HTTP+SSE, and wraps their tools as tools.Tool, so that agents use the
+// filesystem, browser or database servers of the MCP ecosystem like the
+// built-in tools.
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+)
+
+// ProtocolVersion is the MCP revision the client asks for, the newest of
+// ProtocolVersions
+const ProtocolVersion = "2025-03-26"
+
+// ProtocolVersions are the revisions the client speaks, newest first. The
+// client uses nothing that differs between them, so it accepts any.
+var ProtocolVersions = []string{ProtocolVersion, "2024-11-05"}
+
+// DefaultTimeout bounds connecting and each call when the server's
+// configuration gives no timeout
+const DefaultTimeout = 60 * time.Second
+
+// ErrClosed is returned by calls on a connection that has ended
+var ErrClosed = errors.New("mcp connection closed")
+
+// ToolInfo describes a tool of a server, as tools/list returns it
+type ToolInfo struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema,omitempty"`
+}
+
+// Content is a piece of a tool result
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// CallResult is the result of tools/call. IsError is set when the tool
+// ran and failed.
+type CallResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text joins the text content of a result; other content is named by its
+// type
+func (r *CallResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		} else {
+			parts = append(parts, fmt.Sprintf("[%s content]", c.Type))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// RPCError is a JSON-RPC error returned by a server
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// message is any JSON-RPC 2.0 message
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// transport carries the messages of one connection
+type transport interface {
+	// send delivers one encoded message to the server
+	send(ctx context.Context, data []byte) error
+	// incoming returns the messages from the server; it is closed when the
+	// connection ends
+	incoming() <-chan []byte
+	close() error
+}
+
+// Client is a connection to an external MCP server
+type Client struct {
+	name       string
+	namespace  string
+	t          transport
+	timeout    time.Duration
+	logger     *log.Logger
+	serverName string
+	// protocolVersion is the revision negotiated in the handshake
+	protocolVersion string
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+	done    chan struct{}
+}
+
+// Connect starts or dials the server of cfg and completes the MCP
+// handshake
+func Connect(ctx context.Context, cfg config.MCPServerConfig) (*Client, error) {
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	logger := logging.New("mcpclient", "[MCP-CLIENT] ", log.Writer())
+	var (
+		t   transport
+		err error
+	)
+	if cfg.Command != "" {
+		t, err = startStdio(cfg, logger)
+	} else {
+		t, err = dialSSE(ctx, cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mcp server %s: %w", cfg.Name, err)
+	}
+
+	c := &Client{
+		name:      cfg.Name,
+		namespace: cfg.ToolNamespace(),
+		t:         t,
+		timeout:   timeout,
+		logger:    logger,
+		pending:   make(map[int64]chan *message),
+		done:      make(chan struct{}),
+	}
+	go c.read()
+
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("mcp server %s: %w", cfg.Name, err)
+	}
+	return c, nil
+}
+
+// Name returns the name of the server in the configuration
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Namespace returns the prefix of the names of the server's tools
+func (c *Client) Namespace() string {
+	if c.namespace != "" {
+		return c.namespace
+	}
+	return c.name
+}
+
+// ServerName returns the name the server gave in its handshake
+func (c *Client) ServerName() string {
+	return c.serverName
+}
+
+// ProtocolVersion returns the protocol revision negotiated with the server
+func (c *Client) ProtocolVersion() string {
+	return c.protocolVersion
+}
+
+// Done is closed when the connection ends
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// supported reports whether the client speaks a protocol revision
+func supported(version string) bool {
+	for _, v := range ProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// initialize performs the handshake: initialize, then
+// notifications/initialized. The server answers with the revision to
+// speak, which the client refuses if it does not know it.
+func (c *Client) initialize(ctx context.Context) error {
+	var result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"serverInfo"`
+	}
+	err := c.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "skagent", "version": "2.0.0"},
+	}, &result)
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if !supported(result.ProtocolVersion) {
+		return fmt.Errorf("initialize: the server speaks protocol %q; the client speaks %s", result.ProtocolVersion, strings.Join(ProtocolVersions, ", "))
+	}
+	c.protocolVersion = result.ProtocolVersion
+	c.serverName = result.ServerInfo.Name
+	c.logger.Printf("Connected to MCP server %s (%s %s, protocol %s)", c.name, result.ServerInfo.Name, result.ServerInfo.Version, result.ProtocolVersion)
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+// ListTools returns every tool of the server, following pagination
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var all []ToolInfo
+	cursor := ""
+	for {
+		var params map[string]interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+		var page struct {
+			Tools      []ToolInfo `json:"tools"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool runs a tool of the server. A tool that ran and failed returns a
+// result with IsError set, not an error.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var result CallResult
+	if err := c.call(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close ends the connection, stopping the server process of a stdio
+// server
+func (c *Client) Close() error {
+	return c.t.close()
+}
+
+// call sends a request and decodes its result into out. Without a
+// deadline in ctx the call is bounded by the server's timeout; a call
+// given up on is cancelled on the server.
+func (c *Client) call(ctx context.Context, method string, params, out interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(message{JSONRPC: "2.0", ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if err := c.t.send(ctx, data); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if out == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, out)
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		c.notify(context.Background(), "notifications/cancelled", map[string]interface{}{
+			"requestId": id,
+			"reason":    ctx.Err().Error(),
+		})
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+// notify sends a notification
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	data, err := json.Marshal(message{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	return c.t.send(ctx, data)
+}
+
+// read delivers responses to their callers and answers the server's own
+// requests until the connection ends
+func (c *Client) read() {
+	defer close(c.done)
+	for data := range c.t.incoming() {
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.logger.Printf("ERROR: MCP server %s sent an invalid message: %v", c.name, err)
+			continue
+		}
+		switch {
+		case msg.Method != "" && len(msg.ID) > 0:
+			c.answer(&msg)
+		case msg.Method != "":
+			// Notifications: nothing to do yet
+		default:
+			id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[id]
+			c.mu.Unlock()
+			if ok {
+				ch <- &msg
+			}
+		}
+	}
+	c.logger.Printf("Connection to MCP server %s closed", c.name)
+}
+
+// answer replies to a request from the server: ping is answered, the
+// rest are not supported
+func (c *Client) answer(req *message) {
+	reply := message{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &RPCError{Code: -32601, Message: "method not supported by skagent: " + req.Method}
+	}
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if err := c.t.send(ctx, data); err != nil {
+		c.logger.Printf("ERROR: Failed to answer %s of MCP server %s: %v", req.Method, c.name, err)
+	}
+}
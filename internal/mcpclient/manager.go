@@ -0,0 +1,340 @@
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// ErrServerNotFound is returned for a server the configuration does not
+// declare
+var ErrServerNotFound = errors.New("mcp server not found")
+
+// ServerStatus is the state of a configured server
+type ServerStatus struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Transport is "stdio" or "sse"
+	Transport string `json:"transport"`
+	Enabled   bool   `json:"enabled"`
+	Connected bool   `json:"connected"`
+	// Tools are the names of the server's tools agents can use
+	Tools []string `json:"tools"`
+	// Shadowed are tools of the server left out because a built-in tool
+	// or an earlier server has the name
+	Shadowed []string `json:"shadowed,omitempty"`
+	// Error is why the last connection failed
+	Error string `json:"error,omitempty"`
+}
+
+// upstream is a configured server and its connection, if any
+type upstream struct {
+	cfg      config.MCPServerConfig
+	enabled  bool
+	client   *Client
+	tools    []ToolInfo
+	err      string
+	added    []string
+	shadowed []string
+}
+
+// connected reports whether the server's connection is up
+func (u *upstream) connected() bool {
+	if u.client == nil {
+		return false
+	}
+	select {
+	case <-u.client.Done():
+		return false
+	default:
+		return true
+	}
+}
+
+// Manager holds the connections to the configured MCP servers and keeps
+// the tools of the enabled ones in a ToolManager. A tool is named after
+// its server's namespace, as in "fs.read_file". When two servers offer
+// the same name the one declared first in the configuration has it, and
+// a built-in tool keeps its name over every server, whatever the order in
+// which servers were enabled.
+type Manager struct {
+	tm     *tools.ToolManager
+	logger *log.Logger
+
+	// opMu orders Enable and Disable, which connect outside mu
+	opMu sync.Mutex
+
+	mu      sync.Mutex
+	servers []*upstream
+	// added are the tools the manager added to tm, by name
+	added map[string]*Tool
+}
+
+// ConnectAll connects to the enabled servers, in parallel, and adds their
+// tools to tm. A server that cannot be reached is logged and skipped; it
+// can be enabled again later.
+func ConnectAll(ctx context.Context, servers []config.MCPServerConfig, tm *tools.ToolManager) *Manager {
+	m := &Manager{
+		tm:      tm,
+		logger:  logging.New("mcpclient", "[MCP-CLIENT] ", log.Writer()),
+		servers: make([]*upstream, len(servers)),
+		added:   make(map[string]*Tool),
+	}
+
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		u := &upstream{cfg: srv, enabled: !srv.Disabled}
+		m.servers[i] = u
+		if !u.enabled {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u.client, u.tools, u.err = m.connect(ctx, srv)
+			if u.client == nil {
+				u.enabled = false
+			}
+		}()
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sync()
+	return m
+}
+
+// connect connects to a server and lists its tools; it returns the
+// connection error as text
+func (m *Manager) connect(ctx context.Context, srv config.MCPServerConfig) (*Client, []ToolInfo, string) {
+	c, err := Connect(ctx, srv)
+	if err != nil {
+		m.logger.Printf("WARN: Skipping MCP server %s: %v", srv.Name, err)
+		return nil, nil, err.Error()
+	}
+	list, err := c.ListTools(ctx)
+	if err != nil {
+		m.logger.Printf("WARN: Skipping MCP server %s: listing tools: %v", srv.Name, err)
+		c.Close()
+		return nil, nil, "listing tools: " + err.Error()
+	}
+	return c, list, ""
+}
+
+// sync brings the tools of tm in line with the enabled servers; the
+// caller holds m.mu
+func (m *Manager) sync() {
+	want := make(map[string]*Tool)
+	for _, u := range m.servers {
+		u.added, u.shadowed = nil, nil
+		if !u.enabled || u.client == nil {
+			continue
+		}
+		for _, info := range u.tools {
+			tool := NewTool(u.client, info)
+			name := tool.Name()
+			if _, taken := want[name]; taken || m.builtin(name) {
+				u.shadowed = append(u.shadowed, info.Name)
+				continue
+			}
+			want[name] = tool
+			u.added = append(u.added, name)
+		}
+		sort.Strings(u.added)
+		sort.Strings(u.shadowed)
+	}
+
+	for name, tool := range m.added {
+		if w, ok := want[name]; !ok || w.client != tool.client {
+			m.tm.RemoveTool(name)
+			delete(m.added, name)
+		}
+	}
+	names := make([]string, 0, len(want))
+	for name := range want {
+		if _, ok := m.added[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m.tm.AddTool(want[name])
+		m.added[name] = want[name]
+	}
+
+	for _, u := range m.servers {
+		if len(u.added) > 0 || len(u.shadowed) > 0 {
+			m.logger.Printf("MCP server %s: %d tools added, %d shadowed %v", u.cfg.Name, len(u.added), len(u.shadowed), u.shadowed)
+		}
+	}
+}
+
+// builtin reports whether tm has a tool of that name the manager did not
+// add; the caller holds m.mu
+func (m *Manager) builtin(name string) bool {
+	_, ours := m.added[name]
+	return !ours && m.tm.GetTool(name) != nil
+}
+
+// find returns a configured server; the caller holds m.mu
+func (m *Manager) find(name string) (*upstream, bool) {
+	for _, u := range m.servers {
+		if u.cfg.Name == name {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// Enable connects to a server and adds its tools, taking over the names a
+// later server had meanwhile. A server already connected is left as is.
+func (m *Manager) Enable(ctx context.Context, name string) (ServerStatus, error) {
+	m.opMu.Lock()
+	defer m.opMu.Unlock()
+
+	m.mu.Lock()
+	u, ok := m.find(name)
+	if !ok {
+		m.mu.Unlock()
+		return ServerStatus{}, ErrServerNotFound
+	}
+	if u.enabled && u.connected() {
+		defer m.mu.Unlock()
+		return u.status(), nil
+	}
+	cfg, old := u.cfg, u.client
+	m.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	client, list, errText := m.connect(ctx, cfg)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u.client, u.tools, u.err = client, list, errText
+	u.enabled = client != nil
+	m.sync()
+	if client == nil {
+		return u.status(), errors.New(errText)
+	}
+	m.logger.Printf("Enabled MCP server %s", name)
+	return u.status(), nil
+}
+
+// Disable disconnects from a server and removes its tools, handing the
+// names it shadowed back to the next server that has them
+func (m *Manager) Disable(name string) (ServerStatus, error) {
+	m.opMu.Lock()
+	defer m.opMu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.find(name)
+	if !ok {
+		return ServerStatus{}, ErrServerNotFound
+	}
+	u.enabled = false
+	if u.client != nil {
+		u.client.Close()
+		u.client, u.tools = nil, nil
+	}
+	m.sync()
+	m.logger.Printf("Disabled MCP server %s", name)
+	return u.status(), nil
+}
+
+// status returns the state of u; the caller holds m.mu
+func (u *upstream) status() ServerStatus {
+	s := ServerStatus{
+		Name:      u.cfg.Name,
+		Namespace: u.cfg.ToolNamespace(),
+		Transport: "sse",
+		Enabled:   u.enabled,
+		Connected: u.connected(),
+		Tools:     append([]string{}, u.added...),
+		Shadowed:  append([]string(nil), u.shadowed...),
+		Error:     u.err,
+	}
+	if u.cfg.Command != "" {
+		s.Transport = "stdio"
+	}
+	return s
+}
+
+// Servers returns the state of every configured server, in configuration
+// order
+func (m *Manager) Servers() []ServerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ServerStatus, 0, len(m.servers))
+	for _, u := range m.servers {
+		out = append(out, u.status())
+	}
+	return out
+}
+
+// Server returns the state of a configured server
+func (m *Manager) Server(name string) (ServerStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.find(name)
+	if !ok {
+		return ServerStatus{}, false
+	}
+	return u.status(), true
+}
+
+// Clients returns the connected servers, in configuration order
+func (m *Manager) Clients() []*Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Client
+	for _, u := range m.servers {
+		if u.client != nil {
+			out = append(out, u.client)
+		}
+	}
+	return out
+}
+
+// Close disconnects from every server, stopping the stdio ones. It has the
+// signature of a shutdown step.
+func (m *Manager) Close(ctx context.Context, force bool) error {
+	m.mu.Lock()
+	var clients []*Client
+	for _, u := range m.servers {
+		if u.client != nil {
+			clients = append(clients, u.client)
+			u.client = nil
+		}
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			c.Close()
+		}(c)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
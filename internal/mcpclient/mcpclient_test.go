@@ -0,0 +1,233 @@
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/server/mcp"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// TestMain lets the test binary serve MCP over stdio, as the server
+// process of the stdio tests
+func TestMain(m *testing.M) {
+	if os.Getenv("MCPCLIENT_SERVE_STDIO") == "1" {
+		ctx := context.Background()
+		registry := agents.NewRegistry(ctx)
+		registry.RegisterAgent(&agents.Agent{ID: "coder", Name: "coder"})
+		server := mcp.NewServer(ctx, registry, config.MCPConfig{})
+		if err := server.ServeStdio(ctx, os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestConnectAllOverSSE(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	registry := agents.NewRegistry(ctx)
+	registry.RegisterAgent(&agents.Agent{ID: "coder", Name: "coder"})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	server := mcp.NewServer(ctx, registry, config.MCPConfig{Host: "127.0.0.1", Port: port})
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	tm := tools.NewToolManager()
+	tm.AddTool(tools.NewGitTool(""))
+	m := ConnectAll(ctx, []config.MCPServerConfig{
+		{Name: "fleet", URL: fmt.Sprintf("http://127.0.0.1:%d/sse", port)},
+		{Name: "off", URL: "http://127.0.0.1:1/sse", Disabled: true},
+		{Name: "gone", URL: "http://127.0.0.1:1/sse", Timeout: 1},
+	}, tm)
+	defer m.Close(ctx, false)
+
+	if n := len(m.Clients()); n != 1 {
+		t.Fatalf("connected to %d servers", n)
+	}
+	if v := m.Clients()[0].ProtocolVersion(); v != ProtocolVersion {
+		t.Errorf("negotiated protocol %q", v)
+	}
+	tool, ok := tm.GetTool("fleet.get_agent").(*Tool)
+	if !ok || tool.Server() != "fleet" || tool.RemoteName() != "get_agent" {
+		t.Fatalf("fleet.get_agent = %v", tm.GetTool("fleet.get_agent"))
+	}
+	out, err := tm.ExecuteByName(ctx, "fleet.get_agent", "coder")
+	if err != nil || !strings.Contains(out, `"coder"`) {
+		t.Errorf("fleet.get_agent coder = %q, %v", out, err)
+	}
+	if _, err := tm.ExecuteByName(ctx, "fleet.get_agent", `{"agent_id": "missing"}`); err == nil || !strings.Contains(err.Error(), "fleet.get_agent") {
+		t.Errorf("a failed call should be an error: %v", err)
+	}
+}
+
+func TestManager_Namespaces(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	registry := agents.NewRegistry(ctx)
+	registry.RegisterAgent(&agents.Agent{ID: "coder", Name: "coder"})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	server := mcp.NewServer(ctx, registry, config.MCPConfig{Host: "127.0.0.1", Port: port})
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	url := fmt.Sprintf("http://127.0.0.1:%d/sse", port)
+
+	// A built-in tool keeps its name, and of two servers in the same
+	// namespace the first one declared has the others
+	tm := tools.NewToolManager()
+	tm.AddTool(builtinTool{})
+	m := ConnectAll(ctx, []config.MCPServerConfig{
+		{Name: "fleet", URL: url, Disabled: true},
+		{Name: "mirror", Namespace: "fleet", URL: url},
+		{Name: "copy", URL: url},
+	}, tm)
+	defer m.Close(ctx, false)
+
+	mirror, _ := m.Server("mirror")
+	if !mirror.Connected || !contains(mirror.Tools, "fleet.get_agent") || !contains(mirror.Shadowed, "list_agents") {
+		t.Fatalf("mirror: %+v", mirror)
+	}
+	if _, ok := tm.GetTool("fleet.list_agents").(builtinTool); !ok {
+		t.Fatal("a server took the name of a built-in tool")
+	}
+	if tm.GetTool("copy.get_agent") == nil {
+		t.Fatal("copy.get_agent is missing")
+	}
+
+	// Enabling the first server hands it the names of the namespace
+	fleet, err := m.Enable(ctx, "fleet")
+	if err != nil || !fleet.Enabled || !contains(fleet.Tools, "fleet.get_agent") {
+		t.Fatalf("Enable(fleet) = %+v, %v", fleet, err)
+	}
+	if tool, ok := tm.GetTool("fleet.get_agent").(*Tool); !ok || tool.Server() != "fleet" {
+		t.Fatalf("fleet.get_agent = %v", tm.GetTool("fleet.get_agent"))
+	}
+	if mirror, _ := m.Server("mirror"); len(mirror.Tools) != 0 || !contains(mirror.Shadowed, "get_agent") {
+		t.Errorf("mirror after enabling fleet: %+v", mirror)
+	}
+
+	// and disabling it gives them back
+	if fleet, err := m.Disable("fleet"); err != nil || fleet.Enabled || fleet.Connected {
+		t.Fatalf("Disable(fleet) = %+v, %v", fleet, err)
+	}
+	if tool, ok := tm.GetTool("fleet.get_agent").(*Tool); !ok || tool.Server() != "mirror" {
+		t.Fatalf("fleet.get_agent after Disable = %v", tm.GetTool("fleet.get_agent"))
+	}
+	if _, err := m.Disable("copy"); err != nil || tm.GetTool("copy.get_agent") != nil {
+		t.Errorf("copy.get_agent after Disable = %v, %v", tm.GetTool("copy.get_agent"), err)
+	}
+	if _, err := m.Enable(ctx, "missing"); !errors.Is(err, ErrServerNotFound) {
+		t.Errorf("Enable(missing) = %v", err)
+	}
+	if got := len(m.Servers()); got != 3 {
+		t.Errorf("Servers() has %d servers", got)
+	}
+}
+
+// builtinTool is a built-in tool named like a tool of the MCP server
+type builtinTool struct{}
+
+func (builtinTool) Name() string                 { return "fleet.list_agents" }
+func (builtinTool) Description() string          { return "Lists agents" }
+func (builtinTool) CanHandle(intent string) bool { return false }
+func (builtinTool) Execute(ctx context.Context, input string) (string, error) {
+	return "", nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConnectOverStdio(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := Connect(ctx, config.MCPServerConfig{
+		Name:    "self",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^$"},
+		Env:     map[string]string{"MCPCLIENT_SERVE_STDIO": "1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := c.ListTools(ctx)
+	if err != nil || len(list) == 0 {
+		t.Fatalf("tools/list: %d tools, %v", len(list), err)
+	}
+	result, err := c.CallTool(ctx, "list_agents", nil)
+	if err != nil || result.IsError || !strings.Contains(result.Text(), "coder") {
+		t.Fatalf("list_agents = %+v, %v", result, err)
+	}
+
+	c.Close()
+	select {
+	case <-c.Done():
+	case <-ctx.Done():
+		t.Fatal("the connection did not end after Close")
+	}
+	if _, err := c.ListTools(ctx); err == nil {
+		t.Error("expected an error after Close")
+	}
+}
+
+func TestArguments(t *testing.T) {
+	tool := NewTool(&Client{name: "fs"}, ToolInfo{
+		Name: "read_file",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path":     map[string]interface{}{"type": "string"},
+				"encoding": map[string]interface{}{"type": "string"},
+			},
+			"required": []interface{}{"path"},
+		},
+	})
+	if args, err := tool.arguments("/etc/hosts"); err != nil || args["path"] != "/etc/hosts" {
+		t.Errorf("plain text = %v, %v", args, err)
+	}
+	if args, err := tool.arguments(`{"path": "a", "encoding": "utf8"}`); err != nil || args["encoding"] != "utf8" {
+		t.Errorf("JSON = %v, %v", args, err)
+	}
+
+	many := NewTool(&Client{name: "db"}, ToolInfo{
+		Name: "query",
+		InputSchema: map[string]interface{}{
+			"properties": map[string]interface{}{
+				"sql":   map[string]interface{}{"type": "string"},
+				"limit": map[string]interface{}{"type": "integer"},
+			},
+			"required": []interface{}{"sql", "limit"},
+		},
+	})
+	if _, err := many.arguments("select 1"); err == nil || !strings.Contains(err.Error(), "limit, sql") {
+		t.Errorf("several required parameters: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tool is a tool of an external MCP server, usable as a tools.Tool under
+// the server's namespace, as in "fs.read_file"
+type Tool struct {
+	client *Client
+	info   ToolInfo
+}
+
+// NewTool wraps a tool listed by c
+func NewTool(c *Client, info ToolInfo) *Tool {
+	return &Tool{client: c, info: info}
+}
+
+// Name implements tools.Tool: the name of the tool on its server, in the
+// server's namespace
+func (t *Tool) Name() string {
+	return t.client.Namespace() + "." + t.info.Name
+}
+
+// RemoteName returns the name of the tool on its server
+func (t *Tool) RemoteName() string {
+	return t.info.Name
+}
+
+// Description implements tools.Tool
+func (t *Tool) Description() string {
+	if t.info.Description == "" {
+		return fmt.Sprintf("Tool %s of MCP server %s", t.info.Name, t.client.Name())
+	}
+	return t.info.Description
+}
+
+// Server returns the name of the tool's server in the configuration
+func (t *Tool) Server() string {
+	return t.client.Name()
+}
+
+// InputSchema returns the JSON schema of the tool's arguments
+func (t *Tool) InputSchema() map[string]interface{} {
+	return t.info.InputSchema
+}
+
+// CanHandle implements tools.Tool: an intent naming the tool
+func (t *Tool) CanHandle(intent string) bool {
+	return strings.Contains(strings.ToLower(intent), strings.ToLower(t.info.Name))
+}
+
+// Execute implements tools.Tool. input is either a JSON object of
+// arguments or, for a tool taking a single string, that string. A result
+// the server flags as an error is returned as one.
+func (t *Tool) Execute(ctx context.Context, input string) (string, error) {
+	args, err := t.arguments(input)
+	if err != nil {
+		return "", err
+	}
+	result, err := t.client.CallTool(ctx, t.info.Name, args)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", t.Name(), err)
+	}
+	if result.IsError {
+		return "", fmt.Errorf("%s: %s", t.Name(), result.Text())
+	}
+	return result.Text(), nil
+}
+
+// arguments turns input into the tool's arguments
+func (t *Tool) arguments(input string) (map[string]interface{}, error) {
+	input = strings.TrimSpace(input)
+	if strings.HasPrefix(input, "{") {
+		var args map[string]interface{}
+		if err := json.Unmarshal([]byte(input), &args); err != nil {
+			return nil, fmt.Errorf("%s: invalid JSON arguments: %w", t.Name(), err)
+		}
+		return args, nil
+	}
+
+	props, _ := t.info.InputSchema["properties"].(map[string]interface{})
+	if param, ok := stringParam(props, required(t.info.InputSchema)); ok {
+		return map[string]interface{}{param: input}, nil
+	}
+	if input == "" && len(required(t.info.InputSchema)) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("%s takes a JSON object of arguments (%s)", t.Name(), strings.Join(names, ", "))
+}
+
+// stringParam finds the parameter plain text goes to: the only required
+// parameter, or else the only parameter, when it is a string
+func stringParam(props map[string]interface{}, req []string) (string, bool) {
+	var candidate string
+	switch {
+	case len(req) == 1:
+		candidate = req[0]
+	case len(req) == 0 && len(props) == 1:
+		for name := range props {
+			candidate = name
+		}
+	default:
+		return "", false
+	}
+	prop, _ := props[candidate].(map[string]interface{})
+	typ, _ := prop["type"].(string)
+	return candidate, typ == "string"
+}
+
+// required lists the required parameters of a schema
+func required(schema map[string]interface{}) []string {
+	list, _ := schema["required"].([]interface{})
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,268 @@
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// maxMessageSize bounds one message from a server
+const maxMessageSize = 16 << 20
+
+// stopGrace is how long a stdio server has to exit after its stdin closes
+const stopGrace = 2 * time.Second
+
+// stdioTransport talks to a server process over its stdin and stdout, one
+// message per line
+type stdioTransport struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	out    chan []byte
+	exited chan struct{}
+
+	writeMu sync.Mutex
+	once    sync.Once
+}
+
+// startStdio starts the command of cfg; its stderr goes to the log
+func startStdio(cfg config.MCPServerConfig, logger *log.Logger) (*stdioTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stderr = &stderrLog{logger: logger, name: cfg.Name}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	t := &stdioTransport{cmd: cmd, stdin: stdin, out: make(chan []byte, 16), exited: make(chan struct{})}
+	go func() {
+		defer close(t.out)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64<<10), maxMessageSize)
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				t.out <- append([]byte(nil), line...)
+			}
+		}
+		cmd.Wait()
+		close(t.exited)
+	}()
+	return t, nil
+}
+
+func (t *stdioTransport) send(ctx context.Context, data []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err := t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) incoming() <-chan []byte {
+	return t.out
+}
+
+// close closes the server's stdin, which asks it to exit, and kills it
+// if it is still running after stopGrace
+func (t *stdioTransport) close() error {
+	t.once.Do(func() {
+		t.stdin.Close()
+		select {
+		case <-t.exited:
+		case <-time.After(stopGrace):
+			t.cmd.Process.Kill()
+		}
+	})
+	return nil
+}
+
+// stderrLog logs what a stdio server writes to stderr, line by line
+type stderrLog struct {
+	logger *log.Logger
+	name   string
+	buf    []byte
+}
+
+func (w *stderrLog) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := strings.TrimSpace(string(w.buf[:i])); line != "" {
+			w.logger.Printf("MCP server %s: %s", w.name, line)
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// sseTransport talks to a server over the HTTP+SSE transport: messages
+// come as events of one long GET, and go as POSTs to the URL the first
+// event names
+type sseTransport struct {
+	client   *http.Client
+	headers  map[string]string
+	endpoint string
+	out      chan []byte
+	cancel   context.CancelFunc
+}
+
+// dialSSE opens the event stream of cfg.URL and waits for the endpoint
+// event until ctx is done
+func dialSSE(ctx context.Context, cfg config.MCPServerConfig) (*sseTransport, error) {
+	base, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	// The stream outlives ctx, which only bounds the handshake
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, cfg.URL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	t := &sseTransport{client: &http.Client{}, headers: cfg.Headers, out: make(chan []byte, 16), cancel: cancel}
+	type dialed struct {
+		resp *http.Response
+		err  error
+	}
+	respCh := make(chan dialed, 1)
+	go func() {
+		resp, err := t.client.Do(req)
+		respCh <- dialed{resp, err}
+	}()
+	var resp *http.Response
+	select {
+	case d := <-respCh:
+		if d.err != nil {
+			cancel()
+			return nil, d.err
+		}
+		resp = d.resp
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("GET %s: %s", cfg.URL, resp.Status)
+	}
+
+	endpoint := make(chan string, 1)
+	go t.readEvents(resp.Body, endpoint)
+	select {
+	case e, ok := <-endpoint:
+		if !ok {
+			cancel()
+			return nil, fmt.Errorf("the stream ended before the endpoint event")
+		}
+		ref, err := url.Parse(e)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("invalid endpoint %q: %w", e, err)
+		}
+		t.endpoint = base.ResolveReference(ref).String()
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	}
+	return t, nil
+}
+
+// readEvents parses the event stream: the endpoint event goes to
+// endpoint, messages to t.out
+func (t *sseTransport) readEvents(body io.ReadCloser, endpoint chan<- string) {
+	defer body.Close()
+	defer close(t.out)
+	sentEndpoint := false
+	defer func() {
+		if !sentEndpoint {
+			close(endpoint)
+		}
+	}()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxMessageSize)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			payload := strings.Join(data, "\n")
+			switch {
+			case event == "endpoint" && !sentEndpoint:
+				endpoint <- payload
+				sentEndpoint = true
+			case (event == "" || event == "message") && payload != "":
+				t.out <- []byte(payload)
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment, sent to keep the connection alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+func (t *sseTransport) send(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("POST %s: %s", t.endpoint, resp.Status)
+	}
+	return nil
+}
+
+func (t *sseTransport) incoming() <-chan []byte {
+	return t.out
+}
+
+func (t *sseTransport) close() error {
+	t.cancel()
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package metrics is a small Prometheus-compatible metrics registry:
+// counters, gauges and histograms with labels, rendered in the text
+// exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are latency buckets, in seconds, suited to API requests
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ProviderBuckets are latency buckets, in seconds, suited to model calls
+var ProviderBuckets = []float64{.25, .5, 1, 2.5, 5, 10, 20, 30, 60, 120}
+
+// Default is the process-wide registry
+var Default = NewRegistry()
+
+// Registry holds metric families in registration order
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+	byName   map[string]*family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]*family)}
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string // counter, gauge, histogram
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64  // counter and gauge
+	counts      []uint64 // histogram, per bucket (not cumulative)
+	sum         float64  // histogram
+	count       uint64   // histogram
+}
+
+func (r *Registry) register(name, help, kind string, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.byName[name]; ok {
+		if f.kind != kind || strings.Join(f.labels, ",") != strings.Join(labels, ",") {
+			panic(fmt.Sprintf("metrics: %s registered twice with different definitions", name))
+		}
+		return f
+	}
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families = append(r.families, f)
+	r.byName[name] = f
+	return f
+}
+
+// with returns the series for labelValues, creating it; the caller holds
+// f.mu
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter is a monotonically increasing value per label set
+type Counter struct{ f *family }
+
+// NewCounter registers a counter
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(name, help, "counter", nil, labels)}
+}
+
+// Inc adds one
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.f.mu.Lock()
+	c.f.with(labelValues).value += v
+	c.f.mu.Unlock()
+}
+
+// Gauge is a value that can go up and down per label set
+type Gauge struct{ f *family }
+
+// NewGauge registers a gauge
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, "gauge", nil, labels)}
+}
+
+// Set sets the gauge
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.with(labelValues).value = v
+	g.f.mu.Unlock()
+}
+
+// Add adds v, which may be negative
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.with(labelValues).value += v
+	g.f.mu.Unlock()
+}
+
+// Histogram counts observations into buckets per label set
+type Histogram struct{ f *family }
+
+// NewHistogram registers a histogram with ascending bucket upper bounds
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{r.register(name, help, "histogram", buckets, labels)}
+}
+
+// Observe records one value
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+
+	s := h.f.with(labelValues)
+	i := sort.SearchFloat64s(h.f.buckets, v)
+	s.counts[i]++
+	s.sum += v
+	s.count++
+}
+
+// WriteText writes every family in the Prometheus text format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := append([]*family(nil), r.families...)
+	r.mu.Unlock()
+
+	for _, f := range families {
+		if err := f.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *family) write(w io.Writer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.series) == 0 && len(f.labels) > 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+
+	if len(f.series) == 0 {
+		// Unlabelled metrics are reported as zero before first use
+		f.with(nil)
+	}
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := f.series[k]
+		if f.kind != "histogram" {
+			fmt.Fprintf(&b, "%s%s %s\n", f.name, labelString(f.labels, s.labelValues, "", ""), formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, labelString(f.labels, s.labelValues, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, labelString(f.labels, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(&b, "%s_sum%s %s\n", f.name, labelString(f.labels, s.labelValues, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(&b, "%s_count%s %d\n", f.name, labelString(f.labels, s.labelValues, "", ""), s.count)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func labelString(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+1)
+	for i, n := range names {
+		pairs = append(pairs, n+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func escapeHelp(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
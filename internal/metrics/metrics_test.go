@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Requests.", "method")
+	g := r.NewGauge("up", "Up.")
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	r.NewCounter("unused_total", "Never incremented.", "kind")
+
+	c.Inc("GET")
+	c.Add(2, "GET")
+	c.Inc(`P"O\ST`)
+	g.Set(1)
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a")
+	h.Observe(3, "/a")
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE requests_total counter\n",
+		`requests_total{method="GET"} 3` + "\n",
+		`requests_total{method="P\"O\\ST"} 1` + "\n",
+		"# TYPE up gauge\nup 1\n",
+		`latency_seconds_bucket{route="/a",le="0.1"} 2` + "\n",
+		`latency_seconds_bucket{route="/a",le="1"} 2` + "\n",
+		`latency_seconds_bucket{route="/a",le="+Inf"} 3` + "\n",
+		`latency_seconds_count{route="/a"} 3` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "unused_total") {
+		t.Error("labelled metric without series was written")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	r := NewRegistry()
+	a := r.NewCounter("x_total", "X.", "k")
+	b := r.NewCounter("x_total", "X.", "k")
+	a.Inc("v")
+	b.Inc("v")
+
+	var out strings.Builder
+	r.WriteText(&out)
+	if !strings.Contains(out.String(), `x_total{k="v"} 2`) {
+		t.Fatalf("re-registration did not share the family:\n%s", out.String())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("conflicting registration did not panic")
+		}
+	}()
+	r.NewGauge("x_total", "X.", "k")
+}
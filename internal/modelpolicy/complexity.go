@@ -0,0 +1,109 @@
+package modelpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// similarityThreshold is the keyword overlap above which two tasks count
+// as similar
+const similarityThreshold = 0.3
+
+// wordsForFullLength is the description length, in words, that alone
+// reaches the length component's maximum
+const wordsForFullLength = 400
+
+// labelWeights raise or lower the complexity of tasks carrying a label
+var labelWeights = map[string]float64{
+	"architecture":  0.3,
+	"design":        0.2,
+	"refactor":      0.2,
+	"security":      0.25,
+	"performance":   0.2,
+	"concurrency":   0.25,
+	"migration":     0.2,
+	"bug":           0.1,
+	"feature":       0.1,
+	"docs":          -0.15,
+	"documentation": -0.15,
+	"typo":          -0.2,
+	"chore":         -0.1,
+	"test":          -0.05,
+}
+
+// stopWords are left out of the keywords compared between tasks
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true,
+	"that": true, "this": true, "into": true, "when": true, "add": true,
+	"use": true, "are": true, "not": true, "all": true, "its": true,
+}
+
+// Estimate rates how hard a task is, from 0 to 1, and explains the
+// rating. Longer descriptions and labels such as "architecture" or
+// "security" raise it; labels such as "docs" lower it.
+func Estimate(task *agents.Task) (float64, []string) {
+	words := len(strings.Fields(task.Title)) + len(strings.Fields(task.Description))
+	length := 0.4 * float64(words) / wordsForFullLength
+	if length > 0.4 {
+		length = 0.4
+	}
+	complexity := 0.1 + length
+	reasons := []string{fmt.Sprintf("%d words of title and description", words)}
+
+	for _, label := range task.Labels {
+		if w, ok := labelWeights[strings.ToLower(label)]; ok {
+			complexity += w
+			reasons = append(reasons, fmt.Sprintf("label %q %+.2f", label, w))
+		}
+	}
+	if task.Priority >= agents.PriorityUrgent {
+		complexity += 0.1
+		reasons = append(reasons, "urgent priority +0.10")
+	}
+
+	switch {
+	case complexity < 0:
+		complexity = 0
+	case complexity > 1:
+		complexity = 1
+	}
+	return complexity, reasons
+}
+
+// keywords returns the distinct words of a task's title, description and
+// labels that are long enough to tell tasks apart
+func keywords(task *agents.Task) map[string]bool {
+	words := make(map[string]bool)
+	add := func(s string) {
+		for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len(w) >= 3 && !stopWords[w] {
+				words[w] = true
+			}
+		}
+	}
+	add(task.Title)
+	add(task.Description)
+	for _, l := range task.Labels {
+		add(l)
+	}
+	return words
+}
+
+// similarity is the Jaccard index of two keyword sets
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,307 @@
+// Package modelpolicy picks the model a task runs on: the cheapest tier
+// expected to handle the task's estimated complexity, moving up a tier
+// each time the task fails.
+package modelpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// DefaultTiers is the ladder of free OpenRouter models used when the
+// configuration lists none, cheapest first
+var DefaultTiers = []config.ModelTier{
+	{Model: "meta-llama/llama-3.2-3b-instruct:free", Cost: 1, MaxComplexity: 0.25},
+	{Model: "mistralai/mistral-small-3.1-24b-instruct:free", Cost: 2, MaxComplexity: 0.5},
+	{Model: "meta-llama/llama-3.3-70b-instruct:free", Cost: 4, MaxComplexity: 0.75},
+	{Model: "qwen/qwen3-coder:free", Cost: 8, MaxComplexity: 0.9},
+	{Model: "deepseek/deepseek-r1-0528:free", Cost: 16, MaxComplexity: 1},
+}
+
+// maxOutcomes bounds the outcomes kept for finding similar tasks
+const maxOutcomes = 500
+
+// DefaultCooldown is how long a rate-limited model is skipped
+const DefaultCooldown = time.Minute
+
+// ErrExhausted is returned by Execute when every allowed tier failed
+var ErrExhausted = errors.New("no stronger model left to try")
+
+// Decision is the model chosen for a task and why
+type Decision struct {
+	Model      string   `json:"model"`
+	Tier       int      `json:"tier"`
+	Cost       float64  `json:"cost"`
+	Complexity float64  `json:"complexity"`
+	Escalation int      `json:"escalation"`
+	Reasons    []string `json:"reasons"`
+}
+
+// outcome is how a model did on a finished task
+type outcome struct {
+	task     string
+	keywords map[string]bool
+	tier     int
+	success  bool
+	// executed marks the outcomes of Execute not yet seen by Run
+	executed bool
+}
+
+// Policy selects models for tasks. It is safe for concurrent use.
+type Policy struct {
+	tiers          []config.ModelTier
+	maxEscalations int
+	cooldown       time.Duration
+
+	mu       sync.Mutex
+	outcomes []outcome
+	next     int
+	limited  map[string]time.Time // model -> end of cooldown
+	now      func() time.Time
+}
+
+// NewPolicy builds a policy from the configuration, sorting its tiers by
+// cost
+func NewPolicy(cfg config.ModelPolicyConfig) *Policy {
+	tiers := cfg.Tiers
+	if len(tiers) == 0 {
+		tiers = DefaultTiers
+	}
+	tiers = append([]config.ModelTier(nil), tiers...)
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].Cost < tiers[j].Cost })
+	return &Policy{
+		tiers:          tiers,
+		maxEscalations: cfg.MaxEscalations,
+		cooldown:       DefaultCooldown,
+		limited:        make(map[string]time.Time),
+		now:            time.Now,
+	}
+}
+
+// Tiers returns the model ladder, cheapest first
+func (p *Policy) Tiers() []config.ModelTier {
+	return append([]config.ModelTier(nil), p.tiers...)
+}
+
+// Select picks the model for a task's first attempt
+func (p *Policy) Select(task *agents.Task) Decision {
+	return p.Escalate(task, 0)
+}
+
+// Escalate picks the model for a task that has already failed
+// escalation times; 0 is the first attempt
+func (p *Policy) Escalate(task *agents.Task, escalation int) Decision {
+	return p.decide(task, escalation, 0)
+}
+
+// decide picks a tier no lower than minTier
+func (p *Policy) decide(task *agents.Task, escalation, minTier int) Decision {
+	complexity, reasons := Estimate(task)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tier := p.tierFor(complexity)
+	reasons = append(reasons, fmt.Sprintf("complexity %.2f fits tier %d", complexity, tier))
+	if t, reason, ok := p.fromHistory(task, tier); ok {
+		tier = t
+		reasons = append(reasons, reason)
+	}
+	if escalation > 0 {
+		tier += escalation
+		reasons = append(reasons, fmt.Sprintf("escalated %d tier(s) after failures", escalation))
+	}
+	if tier < minTier {
+		tier = minTier
+		reasons = append(reasons, fmt.Sprintf("tier %d failed", minTier-1))
+	}
+	if tier >= len(p.tiers) {
+		tier = len(p.tiers) - 1
+	}
+
+	// Skip models that are cooling down after a rate limit, preferring a
+	// stronger one so the task is not set back
+	now := p.now()
+	for t := tier; t < len(p.tiers); t++ {
+		if until, ok := p.limited[p.tiers[t].Model]; !ok || now.After(until) {
+			if t != tier {
+				reasons = append(reasons, fmt.Sprintf("%s is rate limited", p.tiers[tier].Model))
+			}
+			tier = t
+			break
+		}
+	}
+
+	return Decision{
+		Model:      p.tiers[tier].Model,
+		Tier:       tier,
+		Cost:       p.tiers[tier].Cost,
+		Complexity: complexity,
+		Escalation: escalation,
+		Reasons:    reasons,
+	}
+}
+
+// tierFor returns the cheapest tier rated for the complexity
+func (p *Policy) tierFor(complexity float64) int {
+	for i, t := range p.tiers {
+		if complexity <= t.MaxComplexity {
+			return i
+		}
+	}
+	return len(p.tiers) - 1
+}
+
+// fromHistory adjusts the starting tier using similar finished tasks: a
+// similar task that succeeded on a cheaper tier lowers it, one that failed
+// on this tier or above raises it past the failure
+func (p *Policy) fromHistory(task *agents.Task, tier int) (int, string, bool) {
+	words := keywords(task)
+	if len(words) == 0 {
+		return tier, "", false
+	}
+	cheapest, failedAt := -1, -1
+	for _, o := range p.outcomes {
+		if o.keywords == nil || similarity(words, o.keywords) < similarityThreshold {
+			continue
+		}
+		if o.success && (cheapest < 0 || o.tier < cheapest) {
+			cheapest = o.tier
+		}
+		if !o.success && o.tier > failedAt {
+			failedAt = o.tier
+		}
+	}
+	switch {
+	case failedAt >= tier:
+		if failedAt+1 >= len(p.tiers) {
+			return tier, "", false
+		}
+		return failedAt + 1, fmt.Sprintf("a similar task failed on tier %d", failedAt), true
+	case cheapest >= 0 && cheapest < tier:
+		return cheapest, fmt.Sprintf("a similar task succeeded on tier %d", cheapest), true
+	}
+	return tier, "", false
+}
+
+// Record remembers how a model did on a task, for choosing models for
+// similar tasks. Models outside the ladder are ignored.
+func (p *Policy) Record(task *agents.Task, model string, success bool) {
+	p.record(task, model, success, false)
+}
+
+func (p *Policy) record(task *agents.Task, model string, success, executed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tier := p.tierOf(model)
+	if tier < 0 {
+		return
+	}
+	o := outcome{task: task.ID, keywords: keywords(task), tier: tier, success: success, executed: executed}
+	if len(p.outcomes) < maxOutcomes {
+		p.outcomes = append(p.outcomes, o)
+		return
+	}
+	p.outcomes[p.next] = o
+	p.next = (p.next + 1) % maxOutcomes
+}
+
+// RateLimited skips a model for the cooldown, so that tasks spread onto
+// other tiers instead of queueing behind an exhausted quota
+func (p *Policy) RateLimited(model string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.limited[model] = p.now().Add(p.cooldown)
+}
+
+func (p *Policy) tierOf(model string) int {
+	for i, t := range p.tiers {
+		if t.Model == model {
+			return i
+		}
+	}
+	return -1
+}
+
+// Execute runs a task, calling try with the selected model and retrying
+// on a stronger model each time it fails, up to the configured number of
+// escalations. Every attempt is recorded, except those refused with a
+// rate limit, which put the model on cooldown instead. The returned decision is that of
+// the last attempt.
+func (p *Policy) Execute(ctx context.Context, task *agents.Task, try func(ctx context.Context, model string) error) (Decision, error) {
+	var d Decision
+	var err error
+	failed := -1
+	for attempt := 0; attempt <= p.maxEscalations; attempt++ {
+		if failed == len(p.tiers)-1 {
+			break
+		}
+		// The failure just recorded already steers similar tasks, this one
+		// included, past its tier; minTier makes sure of it
+		d = p.decide(task, 0, failed+1)
+		d.Escalation = attempt
+		err = try(ctx, d.Model)
+		if _, limited := ai.AsRateLimit(err); limited {
+			// Out of quota says nothing about what the model can do
+			p.RateLimited(d.Model)
+		} else {
+			p.record(task, d.Model, err == nil, true)
+		}
+		if err == nil || ctx.Err() != nil {
+			return d, err
+		}
+		failed = d.Tier
+	}
+	return d, fmt.Errorf("%w: %v", ErrExhausted, err)
+}
+
+// seen reports whether the last outcome of a task was recorded by
+// Execute, marking it seen so that a later run of the task counts again
+func (p *Policy) seen(taskID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.outcomes {
+		// Newest first; once full, the ring's newest entry is before next
+		j := (len(p.outcomes) - 1 - i + p.next) % len(p.outcomes)
+		if p.outcomes[j].task != taskID {
+			continue
+		}
+		executed := p.outcomes[j].executed
+		p.outcomes[j].executed = false
+		return executed
+	}
+	return false
+}
+
+// Run records the outcome of every task that finishes with a result
+// naming its model, until ctx is done or events is closed. A failed run
+// that will be retried is not an outcome yet, and the runs made through
+// Execute were recorded attempt by attempt.
+func (p *Policy) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type != agents.EventTaskCompleted && e.Type != agents.EventTaskFailed || !e.Final() {
+				continue
+			}
+			task, ok := e.Data["task"].(agents.Task)
+			if !ok || task.Result == nil || task.Result.Model == "" || p.seen(task.ID) {
+				continue
+			}
+			p.Record(&task, task.Result.Model, task.Result.Success)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package modelpolicy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestSelectByComplexity(t *testing.T) {
+	p := NewPolicy(config.ModelPolicyConfig{})
+
+	easy := p.Select(&agents.Task{Title: "Fix typo in README", Labels: []string{"docs"}})
+	if easy.Tier != 0 {
+		t.Errorf("docs typo picked tier %d (%v), want 0", easy.Tier, easy.Reasons)
+	}
+
+	hard := p.Select(&agents.Task{
+		Title:       "Redesign the scheduler",
+		Description: strings.Repeat("word ", 300),
+		Labels:      []string{"architecture", "concurrency"},
+		Priority:    agents.PriorityUrgent,
+	})
+	if hard.Tier != len(DefaultTiers)-1 {
+		t.Errorf("architecture task picked tier %d (complexity %.2f), want the strongest", hard.Tier, hard.Complexity)
+	}
+}
+
+func TestSimilarTasksShiftTheStartingTier(t *testing.T) {
+	p := NewPolicy(config.ModelPolicyConfig{})
+	task := &agents.Task{Title: "Add retry to webhook delivery", Labels: []string{"feature"}}
+	start := p.Select(task).Tier
+
+	p.Record(&agents.Task{Title: "Add retry to webhook delivery client", Labels: []string{"feature"}}, DefaultTiers[start].Model, false)
+	if got := p.Select(task).Tier; got != start+1 {
+		t.Errorf("after a similar failure picked tier %d, want %d", got, start+1)
+	}
+
+	p = NewPolicy(config.ModelPolicyConfig{})
+	p.Record(&agents.Task{Title: "Add retry to webhook delivery", Labels: []string{"feature"}}, DefaultTiers[0].Model, true)
+	if start > 0 {
+		if got := p.Select(task).Tier; got != 0 {
+			t.Errorf("after a similar success on tier 0 picked tier %d", got)
+		}
+	}
+
+	// Unrelated tasks do not count
+	p = NewPolicy(config.ModelPolicyConfig{})
+	p.Record(&agents.Task{Title: "Translate the landing page"}, DefaultTiers[start].Model, false)
+	if got := p.Select(task).Tier; got != start {
+		t.Errorf("an unrelated failure moved the pick to tier %d", got)
+	}
+}
+
+func TestExecuteEscalates(t *testing.T) {
+	p := NewPolicy(config.ModelPolicyConfig{MaxEscalations: 2})
+	task := &agents.Task{Title: "Fix typo", Labels: []string{"docs"}}
+
+	var tried []string
+	d, err := p.Execute(context.Background(), task, func(ctx context.Context, model string) error {
+		tried = append(tried, model)
+		if len(tried) < 3 {
+			return errors.New("wrong answer")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{DefaultTiers[0].Model, DefaultTiers[1].Model, DefaultTiers[2].Model}
+	if strings.Join(tried, ",") != strings.Join(want, ",") {
+		t.Errorf("tried %v, want %v", tried, want)
+	}
+	if d.Tier != 2 || d.Escalation != 2 {
+		t.Errorf("decision = %+v", d)
+	}
+
+	tried = nil
+	_, err = p.Execute(context.Background(), &agents.Task{Title: "Unrelated chore"}, func(ctx context.Context, model string) error {
+		tried = append(tried, model)
+		return errors.New("down")
+	})
+	if !errors.Is(err, ErrExhausted) || len(tried) != 3 {
+		t.Errorf("err = %v after %d attempts, want ErrExhausted after 3", err, len(tried))
+	}
+}
+
+func TestRunSkipsRunsOfExecute(t *testing.T) {
+	p := NewPolicy(config.ModelPolicyConfig{MaxEscalations: 1})
+	task := &agents.Task{ID: "t1", Title: "Fix typo", Labels: []string{"docs"}}
+	failed := false
+	d, _ := p.Execute(context.Background(), task, func(ctx context.Context, model string) error {
+		if !failed {
+			failed = true
+			return errors.New("wrong answer")
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan agents.Event)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Run(ctx, events)
+	}()
+	finished := *task
+	finished.Result = &agents.TaskResult{Success: true, Model: d.Model}
+	ev := agents.Event{Type: agents.EventTaskCompleted, TaskID: task.ID, Data: map[string]interface{}{"task": finished}}
+	events <- ev
+	// A later run of the task outside Execute counts again
+	events <- ev
+	close(events)
+	<-done
+
+	if len(p.outcomes) != 3 {
+		t.Fatalf("%d outcomes recorded, want the 2 attempts and the later run", len(p.outcomes))
+	}
+}
+
+func TestRateLimitedModelIsSkipped(t *testing.T) {
+	p := NewPolicy(config.ModelPolicyConfig{})
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	task := &agents.Task{Title: "Fix typo", Labels: []string{"docs"}}
+
+	p.RateLimited(DefaultTiers[0].Model)
+	if got := p.Select(task).Tier; got != 1 {
+		t.Errorf("picked tier %d while tier 0 is rate limited", got)
+	}
+	now = now.Add(DefaultCooldown + time.Second)
+	if got := p.Select(task).Tier; got != 0 {
+		t.Errorf("picked tier %d after the cooldown", got)
+	}
+}
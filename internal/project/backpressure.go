@@ -0,0 +1,76 @@
+package project
+
+import (
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// Backpressure is whether the integration holds off pulling work because
+// the registry already has more waiting than agents can take on soon
+type Backpressure struct {
+	// Active is set while the queue is over the threshold: polls are
+	// skipped and tasks the project manager pushes wait for capacity
+	Active bool `json:"active"`
+	// QueueDepth counts the tasks waiting for an agent, pending or queued
+	QueueDepth int `json:"queue_depth"`
+	// Threshold is project.max_pending_tasks; 0 means never
+	Threshold int `json:"threshold"`
+	// Since is when backpressure last became active
+	Since *time.Time `json:"since,omitempty"`
+	// SkippedPolls counts the polls skipped while active
+	SkippedPolls int `json:"skipped_polls"`
+	// Waiting lists the pushed tasks held until there is capacity
+	Waiting []string `json:"waiting_for_capacity"`
+}
+
+// queueDepth counts the registry's tasks that wait for an agent
+func queueDepth(registry *agents.Registry) int {
+	_, tasks := registry.StatusCounts()
+	return tasks[agents.TaskStatusPending] + tasks[agents.TaskStatusQueued]
+}
+
+// underPressure reports whether the queue is over the threshold, logging
+// when that changes; the caller holds m.taskMutex
+func (m *Manager) underPressure() bool {
+	threshold := m.config.MaxPendingTasks
+	if threshold <= 0 {
+		return false
+	}
+	depth := queueDepth(m.agentRegistry)
+	active := depth > threshold
+	switch {
+	case active && m.pressureSince == nil:
+		now := time.Now()
+		m.pressureSince = &now
+		m.logger.Printf("Backpressure: %d tasks wait for an agent (threshold %d); pausing task intake", depth, threshold)
+	case !active && m.pressureSince != nil:
+		m.pressureSince = nil
+		m.logger.Printf("Backpressure cleared: %d tasks wait for an agent; resuming task intake", depth)
+	}
+	return active
+}
+
+// Backpressure returns the current backpressure state
+func (m *Manager) Backpressure() Backpressure {
+	m.taskMutex.Lock()
+	defer m.taskMutex.Unlock()
+	b := Backpressure{
+		Active:       m.underPressure(),
+		QueueDepth:   queueDepth(m.agentRegistry),
+		Threshold:    m.config.MaxPendingTasks,
+		SkippedPolls: m.skippedPolls,
+		Waiting:      []string{},
+	}
+	if b.Threshold < 0 {
+		b.Threshold = 0
+	}
+	if m.pressureSince != nil {
+		since := *m.pressureSince
+		b.Since = &since
+	}
+	for id := range m.waiting {
+		b.Waiting = append(b.Waiting, id)
+	}
+	return b
+}
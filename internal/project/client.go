@@ -1,11 +1,14 @@
 package project
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/biodoia/skagent/internal/dryrun"
 )
 
 // Task represents a task from the project manager
@@ -58,7 +61,8 @@ func NewClient(baseURL, apiKey string) *Client {
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: dryrun.Transport("project", nil),
 		},
 	}
 }
@@ -228,6 +232,77 @@ func (c *Client) CreateWebhook(ctx context.Context, callbackURL string) error {
 	return nil
 }
 
+// ProjectRegistration describes a local repository announced to the
+// project manager
+type ProjectRegistration struct {
+	Name    string `json:"name"`
+	RepoURL string `json:"repo_url,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+// RegisteredProject is the project manager's record of a registration
+type RegisteredProject struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	RepoURL string `json:"repo_url,omitempty"`
+}
+
+// RegisterProject registers a repository with the project manager
+func (c *Client) RegisterProject(ctx context.Context, reg ProjectRegistration) (*RegisteredProject, error) {
+	req, err := c.newRequest(ctx, "POST", "/api/v1/projects", reg)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to register project %s: %s", reg.Name, resp.Status)
+	}
+
+	var project RegisteredProject
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// PullRequestLink attaches a pull request to a project manager task
+type PullRequestLink struct {
+	Type   string `json:"type"` // always "pull_request"
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+	Branch string `json:"branch,omitempty"`
+	// AgentTaskID is the skagent task the pull request comes from
+	AgentTaskID string `json:"agent_task_id,omitempty"`
+}
+
+// LinkPullRequest attaches a pull request to a task
+func (c *Client) LinkPullRequest(ctx context.Context, taskID string, link PullRequestLink) error {
+	link.Type = "pull_request"
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/api/v1/tasks/%s/links", taskID), link)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to link pull request to task %s: %s", taskID, resp.Status)
+	}
+
+	return nil
+}
+
 // newRequest creates a new HTTP request with proper headers
 func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
 	var url string
@@ -241,7 +316,11 @@ func (c *Client) newRequest(ctx context.Context, method, path string, body inter
 	var err error
 
 	if body != nil {
-		req, err = http.NewRequestWithContext(ctx, method, url, nil)
+		payload, merr := json.Marshal(body)
+		if merr != nil {
+			return nil, merr
+		}
+		req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,105 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// ImportSource is the source of the registry tasks imported from the
+// project manager
+const ImportSource = "project"
+
+// importTask creates the registry task of a todo task of the project
+// manager, delivered by via, a poll or the webhook. A task delivered
+// again, by the other channel or re-created after an edit, is merged into
+// the registry task imported before as long as that one is not finished:
+// while it waits for an agent it takes the new title, description, labels
+// and priority, which rises as the due date nears, and any duplicates are
+// cancelled. The caller holds taskMutex.
+func (m *Manager) importTask(task *Task, via string) {
+	if !m.config.ImportTasks || (task.Status != "" && task.Status != "todo") {
+		return
+	}
+	priority := m.priorities.Map(task, time.Now())
+
+	if kept, duplicates, conflicts := m.dedupe(task.ID, m.agentRegistry.TasksByExternalID(ImportSource, task.ID)); kept != nil {
+		m.reconcile.Merged++
+		changes := m.merge(kept, task, priority)
+		if len(changes) > 0 || len(duplicates) > 0 || len(conflicts) > 0 {
+			m.report(ReconcileEntry{
+				ExternalID: task.ID,
+				TaskID:     kept.ID,
+				Action:     ReconcileMerged,
+				Via:        via,
+				Changes:    changes,
+				Duplicates: duplicates,
+				Conflicts:  conflicts,
+			})
+		}
+		return
+	}
+
+	meta := map[string]string{}
+	if task.Priority != "" {
+		meta["external_priority"] = task.Priority
+	}
+	if task.DueDate != nil {
+		meta["due_date"] = task.DueDate.Format(time.RFC3339)
+	}
+	created := m.agentRegistry.CreateTaskBy(&agents.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Priority:    priority,
+		Labels:      task.Labels,
+		ExternalID:  task.ID,
+		Source:      ImportSource,
+		Meta:        meta,
+	}, agents.Cause{
+		Actor:  ImportSource,
+		Reason: fmt.Sprintf("imported with priority %s from %q", levelName(priority), task.Priority),
+	})
+	m.reconcile.Imported++
+	m.report(ReconcileEntry{ExternalID: task.ID, TaskID: created.ID, Action: ReconcileImported, Via: via})
+	m.logger.Printf("Imported task %s as %s with priority %s", task.ID, created.ID, levelName(priority))
+}
+
+// merge brings the registry task imported for an external task up to
+// date, if it still waits for an agent, and returns the fields changed
+func (m *Manager) merge(kept *agents.Task, task *Task, priority agents.TaskPriority) []string {
+	if kept.Status != agents.TaskStatusPending {
+		return nil
+	}
+	op := agents.TaskOp{Op: agents.BulkUpdate, ID: kept.ID}
+	var changes []string
+	if task.Title != "" && task.Title != kept.Title {
+		op.Title = task.Title
+		changes = append(changes, "title")
+	}
+	if task.Description != "" && task.Description != kept.Description {
+		op.Description = task.Description
+		changes = append(changes, "description")
+	}
+	if task.Labels != nil && strings.Join(task.Labels, "\x00") != strings.Join(kept.Labels, "\x00") {
+		op.Labels = task.Labels
+		changes = append(changes, "labels")
+	}
+	if priority != kept.Priority {
+		op.Priority = &priority
+		changes = append(changes, "priority")
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	cause := agents.Cause{
+		Actor:  ImportSource,
+		Reason: fmt.Sprintf("%s changed in the project manager", strings.Join(changes, ", ")),
+	}
+	if _, err := m.agentRegistry.ApplyTaskOpsBy([]agents.TaskOp{op}, cause); err != nil {
+		m.logger.Printf("ERROR: Failed to update task %s from %s: %v", kept.ID, task.ID, err)
+		return nil
+	}
+	return changes
+}
@@ -0,0 +1,187 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by Ingest while project.webhook_queue_size
+// events wait for a worker; the project manager should deliver again later
+var ErrQueueFull = errors.New("webhook queue is full")
+
+// IngestStats counts the webhook events by outcome
+type IngestStats struct {
+	Received int64 `json:"received"`
+	// Deduplicated events were merged into one of the same type for the
+	// same task that was still waiting
+	Deduplicated int64 `json:"deduplicated"`
+	// Rejected events arrived while the queue was full
+	Rejected  int64 `json:"rejected"`
+	Processed int64 `json:"processed"`
+	// Queued is how many events wait for a worker
+	Queued  int `json:"queued"`
+	Workers int `json:"workers"`
+	// Rate is the most events processed a second; 0 means no limit
+	Rate int `json:"rate"`
+}
+
+// ingest queues webhook events for a fixed pool of workers, so that the
+// handler answers at once however many events the project manager sends.
+// Events are keyed by type and task: one arriving while another with the
+// same key waits is merged into it. The events of a task always go to the
+// same worker and run one at a time, in order, so a storm can neither
+// process a task twice at once nor assign it twice.
+type ingest struct {
+	mu      sync.Mutex
+	pending map[string]*WebhookEvent
+	limit   int
+	// queues hold the keys of the pending events, one per worker
+	queues []chan string
+	// throttle paces the workers when a rate is set
+	throttle *time.Ticker
+	seq      int64
+	stats    IngestStats
+}
+
+// startIngest starts the workers of the webhook queue, which stop with
+// the manager
+func (m *Manager) startIngest() {
+	workers := m.config.WebhookWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+	limit := m.config.WebhookQueueSize
+	if limit <= 0 {
+		limit = 1000
+	}
+	q := &ingest{
+		pending: make(map[string]*WebhookEvent),
+		limit:   limit,
+		queues:  make([]chan string, workers),
+		stats:   IngestStats{Workers: workers, Rate: m.config.WebhookRate},
+	}
+	if m.config.WebhookRate > 0 {
+		q.throttle = time.NewTicker(time.Second / time.Duration(m.config.WebhookRate))
+	}
+	m.ingest = q
+	for i := range q.queues {
+		// Pending events never exceed the limit, so sends never block
+		q.queues[i] = make(chan string, limit)
+		m.wg.Add(1)
+		go m.ingestWorker(q.queues[i])
+	}
+}
+
+// Ingest queues a webhook event for processing. An event of the same type
+// for the same task that is still waiting absorbs it: the data of the
+// later one replaces the same fields of the earlier.
+func (m *Manager) Ingest(event WebhookEvent) error {
+	q := m.ingest
+	taskID := eventTaskID(event)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stats.Received++
+
+	key := event.Type + "/" + taskID
+	if taskID == "" {
+		// Nothing to merge it with
+		q.seq++
+		key = fmt.Sprintf("%s#%d", event.Type, q.seq)
+	}
+	if queued, ok := q.pending[key]; ok {
+		for k, v := range event.Data {
+			queued.Data[k] = v
+		}
+		if event.Timestamp.After(queued.Timestamp) {
+			queued.Timestamp = event.Timestamp
+		}
+		q.stats.Deduplicated++
+		return nil
+	}
+	if len(q.pending) >= q.limit {
+		q.stats.Rejected++
+		return ErrQueueFull
+	}
+	if event.Data == nil {
+		event.Data = make(map[string]interface{})
+	}
+	q.pending[key] = &event
+	q.queues[shard(taskID, len(q.queues))] <- key
+	return nil
+}
+
+// IngestStats returns the counters of the webhook queue
+func (m *Manager) IngestStats() IngestStats {
+	q := m.ingest
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := q.stats
+	stats.Queued = len(q.pending)
+	return stats
+}
+
+// ingestWorker processes the events whose keys arrive on keys
+func (m *Manager) ingestWorker(keys <-chan string) {
+	defer m.wg.Done()
+	q := m.ingest
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case key := <-keys:
+			if q.throttle != nil {
+				select {
+				case <-q.throttle.C:
+				case <-m.ctx.Done():
+					return
+				}
+			}
+			// Taken off the queue only now, so that events arriving
+			// until the worker is ready merge into this one
+			q.mu.Lock()
+			event := q.pending[key]
+			delete(q.pending, key)
+			q.mu.Unlock()
+
+			m.dispatch(*event)
+
+			q.mu.Lock()
+			q.stats.Processed++
+			q.mu.Unlock()
+		}
+	}
+}
+
+// stopIngest stops pacing the workers, reporting the events dropped
+func (m *Manager) stopIngest() {
+	q := m.ingest
+	if q.throttle != nil {
+		q.throttle.Stop()
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if n := len(q.pending); n > 0 {
+		m.logger.Printf("WARN: Dropping %d queued webhook events; the next poll picks up their tasks", n)
+	}
+}
+
+// eventTaskID returns the project manager task an event is about, if any
+func eventTaskID(event WebhookEvent) string {
+	if task, ok := event.Data["task"].(map[string]interface{}); ok {
+		if id, ok := task["id"].(string); ok {
+			return id
+		}
+	}
+	id, _ := event.Data["task_id"].(string)
+	return id
+}
+
+// shard picks the worker of a task
+func shard(taskID string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(taskID))
+	return int(h.Sum32() % uint32(workers))
+}
@@ -0,0 +1,67 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestIngestMergesAndBoundsEvents(t *testing.T) {
+	registry := agents.NewRegistry(context.Background())
+	m := NewManager(NewClient("http://127.0.0.1:0", "key"), registry,
+		config.ProjectConfig{WebhookWorkers: 1, WebhookQueueSize: 2})
+	// Without workers the events wait in the queue for the test
+	m.Stop()
+
+	events := []WebhookEvent{
+		{Type: "task.created", Data: map[string]interface{}{"task": map[string]interface{}{"id": "PM-1"}}},
+		{Type: "task.updated", Data: map[string]interface{}{"task_id": "PM-1", "status": "in_progress"}},
+		{Type: "task.updated", Data: map[string]interface{}{"task_id": "PM-1", "assignee": "bob"}},
+	}
+	for _, e := range events {
+		if err := m.Ingest(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Ingest(WebhookEvent{Type: "task.created", Data: map[string]interface{}{"task": map[string]interface{}{"id": "PM-2"}}}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("err = %v", err)
+	}
+
+	m.ingest.mu.Lock()
+	merged := m.ingest.pending["task.updated/PM-1"].Data
+	m.ingest.mu.Unlock()
+	if merged["status"] != "in_progress" || merged["assignee"] != "bob" {
+		t.Fatalf("merged update = %v", merged)
+	}
+	stats := m.IngestStats()
+	if stats.Received != 4 || stats.Deduplicated != 1 || stats.Rejected != 1 || stats.Queued != 2 || stats.Workers != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestIngestImportsOnce(t *testing.T) {
+	registry := agents.NewRegistry(context.Background())
+	m := NewManager(NewClient("http://127.0.0.1:0", "key"), registry, config.ProjectConfig{ImportTasks: true})
+	defer m.Stop()
+
+	task := map[string]interface{}{"id": "PM-1", "title": "fix login", "status": "todo"}
+	for i := 0; i < 50; i++ {
+		if err := m.Ingest(WebhookEvent{Type: "task.created", Data: map[string]interface{}{"task": task}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for stats := m.IngestStats(); stats.Processed+stats.Deduplicated < 50; stats = m.IngestStats() {
+		if time.Now().After(deadline) {
+			t.Fatalf("stats = %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(registry.TasksByExternalID(ImportSource, "PM-1")); n != 1 {
+		t.Fatalf("%d registry tasks for PM-1", n)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/biodoia/skagent/internal/agents"
 	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
 )
 
 // Manager orchestrates project manager integration
@@ -28,8 +29,39 @@ type Manager struct {
 	assignments  map[string]*TaskAssignment
 	taskMutex    sync.RWMutex
 	
-	// Webhook handling
+	// Webhook handling: the server, and the queue its events wait in
 	webhookServer *WebhookServer
+	ingest        *ingest
+	
+	// Backpressure, guarded by taskMutex: since when the registry's queue
+	// is over project.max_pending_tasks, the polls skipped meanwhile and
+	// the pushed tasks waiting for capacity
+	pressureSince *time.Time
+	skippedPolls  int
+	waiting       map[string]bool
+	
+	// Import into the registry: the priority mapping and, guarded by
+	// taskMutex, the reconciliation report and the duplicates reported as
+	// conflicts
+	priorities *PriorityMapper
+	reconcile  Reconciliation
+	conflicts  map[string]bool
+	
+	// execute runs the tasks assigned by the manager; see SetExecutor
+	execute Executor
+	
+	// Outcome of the last poll, for readiness checks
+	pollMu      sync.Mutex
+	lastPoll    time.Time
+	lastPollErr error
+}
+
+// Health reports whether the integration is enabled and the outcome of
+// the last poll of the project manager; lastPoll is zero before the first
+func (m *Manager) Health() (enabled bool, lastPoll time.Time, err error) {
+	m.pollMu.Lock()
+	defer m.pollMu.Unlock()
+	return m.config.Enabled, m.lastPoll, m.lastPollErr
 }
 
 // AssignRule defines automatic task assignment rules
@@ -73,10 +105,14 @@ func NewManager(client *Client, agentRegistry *agents.Registry, config config.Pr
 		cancel:       cancel,
 		tasks:        make(map[string]*Task),
 		assignments:  make(map[string]*TaskAssignment),
-		logger:       log.New(os.Stdout, "[PROJECT] ", log.LstdFlags|log.Lmsgprefix),
+		waiting:      make(map[string]bool),
+		priorities:   NewPriorityMapper(config.PriorityMap, time.Duration(config.DueSoonHours)*time.Hour),
+		conflicts:    make(map[string]bool),
+		logger:       logging.New("project", "[PROJECT] ", os.Stdout),
 	}
 	
 	client.SetContext(ctx)
+	m.startIngest()
 	
 	return m
 }
@@ -91,18 +127,21 @@ func (m *Manager) Start() error {
 	}
 	
 	// Start webhook server
-	const webhookPort = 8082 // Default webhook port
+	webhookPort := m.config.WebhookPort
+	if webhookPort == 0 {
+		webhookPort = 8082 // Default webhook port
+	}
 	
 	if webhookPort > 0 {
 		m.webhookServer = NewWebhookServer(m, webhookPort)
 		if err := m.webhookServer.Start(); err != nil {
-			m.logger.Printf("Failed to start webhook server: %v", err)
+			m.logger.Printf("ERROR: Failed to start webhook server: %v", err)
 		} else {
 			m.logger.Printf("Webhook server started on port %d", webhookPort)
 			
 			// Register webhook with project manager
 			if err := m.client.CreateWebhook(m.ctx, fmt.Sprintf("http://localhost:%d/webhook", webhookPort)); err != nil {
-				m.logger.Printf("Failed to register webhook: %v", err)
+				m.logger.Printf("ERROR: Failed to register webhook: %v", err)
 			}
 		}
 	}
@@ -124,6 +163,7 @@ func (m *Manager) Stop() error {
 	if m.webhookServer != nil {
 		m.webhookServer.Stop()
 	}
+	m.stopIngest()
 	
 	// Wait for background goroutines
 	done := make(chan struct{})
@@ -135,7 +175,7 @@ func (m *Manager) Stop() error {
 	select {
 	case <-done:
 	case <-time.After(10 * time.Second):
-		m.logger.Println("Timeout waiting for background tasks")
+		m.logger.Println("WARN: Timeout waiting for background tasks")
 	}
 	
 	return nil
@@ -178,9 +218,22 @@ func (m *Manager) loadTasks() {
 	// 	filters["categories"] = m.config.TaskCategories
 	// }
 	
+	// Leave new work in the project manager while the registry cannot
+	// keep up; the tasks are still todo there at the next poll
+	m.taskMutex.Lock()
+	if m.underPressure() {
+		m.skippedPolls++
+		m.taskMutex.Unlock()
+		return
+	}
+	m.taskMutex.Unlock()
+	
 	tasks, err := m.client.GetTasks(m.ctx, filters)
+	m.pollMu.Lock()
+	m.lastPoll, m.lastPollErr = time.Now(), err
+	m.pollMu.Unlock()
 	if err != nil {
-		m.logger.Printf("Failed to load tasks: %v", err)
+		m.logger.Printf("ERROR: Failed to load tasks: %v", err)
 		return
 	}
 	
@@ -190,12 +243,19 @@ func (m *Manager) loadTasks() {
 	// Update tasks
 	for _, task := range tasks {
 		m.tasks[task.ID] = &task
+		delete(m.waiting, task.ID)
+		m.importTask(&task, "poll")
 		
-		// Auto-assign if enabled
-		if m.config.AutoAssign && task.Assignee == "" {
+		// Auto-assign if enabled; imported tasks are assigned from the
+		// registry's queue instead
+		if m.config.AutoAssign && !m.config.ImportTasks && task.Assignee == "" {
 			m.autoAssignTask(&task)
 		}
 	}
+	if m.config.ImportTasks {
+		m.sweep()
+		m.agentRegistry.AutoAssign(m.ctx)
+	}
 	
 	m.logger.Printf("Loaded %d tasks", len(m.tasks))
 }
@@ -210,7 +270,7 @@ func (m *Manager) autoAssignTask(task *Task) {
 	
 	// Assign task
 	if err := m.client.AssignTask(m.ctx, task.ID, agentID); err != nil {
-		m.logger.Printf("Failed to assign task %s to agent %s: %v", task.ID, agentID, err)
+		m.logger.Printf("ERROR: Failed to assign task %s to agent %s: %v", task.ID, agentID, err)
 		return
 	}
 	
@@ -232,14 +292,12 @@ func (m *Manager) autoAssignTask(task *Task) {
 
 // findBestAgent finds the best agent for a task based on capabilities and load
 func (m *Manager) findBestAgent(task *Task) string {
-	// Get available agents
-	agents := m.agentRegistry.ListAgents()
-	
 	bestAgent := ""
 	bestScore := 0.0
 	
-	for _, agent := range agents {
-		if agent.Status != "active" {
+	// Only idle agents can take new work
+	for _, agent := range m.agentRegistry.ListAgents() {
+		if agent.Status != agents.StatusIdle {
 			continue
 		}
 		
@@ -301,13 +359,13 @@ func (m *Manager) executeTask(assignment *TaskAssignment) {
 	// Get task details
 	task, err := m.client.GetTask(m.ctx, assignment.TaskID)
 	if err != nil {
-		m.logger.Printf("Failed to get task %s: %v", assignment.TaskID, err)
+		m.logger.Printf("ERROR: Failed to get task %s: %v", assignment.TaskID, err)
 		return
 	}
 	
 	// Update task status
 	if err := m.client.UpdateTaskStatus(m.ctx, assignment.TaskID, "in_progress"); err != nil {
-		m.logger.Printf("Failed to update task status: %v", err)
+		m.logger.Printf("ERROR: Failed to update task status: %v", err)
 	}
 	
 	// Execute with agent
@@ -348,9 +406,7 @@ func (m *Manager) executeWithAgent(agentID string, task *Task) *TaskAssignmentRe
 		return result
 	}
 	
-	// Execute task (this is a simplified version)
-	// In a real implementation, this would call the agent's Execute method
-	output, err := m.simulateTaskExecution(task, agent)
+	output, err := m.runTask(task, agent)
 	
 	if err != nil {
 		result.Status = "failed"
@@ -366,26 +422,26 @@ func (m *Manager) executeWithAgent(agentID string, task *Task) *TaskAssignmentRe
 	return result
 }
 
-// simulateTaskExecution simulates task execution (placeholder)
-func (m *Manager) simulateTaskExecution(task *Task, agent *agents.Agent) (string, error) {
-	// This is a placeholder implementation
-	// In a real implementation, this would call the agent's actual execution method
-	
-	m.logger.Printf("Simulating execution of task '%s' with agent '%s'", task.Title, agent.Name)
-	
-	// Simulate some work
-	time.Sleep(2 * time.Second)
-	
-	// Simple simulation based on task type
-	if containsString(task.Title, "code") || containsString(task.Title, "develop") {
-		return "Generated code successfully", nil
-	} else if containsString(task.Title, "test") {
-		return "Ran tests and reported results", nil
-	} else if containsString(task.Title, "review") {
-		return "Reviewed code and provided feedback", nil
-	} else {
-		return "Task completed successfully", nil
+// Executor runs a task of the project manager on an agent and returns
+// its output
+type Executor func(ctx context.Context, task *Task, agent *agents.Agent) (string, error)
+
+// errNoExecutor fails the tasks assigned while no agent runtime is set
+var errNoExecutor = fmt.Errorf("no agent runtime runs tasks; set runner.enabled")
+
+// SetExecutor sets what runs the tasks the manager assigns; call it
+// before Start
+func (m *Manager) SetExecutor(execute Executor) {
+	m.execute = execute
+}
+
+// runTask runs a task on an agent with the executor
+func (m *Manager) runTask(task *Task, agent *agents.Agent) (string, error) {
+	if m.execute == nil {
+		return "", errNoExecutor
 	}
+	m.logger.Printf("Running task '%s' on agent '%s'", task.Title, agent.Name)
+	return m.execute(m.ctx, task, agent)
 }
 
 // GetTaskStatus returns the status of a task
@@ -0,0 +1,90 @@
+package project
+
+import (
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// defaultPriorities maps the priorities of common project managers to the
+// registry's: names, Linear's 1 (urgent) to 4 (low) and P0 to P4
+var defaultPriorities = map[string]agents.TaskPriority{
+	"lowest":   agents.PriorityLow,
+	"low":      agents.PriorityLow,
+	"minor":    agents.PriorityLow,
+	"trivial":  agents.PriorityLow,
+	"medium":   agents.PriorityMedium,
+	"normal":   agents.PriorityMedium,
+	"moderate": agents.PriorityMedium,
+	"high":     agents.PriorityHigh,
+	"major":    agents.PriorityHigh,
+	"highest":  agents.PriorityUrgent,
+	"critical": agents.PriorityUrgent,
+	"urgent":   agents.PriorityUrgent,
+	"blocker":  agents.PriorityUrgent,
+	"1":        agents.PriorityUrgent,
+	"2":        agents.PriorityHigh,
+	"3":        agents.PriorityMedium,
+	"4":        agents.PriorityLow,
+	"p0":       agents.PriorityUrgent,
+	"p1":       agents.PriorityHigh,
+	"p2":       agents.PriorityMedium,
+	"p3":       agents.PriorityLow,
+	"p4":       agents.PriorityLow,
+}
+
+// priorityLevels names the registry's priorities in the configuration
+var priorityLevels = []string{"low", "medium", "high", "urgent"}
+
+func levelName(p agents.TaskPriority) string {
+	if p >= 0 && int(p) < len(priorityLevels) {
+		return priorityLevels[p]
+	}
+	return "unknown"
+}
+
+// PriorityMapper turns project manager priorities into registry ones
+type PriorityMapper struct {
+	levels  map[string]agents.TaskPriority
+	dueSoon time.Duration
+}
+
+// NewPriorityMapper returns a mapper that applies overrides, from external
+// priority to low, medium, high or urgent, over the built-in mapping and
+// raises tasks due within dueSoon. Overrides to other levels are ignored;
+// the configuration's validation reports them.
+func NewPriorityMapper(overrides map[string]string, dueSoon time.Duration) *PriorityMapper {
+	levels := make(map[string]agents.TaskPriority, len(defaultPriorities)+len(overrides))
+	for name, p := range defaultPriorities {
+		levels[name] = p
+	}
+	for name, level := range overrides {
+		for i, l := range priorityLevels {
+			if strings.EqualFold(level, l) {
+				levels[strings.ToLower(strings.TrimSpace(name))] = agents.TaskPriority(i)
+			}
+		}
+	}
+	return &PriorityMapper{levels: levels, dueSoon: dueSoon}
+}
+
+// Map returns the registry priority of task at now: its mapped priority,
+// medium when unknown, one level higher when it is due within the window
+// and urgent once it is overdue
+func (pm *PriorityMapper) Map(task *Task, now time.Time) agents.TaskPriority {
+	p, ok := pm.levels[strings.ToLower(strings.TrimSpace(task.Priority))]
+	if !ok {
+		p = agents.PriorityMedium
+	}
+	if task.DueDate == nil || pm.dueSoon <= 0 {
+		return p
+	}
+	switch left := task.DueDate.Sub(now); {
+	case left < 0:
+		return agents.PriorityUrgent
+	case left <= pm.dueSoon && p < agents.PriorityUrgent:
+		return p + 1
+	}
+	return p
+}
@@ -0,0 +1,43 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestPriorityMapper(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		due := now.Add(d)
+		return &due
+	}
+	mapper := NewPriorityMapper(map[string]string{"Sev-1": "urgent", "low": "medium", "bogus": "asap"}, 48*time.Hour)
+
+	for _, tc := range []struct {
+		priority string
+		due      *time.Time
+		want     agents.TaskPriority
+	}{
+		{"critical", nil, agents.PriorityUrgent},
+		{"High", nil, agents.PriorityHigh},
+		{"P3", nil, agents.PriorityLow},
+		{"sev-1", nil, agents.PriorityUrgent},
+		{"low", nil, agents.PriorityMedium},
+		{"bogus", nil, agents.PriorityMedium},
+		{"", nil, agents.PriorityMedium},
+		{"high", at(24 * time.Hour), agents.PriorityUrgent},
+		{"minor", at(72 * time.Hour), agents.PriorityLow},
+		{"minor", at(-time.Hour), agents.PriorityUrgent},
+		{"urgent", at(time.Hour), agents.PriorityUrgent},
+	} {
+		if got := mapper.Map(&Task{Priority: tc.priority, DueDate: tc.due}, now); got != tc.want {
+			t.Errorf("%q due %v: got %s, want %s", tc.priority, tc.due, levelName(got), levelName(tc.want))
+		}
+	}
+
+	if got := NewPriorityMapper(nil, 0).Map(&Task{Priority: "low", DueDate: at(-time.Hour)}, now); got != agents.PriorityLow {
+		t.Errorf("without due date boosting an overdue low task is %s", levelName(got))
+	}
+}
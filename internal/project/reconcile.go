@@ -0,0 +1,174 @@
+package project
+
+import (
+	"sort"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// maxReconcileEntries bounds the entries a reconciliation report keeps
+const maxReconcileEntries = 100
+
+// ReconcileAction is what importing did with a delivery of an external
+// task
+type ReconcileAction string
+
+const (
+	// ReconcileImported created a registry task
+	ReconcileImported ReconcileAction = "imported"
+	// ReconcileMerged folded the delivery into the registry task imported
+	// before
+	ReconcileMerged ReconcileAction = "merged"
+)
+
+// ReconcileEntry records an import, or a merge that changed something
+type ReconcileEntry struct {
+	Time       time.Time       `json:"time"`
+	ExternalID string          `json:"external_id"`
+	TaskID     string          `json:"task_id"`
+	Action     ReconcileAction `json:"action"`
+	// Via is how the task arrived: poll, webhook or reconcile
+	Via string `json:"via"`
+	// Changes lists the fields the merge updated
+	Changes []string `json:"changes,omitempty"`
+	// Duplicates are the registry tasks for the same external task that
+	// were cancelled in favour of TaskID
+	Duplicates []string `json:"duplicates,omitempty"`
+	// Conflicts are duplicates already taken by an agent, left running
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// Reconciliation reports how external tasks map onto registry tasks
+type Reconciliation struct {
+	// Imported counts the registry tasks created
+	Imported int `json:"imported"`
+	// Merged counts the deliveries of tasks imported before
+	Merged int `json:"merged"`
+	// DuplicatesCancelled counts the parallel registry tasks cancelled
+	DuplicatesCancelled int `json:"duplicates_cancelled"`
+	// LastRun is when the registry was last swept for duplicates
+	LastRun *time.Time `json:"last_run,omitempty"`
+	// Entries are the most recent imports and merges, oldest first
+	Entries []ReconcileEntry `json:"entries"`
+}
+
+// statusRank orders the statuses of duplicates: the task kept is the one
+// furthest along
+var statusRank = map[agents.TaskStatus]int{
+	agents.TaskStatusInProgress: 2,
+	agents.TaskStatusQueued:     1,
+	agents.TaskStatusPending:    0,
+}
+
+// dedupe finds, among the registry tasks imported for an external task,
+// the one that is not finished, cancelling the other pending ones. An agent's work is
+// never thrown away: the task kept is the one furthest along, the oldest
+// among equals, and duplicates an agent already took are only reported
+// as conflicts, the first time they are seen. kept is nil when there is no such task. The caller holds
+// taskMutex.
+func (m *Manager) dedupe(externalID string, imported []*agents.Task) (kept *agents.Task, duplicates, conflicts []string) {
+	var open []*agents.Task
+	for _, t := range imported {
+		if _, ok := statusRank[t.Status]; ok {
+			open = append(open, t)
+		}
+	}
+	for _, t := range open {
+		if kept == nil || statusRank[t.Status] > statusRank[kept.Status] {
+			kept = t
+		}
+	}
+	for _, t := range open {
+		switch {
+		case t == kept:
+		case t.Status != agents.TaskStatusPending:
+			// Reported once, not at every poll
+			if !m.conflicts[t.ID] {
+				m.conflicts[t.ID] = true
+				conflicts = append(conflicts, t.ID)
+			}
+		default:
+			cause := agents.Cause{Actor: ImportSource, Reason: "duplicate of " + kept.ID + " for " + externalID}
+			if err := m.agentRegistry.CancelTask(t.ID, cause); err != nil {
+				m.logger.Printf("ERROR: Failed to cancel duplicate task %s: %v", t.ID, err)
+				continue
+			}
+			duplicates = append(duplicates, t.ID)
+			m.reconcile.DuplicatesCancelled++
+			m.logger.Printf("Cancelled task %s, a duplicate of %s for %s", t.ID, kept.ID, externalID)
+		}
+	}
+	return kept, duplicates, conflicts
+}
+
+// report records an entry of the reconciliation report; the caller holds
+// taskMutex
+func (m *Manager) report(e ReconcileEntry) {
+	e.Time = time.Now()
+	m.reconcile.Entries = append(m.reconcile.Entries, e)
+	if n := len(m.reconcile.Entries); n > maxReconcileEntries {
+		m.reconcile.Entries = append(m.reconcile.Entries[:0], m.reconcile.Entries[n-maxReconcileEntries:]...)
+	}
+}
+
+// Reconcile sweeps the registry for parallel tasks imported for the same
+// external task, cancelling them as importing does, and returns the
+// report
+func (m *Manager) Reconcile() Reconciliation {
+	m.taskMutex.Lock()
+	defer m.taskMutex.Unlock()
+	m.sweep()
+	return m.reconciliation()
+}
+
+// sweep is Reconcile with taskMutex held
+func (m *Manager) sweep() {
+	var order []string
+	imported := make(map[string][]*agents.Task)
+	for _, t := range m.agentRegistry.ListTasks() {
+		if t.Source != ImportSource || t.ExternalID == "" {
+			continue
+		}
+		if _, ok := imported[t.ExternalID]; !ok {
+			order = append(order, t.ExternalID)
+		}
+		imported[t.ExternalID] = append(imported[t.ExternalID], t)
+	}
+	for _, externalID := range order {
+		tasks := imported[externalID]
+		if len(tasks) < 2 {
+			continue
+		}
+		sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].CreatedAt.Before(tasks[j].CreatedAt) })
+		if kept, duplicates, conflicts := m.dedupe(externalID, tasks); len(duplicates) > 0 || len(conflicts) > 0 {
+			m.report(ReconcileEntry{
+				ExternalID: externalID,
+				TaskID:     kept.ID,
+				Action:     ReconcileMerged,
+				Via:        "reconcile",
+				Duplicates: duplicates,
+				Conflicts:  conflicts,
+			})
+		}
+	}
+	now := time.Now()
+	m.reconcile.LastRun = &now
+}
+
+// Reconciliation returns the reconciliation report
+func (m *Manager) Reconciliation() Reconciliation {
+	m.taskMutex.Lock()
+	defer m.taskMutex.Unlock()
+	return m.reconciliation()
+}
+
+func (m *Manager) reconciliation() Reconciliation {
+	r := m.reconcile
+	r.Entries = append([]ReconcileEntry{}, m.reconcile.Entries...)
+	if m.reconcile.LastRun != nil {
+		last := *m.reconcile.LastRun
+		r.LastRun = &last
+	}
+	return r
+}
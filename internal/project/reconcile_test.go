@@ -0,0 +1,61 @@
+package project
+
+import (
+	"context"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestImportMergesDuplicates(t *testing.T) {
+	registry := agents.NewRegistry(context.Background())
+	m := NewManager(NewClient("http://127.0.0.1:0", "key"), registry, config.ProjectConfig{ImportTasks: true})
+	defer m.Stop()
+
+	m.taskMutex.Lock()
+	m.importTask(&Task{ID: "PM-1", Title: "fix login", Status: "todo", Priority: "low"}, "poll")
+	m.importTask(&Task{ID: "PM-1", Title: "fix login", Status: "todo", Priority: "low"}, "webhook")
+	m.importTask(&Task{ID: "PM-1", Title: "fix the login form", Status: "todo", Priority: "critical"}, "webhook")
+	m.taskMutex.Unlock()
+
+	imported := registry.TasksByExternalID(ImportSource, "PM-1")
+	if len(imported) != 1 {
+		t.Fatalf("%d registry tasks for PM-1", len(imported))
+	}
+	kept := imported[0]
+	if kept.Title != "fix the login form" || kept.Priority != agents.PriorityUrgent {
+		t.Fatalf("not merged: %q priority %d", kept.Title, kept.Priority)
+	}
+
+	// A parallel task, as a restart or another importer could leave, is
+	// cancelled by the sweep
+	dup := registry.CreateTask(&agents.Task{Title: "fix login", ExternalID: "PM-1", Source: ImportSource})
+	report := m.Reconcile()
+	if task, _ := registry.GetTask(dup.ID); task.Status != agents.TaskStatusCancelled {
+		t.Fatalf("duplicate is %s", task.Status)
+	}
+	if task, _ := registry.GetTask(kept.ID); task.Status != agents.TaskStatusPending {
+		t.Fatalf("kept task is %s", task.Status)
+	}
+
+	if report.Imported != 1 || report.Merged != 2 || report.DuplicatesCancelled != 1 || report.LastRun == nil {
+		t.Fatalf("report: %+v", report)
+	}
+	last := report.Entries[len(report.Entries)-1]
+	if len(report.Entries) != 3 || last.Via != "reconcile" || last.TaskID != kept.ID || len(last.Duplicates) != 1 || last.Duplicates[0] != dup.ID {
+		t.Fatalf("entries: %+v", report.Entries)
+	}
+	if changes := report.Entries[1].Changes; len(changes) != 2 || changes[0] != "title" || changes[1] != "priority" {
+		t.Fatalf("merge changes: %v", changes)
+	}
+
+	// Once the task is finished a new delivery is a new task
+	registry.CancelTask(kept.ID, agents.Cause{})
+	m.taskMutex.Lock()
+	m.importTask(&Task{ID: "PM-1", Title: "fix the login form", Status: "todo"}, "poll")
+	m.taskMutex.Unlock()
+	if n := len(registry.TasksByExternalID(ImportSource, "PM-1")); n != 3 {
+		t.Fatalf("%d registry tasks after the reopening", n)
+	}
+}
@@ -3,10 +3,13 @@ package project
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/biodoia/skagent/internal/server/bodylimit"
 )
 
 // NewWebhookServer creates a new webhook server
@@ -63,16 +66,35 @@ func (m *Manager) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	r.Body = http.MaxBytesReader(w, r.Body, bodylimit.DefaultLimit)
 	var event WebhookEvent
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
-		m.logger.Printf("Failed to decode webhook event: %v", err)
+		m.logger.Printf("ERROR: Failed to decode webhook event: %v", err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 	
+	// Answer at once: the events of a bulk import in the project manager
+	// would otherwise hold its requests while each one is processed
+	if err := m.Ingest(event); err != nil {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Webhook queue is full", http.StatusServiceUnavailable)
+		return
+	}
+	
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "queued"})
+}
+
+// dispatch processes a webhook event taken from the queue
+func (m *Manager) dispatch(event WebhookEvent) {
 	m.logger.Printf("Received webhook event: %s", event.Type)
 	
-	// Process different event types
 	switch event.Type {
 	case "task.created":
 		m.handleTaskCreated(event)
@@ -83,9 +105,6 @@ func (m *Manager) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	default:
 		m.logger.Printf("Unknown webhook event type: %s", event.Type)
 	}
-	
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "processed"})
 }
 
 // WebhookEvent represents a webhook event from the project manager
@@ -99,30 +118,41 @@ type WebhookEvent struct {
 func (m *Manager) handleTaskCreated(event WebhookEvent) {
 	taskData, ok := event.Data["task"].(map[string]interface{})
 	if !ok {
-		m.logger.Printf("Invalid task data in event")
+		m.logger.Printf("WARN: Invalid task data in event")
 		return
 	}
 	
 	// Convert to Task struct
 	taskJSON, err := json.Marshal(taskData)
 	if err != nil {
-		m.logger.Printf("Failed to marshal task data: %v", err)
+		m.logger.Printf("ERROR: Failed to marshal task data: %v", err)
 		return
 	}
 	
 	var task Task
 	if err := json.Unmarshal(taskJSON, &task); err != nil {
-		m.logger.Printf("Failed to unmarshal task: %v", err)
+		m.logger.Printf("ERROR: Failed to unmarshal task: %v", err)
 		return
 	}
 	
-	// Store task
+	// Store task; while the registry is over its threshold it waits for
+	// capacity and is picked up by the first poll after
 	m.taskMutex.Lock()
 	m.tasks[task.ID] = &task
+	if m.underPressure() {
+		m.waiting[task.ID] = true
+		m.taskMutex.Unlock()
+		m.logger.Printf("Task %s waits for capacity", task.ID)
+		return
+	}
+	m.importTask(&task, "webhook")
 	m.taskMutex.Unlock()
 	
-	// Auto-assign if enabled
-	if m.config.AutoAssign && task.Assignee == "" {
+	// Auto-assign if enabled; imported tasks are assigned from the
+	// registry's queue instead
+	if m.config.ImportTasks {
+		m.agentRegistry.AutoAssign(m.ctx)
+	} else if m.config.AutoAssign && task.Assignee == "" {
 		m.autoAssignTask(&task)
 	}
 	
@@ -133,7 +163,7 @@ func (m *Manager) handleTaskCreated(event WebhookEvent) {
 func (m *Manager) handleTaskUpdated(event WebhookEvent) {
 	taskID, ok := event.Data["task_id"].(string)
 	if !ok {
-		m.logger.Printf("Invalid task_id in update event")
+		m.logger.Printf("WARN: Invalid task_id in update event")
 		return
 	}
 	
@@ -158,13 +188,13 @@ func (m *Manager) handleTaskUpdated(event WebhookEvent) {
 func (m *Manager) handleTaskAssigned(event WebhookEvent) {
 	taskID, ok := event.Data["task_id"].(string)
 	if !ok {
-		m.logger.Printf("Invalid task_id in assignment event")
+		m.logger.Printf("WARN: Invalid task_id in assignment event")
 		return
 	}
 	
 	agentID, ok := event.Data["agent_id"].(string)
 	if !ok {
-		m.logger.Printf("Invalid agent_id in assignment event")
+		m.logger.Printf("WARN: Invalid agent_id in assignment event")
 		return
 	}
 	
@@ -0,0 +1,335 @@
+// Package pullrequest turns the changes a coder agent leaves in its
+// workspace into a GitHub pull request: the git tool commits them to a
+// branch and pushes it, the GitHub tool opens a pull request describing
+// the task, and the project manager links it to the task's issue.
+package pullrequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/dryrun"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/project"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// Task metadata the workflow reads and writes
+const (
+	// MetaWorkspace names the git checkout a task's agent works in
+	MetaWorkspace = "workspace"
+	// MetaURL and MetaBranch record the pull request opened for a task
+	MetaURL    = "pull_request"
+	MetaBranch = "pull_request_branch"
+)
+
+// source names the workflow in task logs
+const source = "pull-request"
+
+var (
+	// ErrNoWorkspace is returned for a task with no workspace when none is
+	// configured either
+	ErrNoWorkspace = errors.New("task has no workspace; set meta.workspace or pull_requests.workspace")
+	// ErrExists is returned when the task already has a pull request
+	ErrExists = errors.New("task already has a pull request")
+	// ErrNoChanges is returned when the workspace has nothing to commit
+	ErrNoChanges = tools.ErrNoChanges
+)
+
+// Linker attaches pull requests to project manager tasks;
+// *project.Client implements it
+type Linker interface {
+	LinkPullRequest(ctx context.Context, taskID string, link project.PullRequestLink) error
+}
+
+// Options override the configuration for one pull request
+type Options struct {
+	Workspace string `json:"workspace,omitempty"`
+	Base      string `json:"base,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Draft     bool   `json:"draft,omitempty"`
+}
+
+// PullRequest is a pull request opened for a task
+type PullRequest struct {
+	TaskID string   `json:"task_id"`
+	URL    string   `json:"url"`
+	Number int      `json:"number,omitempty"`
+	Title  string   `json:"title"`
+	Branch string   `json:"branch"`
+	Base   string   `json:"base"`
+	Commit string   `json:"commit"`
+	Files  []string `json:"files"`
+	// Linked tells whether the pull request was attached to the task's
+	// issue in the project manager
+	Linked    bool      `json:"linked"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Workflow opens pull requests for tasks
+type Workflow struct {
+	cfg      config.PullRequestConfig
+	registry *agents.Registry
+	git      *tools.GitTool
+	github   *tools.GitHubTool
+	linker   Linker
+	logger   *log.Logger
+
+	// mu serializes workflows, which check out branches in a shared
+	// working tree
+	mu sync.Mutex
+}
+
+// New returns a workflow; linker may be nil when there is no project
+// manager
+func New(cfg config.PullRequestConfig, registry *agents.Registry, linker Linker) *Workflow {
+	if cfg.Remote == "" {
+		cfg.Remote = "origin"
+	}
+	if cfg.BranchPrefix == "" {
+		cfg.BranchPrefix = "skagent/"
+	}
+	return &Workflow{
+		cfg:      cfg,
+		registry: registry,
+		git:      tools.NewGitTool(""),
+		github:   tools.NewGitHubTool(""),
+		linker:   linker,
+		logger:   logging.New("pullrequest", "[PR] ", log.Writer()),
+	}
+}
+
+// Open commits the changes in a task's workspace to a new branch, pushes
+// it and opens a pull request for it. The workspace is switched back to
+// the base branch afterwards.
+func (w *Workflow) Open(ctx context.Context, taskID string, opts Options) (*PullRequest, error) {
+	task, ok := w.registry.GetTask(taskID)
+	if !ok {
+		return nil, agents.ErrTaskNotFound
+	}
+	if task.Meta[MetaURL] != "" {
+		return nil, ErrExists
+	}
+	dir := firstNonEmpty(opts.Workspace, task.Meta[MetaWorkspace], w.cfg.Workspace)
+	if dir == "" {
+		return nil, ErrNoWorkspace
+	}
+	title := firstNonEmpty(opts.Title, task.Title)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	branch := BranchName(w.cfg.BranchPrefix, task)
+	tasklog.RecordTool(task.ID, tasklog.KindToolCall, source, "git", "Commit the changes in %s to branch %s", dir, branch)
+	commit, err := w.git.CommitBranch(ctx, dir, branch, CommitMessage(task, title))
+	if err != nil {
+		tasklog.RecordTool(task.ID, tasklog.KindError, source, "git", "Commit failed: %v", err)
+		return nil, err
+	}
+	tasklog.RecordTool(task.ID, tasklog.KindOutput, source, "git", "Committed %d files as %s on %s", len(commit.Files), commit.SHA, commit.Base)
+	base := firstNonEmpty(opts.Base, w.cfg.Base, commit.Base)
+	defer func() {
+		if err := w.git.Checkout(context.Background(), dir, commit.Base); err != nil {
+			w.logger.Printf("ERROR: Failed to switch %s back to %s: %v", dir, commit.Base, err)
+		}
+	}()
+
+	tasklog.RecordTool(task.ID, tasklog.KindToolCall, source, "git", "Push %s to %s", branch, w.cfg.Remote)
+	if err := w.git.Push(ctx, dir, w.cfg.Remote, branch); err != nil {
+		tasklog.RecordTool(task.ID, tasklog.KindError, source, "git", "Push failed: %v", err)
+		return nil, err
+	}
+	tasklog.RecordTool(task.ID, tasklog.KindToolCall, source, "github", "Open a pull request from %s into %s", branch, base)
+	url, err := w.github.CreatePullRequest(ctx, dir, tools.PullRequestSpec{
+		Base:  base,
+		Head:  branch,
+		Title: title,
+		Body:  Description(task, commit.Files, w.cfg.PublicURL),
+		Draft: opts.Draft || w.cfg.Draft,
+	})
+	if err != nil {
+		tasklog.RecordTool(task.ID, tasklog.KindError, source, "github", "Opening the pull request failed: %v", err)
+		return nil, err
+	}
+
+	pr := &PullRequest{
+		TaskID:    task.ID,
+		URL:       url,
+		Number:    pullNumber(url),
+		Title:     title,
+		Branch:    branch,
+		Base:      base,
+		Commit:    commit.SHA,
+		Files:     commit.Files,
+		CreatedAt: time.Now(),
+	}
+	if dryrun.Enabled() {
+		// Nothing was pushed or opened, so there is nothing to link or record
+		w.logger.Printf("Dry run: skipped the pull request of task %s", task.ID)
+		return pr, nil
+	}
+	w.logger.Printf("Opened %s for task %s", url, task.ID)
+	tasklog.RecordTool(task.ID, tasklog.KindOutput, source, "github", "Opened %s", url)
+
+	if w.linker != nil && task.ExternalID != "" {
+		link := project.PullRequestLink{URL: url, Title: title, Branch: branch, AgentTaskID: task.ID}
+		if err := w.linker.LinkPullRequest(ctx, task.ExternalID, link); err != nil {
+			w.logger.Printf("ERROR: Failed to link %s to issue %s: %v", url, task.ExternalID, err)
+		} else {
+			pr.Linked = true
+		}
+	}
+
+	if err := w.registry.SetTaskMeta(task.ID, map[string]string{MetaURL: url, MetaBranch: branch}); err != nil {
+		w.logger.Printf("ERROR: Failed to record %s on task %s: %v", url, task.ID, err)
+	}
+	return pr, nil
+}
+
+// Run opens a pull request for every task a coder agent completes, when
+// the configuration asks for it, until ctx is done or events is closed
+func (w *Workflow) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type != agents.EventTaskCompleted || !w.cfg.Auto {
+				continue
+			}
+			task, ok := e.Data["task"].(agents.Task)
+			if !ok || !w.byCoder(&task) {
+				continue
+			}
+			_, err := w.Open(ctx, task.ID, Options{})
+			switch {
+			case err == nil, errors.Is(err, ErrNoChanges), errors.Is(err, ErrExists), errors.Is(err, ErrNoWorkspace):
+			default:
+				w.logger.Printf("ERROR: Failed to open a pull request for task %s: %v", task.ID, err)
+			}
+		}
+	}
+}
+
+// byCoder reports whether a coder agent did the task
+func (w *Workflow) byCoder(task *agents.Task) bool {
+	if task.Result == nil || !task.Result.Success || task.AssignedTo == "" {
+		return false
+	}
+	agent, ok := w.registry.GetAgent(task.AssignedTo)
+	return ok && agent.Type == agents.AgentTypeCoder
+}
+
+// nonBranchChars are the characters replaced in branch names
+var nonBranchChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// BranchName names the branch of a task: the prefix, the start of the
+// task ID and its title, e.g. skagent/1a2b3c4d-fix-login-redirect
+func BranchName(prefix string, task *agents.Task) string {
+	slug := strings.Trim(nonBranchChars.ReplaceAllString(strings.ToLower(task.Title), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	id := task.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	if slug == "" {
+		return prefix + id
+	}
+	return prefix + id + "-" + slug
+}
+
+// CommitMessage is the message of a task's commit, with trailers naming
+// the task and its issue
+func CommitMessage(task *agents.Task, title string) string {
+	var b strings.Builder
+	b.WriteString(title)
+	b.WriteString("\n\nSkagent-Task: ")
+	b.WriteString(task.ID)
+	if task.ExternalID != "" {
+		fmt.Fprintf(&b, "\nIssue: %s", task.ExternalID)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// maxOutputLines bounds the agent output quoted in a description
+const maxOutputLines = 20
+
+// Description is the body of a task's pull request: the task, a link to
+// it when publicURL is set, its issue, what the agent reported and the
+// files changed
+func Description(task *agents.Task, files []string, publicURL string) string {
+	var b strings.Builder
+	b.WriteString("## Task\n\n")
+	if publicURL != "" {
+		fmt.Fprintf(&b, "[%s](%s/api/v1/tasks/%s) (`%s`)\n", task.Title, strings.TrimRight(publicURL, "/"), task.ID, task.ID)
+	} else {
+		fmt.Fprintf(&b, "%s (`%s`)\n", task.Title, task.ID)
+	}
+	if task.ExternalID != "" {
+		source := firstNonEmpty(task.Source, "project manager")
+		fmt.Fprintf(&b, "\nIssue: %s in %s\n", task.ExternalID, source)
+	}
+	if d := strings.TrimSpace(task.Description); d != "" {
+		fmt.Fprintf(&b, "\n%s\n", d)
+	}
+
+	if task.Result != nil && strings.TrimSpace(task.Result.Output) != "" {
+		lines := strings.Split(strings.TrimSpace(task.Result.Output), "\n")
+		if len(lines) > maxOutputLines {
+			lines = append(lines[:maxOutputLines], "…")
+		}
+		b.WriteString("\n## Agent report\n\n")
+		for _, l := range lines {
+			fmt.Fprintf(&b, "> %s\n", l)
+		}
+	}
+
+	if len(files) > 0 {
+		fmt.Fprintf(&b, "\n## Files changed (%d)\n\n", len(files))
+		for _, f := range files {
+			fmt.Fprintf(&b, "- `%s`\n", f)
+		}
+	}
+	b.WriteString("\n---\nOpened by skagent")
+	if task.AssignedTo != "" {
+		fmt.Fprintf(&b, " for agent `%s`", task.AssignedTo)
+	}
+	b.WriteString(".\n")
+	return b.String()
+}
+
+// pullNumber extracts the number from a pull request URL such as
+// https://github.com/o/r/pull/42
+func pullNumber(url string) int {
+	i := strings.LastIndex(url, "/pull/")
+	if i < 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimRight(url[i+len("/pull/"):], "/"))
+	return n
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package pullrequest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/dryrun"
+	"github.com/biodoia/skagent/internal/project"
+)
+
+type fakeLinker struct {
+	issue string
+	link  project.PullRequestLink
+}
+
+func (f *fakeLinker) LinkPullRequest(ctx context.Context, taskID string, link project.PullRequestLink) error {
+	f.issue, f.link = taskID, link
+	return nil
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmp := t.TempDir()
+	t.Setenv("GIT_AUTHOR_NAME", "skagent")
+	t.Setenv("GIT_AUTHOR_EMAIL", "skagent@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "skagent")
+	t.Setenv("GIT_COMMITTER_EMAIL", "skagent@example.com")
+
+	// A stand-in for gh that records its arguments and prints a URL
+	bin := filepath.Join(tmp, "bin")
+	os.Mkdir(bin, 0o755)
+	ghScript := "#!/bin/sh\nprintf '%s\\n' \"$@\" > \"" + filepath.Join(tmp, "gh-args") + "\"\necho https://github.com/acme/app/pull/42\n"
+	if err := os.WriteFile(filepath.Join(bin, "gh"), []byte(ghScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	remote := filepath.Join(tmp, "remote.git")
+	work := filepath.Join(tmp, "work")
+	git(t, tmp, "init", "--bare", "-b", "main", remote)
+	git(t, tmp, "clone", remote, work)
+	os.WriteFile(filepath.Join(work, "README.md"), []byte("app\n"), 0o644)
+	git(t, work, "add", "-A")
+	git(t, work, "commit", "-m", "init")
+	git(t, work, "push", "origin", "HEAD:main")
+
+	registry := agents.NewRegistry(context.Background())
+	task := registry.CreateTask(&agents.Task{
+		Title:      "Fix login redirect",
+		ExternalID: "ISSUE-7",
+		Meta:       map[string]string{MetaWorkspace: work},
+	})
+	linker := &fakeLinker{}
+	w := New(config.PullRequestConfig{PublicURL: "https://skagent.example.com"}, registry, linker)
+
+	if _, err := w.Open(context.Background(), task.ID, Options{}); err != ErrNoChanges {
+		t.Fatalf("clean workspace: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(work, "login.go"), []byte("package app\n"), 0o644)
+	pr, err := w.Open(context.Background(), task.ID, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Number != 42 || pr.Base != "main" || !pr.Linked || len(pr.Files) != 1 || pr.Files[0] != "login.go" {
+		t.Errorf("pull request = %+v", pr)
+	}
+	if !strings.HasPrefix(pr.Branch, "skagent/") || !strings.HasSuffix(pr.Branch, "-fix-login-redirect") {
+		t.Errorf("branch = %s", pr.Branch)
+	}
+	if got := git(t, remote, "rev-parse", pr.Branch); got != pr.Commit {
+		t.Errorf("remote %s = %s, want %s", pr.Branch, got, pr.Commit)
+	}
+	if got := git(t, work, "rev-parse", "--abbrev-ref", "HEAD"); got != "main" {
+		t.Errorf("workspace left on %s", got)
+	}
+	if linker.issue != "ISSUE-7" || linker.link.URL != pr.URL || linker.link.AgentTaskID != task.ID {
+		t.Errorf("link = %s %+v", linker.issue, linker.link)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(tmp, "gh-args"))
+	if !strings.Contains(string(args), "https://skagent.example.com/api/v1/tasks/"+task.ID) {
+		t.Errorf("description does not link the task:\n%s", args)
+	}
+	if got, _ := registry.GetTask(task.ID); got.Meta[MetaURL] != pr.URL {
+		t.Errorf("task meta = %v", got.Meta)
+	}
+	if _, err := w.Open(context.Background(), task.ID, Options{}); err != ErrExists {
+		t.Errorf("second pull request: %v", err)
+	}
+}
+
+func TestOpenDryRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dryrun.SetDefault(dryrun.New(0))
+	defer dryrun.SetDefault(nil)
+
+	work := t.TempDir()
+	git(t, work, "init", "-b", "main")
+	git(t, work, "-c", "user.name=skagent", "-c", "user.email=skagent@example.com", "commit", "--allow-empty", "-m", "init")
+	os.WriteFile(filepath.Join(work, "login.go"), []byte("package app\n"), 0o644)
+
+	registry := agents.NewRegistry(context.Background())
+	task := registry.CreateTask(&agents.Task{
+		Title:      "Fix login redirect",
+		ExternalID: "ISSUE-7",
+		Meta:       map[string]string{MetaWorkspace: work},
+	})
+	linker := &fakeLinker{}
+	w := New(config.PullRequestConfig{}, registry, linker)
+
+	pr, err := w.Open(context.Background(), task.ID, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.URL != "" || len(pr.Files) != 1 || pr.Files[0] != "login.go" {
+		t.Errorf("pull request = %+v", pr)
+	}
+	if got := git(t, work, "status", "--porcelain"); got != "?? login.go" {
+		t.Errorf("workspace changed: %q", got)
+	}
+	if got := git(t, work, "branch", "--list"); got != "* main" {
+		t.Errorf("branches = %q", got)
+	}
+	if linker.issue != "" {
+		t.Errorf("linked to %s in a dry run", linker.issue)
+	}
+	if got, _ := registry.GetTask(task.ID); got.Meta[MetaURL] != "" {
+		t.Errorf("task meta = %v", got.Meta)
+	}
+
+	var actions []string
+	for _, a := range dryrun.Actions() {
+		actions = append(actions, a.Component+": "+a.Action)
+	}
+	want := []string{"git: run git checkout -b", "git: run git push", "github: run gh pr create", "git: run git checkout main"}
+	if len(actions) != len(want) {
+		t.Fatalf("recorded %q", actions)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(actions[i], prefix) {
+			t.Errorf("action %d = %q, want %s...", i, actions[i], prefix)
+		}
+	}
+}
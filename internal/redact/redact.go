@@ -0,0 +1,201 @@
+// Package redact scrubs secrets from text before it is logged, stored or
+// returned to clients.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// DefaultReplacement is substituted for every redacted value
+const DefaultReplacement = "[REDACTED]"
+
+// minLiteralLength avoids redacting short configured values that would
+// match ordinary text
+const minLiteralLength = 6
+
+type rule struct {
+	re *regexp.Regexp
+	// keep is the number of leading capture groups preserved in front of
+	// the replacement, e.g. the "api_key=" of a key/value pair
+	keep int
+}
+
+// builtinRules match well-known credential formats
+var builtinRules = []rule{
+	// OpenAI, OpenRouter and Anthropic style keys
+	{re: regexp.MustCompile(`\bsk-(?:or-v1-|ant-[a-z0-9]+-|proj-)?[A-Za-z0-9_\-]{20,}`)},
+	// GitHub tokens
+	{re: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{30,}\b`)},
+	{re: regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{30,}\b`)},
+	// AWS access key IDs
+	{re: regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+	// Slack tokens
+	{re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	// JSON Web Tokens
+	{re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	// Authorization: Bearer <token>
+	{re: regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/=-]{8,}`), keep: 1},
+	// api_key=..., "token": "...", password: ...
+	{re: regexp.MustCompile(`(?i)(\b(?:api[_-]?key|access[_-]?token|auth[_-]?token|token|secret|client[_-]?secret|password|passwd|pwd)"?\s*[:=]\s*"?)([^\s"',;&]{4,})`), keep: 1},
+}
+
+// Redactor replaces secrets in text
+type Redactor struct {
+	rules       []rule
+	literals    []string
+	replacement string
+}
+
+// New builds a redactor from the built-in rules, extra regular expressions
+// and literal secret values
+func New(patterns []string, literals []string, replacement string) (*Redactor, error) {
+	if replacement == "" {
+		replacement = DefaultReplacement
+	}
+	r := &Redactor{
+		rules:       append([]rule(nil), builtinRules...),
+		replacement: replacement,
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.rules = append(r.rules, rule{re: re})
+	}
+
+	seen := make(map[string]bool)
+	for _, l := range literals {
+		if len(l) >= minLiteralLength && !seen[l] {
+			seen[l] = true
+			r.literals = append(r.literals, l)
+		}
+	}
+	// Longest first so a secret containing another is replaced whole
+	sort.Slice(r.literals, func(i, j int) bool { return len(r.literals[i]) > len(r.literals[j]) })
+	return r, nil
+}
+
+// FromConfig builds a redactor from the redaction config section and the
+// credentials held in the configuration. It returns nil when redaction
+// is disabled.
+func FromConfig(cfg *config.Config) (*Redactor, error) {
+	if !cfg.Redaction.Enabled {
+		return nil, nil
+	}
+	return New(cfg.Redaction.Patterns, Secrets(cfg), cfg.Redaction.Replacement)
+}
+
+// Secrets returns the credential values held in a configuration
+func Secrets(cfg *config.Config) []string {
+	var out []string
+	for _, p := range cfg.Providers {
+		out = append(out, p.APIKey)
+	}
+	out = append(out, cfg.Project.APIKey, cfg.API.CallbackSecret, cfg.Review.WebhookSecret, cfg.MCP.Token)
+	for _, k := range cfg.Auth.Keys {
+		out = append(out, k.Token)
+	}
+	for _, srv := range cfg.MCPServers {
+		for k, v := range srv.Env {
+			if secretName(k) {
+				out = append(out, v)
+			}
+		}
+		for k, v := range srv.Headers {
+			if secretName(k) {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// secretName reports whether an environment variable or header, such as
+// GITHUB_TOKEN or X-Api-Key, holds a credential
+func secretName(name string) bool {
+	name = strings.ToLower(strings.ReplaceAll(name, "-", "_"))
+	return name == "authorization" || strings.HasSuffix(name, "_key") || config.IsSecretPath(name)
+}
+
+// String returns s with every secret replaced
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, l := range r.literals {
+		if strings.Contains(s, l) {
+			s = strings.ReplaceAll(s, l, r.replacement)
+		}
+	}
+	for _, rl := range r.rules {
+		if rl.keep == 0 {
+			s = rl.re.ReplaceAllLiteralString(s, r.replacement)
+			continue
+		}
+		s = rl.re.ReplaceAllStringFunc(s, func(match string) string {
+			groups := rl.re.FindStringSubmatch(match)
+			var b strings.Builder
+			for _, g := range groups[1 : rl.keep+1] {
+				b.WriteString(g)
+			}
+			if strings.Contains(match, r.replacement) {
+				return match
+			}
+			b.WriteString(r.replacement)
+			return b.String()
+		})
+	}
+	return s
+}
+
+// HasCredential reports whether s contains a token in one of the
+// well-known credential formats. Unlike redaction it ignores key/value
+// pairs, which in source code are mostly variables rather than secrets.
+func HasCredential(s string) bool {
+	for _, rl := range builtinRules {
+		if rl.keep == 0 && rl.re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+var defaultRedactor atomic.Pointer[Redactor]
+
+func init() {
+	r, _ := New(nil, nil, "")
+	defaultRedactor.Store(r)
+}
+
+// SetDefault installs the process-wide redactor used by String. A nil
+// redactor disables redaction.
+func SetDefault(r *Redactor) {
+	defaultRedactor.Store(r)
+}
+
+// Default returns the process-wide redactor
+func Default() *Redactor {
+	return defaultRedactor.Load()
+}
+
+// String redacts s with the process-wide redactor
+func String(s string) string {
+	return Default().String(s)
+}
+
+// Install builds a redactor from cfg and makes it the process-wide default
+func Install(cfg *config.Config) error {
+	r, err := FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	SetDefault(r)
+	return nil
+}
@@ -0,0 +1,67 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestRedactor_String(t *testing.T) {
+	r, err := New([]string{`INTERNAL-\d{4}`}, []string{"hunter2-secret", "abc"}, "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		in       string
+		want     string
+		mustDrop string
+	}{
+		{"key sk-or-v1-0123456789abcdef0123456789", "key [REDACTED]", "sk-or-v1"},
+		{"Authorization: Bearer abcdefghijklmnop", "Authorization: Bearer [REDACTED]", "abcdefghijklmnop"},
+		{`{"api_key": "plainvalue123"}`, `{"api_key": "[REDACTED]"}`, "plainvalue123"},
+		{"login password=letmein now", "login password=[REDACTED] now", "letmein"},
+		{"pass is hunter2-secret", "pass is [REDACTED]", "hunter2-secret"},
+		{"ticket INTERNAL-1234 done", "ticket [REDACTED] done", "INTERNAL-1234"},
+		{"abc stays, too short", "abc stays, too short", ""},
+		{"nothing to see", "nothing to see", ""},
+	}
+	for _, tt := range tests {
+		got := r.String(tt.in)
+		if got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if tt.mustDrop != "" && strings.Contains(got, tt.mustDrop) {
+			t.Errorf("String(%q) leaked %q", tt.in, tt.mustDrop)
+		}
+	}
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	if _, err := New([]string{"("}, nil, ""); err == nil {
+		t.Error("New() accepted an invalid pattern")
+	}
+}
+
+func TestNilRedactorIsNoop(t *testing.T) {
+	var r *Redactor
+	if got := r.String("password=secret1"); got != "password=secret1" {
+		t.Errorf("nil redactor changed input: %q", got)
+	}
+}
+
+func TestSecrets_MCPServers(t *testing.T) {
+	cfg := &config.Config{MCPServers: []config.MCPServerConfig{{
+		Name:    "db",
+		Env:     map[string]string{"GITHUB_TOKEN": "ghp-env-value", "LOG_LEVEL": "debug"},
+		Headers: map[string]string{"X-Api-Key": "header-key-value"},
+	}}}
+	secrets := strings.Join(Secrets(cfg), " ")
+	if !strings.Contains(secrets, "ghp-env-value") || !strings.Contains(secrets, "header-key-value") {
+		t.Errorf("Secrets() = %q, want the token and the key", secrets)
+	}
+	if strings.Contains(secrets, "debug") {
+		t.Errorf("Secrets() = %q includes a plain setting", secrets)
+	}
+}
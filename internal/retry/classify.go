@@ -0,0 +1,49 @@
+package retry
+
+import (
+	"context"
+	"errors"
+)
+
+// Class is the kind of failure an error shows, by which retry policies
+// choose what to retry
+type Class string
+
+const (
+	ClassTimeout   Class = "timeout"    // a deadline passed
+	ClassRateLimit Class = "rate_limit" // the callee throttled the caller
+	ClassTransient Class = "transient"  // a network or server error that may pass
+	ClassPermanent Class = "permanent"  // anything else
+)
+
+// Classes lists every class Classify returns
+var Classes = []Class{ClassTimeout, ClassRateLimit, ClassTransient, ClassPermanent}
+
+// Classify tells what kind of failure err is, from its chain and, for
+// errors that crossed a process boundary as text, its message
+func Classify(err error) Class {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ClassTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return ClassPermanent
+	}
+	msg := err.Error()
+	for _, pattern := range []string{"timeout", "timed out", "deadline exceeded"} {
+		if containsInsensitive(msg, pattern) {
+			return ClassTimeout
+		}
+	}
+	for _, pattern := range []string{"429", "rate limit", "too many requests"} {
+		if containsInsensitive(msg, pattern) {
+			return ClassRateLimit
+		}
+	}
+	if DefaultIsRetryable(err) {
+		return ClassTransient
+	}
+	return ClassPermanent
+}
@@ -0,0 +1,458 @@
+// Package review puts reviewer agents to work on GitHub pull requests. The
+// GitHub webhook delivers pull_request events; every pull request opened,
+// reopened or pushed to becomes a review task for an idle reviewer agent,
+// which runs the diff review tool on the pull request's diff and posts the
+// findings back as a GitHub review with comments on the lines they concern.
+package review
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// Task metadata of review tasks
+const (
+	// MetaRepo, MetaNumber and MetaHeadSHA name the pull request and the
+	// commit under review
+	MetaRepo    = "repo"
+	MetaNumber  = "pull_number"
+	MetaHeadSHA = "head_sha"
+	// MetaURL is the pull request's page
+	MetaURL = "pull_request"
+	// MetaReview is the posted review's page
+	MetaReview = "review"
+)
+
+// Label and Source mark review tasks
+const (
+	Label  = "review"
+	Source = "github"
+)
+
+// cause is recorded on the transitions the pipeline makes
+var cause = agents.Cause{Actor: "github"}
+
+// source names the pipeline in task logs
+const source = "review"
+
+// reviewedActions are the pull_request actions that ask for a review
+var reviewedActions = map[string]bool{
+	"opened":           true,
+	"reopened":         true,
+	"synchronize":      true,
+	"ready_for_review": true,
+}
+
+// GitHub fetches pull request diffs and posts reviews; *tools.GitHubTool
+// implements it
+type GitHub interface {
+	PullRequestDiff(ctx context.Context, repo string, number int) (string, error)
+	PostReview(ctx context.Context, repo string, number int, sha, body, event string, comments []tools.ReviewComment) (string, error)
+}
+
+// Reviewer reviews a diff; *tools.DiffReviewTool implements it
+type Reviewer interface {
+	Review(ctx context.Context, diff string) (*tools.Review, error)
+}
+
+// PullRequestEvent is the part of a pull_request webhook payload the
+// pipeline reads
+type PullRequestEvent struct {
+	Action      string      `json:"action"`
+	Number      int         `json:"number"`
+	PullRequest PullRequest `json:"pull_request"`
+	Repository  struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// PullRequest is a pull request as GitHub describes it
+type PullRequest struct {
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	Draft   bool   `json:"draft"`
+	Head    struct {
+		SHA string `json:"sha"`
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// Delivery is what a webhook delivery came to
+type Delivery struct {
+	// Task is the review task of the pull request
+	Task *agents.Task `json:"task,omitempty"`
+	// Duplicate is set when the commit already had a review task
+	Duplicate bool `json:"duplicate,omitempty"`
+	// Ignored tells why the delivery made no task
+	Ignored string `json:"ignored,omitempty"`
+}
+
+// Pipeline turns pull_request events into review tasks and reviews them
+type Pipeline struct {
+	cfg      config.ReviewConfig
+	registry *agents.Registry
+	github   GitHub
+	reviewer Reviewer
+	logger   *log.Logger
+
+	// mu serializes deliveries and assignment, so that a redelivered
+	// event or two free reviewers cannot pick the same work twice
+	mu sync.Mutex
+}
+
+// New returns a pipeline reviewing with reviewer and talking to GitHub
+// through github
+func New(cfg config.ReviewConfig, registry *agents.Registry, github GitHub, reviewer Reviewer) *Pipeline {
+	return &Pipeline{
+		cfg:      cfg,
+		registry: registry,
+		github:   github,
+		reviewer: reviewer,
+		logger:   logging.New("review", "[REVIEW] ", log.Writer()),
+	}
+}
+
+// VerifySignature reports whether signature, the X-Hub-Signature-256
+// header of a delivery, is the HMAC-SHA256 of body under secret
+func VerifySignature(secret string, body []byte, signature string) bool {
+	hexDigest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok || secret == "" {
+		return false
+	}
+	got, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature against the configured secret
+func (p *Pipeline) Verify(body []byte, signature string) bool {
+	return VerifySignature(p.cfg.WebhookSecret, body, signature)
+}
+
+// Receive handles a delivery of event, the X-GitHub-Event header. Pull
+// requests that ask for a review get a task, assigned at once when a
+// reviewer agent is idle; other events are ignored. It fails only on a
+// payload that cannot be read.
+func (p *Pipeline) Receive(event string, payload []byte) (*Delivery, error) {
+	if event != "pull_request" {
+		return &Delivery{Ignored: fmt.Sprintf("%q events are not reviewed", event)}, nil
+	}
+	var e PullRequestEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("invalid pull_request payload: %w", err)
+	}
+	repo := e.Repository.FullName
+	if repo == "" || e.Number <= 0 || e.PullRequest.Head.SHA == "" {
+		return nil, fmt.Errorf("pull_request payload lacks the repository, number or head commit")
+	}
+
+	switch {
+	case !reviewedActions[e.Action]:
+		return &Delivery{Ignored: fmt.Sprintf("action %q does not ask for a review", e.Action)}, nil
+	case e.PullRequest.Draft && !p.cfg.Drafts:
+		return &Delivery{Ignored: "draft pull requests are not reviewed"}, nil
+	case !p.watches(repo):
+		return &Delivery{Ignored: fmt.Sprintf("repository %s is not reviewed", repo)}, nil
+	}
+
+	p.mu.Lock()
+	if task := p.existing(repo, e.Number, e.PullRequest.Head.SHA); task != nil {
+		p.mu.Unlock()
+		return &Delivery{Task: task, Duplicate: true}, nil
+	}
+	task := p.registry.CreateTaskBy(newTask(&e), agents.Cause{
+		Actor:  cause.Actor,
+		Reason: fmt.Sprintf("pull request %s#%d %s", repo, e.Number, e.Action),
+	})
+	p.assignLocked()
+	p.mu.Unlock()
+
+	p.logger.Printf("Created review task %s for %s#%d at %s", task.ID, repo, e.Number, shortSHA(e.PullRequest.Head.SHA))
+	if current, ok := p.registry.GetTask(task.ID); ok {
+		task = current
+	}
+	return &Delivery{Task: task}, nil
+}
+
+// newTask is the review task of a pull request event
+func newTask(e *PullRequestEvent) *agents.Task {
+	repo := e.Repository.FullName
+	pr := &e.PullRequest
+	description := fmt.Sprintf("Review pull request %s#%d by @%s, %s into %s.\n%s", repo, e.Number, pr.User.Login, pr.Head.Ref, pr.Base.Ref, pr.HTMLURL)
+	if body := strings.TrimSpace(pr.Body); body != "" {
+		description += "\n\n" + body
+	}
+	return &agents.Task{
+		Title:       fmt.Sprintf("Review #%d: %s", e.Number, pr.Title),
+		Description: description,
+		Priority:    agents.PriorityMedium,
+		Labels:      []string{Label},
+		ExternalID:  fmt.Sprintf("%s#%d", repo, e.Number),
+		Source:      Source,
+		Meta: map[string]string{
+			MetaRepo:    repo,
+			MetaNumber:  strconv.Itoa(e.Number),
+			MetaHeadSHA: pr.Head.SHA,
+			MetaURL:     pr.HTMLURL,
+		},
+	}
+}
+
+// watches reports whether the configuration reviews repo
+func (p *Pipeline) watches(repo string) bool {
+	if len(p.cfg.Repositories) == 0 {
+		return true
+	}
+	for _, r := range p.cfg.Repositories {
+		if strings.EqualFold(r, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// existing returns the review task of a commit, unless it failed or was
+// cancelled
+func (p *Pipeline) existing(repo string, number int, sha string) *agents.Task {
+	for _, t := range p.registry.ListTasks() {
+		if !isReviewTask(t) || t.Meta[MetaRepo] != repo || t.Meta[MetaNumber] != strconv.Itoa(number) || t.Meta[MetaHeadSHA] != sha {
+			continue
+		}
+		if t.Status != agents.TaskStatusFailed && t.Status != agents.TaskStatusCancelled {
+			return t
+		}
+	}
+	return nil
+}
+
+// isReviewTask reports whether the pipeline made task
+func isReviewTask(task *agents.Task) bool {
+	return task.Source == Source && task.Meta[MetaRepo] != "" && task.Meta[MetaNumber] != "" && task.Meta[MetaHeadSHA] != ""
+}
+
+// assign hands pending review tasks, oldest first, to idle reviewer agents
+func (p *Pipeline) assign() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.assignLocked()
+}
+
+func (p *Pipeline) assignLocked() {
+	var pending []*agents.Task
+	for _, t := range p.registry.GetPendingTasks() {
+		if isReviewTask(t) {
+			pending = append(pending, t)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+
+	for _, agent := range p.registry.GetAgentsByType(agents.AgentTypeReviewer) {
+		if len(pending) == 0 {
+			return
+		}
+		if agent.Status != agents.StatusIdle {
+			continue
+		}
+		task := pending[0]
+		err := p.registry.AssignTaskBy(task.ID, agent.ID, agents.Cause{
+			Actor:  cause.Actor,
+			Reason: fmt.Sprintf("idle reviewer agent %s", agent.Name),
+		})
+		if err != nil {
+			continue
+		}
+		pending = pending[1:]
+	}
+}
+
+// Run reviews every review task once it is assigned, and assigns waiting
+// ones as reviewer agents free up, until ctx is done or events is closed.
+// Review tasks created before Run starts are assigned on its first event.
+func (p *Pipeline) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			switch e.Type {
+			case agents.EventTaskAssigned:
+				task, ok := e.Data["task"].(agents.Task)
+				if ok && isReviewTask(&task) {
+					go p.review(ctx, &task)
+				}
+			case agents.EventTaskCompleted, agents.EventTaskFailed, agents.EventTaskCancelled,
+				agents.EventAgentCreated, agents.EventAgentStarted, agents.EventAgentUpdated:
+				p.assign()
+			}
+		}
+	}
+}
+
+// review reviews the pull request of an assigned task, posts the review
+// and completes the task with its outcome
+func (p *Pipeline) review(ctx context.Context, task *agents.Task) {
+	start := time.Now()
+	posted, r, err := p.Review(ctx, task)
+
+	if current, ok := p.registry.GetTask(task.ID); !ok || current.Status == agents.TaskStatusCancelled {
+		return
+	}
+	result := &agents.TaskResult{
+		Success:   err == nil,
+		Duration:  time.Since(start).Milliseconds(),
+		Timestamp: time.Now(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		p.logger.Printf("ERROR: Review task %s failed: %v", task.ID, err)
+	} else {
+		result.Output = fmt.Sprintf("%s\n\nVerdict: %s, %s. %s", r.Summary, r.Verdict, countComments(r.Comments), posted)
+		result.Model = r.Model
+		if posted != "" {
+			result.Artifacts = []string{posted}
+		}
+	}
+	if err := p.registry.CompleteTaskBy(task.ID, result, cause); err != nil {
+		p.logger.Printf("ERROR: Failed to complete review task %s: %v", task.ID, err)
+	}
+}
+
+// Review fetches the diff of a review task's pull request, reviews it and
+// posts the review to GitHub. It returns the URL of the posted review.
+func (p *Pipeline) Review(ctx context.Context, task *agents.Task) (string, *tools.Review, error) {
+	repo := task.Meta[MetaRepo]
+	number, err := strconv.Atoi(task.Meta[MetaNumber])
+	if err != nil || repo == "" {
+		return "", nil, fmt.Errorf("task %s does not name a pull request", task.ID)
+	}
+	sha := task.Meta[MetaHeadSHA]
+
+	tasklog.RecordTool(task.ID, tasklog.KindToolCall, source, "github", "Fetch the diff of %s#%d", repo, number)
+	diff, err := p.github.PullRequestDiff(ctx, repo, number)
+	if err != nil {
+		tasklog.RecordTool(task.ID, tasklog.KindError, source, "github", "Fetching the diff failed: %v", err)
+		return "", nil, fmt.Errorf("fetching the diff: %w", err)
+	}
+	tasklog.RecordTool(task.ID, tasklog.KindOutput, source, "github", "Diff of %d bytes", len(diff))
+	tasklog.RecordTool(task.ID, tasklog.KindToolCall, source, "diff_review", "Review the diff")
+	r, err := p.reviewer.Review(ctx, diff)
+	if err != nil {
+		tasklog.RecordTool(task.ID, tasklog.KindError, source, "diff_review", "Review failed: %v", err)
+		return "", nil, fmt.Errorf("reviewing the diff: %w", err)
+	}
+	tasklog.RecordTool(task.ID, tasklog.KindOutput, source, "diff_review", "Verdict %s, %s: %s", r.Verdict, countComments(r.Comments), r.Summary)
+	event := r.Event()
+	if p.cfg.CommentOnly {
+		event = "COMMENT"
+	}
+	tasklog.RecordTool(task.ID, tasklog.KindToolCall, source, "github", "Post a %s review on %s#%d", event, repo, number)
+	url, err := p.github.PostReview(ctx, repo, number, sha, Body(r, task), event, r.Comments)
+	if err != nil {
+		tasklog.RecordTool(task.ID, tasklog.KindError, source, "github", "Posting the review failed: %v", err)
+		return "", r, fmt.Errorf("posting the review: %w", err)
+	}
+	p.logger.Printf("Posted review of %s#%d: %s", repo, number, r.Verdict)
+	if url != "" {
+		if err := p.registry.SetTaskMeta(task.ID, map[string]string{MetaReview: url}); err != nil {
+			p.logger.Printf("ERROR: Failed to record review %s on task %s: %v", url, task.ID, err)
+		}
+	}
+	return url, r, nil
+}
+
+// verdicts are the verdicts as they read in a review
+var verdicts = map[string]string{
+	tools.VerdictApprove:        "approve",
+	tools.VerdictComment:        "comment",
+	tools.VerdictRequestChanges: "request changes",
+}
+
+// Body is the text of a posted review: the summary, the verdict, the
+// findings the diff does not show and who made the review
+func Body(r *tools.Review, task *agents.Task) string {
+	var b strings.Builder
+	b.WriteString("## skagent review\n\n")
+	if s := strings.TrimSpace(r.Summary); s != "" {
+		fmt.Fprintf(&b, "%s\n\n", s)
+	}
+	fmt.Fprintf(&b, "**Verdict:** %s, %s\n", verdicts[r.Verdict], countComments(r.Comments))
+	if len(r.Notes) > 0 {
+		b.WriteString("\n### Other notes\n\n")
+		for _, n := range r.Notes {
+			fmt.Fprintf(&b, "- %s\n", n)
+		}
+	}
+	b.WriteString("\n---\nReviewed by skagent")
+	if r.Model != "" {
+		fmt.Fprintf(&b, " with %s", r.Model)
+	}
+	fmt.Fprintf(&b, " for task `%s`.\n", task.ID)
+	return b.String()
+}
+
+// countComments describes comments by severity, e.g. "1 error, 2
+// warnings"
+func countComments(comments []tools.ReviewComment) string {
+	if len(comments) == 0 {
+		return "no comments"
+	}
+	counts := make(map[string]int)
+	for _, c := range comments {
+		counts[c.Severity]++
+	}
+	var parts []string
+	for _, kind := range []struct{ severity, one, many string }{
+		{tools.SeverityError, "error", "errors"},
+		{tools.SeverityWarning, "warning", "warnings"},
+		{tools.SeverityInfo, "suggestion", "suggestions"},
+	} {
+		switch n := counts[kind.severity]; n {
+		case 0:
+		case 1:
+			parts = append(parts, "1 "+kind.one)
+		default:
+			parts = append(parts, fmt.Sprintf("%d %s", n, kind.many))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
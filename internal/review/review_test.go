@@ -0,0 +1,134 @@
+package review
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+const diff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,3 @@
+ package main
++var debug = true
+ func main() {}
+`
+
+const payload = `{"action": "opened", "number": 7,
+	"pull_request": {"title": "Add debug flag", "html_url": "https://github.com/o/r/pull/7",
+		"head": {"sha": "abc1234def", "ref": "debug"}, "base": {"ref": "main"}, "user": {"login": "dev"}},
+	"repository": {"full_name": "o/r"}}`
+
+type fakeGitHub struct {
+	mu       sync.Mutex
+	posted   chan string
+	event    string
+	body     string
+	comments []tools.ReviewComment
+}
+
+func (g *fakeGitHub) PullRequestDiff(ctx context.Context, repo string, number int) (string, error) {
+	return diff, nil
+}
+
+func (g *fakeGitHub) PostReview(ctx context.Context, repo string, number int, sha, body, event string, comments []tools.ReviewComment) (string, error) {
+	g.mu.Lock()
+	g.event, g.body, g.comments = event, body, comments
+	g.mu.Unlock()
+	url := "https://github.com/" + repo + "/pull/7#pullrequestreview-1"
+	g.posted <- url
+	return url, nil
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	if !VerifySignature("s3cret", []byte(payload), sign("s3cret", payload)) {
+		t.Error("valid signature rejected")
+	}
+	for _, sig := range []string{"", sign("other", payload), "sha1=00", "sha256=zz"} {
+		if VerifySignature("s3cret", []byte(payload), sig) {
+			t.Errorf("signature %q accepted", sig)
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := agents.NewRegistry(ctx)
+	reviewer := &agents.Agent{Name: "Reviewer", Type: agents.AgentTypeReviewer}
+	registry.RegisterAgent(reviewer)
+
+	provider := ai.NewMockProvider(`{"summary": "Adds a debug flag.", "verdict": "approve",
+		"comments": [{"path": "main.go", "line": 2, "severity": "warning", "body": "debug should default to false"},
+		{"path": "other.go", "line": 9, "severity": "info", "body": "unrelated"}]}`)
+	github := &fakeGitHub{posted: make(chan string, 1)}
+	p := New(config.ReviewConfig{WebhookSecret: "s3cret"}, registry, github,
+		tools.NewDiffReviewTool(func() ai.Provider { return provider }, 0))
+	events, unsubscribe := registry.Subscribe(64)
+	defer unsubscribe()
+	go p.Run(ctx, events)
+
+	d, err := p.Receive("pull_request", []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Task == nil || d.Task.AssignedTo != reviewer.ID || d.Task.Meta[MetaHeadSHA] != "abc1234def" {
+		t.Fatalf("delivery = %+v", d)
+	}
+
+	select {
+	case <-github.posted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no review posted")
+	}
+	github.mu.Lock()
+	if github.event != "COMMENT" || len(github.comments) != 1 || !strings.Contains(github.body, "other.go:9: unrelated") {
+		t.Errorf("posted %s %+v\n%s", github.event, github.comments, github.body)
+	}
+	github.mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		task, _ := registry.GetTask(d.Task.ID)
+		if task.Status == agents.TaskStatusCompleted {
+			if !task.Result.Success || task.Meta[MetaReview] == "" {
+				t.Errorf("task = %+v, result %+v", task, task.Result)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task still %s", task.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A redelivery of the same commit does not make another task
+	again, err := p.Receive("pull_request", []byte(payload))
+	if err != nil || !again.Duplicate || again.Task.ID != d.Task.ID {
+		t.Errorf("redelivery = %+v, %v", again, err)
+	}
+	if ignored, _ := p.Receive("push", []byte(`{}`)); ignored.Ignored == "" {
+		t.Error("push event not ignored")
+	}
+	closed := strings.Replace(payload, `"opened"`, `"closed"`, 1)
+	if ignored, _ := p.Receive("pull_request", []byte(closed)); ignored.Ignored == "" {
+		t.Error("closed pull request not ignored")
+	}
+}
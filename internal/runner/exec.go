@@ -0,0 +1,198 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/lessons"
+	"github.com/biodoia/skagent/internal/modelpolicy"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// maxToolOutput bounds the part of a tool's output given back to the
+// model, in bytes
+const maxToolOutput = 16 << 10
+
+// defaultSystemPrompt is given to agents without a system prompt of their
+// own
+const defaultSystemPrompt = "You are an autonomous software agent. Complete the task you are given, " +
+	"then answer with the result: what you did and what it produced."
+
+// toolCall matches a tool call in a reply: <tool name="NAME">INPUT</tool>
+var toolCall = regexp.MustCompile(`(?s)<tool name="([^"]+)">(.*?)</tool>`)
+
+// run prompts the agent's model with the task until it answers without
+// calling a tool, running the tools it calls in between. Agents naming no
+// model of their own run on the model policy's pick, moving to a stronger
+// model each time a run fails.
+func (r *Runner) run(ctx context.Context, task *agents.Task, agent *agents.Agent) *agents.TaskResult {
+	p, err := r.provider(agent)
+	if err != nil {
+		return &agents.TaskResult{Error: err.Error()}
+	}
+	tm := r.tools(agent.Workspace)
+	system := systemPrompt(agent, tm)
+	if text := r.lessonPrompt(task, agent); text != "" {
+		system += "\n\n" + text
+	}
+
+	model := agent.Config.Model
+	if model == "" && r.policy != nil {
+		// Any model will do: this only asks whether p can switch
+		if _, ok := ai.WithModel(p, model); ok {
+			return r.escalate(ctx, task, agent, p, tm, system)
+		}
+		r.logger.Printf("WARN: Provider %s cannot switch models; agent %s runs with its own model", p.Name(), agent.ID)
+	}
+	if model != "" {
+		if m, ok := ai.WithModel(p, model); ok {
+			result, _ := r.converse(ctx, task, agent, m, tm, system)
+			result.Model = model
+			return result
+		}
+		r.logger.Printf("WARN: Provider %s cannot switch to %s; agent %s runs with its own model", p.Name(), model, agent.ID)
+	}
+	result, _ := r.converse(ctx, task, agent, p, tm, system)
+	result.Model = p.Name()
+	return result
+}
+
+// escalate runs the task through the model policy, which starts on the
+// cheapest model fit for it and moves up a tier after each failed run
+func (r *Runner) escalate(ctx context.Context, task *agents.Task, agent *agents.Agent, p ai.Provider, tm *tools.ToolManager, system string) *agents.TaskResult {
+	var result *agents.TaskResult
+	d, err := r.policy.Execute(ctx, task, func(ctx context.Context, model string) error {
+		if result != nil {
+			tasklog.Record(task.ID, tasklog.KindNote, agent.ID, "Run on %s failed; trying %s", result.Model, model)
+		}
+		m, _ := ai.WithModel(p, model)
+		var err error
+		result, err = r.converse(ctx, task, agent, m, tm, system)
+		result.Model = model
+		return err
+	})
+	tasklog.Record(task.ID, tasklog.KindNote, agent.ID, "Model %s: %s", d.Model, strings.Join(d.Reasons, "; "))
+	if errors.Is(err, modelpolicy.ErrExhausted) {
+		tasklog.Record(task.ID, tasklog.KindNote, agent.ID, "%v", err)
+	}
+	return result
+}
+
+// converse holds the conversation of a run with provider; the error,
+// returned along with a failed result, is what failed the run
+func (r *Runner) converse(ctx context.Context, task *agents.Task, agent *agents.Agent, provider ai.Provider, tm *tools.ToolManager, system string) (*agents.TaskResult, error) {
+	result := &agents.TaskResult{}
+	messages := []ai.Message{{Role: "user", Content: taskPrompt(task)}}
+	tasklog.Record(task.ID, tasklog.KindPrompt, agent.ID, "%s", messages[0].Content)
+
+	steps := r.cfg.MaxSteps
+	if steps <= 0 {
+		steps = defaultSteps
+	}
+	for step := 0; ; step++ {
+		reply, err := provider.Complete(ctx, messages, system)
+		if err != nil {
+			result.Error = err.Error()
+			return result, err
+		}
+		tasklog.Record(task.ID, tasklog.KindResponse, agent.ID, "%s", reply)
+		messages = append(messages, ai.Message{Role: "assistant", Content: reply})
+
+		m := toolCall.FindStringSubmatch(reply)
+		if m == nil {
+			result.Success = true
+			result.Output = strings.TrimSpace(reply)
+			return result, nil
+		}
+		if step == steps {
+			result.Error = fmt.Sprintf("the agent was still calling tools after %d steps", steps)
+			return result, errors.New(result.Error)
+		}
+		name, input := m[1], strings.TrimSpace(m[2])
+		tasklog.RecordTool(task.ID, tasklog.KindToolCall, agent.ID, name, "%s", input)
+		output, err := tm.ExecuteByName(ctx, name, input)
+		if err != nil {
+			output = "error: " + err.Error()
+		}
+		if len(output) > maxToolOutput {
+			output = output[:maxToolOutput] + "\n[truncated]"
+		}
+		tasklog.RecordTool(task.ID, tasklog.KindOutput, agent.ID, name, "%s", output)
+		messages = append(messages, ai.Message{
+			Role:    "user",
+			Content: fmt.Sprintf("Output of %s:\n%s", name, output),
+		})
+	}
+}
+
+// lessonPrompt returns what the agent learned from tasks similar to task,
+// for its system prompt. The lessons given for a task of the registry are
+// credited with its outcome.
+func (r *Runner) lessonPrompt(task *agents.Task, agent *agents.Agent) string {
+	if r.lessons == nil {
+		return ""
+	}
+	if _, ok := r.registry.GetTask(task.ID); ok {
+		return lessons.Prompt(r.lessons.ForTask(task, agent.ID))
+	}
+	return lessons.Prompt(r.lessons.Relevant(agent.ID, task.Title+"\n"+task.Description, task.Labels, 0))
+}
+
+// systemPrompt is the agent's system prompt followed by how to call the
+// tools of tm
+func systemPrompt(agent *agents.Agent, tm *tools.ToolManager) string {
+	var b strings.Builder
+	if agent.Config.SystemPrompt != "" {
+		b.WriteString(agent.Config.SystemPrompt)
+	} else {
+		b.WriteString(defaultSystemPrompt)
+	}
+	descriptions := tm.GetToolDescriptions()
+	if len(descriptions) == 0 {
+		return b.String()
+	}
+	names := make([]string, 0, len(descriptions))
+	for name := range descriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.WriteString("\n\nTo use a tool, reply with only a call such as <tool name=\"NAME\">INPUT</tool>; " +
+		"its output comes back in the next message. Reply without a call when the task is done. Tools:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "- %s: %s\n", name, descriptions[name])
+	}
+	return b.String()
+}
+
+// taskPrompt describes the task to the agent
+func taskPrompt(task *agents.Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Task: %s\n", task.Title)
+	if task.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", task.Description)
+	}
+	if len(task.AcceptanceCriteria) > 0 {
+		b.WriteString("\nAcceptance criteria:\n")
+		for _, c := range task.AcceptanceCriteria {
+			fmt.Fprintf(&b, "- %s\n", c)
+		}
+	}
+	if len(task.Labels) > 0 {
+		fmt.Fprintf(&b, "\nLabels: %s\n", strings.Join(task.Labels, ", "))
+	}
+	if task.Revision > 0 && task.Feedback != "" {
+		fmt.Fprintf(&b, "\nThis is revision %d. Your previous result was sent back with this feedback:\n%s\n",
+			task.Revision, task.Feedback)
+		if task.Result != nil && task.Result.Output != "" {
+			fmt.Fprintf(&b, "\nPrevious result:\n%s\n", task.Result.Output)
+		}
+	}
+	return b.String()
+}
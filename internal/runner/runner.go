@@ -0,0 +1,325 @@
+// Package runner is the agent runtime: it runs the tasks assigned to
+// agents. Each run prompts the agent's model with the task, lets the model
+// call the tools of the tool manager, and records its final answer as the
+// task's result. Runs are bounded in number, steps and time; a task that
+// is cancelled or reassigned meanwhile loses its run.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/lessons"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/modelpolicy"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// Defaults of the zero values of config.RunnerConfig
+const (
+	defaultConcurrent = 4
+	defaultSteps      = 8
+	defaultTimeout    = 600 * time.Second
+)
+
+// sweepInterval is how often the runner looks for assigned tasks it has
+// not started, in case it missed their events
+const sweepInterval = 10 * time.Second
+
+// cause is recorded on the transitions of runs
+var cause = agents.Cause{Actor: "runner"}
+
+// Providers returns the model of an agent from the name of its provider;
+// the empty name is the default provider
+type Providers func(name string) (ai.Provider, error)
+
+// Tools returns the tool manager of the agents of a workspace
+type Tools func(workspace string) *tools.ToolManager
+
+// Runner runs the tasks assigned to agents
+type Runner struct {
+	cfg       config.RunnerConfig
+	registry  *agents.Registry
+	tools     Tools
+	providers Providers
+	logger    *log.Logger
+
+	// lessons, when set, go into the prompts of the tasks they suit
+	lessons *lessons.Store
+	// policy, when set, picks the model of agents that name none
+	policy *modelpolicy.Policy
+
+	// slots bounds the runs at once
+	slots chan struct{}
+
+	mu sync.Mutex
+	// running cancels the run of each task, by task ID
+	running map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New returns a runner for the agents of registry, giving their models
+// the tools of their workspace
+func New(cfg config.RunnerConfig, registry *agents.Registry, tm Tools, providers Providers) *Runner {
+	concurrent := cfg.MaxConcurrent
+	if concurrent <= 0 {
+		concurrent = defaultConcurrent
+	}
+	return &Runner{
+		cfg:       cfg,
+		registry:  registry,
+		tools:     tm,
+		providers: providers,
+		logger:    logging.New("runner", "[RUNNER] ", log.Writer()),
+		slots:     make(chan struct{}, concurrent),
+		running:   make(map[string]context.CancelFunc),
+	}
+}
+
+// SetLessons gives each run the lessons its agent learned from similar
+// tasks
+func (r *Runner) SetLessons(store *lessons.Store) {
+	r.lessons = store
+}
+
+// SetModelPolicy has the policy pick the model of the agents that do not
+// name one
+func (r *Runner) SetModelPolicy(p *modelpolicy.Policy) {
+	r.policy = p
+}
+
+// Run starts a run for each task in progress on an agent of the runner, and
+// stops those of tasks cancelled or deleted, until ctx is done or events
+// is closed. Pending tasks are assigned whenever an agent may be free.
+// Runs interrupted by ctx are left in progress, for a restart to requeue.
+func (r *Runner) Run(ctx context.Context, events <-chan agents.Event) {
+	defer r.wg.Wait()
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	r.sweep(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.sweep(ctx)
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			switch ev.Type {
+			case agents.EventTaskCreated, agents.EventAgentCreated, agents.EventAgentStarted:
+				r.registry.AutoAssign(ctx)
+			case agents.EventTaskAssigned, agents.EventTaskStarted:
+				if task, ok := r.registry.GetTask(ev.TaskID); ok {
+					r.start(ctx, task)
+				}
+			case agents.EventTaskCancelled, agents.EventTaskDeleted:
+				r.stop(ev.TaskID)
+			}
+		}
+	}
+}
+
+// sweep assigns pending tasks and starts the runs the runner missed
+func (r *Runner) sweep(ctx context.Context) {
+	r.registry.AutoAssign(ctx)
+	for _, task := range r.registry.ListTasks() {
+		r.start(ctx, task)
+	}
+}
+
+// start runs a task in the background unless it is not assigned to an
+// agent of the runner, was assigned by another instance sharing the store,
+// or already runs
+func (r *Runner) start(ctx context.Context, task *agents.Task) {
+	if task.AssignedTo == "" || task.Status != agents.TaskStatusInProgress || !r.registry.Owns(task) {
+		return
+	}
+	agent, ok := r.registry.GetAgent(task.AssignedTo)
+	if !ok || !r.handles(agent) {
+		return
+	}
+	r.mu.Lock()
+	if _, ok := r.running[task.ID]; ok {
+		r.mu.Unlock()
+		return
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	r.running[task.ID] = cancel
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, task.ID)
+			r.mu.Unlock()
+			cancel()
+		}()
+		select {
+		case r.slots <- struct{}{}:
+		case <-runCtx.Done():
+			return
+		}
+		defer func() { <-r.slots }()
+		r.execute(runCtx, task.ID, agent.ID)
+		// The agent may be free for the next task
+		if ctx.Err() == nil {
+			r.registry.AutoAssign(ctx)
+		}
+	}()
+}
+
+// stop cancels the run of a task, if any
+func (r *Runner) stop(taskID string) {
+	r.mu.Lock()
+	cancel, ok := r.running[taskID]
+	r.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// Running returns the number of tasks the runner has started and not
+// finished, including those waiting for a slot
+func (r *Runner) Running() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.running)
+}
+
+// handles reports whether the runner runs the tasks of agent
+func (r *Runner) handles(agent *agents.Agent) bool {
+	if len(r.cfg.AgentTypes) == 0 {
+		return true
+	}
+	for _, t := range r.cfg.AgentTypes {
+		if t == string(agent.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// execute runs a task on an agent and records the result
+func (r *Runner) execute(ctx context.Context, taskID, agentID string) {
+	task, ok1 := r.registry.GetTask(taskID)
+	agent, ok2 := r.registry.GetAgent(agentID)
+	if !ok1 || !ok2 || task.Status != agents.TaskStatusInProgress || task.AssignedTo != agentID || !r.registry.Owns(task) {
+		// Cancelled or reassigned while waiting for a slot
+		return
+	}
+
+	result := r.Execute(ctx, task, agent)
+	if ctx.Err() != nil {
+		// Cancelled, or the runner is shutting down: nothing to record
+		r.logger.Printf("Run of task %s stopped: %v", taskID, ctx.Err())
+		return
+	}
+	if !result.Success {
+		tasklog.Record(taskID, tasklog.KindError, agentID, "%s", result.Error)
+	}
+	if err := r.registry.FinishTask(taskID, agentID, result, cause); err != nil {
+		r.logger.Printf("ERROR: Dropping the result of task %s: %v", taskID, err)
+	}
+}
+
+// Execute runs task on agent and returns the result, without recording
+// it; the run is bounded by the agent's timeout, or the runner's
+func (r *Runner) Execute(ctx context.Context, task *agents.Task, agent *agents.Agent) *agents.TaskResult {
+	timeout := defaultTimeout
+	if r.cfg.Timeout > 0 {
+		timeout = time.Duration(r.cfg.Timeout) * time.Second
+	}
+	if agent.Config.Timeout > 0 {
+		timeout = time.Duration(agent.Config.Timeout) * time.Second
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := r.run(runCtx, task, agent)
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		result.Success = false
+		result.Error = fmt.Sprintf("the run timed out after %s", timeout)
+	}
+	result.Duration = time.Since(start).Milliseconds()
+	result.Timestamp = time.Now()
+	return result
+}
+
+// provider returns the provider an agent runs on
+func (r *Runner) provider(agent *agents.Agent) (ai.Provider, error) {
+	if r.providers == nil {
+		return nil, errors.New("no model provider is configured")
+	}
+	p, err := r.providers(agent.Config.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, errors.New("no model provider is configured")
+	}
+	return p, nil
+}
+
+// ProviderCache resolves provider names against the providers of a
+// configuration, creating each once until the configuration is replaced;
+// the empty name and the default provider are served by fallback, which
+// tracks configuration reloads
+type ProviderCache struct {
+	fallback func() ai.Provider
+
+	mu      sync.Mutex
+	cfg     config.Config
+	created map[string]ai.Provider
+}
+
+// NewProviderCache returns a cache of the providers of cfg
+func NewProviderCache(cfg *config.Config, fallback func() ai.Provider) *ProviderCache {
+	c := &ProviderCache{fallback: fallback}
+	c.SetConfig(cfg)
+	return c
+}
+
+// SetConfig replaces the configuration, as a reload does, dropping the
+// providers created from the previous one
+func (c *ProviderCache) SetConfig(cfg *config.Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg = *cfg
+	c.created = make(map[string]ai.Provider)
+}
+
+// Provider returns the provider named name; it is a Providers
+func (c *ProviderCache) Provider(name string) (ai.Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name == "" || name == string(c.cfg.DefaultProvider) {
+		return c.fallback(), nil
+	}
+	if p, ok := c.created[name]; ok {
+		return p, nil
+	}
+	if _, ok := c.cfg.Providers[config.Provider(name)]; !ok {
+		return nil, fmt.Errorf("provider %q is not configured", name)
+	}
+	cfg := c.cfg
+	cfg.DefaultProvider = config.Provider(name)
+	p, err := ai.CreateProvider(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.created[name] = p
+	return p, nil
+}
@@ -0,0 +1,239 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/lessons"
+	"github.com/biodoia/skagent/internal/modelpolicy"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// echoTool returns its input
+type echoTool struct{}
+
+func (echoTool) Name() string                 { return "echo" }
+func (echoTool) Description() string          { return "repeats its input" }
+func (echoTool) CanHandle(intent string) bool { return false }
+func (echoTool) Execute(ctx context.Context, input string) (string, error) {
+	return "echo: " + input, nil
+}
+
+// start runs a runner for r until the test ends
+func start(t *testing.T, r *agents.Registry, model ai.Provider) *Runner {
+	t.Helper()
+	tm := tools.NewToolManager()
+	tm.AddTool(echoTool{})
+	runner := New(config.RunnerConfig{MaxSteps: 2}, r, func(string) *tools.ToolManager { return tm }, func(string) (ai.Provider, error) { return model, nil })
+	ctx, cancel := context.WithCancel(context.Background())
+	events, unsubscribe := r.Subscribe(1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runner.Run(ctx, events)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		unsubscribe()
+		<-done
+	})
+	return runner
+}
+
+// wait polls the task until done accepts it
+func wait(t *testing.T, r *agents.Registry, id string, done func(*agents.Task) bool) *agents.Task {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		task, _ := r.GetTask(id)
+		if done(task) {
+			return task
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task = %+v", task)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunnerCompletesTasks(t *testing.T) {
+	r := agents.NewRegistry(context.Background())
+	model := ai.NewMockProvider(`Let me check. <tool name="echo">hello</tool>`, "Done: the tool said hello.")
+	start(t, r, model)
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+	task := r.CreateTask(&agents.Task{Title: "Say hello", AcceptanceCriteria: []string{"Says hello"}})
+
+	got := wait(t, r, task.ID, func(t *agents.Task) bool { return t.Status == agents.TaskStatusCompleted })
+	if got.AssignedTo != agent.ID || got.Result == nil || !got.Result.Success ||
+		got.Result.Output != "Done: the tool said hello." || got.Result.Model != "mock" {
+		t.Fatalf("task = %+v, result = %+v", got, got.Result)
+	}
+	calls := model.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("%d calls", len(calls))
+	}
+	if !strings.Contains(calls[0].Messages[0].Content, "- Says hello") ||
+		!strings.Contains(calls[0].SystemPrompt, "- echo: repeats its input") {
+		t.Errorf("first call misses the task or the tools: %+v", calls[0])
+	}
+	if last := calls[1].Messages[2].Content; last != "Output of echo:\necho: hello" {
+		t.Errorf("tool output given back as %q", last)
+	}
+	if a, _ := r.GetAgent(agent.ID); a.Status != agents.StatusIdle || a.Stats.TasksCompleted != 1 {
+		t.Errorf("agent = %+v", a)
+	}
+}
+
+func TestRunnerBoundsSteps(t *testing.T) {
+	r := agents.NewRegistry(context.Background())
+	start(t, r, ai.NewMockProvider(`<tool name="echo">1</tool>`, `<tool name="echo">2</tool>`, `<tool name="echo">3</tool>`))
+	r.CreateAgent("coder", "coder", nil)
+	task := r.CreateTask(&agents.Task{Title: "Loop"})
+
+	got := wait(t, r, task.ID, func(t *agents.Task) bool { return t.Status == agents.TaskStatusCompleted })
+	if got.Result.Success || !strings.Contains(got.Result.Error, "after 2 steps") {
+		t.Fatalf("result = %+v", got.Result)
+	}
+}
+
+func TestRunnerStopsCancelledTasks(t *testing.T) {
+	r := agents.NewRegistry(context.Background())
+	model := ai.NewMockProvider("never sent")
+	model.Latency = time.Hour
+	runner := start(t, r, model)
+	r.CreateAgent("coder", "coder", nil)
+	task := r.CreateTask(&agents.Task{Title: "Slow"})
+
+	wait(t, r, task.ID, func(t *agents.Task) bool { return t.Status == agents.TaskStatusInProgress })
+	if err := r.CancelTask(task.ID, agents.Cause{Actor: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for runner.Running() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the run was not stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, _ := r.GetTask(task.ID); got.Status != agents.TaskStatusCancelled || got.Result != nil {
+		t.Fatalf("task = %+v", got)
+	}
+}
+
+func TestRunnerSkipsTasksOfOtherInstances(t *testing.T) {
+	r := agents.NewRegistry(context.Background())
+	first, _ := r.CreateAgent("coder-1", "coder", nil)
+	foreign := r.CreateTask(&agents.Task{Title: "Claimed elsewhere"})
+	if err := r.AssignTask(foreign.ID, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	// From now on the registry is another instance sharing the store
+	r.SetInstance("another-instance")
+	model := ai.NewMockProvider("Done.")
+	start(t, r, model)
+
+	r.CreateAgent("coder-2", "coder", nil)
+	own := r.CreateTask(&agents.Task{Title: "Claimed here"})
+	wait(t, r, own.ID, func(t *agents.Task) bool { return t.Status == agents.TaskStatusCompleted })
+	if got, _ := r.GetTask(foreign.ID); got.Status != agents.TaskStatusInProgress || got.Result != nil || len(model.Calls()) != 1 {
+		t.Fatalf("the other instance's task was run: %+v after %d calls", got, len(model.Calls()))
+	}
+}
+
+func TestRunnerUsesLessonsAndModelPolicy(t *testing.T) {
+	r := agents.NewRegistry(context.Background())
+	model := ai.NewMockProvider("Done.")
+	runner := start(t, r, model)
+	store, err := lessons.NewStore(filepath.Join(t.TempDir(), "lessons.json"), config.LessonsConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.SetLessons(store)
+	runner.SetModelPolicy(modelpolicy.NewPolicy(config.ModelPolicyConfig{
+		Tiers: []config.ModelTier{{Model: "small", Cost: 1, MaxComplexity: 1}},
+	}))
+
+	agent, _ := r.CreateAgent("coder", "coder", nil)
+	if _, err := store.Add(lessons.Lesson{AgentID: agent.ID, Kind: lessons.KindPitfall, Text: "Run the linter before answering"}); err != nil {
+		t.Fatal(err)
+	}
+	task := r.CreateTask(&agents.Task{Title: "Fix the linter warnings"})
+	got := wait(t, r, task.ID, func(t *agents.Task) bool { return t.Status == agents.TaskStatusCompleted })
+	if got.Result.Model != "small" {
+		t.Errorf("result model = %q, want the policy's pick", got.Result.Model)
+	}
+	calls := model.Calls()
+	if len(calls) != 1 || calls[0].Model != "small" || !strings.Contains(calls[0].SystemPrompt, "Run the linter before answering") {
+		t.Fatalf("calls = %+v", calls)
+	}
+}
+
+func TestRunnerEscalatesFailedRuns(t *testing.T) {
+	r := agents.NewRegistry(context.Background())
+	model := ai.NewMockProvider()
+	replies := 0
+	model.Respond = func(messages []ai.Message, systemPrompt string) (string, error) {
+		if replies++; replies == 1 {
+			return "", errors.New("the model is overloaded")
+		}
+		return "Done.", nil
+	}
+	runner := start(t, r, model)
+	runner.SetModelPolicy(modelpolicy.NewPolicy(config.ModelPolicyConfig{
+		Tiers: []config.ModelTier{
+			{Model: "small", Cost: 1, MaxComplexity: 1},
+			{Model: "large", Cost: 2, MaxComplexity: 1},
+		},
+		MaxEscalations: 1,
+	}))
+
+	r.CreateAgent("coder", "coder", nil)
+	task := r.CreateTask(&agents.Task{Title: "Fix the linter warnings"})
+	got := wait(t, r, task.ID, func(t *agents.Task) bool { return t.Status == agents.TaskStatusCompleted })
+	if got.Result.Model != "large" {
+		t.Errorf("result model = %q, want the stronger tier", got.Result.Model)
+	}
+	calls := model.Calls()
+	if len(calls) != 2 || calls[0].Model != "small" || calls[1].Model != "large" {
+		t.Fatalf("calls = %+v, want small then large", calls)
+	}
+}
+
+func TestProviderCacheFollowsReloads(t *testing.T) {
+	fallback := ai.NewMockProvider()
+	withKey := func(key string) *config.Config {
+		cfg := config.DefaultConfig()
+		p := cfg.Providers[config.ProviderDeepSeek]
+		p.APIKey = key
+		cfg.Providers[config.ProviderDeepSeek] = p
+		return cfg
+	}
+	cache := NewProviderCache(withKey("key-one"), func() ai.Provider { return fallback })
+
+	if p, err := cache.Provider(""); err != nil || p != fallback {
+		t.Fatalf("default provider = %v, %v", p, err)
+	}
+	first, err := cache.Provider("deepseek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := cache.Provider("deepseek"); again != first {
+		t.Fatal("the provider was created twice")
+	}
+
+	cache.SetConfig(withKey("key-two"))
+	if second, err := cache.Provider("deepseek"); err != nil || second == first {
+		t.Fatalf("after a reload: %v, %v; want a new provider", second, err)
+	}
+	cache.SetConfig(withKey(""))
+	if _, err := cache.Provider("deepseek"); err == nil {
+		t.Fatal("a provider whose key was removed is still served")
+	}
+}
@@ -0,0 +1,155 @@
+// Package bodylimit bounds the size of HTTP request bodies, both as sent
+// and, for gzip-encoded bodies, once decompressed, so that neither a large
+// upload nor a small decompression bomb can exhaust memory.
+package bodylimit
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultLimit is the body size limit when none is configured: 1 MiB
+const DefaultLimit int64 = 1 << 20
+
+// Middleware limits request bodies to limit bytes, or DefaultLimit when
+// limit is 0. A body that declares a larger Content-Length fails on its
+// first read, before anything is read from the connection; one that grows
+// past the limit fails when it does. In both cases reads return an
+// *http.MaxBytesError.
+//
+// Bodies sent with Content-Encoding gzip are decompressed, and both the
+// compressed and the decompressed stream count against the limit. Other
+// encodings are refused with reject and status 415.
+func Middleware(limit int64, reject func(w http.ResponseWriter, status int, msg string)) func(http.Handler) http.Handler {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			b := &Body{limit: limit, declared: r.ContentLength}
+			b.raw = &counter{r: r.Body, body: b}
+			b.r, b.closer = b.raw, r.Body
+
+			switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+			case "", "identity":
+			case "gzip", "x-gzip":
+				b.gzip = true
+				r.Header.Del("Content-Encoding")
+				r.ContentLength = -1
+			default:
+				reject(w, http.StatusUnsupportedMediaType, "unsupported Content-Encoding "+encoding+"; use gzip or none")
+				return
+			}
+
+			r.Body = b
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Body is a request body read through Middleware
+type Body struct {
+	r      io.Reader
+	closer io.Closer
+	raw    *counter
+	// gzip is set for gzip bodies until the decompressor is opened
+	gzip bool
+	// read counts the bytes handed out, after decompression
+	read     int64
+	limit    int64
+	declared int64
+	err      error
+}
+
+// Read reads the body, failing with *http.MaxBytesError past the limit
+func (b *Body) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.read == 0 && b.declared > b.limit {
+		b.err = &http.MaxBytesError{Limit: b.limit}
+		return 0, b.err
+	}
+	if b.gzip {
+		b.gzip = false
+		gz, err := gzip.NewReader(b.raw)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if !errors.As(err, &tooLarge) {
+				err = errors.New("request body is not valid gzip: " + err.Error())
+			}
+			b.err = err
+			return 0, err
+		}
+		b.r = gz
+	}
+
+	// Read one byte more than is left, to tell a body of exactly the
+	// limit from a longer one
+	if left := b.limit - b.read + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := b.r.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		n -= int(b.read - b.limit)
+		b.read = b.limit
+		b.err = &http.MaxBytesError{Limit: b.limit}
+		return n, b.err
+	}
+	if err != nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// Close closes the underlying body
+func (b *Body) Close() error {
+	return b.closer.Close()
+}
+
+// SetLimit changes the limit of a request body read through Middleware,
+// for routes such as uploads that accept more than the default. It
+// reports false when the body is not one.
+func SetLimit(r *http.Request, limit int64) bool {
+	b, ok := r.Body.(*Body)
+	if !ok || limit <= 0 {
+		return false
+	}
+	b.limit = limit
+	return true
+}
+
+// counter bounds the bytes read off the connection, which for a gzip body
+// may be many more than the decompressed bytes it yields
+type counter struct {
+	r    io.Reader
+	body *Body
+	n    int64
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	if c.n >= c.body.limit {
+		// Probe for one more byte to tell the end of the body from more
+		var one [1]byte
+		n, err := c.r.Read(one[:])
+		if n > 0 {
+			return 0, &http.MaxBytesError{Limit: c.body.limit}
+		}
+		return 0, err
+	}
+	if left := c.body.limit - c.n; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,83 @@
+package bodylimit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var read int
+	var readErr error
+	handler := Middleware(64, func(w http.ResponseWriter, status int, msg string) {
+		http.Error(w, msg, status)
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/upload" {
+			SetLimit(r, 1<<20)
+		}
+		body, err := io.ReadAll(r.Body)
+		read, readErr = len(body), err
+	}))
+
+	gzipped := func(data []byte) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(data)
+		gz.Close()
+		return &buf
+	}
+	// 10 MiB of zeros compress to about 10 KiB
+	bomb := gzipped(make([]byte, 10<<20))
+
+	cases := []struct {
+		name     string
+		path     string
+		body     io.Reader
+		encoding string
+		want     int
+		tooLarge bool
+	}{
+		{name: "at the limit", body: strings.NewReader(strings.Repeat("x", 64)), want: 64},
+		{name: "declared too large", body: strings.NewReader(strings.Repeat("x", 65)), tooLarge: true},
+		{name: "streamed too large", body: io.MultiReader(strings.NewReader(strings.Repeat("x", 100))), tooLarge: true},
+		{name: "gzip", body: gzipped([]byte(`{"task":"x"}`)), encoding: "gzip", want: 12},
+		{name: "gzip bomb", body: bomb, encoding: "gzip", tooLarge: true},
+		{name: "raised limit", path: "/upload", body: strings.NewReader(strings.Repeat("x", 1000)), want: 1000},
+	}
+	for _, c := range cases {
+		read, readErr = 0, nil
+		path := c.path
+		if path == "" {
+			path = "/"
+		}
+		req := httptest.NewRequest(http.MethodPost, path, c.body)
+		if c.encoding != "" {
+			req.Header.Set("Content-Encoding", c.encoding)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		var tooLarge *http.MaxBytesError
+		if got := errors.As(readErr, &tooLarge); got != c.tooLarge {
+			t.Errorf("%s: read error %v", c.name, readErr)
+		}
+		if !c.tooLarge && read != c.want {
+			t.Errorf("%s: read %d bytes, want %d", c.name, read, c.want)
+		}
+		if c.tooLarge && read > 64 {
+			t.Errorf("%s: read %d bytes past the limit", c.name, read)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x"))
+	req.Header.Set("Content-Encoding", "br")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("brotli body: %d", rec.Code)
+	}
+}
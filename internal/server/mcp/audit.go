@@ -0,0 +1,22 @@
+package mcp
+
+import (
+	"net/http"
+
+	"github.com/biodoia/skagent/internal/audit"
+)
+
+// SetAuditLog records every tool call and agent execution in l. It must
+// be called before the server starts.
+func (s *Server) SetAuditLog(l *audit.Log) {
+	s.audit = l
+}
+
+// auditMiddleware records mutating requests in the audit log, if there is
+// one
+func (s *Server) auditMiddleware(next http.Handler) http.Handler {
+	if s.audit == nil {
+		return next
+	}
+	return audit.Middleware(s.audit, "mcp", s.logger)(next)
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/auth"
+)
+
+// JSON-RPC error codes of requests refused for their credentials
+const (
+	CodeUnauthorized = -32001 // no key, or one the server does not know
+	CodeForbidden    = -32003 // the key's role lacks the permission
+)
+
+// toolPermissions maps each MCP tool to the permission needed to call it.
+// Tools missing from the map require tools:execute.
+var toolPermissions = map[string]auth.Permission{
+	"list_agents":          auth.PermAgentsRead,
+	"get_agent":            auth.PermAgentsRead,
+	"start_agent":          auth.PermAgentsControl,
+	"stop_agent":           auth.PermAgentsControl,
+	"create_task":          auth.PermTasksWrite,
+	"get_task_status":      auth.PermTasksRead,
+	"get_system_status":    auth.PermSystemRead,
+	"get_system_config":    auth.PermSystemRead,
+	"list_project_tasks":   auth.PermProjectRead,
+	"assign_task_to_agent": auth.PermProjectWrite,
+	"recommend_agents":     auth.PermAgentsRead,
+}
+
+// ToolPermission returns the permission required to call an MCP tool
+func ToolPermission(toolName string) auth.Permission {
+	if perm, ok := toolPermissions[toolName]; ok {
+		return perm
+	}
+	return auth.PermToolsExecute
+}
+
+// SetAuthorizer enables API key authentication and per-tool role checks.
+// With no authorizer every request is allowed.
+func (s *Server) SetAuthorizer(a *auth.Authorizer) {
+	s.authz = a
+}
+
+// authMiddleware resolves the caller's API key to a principal. The health
+// endpoint stays public.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authz == nil || r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, ok := s.authz.Authenticate(auth.TokenFromRequest(r))
+		if !ok {
+			s.authz.AuditUnauthenticated(r.Method + " " + r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="skagent-mcp"`)
+			s.writeFailure(w, r, http.StatusUnauthorized, CodeUnauthorized, "missing or invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// authorize checks a permission for the request's principal and writes a
+// 403 response, as a JSON-RPC error on JSON-RPC routes, when it is missing
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, perm auth.Permission, target string) bool {
+	if s.authz == nil {
+		return true
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !s.authz.Check(principal, perm, target) {
+		s.writeFailure(w, r, http.StatusForbidden, CodeForbidden, "role "+string(principal.Role)+" lacks permission "+string(perm))
+		return false
+	}
+	return true
+}
+
+// visibleAgents lists the agents in the workspaces the caller of ctx may
+// use
+func (s *Server) visibleAgents(ctx context.Context) []agents.AgentView {
+	all := s.agentRegistry.ListAgentViews()
+	out := make([]agents.AgentView, 0, len(all))
+	for _, a := range all {
+		if auth.CanAccess(ctx, a.Workspace) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// visibleAgent returns an agent when the caller of ctx may use its
+// workspace; other agents are reported as not found
+func (s *Server) visibleAgent(ctx context.Context, id string) (agents.AgentView, bool) {
+	agent, ok := s.agentRegistry.GetAgentView(id)
+	if !ok || !auth.CanAccess(ctx, agent.Workspace) {
+		return agents.AgentView{}, false
+	}
+	return agent, true
+}
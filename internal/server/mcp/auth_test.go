@@ -0,0 +1,196 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestSharedTokenScopes(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DefaultConfig()
+	cfg.MCP.EnableAuth = true
+	cfg.MCP.Token = "shared-secret"
+	cfg.Auth.Keys = map[string]config.APIKeyConfig{"ci": {Token: "ci-token", Role: "operator"}}
+	authz, err := auth.New(cfg.MCPAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	server.initializeTools()
+	server.SetAuthorizer(authz)
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	// post sends a message, opening a session for token on the first call
+	sessions := map[string]string{}
+	post := func(token, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if id := sessions[token]; id != "" {
+			req.Header.Set(SessionHeader, id)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if id := res.Header.Get(SessionHeader); id != "" {
+			sessions[token] = id
+		}
+		var out bytes.Buffer
+		raw, _ := io.ReadAll(res.Body)
+		json.Compact(&out, raw)
+		return res.StatusCode, out.String()
+	}
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","clientInfo":{"name":"host"}}}`
+	list := `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`
+	create := `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"create_task","arguments":{"task":"build"}}}`
+
+	if status, _ := post("", initialize); status != http.StatusUnauthorized {
+		t.Errorf("no token: %d", status)
+	}
+	if status, _ := post("wrong", initialize); status != http.StatusUnauthorized {
+		t.Errorf("wrong token: %d", status)
+	}
+
+	// The shared token is read-only by default
+	if status, body := post("shared-secret", initialize); status != http.StatusOK {
+		t.Fatalf("initialize with the shared token: %d %s", status, body)
+	}
+	_, body := post("shared-secret", list)
+	if !strings.Contains(body, `"list_agents"`) || strings.Contains(body, `"create_task"`) {
+		t.Errorf("read-only tools/list: %s", body)
+	}
+	if _, body := post("shared-secret", create); !strings.Contains(body, `"isError":true`) || !strings.Contains(body, "tasks:write") {
+		t.Errorf("read-only create_task: %s", body)
+	}
+
+	post("ci-token", initialize)
+	if _, body := post("ci-token", list); !strings.Contains(body, `"create_task"`) {
+		t.Errorf("operator tools/list: %s", body)
+	}
+	// The operator gets past the permission check to argument validation
+	if _, body := post("ci-token", create); !strings.Contains(body, "agent_id is required") {
+		t.Errorf("operator create_task: %s", body)
+	}
+}
+
+func TestRoutesRequirePermissions(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DefaultConfig()
+	cfg.MCP.EnableAuth = true
+	cfg.Auth.Roles = map[string][]string{"tasks-only": {"tasks:read"}}
+	cfg.Auth.Keys = map[string]config.APIKeyConfig{
+		"narrow": {Token: "narrow-token", Role: "tasks-only"},
+		"viewer": {Token: "viewer-token", Role: "viewer"},
+	}
+	authz, err := auth.New(cfg.MCPAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("coder", "coder", nil)
+	server := NewServer(ctx, registry, config.MCPConfig{})
+	server.initializeTools()
+	server.SetAuthorizer(authz)
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	for _, path := range []string{"/agents", "/agents/" + agent.ID, "/tools", "/tools/list_agents", "/info"} {
+		for token, want := range map[string]int{"narrow-token": http.StatusForbidden, "viewer-token": http.StatusOK} {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != want {
+				t.Errorf("GET %s with %s: status %d, want %d", path, token, res.StatusCode, want)
+			}
+		}
+	}
+}
+
+func TestRefusalsAnswerJSONRPC(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DefaultConfig()
+	cfg.MCP.EnableAuth = true
+	cfg.Auth.Keys = map[string]config.APIKeyConfig{
+		"viewer":   {Token: "viewer-token", Role: "viewer"},
+		"operator": {Token: "operator-token", Role: "operator"},
+	}
+	authz, err := auth.New(cfg.MCPAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("coder", "coder", nil)
+	server := NewServer(ctx, registry, config.MCPConfig{})
+	server.initializeTools()
+	server.SetAuthorizer(authz)
+	server.SetMaxBodySize(256)
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	call := `{"jsonrpc":"2.0","id":"call-7","method":"tools/call","params":{"name":"start_agent","arguments":{"agent_id":"` + agent.ID + `"}}}`
+	execute := `{"jsonrpc":"2.0","id":8,"method":"agents/execute","params":{"task":"build"}}`
+	tests := []struct {
+		name, path, token, body string
+		status, code            int
+		id                      string
+	}{
+		{"unauthenticated call", "/tools/start_agent/call", "", call, http.StatusUnauthorized, CodeUnauthorized, `"call-7"`},
+		{"forbidden call", "/tools/start_agent/call", "viewer-token", call, http.StatusForbidden, CodeForbidden, `"call-7"`},
+		{"forbidden execute", "/agents/" + agent.ID + "/execute", "viewer-token", execute, http.StatusForbidden, CodeForbidden, `8`},
+		{"body too large", "/agents/" + agent.ID + "/execute", "operator-token", `{"task":"` + strings.Repeat("x", 300) + `"}`, http.StatusRequestEntityTooLarge, CodeInvalidRequest, `null`},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+tt.path, strings.NewReader(tt.body))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp struct {
+			JSONRPC string          `json:"jsonrpc"`
+			ID      json.RawMessage `json:"id"`
+			Error   *Error          `json:"error"`
+		}
+		json.NewDecoder(res.Body).Decode(&resp)
+		res.Body.Close()
+		if res.StatusCode != tt.status || resp.JSONRPC != "2.0" || string(resp.ID) != tt.id ||
+			resp.Error == nil || resp.Error.Code != tt.code {
+			t.Errorf("%s: status %d, response %+v; want %d with code %d and id %s", tt.name, res.StatusCode, resp, tt.status, tt.code, tt.id)
+		}
+	}
+
+	// Routes that do not take JSON-RPC keep the plain error
+	res, err := http.Get(ts.URL + "/agents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plain map[string]interface{}
+	json.NewDecoder(res.Body).Decode(&plain)
+	res.Body.Close()
+	if _, rpc := plain["jsonrpc"]; res.StatusCode != http.StatusUnauthorized || rpc {
+		t.Errorf("GET /agents unauthenticated: %d %v", res.StatusCode, plain)
+	}
+}
@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// SetWorkspaceTools has the tools of AddToolManager run through the
+// manager of the caller's workspace, as returned by f: the one named by
+// the call's "workspace" argument, else the first the caller's key is
+// bound to. Callers bound to no workspace and naming none use the manager
+// given to AddToolManager.
+func (s *Server) SetWorkspaceTools(f func(workspace string) *tools.ToolManager) {
+	s.workspaceTools = f
+}
+
+// toolsFor returns the manager that runs a tool call with args for the
+// caller of ctx, tm unless SetWorkspaceTools says otherwise
+func (s *Server) toolsFor(ctx context.Context, tm *tools.ToolManager, args map[string]interface{}) (*tools.ToolManager, error) {
+	workspace, _ := args["workspace"].(string)
+	if workspace != "" && !auth.CanAccess(ctx, workspace) {
+		return nil, fmt.Errorf("workspace %q is not accessible", workspace)
+	}
+	if p, ok := auth.PrincipalFromContext(ctx); ok && workspace == "" && p.Bound() {
+		workspace = p.Workspaces[0]
+	}
+	if workspace == "" || s.workspaceTools == nil {
+		return tm, nil
+	}
+	return s.workspaceTools(workspace), nil
+}
+
+// AddToolManager exposes the tools of tm as MCP tools, including those
+// added to it later, and withdraws those removed from it. Each takes its
+// request as the "input" string and runs through tm, or the manager of
+// the caller's workspace (see SetWorkspaceTools), which scrubs secrets
+// from the output. A tool named like a built-in MCP tool is skipped.
+func (s *Server) AddToolManager(tm *tools.ToolManager) {
+	s.initializeTools()
+	tm.WatchRemoved(s.UnregisterTool)
+	tm.Watch(func(tool tools.Tool) {
+		name := tool.Name()
+		if s.builtinTool(name) {
+			s.logger.Printf("Not exposing tool %s: the name is taken by a built-in tool", name)
+			return
+		}
+		s.RegisterTool(toolDefinition(tool), func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+			input, _ := args["input"].(string)
+			if strings.TrimSpace(input) == "" {
+				return nil, fmt.Errorf("input is required")
+			}
+			scoped, err := s.toolsFor(ctx, tm, args)
+			if err != nil {
+				return nil, err
+			}
+			output, err := scoped.ExecuteByName(ctx, name, input)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"tool":   name,
+				"output": output,
+			}, nil
+		})
+	})
+}
+
+// builtinTool reports whether name is one of the tools of initializeTools
+func (s *Server) builtinTool(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, defined := s.tools[name]
+	_, registered := s.handlers[name]
+	return defined && !registered
+}
+
+// toolDefinition generates the MCP definition of a ToolManager tool. Tools
+// take free text, so the schema has a single required string.
+func toolDefinition(tool tools.Tool) ToolDefinition {
+	return ToolDefinition{
+		Name:        tool.Name(),
+		Description: tool.Description(),
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"input": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("Request for the %s tool in plain words, e.g. the command and its arguments", tool.Name()),
+				},
+				"workspace": map[string]interface{}{
+					"type":        "string",
+					"description": "Workspace whose checkout the tool works in; by default the one the API key is bound to",
+				},
+			},
+			"required": []string{"input"},
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// echoTool is a ToolManager tool that answers with its input
+type echoTool struct{ name string }
+
+func (e echoTool) Name() string                 { return e.name }
+func (e echoTool) Description() string          { return "Repeat the input" }
+func (e echoTool) CanHandle(intent string) bool { return false }
+func (e echoTool) Execute(ctx context.Context, input string) (string, error) {
+	return "echo: " + input, nil
+}
+
+func TestAddToolManager(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	tm := tools.NewToolManager()
+	tm.AddTool(echoTool{name: "echo"})
+	tm.AddTool(echoTool{name: "get_agent"})
+	server.AddToolManager(tm)
+	tm.AddTool(echoTool{name: "later"})
+
+	var session Session
+	call := func(msg string) string {
+		t.Helper()
+		return toJSON(t, server.HandleMessage(ctx, &session, []byte(msg)))
+	}
+	call(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+
+	list := call(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	for _, want := range []string{`"name":"echo"`, `"name":"later"`, `"required":["input"]`} {
+		if !strings.Contains(list, want) {
+			t.Errorf("tools/list lacks %s: %s", want, list)
+		}
+	}
+
+	if body := call(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"input":"hello"}}}`); !strings.Contains(body, `echo: hello`) {
+		t.Errorf("echo: %s", body)
+	}
+	if body := call(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"later","arguments":{}}}`); !strings.Contains(body, `"isError":true`) || !strings.Contains(body, "input is required") {
+		t.Errorf("missing input: %s", body)
+	}
+	// The built-in tool keeps its name
+	if body := call(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"get_agent","arguments":{"input":"x"}}}`); strings.Contains(body, "echo:") {
+		t.Errorf("get_agent was replaced: %s", body)
+	}
+}
+
+// prefixTool is a ToolManager tool that tells which manager ran it
+type prefixTool struct{ prefix string }
+
+func (p prefixTool) Name() string                 { return "echo" }
+func (p prefixTool) Description() string          { return "Repeat the input" }
+func (p prefixTool) CanHandle(intent string) bool { return false }
+func (p prefixTool) Execute(ctx context.Context, input string) (string, error) {
+	return p.prefix + ": " + input, nil
+}
+
+func TestToolsRunInTheCallersWorkspace(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	global := tools.NewToolManager()
+	global.AddTool(prefixTool{prefix: "global"})
+	scoped := map[string]*tools.ToolManager{}
+	for _, ws := range []string{"team-a", "team-b"} {
+		scoped[ws] = tools.NewToolManager()
+		scoped[ws].AddTool(prefixTool{prefix: ws})
+	}
+	server.AddToolManager(global)
+	server.SetWorkspaceTools(func(workspace string) *tools.ToolManager {
+		if tm, ok := scoped[workspace]; ok {
+			return tm
+		}
+		return global
+	})
+
+	call := func(ctx context.Context, args string) string {
+		t.Helper()
+		var session Session
+		server.HandleMessage(ctx, &session, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+		return toJSON(t, server.HandleMessage(ctx, &session, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":`+args+`}}`)))
+	}
+	bound := auth.WithPrincipal(ctx, auth.Principal{Name: "ci", Role: auth.RoleOperator, Workspaces: []string{"team-a"}})
+
+	if body := call(ctx, `{"input":"hi"}`); !strings.Contains(body, "global: hi") {
+		t.Errorf("unbound caller: %s", body)
+	}
+	if body := call(ctx, `{"input":"hi","workspace":"team-b"}`); !strings.Contains(body, "team-b: hi") {
+		t.Errorf("named workspace: %s", body)
+	}
+	if body := call(bound, `{"input":"hi"}`); !strings.Contains(body, "team-a: hi") {
+		t.Errorf("bound caller: %s", body)
+	}
+	if body := call(bound, `{"input":"hi","workspace":"team-b"}`); !strings.Contains(body, `"isError":true`) || strings.Contains(body, "team-b: hi") {
+		t.Errorf("bound caller ran another workspace's tool: %s", body)
+	}
+}
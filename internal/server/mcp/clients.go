@@ -0,0 +1,178 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// Transports of a client session
+const (
+	TransportStdio      = "stdio"
+	TransportStreamable = config.MCPTransportStreamable
+	TransportSSE        = config.MCPTransportSSE
+	TransportWebSocket  = config.MCPTransportWebSocket
+	// TransportHTTP names the plain /tools and /agents routes, which have
+	// no session, for matching views
+	TransportHTTP = "http"
+)
+
+// CodeRateLimited answers the requests of a session over
+// mcp.session_rate_limit
+const CodeRateLimited = -32029
+
+// ClientSession is what the admin view shows of a connected client
+type ClientSession struct {
+	ID              string     `json:"id"`
+	Transport       string     `json:"transport"`
+	Principal       string     `json:"principal,omitempty"`
+	Initialized     bool       `json:"initialized"`
+	Client          ClientInfo `json:"client"`
+	ProtocolVersion string     `json:"protocol_version,omitempty"`
+	// Capabilities are the names of the capabilities the client declared
+	Capabilities []string `json:"capabilities"`
+	// View is the mcp.views entry restricting the session, if any
+	View       string    `json:"view,omitempty"`
+	OpenedAt   time.Time `json:"opened_at"`
+	LastActive time.Time `json:"last_active"`
+	Requests   int64     `json:"requests"`
+	// RateLimited counts the requests refused over the session's limit
+	RateLimited int64 `json:"rate_limited"`
+}
+
+// trackSession lists a session in the admin view until untrack is called
+func (s *Server) trackSession(session *Session, id, transport, principal string) (untrack func()) {
+	now := time.Now()
+	session.mu.Lock()
+	session.id = id
+	session.transport = transport
+	session.principal = principal
+	session.openedAt = now
+	session.lastActive = now
+	session.mu.Unlock()
+
+	s.clientsMu.Lock()
+	if s.clients == nil {
+		s.clients = make(map[string]*Session)
+	}
+	s.clients[id] = session
+	s.clientsMu.Unlock()
+	return func() {
+		s.clientsMu.Lock()
+		delete(s.clients, id)
+		s.clientsMu.Unlock()
+	}
+}
+
+// clientCount returns the number of connected clients
+func (s *Server) clientCount() int {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	return len(s.clients)
+}
+
+// ClientSessions returns the connected clients, oldest first
+func (s *Server) ClientSessions() []ClientSession {
+	s.clientsMu.Lock()
+	sessions := make([]*Session, 0, len(s.clients))
+	for _, session := range s.clients {
+		sessions = append(sessions, session)
+	}
+	s.clientsMu.Unlock()
+
+	out := make([]ClientSession, 0, len(sessions))
+	for _, session := range sessions {
+		out = append(out, session.info())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].OpenedAt.Equal(out[j].OpenedAt) {
+			return out[i].OpenedAt.Before(out[j].OpenedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// info returns the admin view of the session
+func (s *Session) info() ClientSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	caps := make([]string, 0, len(s.capabilities))
+	for name := range s.capabilities {
+		caps = append(caps, name)
+	}
+	sort.Strings(caps)
+	return ClientSession{
+		ID:              s.id,
+		Transport:       s.transport,
+		Principal:       s.principal,
+		Initialized:     s.initialized,
+		Client:          s.client,
+		ProtocolVersion: s.protocolVersion,
+		Capabilities:    caps,
+		View:            s.view.name(),
+		OpenedAt:        s.openedAt,
+		LastActive:      s.lastActive,
+		Requests:        s.requests,
+		RateLimited:     s.limited,
+	}
+}
+
+// admit counts a request of the session and takes a token from its bucket,
+// which holds a minute's worth of requests and refills continuously. It
+// returns how long until the next token when the request is refused; a
+// limit of 0 admits everything.
+func (s *Session) admit(perMinute int, now time.Time) (retryAfter time.Duration, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = now
+	if perMinute <= 0 {
+		s.requests++
+		return 0, true
+	}
+	limit := float64(perMinute)
+	rate := limit / 60 // tokens per second
+	if s.refilled.IsZero() {
+		s.tokens = limit
+	} else {
+		s.tokens = math.Min(limit, s.tokens+now.Sub(s.refilled).Seconds()*rate)
+	}
+	s.refilled = now
+	if s.tokens < 1 {
+		s.limited++
+		return time.Duration((1 - s.tokens) / rate * float64(time.Second)), false
+	}
+	s.tokens--
+	s.requests++
+	return 0, true
+}
+
+// rateLimited is the error of a request over the session's limit
+func rateLimited(perMinute int, retryAfter time.Duration) *Error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	return &Error{
+		Code:    CodeRateLimited,
+		Message: fmt.Sprintf("the session is over its limit of %d requests a minute; retry in %ds", perMinute, seconds),
+		Data:    map[string]interface{}{"retry_after_seconds": seconds},
+	}
+}
+
+// handleListClients serves the connected clients: their transport,
+// handshake, capabilities and activity
+func (s *Server) handleListClients(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r, auth.PermSystemRead, "client sessions") {
+		return
+	}
+	clients := s.ClientSessions()
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"clients":            clients,
+		"count":              len(clients),
+		"session_rate_limit": s.sessionRateLimit,
+		"timestamp":          time.Now(),
+	})
+}
@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestClientSessions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{SessionRateLimit: 3})
+	server.initializeTools()
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	post := func(session, body string) (*http.Response, Response) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if session != "" {
+			req.Header.Set(SessionHeader, session)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var resp Response
+		if res.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+				t.Fatalf("%s: %v", body, err)
+			}
+		}
+		return res, resp
+	}
+	clients := func() (list struct {
+		Clients []ClientSession `json:"clients"`
+		Count   int             `json:"count"`
+		Limit   int             `json:"session_rate_limit"`
+	}) {
+		t.Helper()
+		res, err := http.Get(ts.URL + "/clients")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("GET /clients: %d", res.StatusCode)
+		}
+		if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	res, _ := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"sampling":{},"roots":{}},"clientInfo":{"name":"remote","version":"1.2"}}}`)
+	session := res.Header.Get(SessionHeader)
+	if session == "" {
+		t.Fatalf("initialize: %d", res.StatusCode)
+	}
+	post(session, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	list := clients()
+	if list.Count != 1 || list.Limit != 3 {
+		t.Fatalf("clients: %+v", list)
+	}
+	c := list.Clients[0]
+	if c.ID != session || c.Transport != TransportStreamable || !c.Initialized ||
+		c.Client.Name != "remote" || c.ProtocolVersion != "2024-11-05" ||
+		strings.Join(c.Capabilities, ",") != "roots,sampling" || c.Requests != 1 {
+		t.Fatalf("client: %+v", c)
+	}
+
+	// initialize took the first of three requests
+	for id := 2; id <= 3; id++ {
+		if _, resp := post(session, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/list"}`, id)); resp.Error != nil {
+			t.Fatalf("request %d: %+v", id, resp.Error)
+		}
+	}
+	_, resp := post(session, `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`)
+	if resp.Error == nil || resp.Error.Code != CodeRateLimited || string(resp.ID) != "4" {
+		t.Fatalf("over the limit: %+v", resp)
+	}
+	if c := clients().Clients[0]; c.Requests != 3 || c.RateLimited != 1 {
+		t.Fatalf("after the limit: %+v", c)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/mcp", nil)
+	req.Header.Set(SessionHeader, session)
+	del, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	del.Body.Close()
+	if list := clients(); list.Count != 0 {
+		t.Fatalf("after DELETE: %+v", list)
+	}
+}
+
+func TestSessionAdmit(t *testing.T) {
+	var session Session
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, ok := session.admit(2, now); !ok {
+			t.Fatalf("request %d refused", i)
+		}
+	}
+	wait, ok := session.admit(2, now)
+	if ok || wait != 30*time.Second {
+		t.Fatalf("third request: %v %v", wait, ok)
+	}
+	if _, ok := session.admit(2, now.Add(30*time.Second)); !ok {
+		t.Fatal("refilled token refused")
+	}
+	for i := 0; i < 5; i++ {
+		if _, ok := (&Session{}).admit(0, now); !ok {
+			t.Fatal("a limit of 0 refused a request")
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/server/requestid"
+	"github.com/biodoia/skagent/internal/validate"
+)
+
+// readCall reads the body of a tool call or agent execution: the
+// arguments as a JSON object or, from JSON-RPC clients, a request for
+// method, whose ID the answer echoes. tools/call takes the {name,
+// arguments} params of the MCP transports, where name, if set, must be
+// the tool of the URL; other methods take the arguments as params.
+func (s *Server) readCall(w http.ResponseWriter, r *http.Request, method, tool string) (id json.RawMessage, args map[string]interface{}, ok bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeDecodeError(w, err)
+		return nil, nil, false
+	}
+	body = bytes.TrimSpace(body)
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		s.writeRPCError(w, http.StatusBadRequest, nullID, CodeParseError, "invalid JSON: "+err.Error())
+		return nil, nil, false
+	}
+	if _, envelope := probe["jsonrpc"]; !envelope {
+		if err := json.Unmarshal(body, &args); err != nil {
+			s.writeRPCError(w, http.StatusBadRequest, nullID, CodeInvalidParams, "invalid arguments: "+err.Error())
+			return nil, nil, false
+		}
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		return nullID, args, true
+	}
+
+	req, rerr := decodeRequest(body)
+	if rerr != nil {
+		s.writeRPCError(w, http.StatusBadRequest, nullID, rerr.Code, rerr.Message)
+		return nil, nil, false
+	}
+	if req == nil || req.JSONRPC != "2.0" {
+		s.writeRPCError(w, http.StatusBadRequest, nullID, CodeInvalidRequest, `requests need "jsonrpc": "2.0" and a method`)
+		return nil, nil, false
+	}
+	id = req.ID
+	if len(id) == 0 {
+		id = nullID
+	}
+	if req.Method != method {
+		s.writeRPCError(w, http.StatusBadRequest, id, CodeMethodNotFound, "this endpoint takes "+method+", not "+req.Method)
+		return nil, nil, false
+	}
+
+	params := req.Params
+	if method == "tools/call" && len(params) > 0 {
+		var call callParams
+		if err := json.Unmarshal(params, &call); err != nil {
+			s.writeRPCError(w, http.StatusBadRequest, id, CodeInvalidParams, "invalid tools/call parameters: "+err.Error())
+			return nil, nil, false
+		}
+		if call.Name != "" && call.Name != tool {
+			s.writeRPCError(w, http.StatusBadRequest, id, CodeInvalidParams, "params name "+call.Name+" does not match the tool "+tool)
+			return nil, nil, false
+		}
+		args = call.Arguments
+	} else if len(params) > 0 && string(params) != "null" {
+		if err := json.Unmarshal(params, &args); err != nil {
+			s.writeRPCError(w, http.StatusBadRequest, id, CodeInvalidParams, "params must be an object of arguments")
+			return nil, nil, false
+		}
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return id, args, true
+}
+
+// writeRPCResult answers a call with a JSON-RPC result
+func (s *Server) writeRPCResult(w http.ResponseWriter, id json.RawMessage, result interface{}) {
+	s.writeJSON(w, http.StatusOK, &Response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+// writeRPCError answers a call with a JSON-RPC error, keeping the HTTP
+// status for clients that read it. The data of the error repeats the
+// status and the request ID.
+func (s *Server) writeRPCError(w http.ResponseWriter, status int, id json.RawMessage, code int, message string) {
+	s.writeJSON(w, status, &Response{JSONRPC: "2.0", ID: id, Error: &Error{
+		Code:    code,
+		Message: message,
+		Data: map[string]interface{}{
+			"status":     status,
+			"request_id": requestid.FromResponse(w),
+		},
+	}})
+}
+
+// writeRPCInvalid answers a call whose arguments do not match the schema
+// of the tool with 400 and the invalid fields in the error's details
+func (s *Server) writeRPCInvalid(w http.ResponseWriter, id json.RawMessage, errs validate.Errors) {
+	s.writeJSON(w, http.StatusBadRequest, &Response{JSONRPC: "2.0", ID: id, Error: &Error{
+		Code:    CodeInvalidParams,
+		Message: argumentsMessage(errs),
+		Data: map[string]interface{}{
+			"status":     http.StatusBadRequest,
+			"request_id": requestid.FromResponse(w),
+			"details":    errs,
+		},
+	}})
+}
+
+// writeFailure answers a request refused before its handler reads it, for
+// its credentials or its body: with a JSON-RPC error echoing the ID of the
+// request, when it can be read, on the routes that take JSON-RPC, and with
+// a plain error elsewhere
+func (s *Server) writeFailure(w http.ResponseWriter, r *http.Request, status, code int, message string) {
+	if !takesJSONRPC(r) {
+		s.writeError(w, status, message)
+		return
+	}
+	id := nullID
+	if status != http.StatusRequestEntityTooLarge && status != http.StatusUnsupportedMediaType && r.Body != nil {
+		var probe struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if json.NewDecoder(r.Body).Decode(&probe) == nil && len(probe.ID) > 0 {
+			id = probe.ID
+		}
+	}
+	s.writeRPCError(w, status, id, code, message)
+}
+
+// takesJSONRPC reports whether a request goes to a route that answers in
+// JSON-RPC: the MCP transports, tool calls and agent executions
+func takesJSONRPC(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	p := r.URL.Path
+	switch {
+	case p == "/mcp", p == "/messages":
+		return true
+	case strings.HasPrefix(p, "/tools/") && strings.HasSuffix(p, "/call"):
+		return true
+	case strings.HasPrefix(p, "/agents/") && strings.HasSuffix(p, "/execute"):
+		return true
+	}
+	return false
+}
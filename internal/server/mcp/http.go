@@ -0,0 +1,430 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/google/uuid"
+)
+
+// SessionHeader carries the session of the Streamable HTTP transport
+const SessionHeader = "Mcp-Session-Id"
+
+const (
+	// sessionIdle is how long an HTTP session without an open stream is
+	// kept after its last message
+	sessionIdle = 30 * time.Minute
+	// keepAliveInterval spaces the comments that keep idle event streams
+	// open through proxies
+	keepAliveInterval = 25 * time.Second
+	// outboxSize bounds the messages queued for a session's stream
+	outboxSize = 64
+)
+
+// httpSession is a session of the HTTP transports: the protocol state and
+// the messages queued for the client's event stream
+type httpSession struct {
+	Session
+	id string
+	// principal is the API key that opened the session; other keys cannot
+	// use it
+	principal string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	outbox    chan interface{}
+	streams   atomic.Int32
+	lastUsed  atomic.Int64
+}
+
+func (hs *httpSession) touch() {
+	hs.lastUsed.Store(time.Now().UnixNano())
+}
+
+// send queues a message for the session's stream, giving up when the
+// session ends
+func (hs *httpSession) send(msg interface{}) {
+	select {
+	case hs.outbox <- msg:
+	case <-hs.ctx.Done():
+	}
+}
+
+// principalName is the name of the request's API key, "" without auth
+func principalName(ctx context.Context) string {
+	p, _ := auth.PrincipalFromContext(ctx)
+	return p.Name
+}
+
+// newSession opens an HTTP session of transport for the caller of ctx,
+// dropping the sessions left idle
+func (s *Server) newSession(ctx context.Context, transport string) *httpSession {
+	hs := &httpSession{
+		id:        uuid.NewString(),
+		principal: principalName(ctx),
+		outbox:    make(chan interface{}, outboxSize),
+	}
+	hs.ctx, hs.cancel = context.WithCancel(s.ctx)
+	hs.touch()
+	// Requests of the server go on the session's stream, so they need one
+	// open
+	hs.Session.send = func(msg interface{}) error {
+		if hs.streams.Load() == 0 {
+			return fmt.Errorf("the session has no open stream")
+		}
+		select {
+		case hs.outbox <- msg:
+			return nil
+		case <-hs.ctx.Done():
+			return hs.ctx.Err()
+		}
+	}
+
+	// Notifications go to the open streams of the session and are dropped
+	// while none is open or its queue is full; the listener keeps the
+	// principal of ctx but not the request's lifetime
+	stop := s.listen(context.WithoutCancel(ctx), &hs.Session, func(n *Notification) {
+		if hs.streams.Load() == 0 {
+			return
+		}
+		select {
+		case hs.outbox <- n:
+		default:
+		}
+	})
+	untrack := s.trackSession(&hs.Session, hs.id, transport, hs.principal)
+	cancel := hs.cancel
+	hs.cancel = func() {
+		untrack()
+		stop()
+		cancel()
+	}
+
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]*httpSession)
+	}
+	cutoff := time.Now().Add(-sessionIdle).UnixNano()
+	for id, old := range s.sessions {
+		if old.streams.Load() == 0 && old.lastUsed.Load() < cutoff {
+			old.cancel()
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[hs.id] = hs
+	return hs
+}
+
+// session finds an open session of the caller of ctx
+func (s *Server) session(ctx context.Context, id string) (*httpSession, bool) {
+	s.sessionsMu.Lock()
+	hs, ok := s.sessions[id]
+	s.sessionsMu.Unlock()
+	if !ok || hs.principal != principalName(ctx) || hs.ctx.Err() != nil {
+		return nil, false
+	}
+	hs.touch()
+	return hs, true
+}
+
+// closeSession ends a session and the streams open on it
+func (s *Server) closeSession(id string) {
+	s.sessionsMu.Lock()
+	hs, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.sessionsMu.Unlock()
+	if ok {
+		hs.cancel()
+	}
+}
+
+// closeSessions ends every HTTP session, so that their streams let a
+// graceful shutdown finish
+func (s *Server) closeSessions() {
+	s.sessionsMu.Lock()
+	sessions := s.sessions
+	s.sessions = nil
+	s.sessionsMu.Unlock()
+	for _, hs := range sessions {
+		hs.cancel()
+	}
+}
+
+// readMessage reads the JSON-RPC message or batch of a request body,
+// answering bodies that are not JSON with a parse error
+func (s *Server) readMessage(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeDecodeError(w, err)
+		return nil, false
+	}
+	body = bytes.TrimSpace(body)
+	if _, rerr := decodeRequest(body); rerr != nil && rerr.Code == CodeParseError {
+		s.writeJSON(w, http.StatusBadRequest, &Response{JSONRPC: "2.0", ID: nullID, Error: rerr})
+		return nil, false
+	}
+	return body, true
+}
+
+// handleStreamablePost takes a message or batch of the Streamable HTTP
+// transport. initialize opens a session, named in the Mcp-Session-Id
+// header of its response, that every later message must carry. Requests
+// are answered in the response, as JSON or, for clients that only accept
+// event streams, as a one-event stream; a body of only notifications and
+// responses gets 202. For clients that accept event streams, a tool call
+// that reports progress turns the response into a stream: the
+// notifications first, then the answer.
+func (s *Server) handleStreamablePost(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.readMessage(w, r)
+	if !ok {
+		return
+	}
+
+	var hs *httpSession
+	method, id := peek(body)
+	if len(id) == 0 {
+		id = nullID
+	}
+	if method == "initialize" {
+		hs = s.newSession(r.Context(), TransportStreamable)
+	} else {
+		sessionID := r.Header.Get(SessionHeader)
+		if sessionID == "" {
+			s.writeJSON(w, http.StatusBadRequest, &Response{JSONRPC: "2.0", ID: id,
+				Error: &Error{Code: CodeInvalidRequest, Message: "missing " + SessionHeader + " header; send initialize first"}})
+			return
+		}
+		if hs, ok = s.session(r.Context(), sessionID); !ok {
+			s.writeJSON(w, http.StatusNotFound, &Response{JSONRPC: "2.0", ID: id,
+				Error: &Error{Code: CodeInvalidRequest, Message: "session not found; initialize a new one"}})
+			return
+		}
+	}
+
+	ctx := r.Context()
+	accept := r.Header.Get("Accept")
+	var rs *responseStream
+	if method != "initialize" && strings.Contains(accept, "text/event-stream") {
+		rs = &responseStream{w: w}
+		ctx = withNotifier(ctx, rs.send)
+	}
+	out := s.HandleMessage(ctx, &hs.Session, body)
+	if rs != nil && rs.close() {
+		if out != nil {
+			writeEvent(w, "message", out)
+			rs.flusher.Flush()
+		}
+		return
+	}
+	if method == "initialize" {
+		if resp, ok := out.(*Response); ok && resp.Error != nil {
+			// The handshake failed, so there is no session to carry on
+			s.closeSession(hs.id)
+		} else {
+			w.Header().Set(SessionHeader, hs.id)
+		}
+	}
+	if out == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if strings.Contains(accept, "text/event-stream") && !strings.Contains(accept, "application/json") {
+		if flusher, ok := startStream(w); ok {
+			writeEvent(w, "message", out)
+			flusher.Flush()
+			return
+		}
+	}
+	s.writeJSON(w, http.StatusOK, out)
+}
+
+// responseStream answers a POST as an event stream, started by the first
+// message sent on it
+type responseStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	closed  bool
+}
+
+// send writes msg as an event of the stream, starting it if needed
+func (rs *responseStream) send(msg interface{}) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.closed {
+		return fmt.Errorf("the request was answered")
+	}
+	if rs.flusher == nil {
+		flusher, ok := startStream(rs.w)
+		if !ok {
+			rs.closed = true
+			return fmt.Errorf("streaming is not supported")
+		}
+		rs.flusher = flusher
+	}
+	writeEvent(rs.w, "message", msg)
+	rs.flusher.Flush()
+	return nil
+}
+
+// close refuses later messages and reports whether the stream started,
+// so that the answer must go on it
+func (rs *responseStream) close() (started bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.closed = true
+	return rs.flusher != nil
+}
+
+// handleStreamableGet opens the event stream of a Streamable HTTP session,
+// on which the server sends messages the client did not ask for
+func (s *Server) handleStreamableGet(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.writeError(w, http.StatusMethodNotAllowed, "GET opens an event stream; accept text/event-stream")
+		return
+	}
+	hs, ok := s.session(r.Context(), r.Header.Get(SessionHeader))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "session not found; initialize a new one")
+		return
+	}
+	s.stream(w, r, hs, nil)
+}
+
+// handleStreamableDelete ends a Streamable HTTP session
+func (s *Server) handleStreamableDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(SessionHeader)
+	if _, ok := s.session(r.Context(), id); !ok {
+		s.writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	s.closeSession(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSSE opens a session of the HTTP+SSE transport of protocol
+// 2024-11-05: the stream first names, in an endpoint event, the URL to
+// POST messages to; their answers come back on the stream. The session
+// ends with the stream.
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	hs := s.newSession(r.Context(), TransportSSE)
+	defer s.closeSession(hs.id)
+	endpoint := "/messages?sessionId=" + hs.id
+	s.stream(w, r, hs, func() {
+		fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", endpoint)
+	})
+}
+
+// handleSSEMessage takes a message for an HTTP+SSE session. It is handled
+// in the background and answered on the session's stream.
+func (s *Server) handleSSEMessage(w http.ResponseWriter, r *http.Request) {
+	hs, ok := s.session(r.Context(), r.URL.Query().Get("sessionId"))
+	if !ok {
+		s.writeRPCError(w, http.StatusNotFound, nullID, CodeInvalidRequest, "session not found; open a new one at /sse")
+		return
+	}
+	body, ok := s.readMessage(w, r)
+	if !ok {
+		return
+	}
+
+	// The request ends before the message is handled, so the handling
+	// lives as long as the session, as the caller
+	ctx := hs.ctx
+	if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+		ctx = auth.WithPrincipal(ctx, p)
+	}
+	if method, _ := peek(body); !hs.Initialized() || method == "initialize" {
+		// Keep the handshake in order with the messages after it
+		if out := s.HandleMessage(ctx, &hs.Session, body); out != nil {
+			hs.send(out)
+		}
+	} else {
+		go func() {
+			if out := s.HandleMessage(ctx, &hs.Session, body); out != nil {
+				hs.send(out)
+			}
+		}()
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// stream sends a session's queued messages as "message" events until the
+// client goes away or the session ends; first, if set, writes the events
+// the stream opens with
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, hs *httpSession, first func()) {
+	flusher, ok := startStream(w)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	hs.streams.Add(1)
+	defer func() {
+		hs.streams.Add(-1)
+		hs.touch()
+	}()
+	if first != nil {
+		first()
+		flusher.Flush()
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-hs.ctx.Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case msg := <-hs.outbox:
+			writeEvent(w, "message", msg)
+		}
+		flusher.Flush()
+	}
+}
+
+// startStream sends the headers of an event stream; the write deadline is
+// lifted, since streams outlive the server's write timeout
+func startStream(w http.ResponseWriter) (http.Flusher, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	// Ignore the error: not every writer supports deadlines
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return flusher, true
+}
+
+// writeEvent writes one server-sent event with v as its JSON data
+func writeEvent(w io.Writer, event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// sessionCount is the number of open HTTP sessions
+func (s *Server) sessionCount() int {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	return len(s.sessions)
+}
@@ -0,0 +1,191 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestStreamableHTTP(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	server.initializeTools()
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	post := func(session, body string) (*http.Response, Response) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if session != "" {
+			req.Header.Set(SessionHeader, session)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var resp Response
+		if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusNoContent {
+			if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+				t.Fatalf("%s: %v", body, err)
+			}
+		}
+		return res, resp
+	}
+
+	res, resp := post("", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if res.StatusCode != http.StatusBadRequest || resp.Error == nil {
+		t.Fatalf("tools/list without a session: %d %+v", res.StatusCode, resp)
+	}
+
+	res, resp = post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","clientInfo":{"name":"remote"}}}`)
+	session := res.Header.Get(SessionHeader)
+	if res.StatusCode != http.StatusOK || session == "" || resp.Error != nil {
+		t.Fatalf("initialize: %d %q %+v", res.StatusCode, session, resp)
+	}
+	if res, _ := post(session, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); res.StatusCode != http.StatusAccepted {
+		t.Fatalf("notification: %d", res.StatusCode)
+	}
+	res, resp = post(session, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	if res.StatusCode != http.StatusOK || string(resp.ID) != "2" || !strings.Contains(toJSON(t, resp.Result), `"list_agents"`) {
+		t.Fatalf("tools/list: %d %+v", res.StatusCode, resp)
+	}
+	if res, _ := post("unknown", `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown session: %d", res.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/mcp", nil)
+	req.Header.Set(SessionHeader, session)
+	del, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	del.Body.Close()
+	if del.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE: %d", del.StatusCode)
+	}
+	if res, _ := post(session, `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("deleted session: %d", res.StatusCode)
+	}
+}
+
+func TestSSETransport(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	server.initializeTools()
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/sse", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	if ct := stream.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	events := bufio.NewScanner(stream.Body)
+	next := func() (event, data string) {
+		t.Helper()
+		for events.Scan() {
+			line := events.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "" && event != "":
+				return event, data
+			}
+		}
+		t.Fatalf("stream ended: %v", events.Err())
+		return "", ""
+	}
+
+	event, endpoint := next()
+	if event != "endpoint" || !strings.HasPrefix(endpoint, "/messages?sessionId=") {
+		t.Fatalf("first event %s %q", event, endpoint)
+	}
+	send := func(body string) {
+		t.Helper()
+		res, err := http.Post(ts.URL+endpoint, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusAccepted {
+			t.Fatalf("%s: %d", body, res.StatusCode)
+		}
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	for _, id := range []string{"1", "2"} {
+		event, data := next()
+		var resp Response
+		if err := json.Unmarshal([]byte(data), &resp); err != nil || event != "message" || string(resp.ID) != id || resp.Error != nil {
+			t.Fatalf("response %s: %s %s", id, event, data)
+		}
+	}
+
+	res, err := http.Post(ts.URL+"/messages?sessionId=unknown", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":3,"method":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown session: %d", res.StatusCode)
+	}
+}
+
+func TestStartUsesConfiguredAddress(t *testing.T) {
+	ctx := context.Background()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{Host: "127.0.0.1", Port: port})
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("health: %d", res.StatusCode)
+	}
+
+	// The port is taken now
+	busy := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{Host: "127.0.0.1", Port: port})
+	if err := busy.Start(); err == nil {
+		busy.Stop()
+		t.Error("expected an error for a port in use")
+	}
+
+	disabled := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{Host: "127.0.0.1"})
+	if err := disabled.Start(); err != nil || !disabled.IsHealthy() || disabled.GetStatus()["status"] != "disabled" {
+		t.Errorf("port 0: err %v, status %v", err, disabled.GetStatus()["status"])
+	}
+}
@@ -0,0 +1,554 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/validate"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Request is a JSON-RPC 2.0 request, or a notification when it has no ID
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification reports whether the request expects no response
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Response is a JSON-RPC 2.0 response
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC 2.0 error object
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+// nullID answers requests whose ID could not be read
+var nullID = json.RawMessage("null")
+
+// ClientInfo names the MCP host at the other end of a session
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Session is the state of one MCP connection: the handshake, what the
+// client told about itself and the requests the server sent it
+type Session struct {
+	mu              sync.Mutex
+	initialized     bool
+	protocolVersion string
+	client          ClientInfo
+	capabilities    map[string]interface{}
+	// view is the subset of tools and resources the session sees, chosen
+	// in initialize
+	view *view
+	// send writes a message to the client; nil when the transport cannot
+	// carry messages the client did not ask for
+	send    func(interface{}) error
+	nextID  int64
+	pending map[string]chan reply
+
+	// What the admin view shows, set once the server tracks the session
+	id         string
+	transport  string
+	principal  string
+	openedAt   time.Time
+	lastActive time.Time
+	requests   int64
+	limited    int64
+	// tokens, refilled at refilled, is the bucket of the session's rate
+	// limit
+	tokens   float64
+	refilled time.Time
+}
+
+// reply is the client's response to a request of the server
+type reply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Initialized reports whether the client completed the handshake
+func (s *Session) Initialized() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.initialized
+}
+
+// Client returns what the client said about itself in initialize
+func (s *Session) Client() ClientInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+// Supports reports whether the client declared capability, such as
+// "sampling", in initialize
+func (s *Session) Supports(capability string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.capabilities[capability]
+	return ok
+}
+
+// request sends a request to the client and waits until it answers or ctx
+// is done; the result is decoded into out. A request given up is
+// cancelled with notifications/cancelled.
+func (s *Session) request(ctx context.Context, method string, params, out interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	send := s.send
+	if send == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("the transport cannot send requests to the client")
+	}
+	s.nextID++
+	id := json.RawMessage(fmt.Sprintf(`"skagent-%d"`, s.nextID))
+	if s.pending == nil {
+		s.pending = make(map[string]chan reply)
+	}
+	ch := make(chan reply, 1)
+	s.pending[string(id)] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, string(id))
+		s.mu.Unlock()
+	}()
+
+	if err := send(&Request{JSONRPC: "2.0", ID: id, Method: method, Params: raw}); err != nil {
+		return fmt.Errorf("sending %s: %w", method, err)
+	}
+	select {
+	case r := <-ch:
+		if r.Error != nil {
+			return r.Error
+		}
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(r.Result, out)
+	case <-ctx.Done():
+		send(&Notification{JSONRPC: "2.0", Method: "notifications/cancelled",
+			Params: map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()}})
+		return ctx.Err()
+	}
+}
+
+// deliver hands a response of the client to the request waiting for it.
+// It reports false when no request has the response's ID.
+func (s *Session) deliver(data []byte) bool {
+	var r reply
+	if err := json.Unmarshal(data, &r); err != nil {
+		return false
+	}
+	s.mu.Lock()
+	ch, ok := s.pending[string(bytes.TrimSpace(r.ID))]
+	delete(s.pending, string(bytes.TrimSpace(r.ID)))
+	s.mu.Unlock()
+	if ok {
+		ch <- r
+	}
+	return ok
+}
+
+// initializeParams are the parameters of initialize
+type initializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      ClientInfo             `json:"clientInfo"`
+}
+
+// callParams are the parameters of tools/call
+type callParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Meta      requestMeta            `json:"_meta"`
+}
+
+// content is one item of a tool result
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// HandleMessage answers the JSON-RPC message data of a session: a request,
+// a notification or a batch of them, whose requests run concurrently. It
+// returns a *Response, the []*Response of a batch, or nil when there is
+// nothing to answer: notifications, the client's responses to requests of
+// the server and batches of only those.
+func (s *Server) HandleMessage(ctx context.Context, session *Session, data []byte) interface{} {
+	data = bytes.TrimSpace(data)
+	if !isBatch(data) {
+		if resp := s.handleOne(ctx, session, data, false); resp != nil {
+			return resp
+		}
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return &Response{JSONRPC: "2.0", ID: nullID, Error: &Error{Code: CodeParseError, Message: "invalid JSON: " + err.Error()}}
+	}
+	if len(items) == 0 {
+		return &Response{JSONRPC: "2.0", ID: nullID, Error: &Error{Code: CodeInvalidRequest, Message: "empty batch"}}
+	}
+	if !session.Has(FeatureBatching) {
+		return &Response{JSONRPC: "2.0", ID: nullID, Error: &Error{
+			Code:    CodeInvalidRequest,
+			Message: fmt.Sprintf("batches need protocol %s or later; the session negotiated %s", featureSince[FeatureBatching], session.ProtocolVersion()),
+		}}
+	}
+	responses := make([]*Response, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = s.handleOne(ctx, session, item, true)
+		}()
+	}
+	wg.Wait()
+
+	answered := responses[:0]
+	for _, resp := range responses {
+		if resp != nil {
+			answered = append(answered, resp)
+		}
+	}
+	if len(answered) == 0 {
+		return nil
+	}
+	return answered
+}
+
+// handleOne answers one message, which may be an item of a batch
+func (s *Server) handleOne(ctx context.Context, session *Session, data []byte, batched bool) *Response {
+	req, rerr := decodeRequest(data)
+	switch {
+	case rerr != nil:
+		return &Response{JSONRPC: "2.0", ID: nullID, Error: rerr}
+	case req == nil:
+		if !session.deliver(data) {
+			s.logger.Printf("Ignoring a response to no pending request")
+		}
+		return nil
+	case batched && req.Method == "initialize":
+		if req.IsNotification() {
+			return nil
+		}
+		return &Response{JSONRPC: "2.0", ID: req.ID, Error: &Error{Code: CodeInvalidRequest, Message: "initialize cannot be part of a batch"}}
+	case !req.IsNotification():
+		if wait, ok := session.admit(s.sessionRateLimit, time.Now()); !ok {
+			return &Response{JSONRPC: "2.0", ID: req.ID, Error: rateLimited(s.sessionRateLimit, wait)}
+		}
+	}
+	return s.Handle(ctx, session, req)
+}
+
+// decodeRequest reads a request or notification. It returns nil for a
+// response, which the server takes without answering.
+func decodeRequest(data []byte) (*Request, *Error) {
+	if !json.Valid(data) {
+		var v interface{}
+		err := json.Unmarshal(data, &v)
+		return nil, &Error{Code: CodeParseError, Message: "invalid JSON: " + err.Error()}
+	}
+	var msg struct {
+		Request
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, &Error{Code: CodeInvalidRequest, Message: "a message must be a JSON-RPC object: " + err.Error()}
+	}
+	if msg.Method == "" && (msg.Result != nil || msg.Error != nil) {
+		return nil, nil
+	}
+	if !validID(msg.ID) {
+		return nil, &Error{Code: CodeInvalidRequest, Message: "the id must be a string, a number or null"}
+	}
+	return &msg.Request, nil
+}
+
+// validID reports whether id is absent or a string, number or null, the
+// IDs JSON-RPC allows
+func validID(id json.RawMessage) bool {
+	if len(id) == 0 {
+		return true
+	}
+	switch c := id[0]; {
+	case c == '"', c == '-', c >= '0' && c <= '9':
+		return true
+	default:
+		return string(id) == "null"
+	}
+}
+
+func isBatch(data []byte) bool {
+	return len(data) > 0 && data[0] == '['
+}
+
+// peek reads the method and ID of a message without handling it; both are
+// empty for batches and messages that are not objects
+func peek(data []byte) (method string, id json.RawMessage) {
+	var msg struct {
+		Method string          `json:"method"`
+		ID     json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(data, &msg) != nil {
+		return "", nil
+	}
+	return msg.Method, msg.ID
+}
+
+// Handle answers one JSON-RPC message of a session. It returns nil for
+// notifications, which get no response.
+func (s *Server) Handle(ctx context.Context, session *Session, req *Request) *Response {
+	result, err := s.dispatch(ctx, session, req)
+	if req.IsNotification() {
+		if err != nil {
+			s.logger.Printf("ERROR: Notification %s failed: %v", req.Method, err)
+		}
+		return nil
+	}
+	resp := &Response{JSONRPC: "2.0", ID: req.ID}
+	switch {
+	case err != nil:
+		resp.Error = err
+	case result == nil:
+		resp.Result = struct{}{}
+	default:
+		resp.Result = result
+	}
+	return resp
+}
+
+func (s *Server) dispatch(ctx context.Context, session *Session, req *Request) (interface{}, *Error) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &Error{Code: CodeInvalidRequest, Message: `requests need "jsonrpc": "2.0" and a method`}
+	}
+	if p := bytes.TrimSpace(req.Params); len(p) > 0 && p[0] != '{' && p[0] != '[' && string(p) != "null" {
+		return nil, &Error{Code: CodeInvalidRequest, Message: "params must be an object or an array"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return s.initialize(session, req.Params)
+	case "notifications/initialized":
+		return nil, nil
+	case "ping":
+		return struct{}{}, nil
+	}
+	if !session.Initialized() {
+		return nil, &Error{Code: CodeInvalidRequest, Message: "the session is not initialized; send initialize first"}
+	}
+
+	switch req.Method {
+	case "tools/list":
+		return map[string]interface{}{"tools": s.toolList(ctx, session)}, nil
+	case "tools/call":
+		return s.callTool(ctx, session, req.Params)
+	case "resources/list":
+		return map[string]interface{}{"resources": s.resourceList(ctx, session.currentView())}, nil
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": resourceTemplates(session.currentView())}, nil
+	case "resources/read":
+		return s.readResource(ctx, session.currentView(), req.Params)
+	default:
+		if strings.HasPrefix(req.Method, "notifications/") {
+			// Notifications the server does not act on are ignored
+			return nil, nil
+		}
+		return nil, &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+// initialize records the client, negotiates the protocol revision and
+// answers with the server's version and capabilities. A session
+// initializes once.
+func (s *Server) initialize(session *Session, raw json.RawMessage) (interface{}, *Error) {
+	var params initializeParams
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: "invalid initialize parameters: " + err.Error()}
+		}
+	}
+
+	version := negotiateVersion(params.ProtocolVersion)
+	session.mu.Lock()
+	if session.initialized {
+		// The view was chosen for the client the session first declared
+		session.mu.Unlock()
+		return nil, &Error{Code: CodeInvalidRequest, Message: "the session is already initialized"}
+	}
+	session.initialized = true
+	session.protocolVersion = version
+	session.client = params.ClientInfo
+	session.capabilities = params.Capabilities
+	session.view = s.viewFor(params.ClientInfo.Name, session.principal, session.transport)
+	session.mu.Unlock()
+	if version != params.ProtocolVersion {
+		s.logger.Printf("MCP client %s %s initialized (asked for protocol %q, offered %s)", params.ClientInfo.Name, params.ClientInfo.Version, params.ProtocolVersion, version)
+	} else {
+		s.logger.Printf("MCP client %s %s initialized (protocol %s)", params.ClientInfo.Name, params.ClientInfo.Version, version)
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools":     map[string]interface{}{"listChanged": true},
+			"resources": map[string]interface{}{"listChanged": false, "subscribe": false},
+			"experimental": map[string]interface{}{
+				"skagent/notifications": map[string]interface{}{"methods": notificationMethods()},
+			},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    "skagent",
+			"version": "2.0.0",
+		},
+	}, nil
+}
+
+// toolList returns by name the definitions of the tools the caller of ctx
+// may call and the session's view exposes, so that a read-only key is not
+// offered tools it cannot run. Sessions of a revision with tool
+// annotations get those of the built-in tools.
+func (s *Server) toolList(ctx context.Context, session *Session) []ToolDefinition {
+	if !s.permitted(ctx, auth.PermToolsRead) {
+		return []ToolDefinition{}
+	}
+	annotate := session.Has(FeatureToolAnnotations)
+	v := session.currentView()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]ToolDefinition, 0, len(s.tools))
+	for _, t := range s.tools {
+		if v.allowsTool(t.Name) && s.permitted(ctx, ToolPermission(t.Name)) {
+			if annotate {
+				t.Annotations = builtinAnnotations(t.Name)
+			}
+			list = append(list, t)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// callTool runs a tool. Failures of the tool itself are results with
+// isError set, as MCP asks, so the model can see and handle them. When the
+// client grants sampling, the tool can ask its model for completions; when
+// the call carries a progress token, the tool reports its progress.
+func (s *Server) callTool(ctx context.Context, session *Session, raw json.RawMessage) (interface{}, *Error) {
+	var params callParams
+	if err := json.Unmarshal(raw, &params); err != nil || params.Name == "" {
+		return nil, &Error{Code: CodeInvalidParams, Message: "tools/call needs the name of a tool"}
+	}
+	s.mu.RLock()
+	_, exists := s.tools[params.Name]
+	s.mu.RUnlock()
+	// Tools outside the session's view do not exist for it
+	if !exists || !session.currentView().allowsTool(params.Name) {
+		return nil, &Error{Code: CodeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+
+	if s.authz != nil {
+		principal, _ := auth.PrincipalFromContext(ctx)
+		perm := ToolPermission(params.Name)
+		if !s.authz.Check(principal, perm, "tool "+params.Name) {
+			return toolError("role " + string(principal.Role) + " lacks permission " + string(perm)), nil
+		}
+	}
+	if params.Arguments == nil {
+		params.Arguments = map[string]interface{}{}
+	}
+	// Invalid arguments are a failure of the call the model can fix, so
+	// they are a tool error too
+	if errs := s.checkArguments(params.Name, params.Arguments); errs != nil {
+		result := toolError(argumentsMessage(errs))
+		result["structuredContent"] = map[string]interface{}{"errors": errs}
+		return result, nil
+	}
+
+	ctx = s.withSampling(ctx, session)
+	ctx, stop := s.withProgress(ctx, session, params.Meta.ProgressToken)
+	result, err := s.executeTool(ctx, params.Name, params.Arguments)
+	stop()
+	if err != nil {
+		return toolError(err.Error()), nil
+	}
+	text, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, &Error{Code: CodeInternalError, Message: "encoding the tool result: " + err.Error()}
+	}
+	return map[string]interface{}{
+		"content": []content{{Type: "text", Text: string(text)}},
+		"isError": false,
+	}, nil
+}
+
+// checkArguments checks the arguments of a call against the input schema
+// of the tool, so that tools get the types they assert
+func (s *Server) checkArguments(toolName string, args map[string]interface{}) validate.Errors {
+	s.mu.RLock()
+	tool, ok := s.tools[toolName]
+	s.mu.RUnlock()
+	if !ok || tool.InputSchema == nil {
+		return nil
+	}
+	return validate.Schema(tool.InputSchema, args)
+}
+
+// argumentsMessage describes invalid arguments in one sentence, as in
+// "invalid arguments: agent_id is required"
+func argumentsMessage(errs validate.Errors) string {
+	parts := make([]string, len(errs))
+	for i, fe := range errs {
+		parts[i] = fe.Field + " " + fe.Message
+	}
+	return "invalid arguments: " + strings.Join(parts, "; ")
+}
+
+func toolError(message string) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []content{{Type: "text", Text: message}},
+		"isError": true,
+	}
+}
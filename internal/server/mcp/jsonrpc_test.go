@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestHandleMessageBatch(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	server.initializeTools()
+	var session Session
+	if resp, ok := server.HandleMessage(ctx, &session, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`)).(*Response); !ok || resp.Error != nil {
+		t.Fatalf("initialize: %+v", resp)
+	}
+
+	out := server.HandleMessage(ctx, &session, []byte(`[
+		{"jsonrpc":"2.0","id":"a","method":"ping"},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":2,"method":"nope"},
+		{"jsonrpc":"2.0","id":3,"method":"tools/call","params":"get_agent"},
+		{"jsonrpc":"2.0","id":{"x":1},"method":"ping"},
+		{"jsonrpc":"2.0","id":4,"method":"initialize"},
+		{"jsonrpc":"2.0","id":9,"result":{}},
+		7
+	]`))
+	responses, ok := out.([]*Response)
+	if !ok {
+		t.Fatalf("batch answered with %T", out)
+	}
+	want := []struct {
+		id   string
+		code int
+	}{
+		{`"a"`, 0},
+		{"2", CodeMethodNotFound},
+		{"3", CodeInvalidRequest},
+		{"null", CodeInvalidRequest},
+		{"4", CodeInvalidRequest},
+		{"null", CodeInvalidRequest},
+	}
+	if len(responses) != len(want) {
+		t.Fatalf("%d responses: %s", len(responses), toJSON(t, responses))
+	}
+	for i, w := range want {
+		resp := responses[i]
+		code := 0
+		if resp.Error != nil {
+			code = resp.Error.Code
+		}
+		if string(resp.ID) != w.id || code != w.code {
+			t.Errorf("response %d: id %s, code %d; want %s, %d", i, resp.ID, code, w.id, w.code)
+		}
+	}
+
+	if out := server.HandleMessage(ctx, &session, []byte(`[{"jsonrpc":"2.0","method":"notifications/initialized"}]`)); out != nil {
+		t.Fatalf("batch of notifications answered with %v", out)
+	}
+	if resp, ok := server.HandleMessage(ctx, &session, []byte(`[]`)).(*Response); !ok || resp.Error.Code != CodeInvalidRequest {
+		t.Fatalf("empty batch: %+v", resp)
+	}
+	if resp, ok := server.HandleMessage(ctx, &session, []byte(`[{"jsonrpc":"2.0"`)).(*Response); !ok || resp.Error.Code != CodeParseError {
+		t.Fatalf("truncated batch: %+v", resp)
+	}
+}
+
+func TestCallEnvelope(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("writer", "coder", nil)
+	server := NewServer(ctx, registry, config.MCPConfig{})
+	server.initializeTools()
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	call := func(path, body string) (int, Response) {
+		t.Helper()
+		res, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var resp Response
+		if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, resp
+	}
+
+	status, resp := call("/tools/get_agent/call", `{"agent_id":"`+agent.ID+`"}`)
+	if status != http.StatusOK || resp.JSONRPC != "2.0" || string(resp.ID) != "null" || !strings.Contains(toJSON(t, resp.Result), "writer") {
+		t.Fatalf("plain arguments: %d %+v", status, resp)
+	}
+	status, resp = call("/tools/get_agent/call", `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"get_agent","arguments":{"agent_id":"`+agent.ID+`"}}}`)
+	if status != http.StatusOK || string(resp.ID) != "7" || resp.Error != nil {
+		t.Fatalf("envelope: %d %+v", status, resp)
+	}
+	status, resp = call("/tools/get_agent/call", `{"jsonrpc":"2.0","id":8,"method":"tools/list"}`)
+	if status != http.StatusBadRequest || string(resp.ID) != "8" || resp.Error == nil || resp.Error.Code != CodeMethodNotFound {
+		t.Fatalf("wrong method: %d %+v", status, resp)
+	}
+	status, resp = call("/tools/missing/call", `{"jsonrpc":"2.0","id":"m","method":"tools/call"}`)
+	if status != http.StatusNotFound || string(resp.ID) != `"m"` || resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Fatalf("unknown tool: %d %+v", status, resp)
+	}
+	status, resp = call("/agents/"+agent.ID+"/execute", `{"jsonrpc":"2.0","id":9,"method":"agents/execute","params":{}}`)
+	if status != http.StatusBadRequest || string(resp.ID) != "9" || resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Fatalf("execute without a task: %d %+v", status, resp)
+	}
+
+	// Arguments are checked against the tool's schema, field by field
+	status, resp = call("/tools/create_task/call", `{"agent_id": 7, "task": "x", "priority": 11}`)
+	if status != http.StatusBadRequest || resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Fatalf("invalid arguments: %d %+v", status, resp)
+	}
+	if body := toJSON(t, resp.Error.Data); !strings.Contains(body, `{"field":"agent_id","message":"must be a string"}`) ||
+		!strings.Contains(body, `{"field":"priority","message":"must be at most 10"}`) {
+		t.Errorf("details: %s", body)
+	}
+}
@@ -3,34 +3,40 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/audit"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/server/bodylimit"
+	"github.com/biodoia/skagent/internal/server/requestid"
+	"github.com/biodoia/skagent/internal/tools"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-type MCPRequest struct {
-	ID          string                 `json:"id"`
-	Method      string                 `json:"method"`
-	Params      map[string]interface{} `json:"params,omitempty"`
-	JSONRPC     string                 `json:"jsonrpc"`
-}
-
-type MCPResponse struct {
-	ID     string                 `json:"id"`
-	Result map[string]interface{} `json:"result,omitempty"`
-	Error  map[string]interface{} `json:"error,omitempty"`
-}
+// MCPRequest and MCPResponse are the JSON-RPC 2.0 envelopes of the tool
+// call and agent execution endpoints
+type MCPRequest = Request
+type MCPResponse = Response
 
 type ToolDefinition struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	InputSchema map[string]interface{} `json:"inputSchema"`
+	// Annotations are given to sessions of a revision that has them
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
 }
 
 type AgentDefinition struct {
@@ -42,6 +48,7 @@ type AgentDefinition struct {
 }
 
 type Server struct {
+	host          string
 	port          int
 	ctx           context.Context
 	agentRegistry *agents.Registry
@@ -50,62 +57,160 @@ type Server struct {
 	tools         map[string]ToolDefinition
 	mu            sync.RWMutex
 	activeConnections int
+	authz         *auth.Authorizer
+	audit         *audit.Log
+	maxBodySize   int64
+	sessionsMu    sync.Mutex
+	sessions      map[string]*httpSession
+	sessionSource SessionSource
+	specsDir      string
+	logs          *logging.Ring
+	socketPath    string
+	socketMode    os.FileMode
+	handlers      map[string]ToolHandler
+	notifyMu      sync.Mutex
+	listeners     map[*listener]bool
+	watchOnce     sync.Once
+	stats         *toolStats
+	sampling      string
+	samplingMax   int
+	// clients are the sessions of every transport, by ID, for the admin
+	// view
+	clientsMu        sync.Mutex
+	clients          map[string]*Session
+	sessionRateLimit int
+	transports       []string
+	// views restrict what the sessions they match see
+	views       []config.MCPViewConfig
+	defaultView string
+	// wsOrigins are the other origins allowed to open WebSockets
+	wsOrigins []string
+	// workspaceTools, when set, returns the tools of a workspace
+	workspaceTools func(workspace string) *tools.ToolManager
 }
 
-func NewServer(ctx context.Context, registry *agents.Registry) *Server {
+// NewServer creates an MCP server that listens on cfg's host and port; a
+// port of 0 disables the TCP listener. The stdio transport ignores both.
+func NewServer(ctx context.Context, registry *agents.Registry, cfg config.MCPConfig) *Server {
 	return &Server{
+		host:          cfg.Host,
+		port:          cfg.Port,
 		ctx:           ctx,
 		agentRegistry: registry,
-		logger:        log.New(log.Writer(), "[MCP] ", log.LstdFlags|log.Lmsgprefix),
+		logger:        logging.New("mcp", "[MCP] ", log.Writer()),
 		tools:         make(map[string]ToolDefinition),
 		activeConnections: 0,
+		logs:          logging.Default(),
+		stats:         newToolStats(),
+		sampling:      cfg.Sampling,
+		samplingMax:   cfg.SamplingMaxTokens,
+		sessionRateLimit: cfg.SessionRateLimit,
+		transports:       cfg.Transports,
+		views:            cfg.Views,
+		defaultView:      cfg.DefaultView,
+		wsOrigins:        cfg.WebSocketOrigins,
 	}
 }
 
+// Start serves the HTTP transports on the configured address and socket.
+// With neither it does nothing; an address already in use is an error.
 func (s *Server) Start() error {
+	if !s.Enabled() {
+		s.logger.Printf("MCP server disabled (mcp.port is 0 and no socket is set)")
+		return nil
+	}
+	
 	// Initialize built-in tools
 	s.initializeTools()
 	
 	router := s.setupRoutes()
 	
 	s.server = &http.Server{
-		Addr:         ":8081",
+		Addr:         s.Addr(),
 		Handler:      router,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	if s.authz == nil {
+		s.logger.Printf("WARNING: authentication is off; anyone who reaches the server can control agents and run tools (set mcp.enable_auth)")
+	}
+	if err := s.serveSocket(); err != nil {
+		return err
+	}
+	if s.port == 0 {
+		return nil
+	}
 	
-	s.logger.Printf("Starting MCP server on port 8081")
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("mcp server: %w", err)
+	}
+	s.logger.Printf("Starting MCP server on http://%s", s.server.Addr)
 	
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			s.logger.Printf("MCP server error: %v", err)
+		if err := s.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			s.logger.Printf("ERROR: MCP server error: %v", err)
 		}
 	}()
 	
 	return nil
 }
 
+// Enabled reports whether Start serves anything: a TCP port or a socket
+func (s *Server) Enabled() bool {
+	return s.port > 0 || s.socketPath != ""
+}
+
+// Addr returns the host:port the server listens on
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.host, strconv.Itoa(s.port))
+}
+
 func (s *Server) Stop() error {
-	if s.server != nil {
-		return s.server.Shutdown(s.ctx)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.Shutdown(ctx, false)
+}
+
+// Shutdown stops the server. A graceful shutdown waits for active requests
+// until ctx is done; a forced one closes every connection immediately.
+func (s *Server) Shutdown(ctx context.Context, force bool) error {
+	if s.server == nil {
+		return nil
+	}
+	// Event streams only end with their sessions
+	s.closeSessions()
+	if force {
+		return s.server.Close()
+	}
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+		return err
 	}
 	return nil
 }
 
+// IsHealthy reports whether the server is serving, or is disabled
 func (s *Server) IsHealthy() bool {
-	return s.server != nil
+	return s.server != nil || !s.Enabled()
 }
 
 func (s *Server) GetStatus() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
+	status := "running"
+	if !s.Enabled() {
+		status = "disabled"
+	}
 	return map[string]interface{}{
-		"status":              "running",
-		"port":                8081,
+		"status":              status,
+		"host":                s.host,
+		"port":                s.port,
 		"active_connections":  s.activeConnections,
+		"sessions":            s.sessionCount(),
+		"clients":             s.clientCount(),
 		"registered_tools":    len(s.tools),
 		"agent_registry":      s.agentRegistry.GetStats(),
 	}
@@ -115,9 +220,11 @@ func (s *Server) setupRoutes() http.Handler {
 	router := chi.NewRouter()
 	
 	// Middleware
-	router.Use(middleware.Logger)
+	router.Use(requestid.Middleware)
+	router.Use(middleware.RequestLogger(&middleware.DefaultLogFormatter{Logger: s.logger, NoColor: true}))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.Compress(5))
+	router.Use(s.bodyLimitMiddleware)
 	router.Use(s.connectionMiddleware)
 	
 	// MCP-specific middleware
@@ -125,8 +232,9 @@ func (s *Server) setupRoutes() http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Request-ID, "+SessionHeader)
+			w.Header().Set("Access-Control-Expose-Headers", SessionHeader)
 			
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(http.StatusOK)
@@ -136,6 +244,8 @@ func (s *Server) setupRoutes() http.Handler {
 			next.ServeHTTP(w, r)
 		})
 	})
+	router.Use(s.authMiddleware)
+	router.Use(s.auditMiddleware)
 	
 	// MCP endpoints
 	router.Get("/health", s.handleMCPHealth)
@@ -143,6 +253,22 @@ func (s *Server) setupRoutes() http.Handler {
 	router.Get("/tools/{toolName}", s.handleGetTool)
 	router.Post("/tools/{toolName}/call", s.handleCallTool)
 	
+	// MCP protocol over the transports of mcp.transports: Streamable HTTP,
+	// HTTP+SSE for older clients and WebSocket
+	for _, t := range s.httpTransports() {
+		switch t {
+		case TransportStreamable:
+			router.Post("/mcp", s.handleStreamablePost)
+			router.Get("/mcp", s.handleStreamableGet)
+			router.Delete("/mcp", s.handleStreamableDelete)
+		case TransportSSE:
+			router.Get("/sse", s.handleSSE)
+			router.Post("/messages", s.handleSSEMessage)
+		case TransportWebSocket:
+			router.Get("/ws", s.handleWebSocket)
+		}
+	}
+	
 	// Agent endpoints
 	router.Get("/agents", s.handleListAgents)
 	router.Get("/agents/{agentID}", s.handleGetAgent)
@@ -151,10 +277,20 @@ func (s *Server) setupRoutes() http.Handler {
 	// System endpoints
 	router.Get("/info", s.handleServerInfo)
 	router.Get("/capabilities", s.handleGetCapabilities)
+	router.Get("/calls", s.handleListCalls)
+	router.Get("/clients", s.handleListClients)
 	
 	return router
 }
 
+// httpTransports returns the transports served on the port
+func (s *Server) httpTransports() []string {
+	if len(s.transports) == 0 {
+		return config.DefaultMCPTransports
+	}
+	return s.transports
+}
+
 func (s *Server) connectionMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		s.mu.Lock()
@@ -239,7 +375,7 @@ func (s *Server) initializeTools() {
 	// Task management tools
 	s.tools["create_task"] = ToolDefinition{
 		Name:        "create_task",
-		Description: "Create a new task for an agent",
+		Description: "Create a new task for an agent; with wait, follow it until it finishes, reporting its progress",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -257,6 +393,16 @@ func (s *Server) initializeTools() {
 					"minimum":     1,
 					"maximum":     10,
 				},
+				"wait": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Wait for the task to finish and return its result",
+				},
+				"timeout_seconds": map[string]interface{}{
+					"type":        "integer",
+					"description": "How long to wait, 300 by default; the task goes on after it",
+					"minimum":     1,
+					"maximum":     3600,
+				},
 			},
 			"required": []string{"agent_id", "task"},
 		},
@@ -382,12 +528,23 @@ func (s *Server) handleMCPHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r, auth.PermToolsRead, "tools") {
+		return
+	}
+	
+	v := s.routeView(r)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
+	tools := make(map[string]ToolDefinition, len(s.tools))
+	for name, tool := range s.tools {
+		if v.allowsTool(name) {
+			tools[name] = tool
+		}
+	}
 	response := map[string]interface{}{
-		"tools":    s.tools,
-		"count":    len(s.tools),
+		"tools":    tools,
+		"count":    len(tools),
 		"server":   "skagent-mcp",
 		"version":  "2.0.0",
 		"timestamp": time.Now(),
@@ -399,11 +556,15 @@ func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetTool(w http.ResponseWriter, r *http.Request) {
 	toolName := chi.URLParam(r, "toolName")
 	
+	if !s.authorize(w, r, auth.PermToolsRead, "tool "+toolName) {
+		return
+	}
+	
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
 	tool, exists := s.tools[toolName]
-	if !exists {
+	if !exists || !s.routeView(r).allowsTool(toolName) {
 		s.writeError(w, http.StatusNotFound, "Tool not found")
 		return
 	}
@@ -419,32 +580,47 @@ func (s *Server) handleGetTool(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleCallTool(w http.ResponseWriter, r *http.Request) {
 	toolName := chi.URLParam(r, "toolName")
 	
-	var params map[string]interface{}
-	if err := s.parseJSON(r, &params); err != nil {
-		s.writeError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+	if !s.authorize(w, r, ToolPermission(toolName), "tool "+toolName) {
 		return
 	}
 	
-	// Execute tool
-	result, err := s.executeTool(toolName, params)
-	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+	id, params, ok := s.readCall(w, r, "tools/call", toolName)
+	if !ok {
+		return
+	}
+	s.mu.RLock()
+	_, exists := s.tools[toolName]
+	s.mu.RUnlock()
+	// Tools outside the caller's view do not exist for it
+	if !exists || !s.routeView(r).allowsTool(toolName) {
+		s.writeRPCError(w, http.StatusNotFound, id, CodeInvalidParams, "unknown tool: "+toolName)
+		return
+	}
+	if errs := s.checkArguments(toolName, params); errs != nil {
+		s.writeRPCInvalid(w, id, errs)
 		return
 	}
 	
-	response := MCPResponse{
-		Result: map[string]interface{}{
-			"tool":     toolName,
-			"result":   result,
-			"timestamp": time.Now(),
-		},
+	// Execute tool
+	result, err := s.executeTool(r.Context(), toolName, params)
+	if err != nil {
+		s.writeRPCError(w, http.StatusInternalServerError, id, CodeInternalError, err.Error())
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, response)
+	s.writeRPCResult(w, id, map[string]interface{}{
+		"tool":     toolName,
+		"result":   result,
+		"timestamp": time.Now(),
+	})
 }
 
 func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
-	agents := s.agentRegistry.ListAgents()
+	if !s.authorize(w, r, auth.PermAgentsRead, "agents") || !s.viewAllows(w, r, "list_agents") {
+		return
+	}
+	
+	agents := s.visibleAgents(r.Context())
 	
 	response := map[string]interface{}{
 		"agents":    agents,
@@ -458,7 +634,11 @@ func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetAgent(w http.ResponseWriter, r *http.Request) {
 	agentID := chi.URLParam(r, "agentID")
 	
-	agent, ok := s.agentRegistry.GetAgent(agentID)
+	if !s.authorize(w, r, auth.PermAgentsRead, "agent "+agentID) || !s.viewAllows(w, r, "get_agent") {
+		return
+	}
+	
+	agent, ok := s.visibleAgent(r.Context(), agentID)
 	if !ok {
 		s.writeError(w, http.StatusNotFound, "Agent not found")
 		return
@@ -472,37 +652,119 @@ func (s *Server) handleGetAgent(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// handleExecuteAgent gives an agent a task, taking the arguments of
+// create_task but agent_id, and answers with the task to poll with
+// get_task_status: 202 while it runs, 200 once it is finished. With wait
+// set, in the arguments or as ?wait=true, the call waits for the task as
+// create_task does, for at most timeout_seconds (also a query parameter);
+// a client that accepts text/event-stream then gets the task's progress
+// as notifications before the answer, under the progressToken of the
+// params' _meta or else the ID of the request.
 func (s *Server) handleExecuteAgent(w http.ResponseWriter, r *http.Request) {
 	agentID := chi.URLParam(r, "agentID")
 	
-	var params map[string]interface{}
-	if err := s.parseJSON(r, &params); err != nil {
-		s.writeError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+	if !s.authorize(w, r, auth.PermTasksWrite, "agent "+agentID) || !s.viewAllows(w, r, "create_task") {
 		return
 	}
 	
-	task, ok := params["task"].(string)
+	id, params, ok := s.readCall(w, r, "agents/execute", "")
 	if !ok {
-		s.writeError(w, http.StatusBadRequest, "Task parameter required")
 		return
 	}
-	
-	// Execute agent task
-	result := map[string]interface{}{
-		"agent_id":  agentID,
-		"task":      task,
-		"status":    "submitted",
-		"timestamp": time.Now(),
+	if _, ok := s.visibleAgent(r.Context(), agentID); !ok {
+		s.writeRPCError(w, http.StatusNotFound, id, CodeInvalidParams, "Agent not found")
+		return
 	}
 	
-	response := MCPResponse{
-		Result: result,
+	params["agent_id"] = agentID
+	query := r.URL.Query()
+	if v := query.Get("wait"); v != "" {
+		wait, err := strconv.ParseBool(v)
+		if err != nil {
+			s.writeRPCError(w, http.StatusBadRequest, id, CodeInvalidParams, "wait must be true or false")
+			return
+		}
+		params["wait"] = wait
+	}
+	if v := query.Get("timeout_seconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			s.writeRPCError(w, http.StatusBadRequest, id, CodeInvalidParams, "timeout_seconds must be a positive integer")
+			return
+		}
+		params["timeout_seconds"] = float64(n)
+	}
+	// The arguments are those of create_task, and so is their schema
+	if errs := s.checkArguments("create_task", params); errs != nil {
+		s.writeRPCInvalid(w, id, errs)
+		return
+	}
+	req, err := parseTaskRequest(params)
+	if err != nil {
+		s.writeRPCError(w, http.StatusBadRequest, id, CodeInvalidParams, "Task parameter required")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, response)
+	ctx := r.Context()
+	stop := func() {}
+	var rs *responseStream
+	if req.Wait && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		rs = &responseStream{w: w}
+		token := id
+		if meta, ok := params["_meta"].(map[string]interface{}); ok && meta["progressToken"] != nil {
+			token, _ = json.Marshal(meta["progressToken"])
+		}
+		ctx, stop = s.withProgress(withNotifier(ctx, rs.send), &Session{}, token)
+	}
+	result, err := s.dispatchTask(ctx, req, "executed through the MCP agent route")
+	stop()
+	if rs != nil && rs.close() {
+		// The stream started, so the answer is its last event
+		resp := &Response{JSONRPC: "2.0", ID: id, Result: result}
+		if err != nil {
+			resp = &Response{JSONRPC: "2.0", ID: id, Error: &Error{Code: CodeInternalError, Message: err.Error()}}
+		}
+		writeEvent(w, "message", resp)
+		rs.flusher.Flush()
+		return
+	}
+	if err != nil {
+		s.writeRPCError(w, http.StatusInternalServerError, id, CodeInternalError, err.Error())
+		return
+	}
+	status := http.StatusOK
+	if taskState, _ := result["status"].(agents.TaskStatus); !taskState.Finished() {
+		status = http.StatusAccepted
+	}
+	s.writeJSON(w, status, &Response{JSONRPC: "2.0", ID: id, Result: result})
 }
 
+// handleServerInfo describes the server, its endpoints and transports and
+// how the tools fare
 func (s *Server) handleServerInfo(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r, auth.PermSystemRead, "server info") {
+		return
+	}
+	
+	endpoints := map[string]interface{}{
+		"health":       "/health",
+		"tools":        "/tools",
+		"agents":       "/agents",
+		"info":         "/info",
+		"capabilities": "/capabilities",
+		"calls":        "/calls",
+		"clients":      "/clients",
+	}
+	for _, t := range s.httpTransports() {
+		switch t {
+		case TransportStreamable:
+			endpoints["mcp"] = "/mcp"
+		case TransportSSE:
+			endpoints["sse"] = "/sse"
+		case TransportWebSocket:
+			endpoints["websocket"] = "/ws"
+		}
+	}
 	response := map[string]interface{}{
 		"name":        "SKAgent MCP Server",
 		"version":     "2.0.0",
@@ -514,15 +776,11 @@ func (s *Server) handleServerInfo(w http.ResponseWriter, r *http.Request) {
 			"tool_execution",
 			"system_monitoring",
 		},
-		"endpoints": map[string]interface{}{
-			"health":      "/health",
-			"tools":       "/tools",
-			"agents":      "/agents",
-			"info":        "/info",
-			"capabilities": "/capabilities",
-		},
+		"endpoints": endpoints,
+		"transports": s.httpTransports(),
 		"timestamp": time.Now(),
 	}
+	response["tool_stats"] = s.stats.snapshot()
 	
 	s.writeJSON(w, http.StatusOK, response)
 }
@@ -539,7 +797,8 @@ func (s *Server) handleGetCapabilities(w http.ResponseWriter, r *http.Request) {
 			"logging",
 			"metrics",
 		},
-		"protocol_version": "2024-11-05",
+		"protocol_version":  ProtocolVersion,
+		"protocol_versions": protocolFeatures(),
 		"server_info": map[string]interface{}{
 			"name":    "SKAgent",
 			"version": "2.0.0",
@@ -550,11 +809,16 @@ func (s *Server) handleGetCapabilities(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, response)
 }
 
-func (s *Server) executeTool(toolName string, params map[string]interface{}) (map[string]interface{}, error) {
+// runTool runs a tool, registered or built-in
+func (s *Server) runTool(ctx context.Context, toolName string, params map[string]interface{}) (map[string]interface{}, error) {
+	if handler, ok := s.handler(toolName); ok {
+		return handler(ctx, params)
+	}
+
 	switch toolName {
 	case "list_agents":
 		status, _ := params["status"].(string)
-		agents := s.agentRegistry.ListAgents()
+		agents := s.visibleAgents(ctx)
 		
 		if status != "" {
 			filtered := []map[string]interface{}{}
@@ -580,7 +844,7 @@ func (s *Server) executeTool(toolName string, params map[string]interface{}) (ma
 			return nil, fmt.Errorf("agent_id parameter required")
 		}
 		
-		agent, ok := s.agentRegistry.GetAgent(agentID)
+		agent, ok := s.visibleAgent(ctx, agentID)
 		if !ok {
 			return nil, fmt.Errorf("agent not found")
 		}
@@ -601,6 +865,9 @@ func (s *Server) executeTool(toolName string, params map[string]interface{}) (ma
 			return nil, fmt.Errorf("agent_id parameter required")
 		}
 		
+		if _, ok := s.visibleAgent(ctx, agentID); !ok {
+			return nil, fmt.Errorf("failed to start agent: %w", agents.ErrAgentNotFound)
+		}
 		if err := s.agentRegistry.StartAgent(agentID); err != nil {
 			return nil, fmt.Errorf("failed to start agent: %w", err)
 		}
@@ -613,6 +880,9 @@ func (s *Server) executeTool(toolName string, params map[string]interface{}) (ma
 			return nil, fmt.Errorf("agent_id parameter required")
 		}
 		
+		if _, ok := s.visibleAgent(ctx, agentID); !ok {
+			return nil, fmt.Errorf("failed to stop agent: %w", agents.ErrAgentNotFound)
+		}
 		if err := s.agentRegistry.StopAgent(agentID); err != nil {
 			return nil, fmt.Errorf("failed to stop agent: %w", err)
 		}
@@ -620,26 +890,7 @@ func (s *Server) executeTool(toolName string, params map[string]interface{}) (ma
 		return map[string]interface{}{"status": "stopped", "agent_id": agentID}, nil
 		
 	case "create_task":
-		agentID, ok := params["agent_id"].(string)
-		if !ok {
-			return nil, fmt.Errorf("agent_id parameter required")
-		}
-		
-		task, ok := params["task"].(string)
-		if !ok {
-			return nil, fmt.Errorf("task parameter required")
-		}
-		
-		priority, _ := params["priority"].(float64)
-		taskID := fmt.Sprintf("task-%d", time.Now().Unix())
-		
-		return map[string]interface{}{
-			"task_id":  taskID,
-			"agent_id": agentID,
-			"task":     task,
-			"priority": int(priority),
-			"status":   "created",
-		}, nil
+		return s.createTask(ctx, params)
 		
 	case "get_task_status":
 		taskID, ok := params["task_id"].(string)
@@ -647,11 +898,7 @@ func (s *Server) executeTool(toolName string, params map[string]interface{}) (ma
 			return nil, fmt.Errorf("task_id parameter required")
 		}
 		
-		return map[string]interface{}{
-			"task_id":  taskID,
-			"status":   "running",
-			"progress": 50,
-		}, nil
+		return s.getTaskStatus(ctx, taskID)
 		
 	case "get_system_status":
 		return map[string]interface{}{
@@ -742,8 +989,35 @@ func (s *Server) executeTool(toolName string, params map[string]interface{}) (ma
 }
 
 // Helper methods
-func (s *Server) parseJSON(r *http.Request, v interface{}) error {
-	return json.NewDecoder(r.Body).Decode(v)
+
+// writeDecodeError answers a JSON-RPC request whose body could not be
+// read
+func (s *Server) writeDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		s.writeRPCError(w, http.StatusRequestEntityTooLarge, nullID, CodeInvalidRequest,
+			fmt.Sprintf("request body exceeds the limit of %d bytes", tooLarge.Limit))
+		return
+	}
+	s.writeRPCError(w, http.StatusBadRequest, nullID, CodeParseError, "invalid JSON: "+err.Error())
+}
+
+// bodyLimitMiddleware bounds request bodies to the configured size,
+// refusing other encodings than gzip as writeFailure does
+func (s *Server) bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reject := func(w http.ResponseWriter, status int, message string) {
+			s.writeFailure(w, r, status, CodeInvalidRequest, message)
+		}
+		bodylimit.Middleware(s.maxBodySize, reject)(next).ServeHTTP(w, r)
+	})
+}
+
+// SetMaxBodySize bounds request bodies, after decompression, to n bytes;
+// 0 uses bodylimit.DefaultLimit. It must be called before the server
+// starts.
+func (s *Server) SetMaxBodySize(n int64) {
+	s.maxBodySize = n
 }
 
 func (s *Server) writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
@@ -755,15 +1029,16 @@ func (s *Server) writeJSON(w http.ResponseWriter, statusCode int, v interface{})
 	encoder.SetIndent("", "  ")
 	
 	if err := encoder.Encode(v); err != nil {
-		s.logger.Printf("Error encoding JSON response: %v", err)
+		s.logger.Printf("ERROR: Error encoding JSON response: %v", err)
 	}
 }
 
 func (s *Server) writeError(w http.ResponseWriter, statusCode int, message string) {
 	response := map[string]interface{}{
 		"error": map[string]interface{}{
-			"code":    statusCode,
-			"message": message,
+			"code":       statusCode,
+			"message":    message,
+			"request_id": requestid.FromResponse(w),
 		},
 		"timestamp": time.Now(),
 	}
@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/auth"
+)
+
+// Notification methods the server sends
+const (
+	MethodToolsListChanged = "notifications/tools/list_changed"
+	// MethodTaskNotification reports a change of a task: created,
+	// assigned, completed, failed and so on
+	MethodTaskNotification = "notifications/skagent/task"
+	// MethodAgentNotification reports a change of an agent
+	MethodAgentNotification = "notifications/skagent/agent"
+)
+
+// Notification is a JSON-RPC 2.0 notification from the server
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// ToolHandler runs a tool registered with RegisterTool
+type ToolHandler func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error)
+
+// listener is a session that receives notifications. ctx carries the
+// principal whose permissions decide what it may see.
+type listener struct {
+	session *Session
+	ctx     context.Context
+	deliver func(*Notification)
+}
+
+// listen registers a session for notifications, delivered by deliver once
+// the session is initialized; the returned function unregisters it
+func (s *Server) listen(ctx context.Context, session *Session, deliver func(*Notification)) func() {
+	l := &listener{session: session, ctx: ctx, deliver: deliver}
+	s.notifyMu.Lock()
+	if s.listeners == nil {
+		s.listeners = make(map[*listener]bool)
+	}
+	s.listeners[l] = true
+	s.notifyMu.Unlock()
+	s.watchOnce.Do(func() {
+		events, cancel := s.agentRegistry.Subscribe(256)
+		go s.watchRegistry(events, cancel)
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.notifyMu.Lock()
+			delete(s.listeners, l)
+			s.notifyMu.Unlock()
+		})
+	}
+}
+
+// broadcast sends a notification to the initialized sessions for which
+// visible, if set, allows it
+func (s *Server) broadcast(n *Notification, visible func(ctx context.Context) bool) {
+	s.notifyMu.Lock()
+	targets := make([]*listener, 0, len(s.listeners))
+	for l := range s.listeners {
+		targets = append(targets, l)
+	}
+	s.notifyMu.Unlock()
+
+	for _, l := range targets {
+		if !l.session.Initialized() || (visible != nil && !visible(l.ctx)) {
+			continue
+		}
+		l.deliver(n)
+	}
+}
+
+// watchRegistry turns registry events into task and agent notifications
+// until the server's context ends
+func (s *Server) watchRegistry(events <-chan agents.Event, cancel func()) {
+	defer cancel()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			s.notifyEvent(e)
+		}
+	}
+}
+
+// notifyEvent sends the notification of a registry event to the sessions
+// allowed to read the agent or task
+func (s *Server) notifyEvent(e agents.Event) {
+	params := map[string]interface{}{
+		"event":     e.Type,
+		"time":      e.Time,
+		"workspace": e.Workspace,
+	}
+	method, perm := MethodAgentNotification, auth.PermAgentsRead
+	if e.TaskID != "" {
+		method, perm = MethodTaskNotification, auth.PermTasksRead
+		params["task_id"] = e.TaskID
+		if task, ok := e.Data["task"].(agents.Task); ok {
+			params["title"] = task.Title
+			params["status"] = task.Status
+			if task.Result != nil && task.Result.Error != "" {
+				params["error"] = task.Result.Error
+			}
+		}
+	}
+	if e.AgentID != "" {
+		params["agent_id"] = e.AgentID
+	}
+	if method == MethodAgentNotification {
+		if agent, ok := e.Data["agent"].(map[string]interface{}); ok {
+			params["name"] = agent["name"]
+			params["status"] = agent["status"]
+		}
+	}
+	s.broadcast(&Notification{JSONRPC: "2.0", Method: method, Params: params}, func(ctx context.Context) bool {
+		return s.permitted(ctx, perm) && auth.CanAccess(ctx, e.Workspace)
+	})
+}
+
+// RegisterTool adds or replaces a tool run by handler and tells connected
+// clients that the tool list changed
+func (s *Server) RegisterTool(def ToolDefinition, handler ToolHandler) {
+	s.mu.Lock()
+	s.tools[def.Name] = def
+	if s.handlers == nil {
+		s.handlers = make(map[string]ToolHandler)
+	}
+	s.handlers[def.Name] = handler
+	s.mu.Unlock()
+	s.notifyToolsChanged()
+}
+
+// UnregisterTool removes a tool added with RegisterTool
+func (s *Server) UnregisterTool(name string) {
+	s.mu.Lock()
+	_, ok := s.handlers[name]
+	if ok {
+		delete(s.tools, name)
+		delete(s.handlers, name)
+	}
+	s.mu.Unlock()
+	if ok {
+		s.notifyToolsChanged()
+	}
+}
+
+// handler returns the handler of a tool added with RegisterTool
+func (s *Server) handler(name string) (ToolHandler, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h, ok := s.handlers[name]
+	return h, ok
+}
+
+// notifyToolsChanged sends notifications/tools/list_changed to the
+// sessions allowed to list tools
+func (s *Server) notifyToolsChanged() {
+	s.broadcast(&Notification{JSONRPC: "2.0", Method: MethodToolsListChanged}, func(ctx context.Context) bool {
+		return s.permitted(ctx, auth.PermToolsRead)
+	})
+}
+
+// notificationMethods lists the custom notifications, for the
+// capabilities of initialize
+func notificationMethods() []string {
+	methods := []string{MethodTaskNotification, MethodAgentNotification, MethodPartialNotification}
+	sort.Strings(methods)
+	return methods
+}
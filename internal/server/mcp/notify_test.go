@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestNotifications(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	registry := agents.NewRegistry(ctx)
+	server := NewServer(ctx, registry, config.MCPConfig{})
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		server.ServeStdio(ctx, inR, outW)
+		outW.Close()
+	}()
+	lines := bufio.NewScanner(outR)
+	send := func(msg string) {
+		t.Helper()
+		if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// next reads messages until one has the method, skipping the others
+	next := func(method string) map[string]interface{} {
+		t.Helper()
+		for lines.Scan() {
+			var msg struct {
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			}
+			if err := json.Unmarshal(lines.Bytes(), &msg); err != nil {
+				t.Fatalf("message %s: %v", lines.Bytes(), err)
+			}
+			if msg.Method == method {
+				return msg.Params
+			}
+		}
+		t.Fatalf("no %s: %v", method, lines.Err())
+		return nil
+	}
+
+	// Changes before initialize are not sent
+	registry.CreateTask(&agents.Task{Title: "early"})
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"host","version":"1.0"}}}`)
+	if !lines.Scan() {
+		t.Fatal("no initialize response")
+	}
+	if body := lines.Text(); !json.Valid([]byte(body)) || !contains(body, `"listChanged":true`, MethodTaskNotification) {
+		t.Fatalf("initialize: %s", body)
+	}
+
+	task := registry.CreateTask(&agents.Task{Title: "build"})
+	params := next(MethodTaskNotification)
+	if params["task_id"] != task.ID || params["event"] != string(agents.EventTaskCreated) || params["title"] != "build" {
+		t.Fatalf("task notification: %v", params)
+	}
+
+	server.RegisterTool(ToolDefinition{Name: "echo", Description: "Echo the arguments"}, func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+		return args, nil
+	})
+	next(MethodToolsListChanged)
+	send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"word":"hi"}}}`)
+	if !lines.Scan() || !contains(lines.Text(), `"id":2`, `hi`) {
+		t.Fatalf("registered tool: %s", lines.Text())
+	}
+	server.UnregisterTool("echo")
+	next(MethodToolsListChanged)
+	inW.Close()
+}
+
+func contains(s string, parts ...string) bool {
+	for _, p := range parts {
+		if !strings.Contains(s, p) {
+			return false
+		}
+	}
+	return true
+}
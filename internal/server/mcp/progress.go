@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// Notifications of a tool call in progress
+const (
+	// MethodProgress reports the progress of a request that carries a
+	// progress token in its _meta
+	MethodProgress = "notifications/progress"
+	// MethodPartialNotification carries output of a tool call ready before
+	// its result, under the same progress token
+	MethodPartialNotification = "notifications/skagent/partial"
+)
+
+// requestMeta is the _meta of a request's params
+type requestMeta struct {
+	ProgressToken json.RawMessage `json:"progressToken,omitempty"`
+}
+
+type notifierKey struct{}
+
+// withNotifier sends the notifications about the requests handled under
+// ctx with send, such as on the event stream of the HTTP response that
+// answers them, instead of the session's
+func withNotifier(ctx context.Context, send func(interface{}) error) context.Context {
+	return context.WithValue(ctx, notifierKey{}, send)
+}
+
+// validToken reports whether a progress token is a string or a number
+func validToken(token json.RawMessage) bool {
+	return len(token) > 0 && string(token) != "null" && validID(token)
+}
+
+// withProgress has the tool run under ctx report its progress to the
+// client as notifications/progress, and its partial output as
+// notifications/skagent/partial, under token. Updates that do not move
+// the progress forward send no notifications/progress, as MCP asks, but
+// their partial output is still sent. Nothing is sent once stop is
+// called, so that no notification follows the response.
+func (s *Server) withProgress(ctx context.Context, session *Session, token json.RawMessage) (_ context.Context, stop func()) {
+	if !validToken(token) {
+		return ctx, func() {}
+	}
+	send, _ := ctx.Value(notifierKey{}).(func(interface{}) error)
+	if send == nil {
+		session.mu.Lock()
+		send = session.send
+		session.mu.Unlock()
+	}
+	if send == nil {
+		return ctx, func() {}
+	}
+
+	var (
+		mu      sync.Mutex
+		stopped bool
+		last    = -1.0
+	)
+	ctx = tools.WithProgress(ctx, func(p tools.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped {
+			return
+		}
+		if p.Progress > last {
+			last = p.Progress
+			params := map[string]interface{}{"progressToken": token, "progress": p.Progress}
+			if p.Total > 0 {
+				params["total"] = p.Total
+			}
+			if p.Message != "" && session.Has(FeatureProgressMessage) {
+				params["message"] = p.Message
+			}
+			// A client that went away only misses updates
+			_ = send(&Notification{JSONRPC: "2.0", Method: MethodProgress, Params: params})
+		}
+		if p.Partial != "" {
+			_ = send(&Notification{JSONRPC: "2.0", Method: MethodPartialNotification, Params: map[string]interface{}{
+				"progressToken": token,
+				"content":       []content{{Type: "text", Text: p.Partial}},
+			}})
+		}
+	})
+	return ctx, func() {
+		mu.Lock()
+		stopped = true
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,228 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// progressServer has a tool that reports two steps, one of which does not
+// move forward, and partial output
+func progressServer(ctx context.Context) *Server {
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	server.initializeTools()
+	server.RegisterTool(ToolDefinition{Name: "scrape", InputSchema: map[string]interface{}{"type": "object"}},
+		func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+			tools.ReportProgress(ctx, tools.Progress{Progress: 1, Total: 2, Message: "page 1", Partial: "first page"})
+			tools.ReportProgress(ctx, tools.Progress{Progress: 1, Partial: "more of page 1"})
+			tools.ReportProgress(ctx, tools.Progress{Progress: 2, Total: 2})
+			return map[string]interface{}{"pages": 2}, nil
+		})
+	return server
+}
+
+func TestProgress(t *testing.T) {
+	ctx := context.Background()
+	server := progressServer(ctx)
+
+	var mu sync.Mutex
+	var sent []*Notification
+	var session Session
+	session.send = func(v interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, v.(*Notification))
+		return nil
+	}
+	server.HandleMessage(ctx, &session, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"host"}}}`))
+
+	body := toJSON(t, server.HandleMessage(ctx, &session, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"scrape"}}`)))
+	if !strings.Contains(body, `\"pages\": 2`) || len(sent) != 0 {
+		t.Fatalf("without a token: %s %+v", body, sent)
+	}
+
+	server.HandleMessage(ctx, &session, []byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"scrape","_meta":{"progressToken":"tok"}}}`))
+	var methods []string
+	for _, n := range sent {
+		methods = append(methods, n.Method)
+	}
+	want := []string{MethodProgress, MethodPartialNotification, MethodPartialNotification, MethodProgress}
+	if strings.Join(methods, " ") != strings.Join(want, " ") {
+		t.Fatalf("notifications: %v", methods)
+	}
+	if got := toJSON(t, sent[0]); !strings.Contains(got, `"progressToken":"tok"`) || !strings.Contains(got, `"total":2`) || !strings.Contains(got, `"message":"page 1"`) {
+		t.Errorf("progress: %s", got)
+	}
+	if got := toJSON(t, sent[2]); !strings.Contains(got, `"text":"more of page 1"`) {
+		t.Errorf("partial: %s", got)
+	}
+}
+
+func TestProgress_StreamableHTTP(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ts := httptest.NewServer(progressServer(ctx).setupRoutes())
+	defer ts.Close()
+
+	post := func(session, body string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(body))
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		req.Header.Set(SessionHeader, session)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		data, _ := io.ReadAll(res.Body)
+		return res, string(data)
+	}
+	res, _ := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"remote"}}}`)
+	session := res.Header.Get(SessionHeader)
+
+	// Without a token the answer stays JSON
+	res, body := post(session, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"scrape"}}`)
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+		t.Fatalf("without a token: %s %s", res.Header.Get("Content-Type"), body)
+	}
+
+	res, body = post(session, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"scrape","_meta":{"progressToken":7}}}`)
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("with a token: %s %s", res.Header.Get("Content-Type"), body)
+	}
+	progress := strings.Index(body, MethodProgress)
+	partial := strings.Index(body, `"text":"first page"`)
+	answer := strings.Index(body, `"id":3`)
+	if progress < 0 || partial < progress || answer < partial || strings.Count(body, "event: message") != 5 {
+		t.Errorf("stream: %s", body)
+	}
+}
+
+func TestCreateTask_Wait(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	registry := agents.NewRegistry(ctx)
+	agent := &agents.Agent{Name: "worker", Status: agents.StatusIdle}
+	registry.RegisterAgent(agent)
+	server := NewServer(ctx, registry, config.MCPConfig{})
+
+	var mu sync.Mutex
+	var messages []string
+	ctx = tools.WithProgress(ctx, func(p tools.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, p.Message)
+	})
+	go func() {
+		// The agent finishes the task it was given
+		for {
+			for _, task := range registry.ListTasks() {
+				if task.Status == agents.TaskStatusInProgress {
+					registry.CompleteTask(task.ID, &agents.TaskResult{Success: true, Output: "built"})
+					return
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	out, err := server.runTool(ctx, "create_task", map[string]interface{}{"agent_id": agent.ID, "task": "build it", "wait": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _ := out["result"].(*agents.TaskResult)
+	if out["status"] != agents.TaskStatusCompleted || result == nil || result.Output != "built" || out["timed_out"] != nil {
+		t.Fatalf("create_task: %+v", out)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(messages) < 2 || !strings.HasPrefix(messages[len(messages)-1], "Completed") {
+		t.Errorf("progress: %q", messages)
+	}
+
+	status, err := server.runTool(ctx, "get_task_status", map[string]interface{}{"task_id": out["task_id"]})
+	if err != nil || status["status"] != agents.TaskStatusCompleted {
+		t.Errorf("get_task_status: %+v %v", status, err)
+	}
+	if _, err := server.runTool(ctx, "get_task_status", map[string]interface{}{"task_id": "missing"}); err == nil {
+		t.Error("found a missing task")
+	}
+}
+
+func TestExecuteAgent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	registry := agents.NewRegistry(ctx)
+	agent := &agents.Agent{Name: "worker", Status: agents.StatusIdle}
+	registry.RegisterAgent(agent)
+	server := NewServer(ctx, registry, config.MCPConfig{})
+	server.initializeTools()
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+	url := ts.URL + "/agents/" + agent.ID + "/execute"
+
+	// Without wait the task is handed to the agent and left running
+	res, err := http.Post(url, "application/json", strings.NewReader(`{"task": "build it"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	json.NewDecoder(res.Body).Decode(&resp)
+	res.Body.Close()
+	result, _ := resp.Result.(map[string]interface{})
+	taskID, _ := result["task_id"].(string)
+	if res.StatusCode != http.StatusAccepted || taskID == "" || result["assigned_to"] != agent.ID {
+		t.Fatalf("execute: %d %+v", res.StatusCode, resp)
+	}
+	if task, ok := registry.GetTask(taskID); !ok || task.Status != agents.TaskStatusInProgress || task.Source != TaskSource {
+		t.Fatalf("task = %+v", task)
+	}
+	registry.CompleteTask(taskID, &agents.TaskResult{Success: true, Output: "built"})
+
+	// With ?wait=true and an event stream the progress comes first
+	go func() {
+		for {
+			for _, task := range registry.ListTasks() {
+				if task.Status == agents.TaskStatusInProgress {
+					registry.CompleteTask(task.ID, &agents.TaskResult{Success: true, Output: "tested"})
+					return
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	req, _ := http.NewRequest(http.MethodPost, url+"?wait=true",
+		strings.NewReader(`{"jsonrpc":"2.0","id":3,"method":"agents/execute","params":{"task":"test it"}}`))
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	stream := string(body)
+	progress := strings.Index(stream, `"method":"notifications/progress"`)
+	answer := strings.Index(stream, `"id":3`)
+	if progress < 0 || answer < progress || !strings.Contains(stream, `"status":"completed"`) || !strings.Contains(stream, "tested") {
+		t.Fatalf("stream:\n%s", stream)
+	}
+
+	res, err = http.Post(url+"?wait=maybe", "application/json", strings.NewReader(`{"task": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid wait: %d", res.StatusCode)
+	}
+}
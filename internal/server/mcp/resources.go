@@ -0,0 +1,251 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/core"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/redact"
+)
+
+// CodeResourceNotFound is the error MCP uses for unknown resource URIs
+const CodeResourceNotFound = -32002
+
+// Resource URIs
+const (
+	sessionsURI = "skagent://sessions/"
+	specsURI    = "skagent://specs/"
+	logsURI     = "skagent://logs/recent"
+)
+
+// recentLogs is how many log entries the logs resource holds
+const recentLogs = 200
+
+// specArtifacts are the SpecKit files exposed as resources
+var specArtifacts = map[string]bool{"spec.md": true, "plan.md": true, "tasks.md": true}
+
+// SessionSource provides the conversations exposed as resources; the
+// engine is one
+type SessionSource interface {
+	SessionSnapshots() []core.Session
+	SessionSnapshot(id string) (core.Session, bool)
+}
+
+// Resource describes a resource in resources/list
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType"`
+}
+
+// ResourceTemplate describes a family of resources in
+// resources/templates/list
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType"`
+}
+
+// resourceContents is one item of a resources/read result
+type resourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// SetSessions exposes the sessions of src as resources. It must be called
+// before the server starts.
+func (s *Server) SetSessions(src SessionSource) {
+	s.sessionSource = src
+}
+
+// SetSpecsDir exposes the spec.md, plan.md and tasks.md files under dir,
+// the SpecKit specs directory, as resources. It must be called before the
+// server starts.
+func (s *Server) SetSpecsDir(dir string) {
+	s.specsDir = dir
+}
+
+// permitted reports, without auditing, whether the caller of ctx may read
+// resources needing perm
+func (s *Server) permitted(ctx context.Context, perm auth.Permission) bool {
+	if s.authz == nil {
+		return true
+	}
+	principal, _ := auth.PrincipalFromContext(ctx)
+	return s.authz.Allowed(principal.Role, perm)
+}
+
+// resourceList lists the resources the caller of ctx may read and v
+// exposes
+func (s *Server) resourceList(ctx context.Context, v *view) []Resource {
+	list := []Resource{}
+	if s.sessionSource != nil && s.permitted(ctx, auth.PermSessionsRead) {
+		sessions := s.sessionSource.SessionSnapshots()
+		sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+		for _, session := range sessions {
+			if !auth.CanAccess(ctx, session.Workspace) {
+				continue
+			}
+			name := session.Metadata.Title
+			if name == "" {
+				name = "Session " + session.ID
+			}
+			list = append(list, Resource{
+				URI:         sessionsURI + session.ID,
+				Name:        name,
+				Description: "Conversation with its messages and metadata",
+				MimeType:    "application/json",
+			})
+		}
+	}
+	if s.specsDir != "" && s.permitted(ctx, auth.PermProjectRead) {
+		for _, rel := range s.specFiles() {
+			list = append(list, Resource{
+				URI:         specsURI + rel,
+				Name:        rel,
+				Description: "SpecKit " + strings.TrimSuffix(path.Base(rel), ".md"),
+				MimeType:    "text/markdown",
+			})
+		}
+	}
+	if s.permitted(ctx, auth.PermSystemRead) {
+		list = append(list, Resource{
+			URI:         logsURI,
+			Name:        "Recent logs",
+			Description: "The most recent log lines of the server",
+			MimeType:    "text/plain",
+		})
+	}
+	visible := list[:0]
+	for _, r := range list {
+		if v.allowsResource(r.URI) {
+			visible = append(visible, r)
+		}
+	}
+	return visible
+}
+
+// specFiles lists the SpecKit artifacts under the specs directory, as
+// slash-separated paths relative to it; the templates are left out
+func (s *Server) specFiles() []string {
+	var files []string
+	filepath.WalkDir(s.specsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == "templates" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && specArtifacts[d.Name()] {
+			if rel, err := filepath.Rel(s.specsDir, p); err == nil {
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// resourceTemplates describes the families of resources v exposes
+func resourceTemplates(v *view) []ResourceTemplate {
+	templates := []ResourceTemplate{}
+	for _, t := range []ResourceTemplate{
+		{URITemplate: sessionsURI + "{id}", Name: "Session", Description: "A conversation by ID", MimeType: "application/json"},
+		{URITemplate: specsURI + "{path}", Name: "SpecKit artifact", Description: "A spec.md, plan.md or tasks.md under the specs directory", MimeType: "text/markdown"},
+	} {
+		if v.allowsResource(t.URITemplate[:strings.Index(t.URITemplate, "{")]) {
+			templates = append(templates, t)
+		}
+	}
+	return templates
+}
+
+// readResource answers resources/read; resources v does not expose are
+// not found
+func (s *Server) readResource(ctx context.Context, v *view, raw json.RawMessage) (interface{}, *Error) {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil || params.URI == "" {
+		return nil, &Error{Code: CodeInvalidParams, Message: "resources/read needs the uri of a resource"}
+	}
+	notFound := &Error{Code: CodeResourceNotFound, Message: "resource not found", Data: map[string]string{"uri": params.URI}}
+	if !v.allowsResource(params.URI) {
+		return nil, notFound
+	}
+
+	var contents resourceContents
+	switch uri := params.URI; {
+	case strings.HasPrefix(uri, sessionsURI) && s.sessionSource != nil:
+		if !s.checkResource(ctx, auth.PermSessionsRead, uri) {
+			return nil, denied(ctx, auth.PermSessionsRead)
+		}
+		session, ok := s.sessionSource.SessionSnapshot(strings.TrimPrefix(uri, sessionsURI))
+		if !ok || !auth.CanAccess(ctx, session.Workspace) {
+			return nil, notFound
+		}
+		text, err := json.MarshalIndent(session, "", "  ")
+		if err != nil {
+			return nil, &Error{Code: CodeInternalError, Message: "encoding the session: " + err.Error()}
+		}
+		contents = resourceContents{URI: uri, MimeType: "application/json", Text: string(text)}
+
+	case strings.HasPrefix(uri, specsURI) && s.specsDir != "":
+		if !s.checkResource(ctx, auth.PermProjectRead, uri) {
+			return nil, denied(ctx, auth.PermProjectRead)
+		}
+		rel := strings.TrimPrefix(uri, specsURI)
+		if !filepath.IsLocal(filepath.FromSlash(rel)) || !specArtifacts[path.Base(rel)] {
+			return nil, notFound
+		}
+		text, err := os.ReadFile(filepath.Join(s.specsDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, notFound
+		}
+		contents = resourceContents{URI: uri, MimeType: "text/markdown", Text: string(text)}
+
+	case uri == logsURI:
+		if !s.checkResource(ctx, auth.PermSystemRead, uri) {
+			return nil, denied(ctx, auth.PermSystemRead)
+		}
+		var b strings.Builder
+		for _, e := range s.logs.Query(logging.Filter{MinLevel: logging.LevelDebug, Limit: recentLogs}) {
+			b.WriteString(e.Timestamp.Format("2006-01-02T15:04:05.000Z07:00"))
+			b.WriteString(" " + e.Level.String() + " [" + e.Component + "] ")
+			b.WriteString(redact.String(e.Message))
+			b.WriteByte('\n')
+		}
+		contents = resourceContents{URI: uri, MimeType: "text/plain", Text: b.String()}
+
+	default:
+		return nil, notFound
+	}
+	return map[string]interface{}{"contents": []resourceContents{contents}}, nil
+}
+
+// checkResource checks, auditing denials, that the caller of ctx may read
+// a resource needing perm
+func (s *Server) checkResource(ctx context.Context, perm auth.Permission, uri string) bool {
+	if s.authz == nil {
+		return true
+	}
+	principal, _ := auth.PrincipalFromContext(ctx)
+	return s.authz.Check(principal, perm, "resource "+uri)
+}
+
+func denied(ctx context.Context, perm auth.Permission) *Error {
+	principal, _ := auth.PrincipalFromContext(ctx)
+	return &Error{Code: CodeInvalidRequest, Message: "role " + string(principal.Role) + " lacks permission " + string(perm)}
+}
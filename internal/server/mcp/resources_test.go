@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/core"
+	"github.com/biodoia/skagent/internal/logging"
+)
+
+type fakeSessions map[string]core.Session
+
+func (f fakeSessions) SessionSnapshots() []core.Session {
+	var out []core.Session
+	for _, s := range f {
+		out = append(out, s)
+	}
+	return out
+}
+
+func (f fakeSessions) SessionSnapshot(id string) (core.Session, bool) {
+	s, ok := f[id]
+	return s, ok
+}
+
+func TestResources(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	server.SetSessions(fakeSessions{"s1": {ID: "s1", CreatedAt: time.Now(), Metadata: core.SessionMeta{Title: "Login flow"}}})
+
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"001-login/spec.md":   "# Login",
+		"001-login/notes.md":  "not exposed",
+		"templates/plan.md":   "template",
+		"002-search/tasks.md": "- [ ] index",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.SetSpecsDir(dir)
+	server.logs = logging.NewRing(10)
+	server.logs.Append(logging.Entry{Timestamp: time.Now(), Level: logging.LevelInfo, Component: "core", Message: "using key sk-or-v1-0123456789abcdef0123456789"})
+
+	var uris []string
+	for _, r := range server.resourceList(ctx, nil) {
+		uris = append(uris, r.URI)
+	}
+	want := "skagent://sessions/s1 skagent://specs/001-login/spec.md skagent://specs/002-search/tasks.md skagent://logs/recent"
+	if got := strings.Join(uris, " "); got != want {
+		t.Fatalf("resources: %s", got)
+	}
+
+	read := func(uri string) (resourceContents, *Error) {
+		t.Helper()
+		params, _ := json.Marshal(map[string]string{"uri": uri})
+		result, rerr := server.readResource(ctx, nil, params)
+		if rerr != nil {
+			return resourceContents{}, rerr
+		}
+		return result.(map[string]interface{})["contents"].([]resourceContents)[0], nil
+	}
+	if c, rerr := read("skagent://sessions/s1"); rerr != nil || c.MimeType != "application/json" || !strings.Contains(c.Text, "Login flow") {
+		t.Fatalf("session: %+v %+v", c, rerr)
+	}
+	if c, rerr := read("skagent://specs/001-login/spec.md"); rerr != nil || c.MimeType != "text/markdown" || c.Text != "# Login" {
+		t.Fatalf("spec: %+v %+v", c, rerr)
+	}
+	if c, rerr := read("skagent://logs/recent"); rerr != nil || !strings.Contains(c.Text, "[REDACTED]") || strings.Contains(c.Text, "sk-or-v1") {
+		t.Fatalf("logs: %+v %+v", c, rerr)
+	}
+	for _, uri := range []string{
+		"skagent://sessions/missing",
+		"skagent://specs/001-login/notes.md",
+		"skagent://specs/../spec.md",
+		"skagent://other",
+	} {
+		if _, rerr := read(uri); rerr == nil || rerr.Code != CodeResourceNotFound {
+			t.Errorf("%s: %+v", uri, rerr)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// MethodCreateMessage asks the client for a completion of its model
+const MethodCreateMessage = "sampling/createMessage"
+
+// samplingTimeout bounds the wait for a completion of the client, which
+// may ask its user to approve the request first
+const samplingTimeout = 5 * time.Minute
+
+// samplingMessage is a message of sampling/createMessage
+type samplingMessage struct {
+	Role    string  `json:"role"`
+	Content content `json:"content"`
+}
+
+// createMessageParams are the parameters of sampling/createMessage
+type createMessageParams struct {
+	Messages       []samplingMessage `json:"messages"`
+	SystemPrompt   string            `json:"systemPrompt,omitempty"`
+	IncludeContext string            `json:"includeContext,omitempty"`
+	MaxTokens      int               `json:"maxTokens"`
+}
+
+// createMessageResult is the client's completion
+type createMessageResult struct {
+	Role       string  `json:"role"`
+	Content    content `json:"content"`
+	Model      string  `json:"model"`
+	StopReason string  `json:"stopReason,omitempty"`
+}
+
+// samplingProvider is an ai.Provider that asks the client of a session
+// for completions
+type samplingProvider struct {
+	session   *Session
+	maxTokens int
+}
+
+// Name implements ai.Provider
+func (p *samplingProvider) Name() string {
+	if client := p.session.Client(); client.Name != "" {
+		return "mcp-sampling/" + client.Name
+	}
+	return "mcp-sampling"
+}
+
+// Complete implements ai.Provider. MCP has no system role, so system
+// messages join the system prompt; the host adds none of its own context.
+func (p *samplingProvider) Complete(ctx context.Context, messages []ai.Message, systemPrompt string) (string, error) {
+	params := createMessageParams{IncludeContext: "none", MaxTokens: p.maxTokens}
+	system := []string{}
+	if systemPrompt != "" {
+		system = append(system, systemPrompt)
+	}
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			system = append(system, m.Content)
+		case "assistant":
+			params.Messages = append(params.Messages, samplingMessage{Role: "assistant", Content: content{Type: "text", Text: m.Content}})
+		default:
+			params.Messages = append(params.Messages, samplingMessage{Role: "user", Content: content{Type: "text", Text: m.Content}})
+		}
+	}
+	params.SystemPrompt = strings.Join(system, "\n\n")
+
+	ctx, cancel := context.WithTimeout(ctx, samplingTimeout)
+	defer cancel()
+	var result createMessageResult
+	if err := p.session.request(ctx, MethodCreateMessage, params, &result); err != nil {
+		return "", fmt.Errorf("sampling: %w", err)
+	}
+	if result.Content.Type != "text" {
+		return "", fmt.Errorf("sampling: the client answered with %q content, not text", result.Content.Type)
+	}
+	ai.RecordFinish(ctx, result.StopReason)
+	return result.Content.Text, nil
+}
+
+// withSampling offers the tools run under ctx the model of the session's
+// client, when it grants sampling and mcp.sampling allows it
+func (s *Server) withSampling(ctx context.Context, session *Session) context.Context {
+	if s.sampling == config.SamplingOff || !session.Supports("sampling") {
+		return ctx
+	}
+	maxTokens := s.samplingMax
+	if maxTokens <= 0 {
+		maxTokens = config.DefaultSamplingMaxTokens
+	}
+	provider := &samplingProvider{session: session, maxTokens: maxTokens}
+	return ai.WithProvider(ctx, provider, s.sampling != config.SamplingFallback)
+}
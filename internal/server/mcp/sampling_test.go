@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestSampling(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name         string
+		mode         string
+		capabilities string
+		own          bool
+		want         string
+	}{
+		{"prefer", "", `{"sampling":{}}`, true, "from host"},
+		{"fallback with a provider", config.SamplingFallback, `{"sampling":{}}`, true, "from skagent"},
+		{"fallback without a provider", config.SamplingFallback, `{"sampling":{}}`, false, "from host"},
+		{"off", config.SamplingOff, `{"sampling":{}}`, false, "no model"},
+		{"not granted", "", `{}`, true, "from skagent"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var own ai.Provider
+			if tc.own {
+				own = ai.NewMockProvider("from skagent")
+			}
+			server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{Sampling: tc.mode, SamplingMaxTokens: 100})
+			server.initializeTools()
+			server.RegisterTool(ToolDefinition{Name: "ask", InputSchema: map[string]interface{}{"type": "object"}},
+				func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+					provider := ai.ProviderFor(ctx, own)
+					if provider == nil {
+						return map[string]interface{}{"answer": "no model"}, nil
+					}
+					answer, err := provider.Complete(ctx, []ai.Message{{Role: "system", Content: "be brief"}, {Role: "user", Content: "hi"}}, "you review code")
+					return map[string]interface{}{"answer": answer, "model": provider.Name()}, err
+				})
+
+			var session Session
+			var sent []Request
+			session.send = func(v interface{}) error {
+				req := v.(*Request)
+				sent = append(sent, *req)
+				// The host answers on its own, as a transport would deliver it
+				go server.HandleMessage(ctx, &session, []byte(`{"jsonrpc":"2.0","id":`+string(req.ID)+
+					`,"result":{"role":"assistant","content":{"type":"text","text":"from host"},"model":"big"}}`))
+				return nil
+			}
+			server.HandleMessage(ctx, &session, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":`+tc.capabilities+`,"clientInfo":{"name":"host"}}}`))
+
+			body := toJSON(t, server.HandleMessage(ctx, &session, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"ask"}}`)))
+			if !strings.Contains(body, tc.want) || strings.Contains(body, `"isError":true`) {
+				t.Fatalf("tools/call: %s", body)
+			}
+			if tc.want != "from host" {
+				if len(sent) != 0 {
+					t.Fatalf("sampling requested: %+v", sent)
+				}
+				return
+			}
+			if !strings.Contains(body, "mcp-sampling/host") {
+				t.Errorf("model: %s", body)
+			}
+			var params createMessageParams
+			if len(sent) != 1 || sent[0].Method != MethodCreateMessage || json.Unmarshal(sent[0].Params, &params) != nil {
+				t.Fatalf("requests: %+v", sent)
+			}
+			if params.MaxTokens != 100 || params.SystemPrompt != "you review code\n\nbe brief" ||
+				len(params.Messages) != 1 || params.Messages[0].Role != "user" || params.Messages[0].Content.Text != "hi" {
+				t.Errorf("params: %+v", params)
+			}
+		})
+	}
+}
+
+func TestSampling_Errors(t *testing.T) {
+	ctx := context.Background()
+	var session Session
+	provider := &samplingProvider{session: &session, maxTokens: 10}
+	if _, err := provider.Complete(ctx, []ai.Message{{Role: "user", Content: "hi"}}, ""); err == nil {
+		t.Fatal("no error without a transport")
+	}
+
+	session.send = func(v interface{}) error {
+		req, ok := v.(*Request)
+		if !ok {
+			return nil
+		}
+		go session.deliver([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"error":{"code":-1,"message":"user rejected sampling request"}}`))
+		return nil
+	}
+	if _, err := provider.Complete(ctx, []ai.Message{{Role: "user", Content: "hi"}}, ""); err == nil || !strings.Contains(err.Error(), "user rejected") {
+		t.Fatalf("rejected request: %v", err)
+	}
+
+	var cancelled []interface{}
+	session.send = func(v interface{}) error {
+		cancelled = append(cancelled, v)
+		return nil
+	}
+	short, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := provider.Complete(short, []ai.Message{{Role: "user", Content: "hi"}}, ""); err == nil {
+		t.Fatal("no error for a cancelled context")
+	}
+	if n, ok := cancelled[len(cancelled)-1].(*Notification); !ok || n.Method != "notifications/cancelled" {
+		t.Errorf("not cancelled: %+v", cancelled)
+	}
+	if session.deliver([]byte(`{"jsonrpc":"2.0","id":"skagent-99","result":{}}`)) {
+		t.Error("delivered a response to no request")
+	}
+}
@@ -0,0 +1,34 @@
+package mcp
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/biodoia/skagent/internal/server/unixsock"
+)
+
+// SetSocket also serves the HTTP transports on a Unix domain socket at
+// path, whose file mode decides which local users may connect. It must be
+// called before Start.
+func (s *Server) SetSocket(path string, mode os.FileMode) {
+	s.socketPath = path
+	s.socketMode = mode
+}
+
+// serveSocket starts serving on the configured socket, if any
+func (s *Server) serveSocket() error {
+	if s.socketPath == "" {
+		return nil
+	}
+	ln, err := unixsock.Listen(s.socketPath, s.socketMode)
+	if err != nil {
+		return err
+	}
+	s.logger.Printf("Starting MCP server on unix://%s (mode %04o)", s.socketPath, s.socketMode)
+	go func() {
+		if err := s.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			s.logger.Printf("ERROR: Socket server error: %v", err)
+		}
+	}()
+	return nil
+}
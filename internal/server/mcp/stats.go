@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/metrics"
+)
+
+// callLogSize bounds the call log
+const callLogSize = 500
+
+var (
+	toolCalls = metrics.Default.NewCounter("skagent_mcp_tool_calls_total",
+		"MCP tool calls, by tool and outcome.", "tool", "outcome")
+	toolDuration = metrics.Default.NewHistogram("skagent_mcp_tool_call_duration_seconds",
+		"Latency of MCP tool calls, by tool.", metrics.DefBuckets, "tool")
+)
+
+// ToolStats sums up the calls of one tool since the server started
+type ToolStats struct {
+	Tool      string    `json:"tool"`
+	Calls     int64     `json:"calls"`
+	Errors    int64     `json:"errors"`
+	ErrorRate float64   `json:"error_rate"`
+	AvgMS     float64   `json:"avg_ms"`
+	MaxMS     float64   `json:"max_ms"`
+	LastCall  time.Time `json:"last_call"`
+	// LastError is the error of the last failed call, kept after later
+	// successes
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// ToolCall is an entry of the call log
+type ToolCall struct {
+	Time time.Time `json:"time"`
+	Tool string    `json:"tool"`
+	// Caller is the name of the API key, when authentication is on
+	Caller     string  `json:"caller,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// toolStats counts the tool calls of one server and keeps the last
+// callLogSize of them
+type toolStats struct {
+	mu     sync.Mutex
+	byTool map[string]*ToolStats
+	total  map[string]time.Duration
+	log    []ToolCall
+}
+
+func newToolStats() *toolStats {
+	return &toolStats{byTool: make(map[string]*ToolStats), total: make(map[string]time.Duration)}
+}
+
+func (st *toolStats) record(call ToolCall, d time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	ts, ok := st.byTool[call.Tool]
+	if !ok {
+		ts = &ToolStats{Tool: call.Tool}
+		st.byTool[call.Tool] = ts
+	}
+	ts.Calls++
+	ts.LastCall = call.Time
+	st.total[call.Tool] += d
+	if call.DurationMS > ts.MaxMS {
+		ts.MaxMS = call.DurationMS
+	}
+	if call.Error != "" {
+		ts.Errors++
+		at := call.Time
+		ts.LastError, ts.LastErrorAt = call.Error, &at
+	}
+
+	if len(st.log) == callLogSize {
+		copy(st.log, st.log[1:])
+		st.log = st.log[:callLogSize-1]
+	}
+	st.log = append(st.log, call)
+}
+
+// snapshot returns the stats of every tool called, the most called first
+func (st *toolStats) snapshot() []ToolStats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	out := make([]ToolStats, 0, len(st.byTool))
+	for name, ts := range st.byTool {
+		s := *ts
+		s.ErrorRate = round(float64(s.Errors) / float64(s.Calls))
+		s.AvgMS = round(float64(st.total[name].Microseconds()) / 1000 / float64(s.Calls))
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Calls != out[j].Calls {
+			return out[i].Calls > out[j].Calls
+		}
+		return out[i].Tool < out[j].Tool
+	})
+	return out
+}
+
+// recent returns up to limit calls of the log, newest first, of one tool
+// or, with tool empty, of all
+func (st *toolStats) recent(tool string, limit int) []ToolCall {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	out := []ToolCall{}
+	for i := len(st.log) - 1; i >= 0 && len(out) < limit; i-- {
+		if tool == "" || st.log[i].Tool == tool {
+			out = append(out, st.log[i])
+		}
+	}
+	return out
+}
+
+// round keeps three decimals
+func round(v float64) float64 {
+	return float64(int64(v*1000+0.5)) / 1000
+}
+
+// executeTool runs a tool and records the call in the stats, the call log
+// and the metrics
+func (s *Server) executeTool(ctx context.Context, toolName string, params map[string]interface{}) (map[string]interface{}, error) {
+	start := time.Now()
+	result, err := s.runTool(ctx, toolName, params)
+	d := time.Since(start)
+
+	call := ToolCall{Time: start, Tool: toolName, DurationMS: round(float64(d.Microseconds()) / 1000)}
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		call.Caller = principal.Name
+	}
+	outcome := "success"
+	if err != nil {
+		call.Error = err.Error()
+		outcome = "error"
+	}
+	s.stats.record(call, d)
+	toolCalls.Inc(toolName, outcome)
+	toolDuration.Observe(d.Seconds(), toolName)
+	return result, err
+}
+
+// handleListCalls serves the call log, newest first; ?tool= keeps the calls
+// of one tool and ?limit= (default 50) bounds the list
+func (s *Server) handleListCalls(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r, auth.PermSystemRead, "tool calls") {
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			s.writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+	if limit > callLogSize {
+		limit = callLogSize
+	}
+
+	calls := s.stats.recent(r.URL.Query().Get("tool"), limit)
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"calls":     calls,
+		"count":     len(calls),
+		"timestamp": time.Now(),
+	})
+}
@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/metrics"
+)
+
+func TestToolStats(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	server.initializeTools()
+
+	var session Session
+	call := func(msg string) {
+		t.Helper()
+		server.HandleMessage(ctx, &session, []byte(msg))
+	}
+	call(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`)
+	call(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"list_agents","arguments":{}}}`)
+	call(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"list_agents","arguments":{}}}`)
+	call(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get_agent","arguments":{"agent_id":"missing"}}}`)
+
+	stats := server.stats.snapshot()
+	if len(stats) != 2 || stats[0].Tool != "list_agents" || stats[0].Calls != 2 || stats[0].Errors != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if got := stats[1]; got.Tool != "get_agent" || got.ErrorRate != 1 || got.LastError != "agent not found" || got.LastErrorAt == nil {
+		t.Errorf("get_agent stats = %+v", got)
+	}
+
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+	res, err := http.Get(ts.URL + "/calls?tool=list_agents&limit=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Calls []ToolCall `json:"calls"`
+	}
+	json.NewDecoder(res.Body).Decode(&body)
+	res.Body.Close()
+	if len(body.Calls) != 1 || body.Calls[0].Tool != "list_agents" {
+		t.Errorf("/calls = %+v", body.Calls)
+	}
+	if res, _ := http.Get(ts.URL + "/calls?limit=0"); res == nil || res.StatusCode != http.StatusBadRequest {
+		t.Errorf("limit=0 should be rejected")
+	}
+
+	res, err = http.Get(ts.URL + "/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info struct {
+		ToolStats []ToolStats `json:"tool_stats"`
+	}
+	json.NewDecoder(res.Body).Decode(&info)
+	res.Body.Close()
+	if len(info.ToolStats) != 2 {
+		t.Errorf("/info tool_stats = %+v", info.ToolStats)
+	}
+
+	var out strings.Builder
+	metrics.Default.WriteText(&out)
+	if !strings.Contains(out.String(), `skagent_mcp_tool_calls_total{tool="get_agent",outcome="error"}`) {
+		t.Errorf("metrics lack the failed call:\n%s", out.String())
+	}
+}
+
+func TestCallLogIsBounded(t *testing.T) {
+	st := newToolStats()
+	for i := 0; i < callLogSize+10; i++ {
+		st.record(ToolCall{Tool: "echo", DurationMS: float64(i)}, 0)
+	}
+	recent := st.recent("", callLogSize*2)
+	if len(recent) != callLogSize || recent[0].DurationMS != callLogSize+9 {
+		t.Errorf("log holds %d calls, newest %v", len(recent), recent[0].DurationMS)
+	}
+	if s := st.snapshot(); s[0].Calls != callLogSize+10 || s[0].MaxMS != callLogSize+9 {
+		t.Errorf("stats = %+v", s[0])
+	}
+}
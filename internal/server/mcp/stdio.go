@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/biodoia/skagent/internal/server/bodylimit"
+	"github.com/google/uuid"
+)
+
+// ServeStdio speaks MCP over a pair of streams, as MCP hosts do with the
+// servers they launch: one JSON-RPC message or batch per line in, one per
+// line out. Requests are handled concurrently; ServeStdio returns once in is
+// closed and every answer is written, or when ctx is done.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.initializeTools()
+
+	limit := s.maxBodySize
+	if limit <= 0 {
+		limit = bodylimit.DefaultLimit
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64<<10), int(limit))
+	next := func() ([]byte, error) {
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				return append([]byte(nil), line...), nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	send := func(data []byte) error {
+		_, err := out.Write(append(data, '\n'))
+		return err
+	}
+
+	s.logger.Printf("Serving MCP over stdio")
+	return s.serveMessages(ctx, TransportStdio, next, send)
+}
+
+// serveMessages speaks MCP on a connection that carries whole messages
+// both ways, as stdio and WebSocket do: next returns each message or
+// batch received, io.EOF after the last, and send writes one. The
+// connection is one session. Requests are handled concurrently;
+// serveMessages returns once next ends and every answer is sent, or when
+// ctx is done.
+func (s *Server) serveMessages(ctx context.Context, transport string, next func() ([]byte, error), send func([]byte) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		session Session
+		writeMu sync.Mutex
+		wg      sync.WaitGroup
+		werr    error
+	)
+	writeErr := func() error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return werr
+	}
+	write := func(v interface{}) {
+		if v == nil {
+			return
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			s.logger.Printf("ERROR: Failed to encode a response: %v", err)
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if werr != nil {
+			return
+		}
+		if err := send(data); err != nil {
+			werr = err
+			cancel()
+		}
+	}
+
+	// Requests of the server, such as sampling, go out like answers
+	session.send = func(v interface{}) error {
+		write(v)
+		return writeErr()
+	}
+	defer s.trackSession(&session, uuid.NewString(), transport, principalName(ctx))()
+
+	// Notifications are queued so that a slow host does not hold up the
+	// server; they are dropped while the queue is full
+	notes := make(chan *Notification, outboxSize)
+	stop := s.listen(ctx, &session, func(n *Notification) {
+		select {
+		case notes <- n:
+		default:
+		}
+	})
+	defer stop()
+	notesDone := make(chan struct{})
+	defer func() {
+		cancel()
+		<-notesDone
+	}()
+	go func() {
+		defer close(notesDone)
+		for {
+			select {
+			case n := <-notes:
+				write(n)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Reads block, so watch ctx apart from them
+	messages := make(chan []byte)
+	var rerr error
+	go func() {
+		defer close(messages)
+		for {
+			msg, err := next()
+			if err != nil {
+				if err != io.EOF {
+					rerr = err
+				}
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var msg []byte
+		var ok bool
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			if err := writeErr(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case msg, ok = <-messages:
+		}
+		if !ok {
+			break
+		}
+
+		// Until the handshake is done messages are handled in order, so
+		// that requests sent right after initialize see the session
+		// initialized and those sent before it do not
+		if method, _ := peek(msg); !session.Initialized() || method == "initialize" {
+			write(s.HandleMessage(ctx, &session, msg))
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			write(s.HandleMessage(ctx, &session, msg))
+		}()
+	}
+
+	wg.Wait()
+	if rerr != nil {
+		return rerr
+	}
+	return writeErr()
+}
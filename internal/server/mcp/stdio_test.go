@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestServeStdio(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("writer", "coder", nil)
+	server := NewServer(ctx, registry, config.MCPConfig{})
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- server.ServeStdio(ctx, inR, outW)
+		outW.Close()
+	}()
+	responses := bufio.NewScanner(outR)
+
+	send := func(msg string) {
+		t.Helper()
+		if _, err := io.WriteString(inW, msg+"\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive := func() Response {
+		t.Helper()
+		if !responses.Scan() {
+			t.Fatalf("no response: %v", responses.Err())
+		}
+		var resp Response
+		if err := json.Unmarshal(responses.Bytes(), &resp); err != nil {
+			t.Fatalf("response %s: %v", responses.Bytes(), err)
+		}
+		return resp
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if resp := receive(); resp.Error == nil || resp.Error.Code != CodeInvalidRequest {
+		t.Fatalf("tools/list before initialize: %+v", resp)
+	}
+
+	send(`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"host","version":"1.0"}}}`)
+	resp := receive()
+	if string(resp.ID) != "2" || resp.Error != nil || !strings.Contains(toJSON(t, resp.Result), `"protocolVersion":"2024-11-05"`) {
+		t.Fatalf("initialize: %+v", resp)
+	}
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	send(`{"jsonrpc":"2.0","id":"list","method":"tools/list"}`)
+	resp = receive()
+	if string(resp.ID) != `"list"` || !strings.Contains(toJSON(t, resp.Result), `"name":"get_agent"`) {
+		t.Fatalf("tools/list: %+v", resp)
+	}
+
+	send(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"get_agent","arguments":{"agent_id":"` + agent.ID + `"}}}`)
+	resp = receive()
+	if body := toJSON(t, resp.Result); !strings.Contains(body, `"isError":false`) || !strings.Contains(body, "writer") {
+		t.Fatalf("tools/call: %s", body)
+	}
+	send(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get_agent","arguments":{}}}`)
+	if body := toJSON(t, receive().Result); !strings.Contains(body, `"isError":true`) {
+		t.Fatalf("failing tool: %s", body)
+	}
+	send(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"missing"}}`)
+	if resp := receive(); resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Fatalf("unknown tool: %+v", resp)
+	}
+	send(`{"jsonrpc":"2.0","id":6,"method":"prompts/list"}`)
+	if resp := receive(); resp.Error == nil || resp.Error.Code != CodeMethodNotFound {
+		t.Fatalf("unknown method: %+v", resp)
+	}
+	send(`{not json`)
+	if resp := receive(); resp.Error == nil || resp.Error.Code != CodeParseError || string(resp.ID) != "null" {
+		t.Fatalf("parse error: %+v", resp)
+	}
+
+	inW.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("ServeStdio: %v", err)
+	}
+}
+
+func toJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,207 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// TaskSource is the source of the tasks created with create_task
+const TaskSource = "mcp"
+
+const (
+	// defaultTaskWait is how long create_task waits for its task by default
+	defaultTaskWait = 5 * time.Minute
+	// maxTaskWait bounds timeout_seconds of create_task
+	maxTaskWait = time.Hour
+	// followPoll is how often a followed task is looked at again, in case
+	// an event was dropped
+	followPoll = time.Second
+)
+
+// taskRequest is a task to give an agent, as create_task and the execute
+// route take it
+type taskRequest struct {
+	AgentID  string
+	Text     string
+	Priority float64
+	Wait     bool
+	Timeout  time.Duration
+}
+
+// parseTaskRequest reads the agent_id, task, priority, wait and
+// timeout_seconds arguments of create_task
+func parseTaskRequest(params map[string]interface{}) (taskRequest, error) {
+	req := taskRequest{Timeout: defaultTaskWait}
+	var ok bool
+	if req.AgentID, ok = params["agent_id"].(string); !ok {
+		return req, fmt.Errorf("agent_id parameter required")
+	}
+	if req.Text, ok = params["task"].(string); !ok || strings.TrimSpace(req.Text) == "" {
+		return req, fmt.Errorf("task parameter required")
+	}
+	req.Priority, _ = params["priority"].(float64)
+	req.Wait, _ = params["wait"].(bool)
+	if v, ok := params["timeout_seconds"].(float64); ok && v > 0 {
+		req.Timeout = time.Duration(v * float64(time.Second))
+	}
+	if req.Timeout > maxTaskWait {
+		req.Timeout = maxTaskWait
+	}
+	return req, nil
+}
+
+// createTask creates a task for an agent and assigns it, leaving it queued
+// when the agent is busy. With wait set it follows the task until it
+// finishes or timeout_seconds pass, reporting each change as progress.
+func (s *Server) createTask(ctx context.Context, params map[string]interface{}) (map[string]interface{}, error) {
+	req, err := parseTaskRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	return s.dispatchTask(ctx, req, "created with create_task")
+}
+
+// dispatchTask creates the task of req in the registry and assigns it to
+// its agent, following it when req asks to wait; reason is recorded as
+// the cause of the task
+func (s *Server) dispatchTask(ctx context.Context, req taskRequest, reason string) (map[string]interface{}, error) {
+	agent, ok := s.visibleAgent(ctx, req.AgentID)
+	if !ok {
+		return nil, fmt.Errorf("failed to create task: %w", agents.ErrAgentNotFound)
+	}
+
+	actor := principalName(ctx)
+	if actor == "" {
+		actor = TaskSource
+	}
+	c := agents.Cause{Actor: actor, Reason: reason}
+	title, _, _ := strings.Cut(strings.TrimSpace(req.Text), "\n")
+	task := s.agentRegistry.CreateTaskBy(&agents.Task{
+		Title:       title,
+		Description: req.Text,
+		Priority:    taskPriority(req.Priority),
+		Workspace:   agent.Workspace,
+		Source:      TaskSource,
+	}, c)
+	var assignErr error
+	if err := s.agentRegistry.AssignTaskBy(task.ID, req.AgentID, c); err != nil {
+		assignErr = err
+	}
+
+	if req.Wait {
+		waitCtx, cancel := context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+		followed, err := s.followTask(waitCtx, task.ID)
+		switch {
+		case err == nil:
+			task = followed
+		case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+			// The wait is over but the task goes on; the client can poll it
+			task = followed
+		default:
+			return nil, err
+		}
+	} else if current, ok := s.agentRegistry.GetTask(task.ID); ok {
+		task = current
+	}
+
+	out := taskStatus(task)
+	out["agent_id"] = req.AgentID
+	out["priority"] = int(req.Priority)
+	if assignErr != nil {
+		out["queued"] = "not assigned yet: " + assignErr.Error()
+	}
+	if req.Wait && !task.Status.Finished() {
+		out["timed_out"] = true
+	}
+	return out, nil
+}
+
+// getTaskStatus returns the state of a task the caller of ctx may see
+func (s *Server) getTaskStatus(ctx context.Context, taskID string) (map[string]interface{}, error) {
+	task, ok := s.agentRegistry.GetTask(taskID)
+	if !ok || !auth.CanAccess(ctx, task.Workspace) {
+		return nil, fmt.Errorf("task not found")
+	}
+	return taskStatus(task), nil
+}
+
+// taskStatus is the state of a task as the task tools return it
+func taskStatus(task *agents.Task) map[string]interface{} {
+	out := map[string]interface{}{
+		"task_id":     task.ID,
+		"task":        task.Title,
+		"status":      task.Status,
+		"assigned_to": task.AssignedTo,
+	}
+	if task.Result != nil {
+		out["result"] = task.Result
+	}
+	return out
+}
+
+// followTask waits until a task finishes or ctx is done, reporting each
+// change of the task as progress, and returns the task as last seen
+func (s *Server) followTask(ctx context.Context, taskID string) (*agents.Task, error) {
+	// Subscribe before the first look so no change is missed in between
+	events, cancel := s.agentRegistry.Subscribe(64)
+	defer cancel()
+	poll := time.NewTicker(followPoll)
+	defer poll.Stop()
+
+	step := 0.0
+	report := func(message string) {
+		step++
+		tools.ReportProgress(ctx, tools.Progress{Progress: step, Message: message})
+	}
+	task, ok := s.agentRegistry.GetTask(taskID)
+	if !ok {
+		return nil, agents.ErrTaskNotFound
+	}
+	report(fmt.Sprintf("Task %s is %s", task.ID, task.Status))
+	for !task.Status.Finished() {
+		select {
+		case <-ctx.Done():
+			return task, ctx.Err()
+		case <-poll.C:
+		case e := <-events:
+			if e.TaskID != taskID {
+				continue
+			}
+			if t, ok := e.Data["task"].(agents.Task); ok {
+				if message := tasklog.StatusMessage(e.Type, &t); message != "" {
+					report(message)
+				}
+			}
+		}
+		if task, ok = s.agentRegistry.GetTask(taskID); !ok {
+			return nil, agents.ErrTaskNotFound
+		}
+	}
+	return task, nil
+}
+
+// taskPriority maps the priority of create_task, 1 to 10, onto the
+// registry's levels; 0 is medium
+func taskPriority(p float64) agents.TaskPriority {
+	switch {
+	case p <= 0:
+		return agents.PriorityMedium
+	case p <= 3:
+		return agents.PriorityLow
+	case p <= 6:
+		return agents.PriorityMedium
+	case p <= 8:
+		return agents.PriorityHigh
+	default:
+		return agents.PriorityUrgent
+	}
+}
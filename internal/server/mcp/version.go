@@ -0,0 +1,114 @@
+package mcp
+
+import "github.com/biodoia/skagent/internal/auth"
+
+// Protocol revisions the server speaks
+const (
+	Protocol20250326 = "2025-03-26"
+	Protocol20241105 = "2024-11-05"
+)
+
+// ProtocolVersion is the newest revision the server speaks, offered to
+// clients that ask for one it does not
+const ProtocolVersion = Protocol20250326
+
+// ProtocolVersions are the revisions the server speaks, newest first
+var ProtocolVersions = []string{Protocol20250326, Protocol20241105}
+
+// Feature is a part of the protocol that came with a revision, offered
+// only to the sessions that negotiated it or a later one
+type Feature string
+
+// Features gated on the negotiated revision
+const (
+	// FeatureBatching accepts JSON-RPC batches
+	FeatureBatching Feature = "batching"
+	// FeatureToolAnnotations describes in tools/list whether a tool only
+	// reads and whether it reaches outside the server
+	FeatureToolAnnotations Feature = "tool_annotations"
+	// FeatureProgressMessage adds a message to notifications/progress
+	FeatureProgressMessage Feature = "progress_message"
+)
+
+// featureSince is the revision that introduced each feature
+var featureSince = map[Feature]string{
+	FeatureBatching:        Protocol20250326,
+	FeatureToolAnnotations: Protocol20250326,
+	FeatureProgressMessage: Protocol20250326,
+}
+
+// negotiateVersion answers the revision a client asked for in initialize:
+// the same one when the server speaks it, otherwise the newest, which the
+// client may refuse by disconnecting
+func negotiateVersion(requested string) string {
+	for _, v := range ProtocolVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return ProtocolVersion
+}
+
+// Has reports whether the session's revision includes feature. A session
+// that has not negotiated one, such as a one-off HTTP call, gets every
+// feature of the newest.
+func (s *Session) Has(feature Feature) bool {
+	s.mu.Lock()
+	version := s.protocolVersion
+	s.mu.Unlock()
+	if version == "" {
+		version = ProtocolVersion
+	}
+	// Revisions are dates, so they sort as strings
+	return version >= featureSince[feature]
+}
+
+// ProtocolVersion returns the revision the session negotiated, or "" before
+// initialize
+func (s *Session) ProtocolVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.protocolVersion
+}
+
+// protocolFeatures lists the revisions the server speaks with the gated
+// features of each, for the capabilities endpoint
+func protocolFeatures() []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(ProtocolVersions))
+	for _, v := range ProtocolVersions {
+		features := []Feature{}
+		for _, f := range []Feature{FeatureBatching, FeatureToolAnnotations, FeatureProgressMessage} {
+			if v >= featureSince[f] {
+				features = append(features, f)
+			}
+		}
+		out = append(out, map[string]interface{}{"version": v, "features": features})
+	}
+	return out
+}
+
+// ToolAnnotations are the hints of MCP 2025-03-26 about a tool's behavior
+type ToolAnnotations struct {
+	ReadOnlyHint    bool  `json:"readOnlyHint"`
+	DestructiveHint *bool `json:"destructiveHint,omitempty"`
+	OpenWorldHint   *bool `json:"openWorldHint,omitempty"`
+}
+
+// builtinAnnotations describes the built-in tools, which act only on the
+// fleet: the read ones change nothing and the others destroy nothing.
+// Registered tools, which may do anything, get none.
+func builtinAnnotations(name string) *ToolAnnotations {
+	perm, ok := toolPermissions[name]
+	if !ok {
+		return nil
+	}
+	no := false
+	a := &ToolAnnotations{OpenWorldHint: &no}
+	switch perm {
+	case auth.PermAgentsRead, auth.PermTasksRead, auth.PermSystemRead, auth.PermProjectRead:
+		a.ReadOnlyHint = true
+	default:
+		a.DestructiveHint = &no
+	}
+	return a
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestVersionNegotiation(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{})
+	server.initializeTools()
+
+	initialize := func(version string) *Session {
+		t.Helper()
+		session := &Session{}
+		resp, ok := server.HandleMessage(ctx, session, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"`+version+`"}}`)).(*Response)
+		if !ok || resp.Error != nil {
+			t.Fatalf("initialize %s: %+v", version, resp)
+		}
+		return session
+	}
+	listTools := func(session *Session) string {
+		t.Helper()
+		resp := server.HandleMessage(ctx, session, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)).(*Response)
+		return toJSON(t, resp.Result)
+	}
+	batch := []byte(`[{"jsonrpc":"2.0","id":3,"method":"ping"}]`)
+
+	for requested, want := range map[string]string{
+		Protocol20241105: Protocol20241105,
+		Protocol20250326: Protocol20250326,
+		"2099-01-01":     ProtocolVersion,
+		"":               ProtocolVersion,
+	} {
+		if got := initialize(requested).ProtocolVersion(); got != want {
+			t.Errorf("asked for %q, negotiated %q; want %q", requested, got, want)
+		}
+	}
+
+	old := initialize(Protocol20241105)
+	if tools := listTools(old); strings.Contains(tools, "annotations") {
+		t.Errorf("2024-11-05 tools carry annotations: %s", tools)
+	}
+	resp, ok := server.HandleMessage(ctx, old, batch).(*Response)
+	if !ok || resp.Error == nil || resp.Error.Code != CodeInvalidRequest || !strings.Contains(resp.Error.Message, Protocol20250326) {
+		t.Fatalf("2024-11-05 batch: %+v", resp)
+	}
+
+	current := initialize(Protocol20250326)
+	tools := listTools(current)
+	if !strings.Contains(tools, `"name":"list_agents","description":"List all available agents","inputSchema":`) ||
+		!strings.Contains(tools, `"annotations":{"readOnlyHint":true,"openWorldHint":false}`) ||
+		!strings.Contains(tools, `"annotations":{"readOnlyHint":false,"destructiveHint":false,"openWorldHint":false}`) {
+		t.Errorf("2025-03-26 tools: %s", tools)
+	}
+	if responses, ok := server.HandleMessage(ctx, current, batch).([]*Response); !ok || len(responses) != 1 || responses[0].Error != nil {
+		t.Fatalf("2025-03-26 batch: %s", toJSON(t, responses))
+	}
+}
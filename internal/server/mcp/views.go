@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// view is the subset of tools and resources a session sees, from
+// mcp.views; a nil view sees everything
+type view config.MCPViewConfig
+
+// viewFor returns the view of a session: the first that matches its
+// client, principal and transport, else the default view
+func (s *Server) viewFor(client, principal, transport string) *view {
+	var fallback *view
+	for i := range s.views {
+		v := (*view)(&s.views[i])
+		if matchAny(v.Clients, client) && matchAny(v.Principals, principal) && matchAny(v.Transports, transport) {
+			return v
+		}
+		if v.Name == s.defaultView {
+			fallback = v
+		}
+	}
+	return fallback
+}
+
+// routeView returns the view of a request to the plain tool and agent
+// routes. They have no session and so no client name: the view is the one
+// matching the request's principal on the http transport.
+func (s *Server) routeView(r *http.Request) *view {
+	return s.viewFor("", principalName(r.Context()), TransportHTTP)
+}
+
+// viewAllows checks that the view of a request to an agent route exposes
+// the tool doing the same, and writes a 404 when it does not: the route
+// does not exist for the caller
+func (s *Server) viewAllows(w http.ResponseWriter, r *http.Request, tool string) bool {
+	if s.routeView(r).allowsTool(tool) {
+		return true
+	}
+	s.writeFailure(w, r, http.StatusNotFound, CodeInvalidParams, "Not found")
+	return false
+}
+
+// allowsTool reports whether the view exposes a tool
+func (v *view) allowsTool(name string) bool {
+	if v == nil {
+		return true
+	}
+	return matchAny(v.Tools, name) && !(len(v.DenyTools) > 0 && matchAny(v.DenyTools, name))
+}
+
+// allowsResource reports whether the view exposes the resource at uri, or
+// the resources of a template whose fixed part uri is
+func (v *view) allowsResource(uri string) bool {
+	if v == nil || len(v.ResourceRoots) == 0 {
+		return true
+	}
+	for _, root := range v.ResourceRoots {
+		if strings.HasPrefix(uri, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the name of the view, empty for none
+func (v *view) name() string {
+	if v == nil {
+		return ""
+	}
+	return v.Name
+}
+
+// matchAny reports whether s matches one of patterns; no patterns match
+// everything
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// View returns the view the session took when it initialized
+func (s *Session) View() string {
+	return s.currentView().name()
+}
+
+func (s *Session) currentView() *view {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.view
+}
@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestViewsRestrictSessions(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{
+		Views: []config.MCPViewConfig{
+			{Name: "ide", Clients: []string{"cursor*"}, Principals: []string{"dev"}},
+			{Name: "chat", Tools: []string{"list_*", "get_*"}, DenyTools: []string{"get_system_config"}, ResourceRoots: []string{"skagent://specs/"}},
+		},
+		DefaultView: "chat",
+	})
+	server.initializeTools()
+	server.SetSpecsDir(t.TempDir())
+
+	open := func(client, principal string) *Session {
+		t.Helper()
+		session := &Session{principal: principal}
+		resp := server.HandleMessage(ctx, session, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"`+client+`"}}}`)).(*Response)
+		if resp.Error != nil {
+			t.Fatalf("initialize: %+v", resp.Error)
+		}
+		return session
+	}
+	call := func(session *Session, method, params string) *Response {
+		t.Helper()
+		return server.HandleMessage(ctx, session, []byte(`{"jsonrpc":"2.0","id":2,"method":"`+method+`","params":`+params+`}`)).(*Response)
+	}
+
+	ide := open("cursor-vscode", "dev")
+	if ide.View() != "ide" {
+		t.Fatalf("view %q", ide.View())
+	}
+	if tools := toJSON(t, call(ide, "tools/list", "{}").Result); !strings.Contains(tools, `"stop_agent"`) {
+		t.Errorf("the ide view hides fleet control: %s", tools)
+	}
+
+	// Claiming the IDE's name without its key gets the default view
+	chat := open("cursor-vscode", "")
+	if chat.View() != "chat" {
+		t.Fatalf("view %q", chat.View())
+	}
+	tools := toJSON(t, call(chat, "tools/list", "{}").Result)
+	if !strings.Contains(tools, `"list_agents"`) || strings.Contains(tools, `"stop_agent"`) || strings.Contains(tools, `"get_system_config"`) {
+		t.Errorf("chat tools: %s", tools)
+	}
+	if resp := call(chat, "tools/call", `{"name":"stop_agent","arguments":{"agent_id":"a"}}`); resp.Error == nil || !strings.Contains(resp.Error.Message, "unknown tool") {
+		t.Errorf("stop_agent from the chat view: %+v", resp)
+	}
+	if templates := toJSON(t, call(chat, "resources/templates/list", "{}").Result); strings.Contains(templates, "sessions") || !strings.Contains(templates, "specs") {
+		t.Errorf("chat templates: %s", templates)
+	}
+	if resp := call(chat, "resources/read", `{"uri":"skagent://logs/recent"}`); resp.Error == nil || resp.Error.Code != CodeResourceNotFound {
+		t.Errorf("logs from the chat view: %+v", resp)
+	}
+
+	// A session cannot initialize again to take another view
+	if resp := call(chat, "initialize", `{"clientInfo":{"name":"cursor"}}`); resp.Error == nil || chat.View() != "chat" {
+		t.Errorf("second initialize: %+v, view %q", resp, chat.View())
+	}
+}
+
+func TestViewsRestrictTheHTTPRoutes(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DefaultConfig()
+	cfg.MCP.EnableAuth = true
+	cfg.Auth.Keys = map[string]config.APIKeyConfig{
+		"dev":  {Token: "dev-token", Role: "admin"},
+		"host": {Token: "host-token", Role: "admin"},
+	}
+	authz, err := auth.New(cfg.MCPAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{
+		Views: []config.MCPViewConfig{
+			{Name: "host", Principals: []string{"host"}, Tools: []string{"get_*"}},
+		},
+	})
+	server.initializeTools()
+	server.SetAuthorizer(authz)
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	do := func(token, method, path, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		raw, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(raw)
+	}
+
+	if _, body := do("host-token", "GET", "/tools", ""); strings.Contains(body, `"start_agent"`) || !strings.Contains(body, `"get_agent"`) {
+		t.Errorf("host tools: %s", body)
+	}
+	if _, body := do("dev-token", "GET", "/tools", ""); !strings.Contains(body, `"start_agent"`) {
+		t.Errorf("dev tools: %s", body)
+	}
+	call := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"agent_id":"a1"}}`
+	for _, path := range []string{"/tools/start_agent/call", "/tools/create_task/call"} {
+		if status, body := do("host-token", "POST", path, call); status != http.StatusNotFound || !strings.Contains(body, "unknown tool") {
+			t.Errorf("host %s: %d %s", path, status, body)
+		}
+	}
+	if status, _ := do("host-token", "GET", "/tools/start_agent", ""); status != http.StatusNotFound {
+		t.Errorf("host reads start_agent: %d", status)
+	}
+	if status, _ := do("host-token", "POST", "/agents/a1/execute", `{"jsonrpc":"2.0","id":1,"params":{"task":"build"}}`); status != http.StatusNotFound {
+		t.Errorf("host executes an agent: %d", status)
+	}
+	if status, _ := do("host-token", "GET", "/agents", ""); status != http.StatusNotFound {
+		t.Errorf("host lists agents outside its view: %d", status)
+	}
+}
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/server/bodylimit"
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketProtocol is the subprotocol of the WebSocket transport, selected
+// when the client offers it
+const WebSocketProtocol = "mcp"
+
+// controlTimeout bounds the writes of pings and close frames
+const controlTimeout = 5 * time.Second
+
+// handleWebSocket serves the WebSocket transport, for clients behind
+// proxies that do not hold event streams open: the connection is a session
+// and each text message carries one JSON-RPC message or batch, both ways,
+// as a line does on stdio. The server pings the connection while it is
+// idle, and the session ends with it.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		s.writeError(w, http.StatusUpgradeRequired, "bad handshake; /ws takes WebSocket connections")
+		return
+	}
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{WebSocketProtocol},
+		CheckOrigin:  s.checkOrigin,
+	}
+	// On failure the upgrader has answered the request
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Printf("WARN: WebSocket upgrade refused: %v", err)
+		return
+	}
+	defer conn.Close()
+	limit := s.maxBodySize
+	if limit <= 0 {
+		limit = bodylimit.DefaultLimit
+	}
+	conn.SetReadLimit(limit)
+
+	// The connection outlives no shutdown: the server's context closes it,
+	// which ends the reads
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
+	go func() {
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(controlTimeout))
+				conn.Close()
+				return
+			case <-keepAlive.C:
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(controlTimeout))
+			}
+		}
+	}()
+
+	read := func() ([]byte, error) {
+		_, data, err := conn.ReadMessage()
+		return data, err
+	}
+	write := func(data []byte) error {
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+	err = s.serveMessages(ctx, TransportWebSocket, read, write)
+	if err != nil && !errors.Is(err, context.Canceled) && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		s.logger.Printf("WebSocket session ended: %v", err)
+	}
+}
+
+// checkOrigin accepts the handshakes of clients that are not browsers,
+// which send no Origin, and of pages served by this host or by an origin
+// of mcp.websocket_origins. Browsers let any page open a WebSocket to any
+// host, so without the check any site could drive a local server.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range s.wsOrigins {
+		if matchAny([]string{allowed}, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketTransport(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{Transports: []string{config.MCPTransportWebSocket}})
+	server.initializeTools()
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{WebSocketProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != WebSocketProtocol {
+		t.Errorf("subprotocol = %q", conn.Subprotocol())
+	}
+	call := func(msg string) Response {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp Response
+		if err := json.Unmarshal(data, &resp); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		return resp
+	}
+
+	if resp := call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","clientInfo":{"name":"behind-proxy"}}}`); resp.Error != nil {
+		t.Fatalf("initialize: %+v", resp.Error)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); err != nil {
+		t.Fatal(err)
+	}
+	resp := call(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	if resp.Error != nil || string(resp.ID) != "2" || !strings.Contains(toJSON(t, resp.Result), `"list_agents"`) {
+		t.Fatalf("tools/list: %+v", resp)
+	}
+	clients := server.ClientSessions()
+	if len(clients) != 1 || clients[0].Transport != TransportWebSocket || clients[0].Client.Name != "behind-proxy" {
+		t.Fatalf("clients = %+v", clients)
+	}
+
+	// Only the configured transports are served
+	for _, path := range []string{"/mcp", "/sse"} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: %d", path, res.StatusCode)
+		}
+	}
+	res, err := http.Get(ts.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("GET /ws without upgrading: %d", res.StatusCode)
+	}
+
+	// The session ends with the connection
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(server.ClientSessions()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(server.ClientSessions()); n != 0 {
+		t.Errorf("%d clients after the connection closed", n)
+	}
+}
+
+func TestWebSocketChecksTheOrigin(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server := NewServer(ctx, agents.NewRegistry(ctx), config.MCPConfig{
+		Transports:       []string{config.MCPTransportWebSocket},
+		WebSocketOrigins: []string{"https://*.example.com"},
+	})
+	server.initializeTools()
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	tests := []struct {
+		origin string
+		ok     bool
+	}{
+		{"", true},
+		{ts.URL, true},
+		{"https://app.example.com", true},
+		{"https://evil.test", false},
+		{"http://app.example.com", false},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.origin != "" {
+			header.Set("Origin", tt.origin)
+		}
+		conn, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("origin %q: %v", tt.origin, err)
+				continue
+			}
+			conn.Close()
+			continue
+		}
+		if err == nil {
+			conn.Close()
+			t.Errorf("origin %q: the upgrade was accepted", tt.origin)
+		} else if res == nil || res.StatusCode != http.StatusForbidden {
+			t.Errorf("origin %q: %v, want 403", tt.origin, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package requestid assigns every HTTP request an ID that is echoed in the
+// X-Request-ID response header, included in request logs and attached to
+// error responses for correlation.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// Header carries the request ID in both directions
+const Header = "X-Request-ID"
+
+// maxLength bounds client-supplied IDs
+const maxLength = 128
+
+// Middleware reuses a well-formed X-Request-ID from the client or
+// generates a new one, sets it on the response and stores it in the
+// request context where chi's request logger picks it up
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = uuid.New().String()
+		}
+
+		w.Header().Set(Header, id)
+		ctx := context.WithValue(r.Context(), middleware.RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext returns the request ID stored by Middleware
+func FromContext(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
+
+// FromResponse returns the request ID already set on a response, which
+// lets error writers without access to the request include it
+func FromResponse(w http.ResponseWriter) string {
+	return w.Header().Get(Header)
+}
+
+// valid accepts IDs made of printable, header-safe characters
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '/', c == '+', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// serve runs a request with the X-Request-ID id, if any, through the
+// middleware and returns the response and the ID the handler saw
+func serve(id string) (*httptest.ResponseRecorder, string) {
+	var seen string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+		if got := FromResponse(w); got != seen {
+			seen = "response header " + got + " differs from the context"
+		}
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	if id != "" {
+		req.Header.Set(Header, id)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec, seen
+}
+
+func TestMiddlewarePropagatesIncomingID(t *testing.T) {
+	rec, seen := serve("client-trace:42")
+	if got := rec.Header().Get(Header); got != "client-trace:42" {
+		t.Errorf("%s = %q, want the client's ID", Header, got)
+	}
+	if seen != "client-trace:42" {
+		t.Errorf("context ID = %q, want the client's ID", seen)
+	}
+}
+
+func TestMiddlewareGeneratesMissingOrInvalidID(t *testing.T) {
+	for _, id := range []string{"", "has spaces", "bad\"quote", strings.Repeat("a", maxLength+1)} {
+		rec, seen := serve(id)
+		got := rec.Header().Get(Header)
+		if _, err := uuid.Parse(got); err != nil {
+			t.Errorf("incoming %q: %s = %q, want a generated UUID", id, Header, got)
+		}
+		if seen != got {
+			t.Errorf("incoming %q: context ID = %q, want %q", id, seen, got)
+		}
+	}
+	first, _ := serve("")
+	second, _ := serve("")
+	if first.Header().Get(Header) == second.Header().Get(Header) {
+		t.Error("two requests got the same generated ID")
+	}
+}
@@ -6,10 +6,33 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/artifacts"
+	"github.com/biodoia/skagent/internal/audit"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/constitution"
 	"github.com/biodoia/skagent/internal/core"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/digest"
+	"github.com/biodoia/skagent/internal/mcpclient"
+	"github.com/biodoia/skagent/internal/evaluation"
+	"github.com/biodoia/skagent/internal/lessons"
+	"github.com/biodoia/skagent/internal/modelpolicy"
+	"github.com/biodoia/skagent/internal/project"
+	"github.com/biodoia/skagent/internal/pullrequest"
+	"github.com/biodoia/skagent/internal/snapshot"
+	"github.com/biodoia/skagent/internal/review"
+	"github.com/biodoia/skagent/internal/server/bodylimit"
+	"github.com/biodoia/skagent/internal/server/requestid"
+	"github.com/biodoia/skagent/internal/shutdown"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/webhooks"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -22,29 +45,65 @@ type APIServer struct {
 	ctx         context.Context
 	server      *http.Server
 	logger      *log.Logger
+	logs        *logging.Ring
+	authz       *auth.Authorizer
+	shutdown    *shutdown.Coordinator
+	closing     chan struct{}
+	closeOnce   sync.Once
+	tlsConfig   config.TLSConfig
+	socketPath  string
+	socketMode  os.FileMode
+	cors        *corsPolicy
+	idempotency *idempotencyStore
+	startedAt   time.Time
+	requests    *requestStats
+	artifacts   *artifacts.LocalStore
+	webhooks    *webhooks.Manager
+	callbacks   *webhooks.CallbackDispatcher
+	rateLimit   *rateLimiter
+	reloader    ConfigReloader
+	modelPolicy *modelpolicy.Policy
+	lessons     *lessons.Store
+	audit       *audit.Log
+	constitution *constitution.Checker
+	maxBodySize int64
+	pullRequests *pullrequest.Workflow
+	snapshots   *snapshot.Manager
+	review      *review.Pipeline
+	evaluator   *evaluation.Evaluator
+	digest      *digest.Digest
+	mcpClients  *mcpclient.Manager
+	taskLog     *tasklog.Store
+	// Server timeouts, in nanoseconds; the request timeout follows the
+	// write timeout
+	readTimeout  atomic.Int64
+	writeTimeout atomic.Int64
 }
 
 type APIResponse struct {
 	Success bool                   `json:"success"`
 	Data    map[string]interface{} `json:"data,omitempty"`
-	Error   string                 `json:"error,omitempty"`
+	Error   *APIError              `json:"error,omitempty"`
 	Message string                 `json:"message,omitempty"`
 	Timestamp time.Time            `json:"timestamp"`
 }
 
 type AgentRequest struct {
-	Name        string                 `json:"name"`
-	Type        string                 `json:"type"`
+	Name        string                 `json:"name" validate:"required,max=100"`
+	Type        string                 `json:"type" validate:"required,oneof=coder reviewer planner documenter tester general"`
 	Config      map[string]interface{} `json:"config,omitempty"`
 	AgentID     string                 `json:"agent_id,omitempty"`
 }
 
 type TaskRequest struct {
 	AgentID     string                 `json:"agent_id"`
-	Task        string                 `json:"task"`
-	Priority    int                    `json:"priority"`
+	Task        string                 `json:"task" validate:"required,max=10000"`
+	Priority    int                    `json:"priority" validate:"min=0,max=3"`
 	Parameters  map[string]interface{} `json:"parameters,omitempty"`
-	CallbackURL string                 `json:"callback_url,omitempty"`
+	CallbackURL string                 `json:"callback_url,omitempty" validate:"url"`
+	AcceptanceCriteria []string        `json:"acceptance_criteria,omitempty"`
+	// Retry overrides the configured retry policy for the task
+	Retry       *config.RetryPolicy    `json:"retry,omitempty"`
 }
 
 type SystemRequest struct {
@@ -53,41 +112,92 @@ type SystemRequest struct {
 }
 
 func NewServer(ctx context.Context, port int, host string, engine *core.Engine, registry *agents.Registry) *APIServer {
-	return &APIServer{
+	s := &APIServer{
 		port:         port,
 		host:         host,
 		engine:       engine,
 		agentRegistry: registry,
 		ctx:          ctx,
-		logger:       log.New(log.Writer(), "[API] ", log.LstdFlags|log.Lmsgprefix),
-	}
+		logger:       logging.New("api", "[API] ", log.Writer()),
+		logs:         logging.Default(),
+		closing:      make(chan struct{}),
+		cors:         newCORSPolicy(true, config.CORSConfig{AllowedOrigins: []string{"*"}}),
+		idempotency:  newIdempotencyStore(DefaultIdempotencyTTL),
+		startedAt:    time.Now(),
+		requests:     newRequestStats(),
+		rateLimit:    newRateLimiter(),
+	}
+	s.SetTimeouts(DefaultTimeout, DefaultTimeout)
+	return s
 }
 
 func (s *APIServer) Start() error {
 	router := s.setupRoutes()
+	s.startedAt = time.Now()
 	
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", s.host, s.port),
 		Handler:      router,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		ReadTimeout:  time.Duration(s.readTimeout.Load()),
+		WriteTimeout: time.Duration(s.writeTimeout.Load()),
 		IdleTimeout:  60 * time.Second,
 	}
+	if err := s.serveSocket(); err != nil {
+		return err
+	}
+	
+	if !s.tlsConfig.Enabled() {
+		s.logger.Printf("Starting API server on http://%s:%d", s.host, s.port)
+		go func() {
+			if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				s.logger.Printf("ERROR: Server error: %v", err)
+			}
+		}()
+		return nil
+	}
 	
-	s.logger.Printf("Starting API server on %s:%d", s.host, s.port)
+	tlsConfig, err := buildTLSConfig(s.tlsConfig)
+	if err != nil {
+		return err
+	}
+	s.server.TLSConfig = tlsConfig
 	
+	s.logger.Printf("Starting API server on https://%s:%d (client certificates: %s)", s.host, s.port, tlsConfig.ClientAuth)
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			s.logger.Printf("Server error: %v", err)
+		if err := s.server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			s.logger.Printf("ERROR: Server error: %v", err)
 		}
 	}()
 	
 	return nil
 }
 
+// Handler returns the API routes with their middleware, without starting
+// a listener
+func (s *APIServer) Handler() http.Handler {
+	return s.setupRoutes()
+}
+
 func (s *APIServer) Stop() error {
-	if s.server != nil {
-		return s.server.Shutdown(s.ctx)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.Shutdown(ctx, false)
+}
+
+// Shutdown stops the server. A graceful shutdown ends open streams and waits
+// for active requests until ctx is done; a forced one closes every
+// connection immediately.
+func (s *APIServer) Shutdown(ctx context.Context, force bool) error {
+	s.closeOnce.Do(func() { close(s.closing) })
+	if s.server == nil {
+		return nil
+	}
+	if force {
+		return s.server.Close()
+	}
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+		return err
 	}
 	return nil
 }
@@ -108,80 +218,207 @@ func (s *APIServer) setupRoutes() http.Handler {
 	router := chi.NewRouter()
 	
 	// Middleware
-	router.Use(middleware.Logger)
+	router.Use(requestid.Middleware)
+	router.Use(s.metricsMiddleware)
+	router.Use(middleware.RequestLogger(&middleware.DefaultLogFormatter{Logger: s.logger, NoColor: true}))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.Compress(5))
-	router.Use(middleware.Timeout(30 * time.Second))
-	
-	// CORS headers
-	router.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			
-			next.ServeHTTP(w, r)
-		})
+	router.Use(bodylimit.Middleware(s.maxBodySize, func(w http.ResponseWriter, status int, msg string) {
+		s.writeErrorCode(w, status, CodeUnsupportedMediaType, msg)
+	}))
+	router.Use(s.timeoutMiddleware)
+	router.Use(s.corsMiddleware)
+	router.Use(prettyMiddleware)
+	
+	// Unknown routes get the same error envelope as handler errors
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		s.writeErrorCode(w, http.StatusNotFound, CodeNotFound, "no route for "+r.Method+" "+r.URL.Path)
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		s.writeErrorCode(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
 	})
 	
 	// Routes
 	router.Get("/", s.handleRoot)
 	router.Get("/health", s.handleHealth)
+	router.Get("/healthz", s.handleHealthz)
+	router.Get("/readyz", s.handleReadyz)
 	router.Get("/status", s.handleStatus)
+	router.With(s.authMiddleware, s.require(auth.PermSystemRead)).Get("/metrics", s.handleMetrics)
+	
+	// Versioned API
+	router.Route("/api/v1", func(r chi.Router) {
+		r.Use(s.versionMiddleware(APIVersion1))
+		r.Get("/", s.handleRoot)
+		r.Get("/health", s.handleHealth)
+		r.Get("/healthz", s.handleHealthz)
+		r.Get("/readyz", s.handleReadyz)
+		r.Get("/status", s.handleStatus)
+		// GitHub signs its deliveries instead of sending an API key
+		r.Post("/github/webhook", s.handleGitHubWebhook)
+		r.Group(func(r chi.Router) {
+			r.Use(s.authMiddleware)
+			r.Use(s.auditMiddleware)
+			r.Use(s.rateLimitMiddleware)
+			r.Use(s.idempotencyMiddleware)
+			r.Use(s.workspaceMiddleware)
+			s.mountResourceRoutes(r)
+		})
+	})
 	
+	// Legacy unversioned prefixes, kept until the sunset date
+	router.Group(func(r chi.Router) {
+		r.Use(deprecatedMiddleware("/api/v1"))
+		r.Use(s.versionMiddleware(APIVersion1))
+		r.Use(s.authMiddleware)
+		r.Use(s.auditMiddleware)
+		r.Use(s.rateLimitMiddleware)
+		r.Use(s.idempotencyMiddleware)
+		r.Use(s.workspaceMiddleware)
+		s.mountResourceRoutes(r)
+	})
+	
+	return router
+}
+
+// mountResourceRoutes registers the resource routes shared by /api/v1 and
+// the legacy unversioned prefixes
+func (s *APIServer) mountResourceRoutes(router chi.Router) {
 	// Agent routes
 	router.Route("/agents", func(r chi.Router) {
-		r.Get("/", s.handleListAgents)
-		r.Post("/", s.handleCreateAgent)
-		r.Get("/{agentID}", s.handleGetAgent)
-		r.Put("/{agentID}", s.handleUpdateAgent)
-		r.Delete("/{agentID}", s.handleDeleteAgent)
-		r.Post("/{agentID}/start", s.handleStartAgent)
-		r.Post("/{agentID}/stop", s.handleStopAgent)
-		r.Get("/{agentID}/tasks", s.handleGetAgentTasks)
+		r.With(s.require(auth.PermAgentsRead)).Get("/", s.handleListAgents)
+		r.With(s.require(auth.PermAgentsWrite)).Post("/", s.handleCreateAgent)
+		r.With(s.require(auth.PermAgentsWrite)).Post("/bulk", s.handleBulkAgents)
+		r.With(s.require(auth.PermAgentsRead), s.owned).Get("/{agentID}", s.handleGetAgent)
+		r.With(s.require(auth.PermAgentsWrite), s.owned).Put("/{agentID}", s.handleUpdateAgent)
+		r.With(s.require(auth.PermAgentsWrite), s.owned).Delete("/{agentID}", s.handleDeleteAgent)
+		r.With(s.require(auth.PermAgentsControl), s.owned).Post("/{agentID}/start", s.handleStartAgent)
+		r.With(s.require(auth.PermAgentsControl), s.owned).Post("/{agentID}/stop", s.handleStopAgent)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{agentID}/tasks", s.handleGetAgentTasks)
+		r.With(s.require(auth.PermAgentsRead), s.owned).Get("/{agentID}/stream", s.handleAgentStream)
+		r.With(s.require(auth.PermAgentsRead), s.owned).Get("/{agentID}/notes", s.handleGetAgentNotes)
+		r.With(s.require(auth.PermAgentsWrite), s.owned).Put("/{agentID}/notes", s.handleSetAgentNotes)
+		r.With(s.require(auth.PermAgentsRead), s.owned).Get("/{agentID}/lessons", s.handleListLessons)
+		r.With(s.require(auth.PermAgentsWrite), s.owned).Post("/{agentID}/lessons", s.handleCreateLesson)
+		r.With(s.require(auth.PermAgentsWrite), s.owned).Delete("/{agentID}/lessons/{lessonID}", s.handleDeleteLesson)
 	})
 	
 	// Task routes
 	router.Route("/tasks", func(r chi.Router) {
-		r.Get("/", s.handleListTasks)
-		r.Post("/", s.handleCreateTask)
-		r.Get("/{taskID}", s.handleGetTask)
-		r.Put("/{taskID}", s.handleUpdateTask)
-		r.Delete("/{taskID}", s.handleCancelTask)
+		r.With(s.require(auth.PermTasksRead)).Get("/", s.handleListTasks)
+		r.With(s.require(auth.PermTasksWrite)).Post("/", s.handleCreateTask)
+		r.With(s.require(auth.PermTasksWrite)).Post("/bulk", s.handleBulkTasks)
+		r.With(s.require(auth.PermTasksRead)).Get("/templates", s.handleListTemplates)
+		r.With(s.require(auth.PermTasksWrite)).Post("/from-template", s.handleCreateFromTemplate)
+		r.With(s.require(auth.PermTasksRead)).Get("/transitions", s.handleListTransitions)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{taskID}", s.handleGetTask)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{taskID}/history", s.handleTaskHistory)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{taskID}/routing", s.handleTaskRouting)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{taskID}/wait", s.handleWaitTask)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{taskID}/log", s.handleGetTaskLog)
+		r.With(s.require(auth.PermTasksWrite), s.owned).Post("/{taskID}/log", s.handleAppendTaskLog)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{taskID}/model", s.handleTaskModel)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{taskID}/lessons", s.handleTaskLessons)
+		r.With(s.require(auth.PermToolsExecute), s.owned).Post("/{taskID}/pull-request", s.handleOpenPullRequest)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{taskID}/snapshot", s.handleGetSnapshot)
+		r.With(s.require(auth.PermToolsExecute), s.owned).Post("/{taskID}/snapshot", s.handleTakeSnapshot)
+		r.With(s.require(auth.PermToolsExecute), s.owned).Post("/{taskID}/rollback", s.handleRollbackTask)
+		r.With(s.require(auth.PermTasksWrite), s.owned).Post("/{taskID}/evaluate", s.handleEvaluateTask)
+		r.With(s.require(auth.PermTasksWrite), s.owned).Post("/{taskID}/revise", s.handleReviseTask)
+		r.With(s.require(auth.PermTasksWrite), s.owned).Put("/{taskID}", s.handleUpdateTask)
+		r.With(s.require(auth.PermTasksWrite), s.owned).Delete("/{taskID}", s.handleCancelTask)
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{taskID}/artifacts", s.handleListTaskArtifacts)
+		r.With(s.require(auth.PermTasksWrite), s.owned).Post("/{taskID}/artifacts", s.handleUploadArtifact)
+	})
+	
+	// Workspace routes
+	router.Route("/workspaces", func(r chi.Router) {
+		r.With(s.require(auth.PermWorkspacesRead)).Get("/", s.handleListWorkspaces)
+		r.With(s.require(auth.PermWorkspacesWrite)).Post("/", s.handleCreateWorkspace)
+		r.With(s.require(auth.PermWorkspacesRead)).Get("/{workspace}", s.handleGetWorkspace)
+		r.With(s.require(auth.PermWorkspacesWrite)).Delete("/{workspace}", s.handleDeleteWorkspace)
+	})
+	
+	router.Route("/constitution", func(r chi.Router) {
+		r.With(s.require(auth.PermTasksRead)).Get("/", s.handleGetConstitution)
+		r.With(s.require(auth.PermTasksRead)).Post("/check", s.handleCheckConstitution)
+	})
+	
+	// Artifact routes
+	router.Route("/artifacts", func(r chi.Router) {
+		r.With(s.require(auth.PermTasksRead), s.owned).Get("/{artifactID}", s.handleGetArtifact)
+	})
+	
+	// Webhook routes
+	router.Route("/webhooks", func(r chi.Router) {
+		r.With(s.require(auth.PermWebhooksRead)).Get("/", s.handleListWebhooks)
+		r.With(s.require(auth.PermWebhooksWrite)).Post("/", s.handleCreateWebhook)
+		r.With(s.require(auth.PermWebhooksRead)).Get("/{webhookID}", s.handleGetWebhook)
+		r.With(s.require(auth.PermWebhooksWrite)).Put("/{webhookID}", s.handleUpdateWebhook)
+		r.With(s.require(auth.PermWebhooksWrite)).Delete("/{webhookID}", s.handleDeleteWebhook)
+		r.With(s.require(auth.PermWebhooksRead)).Get("/{webhookID}/deliveries", s.handleListWebhookDeliveries)
+		r.With(s.require(auth.PermWebhooksWrite)).Post("/{webhookID}/ping", s.handlePingWebhook)
+	})
+	
+	// Session routes
+	router.Route("/sessions", func(r chi.Router) {
+		r.With(s.require(auth.PermSessionsRead)).Get("/", s.handleListSessions)
+		r.With(s.require(auth.PermSessionsWrite)).Post("/", s.handleCreateSession)
+		r.With(s.require(auth.PermSessionsRead), s.owned).Get("/{sessionID}", s.handleGetSession)
+		r.With(s.require(auth.PermSessionsWrite), s.owned).Put("/{sessionID}", s.handleUpdateSession)
+		r.With(s.require(auth.PermSessionsWrite), s.owned).Delete("/{sessionID}", s.handleDeleteSession)
+		r.With(s.require(auth.PermSessionsRead), s.owned).Get("/{sessionID}/messages", s.handleListSessionMessages)
+		r.With(s.require(auth.PermSessionsWrite), s.owned).Post("/{sessionID}/messages", s.handlePostSessionMessage)
+		r.With(s.require(auth.PermSessionsWrite), s.owned).Post("/{sessionID}/chat/stream", s.handleStreamSessionMessage)
+		r.With(s.require(auth.PermSessionsRead), s.require(auth.PermTasksWrite), s.owned).Post("/{sessionID}/task-from-session", s.handleTaskFromSession)
 	})
 	
 	// Project manager routes
 	router.Route("/project", func(r chi.Router) {
-		r.Get("/tasks", s.handleListProjectTasks)
-		r.Get("/tasks/{taskID}", s.handleGetProjectTask)
-		r.Post("/tasks/{taskID}/assign", s.handleAssignProjectTask)
-		r.Get("/agents", s.handleListProjectAgents)
-		r.Get("/status", s.handleGetProjectStatus)
-		r.Post("/webhook", s.handleProjectWebhook)
+		r.With(s.require(auth.PermProjectRead)).Get("/tasks", s.handleListProjectTasks)
+		r.With(s.require(auth.PermProjectRead)).Get("/tasks/{taskID}", s.handleGetProjectTask)
+		r.With(s.require(auth.PermProjectWrite)).Post("/tasks/{taskID}/assign", s.handleAssignProjectTask)
+		r.With(s.require(auth.PermProjectRead)).Get("/agents", s.handleListProjectAgents)
+		r.With(s.require(auth.PermProjectRead)).Get("/status", s.handleGetProjectStatus)
+		r.With(s.require(auth.PermProjectRead)).Get("/reconciliation", s.handleGetReconciliation)
+		r.With(s.require(auth.PermProjectWrite)).Post("/reconcile", s.handleReconcile)
+		r.With(s.require(auth.PermProjectWrite)).Post("/webhook", s.handleProjectWebhook)
 	})
 	
 	// Tool routes
 	router.Route("/tools", func(r chi.Router) {
-		r.Get("/", s.handleListTools)
-		r.Get("/{toolName}", s.handleGetTool)
-		r.Post("/{toolName}/execute", s.handleExecuteTool)
+		r.With(s.require(auth.PermToolsRead)).Get("/", s.handleListTools)
+		r.With(s.require(auth.PermToolsRead)).Get("/{toolName}", s.handleGetTool)
+		r.With(s.require(auth.PermToolsExecute)).Post("/{toolName}/execute", s.handleExecuteTool)
+	})
+	
+	// Theme routes
+	router.Route("/themes", func(r chi.Router) {
+		r.With(s.require(auth.PermSystemRead)).Get("/", s.handleListThemes)
+		r.With(s.require(auth.PermSystemRead)).Post("/validate", s.handleValidateTheme)
+		r.With(s.require(auth.PermSystemRead)).Get("/{themeName}", s.handleGetTheme)
+	})
+	
+	// Reports
+	router.Route("/reports", func(r chi.Router) {
+		r.With(s.require(auth.PermSystemRead)).Get("/daily", s.handleDailyReport)
 	})
 	
 	// System routes
 	router.Route("/system", func(r chi.Router) {
-		r.Get("/config", s.handleGetConfig)
-		r.Post("/config", s.handleUpdateConfig)
-		r.Get("/stats", s.handleGetStats)
-		r.Post("/shutdown", s.handleShutdown)
-		r.Get("/logs", s.handleGetLogs)
+		r.With(s.require(auth.PermSystemRead)).Get("/config", s.handleGetConfig)
+		r.With(s.require(auth.PermSystemAdmin)).Post("/config", s.handleUpdateConfig)
+		r.With(s.require(auth.PermSystemAdmin)).Post("/config/reload", s.handleReloadConfig)
+		r.With(s.require(auth.PermSystemRead)).Get("/stats", s.handleGetStats)
+		r.With(s.require(auth.PermSystemAdmin)).Post("/shutdown", s.handleShutdown)
+		r.With(s.require(auth.PermSystemRead)).Get("/logs", s.handleGetLogs)
+		r.With(s.require(auth.PermSystemAdmin)).Get("/audit", s.handleListAudit)
+		r.With(s.require(auth.PermSystemRead)).Get("/callbacks/dead-letters", s.handleListDeadLetters)
+		r.With(s.require(auth.PermSystemRead)).Get("/dry-run", s.handleDryRunActions)
+		r.With(s.require(auth.PermSystemRead)).Get("/mcp-servers", s.handleListMCPServers)
+		r.With(s.require(auth.PermSystemAdmin)).Post("/mcp-servers/{name}/enable", s.handleEnableMCPServer)
+		r.With(s.require(auth.PermSystemAdmin)).Post("/mcp-servers/{name}/disable", s.handleDisableMCPServer)
 	})
-	
-	return router
 }
 
 func (s *APIServer) handleRoot(w http.ResponseWriter, r *http.Request) {
@@ -191,12 +428,14 @@ func (s *APIServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 			"name":        "SKAgent API",
 			"version":     "2.0.0",
 			"description": "Advanced AI Agent Framework API",
+			"api_version": CurrentAPIVersion.String(),
+			"versions":    SupportedAPIVersions,
 			"endpoints": map[string]interface{}{
-				"agents":  "/agents - Agent management",
-				"tasks":   "/tasks - Task management",
-				"tools":   "/tools - Tool execution",
-				"system":  "/system - System configuration",
-				"project": "/project - Project Manager integration",
+				"agents":  "/api/v1/agents - Agent management",
+				"tasks":   "/api/v1/tasks - Task management",
+				"tools":   "/api/v1/tools - Tool execution",
+				"system":  "/api/v1/system - System configuration",
+				"project": "/api/v1/project - Project Manager integration",
 			},
 		},
 		Timestamp: time.Now(),
@@ -231,6 +470,7 @@ func (s *APIServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 			"server":   s.GetStatus(),
 			"engine":   s.engine.GetStatus(),
 			"agents":   s.agentRegistry.GetStats(),
+			"backpressure": s.backpressure(),
 			"timestamp": time.Now(),
 		},
 		Timestamp: time.Now(),
@@ -239,38 +479,48 @@ func (s *APIServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// handleListAgents lists agents, whole or shaped by ?fields= and ?expand=
 func (s *APIServer) handleListAgents(w http.ResponseWriter, r *http.Request) {
-	agents := s.agentRegistry.ListAgents()
-	
-	response := APIResponse{
-		Success: true,
-		Data: map[string]interface{}{
-			"agents": agents,
-			"count":  len(agents),
-		},
-		Timestamp: time.Now(),
+	sh, details := agentShape.parse(r)
+	if len(details) > 0 {
+		s.writeShapeError(w, details)
+		return
 	}
-	
-	s.writeJSON(w, http.StatusOK, response)
+	agents := s.workspaceAgents(r)
+	if sh == nil {
+		writeList(s, w, http.StatusOK, "agents", agents, map[string]interface{}{"count": len(agents)})
+		return
+	}
+	shaped, err := shapeList(sh, agents, s.agentExtras)
+	if err != nil {
+		s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, "shaping agents: "+err.Error())
+		return
+	}
+	writeList(s, w, http.StatusOK, "agents", shaped, map[string]interface{}{"count": len(shaped)})
 }
 
 func (s *APIServer) handleCreateAgent(w http.ResponseWriter, r *http.Request) {
 	var req AgentRequest
 	if err := s.parseJSON(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
+		s.writeDecodeError(w, err)
+		return
+	}
+	
+	if !s.validRequest(w, &req, "invalid agent") {
 		return
 	}
 	
-	agent, err := s.agentRegistry.CreateAgent(req.Name, req.Type, req.Config)
+	agent, err := s.agentRegistry.CreateAgentIn(requestWorkspace(r), req.Name, req.Type, req.Config)
 	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		s.writeRegistryError(w, err)
 		return
 	}
 	
+	w.Header().Set("ETag", agentETag(agent.Version))
 	response := APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"agent": agent,
+			"agent": agent.View(),
 		},
 		Message: "Agent created successfully",
 		Timestamp: time.Now(),
@@ -281,17 +531,36 @@ func (s *APIServer) handleCreateAgent(w http.ResponseWriter, r *http.Request) {
 
 func (s *APIServer) handleGetAgent(w http.ResponseWriter, r *http.Request) {
 	agentID := chi.URLParam(r, "agentID")
+	sh, details := agentShape.parse(r)
+	if len(details) > 0 {
+		s.writeShapeError(w, details)
+		return
+	}
 	
-	agent, ok := s.agentRegistry.GetAgent(agentID)
+	agent, ok := s.agentRegistry.GetAgentView(agentID)
 	if !ok {
-		s.writeError(w, http.StatusNotFound, "Agent not found")
+		s.writeErrorCode(w, http.StatusNotFound, CodeAgentNotFound, "agent not found")
+		return
+	}
+	etag := agentETag(agent.Version)
+	if notModified(w, r, etag) {
 		return
 	}
+	w.Header().Set("ETag", etag)
+	var body interface{} = agent
+	if sh != nil {
+		shaped, err := sh.apply(agent, s.agentExtras(sh, agent))
+		if err != nil {
+			s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, "shaping agent: "+err.Error())
+			return
+		}
+		body = shaped
+	}
 	
 	response := APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"agent": agent,
+			"agent": body,
 		},
 		Timestamp: time.Now(),
 	}
@@ -299,18 +568,46 @@ func (s *APIServer) handleGetAgent(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// handleUpdateAgent applies an update conditional on If-Match, so that
+// concurrent writers cannot overwrite each other's changes unseen
 func (s *APIServer) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 	agentID := chi.URLParam(r, "agentID")
-	var req AgentRequest
+	var req agents.AgentUpdate
+	
+	version, ok := s.ifMatchVersion(w, r, true)
+	if !ok {
+		return
+	}
 	
 	if err := s.parseJSON(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
+		s.writeDecodeError(w, err)
+		return
+	}
+	if r.URL.Query().Get("force") == "true" {
+		req.Force = true
+	}
+	
+	if errs := req.Validate(); len(errs) > 0 {
+		details := make([]FieldError, len(errs))
+		for i, e := range errs {
+			details[i] = FieldError{Field: e.Field, Message: e.Message}
+		}
+		s.writeErrorCode(w, http.StatusUnprocessableEntity, CodeValidationFailed, "invalid agent update", details...)
 		return
 	}
 	
-	// Update agent logic would go here
+	agent, err := s.agentRegistry.UpdateAgentIf(agentID, version, req)
+	if err != nil {
+		s.writeAgentWriteError(w, agentID, err)
+		return
+	}
+	
+	w.Header().Set("ETag", agentETag(agent.Version))
 	response := APIResponse{
 		Success: true,
+		Data: map[string]interface{}{
+			"agent": agent.View(),
+		},
 		Message: fmt.Sprintf("Agent %s updated", agentID),
 		Timestamp: time.Now(),
 	}
@@ -321,8 +618,12 @@ func (s *APIServer) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 func (s *APIServer) handleDeleteAgent(w http.ResponseWriter, r *http.Request) {
 	agentID := chi.URLParam(r, "agentID")
 	
-	if err := s.agentRegistry.DeleteAgent(agentID); err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+	version, ok := s.ifMatchVersion(w, r, false)
+	if !ok {
+		return
+	}
+	if err := s.agentRegistry.DeleteAgentIf(agentID, version); err != nil {
+		s.writeAgentWriteError(w, agentID, err)
 		return
 	}
 	
@@ -339,7 +640,7 @@ func (s *APIServer) handleStartAgent(w http.ResponseWriter, r *http.Request) {
 	agentID := chi.URLParam(r, "agentID")
 	
 	if err := s.agentRegistry.StartAgent(agentID); err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		s.writeRegistryError(w, err)
 		return
 	}
 	
@@ -356,7 +657,7 @@ func (s *APIServer) handleStopAgent(w http.ResponseWriter, r *http.Request) {
 	agentID := chi.URLParam(r, "agentID")
 	
 	if err := s.agentRegistry.StopAgent(agentID); err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		s.writeRegistryError(w, err)
 		return
 	}
 	
@@ -395,47 +696,108 @@ func (s *APIServer) handleGetAgentTasks(w http.ResponseWriter, r *http.Request)
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// handleListTasks lists tasks, whole or shaped by ?fields= and ?expand=.
+// ?archived=true lists archived tasks instead, paged by ?limit= and
+// ?offset=.
 func (s *APIServer) handleListTasks(w http.ResponseWriter, r *http.Request) {
-	// List all tasks
-	tasks := []map[string]interface{}{
-		{
-			"id":        "task-1",
-			"status":    "completed",
-			"agent":     "agent-1",
-			"created":   time.Now().Add(-1 * time.Hour),
-			"completed": time.Now().Add(-30 * time.Minute),
-		},
+	sh, details := taskShape.parse(r)
+	if len(details) > 0 {
+		s.writeShapeError(w, details)
+		return
 	}
-	
-	response := APIResponse{
-		Success: true,
-		Data: map[string]interface{}{
-			"tasks": tasks,
-			"count": len(tasks),
-		},
-		Timestamp: time.Now(),
+	aq, details := parseArchiveQuery(r)
+	if len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid archive query", details...)
+		return
 	}
-	
-	s.writeJSON(w, http.StatusOK, response)
+	tasks := s.workspaceTasks(r)
+	if aq.archived {
+		var ok bool
+		if tasks, ok = s.archivedTasks(w, r, aq); !ok {
+			return
+		}
+	}
+	if sh == nil {
+		writeList(s, w, http.StatusOK, "tasks", tasks, map[string]interface{}{"count": len(tasks)})
+		return
+	}
+	shaped, err := shapeList(sh, tasks, s.taskExtras)
+	if err != nil {
+		s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, "shaping tasks: "+err.Error())
+		return
+	}
+	writeList(s, w, http.StatusOK, "tasks", shaped, map[string]interface{}{"count": len(shaped)})
 }
 
 func (s *APIServer) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 	var req TaskRequest
 	if err := s.parseJSON(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
+		s.writeDecodeError(w, err)
 		return
 	}
 	
-	// Create task logic would go here
-	taskID := fmt.Sprintf("task-%d", time.Now().Unix())
+	if !s.validRequest(w, &req, "invalid task") {
+		return
+	}
+	if req.Retry != nil {
+		if err := req.Retry.Validate(); err != nil {
+			s.writeErrorCode(w, http.StatusUnprocessableEntity, CodeValidationFailed, "invalid task",
+				FieldError{Field: "retry", Message: err.Error()})
+			return
+		}
+	}
+	
+	s.createTask(w, r, &agents.Task{
+		Title:       req.Task,
+		Priority:    agents.TaskPriority(req.Priority),
+		Source:      "api",
+		CallbackURL: req.CallbackURL,
+		AcceptanceCriteria: req.AcceptanceCriteria,
+		Retry:       req.Retry,
+	}, req.AgentID)
+}
+
+// createTask creates task in the request's workspace, assigns it to agentID
+// when one is given, and answers 201 with the task
+func (s *APIServer) createTask(w http.ResponseWriter, r *http.Request, task *agents.Task, agentID string) {
+	if s.agentRegistry.Draining() {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeShuttingDown, "server is shutting down")
+		return
+	}
+	
+	workspace := requestWorkspace(r)
+	if agentID != "" {
+		agent, ok := s.agentRegistry.GetAgent(agentID)
+		if !ok || !auth.CanAccess(r.Context(), agent.Workspace) {
+			s.writeErrorCode(w, http.StatusNotFound, CodeAgentNotFound, "agent not found")
+			return
+		}
+		if agent.Workspace != workspace {
+			s.writeRegistryError(w, agents.ErrWorkspaceMismatch)
+			return
+		}
+	}
+	
+	task.Workspace = workspace
+	task = s.agentRegistry.CreateTaskBy(task, cause(r, ""))
+	// A busy agent leaves the task pending for auto-assignment rather than
+	// failing a request whose task already exists
+	message := "Task created successfully"
+	if agentID != "" {
+		if err := s.agentRegistry.AssignTaskBy(task.ID, agentID, cause(r, "agent requested at creation")); err != nil {
+			message = fmt.Sprintf("Task created but not assigned: %v", err)
+		}
+	}
+	task, _ = s.agentRegistry.GetTask(task.ID)
 	
 	response := APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"task_id": taskID,
-			"status":  "created",
+			"task_id": task.ID,
+			"status":  task.Status,
+			"task":    task,
 		},
-		Message: "Task created successfully",
+		Message: message,
 		Timestamp: time.Now(),
 	}
 	
@@ -443,21 +805,44 @@ func (s *APIServer) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *APIServer) handleGetTask(w http.ResponseWriter, r *http.Request) {
-	taskID := chi.URLParam(r, "taskID")
-	
-	// Get task details
-	task := map[string]interface{}{
-		"id":        taskID,
-		"status":    "running",
-		"agent":     "agent-1",
-		"created":   time.Now().Add(-10 * time.Minute),
-		"progress":  50,
+	sh, details := taskShape.parse(r)
+	if len(details) > 0 {
+		s.writeShapeError(w, details)
+		return
+	}
+	aq, details := parseArchiveQuery(r)
+	if len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid archive query", details...)
+		return
+	}
+	var task *agents.Task
+	if aq.archived {
+		var err error
+		if task, err = s.agentRegistry.ArchivedTask(chi.URLParam(r, "taskID")); err != nil {
+			s.writeRegistryError(w, err)
+			return
+		}
+	} else {
+		var ok bool
+		if task, ok = s.agentRegistry.GetTask(chi.URLParam(r, "taskID")); !ok {
+			s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+			return
+		}
+	}
+	var body interface{} = task
+	if sh != nil {
+		shaped, err := sh.apply(task, s.taskExtras(sh, task))
+		if err != nil {
+			s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, "shaping task: "+err.Error())
+			return
+		}
+		body = shaped
 	}
 	
 	response := APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"task": task,
+			"task": body,
 		},
 		Timestamp: time.Now(),
 	}
@@ -477,11 +862,21 @@ func (s *APIServer) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// handleCancelTask cancels a task that has not finished; ?reason= is
+// recorded in its history
 func (s *APIServer) handleCancelTask(w http.ResponseWriter, r *http.Request) {
 	taskID := chi.URLParam(r, "taskID")
+	if err := s.agentRegistry.CancelTask(taskID, cause(r, r.URL.Query().Get("reason"))); err != nil {
+		s.writeRegistryError(w, err)
+		return
+	}
+	task, _ := s.agentRegistry.GetTask(taskID)
 	
 	response := APIResponse{
 		Success: true,
+		Data: map[string]interface{}{
+			"task": task,
+		},
 		Message: fmt.Sprintf("Task %s cancelled", taskID),
 		Timestamp: time.Now(),
 	}
@@ -547,7 +942,7 @@ func (s *APIServer) handleExecuteTool(w http.ResponseWriter, r *http.Request) {
 	var params map[string]interface{}
 	
 	if err := s.parseJSON(r, &params); err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
+		s.writeDecodeError(w, err)
 		return
 	}
 	
@@ -592,7 +987,7 @@ func (s *APIServer) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 func (s *APIServer) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
 	var config map[string]interface{}
 	if err := s.parseJSON(r, &config); err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
+		s.writeDecodeError(w, err)
 		return
 	}
 	
@@ -606,12 +1001,15 @@ func (s *APIServer) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *APIServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	uptime := time.Since(s.startedAt)
 	stats := map[string]interface{}{
-		"uptime":       "N/A",
-		"requests":     0,
-		"agents":       s.agentRegistry.GetStats(),
-		"memory_usage": "N/A",
-		"cpu_usage":    "N/A",
+		"started_at":     s.startedAt,
+		"uptime":         uptime.Round(time.Second).String(),
+		"uptime_seconds": int64(uptime.Seconds()),
+		"requests":       s.requests.snapshot(),
+		"agents":         s.agentRegistry.GetStats(),
+		"memory":         memoryStats(),
+		"cpu":            cpuStats(uptime),
 	}
 	
 	response := APIResponse{
@@ -625,48 +1023,6 @@ func (s *APIServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, response)
 }
 
-func (s *APIServer) handleShutdown(w http.ResponseWriter, r *http.Request) {
-	response := APIResponse{
-		Success: true,
-		Message: "Shutting down server",
-		Timestamp: time.Now(),
-	}
-	
-	s.writeJSON(w, http.StatusOK, response)
-	
-	// Start shutdown in background
-	go func() {
-		time.Sleep(1 * time.Second)
-		// Trigger shutdown
-	}()
-}
-
-func (s *APIServer) handleGetLogs(w http.ResponseWriter, r *http.Request) {
-	logs := []map[string]interface{}{
-		{
-			"timestamp": time.Now().Add(-5 * time.Minute),
-			"level":     "info",
-			"message":   "Server started",
-		},
-		{
-			"timestamp": time.Now().Add(-3 * time.Minute),
-			"level":     "info",
-			"message":   "Agent registered",
-		},
-	}
-	
-	response := APIResponse{
-		Success: true,
-		Data: map[string]interface{}{
-			"logs": logs,
-			"count": len(logs),
-		},
-		Timestamp: time.Now(),
-	}
-	
-	s.writeJSON(w, http.StatusOK, response)
-}
-
 // Project Manager integration endpoints
 func (s *APIServer) handleGetProjectTasks(w http.ResponseWriter, r *http.Request) {
 	projectID := r.URL.Query().Get("project_id")
@@ -713,7 +1069,7 @@ func (s *APIServer) handleGetProjectTasks(w http.ResponseWriter, r *http.Request
 func (s *APIServer) handleCreateProjectTask(w http.ResponseWriter, r *http.Request) {
 	var task map[string]interface{}
 	if err := s.parseJSON(r, &task); err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
+		s.writeDecodeError(w, err)
 		return
 	}
 	
@@ -778,7 +1134,7 @@ func (s *APIServer) handleAssignTask(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	if err := s.parseJSON(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
+		s.writeDecodeError(w, err)
 		return
 	}
 	
@@ -841,7 +1197,7 @@ func (s *APIServer) handleRecommendAgents(w http.ResponseWriter, r *http.Request
 	}
 	
 	if err := s.parseJSON(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
+		s.writeDecodeError(w, err)
 		return
 	}
 	
@@ -881,7 +1237,7 @@ func (s *APIServer) handleRecommendAgents(w http.ResponseWriter, r *http.Request
 func (s *APIServer) handleListProjectTasks(w http.ResponseWriter, r *http.Request) {
 	projectManager := s.engine.GetProjectManager()
 	if projectManager == nil {
-		s.writeError(w, http.StatusServiceUnavailable, "Project manager not available")
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeProjectManagerUnavailable, "project manager not available")
 		return
 	}
 	
@@ -901,13 +1257,13 @@ func (s *APIServer) handleGetProjectTask(w http.ResponseWriter, r *http.Request)
 	
 	projectManager := s.engine.GetProjectManager()
 	if projectManager == nil {
-		s.writeError(w, http.StatusServiceUnavailable, "Project manager not available")
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeProjectManagerUnavailable, "project manager not available")
 		return
 	}
 	
 	taskResult, exists := projectManager.GetTaskStatus(taskID)
 	if !exists {
-		s.writeError(w, http.StatusNotFound, "Task not found")
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
 		return
 	}
 	
@@ -929,13 +1285,13 @@ func (s *APIServer) handleAssignProjectTask(w http.ResponseWriter, r *http.Reque
 	}
 	
 	if err := s.parseJSON(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "Invalid request data")
+		s.writeDecodeError(w, err)
 		return
 	}
 	
 	projectManager := s.engine.GetProjectManager()
 	if projectManager == nil {
-		s.writeError(w, http.StatusServiceUnavailable, "Project manager not available")
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeProjectManagerUnavailable, "project manager not available")
 		return
 	}
 	
@@ -955,7 +1311,7 @@ func (s *APIServer) handleAssignProjectTask(w http.ResponseWriter, r *http.Reque
 func (s *APIServer) handleListProjectAgents(w http.ResponseWriter, r *http.Request) {
 	projectManager := s.engine.GetProjectManager()
 	if projectManager == nil {
-		s.writeError(w, http.StatusServiceUnavailable, "Project manager not available")
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeProjectManagerUnavailable, "project manager not available")
 		return
 	}
 	
@@ -971,6 +1327,7 @@ func (s *APIServer) handleListProjectAgents(w http.ResponseWriter, r *http.Reque
 			"status":       string(agent.Status),
 			"capabilities": agent.Capabilities,
 			"load":         agent.Load,
+			"queue_depth":  len(agent.Queue),
 		}
 	}
 	
@@ -987,7 +1344,7 @@ func (s *APIServer) handleListProjectAgents(w http.ResponseWriter, r *http.Reque
 func (s *APIServer) handleGetProjectStatus(w http.ResponseWriter, r *http.Request) {
 	projectManager := s.engine.GetProjectManager()
 	if projectManager == nil {
-		s.writeError(w, http.StatusServiceUnavailable, "Project manager not available")
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeProjectManagerUnavailable, "project manager not available")
 		return
 	}
 	
@@ -995,6 +1352,9 @@ func (s *APIServer) handleGetProjectStatus(w http.ResponseWriter, r *http.Reques
 		"enabled":   true,
 		"connected": true,
 		"tasks":     len(projectManager.GetTasks()),
+		"backpressure": projectManager.Backpressure(),
+		"reconciliation": projectManager.Reconciliation(),
+		"webhooks":  projectManager.IngestStats(),
 		"timestamp": time.Now(),
 	}
 	
@@ -1011,20 +1371,30 @@ func (s *APIServer) handleGetProjectStatus(w http.ResponseWriter, r *http.Reques
 func (s *APIServer) handleProjectWebhook(w http.ResponseWriter, r *http.Request) {
 	projectManager := s.engine.GetProjectManager()
 	if projectManager == nil {
-		s.writeError(w, http.StatusServiceUnavailable, "Project manager not available")
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeProjectManagerUnavailable, "project manager not available")
 		return
 	}
 	
-	// TODO: Implement webhook handling
-	// This would process the webhook data and update internal state
+	var event project.WebhookEvent
+	if err := s.parseJSON(r, &event); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	// Queued rather than processed here, so that a storm of events does
+	// not hold the requests
+	if err := projectManager.Ingest(event); err != nil {
+		w.Header().Set("Retry-After", "1")
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, err.Error())
+		return
+	}
 	
 	response := APIResponse{
 		Success:   true,
-		Message:   "Webhook received",
+		Message:   "Webhook queued",
 		Timestamp: time.Now(),
 	}
 	
-	s.writeJSON(w, http.StatusOK, response)
+	s.writeJSON(w, http.StatusAccepted, response)
 }
 
 // Helper methods
@@ -1034,25 +1404,3 @@ func (s *APIServer) parseJSON(r *http.Request, v interface{}) error {
 	return decoder.Decode(v)
 }
 
-func (s *APIServer) writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	
-	encoder := json.NewEncoder(w)
-	encoder.SetEscapeHTML(true)
-	encoder.SetIndent("", "  ")
-	
-	if err := encoder.Encode(v); err != nil {
-		s.logger.Printf("Error encoding JSON response: %v", err)
-	}
-}
-
-func (s *APIServer) writeError(w http.ResponseWriter, statusCode int, message string) {
-	response := APIResponse{
-		Success:  false,
-		Error:    message,
-		Timestamp: time.Now(),
-	}
-	
-	s.writeJSON(w, statusCode, response)
-}
\ No newline at end of file
@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// Bounds of ?limit= on GET /tasks?archived=true
+const (
+	defaultArchivedTasks = 100
+	maxArchivedTasks     = 1000
+)
+
+// archiveQuery is the paging of archived tasks
+type archiveQuery struct {
+	archived      bool
+	limit, offset int
+}
+
+// parseArchiveQuery reads ?archived=, and the ?limit= and ?offset= that
+// page archived tasks
+func parseArchiveQuery(r *http.Request) (archiveQuery, []FieldError) {
+	q := r.URL.Query()
+	aq := archiveQuery{limit: defaultArchivedTasks}
+	var details []FieldError
+	if v := q.Get("archived"); v != "" {
+		archived, err := strconv.ParseBool(v)
+		if err != nil {
+			details = append(details, FieldError{Field: "archived", Message: "must be a boolean"})
+		}
+		aq.archived = archived
+	}
+	if !aq.archived {
+		return aq, details
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxArchivedTasks {
+			details = append(details, FieldError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(maxArchivedTasks)})
+		}
+		aq.limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			details = append(details, FieldError{Field: "offset", Message: "must be a non-negative integer"})
+		}
+		aq.offset = n
+	}
+	return aq, details
+}
+
+// archivedTasks pages through the archived tasks of the request's
+// workspace, writing the error when it fails
+func (s *APIServer) archivedTasks(w http.ResponseWriter, r *http.Request, aq archiveQuery) ([]*agents.Task, bool) {
+	tasks, err := s.agentRegistry.ArchivedTasks(requestWorkspace(r), aq.limit, aq.offset)
+	if err != nil {
+		s.writeRegistryError(w, err)
+		return nil, false
+	}
+	return tasks, true
+}
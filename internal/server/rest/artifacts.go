@@ -0,0 +1,185 @@
+package rest
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/artifacts"
+	"github.com/biodoia/skagent/internal/server/bodylimit"
+	"github.com/go-chi/chi/v5"
+)
+
+// multipartOverhead is the allowance for multipart boundaries and part
+// headers on top of the artifact size limit
+const multipartOverhead = 64 << 10
+
+// SetArtifactStore enables the artifact endpoints
+func (s *APIServer) SetArtifactStore(store *artifacts.LocalStore) {
+	s.artifacts = store
+}
+
+// requireArtifacts reports whether an artifact store is configured
+func (s *APIServer) requireArtifacts(w http.ResponseWriter) bool {
+	if s.artifacts == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "artifact store not available")
+		return false
+	}
+	return true
+}
+
+// artifactURL is where an artifact can be downloaded, under the same API
+// prefix as the request
+func artifactURL(r *http.Request, id string) string {
+	if strings.HasPrefix(r.URL.Path, "/api/v1/") {
+		return "/api/v1/artifacts/" + id
+	}
+	return "/artifacts/" + id
+}
+
+// handleUploadArtifact stores a file for a task. The body is either a
+// multipart form with a "file" part, or the raw content with the file
+// name in ?name= and its type in Content-Type.
+func (s *APIServer) handleUploadArtifact(w http.ResponseWriter, r *http.Request) {
+	if !s.requireArtifacts(w) {
+		return
+	}
+
+	taskID := chi.URLParam(r, "taskID")
+	if _, ok := s.agentRegistry.GetTask(taskID); !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	}
+
+	// Uploads are bounded by the artifact limit rather than the body limit
+	bodylimit.SetLimit(r, s.artifacts.MaxSize()+multipartOverhead)
+	r.Body = http.MaxBytesReader(w, r.Body, s.artifacts.MaxSize()+multipartOverhead)
+	content, name, contentType, ok := s.readArtifactUpload(w, r)
+	if !ok {
+		return
+	}
+
+	a, err := s.artifacts.Put(taskID, name, contentType, content)
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.Is(err, artifacts.ErrTooLarge), errors.As(err, &maxBytes):
+		s.writeErrorCode(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "artifact exceeds the size limit")
+		return
+	case err != nil:
+		s.writeError(w, http.StatusInternalServerError, "failed to store artifact")
+		return
+	}
+
+	if err := s.agentRegistry.AddTaskArtifact(taskID, *a); err != nil {
+		s.artifacts.Delete(a.ID)
+		s.writeRegistryError(w, err)
+		return
+	}
+
+	url := artifactURL(r, a.ID)
+	w.Header().Set("Location", url)
+	s.writeJSON(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"artifact": a,
+			"url":      url,
+		},
+		Message:   "Artifact stored",
+		Timestamp: time.Now(),
+	})
+}
+
+// readArtifactUpload returns the content of an upload with its file name
+// and type, writing the error response itself when the request is invalid
+func (s *APIServer) readArtifactUpload(w http.ResponseWriter, r *http.Request) (io.Reader, string, string, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		name := artifacts.CleanName(r.URL.Query().Get("name"))
+		if name == "" {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "artifact name is required",
+				FieldError{Field: "name", Message: "pass the file name as ?name= or upload a multipart form"})
+			return nil, "", "", false
+		}
+		return r.Body, name, r.Header.Get("Content-Type"), true
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeBadRequest, "invalid multipart body")
+		return nil, "", "", false
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "missing file",
+				FieldError{Field: "file", Message: "is required"})
+			return nil, "", "", false
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		name := artifacts.CleanName(part.FileName())
+		if q := r.URL.Query().Get("name"); q != "" {
+			name = artifacts.CleanName(q)
+		}
+		if name == "" {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "artifact name is required",
+				FieldError{Field: "name", Message: "the file part has no file name"})
+			return nil, "", "", false
+		}
+		return part, name, part.Header.Get("Content-Type"), true
+	}
+}
+
+func (s *APIServer) handleListTaskArtifacts(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "taskID")
+	task, ok := s.agentRegistry.GetTask(taskID)
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	}
+
+	list := task.Artifacts
+	if list == nil {
+		list = []artifacts.Artifact{}
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"artifacts": list,
+			"count":     len(list),
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleGetArtifact downloads an artifact; Range and If-Modified-Since
+// requests are honored
+func (s *APIServer) handleGetArtifact(w http.ResponseWriter, r *http.Request) {
+	if !s.requireArtifacts(w) {
+		return
+	}
+
+	a, content, err := s.artifacts.Open(chi.URLParam(r, "artifactID"))
+	switch {
+	case errors.Is(err, artifacts.ErrNotFound):
+		s.writeErrorCode(w, http.StatusNotFound, CodeArtifactNotFound, "artifact not found")
+		return
+	case err != nil:
+		s.writeError(w, http.StatusInternalServerError, "failed to read artifact")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Header().Set("ETag", `"`+a.SHA256+`"`)
+	if rs, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, a.Name, a.CreatedAt, rs)
+		return
+	}
+	io.Copy(w, content)
+}
@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/artifacts"
+)
+
+func TestTaskArtifacts(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	task := registry.CreateTask(&agents.Task{Title: "build"})
+	s := NewServer(ctx, 0, "localhost", nil, registry)
+	store, err := artifacts.NewLocalStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetArtifactStore(store)
+	handler := s.setupRoutes()
+
+	upload := func(path, contentType string, body *bytes.Buffer) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := upload("/api/v1/tasks/"+task.ID+"/artifacts?name=build.log", "text/plain", bytes.NewBufferString("ok\n"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("raw upload: status %d: %s", rec.Code, rec.Body)
+	}
+	location := rec.Header().Get("Location")
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("file", "report.json")
+	part.Write([]byte(`{"passed": 12}`))
+	mw.Close()
+	rec = upload("/api/v1/tasks/"+task.ID+"/artifacts", mw.FormDataContentType(), &form)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("multipart upload: status %d: %s", rec.Code, rec.Body)
+	}
+
+	if got, _ := registry.GetTask(task.ID); len(got.Artifacts) != 2 || got.Artifacts[1].Name != "report.json" {
+		t.Fatalf("task artifacts: %+v", got.Artifacts)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, location, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok\n" || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("download: status %d, type %q: %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), `filename=build.log`) {
+		t.Fatalf("Content-Disposition %q", rec.Header().Get("Content-Disposition"))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+task.ID+"/artifacts", nil))
+	var resp APIResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || resp.Data["count"].(float64) != 2 {
+		t.Fatalf("list: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = upload("/api/v1/tasks/missing/artifacts?name=x", "text/plain", bytes.NewBufferString("x"))
+	if rec.Code != http.StatusNotFound || decodeError(t, rec).Code != CodeTaskNotFound {
+		t.Fatalf("unknown task: status %d: %s", rec.Code, rec.Body)
+	}
+	rec = upload("/api/v1/tasks/"+task.ID+"/artifacts", "text/plain", bytes.NewBufferString("x"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing name: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/artifacts/missing", nil))
+	if rec.Code != http.StatusNotFound || decodeError(t, rec).Code != CodeArtifactNotFound {
+		t.Fatalf("unknown artifact: status %d: %s", rec.Code, rec.Body)
+	}
+}
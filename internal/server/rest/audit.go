@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/audit"
+)
+
+// maxAuditLimit bounds ?limit= on GET /system/audit
+const maxAuditLimit = 1000
+
+// SetAuditLog records every mutating request in l and enables
+// GET /system/audit. It must be called before the server starts.
+func (s *APIServer) SetAuditLog(l *audit.Log) {
+	s.audit = l
+}
+
+// auditMiddleware records mutating requests in the audit log, if there is
+// one
+func (s *APIServer) auditMiddleware(next http.Handler) http.Handler {
+	if s.audit == nil {
+		return next
+	}
+	return audit.Middleware(s.audit, "rest", s.logger)(next)
+}
+
+// parseAuditFilter builds a filter from ?actor=, ?action=, ?resource=,
+// ?resource_id=, ?source=, ?since=, ?until=, ?after=, ?failed= and
+// ?limit=. since and until accept RFC 3339 timestamps or a duration back
+// from now (e.g. 24h).
+func parseAuditFilter(r *http.Request) (audit.Filter, []FieldError) {
+	q := r.URL.Query()
+	f := audit.Filter{
+		Actor:      q.Get("actor"),
+		Action:     q.Get("action"),
+		Resource:   q.Get("resource"),
+		ResourceID: q.Get("resource_id"),
+		Source:     q.Get("source"),
+	}
+	var details []FieldError
+
+	parseTime := func(name string) time.Time {
+		v := q.Get(name)
+		if v == "" {
+			return time.Time{}
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return time.Now().Add(-d)
+		}
+		details = append(details, FieldError{Field: name, Message: "must be an RFC 3339 time or a duration like 24h"})
+		return time.Time{}
+	}
+	f.Since = parseTime("since")
+	f.Until = parseTime("until")
+
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			details = append(details, FieldError{Field: "after", Message: "must be a non-negative integer"})
+		}
+		f.AfterSeq = n
+	}
+	if v := q.Get("failed"); v != "" {
+		failed, err := strconv.ParseBool(v)
+		if err != nil {
+			details = append(details, FieldError{Field: "failed", Message: "must be a boolean"})
+		}
+		f.Failed = &failed
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			details = append(details, FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxAuditLimit)})
+		}
+		f.Limit = n
+	}
+	return f, details
+}
+
+// handleListAudit returns the most recent audit entries matching the
+// query, oldest first
+func (s *APIServer) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "audit log is not enabled")
+		return
+	}
+	filter, details := parseAuditFilter(r)
+	if len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid audit query", details...)
+		return
+	}
+
+	entries, err := s.audit.Query(filter)
+	if err != nil {
+		s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, "reading the audit log: "+err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	writeList(s, w, http.StatusOK, "entries", entries, map[string]interface{}{"count": len(entries)})
+}
@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/audit"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestAuditLogRecordsMutations(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer(ctx, 0, "localhost", nil, agents.NewRegistry(ctx))
+	authz, err := auth.New(config.AuthConfig{Keys: map[string]config.APIKeyConfig{
+		"ops":    {Token: "ops-token", Role: string(auth.RoleAdmin)},
+		"reader": {Token: "reader-token", Role: string(auth.RoleViewer)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetAuthorizer(authz)
+	log, _ := audit.Open("")
+	s.SetAuditLog(log)
+	handler := s.setupRoutes()
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/v1/agents", "ops-token", `{"name":"a","type":"coder"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create agent: %d %s", rec.Code, rec.Body)
+	}
+	do(http.MethodGet, "/api/v1/agents", "ops-token", "")
+	do(http.MethodPost, "/api/v1/tasks", "reader-token", `{"task":"x"}`)
+
+	rec := do(http.MethodGet, "/api/v1/system/audit", "ops-token", "")
+	var resp struct {
+		Data struct {
+			Entries []audit.Entry `json:"entries"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("audit: %d %s", rec.Code, rec.Body)
+	}
+	entries := resp.Data.Entries
+	if len(entries) != 2 {
+		t.Fatalf("audited %d requests, want the 2 mutations: %+v", len(entries), entries)
+	}
+	created, denied := entries[0], entries[1]
+	if created.Actor != "ops" || created.Action != "create" || created.Resource != "agents" || created.Status != http.StatusCreated || created.RequestID == "" {
+		t.Errorf("create entry = %+v", created)
+	}
+	if denied.Actor != "reader" || denied.Status != http.StatusForbidden || denied.Resource != "tasks" {
+		t.Errorf("denied entry = %+v", denied)
+	}
+
+	if rec := do(http.MethodGet, "/api/v1/system/audit?failed=true&actor=reader", "ops-token", ""); !strings.Contains(rec.Body.String(), `"count":1`) {
+		t.Errorf("filtered audit: %s", rec.Body)
+	}
+	if rec := do(http.MethodGet, "/api/v1/system/audit?limit=0", "ops-token", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/v1/system/audit", "reader-token", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("viewer read the audit log: %d", rec.Code)
+	}
+}
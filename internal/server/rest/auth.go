@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/biodoia/skagent/internal/auth"
+)
+
+// SetAuthorizer enables API key authentication and per-route role checks.
+// With no authorizer every request is allowed.
+func (s *APIServer) SetAuthorizer(a *auth.Authorizer) {
+	s.authz = a
+}
+
+// authMiddleware resolves the caller's API key to a principal
+func (s *APIServer) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authz == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, ok := s.authz.Authenticate(auth.TokenFromRequest(r))
+		if !ok {
+			s.authz.AuditUnauthenticated(r.Method + " " + r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="skagent"`)
+			s.writeErrorCode(w, http.StatusUnauthorized, CodeUnauthorized, "missing or invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// require rejects requests whose role lacks the given permission
+func (s *APIServer) require(perm auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.authz == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, _ := auth.PrincipalFromContext(r.Context())
+			if !s.authz.Check(principal, perm, r.Method+" "+r.URL.Path) {
+				s.writeErrorCode(w, http.StatusForbidden, CodeForbidden, "role "+string(principal.Role)+" lacks permission "+string(perm))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
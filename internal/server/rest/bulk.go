@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// maxBulkOperations bounds the size of a single bulk request
+const maxBulkOperations = 500
+
+type BulkAgentRequest struct {
+	Operations []agents.AgentOp `json:"operations"`
+}
+
+type BulkTaskRequest struct {
+	Operations []agents.TaskOp `json:"operations"`
+}
+
+// handleBulkAgents applies a batch of agent create/update/delete
+// operations; either all of them take effect or none does
+func (s *APIServer) handleBulkAgents(w http.ResponseWriter, r *http.Request) {
+	var req BulkAgentRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if !s.checkBulkSize(w, len(req.Operations)) {
+		return
+	}
+
+	results, err := s.agentRegistry.ApplyAgentOpsIn(requestWorkspace(r), req.Operations)
+	s.writeBulkResult(w, results, err, nil)
+}
+
+// handleBulkTasks applies a batch of task create/update/delete operations
+// with the same all-or-nothing semantics as handleBulkAgents. The tasks it
+// creates are checked against the constitution first.
+func (s *APIServer) handleBulkTasks(w http.ResponseWriter, r *http.Request) {
+	var req BulkTaskRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if !s.checkBulkSize(w, len(req.Operations)) {
+		return
+	}
+
+	report, ok := s.checkTaskBatch(w, req.Operations)
+	if !ok {
+		return
+	}
+
+	results, err := s.agentRegistry.ApplyTaskOpsIn(requestWorkspace(r), req.Operations, cause(r, ""))
+	var extra map[string]interface{}
+	if report != nil {
+		extra = map[string]interface{}{"constitution": report}
+	}
+	s.writeBulkResult(w, results, err, extra)
+}
+
+func (s *APIServer) checkBulkSize(w http.ResponseWriter, n int) bool {
+	switch {
+	case n == 0:
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "no operations given",
+			FieldError{Field: "operations", Message: "must not be empty"})
+		return false
+	case n > maxBulkOperations:
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "too many operations",
+			FieldError{Field: "operations", Message: fmt.Sprintf("at most %d operations per request", maxBulkOperations)})
+		return false
+	}
+	return true
+}
+
+// writeBulkResult reports the per-item results of an applied batch, or the
+// operation that caused the whole batch to be rejected. extra is added to
+// the data of a successful response.
+func (s *APIServer) writeBulkResult(w http.ResponseWriter, results []agents.BulkResult, err error, extra map[string]interface{}) {
+	if err != nil {
+		var opErr *agents.OpError
+		if !errors.As(err, &opErr) {
+			s.writeRegistryError(w, err)
+			return
+		}
+		field := fmt.Sprintf("operations[%d]", opErr.Index)
+		if opErr.Field != "" {
+			field += "." + opErr.Field
+		}
+		s.writeRegistryError(w, err, FieldError{Field: field, Message: opErr.Err.Error()})
+		return
+	}
+
+	response := APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"results": results,
+			"count":   len(results),
+		},
+		Message:   fmt.Sprintf("Applied %d operations", len(results)),
+		Timestamp: time.Now(),
+	}
+	for k, v := range extra {
+		response.Data[k] = v
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
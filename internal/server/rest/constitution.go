@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/constitution"
+)
+
+// ConstitutionCheckRequest is the body of POST /constitution/check: a
+// SpecKit tasks.md, or the operations of a task batch
+type ConstitutionCheckRequest struct {
+	TasksMD    string          `json:"tasks_md,omitempty"`
+	Operations []agents.TaskOp `json:"operations,omitempty"`
+}
+
+// SetConstitution checks task batches against the rules of c before they
+// are created and enables the /constitution routes
+func (s *APIServer) SetConstitution(c *constitution.Checker) {
+	s.constitution = c
+}
+
+// requireConstitution writes 503 when constitution checks are not enabled
+func (s *APIServer) requireConstitution(w http.ResponseWriter) bool {
+	if s.constitution == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "constitution checks are not enabled")
+		return false
+	}
+	return true
+}
+
+// handleGetConstitution returns the rules checked and whether they are
+// enforced
+func (s *APIServer) handleGetConstitution(w http.ResponseWriter, r *http.Request) {
+	if !s.requireConstitution(w) {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"rules":    s.constitution.Rules(),
+			"enforced": s.constitution.Enforced(),
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleCheckConstitution reports the violations of a plan without
+// creating anything, whether or not the rules are enforced
+func (s *APIServer) handleCheckConstitution(w http.ResponseWriter, r *http.Request) {
+	if !s.requireConstitution(w) {
+		return
+	}
+	var req ConstitutionCheckRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+
+	var plan constitution.Plan
+	switch {
+	case strings.TrimSpace(req.TasksMD) != "" && len(req.Operations) > 0:
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "give either tasks_md or operations",
+			FieldError{Field: "operations", Message: "must be empty when tasks_md is set"})
+		return
+	case strings.TrimSpace(req.TasksMD) != "":
+		plan = constitution.ParseTasks(req.TasksMD)
+	case len(req.Operations) > 0:
+		plan = constitution.FromTaskOps(req.Operations)
+	default:
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "no plan given",
+			FieldError{Field: "tasks_md", Message: "tasks_md or operations is required"})
+		return
+	}
+
+	report := s.constitution.Check(plan)
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"report": report, "plan": plan},
+		Timestamp: time.Now(),
+	})
+}
+
+// checkTaskBatch checks the tasks a batch creates against the
+// constitution. It writes 422 and returns false when the batch breaks an
+// enforced rule; otherwise it returns the report to add to the response,
+// nil when there is nothing to check.
+func (s *APIServer) checkTaskBatch(w http.ResponseWriter, ops []agents.TaskOp) (*constitution.Report, bool) {
+	if s.constitution == nil {
+		return nil, true
+	}
+	plan := constitution.FromTaskOps(ops)
+	if len(plan.Tasks) == 0 {
+		return nil, true
+	}
+	report := s.constitution.Check(plan)
+	if !report.Rejected() {
+		return &report, true
+	}
+
+	details := make([]FieldError, 0, len(report.Violations))
+	for _, v := range report.Violations {
+		field := "operations"
+		if t, ok := plan.Task(v.TaskID); ok {
+			field = t.Source
+		}
+		details = append(details, FieldError{Field: field, Message: fmt.Sprintf("%s (%s): %s", v.Rule, v.Article, v.Message)})
+	}
+	s.writeErrorCode(w, http.StatusUnprocessableEntity, CodeConstitutionViolation,
+		fmt.Sprintf("the tasks break %d constitution rule(s)", len(report.Violations)), details...)
+	return nil, false
+}
@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/constitution"
+)
+
+func TestBulkTasksCheckedAgainstConstitution(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	s := NewServer(ctx, 0, "localhost", nil, registry)
+	checker, err := constitution.NewChecker(config.ConstitutionConfig{Enforce: true, Rules: []string{"test-first"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetConstitution(checker)
+	handler := s.setupRoutes()
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+
+	untested := `{"operations":[{"op":"create","title":"T001 Write contract tests for the API"},{"op":"create","title":"T002 Implement the API"}]}`
+	rec := post("/api/v1/tasks/bulk", untested)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"CONSTITUTION_VIOLATION"`) || !strings.Contains(rec.Body.String(), `"operations[1]"`) {
+		t.Fatalf("untested batch: %d %s", rec.Code, rec.Body)
+	}
+	if n := len(registry.ListTasks()); n != 0 {
+		t.Fatalf("rejected batch created %d tasks", n)
+	}
+
+	if rec := post("/api/v1/constitution/check", `{"tasks_md":"- [ ] T001 Write tests\n- [ ] T002 Implement it (T001)"}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"violations":[]`) {
+		t.Errorf("check: %d %s", rec.Code, rec.Body)
+	}
+
+	tested := strings.Replace(untested, "Implement the API", "Implement the API to pass T001", 1)
+	if rec := post("/api/v1/tasks/bulk", tested); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"constitution"`) {
+		t.Errorf("tested batch: %d %s", rec.Code, rec.Body)
+	}
+}
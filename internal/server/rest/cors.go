@@ -0,0 +1,134 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// defaultCORSHeaders are allowed when the config lists none
+var defaultCORSHeaders = []string{
+	"Content-Type", "Authorization", "X-API-Key", "X-API-Version", "X-Request-ID", "Idempotency-Key",
+}
+
+// corsExposedHeaders are response headers readable by browser scripts
+var corsExposedHeaders = []string{
+	"X-Request-ID", "X-API-Version", "Deprecation", "Sunset", "Link", "Idempotent-Replayed",
+}
+
+const corsAllowedMethods = "GET, POST, PUT, DELETE, OPTIONS"
+
+// corsPolicy is the resolved CORS configuration of a server
+type corsPolicy struct {
+	enabled     bool
+	anyOrigin   bool
+	origins     map[string]bool
+	suffixes    []string // from "scheme://*.domain" patterns, stored as "scheme://" + ".domain"
+	headers     string
+	credentials bool
+	maxAge      string
+}
+
+func newCORSPolicy(enabled bool, cfg config.CORSConfig) *corsPolicy {
+	p := &corsPolicy{
+		enabled:     enabled,
+		origins:     make(map[string]bool),
+		credentials: cfg.AllowCredentials,
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			p.suffixes = append(p.suffixes, strings.Replace(origin, "://*.", "://.", 1))
+		case origin != "":
+			p.origins[strings.ToLower(origin)] = true
+		}
+	}
+
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	p.headers = strings.Join(headers, ", ")
+
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(cfg.MaxAge)
+	}
+	return p
+}
+
+// allows reports whether a request Origin may access the API
+func (p *corsPolicy) allows(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, suffix := range p.suffixes {
+		scheme, domain, _ := strings.Cut(suffix, "://")
+		rest, ok := strings.CutPrefix(origin, scheme+"://")
+		if ok && strings.HasSuffix(rest, domain) && len(rest) > len(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCORS replaces the default wildcard CORS policy with the configured
+// one. With enabled false no CORS headers are sent.
+func (s *APIServer) SetCORS(enabled bool, cfg config.CORSConfig) {
+	s.cors = newCORSPolicy(enabled, cfg)
+}
+
+// corsMiddleware answers preflight requests and adds CORS headers for
+// allowed origins
+func (s *APIServer) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := s.cors
+		origin := r.Header.Get("Origin")
+		if !p.enabled || origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		allowed := p.allows(origin)
+		if allowed {
+			if p.anyOrigin && !p.credentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if p.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			h.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		}
+
+		// Preflight
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if !allowed {
+				s.writeErrorCode(w, http.StatusForbidden, CodeForbidden, "origin "+origin+" is not allowed")
+				return
+			}
+			h.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			h.Set("Access-Control-Allow-Headers", p.headers)
+			if p.maxAge != "" {
+				h.Set("Access-Control-Max-Age", p.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
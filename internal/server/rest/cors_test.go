@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer(ctx, 0, "localhost", nil, agents.NewRegistry(ctx))
+	s.SetCORS(true, config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+		MaxAge:           60,
+	})
+	h := s.setupRoutes()
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		preflight  bool
+		wantStatus int
+		wantOrigin string
+	}{
+		{"exact origin", "GET", "https://app.example.com", false, 200, "https://app.example.com"},
+		{"wildcard subdomain", "GET", "https://ui.example.org", false, 200, "https://ui.example.org"},
+		{"bare wildcard domain", "GET", "https://example.org", false, 200, ""},
+		{"foreign origin", "GET", "https://evil.test", false, 200, ""},
+		{"preflight allowed", "OPTIONS", "https://app.example.com", true, 204, "https://app.example.com"},
+		{"preflight denied", "OPTIONS", "https://evil.test", true, 403, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if tt.wantOrigin != "" && rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("Allow-Credentials not set")
+			}
+		})
+	}
+}
+
+func TestCORS_Disabled(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer(ctx, 0, "localhost", nil, agents.NewRegistry(ctx))
+	s.SetCORS(false, config.CORSConfig{AllowedOrigins: []string{"*"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	s.setupRoutes().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Allow-Origin = %q with CORS disabled", got)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package rest
+
+import "time"
+
+// processCPUTime is not available on this platform
+func processCPUTime() (user, system time.Duration, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package rest
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime returns the user and system CPU time of the process
+func processCPUTime() (user, system time.Duration, ok bool) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, 0, false
+	}
+	return time.Duration(ru.Utime.Nano()), time.Duration(ru.Stime.Nano()), true
+}
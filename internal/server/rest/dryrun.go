@@ -0,0 +1,29 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/biodoia/skagent/internal/dryrun"
+)
+
+// handleDryRunActions lists the actions dry-run mode skipped, oldest
+// first; ?component= keeps those of one component, such as git or project
+func (s *APIServer) handleDryRunActions(w http.ResponseWriter, r *http.Request) {
+	actions := dryrun.Actions()
+	if component := r.URL.Query().Get("component"); component != "" {
+		kept := actions[:0]
+		for _, a := range actions {
+			if a.Component == component {
+				kept = append(kept, a)
+			}
+		}
+		actions = kept
+	}
+	if actions == nil {
+		actions = []dryrun.Action{}
+	}
+	writeList(s, w, http.StatusOK, "actions", actions, map[string]interface{}{
+		"enabled": dryrun.Enabled(),
+		"count":   len(actions),
+	})
+}
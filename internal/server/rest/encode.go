@@ -0,0 +1,202 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// prettyMarker is set on the response headers by prettyMiddleware so that
+// writeJSON, which has no access to the request, indents its output. It
+// never leaves the server.
+const prettyMarker = "X-Skagent-Pretty"
+
+// streamListThreshold is the list length from which writeList encodes
+// items one at a time instead of building the whole response in memory
+const streamListThreshold = 500
+
+// streamFlushSize is how much a streamed list buffers between writes
+const streamFlushSize = 64 << 10
+
+// maxPooledBuffer keeps the pool from holding on to the buffers of
+// exceptionally large responses
+const maxPooledBuffer = 1 << 20
+
+// encodeBuffer is a buffer with an encoder writing into it
+type encodeBuffer struct {
+	bytes.Buffer
+	enc *json.Encoder
+}
+
+var encodeBuffers = sync.Pool{
+	New: func() interface{} {
+		b := &encodeBuffer{}
+		b.enc = json.NewEncoder(&b.Buffer)
+		b.enc.SetEscapeHTML(true)
+		return b
+	},
+}
+
+func getEncodeBuffer(pretty bool) *encodeBuffer {
+	b := encodeBuffers.Get().(*encodeBuffer)
+	b.Reset()
+	if pretty {
+		b.enc.SetIndent("", "  ")
+	} else {
+		b.enc.SetIndent("", "")
+	}
+	return b
+}
+
+func putEncodeBuffer(b *encodeBuffer) {
+	if b.Cap() <= maxPooledBuffer {
+		encodeBuffers.Put(b)
+	}
+}
+
+// prettyMiddleware honours ?pretty (or ?pretty=1, ?pretty=true) by asking
+// writeJSON for indented output; responses are compact otherwise
+func prettyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if v := q.Get("pretty"); !q.Has("pretty") || (v != "" && v != "1" && v != "true") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set(prettyMarker, "1")
+		next.ServeHTTP(&prettyWriter{ResponseWriter: w}, r)
+	})
+}
+
+// prettyWriter drops the pretty marker from responses that writeJSON did
+// not write, such as artifacts and metrics
+type prettyWriter struct {
+	http.ResponseWriter
+}
+
+func (w *prettyWriter) WriteHeader(status int) {
+	w.Header().Del(prettyMarker)
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *prettyWriter) Write(p []byte) (int, error) {
+	w.Header().Del(prettyMarker)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *prettyWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *prettyWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// takePretty reports whether the response was asked to be indented,
+// removing the marker
+func takePretty(w http.ResponseWriter) bool {
+	if w.Header().Get(prettyMarker) == "" {
+		return false
+	}
+	w.Header().Del(prettyMarker)
+	return true
+}
+
+// writeJSON encodes v into a pooled buffer and writes it with its length.
+// Encoding first means a value that cannot be encoded yields a 500 rather
+// than a truncated body.
+func (s *APIServer) writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	b := getEncodeBuffer(takePretty(w))
+	defer putEncodeBuffer(b)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := b.enc.Encode(v); err != nil {
+		s.logger.Printf("ERROR: Error encoding JSON response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"success":false,"error":{"code":"` + string(CodeInternal) + `","message":"response could not be encoded"}}` + "\n"))
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(b.Len()))
+	w.WriteHeader(statusCode)
+	w.Write(b.Bytes())
+}
+
+// writeList writes a successful response whose data holds items under key
+// next to the other fields in data. Long lists are streamed item by item,
+// so that the response is never held in memory whole; ?pretty output is
+// always built in one piece.
+func writeList[T any](s *APIServer, w http.ResponseWriter, statusCode int, key string, items []T, data map[string]interface{}) {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	if len(items) < streamListThreshold || w.Header().Get(prettyMarker) != "" {
+		data[key] = items
+		s.writeJSON(w, statusCode, APIResponse{Success: true, Data: data, Timestamp: time.Now()})
+		return
+	}
+
+	b := getEncodeBuffer(false)
+	defer putEncodeBuffer(b)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	// Encode fails only on values JSON cannot represent, which the API
+	// types do not hold; past the header there is no way to report it
+	// but to cut the response short
+	encode := func(v interface{}) bool {
+		if err := b.enc.Encode(v); err != nil {
+			s.logger.Printf("ERROR: Error encoding JSON response: %v", err)
+			return false
+		}
+		return true
+	}
+	flush := func(force bool) bool {
+		if !force && b.Len() < streamFlushSize {
+			return true
+		}
+		_, err := w.Write(b.Bytes())
+		b.Reset()
+		return err == nil
+	}
+
+	b.WriteString(`{"success":true,"data":{`)
+	fields := make([]string, 0, len(data))
+	for k := range data {
+		if k != key {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	for _, k := range fields {
+		if !encode(k) {
+			return
+		}
+		b.WriteByte(':')
+		if !encode(data[k]) {
+			return
+		}
+		b.WriteByte(',')
+	}
+	if !encode(key) {
+		return
+	}
+	b.WriteString(":[")
+	for i := range items {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if !encode(items[i]) || !flush(false) {
+			return
+		}
+	}
+	b.WriteString(`]},"timestamp":`)
+	if !encode(time.Now()) {
+		return
+	}
+	b.WriteString("}\n")
+	flush(true)
+}
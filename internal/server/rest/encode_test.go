@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestPrettyAndStreamedLists(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	for i := 0; i < streamListThreshold+10; i++ {
+		registry.CreateTask(&agents.Task{Title: fmt.Sprintf("task <%d>", i)})
+	}
+	handler := NewServer(ctx, 0, "localhost", nil, registry).setupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	compact := get("/api/v1/tasks")
+	if compact.Code != http.StatusOK || strings.Contains(compact.Body.String(), "\n  ") {
+		t.Fatalf("compact list: status %d", compact.Code)
+	}
+	if compact.Header().Get(prettyMarker) != "" {
+		t.Error("pretty marker leaked into the response")
+	}
+	var streamed APIResponse
+	if err := json.Unmarshal(compact.Body.Bytes(), &streamed); err != nil {
+		t.Fatalf("streamed list is not valid JSON: %v", err)
+	}
+	tasks := streamed.Data["tasks"].([]interface{})
+	if !streamed.Success || len(tasks) != streamListThreshold+10 || streamed.Data["count"].(float64) != float64(len(tasks)) {
+		t.Fatalf("streamed %d tasks, count %v", len(tasks), streamed.Data["count"])
+	}
+	if strings.Contains(compact.Body.String(), "<") {
+		t.Error("HTML characters are not escaped")
+	}
+
+	pretty := get("/api/v1/tasks?pretty=1")
+	var indented APIResponse
+	if err := json.Unmarshal(pretty.Body.Bytes(), &indented); err != nil || !strings.Contains(pretty.Body.String(), "\n  \"data\"") {
+		t.Fatalf("?pretty=1 did not indent: %v", err)
+	}
+	if len(indented.Data["tasks"].([]interface{})) != len(tasks) {
+		t.Error("pretty and streamed lists differ")
+	}
+
+	health := get("/healthz?pretty")
+	if !strings.Contains(health.Body.String(), "\n  ") || health.Header().Get(prettyMarker) != "" {
+		t.Errorf("?pretty on /healthz: %q", health.Body.String())
+	}
+	if health.Header().Get("Content-Length") == "" {
+		t.Error("writeJSON did not set Content-Length")
+	}
+}
+
+// largeSession is a transcript of n messages of about 2 KiB each
+func largeSession(n int) APIResponse {
+	messages := make([]map[string]interface{}, n)
+	for i := range messages {
+		messages[i] = map[string]interface{}{
+			"id":      fmt.Sprintf("msg-%d", i),
+			"role":    "assistant",
+			"content": strings.Repeat("lorem ipsum ", 170),
+		}
+	}
+	return APIResponse{Success: true, Data: map[string]interface{}{"messages": messages}}
+}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	s := NewServer(context.Background(), 0, "localhost", nil, agents.NewRegistry(context.Background()))
+	payload := largeSession(1000)
+
+	for _, pretty := range []bool{false, true} {
+		b.Run(fmt.Sprintf("pretty=%v", pretty), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				rec := httptest.NewRecorder()
+				if pretty {
+					rec.Header().Set(prettyMarker, "1")
+				}
+				s.writeJSON(rec, http.StatusOK, payload)
+			}
+		})
+	}
+}
+
+func BenchmarkWriteList(b *testing.B) {
+	s := NewServer(context.Background(), 0, "localhost", nil, agents.NewRegistry(context.Background()))
+	tasks := make([]*agents.Task, 5000)
+	for i := range tasks {
+		tasks[i] = &agents.Task{ID: fmt.Sprintf("task-%d", i), Title: "benchmark task", Description: strings.Repeat("x", 500)}
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		writeList(s, httptest.NewRecorder(), http.StatusOK, "tasks", tasks, map[string]interface{}{"count": len(tasks)})
+	}
+}
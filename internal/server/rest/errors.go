@@ -0,0 +1,256 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/core"
+	"github.com/biodoia/skagent/internal/server/requestid"
+	"github.com/biodoia/skagent/internal/validate"
+)
+
+// ErrorCode is a stable, machine-readable error identifier
+type ErrorCode string
+
+const (
+	CodeBadRequest                ErrorCode = "BAD_REQUEST"
+	CodeInvalidJSON               ErrorCode = "INVALID_JSON"
+	CodeValidationFailed          ErrorCode = "VALIDATION_FAILED"
+	CodeInvalidParameter          ErrorCode = "INVALID_PARAMETER"
+	CodeUnauthorized              ErrorCode = "UNAUTHORIZED"
+	CodeForbidden                 ErrorCode = "FORBIDDEN"
+	CodeNotFound                  ErrorCode = "NOT_FOUND"
+	CodeMethodNotAllowed          ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeAgentNotFound             ErrorCode = "AGENT_NOT_FOUND"
+	CodeTaskNotFound              ErrorCode = "TASK_NOT_FOUND"
+	CodeSessionNotFound           ErrorCode = "SESSION_NOT_FOUND"
+	CodeArtifactNotFound          ErrorCode = "ARTIFACT_NOT_FOUND"
+	CodeWebhookNotFound           ErrorCode = "WEBHOOK_NOT_FOUND"
+	CodeWorkspaceNotFound         ErrorCode = "WORKSPACE_NOT_FOUND"
+	CodeWorkspaceForbidden        ErrorCode = "WORKSPACE_FORBIDDEN"
+	CodeWorkspaceMismatch         ErrorCode = "WORKSPACE_MISMATCH"
+	CodePayloadTooLarge           ErrorCode = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType      ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	CodeConflict                  ErrorCode = "CONFLICT"
+	CodeVersionConflict           ErrorCode = "VERSION_CONFLICT"
+	CodePreconditionRequired      ErrorCode = "PRECONDITION_REQUIRED"
+	CodeIdempotencyInProgress     ErrorCode = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeIdempotencyMismatch       ErrorCode = "IDEMPOTENCY_KEY_MISMATCH"
+	CodeAgentBusy                 ErrorCode = "AGENT_BUSY"
+	CodeSessionBusy               ErrorCode = "SESSION_BUSY"
+	CodeConstitutionViolation     ErrorCode = "CONSTITUTION_VIOLATION"
+	CodePlanPartial               ErrorCode = "PLAN_PARTIAL"
+	CodeRateLimited               ErrorCode = "RATE_LIMITED"
+	CodeInvalidAPIVersion         ErrorCode = "INVALID_API_VERSION"
+	CodeUnsupportedAPIVersion     ErrorCode = "UNSUPPORTED_API_VERSION"
+	CodeServiceUnavailable        ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeNoArchive                 ErrorCode = "NO_ARCHIVE"
+	CodeShuttingDown              ErrorCode = "SHUTTING_DOWN"
+	CodeProjectManagerUnavailable ErrorCode = "PROJECT_MANAGER_UNAVAILABLE"
+	CodeMCPServerUnavailable      ErrorCode = "MCP_SERVER_UNAVAILABLE"
+	CodeProviderError             ErrorCode = "PROVIDER_ERROR"
+	CodeProviderNotConfigured     ErrorCode = "PROVIDER_NOT_CONFIGURED"
+	CodeProviderRateLimited       ErrorCode = "PROVIDER_RATE_LIMITED"
+	CodeInternal                  ErrorCode = "INTERNAL_ERROR"
+)
+
+// FieldError points at a single invalid request field
+type FieldError = validate.FieldError
+
+// APIError is the error envelope returned in APIResponse.Error
+type APIError struct {
+	Code      ErrorCode    `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	// RateLimit is the AI provider's quota on PROVIDER_RATE_LIMITED
+	RateLimit *ai.RateLimit `json:"rate_limit,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
+// codeForStatus is the generic code used when a handler gives none
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	default:
+		if status >= 500 {
+			return CodeInternal
+		}
+		return CodeBadRequest
+	}
+}
+
+// writeError writes an error with the generic code for its status
+func (s *APIServer) writeError(w http.ResponseWriter, statusCode int, message string) {
+	s.writeErrorCode(w, statusCode, codeForStatus(statusCode), message)
+}
+
+// writeErrorCode writes an error envelope with an explicit code and
+// optional field-level details
+func (s *APIServer) writeErrorCode(w http.ResponseWriter, statusCode int, code ErrorCode, message string, details ...FieldError) {
+	response := APIResponse{
+		Success: false,
+		Error: &APIError{
+			Code:      code,
+			Message:   message,
+			Details:   details,
+			RequestID: requestid.FromResponse(w),
+		},
+		Timestamp: time.Now(),
+	}
+
+	s.writeJSON(w, statusCode, response)
+}
+
+// providerError builds the error for a failed completion. A provider that
+// answered 429 yields PROVIDER_RATE_LIMITED with its quota and the time to
+// wait, an engine without a provider 503 PROVIDER_NOT_CONFIGURED; anything
+// else is a generic PROVIDER_ERROR.
+func providerError(err error) (int, *APIError, time.Duration) {
+	if errors.Is(err, core.ErrNoProvider) {
+		return http.StatusServiceUnavailable, &APIError{Code: CodeProviderNotConfigured, Message: err.Error()}, 0
+	}
+	if rl, ok := ai.AsRateLimit(err); ok {
+		limit := rl.Limit
+		return http.StatusTooManyRequests, &APIError{
+			Code:      CodeProviderRateLimited,
+			Message:   err.Error(),
+			RateLimit: &limit,
+		}, limit.RetryAfter(time.Now())
+	}
+	return http.StatusBadGateway, &APIError{Code: CodeProviderError, Message: "completion failed: " + err.Error()}, 0
+}
+
+// writeProviderError reports a failed completion, with Retry-After when
+// the provider said when to come back
+func (s *APIServer) writeProviderError(w http.ResponseWriter, err error) {
+	status, apiErr, wait := providerError(err)
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	}
+	apiErr.RequestID = requestid.FromResponse(w)
+	s.writeJSON(w, status, APIResponse{
+		Success:   false,
+		Error:     apiErr,
+		Timestamp: time.Now(),
+	})
+}
+
+// writeRegistryError maps agent registry errors to status codes
+func (s *APIServer) writeRegistryError(w http.ResponseWriter, err error, details ...FieldError) {
+	status, code := registryErrorCode(err)
+	s.writeErrorCode(w, status, code, err.Error(), details...)
+}
+
+func registryErrorCode(err error) (int, ErrorCode) {
+	switch {
+	case errors.Is(err, agents.ErrAgentNotFound):
+		return http.StatusNotFound, CodeAgentNotFound
+	case errors.Is(err, agents.ErrTaskNotFound):
+		return http.StatusNotFound, CodeTaskNotFound
+	case errors.Is(err, agents.ErrAgentBusy):
+		return http.StatusConflict, CodeAgentBusy
+	case errors.Is(err, agents.ErrVersionConflict):
+		return http.StatusConflict, CodeVersionConflict
+	case errors.Is(err, agents.ErrWorkspaceNotFound):
+		return http.StatusNotFound, CodeWorkspaceNotFound
+	case errors.Is(err, agents.ErrWorkspaceMismatch):
+		return http.StatusConflict, CodeWorkspaceMismatch
+	case errors.Is(err, agents.ErrWorkspaceExists), errors.Is(err, agents.ErrWorkspaceNotEmpty),
+		errors.Is(err, agents.ErrWorkspaceDefault):
+		return http.StatusConflict, CodeConflict
+	case errors.Is(err, agents.ErrWorkspaceInvalid):
+		return http.StatusBadRequest, CodeValidationFailed
+	case errors.Is(err, agents.ErrAgentExists), errors.Is(err, agents.ErrTaskExists),
+		errors.Is(err, agents.ErrTaskActive), errors.Is(err, agents.ErrTaskFinished),
+		errors.Is(err, agents.ErrTaskNotCompleted), errors.Is(err, agents.ErrClaimed):
+		return http.StatusConflict, CodeConflict
+	case errors.Is(err, agents.ErrInvalidOperation):
+		return http.StatusBadRequest, CodeValidationFailed
+	case errors.Is(err, agents.ErrDraining):
+		return http.StatusServiceUnavailable, CodeShuttingDown
+	case errors.Is(err, agents.ErrNoArchive):
+		// Not a transient state: clients must not retry it
+		return http.StatusNotImplemented, CodeNoArchive
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
+
+// writeDecodeError reports a request body that parseJSON rejected, naming
+// the offending field where the decoder exposes it
+func (s *APIServer) writeDecodeError(w http.ResponseWriter, err error) {
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		s.writeErrorCode(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+			fmt.Sprintf("request body exceeds the limit of %d bytes", tooLarge.Limit))
+	case errors.Is(err, io.EOF):
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidJSON, "request body is empty")
+	case errors.As(err, &typeErr):
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "request body has a field of the wrong type",
+			FieldError{Field: typeErr.Field, Message: "must be " + typeErr.Type.String()})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "request body has an unknown field",
+			FieldError{Field: field, Message: "unknown field"})
+	default:
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidJSON, err.Error())
+	}
+}
+
+// validRequest checks req against its validate tags and, when fields are
+// invalid, answers 422 listing every one of them
+func (s *APIServer) validRequest(w http.ResponseWriter, req interface{}, message string) bool {
+	errs := validate.Struct(req)
+	if len(errs) == 0 {
+		return true
+	}
+	s.writeErrorCode(w, http.StatusUnprocessableEntity, CodeValidationFailed, message, errs...)
+	return false
+}
+
+// requireFields returns a detail for every named field whose value is empty
+func requireFields(fields map[string]string) []FieldError {
+	var details []FieldError
+	for _, name := range sortedKeys(fields) {
+		if strings.TrimSpace(fields[name]) == "" {
+			details = append(details, FieldError{Field: name, Message: "is required"})
+		}
+	}
+	return details
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
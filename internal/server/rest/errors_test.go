@@ -0,0 +1,163 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/server/requestid"
+)
+
+func newTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	ctx := context.Background()
+	s := NewServer(ctx, 0, "localhost", nil, agents.NewRegistry(ctx))
+	return s.setupRoutes()
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) *APIError {
+	t.Helper()
+	var resp APIResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Success || resp.Error == nil {
+		t.Fatalf("expected an error response, got %+v", resp)
+	}
+	return resp.Error
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	h := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   ErrorCode
+		field  string
+	}{
+		{"agent not found", "GET", "/api/v1/agents/missing", "", 404, CodeAgentNotFound, ""},
+		{"unknown route", "GET", "/api/v1/nope", "", 404, CodeNotFound, ""},
+		{"missing fields", "POST", "/api/v1/agents", `{"name":""}`, 422, CodeValidationFailed, "name"},
+		{"unknown agent type", "POST", "/api/v1/agents", `{"name":"a","type":"wizard"}`, 422, CodeValidationFailed, "type"},
+		{"priority out of range", "POST", "/api/v1/tasks", `{"task":"t","priority":7}`, 422, CodeValidationFailed, "priority"},
+		{"invalid retry policy", "POST", "/api/v1/tasks", `{"task":"t","retry":{"retry_on":["sometimes"]}}`, 422, CodeValidationFailed, "retry"},
+		{"unknown field", "POST", "/api/v1/agents", `{"nme":"x"}`, 400, CodeValidationFailed, "nme"},
+		{"bad json", "POST", "/api/v1/tasks", `{`, 400, CodeInvalidJSON, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(requestid.Header, "req-123")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get(requestid.Header); got != "req-123" {
+				t.Errorf("%s header = %q, want req-123", requestid.Header, got)
+			}
+			apiErr := decodeError(t, rec)
+			if apiErr.Code != tt.code {
+				t.Errorf("code = %s, want %s", apiErr.Code, tt.code)
+			}
+			if apiErr.RequestID != "req-123" {
+				t.Errorf("request_id = %q, want req-123", apiErr.RequestID)
+			}
+			if tt.field != "" && (len(apiErr.Details) == 0 || apiErr.Details[0].Field != tt.field) {
+				t.Errorf("details = %+v, want field %q", apiErr.Details, tt.field)
+			}
+		})
+	}
+}
+
+func TestValidationListsEveryField(t *testing.T) {
+	h := newTestServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", strings.NewReader(`{"task":" ","priority":-1,"callback_url":"ftp://x"}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	var fields []string
+	for _, d := range decodeError(t, rec).Details {
+		fields = append(fields, d.Field)
+	}
+	if got := strings.Join(fields, ","); got != "task,priority,callback_url" {
+		t.Errorf("details name %s", got)
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	h := newTestServer(t)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(requestid.Header, "bad id with spaces")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	got := rec.Header().Get(requestid.Header)
+	if got == "" || strings.Contains(got, " ") {
+		t.Errorf("%s = %q, want a generated ID", requestid.Header, got)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	h := newTestServer(t)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`skagent_http_requests_total{method="GET",route="/api/v1/agents",status="200"}`,
+		`skagent_agents{status="idle"} 0`,
+		"skagent_task_queue_depth 0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q", want)
+		}
+	}
+}
+
+func TestStatsCountRequests(t *testing.T) {
+	h := newTestServer(t)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/agents/missing", nil))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/stats", nil))
+	var resp struct {
+		Data struct {
+			Stats struct {
+				Uptime   string `json:"uptime"`
+				Requests struct {
+					Total        int64 `json:"total"`
+					ClientErrors int64 `json:"client_errors"`
+				} `json:"requests"`
+				Memory map[string]interface{} `json:"memory"`
+			} `json:"stats"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	stats := resp.Data.Stats
+	if stats.Requests.Total != 2 || stats.Requests.ClientErrors != 1 {
+		t.Errorf("requests = %+v, want 2 total and 1 client error", stats.Requests)
+	}
+	if stats.Uptime == "" || stats.Uptime == "N/A" || stats.Memory["alloc_bytes"] == nil {
+		t.Errorf("stats are not populated: %+v", stats)
+	}
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// agentETag is the entity tag of an agent at a version
+func agentETag(version int64) string {
+	return `"v` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseVersionTag reads the version from an entity tag written by
+// agentETag; weak tags are accepted
+func parseVersionTag(tag string) (int64, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if !strings.HasPrefix(tag, `"v`) || !strings.HasSuffix(tag, `"`) || len(tag) < 4 {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(tag[2:len(tag)-1], 10, 64)
+	if err != nil || v < 1 {
+		return 0, false
+	}
+	return v, true
+}
+
+// ifMatchVersion reads the agent version a write is conditional on from
+// If-Match. "*" matches any version and gives 0. It reports false, having
+// written the error, when the header is missing and required is set, or
+// when it does not hold a single tag of this server.
+func (s *APIServer) ifMatchVersion(w http.ResponseWriter, r *http.Request, required bool) (int64, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch {
+	case header == "":
+		if required {
+			s.writeErrorCode(w, http.StatusPreconditionRequired, CodePreconditionRequired,
+				"updates must send If-Match with the agent's ETag",
+				FieldError{Field: "If-Match", Message: "is required; read the agent to get its ETag"})
+			return 0, false
+		}
+		return 0, true
+	case header == "*":
+		return 0, true
+	}
+	v, ok := parseVersionTag(header)
+	if !ok {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid If-Match header",
+			FieldError{Field: "If-Match", Message: `must be a single ETag such as "v3", or *`})
+		return 0, false
+	}
+	return v, true
+}
+
+// notModified reports whether If-None-Match already names etag, in which
+// case it writes 304
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// writeAgentWriteError reports a failed conditional agent write; a stale
+// one carries the agent's current ETag so that the client can read it
+// again
+func (s *APIServer) writeAgentWriteError(w http.ResponseWriter, agentID string, err error) {
+	if errors.Is(err, agents.ErrVersionConflict) {
+		if agent, ok := s.agentRegistry.GetAgentView(agentID); ok {
+			w.Header().Set("ETag", agentETag(agent.Version))
+		}
+	}
+	s.writeRegistryError(w, err)
+}
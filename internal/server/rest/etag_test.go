@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestAgentETags(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("a", "coder", nil)
+	handler := NewServer(ctx, 0, "localhost", nil, registry).setupRoutes()
+
+	do := func(method, path string, header map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	path := "/api/v1/agents/" + agent.ID
+
+	rec := do(http.MethodGet, path, nil, "")
+	etag := rec.Header().Get("ETag")
+	if etag != `"v1"` || !strings.Contains(rec.Body.String(), `"version":1`) {
+		t.Fatalf("GET: ETag %q, body %s", etag, rec.Body)
+	}
+	if rec := do(http.MethodGet, path, map[string]string{"If-None-Match": etag}, ""); rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: %d", rec.Code)
+	}
+
+	if rec := do(http.MethodPut, path, nil, `{"name":"b"}`); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("PUT without If-Match: %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, path, map[string]string{"If-Match": "v1"}, `{"name":"b"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed If-Match: %d", rec.Code)
+	}
+	rec = do(http.MethodPut, path, map[string]string{"If-Match": etag}, `{"name":"b"}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"v2"` {
+		t.Fatalf("PUT: %d ETag %q %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+
+	rec = do(http.MethodPut, path, map[string]string{"If-Match": etag}, `{"name":"c"}`)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), string(CodeVersionConflict)) {
+		t.Fatalf("stale PUT: %d %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("ETag"); got != `"v2"` {
+		t.Errorf("stale PUT returned ETag %q, want the current one", got)
+	}
+	if a, _ := registry.GetAgent(agent.ID); a.Name != "b" {
+		t.Errorf("stale PUT applied: name %q", a.Name)
+	}
+
+	if rec := do(http.MethodDelete, path, map[string]string{"If-Match": etag}, ""); rec.Code != http.StatusConflict {
+		t.Errorf("stale DELETE: %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, path, map[string]string{"If-Match": "*"}, ""); rec.Code != http.StatusOK {
+		t.Errorf("DELETE If-Match *: %d", rec.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/evaluation"
+	"github.com/go-chi/chi/v5"
+)
+
+// ReviseRequest sends a task back for revision
+type ReviseRequest struct {
+	Feedback string `json:"feedback"`
+}
+
+// SetEvaluator enables POST /tasks/{taskID}/evaluate
+func (s *APIServer) SetEvaluator(e *evaluation.Evaluator) {
+	s.evaluator = e
+}
+
+// handleEvaluateTask scores a completed task's result now, replacing any
+// earlier evaluation
+func (s *APIServer) handleEvaluateTask(w http.ResponseWriter, r *http.Request) {
+	if s.evaluator == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "evaluation is not enabled")
+		return
+	}
+
+	eval, err := s.evaluator.Evaluate(r.Context(), chi.URLParam(r, "taskID"))
+	switch {
+	case errors.Is(err, agents.ErrTaskNotFound), errors.Is(err, agents.ErrTaskNotCompleted):
+		s.writeRegistryError(w, err)
+		return
+	case errors.Is(err, evaluation.ErrNoModel):
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		s.writeProviderError(w, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"evaluation": eval},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleReviseTask sends a completed task back to its agent with feedback
+// on what to change
+func (s *APIServer) handleReviseTask(w http.ResponseWriter, r *http.Request) {
+	var req ReviseRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if details := requireFields(map[string]string{"feedback": strings.TrimSpace(req.Feedback)}); len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "invalid revision", details...)
+		return
+	}
+
+	taskID := chi.URLParam(r, "taskID")
+	if err := s.agentRegistry.ReviseTask(taskID, req.Feedback, cause(r, "")); err != nil {
+		s.writeRegistryError(w, err)
+		return
+	}
+	task, _ := s.agentRegistry.GetTask(taskID)
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"task": task},
+		Message:   "Task sent back for revision",
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,201 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// shapeSpec describes the fields ?fields= and ?expand= may name for one
+// kind of resource
+type shapeSpec struct {
+	// fields are the JSON fields of the resource
+	fields map[string]bool
+	// expandable are the fields left out of a shaped response unless
+	// ?expand= names them: the large ones, and those that ?expand= replaces
+	// with a fuller value
+	expandable map[string]bool
+	// virtual are the names ?expand= adds that the resource does not have
+	virtual map[string]bool
+}
+
+var (
+	agentShape = newShapeSpec(agents.AgentView{}, []string{"config", "stats", "current_task"}, nil)
+	taskShape  = newShapeSpec(agents.Task{}, []string{"result", "artifacts"}, []string{"agent"})
+)
+
+// newShapeSpec reads the fields of v's JSON encoding from its struct tags
+func newShapeSpec(v interface{}, expandable, virtual []string) shapeSpec {
+	spec := shapeSpec{fields: make(map[string]bool), expandable: make(map[string]bool), virtual: make(map[string]bool)}
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			spec.fields[name] = true
+		}
+	}
+	for _, f := range expandable {
+		spec.expandable[f] = true
+	}
+	for _, f := range virtual {
+		spec.virtual[f] = true
+	}
+	return spec
+}
+
+// shape is the selection a request made with ?fields= and ?expand=
+type shape struct {
+	spec   *shapeSpec
+	fields map[string]bool
+	expand map[string]bool
+}
+
+// parse reads ?fields=id,name,status and ?expand=stats,current_task. It
+// returns nil when the request asks for neither, so that the resource is
+// written whole as before. A shaped resource always keeps its id; without
+// ?fields= it holds every field that is not expandable.
+func (spec *shapeSpec) parse(r *http.Request) (*shape, []FieldError) {
+	q := r.URL.Query()
+	if q.Get("fields") == "" && q.Get("expand") == "" {
+		return nil, nil
+	}
+	sh := &shape{spec: spec, expand: make(map[string]bool)}
+	var details []FieldError
+
+	if v := q.Get("fields"); v != "" {
+		sh.fields = map[string]bool{"id": true}
+		var unknown []string
+		for _, f := range splitList(v) {
+			if !spec.fields[f] {
+				unknown = append(unknown, f)
+				continue
+			}
+			sh.fields[f] = true
+		}
+		if len(unknown) > 0 {
+			details = append(details, FieldError{Field: "fields", Message: "unknown field(s) " + strings.Join(unknown, ", ") + "; valid: " + strings.Join(spec.names(spec.fields), ", ")})
+		}
+	}
+
+	var unknown []string
+	for _, f := range splitList(q.Get("expand")) {
+		if !spec.expandable[f] && !spec.virtual[f] {
+			unknown = append(unknown, f)
+			continue
+		}
+		sh.expand[f] = true
+	}
+	if len(unknown) > 0 {
+		valid := spec.names(spec.expandable)
+		valid = append(valid, spec.names(spec.virtual)...)
+		sort.Strings(valid)
+		details = append(details, FieldError{Field: "expand", Message: "cannot expand " + strings.Join(unknown, ", ") + "; valid: " + strings.Join(valid, ", ")})
+	}
+	return sh, details
+}
+
+func (spec *shapeSpec) names(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expands reports whether the request asked to expand name
+func (sh *shape) expands(name string) bool {
+	return sh.expand[name]
+}
+
+// apply keeps the selected fields of v. extra sets the value of expanded
+// fields, replacing the resource's own or adding virtual ones.
+func (sh *shape) apply(v interface{}, extra map[string]interface{}) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]json.RawMessage, len(all))
+	for k, val := range all {
+		keep := sh.fields[k] || sh.expand[k]
+		if sh.fields == nil && !sh.spec.expandable[k] {
+			keep = true
+		}
+		if keep || k == "id" {
+			out[k] = val
+		}
+	}
+	for k, val := range extra {
+		if !sh.expand[k] {
+			continue
+		}
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = encoded
+	}
+	return out, nil
+}
+
+// shapeList applies sh to every item, with the extras extras gives it
+func shapeList[T any](sh *shape, items []T, extras func(*shape, T) map[string]interface{}) ([]map[string]json.RawMessage, error) {
+	out := make([]map[string]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		shaped, err := sh.apply(item, extras(sh, item))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, shaped)
+	}
+	return out, nil
+}
+
+// writeShapeError reports an invalid ?fields= or ?expand=
+func (s *APIServer) writeShapeError(w http.ResponseWriter, details []FieldError) {
+	s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid field selection", details...)
+}
+
+// agentExtras gives an agent's expanded current_task as the whole task
+// rather than a reference
+func (s *APIServer) agentExtras(sh *shape, a agents.AgentView) map[string]interface{} {
+	if !sh.expands("current_task") || a.CurrentTask == nil {
+		return nil
+	}
+	if task, ok := s.agentRegistry.GetTask(a.CurrentTask.ID); ok {
+		return map[string]interface{}{"current_task": task}
+	}
+	return nil
+}
+
+// taskExtras gives a task's expanded agent: the view of the agent it is
+// assigned to
+func (s *APIServer) taskExtras(sh *shape, t *agents.Task) map[string]interface{} {
+	if !sh.expands("agent") || t.AssignedTo == "" {
+		return nil
+	}
+	if agent, ok := s.agentRegistry.GetAgentView(t.AssignedTo); ok {
+		return map[string]interface{}{"agent": agent}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated query value, dropping blanks
+func splitList(v string) []string {
+	var out []string
+	for _, f := range strings.Split(v, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
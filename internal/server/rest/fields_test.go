@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestSparseFieldsets(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	s := NewServer(ctx, 0, "localhost", nil, registry)
+	handler := s.setupRoutes()
+
+	agent, err := registry.CreateAgent("coder-1", "coder", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := registry.CreateTask(&agents.Task{Title: "Fix login"})
+	if err := registry.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) (int, map[string]json.RawMessage) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+	keys := func(raw json.RawMessage) map[string]json.RawMessage {
+		var m map[string]json.RawMessage
+		json.Unmarshal(raw, &m)
+		return m
+	}
+
+	code, data := get("/api/v1/agents?fields=name,status")
+	var list []map[string]json.RawMessage
+	json.Unmarshal(data["agents"], &list)
+	if code != http.StatusOK || len(list) != 1 || len(list[0]) != 3 || list[0]["name"] == nil || list[0]["id"] == nil {
+		t.Fatalf("fields: %d %v", code, list)
+	}
+
+	code, data = get("/api/v1/agents/" + agent.ID + "?expand=current_task")
+	a := keys(data["agent"])
+	if code != http.StatusOK || a["stats"] != nil || a["config"] != nil || a["name"] == nil {
+		t.Fatalf("expand without fields: %d %v", code, a)
+	}
+	if current := keys(a["current_task"]); current["created_at"] == nil {
+		t.Errorf("current_task was not expanded to the task: %s", a["current_task"])
+	}
+
+	code, data = get("/api/v1/tasks/" + task.ID + "?fields=status&expand=agent")
+	if tk := keys(data["task"]); code != http.StatusOK || len(tk) != 3 || keys(tk["agent"])["name"] == nil {
+		t.Errorf("task with agent: %d %v", code, tk)
+	}
+
+	if code, _ := get("/api/v1/tasks?fields=nope"); code != http.StatusBadRequest {
+		t.Errorf("unknown field: %d", code)
+	}
+	if code, _ := get("/api/v1/agents?expand=name"); code != http.StatusBadRequest {
+		t.Errorf("expanding a plain field: %d", code)
+	}
+}
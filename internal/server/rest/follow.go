@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleAgentStream live-tails an agent as server-sent events: an agent
+// event with the agent as it is, then agent events for its changes, task
+// events for those of its tasks and step events for the steps they log,
+// until the client disconnects or the agent is deleted. Prompts, responses
+// and tool outputs are cut to their first line unless ?full=true.
+func (s *APIServer) handleAgentStream(w http.ResponseWriter, r *http.Request) {
+	var full bool
+	if v := r.URL.Query().Get("full"); v != "" {
+		var err error
+		if full, err = strconv.ParseBool(v); err != nil {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid full parameter",
+				FieldError{Field: "full", Message: "must be a boolean"})
+			return
+		}
+	}
+
+	// Subscribe before reading the agent so nothing is lost in between
+	events, cancel := s.agentRegistry.Subscribe(256)
+	defer cancel()
+	var steps <-chan tasklog.Entry
+	if s.taskLog != nil {
+		live, cancelSteps := s.taskLog.Subscribe(256)
+		defer cancelSteps()
+		steps = live
+	}
+
+	agentID := chi.URLParam(r, "agentID")
+	agent, ok := s.agentRegistry.GetAgentView(agentID)
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeAgentNotFound, "agent not found")
+		return
+	}
+
+	flusher, ok := startStream(w)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	send := func(event string, a tasklog.Activity) bool {
+		data, err := json.Marshal(a)
+		if err != nil {
+			return true
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	tail := tasklog.NewTail(agent, full)
+	if !send("agent", tasklog.Activity{Type: tasklog.ActivityAgent, Time: time.Now(), Agent: &agent}) {
+		return
+	}
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		case <-s.closing:
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			a, ok := tail.Event(ev)
+			if !ok {
+				continue
+			}
+			if a.TaskID != "" {
+				if !send("task", a) {
+					return
+				}
+				continue
+			}
+			if view, ok := s.agentRegistry.GetAgentView(agentID); ok {
+				a.Agent = &view
+			}
+			if !send("agent", a) || ev.Type == agents.EventAgentDeleted {
+				return
+			}
+		case e, ok := <-steps:
+			if !ok {
+				return
+			}
+			if a, ok := tail.Step(e); ok && !send("step", a) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/project"
+	"github.com/biodoia/skagent/internal/server/requestid"
+)
+
+// Component states reported by /readyz
+const (
+	componentOK       = "ok"
+	componentFail     = "fail"
+	componentDisabled = "disabled"
+	// componentUnchecked is a component that cannot be probed; it does not
+	// make the server unready
+	componentUnchecked = "unchecked"
+)
+
+// readinessCheckTimeout bounds the provider check of a /readyz request
+const readinessCheckTimeout = 5 * time.Second
+
+// ComponentHealth is the state of one dependency in a readiness report
+type ComponentHealth struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// handleHealthz is the liveness probe: it answers as long as the process
+// can serve requests, whatever the state of its dependencies
+func (s *APIServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"status":         "alive",
+			"uptime_seconds": int64(time.Since(s.startedAt).Seconds()),
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleReadyz is the readiness probe: it answers 503 until the provider
+// is reachable, the registry accepts work and, when enabled, the project
+// manager has been polled successfully
+func (s *APIServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	components := s.readiness(r.Context())
+	ready := true
+	for _, c := range components {
+		if c.Status == componentFail {
+			ready = false
+		}
+	}
+
+	data := map[string]interface{}{
+		"ready":      ready,
+		"components": components,
+	}
+	if ready {
+		s.writeJSON(w, http.StatusOK, APIResponse{
+			Success:   true,
+			Data:      data,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	w.Header().Set("Retry-After", "5")
+	s.writeJSON(w, http.StatusServiceUnavailable, APIResponse{
+		Success:   false,
+		Data:      data,
+		Error:     &APIError{Code: CodeServiceUnavailable, Message: "not ready", RequestID: requestid.FromResponse(w)},
+		Timestamp: time.Now(),
+	})
+}
+
+// readiness checks every dependency the server needs to do useful work
+func (s *APIServer) readiness(ctx context.Context) map[string]ComponentHealth {
+	components := make(map[string]ComponentHealth)
+
+	switch {
+	case s.agentRegistry == nil:
+		components["registry"] = ComponentHealth{Status: componentFail, Message: "not initialized"}
+	case s.agentRegistry.Draining():
+		components["registry"] = ComponentHealth{Status: componentFail, Message: "draining for shutdown"}
+	default:
+		components["registry"] = ComponentHealth{Status: componentOK}
+	}
+
+	if s.engine == nil {
+		components["engine"] = ComponentHealth{Status: componentFail, Message: "not initialized"}
+		components["provider"] = ComponentHealth{Status: componentFail, Message: "no engine"}
+		components["project_manager"] = ComponentHealth{Status: componentDisabled}
+		return components
+	}
+	if s.engine.IsHealthy() {
+		components["engine"] = ComponentHealth{Status: componentOK}
+	} else {
+		components["engine"] = ComponentHealth{Status: componentFail, Message: "provider or tools missing"}
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+	switch checked, err := s.engine.CheckProvider(checkCtx); {
+	case err != nil:
+		components["provider"] = ComponentHealth{Status: componentFail, Message: err.Error()}
+	case !checked:
+		components["provider"] = ComponentHealth{Status: componentUnchecked, Message: "provider cannot be probed"}
+	default:
+		components["provider"] = ComponentHealth{Status: componentOK}
+	}
+
+	components["project_manager"] = ComponentHealth{Status: componentDisabled}
+	if pm := s.engine.GetProjectManager(); pm != nil {
+		b := pm.Backpressure()
+		switch enabled, lastPoll, err := pm.Health(); {
+		case !enabled:
+		case err != nil:
+			components["project_manager"] = ComponentHealth{Status: componentFail, Message: err.Error()}
+		case b.Active:
+			// Polls are skipped on purpose, so an old or missing one is fine
+			components["project_manager"] = ComponentHealth{Status: componentOK,
+				Message: fmt.Sprintf("task intake paused: %d tasks wait for an agent (threshold %d)", b.QueueDepth, b.Threshold)}
+		case lastPoll.IsZero():
+			components["project_manager"] = ComponentHealth{Status: componentFail, Message: "not polled yet"}
+		default:
+			components["project_manager"] = ComponentHealth{Status: componentOK,
+				Message: "last polled " + lastPoll.Format(time.RFC3339)}
+		}
+	}
+	return components
+}
+
+// backpressure is the state of the task queue against the project
+// manager's intake; without the integration nothing is held back
+func (s *APIServer) backpressure() project.Backpressure {
+	if s.engine != nil {
+		if pm := s.engine.GetProjectManager(); pm != nil {
+			if enabled, _, _ := pm.Health(); enabled {
+				return pm.Backpressure()
+			}
+		}
+	}
+	_, tasks := s.agentRegistry.StatusCounts()
+	return project.Backpressure{
+		QueueDepth: tasks[agents.TaskStatusPending] + tasks[agents.TaskStatusQueued],
+		Waiting:    []string{},
+	}
+}
@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/core"
+)
+
+func TestHealthzAndReadyz(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	provider := ai.NewMockProvider()
+	provider.Unreachable = errors.New("API key rejected (status 401)")
+	engine := core.NewEngineWithProvider(ctx, config.DefaultConfig(), registry, provider)
+	handler := NewServer(ctx, 0, "localhost", engine, registry).setupRoutes()
+
+	get := func(path string) (int, APIResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp APIResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: decoding response: %v", path, err)
+		}
+		return rec.Code, resp
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz with a broken provider = %d, want 200", code)
+	}
+
+	code, resp := get("/api/v1/readyz")
+	if code != http.StatusServiceUnavailable || resp.Success || resp.Error == nil || resp.Error.Code != CodeServiceUnavailable {
+		t.Fatalf("/readyz with a broken provider = %d %+v, want 503 %s", code, resp.Error, CodeServiceUnavailable)
+	}
+	components := resp.Data["components"].(map[string]interface{})
+	if p := components["provider"].(map[string]interface{}); p["status"] != componentFail {
+		t.Errorf("provider = %v, want %s", p, componentFail)
+	}
+	if p := components["registry"].(map[string]interface{}); p["status"] != componentOK {
+		t.Errorf("registry = %v, want %s", p, componentOK)
+	}
+	if p := components["project_manager"].(map[string]interface{}); p["status"] != componentDisabled {
+		t.Errorf("project_manager = %v, want %s", p, componentDisabled)
+	}
+
+	// A fresh engine with a working provider is ready until the registry drains
+	engine = core.NewEngineWithProvider(ctx, config.DefaultConfig(), registry, ai.NewMockProvider())
+	handler = NewServer(ctx, 0, "localhost", engine, registry).setupRoutes()
+	if code, resp := get("/readyz"); code != http.StatusOK || resp.Data["ready"] != true {
+		t.Fatalf("/readyz = %d %v, want 200 ready", code, resp.Data)
+	}
+	registry.BeginDrain()
+	if code, _ := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz while draining = %d, want 503", code)
+	}
+}
@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxTransitions bounds a page of GET /tasks/transitions
+const maxTransitions = 1000
+
+// cause credits a change to the caller's API key, or to "api" when
+// authentication is off
+func cause(r *http.Request, reason string) agents.Cause {
+	actor := "api"
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok && principal.Name != "" {
+		actor = principal.Name
+	}
+	return agents.Cause{Actor: actor, Reason: reason}
+}
+
+// handleTaskHistory returns every state transition of a task, oldest
+// first, including those of a deleted task
+func (s *APIServer) handleTaskHistory(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "taskID")
+	history, ok := s.agentRegistry.TaskHistory(taskID)
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"task_id":     taskID,
+			"transitions": history,
+			"count":       len(history),
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleListTransitions pages through the transitions of every task in
+// order. Clients pass the last seq they saw as ?since= to catch up.
+func (s *APIServer) handleListTransitions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var since int64
+	if v := q.Get("since"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid since parameter",
+				FieldError{Field: "since", Message: "must be a non-negative sequence number"})
+			return
+		}
+		since = n
+	}
+	limit := maxTransitions
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTransitions {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid limit parameter",
+				FieldError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(maxTransitions)})
+			return
+		}
+		limit = n
+	}
+
+	transitions := s.agentRegistry.TransitionsSinceIn(since, limit, requestWorkspace(r))
+	next := since
+	if len(transitions) > 0 {
+		next = transitions[len(transitions)-1].Seq
+	}
+	writeList(s, w, http.StatusOK, "transitions", transitions, map[string]interface{}{
+		"count":      len(transitions),
+		"next_since": next,
+	})
+}
+
+// handleTaskRouting explains why a task was assigned to its agent: the
+// agents considered, their scores and the rules they matched. For a
+// pending task it shows what auto-assign would do now.
+func (s *APIServer) handleTaskRouting(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "taskID")
+	decision, ok := s.agentRegistry.Routing(taskID)
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"routing": decision},
+		Timestamp: time.Now(),
+	})
+}
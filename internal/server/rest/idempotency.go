@@ -0,0 +1,194 @@
+package rest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// IdempotencyKeyHeader names the client-chosen key of a POST request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses served from the store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL applies when no TTL is configured
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	maxIdempotencyKeyLen  = 255
+	maxIdempotentBodySize = 1 << 20
+)
+
+// idempotentResponse is a stored response, or a placeholder while the
+// first request with its key is still being handled
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyStore keeps responses by scoped key until they expire
+type idempotencyStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]*idempotentResponse
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &idempotencyStore{
+		ttl:     ttl,
+		entries: make(map[string]*idempotentResponse),
+		now:     time.Now,
+	}
+}
+
+// begin returns the stored entry for key, or reserves the key and returns
+// nil when the caller should handle the request
+func (st *idempotencyStore) begin(key string, fingerprint [sha256.Size]byte) *idempotentResponse {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := st.now()
+	if now.Sub(st.lastSweep) > time.Minute {
+		for k, e := range st.entries {
+			if e.done && now.After(e.expires) {
+				delete(st.entries, k)
+			}
+		}
+		st.lastSweep = now
+	}
+
+	if e, ok := st.entries[key]; ok && !(e.done && now.After(e.expires)) {
+		copied := *e
+		return &copied
+	}
+	st.entries[key] = &idempotentResponse{fingerprint: fingerprint}
+	return nil
+}
+
+// finish stores the response for key; server errors release the key so
+// that a retry is handled again
+func (st *idempotencyStore) finish(key string, status int, header http.Header, body []byte) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	e, ok := st.entries[key]
+	if !ok {
+		return
+	}
+	if status >= 500 {
+		delete(st.entries, key)
+		return
+	}
+	e.done = true
+	e.status = status
+	e.header = header
+	e.body = body
+	e.expires = st.now().Add(st.ttl)
+}
+
+// SetIdempotencyTTL sets how long responses to keyed POST requests are
+// replayed
+func (s *APIServer) SetIdempotencyTTL(ttl time.Duration) {
+	s.idempotency = newIdempotencyStore(ttl)
+}
+
+// idempotencyMiddleware makes POST requests carrying an Idempotency-Key
+// safe to retry: the first response is stored and replayed for later
+// requests with the same key and body. Keys are scoped to the caller and
+// the request path.
+func (s *APIServer) idempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid "+IdempotencyKeyHeader+" header",
+				FieldError{Field: IdempotencyKeyHeader, Message: "must be at most " + strconv.Itoa(maxIdempotencyKeyLen) + " characters"})
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.writeDecodeError(w, err)
+			return
+		}
+		if err != nil {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeBadRequest, "failed to read request body")
+			return
+		}
+		if len(body) > maxIdempotentBodySize {
+			s.writeErrorCode(w, http.StatusRequestEntityTooLarge, CodeBadRequest, "request body too large for an idempotent request")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := ""
+		if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+			scope = principal.Name
+		}
+		storeKey := scope + "\x00" + r.URL.Path + "\x00" + key
+		fingerprint := sha256.Sum256(body)
+
+		if prev := s.idempotency.begin(storeKey, fingerprint); prev != nil {
+			switch {
+			case prev.fingerprint != fingerprint:
+				s.writeErrorCode(w, http.StatusUnprocessableEntity, CodeIdempotencyMismatch,
+					IdempotencyKeyHeader+" was already used with a different request body")
+			case !prev.done:
+				s.writeErrorCode(w, http.StatusConflict, CodeIdempotencyInProgress,
+					"a request with this "+IdempotencyKeyHeader+" is still being processed")
+			default:
+				for k, v := range prev.header {
+					w.Header()[k] = v
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(prev.status)
+				w.Write(prev.body)
+			}
+			return
+		}
+
+		var recorded bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&recorded)
+		defer func() {
+			if p := recover(); p != nil {
+				s.idempotency.finish(storeKey, http.StatusInternalServerError, nil, nil)
+				panic(p)
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			header := http.Header{}
+			if ct := ww.Header().Get("Content-Type"); ct != "" {
+				header.Set("Content-Type", ct)
+			}
+			if loc := ww.Header().Get("Location"); loc != "" {
+				header.Set("Location", loc)
+			}
+			s.idempotency.finish(storeKey, status, header, recorded.Bytes())
+		}()
+		next.ServeHTTP(ww, r)
+	})
+}
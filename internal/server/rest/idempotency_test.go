@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postAgent(t *testing.T, h http.Handler, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/agents", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func agentIDOf(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp struct {
+		Data struct {
+			Agent struct {
+				ID string `json:"id"`
+			} `json:"agent"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return resp.Data.Agent.ID
+}
+
+func TestIdempotencyKeyReplaysResponse(t *testing.T) {
+	h := newTestServer(t)
+	body := `{"name":"a","type":"coder"}`
+
+	first := postAgent(t, h, "k1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d", first.Code)
+	}
+	second := postAgent(t, h, "k1", body)
+	if second.Code != http.StatusCreated || second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("retry: status %d, replayed %q", second.Code, second.Header().Get(IdempotentReplayedHeader))
+	}
+	if a, b := agentIDOf(t, first), agentIDOf(t, second); a == "" || a != b {
+		t.Fatalf("retry created a different agent: %q vs %q", a, b)
+	}
+
+	if other := postAgent(t, h, "k2", body); agentIDOf(t, other) == agentIDOf(t, first) {
+		t.Fatal("a new key replayed the old response")
+	}
+
+	mismatch := postAgent(t, h, "k1", `{"name":"b","type":"coder"}`)
+	if mismatch.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key with another body: status %d", mismatch.Code)
+	}
+	if got := decodeError(t, mismatch).Code; got != CodeIdempotencyMismatch {
+		t.Fatalf("code = %s", got)
+	}
+}
+
+func TestIdempotencyStoreExpiry(t *testing.T) {
+	st := newIdempotencyStore(time.Hour)
+	now := time.Now()
+	st.now = func() time.Time { return now }
+
+	var fp [32]byte
+	if st.begin("k", fp) != nil {
+		t.Fatal("new key was not reserved")
+	}
+	if prev := st.begin("k", fp); prev == nil || prev.done {
+		t.Fatal("in-flight key was not reported")
+	}
+	st.finish("k", http.StatusCreated, nil, []byte("ok"))
+	if prev := st.begin("k", fp); prev == nil || string(prev.body) != "ok" {
+		t.Fatal("stored response was not returned")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if st.begin("k", fp) != nil {
+		t.Fatal("expired key was not released")
+	}
+
+	st.finish("k", http.StatusInternalServerError, nil, nil)
+	if st.begin("k", fp) != nil {
+		t.Fatal("server error response was kept for replay")
+	}
+}
@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/lessons"
+	"github.com/go-chi/chi/v5"
+)
+
+// LessonRequest is the body of POST /agents/{agentID}/lessons
+type LessonRequest struct {
+	// Kind is "success" (the default) or "pitfall"
+	Kind   string   `json:"kind,omitempty"`
+	Text   string   `json:"text"`
+	Labels []string `json:"labels,omitempty"`
+	TaskID string   `json:"task_id,omitempty"`
+}
+
+// SetLessons enables the lesson routes of agents and tasks
+func (s *APIServer) SetLessons(store *lessons.Store) {
+	s.lessons = store
+}
+
+// requireLessons writes 503 when lessons are not enabled
+func (s *APIServer) requireLessons(w http.ResponseWriter) bool {
+	if s.lessons == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "lessons are not enabled")
+		return false
+	}
+	return true
+}
+
+// handleListLessons returns an agent's lessons, newest first, or with ?q=
+// the ones most relevant to q, best first (?limit= of them)
+func (s *APIServer) handleListLessons(w http.ResponseWriter, r *http.Request) {
+	if !s.requireLessons(w) {
+		return
+	}
+	agentID := chi.URLParam(r, "agentID")
+	if _, ok := s.agentRegistry.GetAgent(agentID); !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeAgentNotFound, "agent not found")
+		return
+	}
+
+	q := r.URL.Query()
+	if query := q.Get("q"); query != "" {
+		limit := 0
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid limit parameter",
+					FieldError{Field: "limit", Message: "must be a positive integer"})
+				return
+			}
+			limit = n
+		}
+		ranked := s.lessons.Relevant(agentID, query, q["label"], limit)
+		writeList(s, w, http.StatusOK, "lessons", ranked, map[string]interface{}{"count": len(ranked)})
+		return
+	}
+
+	list := s.lessons.List(agentID)
+	writeList(s, w, http.StatusOK, "lessons", list, map[string]interface{}{"count": len(list)})
+}
+
+// handleCreateLesson records a lesson an operator or a worker wrote
+func (s *APIServer) handleCreateLesson(w http.ResponseWriter, r *http.Request) {
+	if !s.requireLessons(w) {
+		return
+	}
+	agentID := chi.URLParam(r, "agentID")
+	if _, ok := s.agentRegistry.GetAgent(agentID); !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeAgentNotFound, "agent not found")
+		return
+	}
+
+	var req LessonRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	details := requireFields(map[string]string{"text": req.Text})
+	kind := lessons.Kind(req.Kind)
+	if kind != "" && kind != lessons.KindSuccess && kind != lessons.KindPitfall {
+		details = append(details, FieldError{Field: "kind", Message: `must be "success" or "pitfall"`})
+	}
+	if len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "invalid lesson", details...)
+		return
+	}
+
+	lesson, err := s.lessons.Add(lessons.Lesson{
+		AgentID: agentID,
+		TaskID:  req.TaskID,
+		Kind:    kind,
+		Text:    req.Text,
+		Labels:  req.Labels,
+	})
+	if err != nil {
+		s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"lesson": lesson},
+		Message:   "Lesson recorded",
+		Timestamp: time.Now(),
+	})
+}
+
+// handleDeleteLesson forgets a lesson
+func (s *APIServer) handleDeleteLesson(w http.ResponseWriter, r *http.Request) {
+	if !s.requireLessons(w) {
+		return
+	}
+	err := s.lessons.Delete(chi.URLParam(r, "agentID"), chi.URLParam(r, "lessonID"))
+	switch {
+	case errors.Is(err, lessons.ErrNotFound):
+		s.writeErrorCode(w, http.StatusNotFound, CodeNotFound, "lesson not found")
+		return
+	case err != nil:
+		s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Message:   "Lesson deleted",
+		Timestamp: time.Now(),
+	})
+}
+
+// handleTaskLessons returns the lessons to add to a task's prompt, from
+// the agent it is assigned to or ?agent_id=, with the prompt text itself.
+// The task's outcome is credited to these lessons when it finishes.
+func (s *APIServer) handleTaskLessons(w http.ResponseWriter, r *http.Request) {
+	if !s.requireLessons(w) {
+		return
+	}
+	task, ok := s.agentRegistry.GetTask(chi.URLParam(r, "taskID"))
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	}
+	agentID := r.URL.Query().Get("agent_id")
+	if agentID == "" && task.AssignedTo == "" {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "task is not assigned; pass agent_id",
+			FieldError{Field: "agent_id", Message: "is required for an unassigned task"})
+		return
+	}
+
+	ranked := s.lessons.ForTask(task, agentID)
+	if ranked == nil {
+		ranked = []lessons.Ranked{}
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"task_id": task.ID,
+			"lessons": ranked,
+			"prompt":  lessons.Prompt(ranked),
+		},
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,152 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/redact"
+)
+
+// parseLogFilter builds a ring filter from ?level=, ?since=, ?component=
+// and ?limit= query parameters. since accepts RFC 3339 timestamps or a
+// duration relative to now (e.g. 15m).
+func parseLogFilter(r *http.Request) (logging.Filter, error) {
+	q := r.URL.Query()
+	var f logging.Filter
+
+	level, err := logging.ParseLevel(q.Get("level"))
+	if err != nil {
+		return f, err
+	}
+	if q.Get("level") == "" {
+		level = logging.LevelDebug
+	}
+	f.MinLevel = level
+	f.Component = q.Get("component")
+
+	if since := q.Get("since"); since != "" {
+		if t, err := time.Parse(time.RFC3339, since); err == nil {
+			f.Since = t
+		} else if d, err := time.ParseDuration(since); err == nil {
+			f.Since = time.Now().Add(-d)
+		} else {
+			return f, fmt.Errorf("invalid since: %q (use RFC 3339 or a duration like 15m)", since)
+		}
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("invalid limit: %q", limit)
+		}
+		f.Limit = n
+	}
+
+	return f, nil
+}
+
+func (s *APIServer) handleGetLogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	if isStreamingRequest(r) {
+		s.followLogs(w, r, filter)
+		return
+	}
+
+	logs := s.logs.Query(filter)
+	for i := range logs {
+		logs[i].Message = redact.String(logs[i].Message)
+	}
+	response := APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"logs":     logs,
+			"count":    len(logs),
+			"buffered": s.logs.Len(),
+		},
+		Timestamp: time.Now(),
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// followLogs streams the matching backlog and then live entries as
+// server-sent events until the client disconnects. Clients resuming with
+// Last-Event-ID only receive entries after that sequence number.
+func (s *APIServer) followLogs(w http.ResponseWriter, r *http.Request, filter logging.Filter) {
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		if seq, err := strconv.ParseUint(last, 10, 64); err == nil {
+			filter.AfterSeq = seq
+		}
+	}
+
+	// Subscribe before reading the backlog so nothing is lost in between
+	live, cancel := s.logs.Subscribe(256)
+	defer cancel()
+
+	flusher, ok := startStream(w)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	send := func(e logging.Entry) bool {
+		e.Message = redact.String(e.Message)
+		data, err := json.Marshal(e)
+		if err != nil {
+			return true
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", e.Seq, data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	var lastSeq uint64
+	for _, e := range s.logs.Query(filter) {
+		if !send(e) {
+			return
+		}
+		lastSeq = e.Seq
+	}
+
+	// Only the backlog is limited
+	filter.Limit = 0
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		case <-s.closing:
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-live:
+			if !ok {
+				return
+			}
+			if e.Seq <= lastSeq || !filter.Match(e) {
+				continue
+			}
+			if !send(e) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/mcpclient"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetMCPClients enables /system/mcp-servers, the external MCP servers
+// whose tools agents use
+func (s *APIServer) SetMCPClients(m *mcpclient.Manager) {
+	s.mcpClients = m
+}
+
+// handleListMCPServers lists the configured MCP servers with their tools
+func (s *APIServer) handleListMCPServers(w http.ResponseWriter, r *http.Request) {
+	if s.mcpClients == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "external MCP servers are not available")
+		return
+	}
+	servers := s.mcpClients.Servers()
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"servers": servers, "count": len(servers)},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleEnableMCPServer connects to a configured MCP server and adds its
+// tools
+func (s *APIServer) handleEnableMCPServer(w http.ResponseWriter, r *http.Request) {
+	s.setMCPServer(w, r, true)
+}
+
+// handleDisableMCPServer disconnects from a configured MCP server and
+// removes its tools
+func (s *APIServer) handleDisableMCPServer(w http.ResponseWriter, r *http.Request) {
+	s.setMCPServer(w, r, false)
+}
+
+func (s *APIServer) setMCPServer(w http.ResponseWriter, r *http.Request, enable bool) {
+	if s.mcpClients == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "external MCP servers are not available")
+		return
+	}
+	name := chi.URLParam(r, "name")
+	var (
+		server mcpclient.ServerStatus
+		err    error
+	)
+	if enable {
+		server, err = s.mcpClients.Enable(r.Context(), name)
+	} else {
+		server, err = s.mcpClients.Disable(name)
+	}
+	switch {
+	case errors.Is(err, mcpclient.ErrServerNotFound):
+		s.writeErrorCode(w, http.StatusNotFound, CodeNotFound, "MCP server not found: "+name)
+		return
+	case err != nil:
+		s.writeErrorCode(w, http.StatusBadGateway, CodeMCPServerUnavailable, "failed to connect to MCP server "+name+": "+err.Error())
+		return
+	}
+	message := "MCP server disabled"
+	if enable {
+		message = "MCP server enabled"
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"server": server},
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/mcpclient"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+func TestMCPServers(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(ctx, 0, "localhost", nil, agents.NewRegistry(ctx))
+	handler := server.setupRoutes()
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/api/v1/system/mcp-servers"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("without clients: %d", rec.Code)
+	}
+
+	m := mcpclient.ConnectAll(ctx, []config.MCPServerConfig{
+		{Name: "files", Namespace: "fs", URL: "http://127.0.0.1:1/sse", Timeout: 1, Disabled: true},
+	}, tools.NewToolManager())
+	defer m.Close(ctx, false)
+	server.SetMCPClients(m)
+
+	rec := do(http.MethodGet, "/api/v1/system/mcp-servers")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"namespace":"fs"`) || !strings.Contains(rec.Body.String(), `"enabled":false`) {
+		t.Fatalf("GET: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/api/v1/system/mcp-servers/files/enable"); rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), string(CodeMCPServerUnavailable)) {
+		t.Errorf("enable an unreachable server: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/api/v1/system/mcp-servers/files/disable"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"connected":false`) {
+		t.Errorf("disable: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/api/v1/system/mcp-servers/missing/enable"); rec.Code != http.StatusNotFound {
+		t.Errorf("enable a missing server: %d", rec.Code)
+	}
+}
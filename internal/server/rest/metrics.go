@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var (
+	httpRequests = metrics.Default.NewCounter("skagent_http_requests_total",
+		"REST API requests, by method, route pattern and status code.", "method", "route", "status")
+	httpDuration = metrics.Default.NewHistogram("skagent_http_request_duration_seconds",
+		"REST API request latency, by method and route pattern.", metrics.DefBuckets, "method", "route")
+)
+
+// metricsMiddleware records request counts and latencies. Requests are
+// labelled with their route pattern rather than the raw path to keep the
+// number of series bounded.
+func (s *APIServer) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		if isStreamingRequest(r) {
+			return
+		}
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		httpRequests.Inc(r.Method, route, strconv.Itoa(status))
+		s.requests.record(r.Method, route, status)
+		httpDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
+
+// handleMetrics serves process metrics plus a snapshot of the agent
+// registry in the Prometheus text format
+func (s *APIServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	snapshot := metrics.NewRegistry()
+	agentsByStatus := snapshot.NewGauge("skagent_agents", "Registered agents, by status.", "status")
+	tasksByStatus := snapshot.NewGauge("skagent_tasks", "Tasks in the registry, by status.", "status")
+	queueDepth := snapshot.NewGauge("skagent_task_queue_depth", "Tasks waiting for an agent (pending or queued).")
+	inFlight := snapshot.NewGauge("skagent_tasks_in_flight", "Tasks queued on or running in agents.")
+	draining := snapshot.NewGauge("skagent_draining", "1 while the registry drains for shutdown.")
+
+	agentCounts, taskCounts := s.agentRegistry.StatusCounts()
+	for _, st := range []agents.AgentStatus{agents.StatusIdle, agents.StatusWorking, agents.StatusPaused, agents.StatusError, agents.StatusOffline} {
+		agentsByStatus.Set(float64(agentCounts[st]), string(st))
+	}
+	for _, st := range []agents.TaskStatus{agents.TaskStatusPending, agents.TaskStatusQueued, agents.TaskStatusInProgress,
+		agents.TaskStatusCompleted, agents.TaskStatusFailed, agents.TaskStatusCancelled} {
+		tasksByStatus.Set(float64(taskCounts[st]), string(st))
+	}
+	queueDepth.Set(float64(taskCounts[agents.TaskStatusPending] + taskCounts[agents.TaskStatusQueued]))
+	inFlight.Set(float64(taskCounts[agents.TaskStatusQueued] + taskCounts[agents.TaskStatusInProgress]))
+	if s.agentRegistry.Draining() {
+		draining.Set(1)
+	} else {
+		draining.Set(0)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := metrics.Default.WriteText(w); err != nil {
+		s.logger.Printf("ERROR: Error writing metrics: %v", err)
+		return
+	}
+	if err := snapshot.WriteText(w); err != nil {
+		s.logger.Printf("ERROR: Error writing metrics: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// isStreamingRequest reports whether a request holds its connection open
+// (SSE, follow mode, an agent's live tail, a streamed chat reply or a long
+// poll on a task) and must not be cut off by the request timeout
+func isStreamingRequest(r *http.Request) bool {
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return true
+	}
+	if strings.HasSuffix(r.URL.Path, "/chat/stream") {
+		return true
+	}
+	if strings.Contains(r.URL.Path, "/agents/") && strings.HasSuffix(r.URL.Path, "/stream") {
+		return true
+	}
+	if strings.Contains(r.URL.Path, "/tasks/") && strings.HasSuffix(r.URL.Path, "/wait") {
+		return true
+	}
+	follow := r.URL.Query().Get("follow")
+	return follow == "1" || follow == "true"
+}
+
+// DefaultTimeout is the server read and write timeout when none is set
+const DefaultTimeout = 30 * time.Second
+
+// SetTimeouts sets the server's read and write timeouts; zero keeps the
+// default. Requests are cut off after the write timeout. A running server
+// picks up the new request timeout at once; the socket timeouts apply from
+// the next Start.
+func (s *APIServer) SetTimeouts(read, write time.Duration) {
+	if read <= 0 {
+		read = DefaultTimeout
+	}
+	if write <= 0 {
+		write = DefaultTimeout
+	}
+	s.readTimeout.Store(int64(read))
+	s.writeTimeout.Store(int64(write))
+}
+
+// timeoutMiddleware applies the request timeout to everything except
+// streaming requests
+func (s *APIServer) timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isStreamingRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		middleware.Timeout(time.Duration(s.writeTimeout.Load()))(next).ServeHTTP(w, r)
+	})
+}
+
+// SetMaxBodySize bounds request bodies, after decompression, to n bytes;
+// 0 uses bodylimit.DefaultLimit. It must be called before the server
+// starts.
+func (s *APIServer) SetMaxBodySize(n int64) {
+	s.maxBodySize = n
+}
+
+// startStream prepares a response for server-sent events, lifting the
+// server write deadline for this connection
+func startStream(w http.ResponseWriter) (http.Flusher, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	// Ignore the error: not every writer supports deadlines
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return flusher, true
+}
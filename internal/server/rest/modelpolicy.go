@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/modelpolicy"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetModelPolicy enables GET /tasks/{taskID}/model
+func (s *APIServer) SetModelPolicy(p *modelpolicy.Policy) {
+	s.modelPolicy = p
+}
+
+// handleTaskModel explains which model the policy picks for a task's next
+// attempt. ?escalation=n asks for the pick after n failures.
+func (s *APIServer) handleTaskModel(w http.ResponseWriter, r *http.Request) {
+	if s.modelPolicy == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "model policy is not enabled")
+		return
+	}
+	task, ok := s.agentRegistry.GetTask(chi.URLParam(r, "taskID"))
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	}
+	escalation := 0
+	if v := r.URL.Query().Get("escalation"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid escalation parameter",
+				FieldError{Field: "escalation", Message: "must be a non-negative integer"})
+			return
+		}
+		escalation = n
+	}
+
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"task_id":  task.ID,
+			"decision": s.modelPolicy.Escalate(task, escalation),
+			"tiers":    s.modelPolicy.Tiers(),
+		},
+		Timestamp: time.Now(),
+	})
+}
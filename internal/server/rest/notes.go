@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/go-chi/chi/v5"
+)
+
+// NotesRequest is the body of PUT /agents/{agentID}/notes
+type NotesRequest struct {
+	// Text replaces the notes; empty clears them
+	Text string `json:"text"`
+	// Author signs the new version, such as the ID of the agent writing
+	// it; by default it is the caller's API key name or "api"
+	Author string `json:"author,omitempty"`
+}
+
+// handleGetAgentNotes returns an agent's notes with their history
+func (s *APIServer) handleGetAgentNotes(w http.ResponseWriter, r *http.Request) {
+	agentID := chi.URLParam(r, "agentID")
+	agent, ok := s.agentRegistry.GetAgent(agentID)
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeAgentNotFound, "agent not found")
+		return
+	}
+	w.Header().Set("ETag", agentETag(agent.Version))
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"agent_id": agentID,
+			"notes":    agents.AgentNotes(agent),
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleSetAgentNotes replaces an agent's notes, keeping the previous text
+// in their history. If-Match is optional: agents update their own notes as
+// they work, without reading them first.
+func (s *APIServer) handleSetAgentNotes(w http.ResponseWriter, r *http.Request) {
+	agentID := chi.URLParam(r, "agentID")
+	version, ok := s.ifMatchVersion(w, r, false)
+	if !ok {
+		return
+	}
+	var req NotesRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	author := req.Author
+	if author == "" {
+		author = cause(r, "").Actor
+	}
+
+	agent, err := s.agentRegistry.SetAgentNotes(agentID, version, req.Text, author)
+	var fe *agents.FieldError
+	switch {
+	case errors.As(err, &fe):
+		s.writeErrorCode(w, http.StatusUnprocessableEntity, CodeValidationFailed, "invalid notes",
+			FieldError{Field: fe.Field, Message: fe.Message})
+		return
+	case err != nil:
+		s.writeAgentWriteError(w, agentID, err)
+		return
+	}
+
+	w.Header().Set("ETag", agentETag(agent.Version))
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"agent_id": agentID,
+			"notes":    agents.AgentNotes(agent),
+		},
+		Message:   "Notes updated",
+		Timestamp: time.Now(),
+	})
+}
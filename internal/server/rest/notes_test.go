@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestAgentNotes(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("a", "coder", nil)
+	handler := NewServer(ctx, 0, "localhost", nil, registry).setupRoutes()
+
+	do := func(method, path string, header map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	notesOf := func(rec *httptest.ResponseRecorder) agents.Notes {
+		t.Helper()
+		var resp struct {
+			Data struct {
+				Notes agents.Notes `json:"notes"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("body %s: %v", rec.Body, err)
+		}
+		return resp.Data.Notes
+	}
+	path := "/api/v1/agents/" + agent.ID + "/notes"
+
+	rec := do(http.MethodGet, path, nil, "")
+	if rec.Code != http.StatusOK || notesOf(rec).Text != "" || !strings.Contains(rec.Body.String(), `"history":[]`) {
+		t.Fatalf("GET empty notes: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodPut, path, nil, `{"text":"flaky VPN, retry uploads"}`)
+	if notes := notesOf(rec); rec.Code != http.StatusOK || notes.Text != "flaky VPN, retry uploads" || notes.UpdatedBy != "api" {
+		t.Fatalf("PUT: %d %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("ETag") != `"v2"` {
+		t.Errorf("ETag = %q, want \"v2\"", rec.Header().Get("ETag"))
+	}
+
+	rec = do(http.MethodPut, path, map[string]string{"If-Match": `"v2"`}, `{"text":"VPN fixed","author":"`+agent.ID+`"}`)
+	notes := notesOf(rec)
+	if rec.Code != http.StatusOK || notes.UpdatedBy != agent.ID || len(notes.History) != 1 || notes.History[0].Text != "flaky VPN, retry uploads" {
+		t.Fatalf("PUT with If-Match: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPut, path, map[string]string{"If-Match": `"v2"`}, `{"text":"stale"}`); rec.Code != http.StatusConflict {
+		t.Errorf("stale If-Match: %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/v1/agents/"+agent.ID, nil, ""); !strings.Contains(rec.Body.String(), `"notes":"VPN fixed"`) {
+		t.Errorf("the agent's meta lacks the notes: %s", rec.Body)
+	}
+
+	if rec := do(http.MethodPut, path, nil, `{"text":"`+strings.Repeat("x", 20<<10)+`"}`); rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"field":"text"`) {
+		t.Errorf("long notes: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPut, "/api/v1/agents/missing/notes", nil, `{"text":"x"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("missing agent: %d", rec.Code)
+	}
+}
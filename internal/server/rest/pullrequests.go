@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/pullrequest"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetPullRequests enables POST /tasks/{taskID}/pull-request
+func (s *APIServer) SetPullRequests(w *pullrequest.Workflow) {
+	s.pullRequests = w
+}
+
+// handleOpenPullRequest commits the changes in a task's workspace to a new
+// branch and opens a pull request for them. The body is optional and may
+// override the workspace, base branch, title and draft flag.
+func (s *APIServer) handleOpenPullRequest(w http.ResponseWriter, r *http.Request) {
+	if s.pullRequests == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "pull requests are not enabled")
+		return
+	}
+	var opts pullrequest.Options
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &opts); err != nil {
+			s.writeDecodeError(w, err)
+			return
+		}
+	}
+
+	pr, err := s.pullRequests.Open(r.Context(), chi.URLParam(r, "taskID"), opts)
+	switch {
+	case errors.Is(err, agents.ErrTaskNotFound):
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	case errors.Is(err, pullrequest.ErrExists), errors.Is(err, pullrequest.ErrNoChanges):
+		s.writeErrorCode(w, http.StatusConflict, CodeConflict, err.Error())
+		return
+	case errors.Is(err, pullrequest.ErrNoWorkspace):
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, err.Error(),
+			FieldError{Field: "workspace", Message: "is required"})
+		return
+	case err != nil:
+		s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, "opening the pull request: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Location", pr.URL)
+	s.writeJSON(w, http.StatusCreated, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"pull_request": pr},
+		Message:   "Pull request opened",
+		Timestamp: time.Now(),
+	})
+}
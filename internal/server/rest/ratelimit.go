@@ -0,0 +1,123 @@
+package rest
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/auth"
+)
+
+// rateLimiter gives every client a token bucket holding a minute's worth
+// of requests, refilled continuously
+type rateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket)}
+}
+
+// setLimit changes the limit; 0 disables limiting. Buckets keep their
+// tokens, capped at the new limit.
+func (l *rateLimiter) setLimit(perMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.perMinute = perMinute
+	for _, b := range l.buckets {
+		b.tokens = math.Min(b.tokens, float64(perMinute))
+	}
+}
+
+// allow takes a token from the client's bucket. It returns the limit, the
+// tokens left and, when the request is refused, how long until the next
+// token.
+func (l *rateLimiter) allow(client string, now time.Time) (limit, remaining int, retryAfter time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perMinute <= 0 {
+		return 0, 0, 0, true
+	}
+	limit = l.perMinute
+	rate := float64(limit) / 60 // tokens per second
+
+	l.sweep(now, rate)
+	b, found := l.buckets[client]
+	if !found {
+		b = &bucket{tokens: float64(limit), last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return limit, 0, wait, false
+	}
+	b.tokens--
+	return limit, int(b.tokens), 0, true
+}
+
+// sweep forgets clients whose buckets have refilled, so that the map does
+// not grow with every address ever seen
+func (l *rateLimiter) sweep(now time.Time, rate float64) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= float64(l.perMinute) {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// SetRateLimit limits every client to perMinute requests a minute; 0
+// removes the limit. It can be called while the server runs.
+func (s *APIServer) SetRateLimit(perMinute int) {
+	s.rateLimit.setLimit(perMinute)
+}
+
+// rateLimitKey identifies the client a request counts against: its API key
+// when authenticated, otherwise its address
+func rateLimitKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok && principal.Name != "" {
+		return "key:" + principal.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimitMiddleware refuses requests over the client's limit with 429
+// and reports the limit in X-RateLimit-* headers
+func (s *APIServer) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, remaining, retryAfter, ok := s.rateLimit.allow(rateLimitKey(r), time.Now())
+		if limit > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		}
+		if !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			s.writeErrorCode(w, http.StatusTooManyRequests, CodeRateLimited,
+				"rate limit of "+strconv.Itoa(limit)+" requests per minute exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+)
+
+// handleGetReconciliation reports how the project manager's tasks were
+// imported: tasks created, deliveries merged and duplicates cancelled
+func (s *APIServer) handleGetReconciliation(w http.ResponseWriter, r *http.Request) {
+	projectManager := s.engine.GetProjectManager()
+	if projectManager == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeProjectManagerUnavailable, "project manager not available")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"reconciliation": projectManager.Reconciliation()},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleReconcile sweeps the registry for duplicate imported tasks now
+// rather than after the next poll
+func (s *APIServer) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	projectManager := s.engine.GetProjectManager()
+	if projectManager == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeProjectManagerUnavailable, "project manager not available")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"reconciliation": projectManager.Reconcile()},
+		Message:   "reconciliation complete",
+		Timestamp: time.Now(),
+	})
+}
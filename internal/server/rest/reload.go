@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// ConfigReloader re-reads the configuration file and applies what can
+// change without a restart
+type ConfigReloader interface {
+	ReloadConfig() (*ReloadResult, error)
+}
+
+// ReloadResult describes what a configuration reload changed
+type ReloadResult struct {
+	// Changed lists the config paths whose values differ from the running
+	// configuration; secrets are listed but not shown
+	Changed []string `json:"changed"`
+	// Applied are the changes now in effect
+	Applied []string `json:"applied"`
+	// RestartRequired are the changes that take effect on the next start
+	RestartRequired []string `json:"restart_required"`
+	// Provider is the provider serving completions after the reload
+	Provider string `json:"provider"`
+	// ProviderReplaced is set when the provider was re-created
+	ProviderReplaced bool `json:"provider_replaced"`
+	// ProviderError is why a degraded engine still has no provider
+	ProviderError string `json:"provider_error,omitempty"`
+}
+
+// SetConfigReloader enables POST /system/config/reload
+func (s *APIServer) SetConfigReloader(r ConfigReloader) {
+	s.reloader = r
+}
+
+// handleReloadConfig re-reads the configuration file. An invalid file is
+// rejected and leaves the running configuration untouched.
+func (s *APIServer) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if s.reloader == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "config reload is not available in this mode")
+		return
+	}
+
+	result, err := s.reloader.ReloadConfig()
+	if err != nil {
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			details := make([]FieldError, len(invalid.Problems))
+			for i, p := range invalid.Problems {
+				details[i] = FieldError{Field: "config", Message: p}
+			}
+			s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "configuration file is invalid", details...)
+			return
+		}
+		s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, "reloading configuration: "+err.Error())
+		return
+	}
+
+	message := "Configuration reloaded"
+	if len(result.Changed) == 0 {
+		message = "Configuration unchanged"
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"reload": result,
+		},
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+}
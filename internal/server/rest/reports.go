@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/digest"
+)
+
+// SetDigest enables GET /reports/daily
+func (s *APIServer) SetDigest(d *digest.Digest) {
+	s.digest = d
+}
+
+// handleDailyReport returns the latest daily digest. The first request
+// before any is scheduled, and ?refresh=true, build one now.
+func (s *APIServer) handleDailyReport(w http.ResponseWriter, r *http.Request) {
+	if s.digest == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "the daily digest is not enabled")
+		return
+	}
+	refresh := false
+	if v := r.URL.Query().Get("refresh"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid refresh parameter",
+				FieldError{Field: "refresh", Message: "must be a boolean"})
+			return
+		}
+		refresh = parsed
+	}
+
+	report := s.digest.Latest()
+	if report == nil || refresh {
+		report = s.digest.Generate(r.Context())
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"report": report},
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/digest"
+)
+
+func TestDailyReport(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	server := NewServer(ctx, 0, "localhost", nil, registry)
+	handler := server.setupRoutes()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/api/v1/reports/daily"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("disabled digest: %d", rec.Code)
+	}
+
+	server.SetDigest(digest.New(config.DigestConfig{}, registry, nil, ""))
+	registry.CreateTask(&agents.Task{Title: "queued"})
+	rec := get("/api/v1/reports/daily")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"created":1`) {
+		t.Fatalf("GET: %d %s", rec.Code, rec.Body)
+	}
+
+	// The latest digest is served until a refresh
+	registry.CreateTask(&agents.Task{Title: "another"})
+	if rec := get("/api/v1/reports/daily"); !strings.Contains(rec.Body.String(), `"created":1`) {
+		t.Errorf("cached digest: %s", rec.Body)
+	}
+	if rec := get("/api/v1/reports/daily?refresh=true"); !strings.Contains(rec.Body.String(), `"created":2`) {
+		t.Errorf("refreshed digest: %s", rec.Body)
+	}
+	if rec := get("/api/v1/reports/daily?refresh=maybe"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid refresh: %d", rec.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/review"
+)
+
+// SetReview enables POST /github/webhook, which turns GitHub pull_request
+// events into review tasks
+func (s *APIServer) SetReview(p *review.Pipeline) {
+	s.review = p
+}
+
+// handleGitHubWebhook receives GitHub webhook deliveries. The route takes
+// no API key: a delivery is accepted only with an X-Hub-Signature-256
+// matching the configured secret.
+func (s *APIServer) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.review == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "pull request review is not enabled")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if !s.review.Verify(body, r.Header.Get("X-Hub-Signature-256")) {
+		s.writeErrorCode(w, http.StatusUnauthorized, CodeUnauthorized, "missing or invalid X-Hub-Signature-256")
+		return
+	}
+
+	delivery, err := s.review.Receive(r.Header.Get("X-GitHub-Event"), body)
+	if err != nil {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidJSON, err.Error())
+		return
+	}
+
+	status, message := http.StatusOK, "Event ignored"
+	data := map[string]interface{}{"delivery": r.Header.Get("X-GitHub-Delivery")}
+	switch {
+	case delivery.Ignored != "":
+		data["ignored"] = delivery.Ignored
+	case delivery.Duplicate:
+		data["task"] = delivery.Task
+		message = "Commit already has a review task"
+	default:
+		data["task"] = delivery.Task
+		status, message = http.StatusAccepted, "Review task created"
+	}
+	s.writeJSON(w, status, APIResponse{
+		Success:   true,
+		Data:      data,
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+}
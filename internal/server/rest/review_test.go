@@ -0,0 +1,50 @@
+package rest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/review"
+)
+
+func TestGitHubWebhook(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	s := NewServer(ctx, 0, "localhost", nil, registry)
+	s.SetReview(review.New(config.ReviewConfig{WebhookSecret: "s3cret"}, registry, nil, nil))
+	handler := s.setupRoutes()
+
+	deliver := func(event, body, secret string) *httptest.ResponseRecorder {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/github/webhook", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	opened := `{"action":"opened","number":3,"pull_request":{"title":"Fix","head":{"sha":"0123abc"}},"repository":{"full_name":"o/r"}}`
+	if rec := deliver("pull_request", opened, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: %d %s", rec.Code, rec.Body)
+	}
+	if rec := deliver("ping", `{"zen":"Keep it logically awesome."}`, "s3cret"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"ignored"`) {
+		t.Errorf("ping: %d %s", rec.Code, rec.Body)
+	}
+	rec := deliver("pull_request", opened, "s3cret")
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"labels":["review"]`) {
+		t.Fatalf("pull_request: %d %s", rec.Code, rec.Body)
+	}
+	if rec := deliver("pull_request", opened, "s3cret"); rec.Code != http.StatusOK || len(registry.ListTasks()) != 1 {
+		t.Errorf("redelivery: %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,478 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/core"
+	"github.com/go-chi/chi/v5"
+)
+
+// SessionRequest sets the metadata of a session; every field is optional
+type SessionRequest struct {
+	Title      string            `json:"title,omitempty"`
+	Autonomous bool              `json:"autonomous,omitempty"`
+	AgentID    string            `json:"agent_id,omitempty"`
+	ProjectID  string            `json:"project_id,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Custom     map[string]string `json:"custom,omitempty"`
+}
+
+func (req SessionRequest) meta() core.SessionMeta {
+	return core.SessionMeta{
+		Title:      req.Title,
+		Autonomous: req.Autonomous,
+		AgentID:    req.AgentID,
+		ProjectID:  req.ProjectID,
+		Tags:       req.Tags,
+		Custom:     req.Custom,
+	}
+}
+
+// SessionMessageRequest is a user turn sent to a session
+type SessionMessageRequest struct {
+	Content string `json:"content"`
+	// Autonomous runs the turn with the autonomous SpecKit prompt
+	Autonomous bool `json:"autonomous,omitempty"`
+}
+
+// sessionSummary is the list view of a session, without its messages
+type sessionSummary struct {
+	ID           string           `json:"id"`
+	Workspace    string           `json:"workspace"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+	MessageCount int              `json:"message_count"`
+	Metadata     core.SessionMeta `json:"metadata"`
+}
+
+func summarizeSession(session core.Session) sessionSummary {
+	return sessionSummary{
+		ID:           session.ID,
+		Workspace:    session.Workspace,
+		CreatedAt:    session.CreatedAt,
+		UpdatedAt:    session.UpdatedAt,
+		MessageCount: len(session.Messages),
+		Metadata:     session.Metadata,
+	}
+}
+
+// requireEngine reports whether conversations can be served
+func (s *APIServer) requireEngine(w http.ResponseWriter) bool {
+	if s.engine == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "engine not available")
+		return false
+	}
+	return true
+}
+
+func (s *APIServer) writeSessionNotFound(w http.ResponseWriter) {
+	s.writeErrorCode(w, http.StatusNotFound, CodeSessionNotFound, "session not found")
+}
+
+func (s *APIServer) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	if !s.requireEngine(w) {
+		return
+	}
+
+	ws := requestWorkspace(r)
+	summaries := []sessionSummary{}
+	for _, session := range s.engine.SessionSnapshots() {
+		if session.Workspace == ws {
+			summaries = append(summaries, summarizeSession(session))
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessions": summaries,
+			"count":    len(summaries),
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleCreateSession starts a conversation; the body is optional
+func (s *APIServer) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	if !s.requireEngine(w) {
+		return
+	}
+
+	var req SessionRequest
+	if err := s.parseJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeDecodeError(w, err)
+		return
+	}
+
+	created := s.engine.CreateSessionIn(requestWorkspace(r))
+	session, err := s.engine.UpdateSessionMeta(created.ID, req.meta())
+	if err != nil {
+		s.writeSessionNotFound(w)
+		return
+	}
+
+	w.Header().Set("Location", r.URL.Path+"/"+session.ID)
+	s.writeJSON(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"session": session,
+		},
+		Message:   "Session created successfully",
+		Timestamp: time.Now(),
+	})
+}
+
+func (s *APIServer) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	if !s.requireEngine(w) {
+		return
+	}
+
+	session, ok := s.engine.SessionSnapshot(chi.URLParam(r, "sessionID"))
+	if !ok {
+		s.writeSessionNotFound(w)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"session": session,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleUpdateSession replaces the metadata of a session
+func (s *APIServer) handleUpdateSession(w http.ResponseWriter, r *http.Request) {
+	if !s.requireEngine(w) {
+		return
+	}
+
+	var req SessionRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+
+	session, err := s.engine.UpdateSessionMeta(chi.URLParam(r, "sessionID"), req.meta())
+	if err != nil {
+		s.writeSessionNotFound(w)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"session": summarizeSession(session),
+		},
+		Message:   "Session updated",
+		Timestamp: time.Now(),
+	})
+}
+
+func (s *APIServer) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	if !s.requireEngine(w) {
+		return
+	}
+
+	sessionID := chi.URLParam(r, "sessionID")
+	if !s.engine.DeleteSession(sessionID) {
+		s.writeSessionNotFound(w)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Message:   "Session " + sessionID + " deleted",
+		Timestamp: time.Now(),
+	})
+}
+
+// handleListSessionMessages returns the messages of a session; ?after=N
+// skips the first N so that clients can poll for new ones
+func (s *APIServer) handleListSessionMessages(w http.ResponseWriter, r *http.Request) {
+	if !s.requireEngine(w) {
+		return
+	}
+
+	after := 0
+	if v := r.URL.Query().Get("after"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid after parameter",
+				FieldError{Field: "after", Message: "must be a non-negative integer"})
+			return
+		}
+		after = n
+	}
+
+	session, ok := s.engine.SessionSnapshot(chi.URLParam(r, "sessionID"))
+	if !ok {
+		s.writeSessionNotFound(w)
+		return
+	}
+
+	messages := session.Messages
+	if after > len(messages) {
+		after = len(messages)
+	}
+	messages = messages[after:]
+
+	writeList(s, w, http.StatusOK, "messages", messages, map[string]interface{}{
+		"count": len(messages),
+		"total": len(session.Messages),
+	})
+}
+
+// debugParam reads ?debug=, which adds the context budget of a turn to
+// its answer; it answers 400 itself when the value is not a boolean
+func (s *APIServer) debugParam(w http.ResponseWriter, r *http.Request) (debug, ok bool) {
+	v := r.URL.Query().Get("debug")
+	if v == "" {
+		return false, true
+	}
+	debug, err := strconv.ParseBool(v)
+	if err != nil {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid debug parameter",
+			FieldError{Field: "debug", Message: "must be a boolean"})
+		return false, false
+	}
+	return debug, true
+}
+
+// handlePostSessionMessage sends a user turn to the engine and returns
+// the assistant's reply; with ?debug=true also how its prompt was
+// assembled
+func (s *APIServer) handlePostSessionMessage(w http.ResponseWriter, r *http.Request) {
+	if !s.requireEngine(w) {
+		return
+	}
+	debug, ok := s.debugParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req SessionMessageRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if details := requireFields(map[string]string{"content": req.Content}); len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "invalid message", details...)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "sessionID")
+	process := s.engine.Process
+	if req.Autonomous {
+		process = s.engine.ProcessAutonomous
+	}
+	result, err := process(r.Context(), sessionID, req.Content)
+	switch {
+	case errors.Is(err, core.ErrSessionNotFound):
+		s.writeSessionNotFound(w)
+		return
+	case errors.Is(err, core.ErrSessionBusy):
+		s.writeErrorCode(w, http.StatusConflict, CodeSessionBusy, "the session is still answering a previous message")
+		return
+	case err != nil:
+		s.writeProviderError(w, err)
+		return
+	}
+
+	session, _ := s.engine.SessionSnapshot(sessionID)
+	data := map[string]interface{}{
+		"response":    result.Response,
+		"duration_ms": result.Duration,
+	}
+	if n := len(session.Messages); n > 0 {
+		data["message"] = session.Messages[n-1]
+	}
+	if result.FinishReason != "" {
+		data["finish_reason"] = result.FinishReason
+	}
+	if result.Continuations > 0 {
+		data["continuations"] = result.Continuations
+	}
+	if result.Partial {
+		data["partial"] = true
+	}
+	if result.Constitution != nil {
+		data["constitution"] = result.Constitution
+	}
+	if debug {
+		data["context_budget"] = result.Budget
+	}
+
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
+
+// handleStreamSessionMessage sends a user turn to the engine and streams
+// the reply as server-sent events: a "delta" event for each piece of text
+// as the provider produces it, then "done" with the stored message, or
+// "error" if the completion fails part way. With ?debug=true "done" also
+// carries the context budget.
+func (s *APIServer) handleStreamSessionMessage(w http.ResponseWriter, r *http.Request) {
+	if !s.requireEngine(w) {
+		return
+	}
+	debug, ok := s.debugParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req SessionMessageRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if details := requireFields(map[string]string{"content": req.Content}); len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "invalid message", details...)
+		return
+	}
+
+	// Errors found before the stream starts are still plain JSON
+	sessionID := chi.URLParam(r, "sessionID")
+	if _, ok := s.engine.SessionSnapshot(sessionID); !ok {
+		s.writeSessionNotFound(w)
+		return
+	}
+
+	flusher, ok := startStream(w)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	seq := 0
+	send := func(event string, payload interface{}) error {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		seq++
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", seq, event, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	result, err := s.engine.ProcessStream(r.Context(), sessionID, req.Content, req.Autonomous, func(delta string) error {
+		return send("delta", map[string]string{"content": delta})
+	})
+	if err != nil {
+		_, apiErr, _ := providerError(err)
+		switch {
+		case errors.Is(err, core.ErrSessionNotFound):
+			apiErr = &APIError{Code: CodeSessionNotFound, Message: "session not found"}
+		case errors.Is(err, core.ErrSessionBusy):
+			apiErr = &APIError{Code: CodeSessionBusy, Message: "the session is still answering a previous message"}
+		}
+		send("error", apiErr)
+		return
+	}
+
+	done := map[string]interface{}{
+		"response":    result.Response,
+		"duration_ms": result.Duration,
+	}
+	if session, ok := s.engine.SessionSnapshot(sessionID); ok && len(session.Messages) > 0 {
+		done["message"] = session.Messages[len(session.Messages)-1]
+	}
+	if result.FinishReason != "" {
+		done["finish_reason"] = result.FinishReason
+	}
+	if result.Continuations > 0 {
+		done["continuations"] = result.Continuations
+	}
+	if result.Partial {
+		done["partial"] = true
+	}
+	if result.Constitution != nil {
+		done["constitution"] = result.Constitution
+	}
+	if debug {
+		done["context_budget"] = result.Budget
+	}
+	send("done", done)
+}
+
+// handleTaskFromSession creates a task for each step of the plan agreed in
+// a session, in the session's workspace. The body, optional, is a
+// core.DelegateOptions.
+func (s *APIServer) handleTaskFromSession(w http.ResponseWriter, r *http.Request) {
+	if !s.requireEngine(w) {
+		return
+	}
+	var opts core.DelegateOptions
+	if err := s.parseJSON(r, &opts); err != nil && !errors.Is(err, io.EOF) {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if opts.Priority != nil && (*opts.Priority < agents.PriorityLow || *opts.Priority > agents.PriorityUrgent) {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "invalid options",
+			FieldError{Field: "priority", Message: "must be between 0 and 3"})
+		return
+	}
+
+	d, err := s.engine.TasksFromSession(chi.URLParam(r, "sessionID"), opts, cause(r, "created from the session's plan"))
+	switch {
+	case errors.Is(err, core.ErrSessionNotFound):
+		s.writeSessionNotFound(w)
+		return
+	case errors.Is(err, core.ErrNoPlan):
+		field := "messages"
+		if opts.MessageID != "" {
+			field = "message_id"
+		}
+		s.writeErrorCode(w, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error(),
+			FieldError{Field: field, Message: "no markdown checklist of tasks"})
+		return
+	case errors.Is(err, core.ErrPlanDelegated):
+		s.writeErrorCode(w, http.StatusConflict, CodeConflict, err.Error()+"; set force to create them again")
+		return
+	case errors.Is(err, core.ErrPlanPartial):
+		s.writeErrorCode(w, http.StatusUnprocessableEntity, CodePlanPartial, err.Error()+"; set allow_partial to create its tasks anyway")
+		return
+	case errors.Is(err, core.ErrPlanRejected):
+		details := make([]FieldError, 0, len(d.Constitution.Violations))
+		for _, v := range d.Constitution.Violations {
+			// Steps are named by their SpecKit ID, or else their index
+			field := "plan"
+			if i := strings.TrimPrefix(v.TaskID, "operations"); i != v.TaskID {
+				field += i
+			} else if v.TaskID != "" {
+				field += "." + v.TaskID
+			}
+			details = append(details, FieldError{Field: field, Message: fmt.Sprintf("%s (%s): %s", v.Rule, v.Article, v.Message)})
+		}
+		s.writeErrorCode(w, http.StatusUnprocessableEntity, CodeConstitutionViolation,
+			fmt.Sprintf("the plan breaks %d constitution rule(s)", len(d.Constitution.Violations)), details...)
+		return
+	case err != nil:
+		s.writeRegistryError(w, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"delegation": d,
+			"count":      len(d.Tasks),
+		},
+		Timestamp: time.Now(),
+	})
+}
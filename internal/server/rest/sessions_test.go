@@ -0,0 +1,375 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/core"
+)
+
+type echoProvider struct{}
+
+func (echoProvider) Name() string { return "echo" }
+
+func (echoProvider) Complete(ctx context.Context, messages []ai.Message, systemPrompt string) (string, error) {
+	last := messages[len(messages)-1].Content
+	switch last {
+	case "fail":
+		return "", errors.New("upstream down")
+	case "limited":
+		reset := time.Now().Add(30 * time.Second)
+		return "", &ai.RateLimitError{Provider: "echo", Limit: ai.RateLimit{Limit: 20, Remaining: 0, Reset: &reset}}
+	}
+	return "echo: " + last, nil
+}
+
+// wordStreamer streams the echo reply one word at a time
+type wordStreamer struct{ echoProvider }
+
+func (p wordStreamer) Stream(ctx context.Context, messages []ai.Message, systemPrompt string, onDelta func(string) error) (string, error) {
+	response, err := p.Complete(ctx, messages, systemPrompt)
+	if err != nil {
+		return "", err
+	}
+	for _, word := range strings.SplitAfter(response, " ") {
+		if err := onDelta(word); err != nil {
+			return "", err
+		}
+	}
+	return response, nil
+}
+
+func TestSessionChatStream(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	engine := core.NewEngineWithProvider(ctx, config.DefaultConfig(), registry, wordStreamer{})
+	handler := NewServer(ctx, 0, "localhost", engine, registry).setupRoutes()
+	session := engine.CreateSession()
+
+	stream := func(content string) (int, []string, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/sessions/"+session.ID+"/chat/stream",
+			strings.NewReader(`{"content": "`+content+`"}`))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var events []string
+		var last string
+		for _, line := range strings.Split(rec.Body.String(), "\n") {
+			if strings.HasPrefix(line, "event: ") {
+				events = append(events, strings.TrimPrefix(line, "event: "))
+			}
+			if strings.HasPrefix(line, "data: ") {
+				last = strings.TrimPrefix(line, "data: ")
+			}
+		}
+		return rec.Code, events, last
+	}
+
+	code, events, last := stream("two words")
+	if code != http.StatusOK || strings.Join(events, ",") != "delta,delta,delta,done" {
+		t.Fatalf("stream: status %d, events %v", code, events)
+	}
+	if !strings.Contains(last, `"response":"echo: two words"`) {
+		t.Fatalf("done event: %s", last)
+	}
+	if snap, _ := engine.SessionSnapshot(session.ID); len(snap.Messages) != 2 {
+		t.Fatalf("stored %d messages, want 2", len(snap.Messages))
+	}
+
+	_, events, last = stream("fail")
+	if strings.Join(events, ",") != "error" || !strings.Contains(last, string(CodeProviderError)) {
+		t.Fatalf("failure: events %v, data %s", events, last)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sessions/missing/chat/stream", strings.NewReader(`{"content": "hi"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || decodeError(t, rec).Code != CodeSessionNotFound {
+		t.Fatalf("missing session: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestSessionConversation(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	engine := core.NewEngineWithProvider(ctx, config.DefaultConfig(), registry, echoProvider{})
+	handler := NewServer(ctx, 0, "localhost", engine, registry).setupRoutes()
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, APIResponse) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var resp APIResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	rec, resp := do(http.MethodPost, "/api/v1/sessions", `{"title": "demo"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+	id := resp.Data["session"].(map[string]interface{})["id"].(string)
+
+	rec, resp = do(http.MethodPost, "/api/v1/sessions/"+id+"/messages", `{"content": "hello"}`)
+	if rec.Code != http.StatusOK || resp.Data["response"] != "echo: hello" {
+		t.Fatalf("message: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec, resp = do(http.MethodGet, "/api/v1/sessions/"+id+"/messages?after=1", "")
+	if rec.Code != http.StatusOK || resp.Data["count"].(float64) != 1 || resp.Data["total"].(float64) != 2 {
+		t.Fatalf("messages: status %d: %s", rec.Code, rec.Body)
+	}
+	if resp.Data["context_budget"] != nil {
+		t.Errorf("context budget without debug: %s", rec.Body)
+	}
+
+	// The debug flag tells how the prompt was assembled
+	rec, resp = do(http.MethodPost, "/api/v1/sessions/"+id+"/messages?debug=true", `{"content": "and again"}`)
+	budget, _ := resp.Data["context_budget"].(map[string]interface{})
+	if rec.Code != http.StatusOK || budget == nil {
+		t.Fatalf("debug message: status %d: %s", rec.Code, rec.Body)
+	}
+	if budget["history_messages"].(float64) != 2 || budget["input_tokens"].(float64) != 3 || budget["system_prompt_tokens"].(float64) == 0 ||
+		budget["total_tokens"].(float64) < budget["history_tokens"].(float64)+budget["docs_tokens"].(float64)+3 {
+		t.Errorf("context budget: %v", budget)
+	}
+	if rec, _ = do(http.MethodPost, "/api/v1/sessions/"+id+"/messages?debug=maybe", `{"content": "hello"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid debug: status %d", rec.Code)
+	}
+
+	rec, _ = do(http.MethodPost, "/api/v1/sessions/"+id+"/messages", `{"content": "fail"}`)
+	if rec.Code != http.StatusBadGateway || decodeError(t, rec).Code != CodeProviderError {
+		t.Fatalf("provider failure: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec, _ = do(http.MethodPost, "/api/v1/sessions/"+id+"/messages", `{"content": "limited"}`)
+	apiErr := decodeError(t, rec)
+	if rec.Code != http.StatusTooManyRequests || apiErr.Code != CodeProviderRateLimited {
+		t.Fatalf("rate limited: status %d: %s", rec.Code, rec.Body)
+	}
+	if apiErr.RateLimit == nil || apiErr.RateLimit.Limit != 20 || apiErr.RateLimit.Reset == nil {
+		t.Errorf("rate limit = %+v", apiErr.RateLimit)
+	}
+	if after := rec.Header().Get("Retry-After"); after != "30" && after != "29" {
+		t.Errorf("Retry-After = %q", after)
+	}
+
+	rec, _ = do(http.MethodDelete, "/api/v1/sessions/"+id, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete: status %d", rec.Code)
+	}
+	rec, _ = do(http.MethodPost, "/api/v1/sessions/"+id+"/messages", `{"content": "hello"}`)
+	if rec.Code != http.StatusNotFound || decodeError(t, rec).Code != CodeSessionNotFound {
+		t.Fatalf("deleted session: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestSessionConversation_NoProvider(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	engine := core.NewEngineWithProvider(ctx, config.DefaultConfig(), registry, nil)
+	handler := NewServer(ctx, 0, "localhost", engine, registry).setupRoutes()
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, APIResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var resp APIResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	// Sessions can be managed without a provider, but not completed
+	rec, resp := do(http.MethodPost, "/api/v1/sessions", `{}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+	id := resp.Data["session"].(map[string]interface{})["id"].(string)
+	rec, _ = do(http.MethodPost, "/api/v1/sessions/"+id+"/messages", `{"content": "hello"}`)
+	if rec.Code != http.StatusServiceUnavailable || decodeError(t, rec).Code != CodeProviderNotConfigured {
+		t.Fatalf("without a provider: status %d: %s", rec.Code, rec.Body)
+	}
+	if session, _ := engine.SessionSnapshot(id); len(session.Messages) != 0 {
+		t.Errorf("a message was recorded without a provider: %v", session.Messages)
+	}
+
+	engine.SetProvider(echoProvider{})
+	rec, resp = do(http.MethodPost, "/api/v1/sessions/"+id+"/messages", `{"content": "hello"}`)
+	if rec.Code != http.StatusOK || resp.Data["response"] != "echo: hello" {
+		t.Fatalf("after SetProvider: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// blockingProvider answers once release is closed, telling started when
+// a completion begins
+type blockingProvider struct {
+	echoProvider
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p blockingProvider) Complete(ctx context.Context, messages []ai.Message, systemPrompt string) (string, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return p.echoProvider.Complete(ctx, messages, systemPrompt)
+}
+
+func TestSessionConversation_Busy(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	provider := blockingProvider{started: make(chan struct{}, 1), release: make(chan struct{})}
+	engine := core.NewEngineWithProvider(ctx, config.DefaultConfig(), registry, provider)
+	handler := NewServer(ctx, 0, "localhost", engine, registry).setupRoutes()
+	session := engine.CreateSession()
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := engine.Process(ctx, session.ID, "first")
+		first <- err
+	}()
+	<-provider.started
+
+	// A second message while the first is answered is refused, and left
+	// out of the history
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/sessions/"+session.ID+"/messages", strings.NewReader(`{"content": "second"}`)))
+	if rec.Code != http.StatusConflict || decodeError(t, rec).Code != CodeSessionBusy {
+		t.Fatalf("concurrent message: status %d: %s", rec.Code, rec.Body)
+	}
+	close(provider.release)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if snapshot, _ := engine.SessionSnapshot(session.ID); len(snapshot.Messages) != 2 || snapshot.Messages[1].Content != "echo: first" {
+		t.Fatalf("messages = %+v", snapshot.Messages)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/sessions/"+session.ID+"/messages", strings.NewReader(`{"content": "second"}`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("message once the session is free: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestTaskFromSession(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	engine := core.NewEngineWithProvider(ctx, config.DefaultConfig(), registry, echoProvider{})
+	handler := NewServer(ctx, 0, "localhost", engine, registry).setupRoutes()
+	session := engine.CreateSession()
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/sessions/"+session.ID+path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/task-from-session", ""); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("without a plan: %d %s", rec.Code, rec.Body)
+	}
+	if rec := post("/messages", `{"content": "Plan:\n- [ ] T001 Write the API tests\n- [ ] T002 Implement the API (after T001)"}`); rec.Code != http.StatusOK {
+		t.Fatalf("message: %d %s", rec.Code, rec.Body)
+	}
+
+	rec := post("/task-from-session", `{"labels": ["api"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("task-from-session: %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data struct {
+			Delegation core.Delegation `json:"delegation"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	tasks := resp.Data.Delegation.Tasks
+	if len(tasks) != 2 || tasks[0].Title != "T001 Write the API tests" {
+		t.Fatalf("tasks = %+v", tasks)
+	}
+	first, second := tasks[0], tasks[1]
+	if !strings.Contains(second.Description, "Depends on T001 (task "+first.ID+")") {
+		t.Errorf("T002 description = %q", second.Description)
+	}
+	stored, _ := registry.GetTask(second.ID)
+	if stored.Source != core.TaskSource || stored.Meta[core.MetaSession] != session.ID || stored.Meta[core.MetaPlanTask] != "T002" ||
+		len(stored.Labels) != 1 || stored.Labels[0] != "api" {
+		t.Errorf("stored task = %+v", stored)
+	}
+
+	if rec := post("/task-from-session", ""); rec.Code != http.StatusConflict {
+		t.Errorf("converting twice: %d %s", rec.Code, rec.Body)
+	}
+	if rec := post("/task-from-session", `{"force": true}`); rec.Code != http.StatusCreated {
+		t.Errorf("force: %d %s", rec.Code, rec.Body)
+	}
+	if n := len(registry.ListTasks()); n != 4 {
+		t.Errorf("%d tasks in the registry, want 4", n)
+	}
+}
+
+func TestSessionConversation_Truncated(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	provider := ai.NewMockProvider("Plan:\n- [ ] T001 Write ", "the tests\n- [ ] T002 Ship", "Plan:\n- [ ] T001 Blocked")
+	provider.Finishes = []string{"length", "stop", "content_filter"}
+	engine := core.NewEngineWithProvider(ctx, config.DefaultConfig(), registry, provider)
+	handler := NewServer(ctx, 0, "localhost", engine, registry).setupRoutes()
+	session := engine.CreateSession()
+
+	post := func(path, body string) (*httptest.ResponseRecorder, APIResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/sessions/"+session.ID+path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var resp APIResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	// A reply cut off by the token limit is continued and joined
+	rec, resp := post("/messages", `{"content": "plan it"}`)
+	if rec.Code != http.StatusOK || resp.Data["response"] != "Plan:\n- [ ] T001 Write the tests\n- [ ] T002 Ship" ||
+		resp.Data["continuations"] != float64(1) || resp.Data["finish_reason"] != "stop" || resp.Data["partial"] != nil {
+		t.Fatalf("continued reply: %d %s", rec.Code, rec.Body)
+	}
+	calls := provider.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("%d provider calls, want 2", len(calls))
+	}
+	follow := calls[1].Messages
+	if n := len(follow); n != 3 || follow[n-2].Role != "assistant" || follow[n-2].Content != "Plan:\n- [ ] T001 Write " || follow[n-1].Role != "user" {
+		t.Errorf("continuation messages = %+v", follow)
+	}
+	if snap, _ := engine.SessionSnapshot(session.ID); len(snap.Messages) != 2 {
+		t.Errorf("%d messages stored, want 2", len(snap.Messages))
+	}
+
+	// Moderation cannot be continued: the reply is flagged partial and its
+	// plan is not converted without consent
+	rec, resp = post("/messages", `{"content": "plan more"}`)
+	if rec.Code != http.StatusOK || resp.Data["partial"] != true || resp.Data["finish_reason"] != "content_filter" {
+		t.Fatalf("filtered reply: %d %s", rec.Code, rec.Body)
+	}
+	if msg, _ := resp.Data["message"].(map[string]interface{}); msg["metadata"].(map[string]interface{})["partial"] != true {
+		t.Errorf("stored message = %v", msg)
+	}
+	if rec, _ := post("/task-from-session", ""); rec.Code != http.StatusUnprocessableEntity || decodeError(t, rec).Code != CodePlanPartial {
+		t.Fatalf("partial plan: %d %s", rec.Code, rec.Body)
+	}
+	if rec, _ := post("/task-from-session", `{"allow_partial": true}`); rec.Code != http.StatusCreated {
+		t.Fatalf("allow_partial: %d %s", rec.Code, rec.Body)
+	}
+}
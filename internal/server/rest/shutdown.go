@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/shutdown"
+)
+
+// SetShutdownCoordinator lets POST /system/shutdown stop the process
+func (s *APIServer) SetShutdownCoordinator(c *shutdown.Coordinator) {
+	s.shutdown = c
+}
+
+// handleShutdown triggers a graceful shutdown, or a forced one with
+// ?force=true. The response is sent before the REST server itself stops.
+func (s *APIServer) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	if s.shutdown == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "shutdown is not available in this mode")
+		return
+	}
+
+	force := false
+	if v := r.URL.Query().Get("force"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid force parameter",
+				FieldError{Field: "force", Message: "must be a boolean"})
+			return
+		}
+		force = parsed
+	}
+
+	started := s.shutdown.Trigger(shutdown.Options{Force: force, Reason: "api"})
+
+	message := "Graceful shutdown started"
+	switch {
+	case !started && force:
+		message = "Shutdown already in progress, escalated to forced"
+	case !started:
+		message = "Shutdown already in progress"
+	case force:
+		message = "Forced shutdown started"
+	}
+
+	response := APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"force":           force,
+			"in_flight_tasks": s.agentRegistry.InFlightTasks(),
+		},
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+
+	s.writeJSON(w, http.StatusAccepted, response)
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/snapshot"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetSnapshots enables the snapshot and rollback routes of tasks
+func (s *APIServer) SetSnapshots(m *snapshot.Manager) {
+	s.snapshots = m
+}
+
+// handleGetSnapshot serves the checkpoint taken of a task's workspace
+func (s *APIServer) handleGetSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.serveSnapshot(w, r, "", func(taskID string) (*snapshot.Snapshot, error) {
+		return s.snapshots.Get(taskID)
+	})
+}
+
+// handleTakeSnapshot checkpoints a task's workspace, or returns the
+// checkpoint it already has
+func (s *APIServer) handleTakeSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.serveSnapshot(w, r, "Snapshot taken", func(taskID string) (*snapshot.Snapshot, error) {
+		return s.snapshots.Take(r.Context(), taskID)
+	})
+}
+
+// handleRollbackTask restores a task's workspace to its checkpoint,
+// discarding the changes made since
+func (s *APIServer) handleRollbackTask(w http.ResponseWriter, r *http.Request) {
+	s.serveSnapshot(w, r, "Workspace rolled back", func(taskID string) (*snapshot.Snapshot, error) {
+		return s.snapshots.Rollback(r.Context(), taskID)
+	})
+}
+
+// serveSnapshot answers the snapshot routes with the snapshot op returns
+func (s *APIServer) serveSnapshot(w http.ResponseWriter, r *http.Request, message string, op func(taskID string) (*snapshot.Snapshot, error)) {
+	if s.snapshots == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "snapshots are not enabled")
+		return
+	}
+	snap, err := op(chi.URLParam(r, "taskID"))
+	switch {
+	case errors.Is(err, agents.ErrTaskNotFound):
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	case errors.Is(err, snapshot.ErrNoSnapshot):
+		s.writeErrorCode(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	case errors.Is(err, snapshot.ErrNoWorkspace):
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, err.Error(),
+			FieldError{Field: "workspace", Message: "is required"})
+		return
+	case err != nil:
+		s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, "git: "+err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"snapshot": snap},
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,34 @@
+package rest
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/biodoia/skagent/internal/server/unixsock"
+)
+
+// SetSocket also serves the API on a Unix domain socket at path, whose
+// file mode decides which local users may connect. The socket is plain
+// HTTP even when TLS is enabled. It must be called before Start.
+func (s *APIServer) SetSocket(path string, mode os.FileMode) {
+	s.socketPath = path
+	s.socketMode = mode
+}
+
+// serveSocket starts serving on the configured socket, if any
+func (s *APIServer) serveSocket() error {
+	if s.socketPath == "" {
+		return nil
+	}
+	ln, err := unixsock.Listen(s.socketPath, s.socketMode)
+	if err != nil {
+		return err
+	}
+	s.logger.Printf("Starting API server on unix://%s (mode %04o)", s.socketPath, s.socketMode)
+	go func() {
+		if err := s.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			s.logger.Printf("ERROR: Socket server error: %v", err)
+		}
+	}()
+	return nil
+}
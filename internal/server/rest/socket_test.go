@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestServeSocket(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "api.sock")
+
+	s := NewServer(ctx, 0, "127.0.0.1", nil, agents.NewRegistry(ctx))
+	s.SetSocket(path, 0o600)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://skagent/api/v1/agents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("agents over the socket: %d", resp.StatusCode)
+	}
+
+	if err := s.Shutdown(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket left after shutdown: %v", err)
+	}
+}
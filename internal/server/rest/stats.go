@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// requestStats counts the requests served by one API server
+type requestStats struct {
+	mu          sync.Mutex
+	total       int64
+	clientError int64
+	serverError int64
+	byRoute     map[string]int64
+}
+
+func newRequestStats() *requestStats {
+	return &requestStats{byRoute: make(map[string]int64)}
+}
+
+func (st *requestStats) record(method, route string, status int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.total++
+	switch {
+	case status >= 500:
+		st.serverError++
+	case status >= 400:
+		st.clientError++
+	}
+	st.byRoute[method+" "+route]++
+}
+
+// routeCount is the request count of one method and route pattern
+type routeCount struct {
+	Route string `json:"route"`
+	Count int64  `json:"count"`
+}
+
+func (st *requestStats) snapshot() map[string]interface{} {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	routes := make([]routeCount, 0, len(st.byRoute))
+	for route, n := range st.byRoute {
+		routes = append(routes, routeCount{Route: route, Count: n})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Count != routes[j].Count {
+			return routes[i].Count > routes[j].Count
+		}
+		return routes[i].Route < routes[j].Route
+	})
+
+	return map[string]interface{}{
+		"total":         st.total,
+		"client_errors": st.clientError,
+		"server_errors": st.serverError,
+		"by_route":      routes,
+	}
+}
+
+// memoryStats reports Go runtime memory figures in bytes
+func memoryStats() map[string]interface{} {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return map[string]interface{}{
+		"alloc_bytes":      m.Alloc,
+		"heap_inuse_bytes": m.HeapInuse,
+		"sys_bytes":        m.Sys,
+		"num_gc":           m.NumGC,
+		"goroutines":       runtime.NumGoroutine(),
+	}
+}
+
+// cpuStats reports process CPU time and the average utilisation since
+// start, as a percentage of all CPUs
+func cpuStats(uptime time.Duration) map[string]interface{} {
+	stats := map[string]interface{}{
+		"num_cpu": runtime.NumCPU(),
+	}
+	user, system, ok := processCPUTime()
+	if !ok {
+		stats["usage_percent"] = nil
+		return stats
+	}
+	stats["user_seconds"] = user.Seconds()
+	stats["system_seconds"] = system.Seconds()
+	if uptime > 0 {
+		pct := float64(user+system) / float64(uptime) / float64(runtime.NumCPU()) * 100
+		stats["usage_percent"] = float64(int(pct*100)) / 100
+	}
+	return stats
+}
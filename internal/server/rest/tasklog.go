@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/go-chi/chi/v5"
+)
+
+// TaskLogRequest is the body of POST /tasks/{taskID}/log, with which the
+// worker running a task reports its steps
+type TaskLogRequest struct {
+	// Kind is prompt, response, tool_call, output, retry, error or note
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Tool    string `json:"tool,omitempty"`
+	// Source defaults to the task's agent
+	Source string `json:"source,omitempty"`
+}
+
+// SetTaskLog enables the execution log routes of tasks
+func (s *APIServer) SetTaskLog(store *tasklog.Store) {
+	s.taskLog = store
+}
+
+// requireTaskLog writes 503 when task logs are not enabled
+func (s *APIServer) requireTaskLog(w http.ResponseWriter) bool {
+	if s.taskLog == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "task logs are not enabled")
+		return false
+	}
+	return true
+}
+
+// handleGetTaskLog returns the execution log of a task, oldest first.
+// ?kind= keeps one kind of entry, ?after= the entries after a seq and
+// ?limit= the most recent ones. The log of a deleted task is kept.
+func (s *APIServer) handleGetTaskLog(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTaskLog(w) {
+		return
+	}
+	taskID := chi.URLParam(r, "taskID")
+	if _, _, ok := s.taskWorkspace(taskID); !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	}
+
+	q := r.URL.Query()
+	var f tasklog.Filter
+	if v := q.Get("kind"); v != "" {
+		f.Kind = tasklog.Kind(v)
+		if !f.Kind.Valid() {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid kind parameter",
+				FieldError{Field: "kind", Message: "must be one of " + kindNames()})
+			return
+		}
+	}
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid after parameter",
+				FieldError{Field: "after", Message: "must be a non-negative sequence number"})
+			return
+		}
+		f.AfterSeq = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid limit parameter",
+				FieldError{Field: "limit", Message: "must be a positive integer"})
+			return
+		}
+		f.Limit = n
+	}
+
+	entries, _ := s.taskLog.Entries(taskID, f)
+	writeList(s, w, http.StatusOK, "entries", entries, map[string]interface{}{
+		"task_id": taskID,
+		"count":   len(entries),
+	})
+}
+
+// handleAppendTaskLog records a step the worker running a task took
+func (s *APIServer) handleAppendTaskLog(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTaskLog(w) {
+		return
+	}
+	taskID := chi.URLParam(r, "taskID")
+	task, ok := s.agentRegistry.GetTask(taskID)
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	}
+
+	var req TaskLogRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	details := requireFields(map[string]string{"kind": req.Kind, "message": req.Message})
+	kind := tasklog.Kind(req.Kind)
+	if req.Kind != "" && (!kind.Valid() || kind == tasklog.KindStatus) {
+		details = append(details, FieldError{Field: "kind", Message: "must be one of " + kindNames()})
+	}
+	if len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "invalid log entry", details...)
+		return
+	}
+
+	source := req.Source
+	if source == "" {
+		source = task.AssignedTo
+	}
+	if source == "" {
+		source = cause(r, "").Actor
+	}
+	entry, err := s.taskLog.Append(tasklog.Entry{TaskID: taskID, Kind: kind, Source: source, Tool: req.Tool, Message: req.Message})
+	if err != nil {
+		if errors.Is(err, tasklog.ErrInvalidTaskID) {
+			s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+			return
+		}
+		s.writeErrorCode(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"entry": entry},
+		Message:   "Log entry recorded",
+		Timestamp: time.Now(),
+	})
+}
+
+// kindNames lists the kinds a worker may report; status entries are the
+// registry's
+func kindNames() string {
+	names := make([]string, 0, len(tasklog.Kinds))
+	for _, k := range tasklog.Kinds {
+		if k != tasklog.KindStatus {
+			names = append(names, string(k))
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/tasklog"
+)
+
+func TestTaskLog(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("a", "coder", nil)
+	task := registry.CreateTask(&agents.Task{Title: "build"})
+	registry.AssignTask(task.ID, agent.ID)
+	server := NewServer(ctx, 0, "localhost", nil, registry)
+	handler := server.setupRoutes()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	path := "/api/v1/tasks/" + task.ID + "/log"
+
+	if rec := do(http.MethodGet, path, ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("without a store: %d", rec.Code)
+	}
+	store, _ := tasklog.New("", 0)
+	server.SetTaskLog(store)
+
+	if rec := do(http.MethodPost, path, `{"kind":"status","message":"done"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("status entry from a worker: %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, path, `{"kind":"tool_call"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("entry without a message: %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/v1/tasks/missing/log", `{"kind":"note","message":"m"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("missing task: %d", rec.Code)
+	}
+	rec := do(http.MethodPost, path, `{"kind":"tool_call","tool":"git","message":"git status"}`)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"source":"`+agent.ID+`"`) {
+		t.Fatalf("append: %d %s", rec.Code, rec.Body)
+	}
+	do(http.MethodPost, path, `{"kind":"error","message":"not a git repository"}`)
+
+	rec = do(http.MethodGet, path+"?kind=error", "")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "not a git repository") || strings.Contains(body, "git status") {
+		t.Fatalf("filtered log: %d %s", rec.Code, body)
+	}
+	if rec := do(http.MethodGet, path+"?after=1", ""); !strings.Contains(rec.Body.String(), `"count":1`) {
+		t.Errorf("after: %s", rec.Body)
+	}
+	if rec := do(http.MethodGet, path+"?kind=bogus", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad kind: %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/v1/tasks/missing/log", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("log of a missing task: %d", rec.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/templates"
+	"github.com/biodoia/skagent/internal/validate"
+)
+
+// TemplateTaskRequest creates a task from a built-in template
+type TemplateTaskRequest struct {
+	Template    string            `json:"template" validate:"required"`
+	Params      map[string]string `json:"params,omitempty"`
+	AgentID     string            `json:"agent_id,omitempty"`
+	Priority    *int              `json:"priority,omitempty" validate:"min=0,max=3"` // the template's when omitted
+	CallbackURL string            `json:"callback_url,omitempty" validate:"url"`
+}
+
+// handleListTemplates lists the built-in task templates
+func (s *APIServer) handleListTemplates(w http.ResponseWriter, r *http.Request) {
+	list := templates.Builtin()
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"templates": list, "count": len(list)},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleCreateFromTemplate fills a template's placeholders with the given
+// parameters and creates the task it describes, with the template's labels,
+// agent types and acceptance criteria
+func (s *APIServer) handleCreateFromTemplate(w http.ResponseWriter, r *http.Request) {
+	var req TemplateTaskRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if !s.validRequest(w, &req, "invalid task") {
+		return
+	}
+	tmpl, ok := templates.Get(req.Template)
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeNotFound, "template not found",
+			FieldError{Field: "template", Message: "is not a known template"})
+		return
+	}
+	task, err := tmpl.Render(req.Params)
+	var errs validate.Errors
+	if errors.As(err, &errs) {
+		s.writeErrorCode(w, http.StatusUnprocessableEntity, CodeValidationFailed, "invalid template parameters", errs...)
+		return
+	}
+	if req.Priority != nil {
+		task.Priority = agents.TaskPriority(*req.Priority)
+	}
+	task.Source = "api"
+	task.CallbackURL = req.CallbackURL
+	s.createTask(w, r, task, req.AgentID)
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestCreateTaskFromTemplate(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("docs", "documenter", nil)
+	handler := NewServer(ctx, 0, "localhost", nil, registry).setupRoutes()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/api/v1/tasks/templates", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"bug-fix"`) {
+		t.Fatalf("GET templates: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodPost, "/api/v1/tasks/from-template",
+		`{"template":"update-docs","params":{"topic":"task templates"},"agent_id":"`+agent.ID+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST: %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data struct {
+			Task agents.Task `json:"task"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	task := resp.Data.Task
+	if task.Title != "Document task templates" || task.AssignedTo != agent.ID || task.Priority != agents.PriorityLow ||
+		strings.Join(task.Labels, ",") != "docs" || strings.Join(task.AgentTypes, ",") != "documenter" ||
+		task.Meta["template"] != "update-docs" || task.Source != "api" {
+		t.Fatalf("task = %+v", task)
+	}
+
+	for _, tt := range []struct {
+		body   string
+		status int
+		field  string
+	}{
+		{`{"template":"nope"}`, http.StatusNotFound, "template"},
+		{`{"template":"bug-fix"}`, http.StatusUnprocessableEntity, "params.summary"},
+		{`{"template":"bug-fix","params":{"summary":"x"},"priority":9}`, http.StatusUnprocessableEntity, "priority"},
+	} {
+		rec := do(http.MethodPost, "/api/v1/tasks/from-template", tt.body)
+		if rec.Code != tt.status {
+			t.Errorf("%s: %d %s", tt.body, rec.Code, rec.Body)
+			continue
+		}
+		if e := decodeError(t, rec); len(e.Details) == 0 || e.Details[0].Field != tt.field {
+			t.Errorf("%s: details %+v", tt.body, e.Details)
+		}
+	}
+}
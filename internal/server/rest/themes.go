@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/tui/themes"
+	"github.com/go-chi/chi/v5"
+)
+
+// themeManager returns the built-in themes plus the custom ones in the
+// themes directory, and the problems found loading them
+func themeManager() (*themes.ThemeManager, []string) {
+	tm := themes.NewThemeManager()
+	dir, err := themes.ThemesDir()
+	if err != nil {
+		return tm, []string{err.Error()}
+	}
+	var problems []string
+	for _, err := range tm.LoadThemesDir(dir).Errors {
+		problems = append(problems, err.Error())
+	}
+	return tm, problems
+}
+
+func (s *APIServer) handleListThemes(w http.ResponseWriter, r *http.Request) {
+	tm, problems := themeManager()
+
+	list := make([]map[string]interface{}, 0)
+	for _, name := range tm.ListThemes() {
+		theme, _ := tm.GetTheme(name)
+		list = append(list, map[string]interface{}{
+			"name":              name,
+			"description":       theme.Description,
+			"custom":            tm.IsCustom(name),
+			"contrast_failures": len(themes.ContrastFailures(theme.CheckContrast())),
+		})
+	}
+
+	data := map[string]interface{}{
+		"themes": list,
+		"count":  len(list),
+	}
+	if len(problems) > 0 {
+		data["errors"] = problems
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
+
+func (s *APIServer) handleGetTheme(w http.ResponseWriter, r *http.Request) {
+	tm, _ := themeManager()
+	theme, ok := tm.GetTheme(chi.URLParam(r, "themeName"))
+	if !ok {
+		s.writeErrorCode(w, http.StatusNotFound, CodeNotFound, "theme not found")
+		return
+	}
+	s.writeThemeReport(w, theme)
+}
+
+// handleValidateTheme checks a theme before it is installed: its colors
+// must parse, and the contrast of every pair the TUI draws is reported
+func (s *APIServer) handleValidateTheme(w http.ResponseWriter, r *http.Request) {
+	var theme themes.Theme
+	if err := s.parseJSON(r, &theme); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if theme.Name == "" {
+		theme.Name = "custom"
+	}
+
+	if theme.Extends != "" && !themes.IsBuiltin(theme.Extends) {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "theme extends an unknown theme",
+			FieldError{Field: "extends", Message: "must name a built-in theme"})
+		return
+	}
+	if invalid := theme.InvalidColors(); len(invalid) > 0 {
+		details := make([]FieldError, len(invalid))
+		for i, e := range invalid {
+			details[i] = FieldError{Field: e.Field, Message: e.Message()}
+		}
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "theme has invalid colors or styles", details...)
+		return
+	}
+	theme.FillDefaults()
+	s.writeThemeReport(w, &theme)
+}
+
+func (s *APIServer) writeThemeReport(w http.ResponseWriter, theme *themes.Theme) {
+	checks := theme.CheckContrast()
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"theme":             theme,
+			"contrast":          checks,
+			"contrast_failures": len(themes.ContrastFailures(checks)),
+		},
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateTheme(t *testing.T) {
+	handler := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/themes/validate",
+		strings.NewReader(`{"name": "bad", "colors": {"primary": "blue", "muted": "#1E1E2F"}}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid theme: status %d", rec.Code)
+	}
+	if apiErr := decodeError(t, rec); len(apiErr.Details) != 1 || apiErr.Details[0].Field != "colors.primary" {
+		t.Fatalf("details = %+v", apiErr.Details)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/themes/validate",
+		strings.NewReader(`{"name": "dim", "colors": {"muted": "#1E1E2F"}}`))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var resp struct {
+		Data struct {
+			ContrastFailures int `json:"contrast_failures"`
+			Contrast         []struct {
+				Name string `json:"name"`
+				Pass bool   `json:"pass"`
+			} `json:"contrast"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status %d, err %v", rec.Code, err)
+	}
+	for _, c := range resp.Data.Contrast {
+		if c.Name == "muted" && c.Pass {
+			t.Error("muted color on an identical background passed")
+		}
+	}
+	if resp.Data.ContrastFailures == 0 {
+		t.Error("no contrast failures reported")
+	}
+}
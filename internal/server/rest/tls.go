@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// SetTLS serves HTTPS with the given certificate settings. It must be
+// called before Start.
+func (s *APIServer) SetTLS(cfg config.TLSConfig) {
+	s.tlsConfig = cfg
+}
+
+// buildTLSConfig loads the server certificate and, for mTLS, the client CA
+// pool. Errors surface at Start rather than on the first handshake.
+func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.MinVersion == "1.3" {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	switch cfg.ClientAuth {
+	case "none":
+		tlsConfig.ClientAuth = tls.NoClientCert
+	case "request":
+		tlsConfig.ClientAuth = tls.RequestClientCert
+	case "verify_if_given":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	case "":
+		if tlsConfig.ClientCAs != nil {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	default:
+		return nil, fmt.Errorf("unknown client_auth mode %q", cfg.ClientAuth)
+	}
+	if tlsConfig.ClientAuth >= tls.VerifyClientCertIfGiven && tlsConfig.ClientCAs == nil {
+		return nil, fmt.Errorf("client_auth %q requires client_ca_file", cfg.ClientAuth)
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// writeCert creates a self-signed certificate usable as server, client and
+// CA certificate, returning the cert and key file paths
+func writeCert(t *testing.T, dir, name string) (string, string, tls.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, pair
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey, _ := writeCert(t, dir, "server")
+	clientCA, _, clientPair := writeCert(t, dir, "client")
+
+	tlsConfig, err := buildTLSConfig(config.TLSConfig{
+		CertFile:     serverCert,
+		KeyFile:      serverKey,
+		ClientCAFile: clientCA,
+	})
+	if err != nil {
+		t.Fatalf("buildTLSConfig() error = %v", err)
+	}
+	if tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("ClientAuth = %v, want RequireAndVerifyClientCert by default", tlsConfig.ClientAuth)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	defer srv.Close()
+
+	serverPEM, _ := os.ReadFile(serverCert)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(serverPEM)
+
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certs,
+		}}}
+	}
+
+	resp, err := client(clientPair).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with client certificate failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d", resp.StatusCode)
+	}
+
+	if resp, err := client().Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Error("request without client certificate succeeded")
+	}
+}
+
+func TestBuildTLSConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	cert, key, _ := writeCert(t, dir, "server")
+
+	if _, err := buildTLSConfig(config.TLSConfig{CertFile: cert, KeyFile: filepath.Join(dir, "missing.key")}); err == nil {
+		t.Error("accepted a missing key file")
+	}
+	if _, err := buildTLSConfig(config.TLSConfig{CertFile: cert, KeyFile: key, ClientAuth: "require"}); err == nil {
+		t.Error("accepted client_auth=require without a client CA")
+	}
+}
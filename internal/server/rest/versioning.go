@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIVersion identifies a major version of the REST API
+type APIVersion int
+
+const (
+	// APIVersion1 is the first versioned API, mounted under /api/v1
+	APIVersion1 APIVersion = 1
+
+	// CurrentAPIVersion is served when a client does not ask for a version
+	CurrentAPIVersion = APIVersion1
+
+	// VersionHeader carries the requested and served API version
+	VersionHeader = "X-API-Version"
+
+	// vendorMediaPrefix is used for Accept-based negotiation, e.g.
+	// application/vnd.skagent.v1+json
+	vendorMediaPrefix = "application/vnd.skagent.v"
+)
+
+// SupportedAPIVersions lists every version this server can serve
+var SupportedAPIVersions = []APIVersion{APIVersion1}
+
+// Legacy unversioned routes (/agents, /tasks, ...) remain available until
+// legacySunset and advertise their replacement via response headers
+var (
+	legacyDeprecatedAt = time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	legacySunset       = time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type versionKey struct{}
+
+// VersionFromContext returns the negotiated API version of a request
+func VersionFromContext(ctx context.Context) APIVersion {
+	if v, ok := ctx.Value(versionKey{}).(APIVersion); ok {
+		return v
+	}
+	return CurrentAPIVersion
+}
+
+func (v APIVersion) String() string {
+	return "v" + strconv.Itoa(int(v))
+}
+
+func isSupportedVersion(v APIVersion) bool {
+	for _, s := range SupportedAPIVersions {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// requestedVersion extracts the version a client asked for from the
+// X-API-Version header or a vendor media type in Accept. ok is false when
+// the client did not express a preference.
+func requestedVersion(r *http.Request) (v APIVersion, ok bool, err error) {
+	if h := strings.TrimSpace(r.Header.Get(VersionHeader)); h != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(h), "v"))
+		if err != nil {
+			return 0, true, fmt.Errorf("invalid %s header: %q", VersionHeader, h)
+		}
+		return APIVersion(n), true, nil
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		media := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if !strings.HasPrefix(media, vendorMediaPrefix) {
+			continue
+		}
+		rest := strings.TrimSuffix(strings.TrimPrefix(media, vendorMediaPrefix), "+json")
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return 0, true, fmt.Errorf("invalid media type: %q", media)
+		}
+		return APIVersion(n), true, nil
+	}
+
+	return 0, false, nil
+}
+
+// versionMiddleware pins requests to the version implied by the mount
+// point and rejects explicit requests for a different version
+func (s *APIServer) versionMiddleware(mounted APIVersion) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, ok, err := requestedVersion(r)
+			if err != nil {
+				s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidAPIVersion, err.Error())
+				return
+			}
+			if ok && v != mounted {
+				if isSupportedVersion(v) {
+					s.writeErrorCode(w, http.StatusNotAcceptable, CodeUnsupportedAPIVersion,
+						fmt.Sprintf("API %s requested but this route serves %s; use /api/%s", v, mounted, v))
+				} else {
+					s.writeErrorCode(w, http.StatusNotAcceptable, CodeUnsupportedAPIVersion,
+						fmt.Sprintf("unsupported API version %s", v))
+				}
+				return
+			}
+
+			w.Header().Set(VersionHeader, strconv.Itoa(int(mounted)))
+			ctx := context.WithValue(r.Context(), versionKey{}, mounted)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// deprecatedMiddleware marks legacy unversioned routes with Deprecation,
+// Sunset and successor Link headers
+func deprecatedMiddleware(successorPrefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(legacyDeprecatedAt.Unix(), 10))
+			w.Header().Set("Sunset", legacySunset.Format(http.TimeFormat))
+			w.Header().Add("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successorPrefix, r.URL.Path))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
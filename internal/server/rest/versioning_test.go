@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestedVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		accept  string
+		version APIVersion
+		ok      bool
+		err     bool
+	}{
+		{"no preference", "", "application/json", 0, false, false},
+		{"header", "1", "", 1, true, false},
+		{"header with prefix", " V2 ", "", 2, true, false},
+		{"header beats accept", "1", "application/vnd.skagent.v2+json", 1, true, false},
+		{"accept", "", "text/html, application/vnd.skagent.v3+json; q=0.9", 3, true, false},
+		{"bad header", "latest", "", 0, true, true},
+		{"bad media type", "", "application/vnd.skagent.vx+json", 0, true, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set(VersionHeader, tt.header)
+		}
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		v, ok, err := requestedVersion(req)
+		if v != tt.version || ok != tt.ok || (err != nil) != tt.err {
+			t.Errorf("%s: got %v, %v, %v; want %v, %v, error %v", tt.name, v, ok, err, tt.version, tt.ok, tt.err)
+		}
+	}
+}
+
+func TestVersionNegotiation(t *testing.T) {
+	h := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		accept string
+		status int
+		code   ErrorCode
+	}{
+		{"by path", "/api/v1/agents", "", "", 200, ""},
+		{"matching header", "/api/v1/agents", "v1", "", 200, ""},
+		{"matching accept", "/api/v1/agents", "", "application/vnd.skagent.v1+json", 200, ""},
+		{"unsupported header", "/api/v1/agents", "7", "", 406, CodeUnsupportedAPIVersion},
+		{"unsupported accept", "/agents", "", "application/vnd.skagent.v9+json", 406, CodeUnsupportedAPIVersion},
+		{"malformed header", "/api/v1/agents", "one", "", 400, CodeInvalidAPIVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(VersionHeader, tt.header)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code != "" {
+				if got := decodeError(t, rec).Code; got != tt.code {
+					t.Errorf("code = %s, want %s", got, tt.code)
+				}
+				return
+			}
+			if got := rec.Header().Get(VersionHeader); got != "1" {
+				t.Errorf("%s = %q, want 1", VersionHeader, got)
+			}
+		})
+	}
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	h := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/agents", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("legacy route status = %d", rec.Code)
+	}
+	if got := rec.Header().Get("Deprecation"); !strings.HasPrefix(got, "@") {
+		t.Errorf("Deprecation = %q, want an @timestamp", got)
+	}
+	if got := rec.Header().Get("Sunset"); got != legacySunset.Format(http.TimeFormat) {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := rec.Header().Get("Link"); got != `</api/v1/agents>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+	if got := rec.Header().Get(VersionHeader); got != "1" {
+		t.Errorf("%s = %q, want 1", VersionHeader, got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil))
+	if rec.Header().Get("Deprecation") != "" || rec.Header().Get("Sunset") != "" {
+		t.Errorf("versioned route marked deprecated: %v", rec.Header())
+	}
+}
@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/go-chi/chi/v5"
+)
+
+// Bounds of ?timeout= on GET /tasks/{id}/wait
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 10 * time.Minute
+)
+
+// parseWaitTimeout reads ?timeout= as a duration like 60s or a number of
+// seconds
+func parseWaitTimeout(r *http.Request) (time.Duration, []FieldError) {
+	v := r.URL.Query().Get("timeout")
+	if v == "" {
+		return defaultWaitTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		n, nerr := strconv.Atoi(v)
+		if nerr != nil {
+			return 0, []FieldError{{Field: "timeout", Message: "must be a duration like 60s or a number of seconds"}}
+		}
+		d = time.Duration(n) * time.Second
+	}
+	if d < 0 || d > maxWaitTimeout {
+		return 0, []FieldError{{Field: "timeout", Message: fmt.Sprintf("must be between 0 and %s", maxWaitTimeout)}}
+	}
+	return d, nil
+}
+
+// handleWaitTask holds the request until the task finishes or ?timeout=
+// elapses. A finished task is returned with 200 and its result; one still
+// running when the timeout elapses, or when the server shuts down, with
+// 202, so scripts can poll again.
+func (s *APIServer) handleWaitTask(w http.ResponseWriter, r *http.Request) {
+	timeout, details := parseWaitTimeout(r)
+	if len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeInvalidParameter, "invalid timeout", details...)
+		return
+	}
+
+	// The wait may outlast the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second))
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
+
+	start := time.Now()
+	task, err := s.agentRegistry.WaitTask(ctx, chi.URLParam(r, "taskID"))
+	switch {
+	case errors.Is(err, agents.ErrTaskNotFound):
+		s.writeErrorCode(w, http.StatusNotFound, CodeTaskNotFound, "task not found")
+		return
+	case r.Context().Err() != nil:
+		// The client is gone
+		return
+	}
+
+	waited := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		s.writeJSON(w, http.StatusAccepted, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"task":     task,
+				"status":   task.Status,
+				"finished": false,
+				"waited":   waited.String(),
+			},
+			Message:   fmt.Sprintf("Task %s is still %s", task.ID, task.Status),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"task":     task,
+			"status":   task.Status,
+			"finished": true,
+			"result":   task.Result,
+			"waited":   waited.String(),
+		},
+		Message:   fmt.Sprintf("Task %s %s", task.ID, task.Status),
+		Timestamp: time.Now(),
+	})
+}
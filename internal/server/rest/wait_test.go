@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestWaitTask(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	agent, _ := registry.CreateAgent("a", "coder", nil)
+	task := registry.CreateTask(&agents.Task{Title: "build"})
+	registry.AssignTask(task.ID, agent.ID)
+	handler := NewServer(ctx, 0, "localhost", nil, registry).setupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	path := "/api/v1/tasks/" + task.ID + "/wait"
+
+	if rec := get(path + "?timeout=soon"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad timeout: %d", rec.Code)
+	}
+	if rec := get(path + "?timeout=1h"); rec.Code != http.StatusBadRequest {
+		t.Errorf("timeout over the maximum: %d", rec.Code)
+	}
+	if rec := get("/api/v1/tasks/missing/wait"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing task: %d", rec.Code)
+	}
+
+	rec := get(path + "?timeout=20ms")
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"finished":false`) {
+		t.Fatalf("unfinished task: %d %s", rec.Code, rec.Body)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		registry.CompleteTask(task.ID, &agents.TaskResult{Success: true, Output: "built"})
+	}()
+	rec = get(path + "?timeout=5")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `"finished":true`) || !strings.Contains(body, `"output":"built"`) {
+		t.Fatalf("finished task: %d %s", rec.Code, body)
+	}
+}
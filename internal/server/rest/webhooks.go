@@ -0,0 +1,219 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/webhooks"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetWebhookManager enables the outgoing webhook endpoints
+func (s *APIServer) SetWebhookManager(m *webhooks.Manager) {
+	s.webhooks = m
+}
+
+// SetCallbackDispatcher enables the task callback dead-letter endpoint
+func (s *APIServer) SetCallbackDispatcher(d *webhooks.CallbackDispatcher) {
+	s.callbacks = d
+}
+
+// WebhookRequest registers a webhook. Active defaults to true.
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Secret      string   `json:"secret,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// requireWebhooks reports whether a webhook manager is configured
+func (s *APIServer) requireWebhooks(w http.ResponseWriter) bool {
+	if s.webhooks == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "webhooks not available")
+		return false
+	}
+	return true
+}
+
+// writeWebhookError maps webhook manager errors to responses
+func (s *APIServer) writeWebhookError(w http.ResponseWriter, err error) {
+	var invalid *webhooks.ValidationError
+	switch {
+	case errors.Is(err, webhooks.ErrNotFound):
+		s.writeErrorCode(w, http.StatusNotFound, CodeWebhookNotFound, "webhook not found")
+	case errors.As(err, &invalid):
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "invalid webhook",
+			FieldError{Field: invalid.Field, Message: invalid.Message})
+	default:
+		s.writeError(w, http.StatusInternalServerError, "failed to save webhook")
+	}
+}
+
+func (s *APIServer) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w) {
+		return
+	}
+	list := s.webhooks.List()
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"webhooks": list,
+			"count":    len(list),
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleCreateWebhook registers a webhook. The response is the only one
+// that includes the signing secret.
+func (s *APIServer) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w) {
+		return
+	}
+
+	var req WebhookRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if details := requireFields(map[string]string{"url": req.URL}); len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "missing required fields", details...)
+		return
+	}
+
+	active := req.Active == nil || *req.Active
+	sub, err := s.webhooks.Create(webhooks.Subscription{
+		URL:         req.URL,
+		Events:      req.Events,
+		Description: req.Description,
+		Secret:      req.Secret,
+		Active:      active,
+	})
+	if err != nil {
+		s.writeWebhookError(w, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"webhook": sub},
+		Message:   "Webhook created; store the secret, it is not shown again",
+		Timestamp: time.Now(),
+	})
+}
+
+func (s *APIServer) handleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w) {
+		return
+	}
+	sub, err := s.webhooks.Get(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		s.writeWebhookError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"webhook": sub},
+		Timestamp: time.Now(),
+	})
+}
+
+func (s *APIServer) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w) {
+		return
+	}
+
+	var req webhooks.SubscriptionUpdate
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	sub, err := s.webhooks.Update(chi.URLParam(r, "webhookID"), req)
+	if err != nil {
+		s.writeWebhookError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"webhook": sub},
+		Message:   "Webhook updated",
+		Timestamp: time.Now(),
+	})
+}
+
+func (s *APIServer) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w) {
+		return
+	}
+	if err := s.webhooks.Delete(chi.URLParam(r, "webhookID")); err != nil {
+		s.writeWebhookError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Message:   "Webhook deleted",
+		Timestamp: time.Now(),
+	})
+}
+
+// handleListWebhookDeliveries returns the recent deliveries of a webhook,
+// newest first, with the receiver's status and response for debugging
+func (s *APIServer) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w) {
+		return
+	}
+	list, err := s.webhooks.Deliveries(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		s.writeWebhookError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"deliveries": list,
+			"count":      len(list),
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handlePingWebhook sends a "ping" event and waits for its outcome
+func (s *APIServer) handlePingWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w) {
+		return
+	}
+	d, err := s.webhooks.Ping(r.Context(), chi.URLParam(r, "webhookID"))
+	if err != nil {
+		s.writeWebhookError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"delivery": d},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleListDeadLetters returns the task callbacks that failed every
+// delivery attempt, with the payload that was sent
+func (s *APIServer) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if s.callbacks == nil {
+		s.writeErrorCode(w, http.StatusServiceUnavailable, CodeServiceUnavailable, "task callbacks not available")
+		return
+	}
+	list, err := s.callbacks.DeadLetters()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to read the dead-letter log")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"dead_letters": list,
+			"count":        len(list),
+		},
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/webhooks"
+)
+
+func TestWebhookCRUD(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer(ctx, 0, "localhost", nil, agents.NewRegistry(ctx))
+	m, err := webhooks.NewManager("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetWebhookManager(m)
+	handler := s.setupRoutes()
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, APIResponse) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var resp APIResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	rec, resp := do(http.MethodPost, "/api/v1/webhooks", `{"url": "https://ci.example.com/hook", "events": ["task.completed", "agent.error"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+	created := resp.Data["webhook"].(map[string]interface{})
+	if created["secret"] == "" || created["active"] != true {
+		t.Fatalf("created webhook: %v", created)
+	}
+	id := created["id"].(string)
+
+	rec, _ = do(http.MethodGet, "/api/v1/webhooks/"+id, "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("get: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec, resp = do(http.MethodPost, "/api/v1/webhooks", `{"url": "https://ci.example.com/hook", "events": ["nope"]}`)
+	if rec.Code != http.StatusBadRequest || resp.Error.Code != CodeValidationFailed || resp.Error.Details[0].Field != "events" {
+		t.Fatalf("invalid event: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec, _ = do(http.MethodPut, "/api/v1/webhooks/"+id, `{"active": false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status %d: %s", rec.Code, rec.Body)
+	}
+	if got, _ := m.Get(id); got.Active {
+		t.Fatal("webhook still active")
+	}
+
+	rec, _ = do(http.MethodDelete, "/api/v1/webhooks/"+id, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete: status %d: %s", rec.Code, rec.Body)
+	}
+	rec, resp = do(http.MethodGet, "/api/v1/webhooks/"+id+"/deliveries", "")
+	if rec.Code != http.StatusNotFound || resp.Error.Code != CodeWebhookNotFound {
+		t.Fatalf("deliveries of deleted webhook: status %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,225 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// WorkspaceHeader names the workspace a request works in
+const WorkspaceHeader = "X-Workspace"
+
+// WorkspaceRequest creates a workspace
+type WorkspaceRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type workspaceKey struct{}
+
+// requestWorkspace is the workspace the request works in: the one lists
+// show and creates use
+func requestWorkspace(r *http.Request) string {
+	if ws, ok := r.Context().Value(workspaceKey{}).(string); ok {
+		return ws
+	}
+	return agents.DefaultWorkspace
+}
+
+// workspaceMiddleware resolves the workspace of a request from the
+// X-Workspace header or ?workspace=. Without either, a key bound to
+// workspaces works in the first of them and any other caller in the
+// default workspace.
+func (s *APIServer) workspaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws := r.Header.Get(WorkspaceHeader)
+		if ws == "" {
+			ws = r.URL.Query().Get("workspace")
+		}
+		if ws == "" {
+			ws = agents.DefaultWorkspace
+			if p, ok := auth.PrincipalFromContext(r.Context()); ok && p.Bound() {
+				ws = p.Workspaces[0]
+			}
+		}
+
+		if !auth.CanAccess(r.Context(), ws) {
+			s.writeErrorCode(w, http.StatusForbidden, CodeWorkspaceForbidden, "API key is not bound to workspace "+ws)
+			return
+		}
+		if _, ok := s.agentRegistry.GetWorkspace(ws); !ok {
+			s.writeErrorCode(w, http.StatusNotFound, CodeWorkspaceNotFound, "workspace "+ws+" not found")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), workspaceKey{}, ws)))
+	})
+}
+
+// owned hides the agent, task, session or artifact a route names from
+// callers whose key is not bound to its workspace: they get the same 404
+// as for a resource that does not exist. Resources that are not found are
+// left to the handler.
+func (s *APIServer) owned(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ws, code, ok := s.ownerOf(r); ok && !auth.CanAccess(r.Context(), ws) {
+			s.writeErrorCode(w, http.StatusNotFound, code, "resource not found")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ownerOf finds the workspace of the resource a route names, with the code
+// reported when it is not found
+func (s *APIServer) ownerOf(r *http.Request) (string, ErrorCode, bool) {
+	if id := chi.URLParam(r, "agentID"); id != "" {
+		agent, ok := s.agentRegistry.GetAgentView(id)
+		return agent.Workspace, CodeAgentNotFound, ok
+	}
+	if id := chi.URLParam(r, "taskID"); id != "" {
+		return s.taskWorkspace(id)
+	}
+	if id := chi.URLParam(r, "sessionID"); id != "" && s.engine != nil {
+		session, ok := s.engine.SessionSnapshot(id)
+		return session.Workspace, CodeSessionNotFound, ok
+	}
+	if id := chi.URLParam(r, "artifactID"); id != "" && s.artifacts != nil {
+		a, content, err := s.artifacts.Open(id)
+		if err != nil {
+			return "", CodeArtifactNotFound, false
+		}
+		content.Close()
+		ws, _, ok := s.taskWorkspace(a.TaskID)
+		return ws, CodeArtifactNotFound, ok
+	}
+	return "", "", false
+}
+
+// taskWorkspace finds the workspace of a task, of a deleted task from its
+// history, or of an archived task
+func (s *APIServer) taskWorkspace(id string) (string, ErrorCode, bool) {
+	if task, ok := s.agentRegistry.GetTask(id); ok {
+		return task.Workspace, CodeTaskNotFound, true
+	}
+	if history, ok := s.agentRegistry.TaskHistory(id); ok && len(history) > 0 {
+		return history[0].Workspace, CodeTaskNotFound, true
+	}
+	if task, err := s.agentRegistry.ArchivedTask(id); err == nil {
+		return task.Workspace, CodeTaskNotFound, true
+	}
+	return "", CodeTaskNotFound, false
+}
+
+// workspaceAgents lists the agents of the request's workspace
+func (s *APIServer) workspaceAgents(r *http.Request) []agents.AgentView {
+	ws := requestWorkspace(r)
+	all := s.agentRegistry.ListAgentViews()
+	out := make([]agents.AgentView, 0, len(all))
+	for _, a := range all {
+		if a.Workspace == ws {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// workspaceTasks lists the tasks of the request's workspace
+func (s *APIServer) workspaceTasks(r *http.Request) []*agents.Task {
+	ws := requestWorkspace(r)
+	all := s.agentRegistry.ListTasks()
+	out := make([]*agents.Task, 0, len(all))
+	for _, t := range all {
+		if t.Workspace == ws {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// visibleWorkspaces keeps the workspaces the caller may use
+func visibleWorkspaces(r *http.Request, all []*agents.Workspace) []*agents.Workspace {
+	out := make([]*agents.Workspace, 0, len(all))
+	for _, ws := range all {
+		if auth.CanAccess(r.Context(), ws.Name) {
+			out = append(out, ws)
+		}
+	}
+	return out
+}
+
+func (s *APIServer) handleListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	workspaces := visibleWorkspaces(r, s.agentRegistry.ListWorkspaces())
+	writeList(s, w, http.StatusOK, "workspaces", workspaces, map[string]interface{}{"count": len(workspaces)})
+}
+
+func (s *APIServer) handleGetWorkspace(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "workspace")
+	ws, ok := s.agentRegistry.GetWorkspace(name)
+	if !ok || !auth.CanAccess(r.Context(), ws.Name) {
+		s.writeErrorCode(w, http.StatusNotFound, CodeWorkspaceNotFound, "workspace not found")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"workspace": ws},
+		Timestamp: time.Now(),
+	})
+}
+
+// requireUnbound rejects keys bound to workspaces, which may not create or
+// delete them
+func (s *APIServer) requireUnbound(w http.ResponseWriter, r *http.Request) bool {
+	if p, ok := auth.PrincipalFromContext(r.Context()); ok && p.Bound() {
+		s.writeErrorCode(w, http.StatusForbidden, CodeWorkspaceForbidden, "API keys bound to workspaces cannot manage workspaces")
+		return false
+	}
+	return true
+}
+
+func (s *APIServer) handleCreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnbound(w, r) {
+		return
+	}
+	var req WorkspaceRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeDecodeError(w, err)
+		return
+	}
+	if details := requireFields(map[string]string{"name": req.Name}); len(details) > 0 {
+		s.writeErrorCode(w, http.StatusBadRequest, CodeValidationFailed, "invalid workspace", details...)
+		return
+	}
+
+	ws, err := s.agentRegistry.CreateWorkspace(req.Name, req.Description)
+	if err != nil {
+		s.writeRegistryError(w, err)
+		return
+	}
+	w.Header().Set("Location", r.URL.Path+"/"+ws.Name)
+	s.writeJSON(w, http.StatusCreated, APIResponse{
+		Success:   true,
+		Data:      map[string]interface{}{"workspace": ws},
+		Message:   "Workspace created successfully",
+		Timestamp: time.Now(),
+	})
+}
+
+func (s *APIServer) handleDeleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnbound(w, r) {
+		return
+	}
+	name := chi.URLParam(r, "workspace")
+	if err := s.agentRegistry.DeleteWorkspace(name); err != nil {
+		s.writeRegistryError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, APIResponse{
+		Success:   true,
+		Message:   "Workspace " + name + " deleted",
+		Timestamp: time.Now(),
+	})
+}
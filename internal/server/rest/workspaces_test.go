@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/auth"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestWorkspaceBoundKeys(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	registry.CreateWorkspace("team-a", "")
+	registry.CreateWorkspace("team-b", "")
+	s := NewServer(ctx, 0, "localhost", nil, registry)
+	authz, err := auth.New(config.AuthConfig{Keys: map[string]config.APIKeyConfig{
+		"admin": {Token: "admin-token", Role: string(auth.RoleAdmin)},
+		"a":     {Token: "a-token", Role: string(auth.RoleOperator), Workspaces: []string{"team-a"}},
+		"b":     {Token: "b-token", Role: string(auth.RoleOperator), Workspaces: []string{"team-b"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetAuthorizer(authz)
+	handler := s.setupRoutes()
+
+	do := func(method, path, token, workspace, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if workspace != "" {
+			req.Header.Set(WorkspaceHeader, workspace)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/v1/agents", "a-token", "", `{"name":"a","type":"coder"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create agent: %d %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		Data struct {
+			Agent agents.AgentView `json:"agent"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	agent := created.Data.Agent
+	if agent.Workspace != "team-a" {
+		t.Fatalf("agent created in %q, want the key's workspace", agent.Workspace)
+	}
+
+	if rec := do(http.MethodGet, "/api/v1/agents/"+agent.ID, "b-token", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("other workspace read the agent: %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/v1/agents", "b-token", "", ""); !strings.Contains(rec.Body.String(), `"count":0`) {
+		t.Errorf("other workspace listed the agent: %s", rec.Body)
+	}
+	if rec := do(http.MethodGet, "/api/v1/agents", "b-token", "team-a", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("key bound to team-b worked in team-a: %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/v1/agents/"+agent.ID, "a-token", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("own workspace: %d", rec.Code)
+	}
+
+	if rec := do(http.MethodPost, "/api/v1/tasks", "b-token", "", `{"task":"x","agent_id":"`+agent.ID+`"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("task for an agent of another workspace: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/api/v1/tasks", "admin-token", "team-b", `{"task":"x","agent_id":"`+agent.ID+`"}`); rec.Code != http.StatusConflict {
+		t.Errorf("cross-workspace assignment: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := do(http.MethodPost, "/api/v1/workspaces", "a-token", "", `{"name":"team-c"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("bound key created a workspace: %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/v1/workspaces", "a-token", "", ""); !strings.Contains(rec.Body.String(), `"count":1`) {
+		t.Errorf("bound key sees other workspaces: %s", rec.Body)
+	}
+	if rec := do(http.MethodPost, "/api/v1/workspaces", "admin-token", "", `{"name":"team-c"}`); rec.Code != http.StatusCreated {
+		t.Errorf("create workspace: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodDelete, "/api/v1/workspaces/team-a", "admin-token", "", ""); rec.Code != http.StatusConflict {
+		t.Errorf("deleted a workspace with agents: %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/v1/agents", "admin-token", "nope", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown workspace: %d", rec.Code)
+	}
+}
@@ -0,0 +1,73 @@
+// Package unixsock opens the Unix domain sockets the REST and MCP servers
+// can listen on besides their TCP ports. Local clients such as editors and
+// other command-line tools reach the servers without a network port, and
+// the socket's file mode decides which local users may connect.
+package unixsock
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DefaultMode lets only the owner of the process connect
+const DefaultMode os.FileMode = 0o600
+
+// ParseMode parses an octal file mode such as "0660"; the empty string
+// is DefaultMode
+func ParseMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return DefaultMode, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid socket mode %q: want octal permissions such as 0600", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Listen listens on a Unix domain socket at path with the given file
+// mode, creating its directory if needed. A socket left behind by a
+// process that is gone is replaced; one that still accepts connections,
+// or a file that is not a socket, is an error. Closing the listener
+// removes the socket.
+func Listen(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStale(path); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("setting the mode of %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// removeStale removes the socket at path if nothing listens on it
+func removeStale(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
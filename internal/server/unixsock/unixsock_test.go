@@ -0,0 +1,61 @@
+package unixsock
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]os.FileMode{"": DefaultMode, "0660": 0o660, "600": 0o600} {
+		if got, err := ParseMode(in); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %o, %v; want %o", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"rw", "0999", "10000"} {
+		if _, err := ParseMode(in); err == nil {
+			t.Errorf("ParseMode(%q) should fail", in)
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	// Socket paths are short on some systems, so stay near the root
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "run", "api.sock")
+
+	ln, err := Listen(path, 0o660)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o660 {
+		t.Errorf("socket mode: %v %v", info.Mode(), err)
+	}
+	if _, err := Listen(path, DefaultMode); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("second listener: %v", err)
+	}
+
+	// A socket nobody listens on is replaced
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	ln, err = Listen(path, DefaultMode)
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	ln.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket left after Close: %v", err)
+	}
+
+	regular := filepath.Join(dir, "file")
+	os.WriteFile(regular, nil, 0o600)
+	if _, err := Listen(regular, DefaultMode); err == nil {
+		t.Error("a regular file should not be replaced")
+	}
+}
@@ -0,0 +1,13 @@
+// Package service runs the headless daemon as a Windows service and
+// registers it with the service control manager. On other systems the
+// daemon is left to systemd, launchd or a process supervisor, and every
+// function reports ErrUnsupported.
+package service
+
+import "errors"
+
+// DefaultName is the service name used when none is given
+const DefaultName = "skagent"
+
+// ErrUnsupported is returned on systems without Windows services
+var ErrUnsupported = errors.New("Windows services are only available on Windows; run skagent headless --daemon under systemd, launchd or another supervisor")
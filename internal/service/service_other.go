@@ -0,0 +1,25 @@
+//go:build !windows
+
+package service
+
+// IsService reports whether the process was started by the Windows
+// service control manager
+func IsService() (bool, error) {
+	return false, nil
+}
+
+// Run runs the daemon as the service name; see the Windows implementation
+func Run(name string, start func() error, stop func()) error {
+	return ErrUnsupported
+}
+
+// Install registers the running executable as the service name, started
+// with args
+func Install(name string, args ...string) error {
+	return ErrUnsupported
+}
+
+// Uninstall removes the service name
+func Uninstall(name string) error {
+	return ErrUnsupported
+}
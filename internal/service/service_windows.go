@@ -0,0 +1,117 @@
+//go:build windows
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// IsService reports whether the process was started by the Windows
+// service control manager
+func IsService() (bool, error) {
+	return svc.IsWindowsService()
+}
+
+// Run reports to the service control manager as the service name. start
+// runs the daemon and returns once it has stopped; stop asks it to stop
+// and is called when the service is stopped or the system shuts down.
+func Run(name string, start func() error, stop func()) error {
+	return svc.Run(name, &handler{start: start, stop: stop})
+}
+
+type handler struct {
+	start func() error
+	stop  func()
+}
+
+// Execute implements svc.Handler
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepted = svc.AcceptStop | svc.AcceptShutdown
+	status <- svc.Status{State: svc.StartPending}
+
+	done := make(chan error, 1)
+	go func() { done <- h.start() }()
+	status <- svc.Status{State: svc.Running, Accepts: accepted}
+
+	for {
+		select {
+		case err := <-done:
+			// The daemon stopped on its own, e.g. through the API
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				h.stop()
+				if err := <-done; err != nil {
+					return true, 1
+				}
+				return false, 0
+			}
+		}
+	}
+}
+
+// Install registers the running executable as the service name, started
+// automatically with args and restarted if it fails
+func Install(name string, args ...string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to the service manager (run as administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", name)
+	}
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: "SkAgent",
+		Description: "SkAgent headless daemon: REST and MCP servers for the agent registry",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 10 * time.Second}
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, 24*60*60); err != nil {
+		s.Delete()
+		return fmt.Errorf("setting the recovery actions: %w", err)
+	}
+	return nil
+}
+
+// Uninstall stops the service name if it runs and removes it
+func Uninstall(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to the service manager (run as administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+	if st, err := s.Query(); err == nil && st.State != svc.Stopped {
+		s.Control(svc.Stop)
+	}
+	return s.Delete()
+}
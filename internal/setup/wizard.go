@@ -1,7 +1,6 @@
 package setup
 
 import (
-	"fmt"
 	"os/exec"
 	"strings"
 
@@ -10,6 +9,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/i18n"
 )
 
 // Styles
@@ -80,7 +80,7 @@ type ModelItem struct {
 func (i ModelItem) FilterValue() string { return i.model.Name }
 func (i ModelItem) Title() string       { return i.model.Name }
 func (i ModelItem) Description() string {
-	return fmt.Sprintf("%s | %dk context | %s", i.model.Provider, i.model.ContextLength/1000, i.model.Description)
+	return i18n.T("wizard.model.description", i.model.Provider, i.model.ContextLength/1000, i.model.Description)
 }
 
 // Model is the setup wizard model
@@ -105,57 +105,57 @@ func NewWizard() Model {
 	providers := []list.Item{
 		ProviderItem{
 			provider:    config.ProviderOpenRouter,
-			name:        "🌐 OpenRouter (Free Models)",
-			description: "35+ free models including Qwen Coder, DeepSeek R1, Llama 3.3 70B",
+			name:        i18n.T("wizard.provider.openrouter"),
+			description: i18n.T("wizard.desc.openrouter"),
 			authType:    "api_key",
 			available:   true,
 		},
 		ProviderItem{
 			provider:    config.ProviderClaudeMax,
 			name:        "🔮 Claude Max (OAuth)",
-			description: "Use your Claude Max subscription - no API costs",
+			description: i18n.T("wizard.desc.claude"),
 			authType:    "oauth",
 			available:   checkClaudeMaxAvailable(),
 		},
 		ProviderItem{
 			provider:    config.ProviderGeminiCLI,
 			name:        "🔷 Gemini CLI",
-			description: "Use Google's Gemini via CLI - free tier available",
+			description: i18n.T("wizard.desc.gemini"),
 			authType:    "cli",
 			available:   checkGeminiCLIAvailable(),
 		},
 		ProviderItem{
 			provider:    config.ProviderCodex,
 			name:        "🟢 OpenAI Codex CLI",
-			description: "Use OpenAI Codex via CLI",
+			description: i18n.T("wizard.desc.codex"),
 			authType:    "cli",
 			available:   checkCodexAvailable(),
 		},
 		ProviderItem{
 			provider:    config.ProviderKimi,
 			name:        "🌙 Kimi (Moonshot)",
-			description: "Moonshot AI's Kimi model - free tier available",
+			description: i18n.T("wizard.desc.kimi"),
 			authType:    "api_key",
 			available:   true,
 		},
 		ProviderItem{
 			provider:    config.ProviderGLM,
 			name:        "🇨🇳 GLM-4 (Zhipu)",
-			description: "Zhipu's GLM-4 - Chinese-optimized with free tier",
+			description: i18n.T("wizard.desc.glm"),
 			authType:    "api_key",
 			available:   true,
 		},
 		ProviderItem{
 			provider:    config.ProviderDeepSeek,
 			name:        "🔍 DeepSeek",
-			description: "DeepSeek models - very affordable",
+			description: i18n.T("wizard.desc.deepseek"),
 			authType:    "api_key",
 			available:   true,
 		},
 		ProviderItem{
 			provider:    config.ProviderMinimax,
 			name:        "📦 Minimax",
-			description: "Minimax models with coding focus",
+			description: i18n.T("wizard.desc.minimax"),
 			authType:    "api_key",
 			available:   true,
 		},
@@ -163,7 +163,7 @@ func NewWizard() Model {
 
 	providerDelegate := list.NewDefaultDelegate()
 	providerList := list.New(providers, providerDelegate, 60, 15)
-	providerList.Title = "Select AI Provider"
+	providerList.Title = i18n.T("wizard.provider.list")
 	providerList.SetShowHelp(false)
 
 	// Create model list with free models
@@ -174,12 +174,12 @@ func NewWizard() Model {
 
 	modelDelegate := list.NewDefaultDelegate()
 	modelList := list.New(modelItems, modelDelegate, 60, 15)
-	modelList.Title = "Select Model"
+	modelList.Title = i18n.T("wizard.model.list")
 	modelList.SetShowHelp(false)
 
 	// Text input for API keys
 	ti := textinput.New()
-	ti.Placeholder = "Enter your API key..."
+	ti.Placeholder = i18n.T("wizard.key.placeholder")
 	ti.CharLimit = 200
 	ti.Width = 50
 
@@ -251,7 +251,7 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 			switch item.provider {
 			case config.ProviderOpenRouter:
 				providerCfg.BaseURL = "https://openrouter.ai/api/v1"
-				m.inputLabel = "OpenRouter API Key (get free at openrouter.ai):"
+				m.inputLabel = i18n.T("wizard.key.free", "OpenRouter", "openrouter.ai")
 				m.textInput.Placeholder = "sk-or-v1-..."
 				m.textInput.Focus()
 				m.step = StepConfigureProvider
@@ -259,7 +259,7 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 			case config.ProviderClaudeMax:
 				// OAuth login
 				m.step = StepConfigureProvider
-				m.inputLabel = "Starting Claude Max OAuth login..."
+				m.inputLabel = i18n.T("wizard.claude.login")
 				return m, m.startClaudeOAuth()
 
 			case config.ProviderGeminiCLI:
@@ -267,34 +267,34 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 				if checkGeminiCLIAvailable() {
 					m.step = StepTestConnection
 				} else {
-					m.inputLabel = "Run 'gemini auth login' first, then press Enter"
+					m.inputLabel = i18n.T("wizard.gemini.login")
 					m.step = StepConfigureProvider
 				}
 
 			case config.ProviderKimi:
 				providerCfg.BaseURL = "https://api.moonshot.cn/v1"
-				m.inputLabel = "Kimi API Key (get at platform.moonshot.cn):"
+				m.inputLabel = i18n.T("wizard.key.site", "Kimi", "platform.moonshot.cn")
 				m.textInput.Placeholder = "sk-..."
 				m.textInput.Focus()
 				m.step = StepConfigureProvider
 
 			case config.ProviderGLM:
 				providerCfg.BaseURL = "https://open.bigmodel.cn/api/paas/v4"
-				m.inputLabel = "GLM API Key (get at open.bigmodel.cn):"
+				m.inputLabel = i18n.T("wizard.key.site", "GLM", "open.bigmodel.cn")
 				m.textInput.Placeholder = "..."
 				m.textInput.Focus()
 				m.step = StepConfigureProvider
 
 			case config.ProviderDeepSeek:
 				providerCfg.BaseURL = "https://api.deepseek.com/v1"
-				m.inputLabel = "DeepSeek API Key (get at platform.deepseek.com):"
+				m.inputLabel = i18n.T("wizard.key.site", "DeepSeek", "platform.deepseek.com")
 				m.textInput.Placeholder = "sk-..."
 				m.textInput.Focus()
 				m.step = StepConfigureProvider
 
 			case config.ProviderMinimax:
 				providerCfg.BaseURL = "https://api.minimax.chat/v1"
-				m.inputLabel = "Minimax API Key:"
+				m.inputLabel = i18n.T("wizard.key.plain", "Minimax")
 				m.textInput.Placeholder = "..."
 				m.textInput.Focus()
 				m.step = StepConfigureProvider
@@ -350,75 +350,72 @@ func (m Model) View() string {
 	var s strings.Builder
 
 	// Header
-	s.WriteString(titleStyle.Render("🚀 SkAgent Setup Wizard"))
+	s.WriteString(titleStyle.Render(i18n.T("wizard.title")))
 	s.WriteString("\n\n")
 
 	switch m.step {
 	case StepWelcome:
-		s.WriteString(subtitleStyle.Render("Welcome to SkAgent!"))
+		s.WriteString(subtitleStyle.Render(i18n.T("wizard.welcome.title")))
 		s.WriteString("\n\n")
-		s.WriteString("SkAgent is an AI-powered spec-driven development assistant.\n")
-		s.WriteString("Let's configure your AI provider.\n\n")
-		s.WriteString("Available options:\n")
-		s.WriteString(itemStyle.Render("• OpenRouter - 35+ FREE models (no cost!)\n"))
-		s.WriteString(itemStyle.Render("• Claude Max - Use your subscription\n"))
-		s.WriteString(itemStyle.Render("• Gemini/Codex CLI - Free tiers available\n"))
-		s.WriteString(itemStyle.Render("• Kimi, GLM, DeepSeek, Minimax - Free/cheap options\n"))
+		s.WriteString(i18n.T("wizard.welcome.intro"))
+		s.WriteString(i18n.T("wizard.welcome.options"))
+		s.WriteString(itemStyle.Render(i18n.T("wizard.welcome.openrouter")))
+		s.WriteString(itemStyle.Render(i18n.T("wizard.welcome.claude")))
+		s.WriteString(itemStyle.Render(i18n.T("wizard.welcome.cli")))
+		s.WriteString(itemStyle.Render(i18n.T("wizard.welcome.others")))
 		s.WriteString("\n\n")
-		s.WriteString(helpStyle.Render("Press Enter to continue, Ctrl+C to quit"))
+		s.WriteString(helpStyle.Render(i18n.T("wizard.welcome.help")))
 
 	case StepSelectProvider:
-		s.WriteString(subtitleStyle.Render("Step 1: Select AI Provider"))
+		s.WriteString(subtitleStyle.Render(i18n.T("wizard.provider.step")))
 		s.WriteString("\n")
 		s.WriteString(m.providerList.View())
 		s.WriteString("\n")
-		s.WriteString(helpStyle.Render("↑/↓ to navigate, Enter to select, Esc to go back"))
+		s.WriteString(helpStyle.Render(i18n.T("wizard.provider.help")))
 
 	case StepConfigureProvider:
-		s.WriteString(subtitleStyle.Render("Step 2: Configure Provider"))
+		s.WriteString(subtitleStyle.Render(i18n.T("wizard.configure.step")))
 		s.WriteString("\n\n")
 		s.WriteString(m.inputLabel)
 		s.WriteString("\n\n")
 		s.WriteString(m.textInput.View())
 		s.WriteString("\n\n")
-		s.WriteString(helpStyle.Render("Enter to continue, Esc to go back"))
+		s.WriteString(helpStyle.Render(i18n.T("wizard.configure.help")))
 
 	case StepSelectModel:
-		s.WriteString(subtitleStyle.Render("Step 3: Select Model"))
+		s.WriteString(subtitleStyle.Render(i18n.T("wizard.model.step")))
 		s.WriteString("\n")
-		s.WriteString(descStyle.Render("Recommended models are marked with ⭐"))
+		s.WriteString(descStyle.Render(i18n.T("wizard.model.recommended")))
 		s.WriteString("\n")
 		s.WriteString(m.modelList.View())
 		s.WriteString("\n")
-		s.WriteString(helpStyle.Render("↑/↓ to navigate, Enter to select, / to filter"))
+		s.WriteString(helpStyle.Render(i18n.T("wizard.model.help")))
 
 	case StepTestConnection:
-		s.WriteString(subtitleStyle.Render("Step 4: Testing Connection"))
+		s.WriteString(subtitleStyle.Render(i18n.T("wizard.test.step")))
 		s.WriteString("\n\n")
 		if m.testing {
-			s.WriteString("Testing connection...\n")
+			s.WriteString(i18n.T("wizard.test.testing") + "\n")
 		} else if m.err != nil {
-			s.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+			s.WriteString(errorStyle.Render(i18n.T("wizard.error", m.err)))
 			s.WriteString("\n")
 		} else {
-			s.WriteString(successStyle.Render("✓ Configuration saved!"))
+			s.WriteString(successStyle.Render(i18n.T("wizard.test.saved")))
 			s.WriteString("\n\n")
-			s.WriteString(fmt.Sprintf("Provider: %s\n", m.selectedProvider))
+			s.WriteString(i18n.T("wizard.test.provider", m.selectedProvider) + "\n")
 			cfg := m.config.Providers[m.selectedProvider]
 			if cfg.Model != "" {
-				s.WriteString(fmt.Sprintf("Model: %s\n", cfg.Model))
+				s.WriteString(i18n.T("wizard.test.model", cfg.Model) + "\n")
 			}
 		}
 		s.WriteString("\n")
-		s.WriteString(helpStyle.Render("Press Enter to finish"))
+		s.WriteString(helpStyle.Render(i18n.T("wizard.test.help")))
 
 	case StepComplete:
-		s.WriteString(successStyle.Render("🎉 Setup Complete!"))
+		s.WriteString(successStyle.Render(i18n.T("wizard.complete.title")))
 		s.WriteString("\n\n")
-		s.WriteString("Your configuration has been saved.\n\n")
-		s.WriteString("You can now run skagent to start using the assistant.\n")
-		s.WriteString("Use /help in the app to see available commands.\n\n")
-		s.WriteString(helpStyle.Render("Press Enter or Ctrl+C to exit"))
+		s.WriteString(i18n.T("wizard.complete.body"))
+		s.WriteString(helpStyle.Render(i18n.T("wizard.complete.help")))
 	}
 
 	return s.String()
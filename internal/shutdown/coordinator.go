@@ -0,0 +1,195 @@
+// Package shutdown coordinates an ordered, graceful stop of the running
+// services.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/logging"
+)
+
+// Options controls a single shutdown
+type Options struct {
+	// Force skips draining in-flight tasks and gives each step only
+	// ForceTimeout to finish
+	Force bool
+	// Reason is recorded in the log, e.g. "signal" or "api"
+	Reason string
+}
+
+// StopFunc stops one component. force is true for a forced shutdown, in
+// which case the component should abort rather than wait for clients.
+type StopFunc func(ctx context.Context, force bool) error
+
+// DrainFunc blocks until in-flight work has finished or ctx is done
+type DrainFunc func(ctx context.Context) error
+
+type step struct {
+	name string
+	stop StopFunc
+}
+
+// Coordinator runs registered shutdown steps in order, exactly once
+type Coordinator struct {
+	// DrainTimeout bounds how long a graceful shutdown waits for work
+	DrainTimeout time.Duration
+	// StepTimeout bounds each step of a graceful shutdown
+	StepTimeout time.Duration
+	// ForceTimeout bounds each step of a forced shutdown
+	ForceTimeout time.Duration
+
+	mu      sync.Mutex
+	steps   []step
+	drain   DrainFunc
+	started bool
+	force   bool
+	abort   context.CancelFunc
+	done    chan struct{}
+	err     error
+	logger  *log.Logger
+}
+
+// New creates a coordinator with default timeouts
+func New() *Coordinator {
+	return &Coordinator{
+		DrainTimeout: 30 * time.Second,
+		StepTimeout:  10 * time.Second,
+		ForceTimeout: 2 * time.Second,
+		done:         make(chan struct{}),
+		logger:       logging.New("shutdown", "[SHUTDOWN] ", log.Writer()),
+	}
+}
+
+// SetDrain registers the function that waits for in-flight work
+func (c *Coordinator) SetDrain(fn DrainFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drain = fn
+}
+
+// Add appends a component to the shutdown order
+func (c *Coordinator) Add(name string, stop StopFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.steps = append(c.steps, step{name: name, stop: stop})
+}
+
+// Trigger starts a shutdown in the background and reports whether this
+// call started it. A forced trigger while a graceful shutdown is running
+// cuts the drain short and shortens the remaining steps.
+func (c *Coordinator) Trigger(opts Options) bool {
+	c.mu.Lock()
+	if c.started {
+		if opts.Force && !c.force {
+			c.force = true
+			if c.abort != nil {
+				c.abort()
+			}
+			c.logger.Printf("WARN: forced shutdown requested during graceful shutdown (%s)", opts.Reason)
+		}
+		c.mu.Unlock()
+		return false
+	}
+	c.started = true
+	c.force = opts.Force
+	c.mu.Unlock()
+
+	go c.run(opts)
+	return true
+}
+
+// Shutdown triggers a shutdown and waits for it to finish
+func (c *Coordinator) Shutdown(opts Options) error {
+	c.Trigger(opts)
+	<-c.done
+	return c.Err()
+}
+
+// Done is closed once every step has run
+func (c *Coordinator) Done() <-chan struct{} {
+	return c.done
+}
+
+// InProgress reports whether a shutdown has been triggered
+func (c *Coordinator) InProgress() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.started
+}
+
+// Err returns the combined step errors of a finished shutdown
+func (c *Coordinator) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Coordinator) forced() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.force
+}
+
+func (c *Coordinator) run(opts Options) {
+	defer close(c.done)
+
+	start := time.Now()
+	mode := "graceful"
+	if opts.Force {
+		mode = "forced"
+	}
+	c.logger.Printf("Starting %s shutdown (%s)", mode, opts.Reason)
+
+	c.mu.Lock()
+	steps := append([]step(nil), c.steps...)
+	drain := c.drain
+	c.mu.Unlock()
+
+	var errs []error
+
+	if drain != nil && !c.forced() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.DrainTimeout)
+		c.mu.Lock()
+		c.abort = cancel
+		if c.force {
+			cancel()
+		}
+		c.mu.Unlock()
+		if err := drain(ctx); err != nil {
+			c.logger.Printf("WARN: draining tasks did not finish: %v", err)
+		} else {
+			c.logger.Printf("In-flight tasks drained")
+		}
+		cancel()
+	}
+
+	for _, s := range steps {
+		force := c.forced()
+		timeout := c.StepTimeout
+		if force {
+			timeout = c.ForceTimeout
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := s.stop(ctx, force)
+		cancel()
+
+		if err != nil {
+			c.logger.Printf("ERROR: stopping %s failed: %v", s.name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			continue
+		}
+		c.logger.Printf("Stopped %s", s.name)
+	}
+
+	c.mu.Lock()
+	c.err = errors.Join(errs...)
+	c.mu.Unlock()
+
+	c.logger.Printf("Shutdown complete in %s", time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,83 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoordinator_RunsStepsInOrderOnce(t *testing.T) {
+	c := New()
+	var order []string
+	c.SetDrain(func(ctx context.Context) error {
+		order = append(order, "drain")
+		return nil
+	})
+	for _, name := range []string{"engine", "mcp", "rest"} {
+		name := name
+		c.Add(name, func(ctx context.Context, force bool) error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	if err := c.Shutdown(Options{Reason: "test"}); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if c.Trigger(Options{}) {
+		t.Error("second Trigger() started another shutdown")
+	}
+
+	want := []string{"drain", "engine", "mcp", "rest"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestCoordinator_ForceSkipsDrain(t *testing.T) {
+	c := New()
+	drained := false
+	c.SetDrain(func(ctx context.Context) error {
+		drained = true
+		return nil
+	})
+	var gotForce bool
+	c.Add("rest", func(ctx context.Context, force bool) error {
+		gotForce = force
+		return errors.New("boom")
+	})
+
+	err := c.Shutdown(Options{Force: true})
+	if drained {
+		t.Error("forced shutdown drained tasks")
+	}
+	if !gotForce {
+		t.Error("step was not told the shutdown is forced")
+	}
+	if err == nil {
+		t.Error("step error was not reported")
+	}
+}
+
+func TestCoordinator_ForceAbortsDrain(t *testing.T) {
+	c := New()
+	c.DrainTimeout = time.Minute
+	draining := make(chan struct{})
+	c.SetDrain(func(ctx context.Context) error {
+		close(draining)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	c.Trigger(Options{})
+	<-draining
+	c.Trigger(Options{Force: true})
+
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("forced trigger did not cut the drain short")
+	}
+}
@@ -0,0 +1,248 @@
+// Package snapshot checkpoints the workspace of a coder agent in git before
+// it changes anything, so that a failed run can be rolled back: the
+// working tree, untracked files included, is committed under a ref of its
+// own without touching the branch, the index or the files, and restoring
+// the checkpoint puts all three back.
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/dryrun"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/pullrequest"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/tools"
+)
+
+// Task metadata the snapshots record
+const (
+	// MetaCommit is the checkpoint commit
+	MetaCommit = "snapshot"
+	// MetaHead and MetaBranch are what was checked out when it was taken
+	MetaHead   = "snapshot_head"
+	MetaBranch = "snapshot_branch"
+	// MetaWorkspace is the checkout it was taken of
+	MetaWorkspace = "snapshot_workspace"
+	// MetaTakenAt and MetaRolledBackAt are RFC 3339 times
+	MetaTakenAt      = "snapshot_at"
+	MetaRolledBackAt = "snapshot_rolled_back_at"
+)
+
+// RefPrefix is where checkpoints are kept in the workspace's repository,
+// one ref per task
+const RefPrefix = "refs/skagent/snapshots/"
+
+// source names the snapshots in task logs
+const source = "snapshot"
+
+var (
+	// ErrNoWorkspace is returned for a task with no workspace when none is
+	// configured either
+	ErrNoWorkspace = errors.New("task has no workspace; set meta.workspace or snapshots.workspace")
+	// ErrNoSnapshot is returned when rolling back a task never checkpointed
+	ErrNoSnapshot = errors.New("task has no snapshot")
+)
+
+// Snapshot is the checkpoint of a task's workspace
+type Snapshot struct {
+	TaskID    string `json:"task_id"`
+	Workspace string `json:"workspace"`
+	tools.Checkpoint
+	TakenAt      time.Time  `json:"taken_at"`
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty"`
+}
+
+// Manager takes and restores the snapshots of tasks
+type Manager struct {
+	cfg      config.SnapshotConfig
+	registry *agents.Registry
+	git      *tools.GitTool
+	logger   *log.Logger
+
+	// mu serializes snapshots and rollbacks, which may share a working tree
+	mu sync.Mutex
+}
+
+// New returns a manager
+func New(cfg config.SnapshotConfig, registry *agents.Registry) *Manager {
+	return &Manager{
+		cfg:      cfg,
+		registry: registry,
+		git:      tools.NewGitTool(""),
+		logger:   logging.New("snapshot", "[SNAPSHOT] ", log.Writer()),
+	}
+}
+
+// Take checkpoints a task's workspace. A task keeps its first snapshot, so
+// that rolling back undoes the whole run, until it is rolled back; taking
+// one again then starts over from the workspace as it is.
+func (m *Manager) Take(ctx context.Context, taskID string) (*Snapshot, error) {
+	task, ok := m.registry.GetTask(taskID)
+	if !ok {
+		return nil, agents.ErrTaskNotFound
+	}
+	if snap := fromMeta(task); snap != nil && snap.RolledBackAt == nil {
+		return snap, nil
+	}
+	dir := firstNonEmpty(task.Meta[pullrequest.MetaWorkspace], m.cfg.Workspace)
+	if dir == "" {
+		return nil, ErrNoWorkspace
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ref := RefPrefix + task.ID
+	tasklog.RecordTool(task.ID, tasklog.KindToolCall, source, "git", "Snapshot %s as %s", dir, ref)
+	cp, err := m.git.Snapshot(ctx, dir, ref, fmt.Sprintf("skagent snapshot before task %s\n\nSkagent-Task: %s\n", task.Title, task.ID))
+	if err != nil {
+		tasklog.RecordTool(task.ID, tasklog.KindError, source, "git", "Snapshot failed: %v", err)
+		return nil, err
+	}
+	snap := &Snapshot{TaskID: task.ID, Workspace: dir, Checkpoint: *cp, TakenAt: time.Now()}
+	if dryrun.Enabled() {
+		m.logger.Printf("Dry run: skipped the snapshot of task %s", task.ID)
+		return snap, nil
+	}
+	tasklog.RecordTool(task.ID, tasklog.KindOutput, source, "git", "Took %s on %s with %d uncommitted files", shortSHA(cp.Commit), cp.Branch, len(cp.Files))
+	m.logger.Printf("Took snapshot %s of %s for task %s", shortSHA(cp.Commit), dir, task.ID)
+
+	err = m.registry.SetTaskMeta(task.ID, map[string]string{
+		MetaCommit:       cp.Commit,
+		MetaHead:         cp.Head,
+		MetaBranch:       cp.Branch,
+		MetaWorkspace:    dir,
+		MetaTakenAt:      snap.TakenAt.UTC().Format(time.RFC3339),
+		MetaRolledBackAt: "",
+	})
+	if err != nil {
+		m.logger.Printf("ERROR: Failed to record snapshot %s on task %s: %v", cp.Commit, task.ID, err)
+	}
+	return snap, nil
+}
+
+// Get returns the snapshot of a task
+func (m *Manager) Get(taskID string) (*Snapshot, error) {
+	task, ok := m.registry.GetTask(taskID)
+	if !ok {
+		return nil, agents.ErrTaskNotFound
+	}
+	snap := fromMeta(task)
+	if snap == nil {
+		return nil, ErrNoSnapshot
+	}
+	return snap, nil
+}
+
+// Rollback restores a task's workspace to its snapshot, discarding every
+// change made since, committed or not
+func (m *Manager) Rollback(ctx context.Context, taskID string) (*Snapshot, error) {
+	snap, err := m.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tasklog.RecordTool(taskID, tasklog.KindToolCall, source, "git", "Roll %s back to %s", snap.Workspace, shortSHA(snap.Commit))
+	if err := m.git.Restore(ctx, snap.Workspace, &snap.Checkpoint); err != nil {
+		tasklog.RecordTool(taskID, tasklog.KindError, source, "git", "Rollback failed: %v", err)
+		return nil, err
+	}
+	if dryrun.Enabled() {
+		m.logger.Printf("Dry run: skipped the rollback of task %s", taskID)
+		return snap, nil
+	}
+	now := time.Now()
+	snap.RolledBackAt = &now
+	tasklog.RecordTool(taskID, tasklog.KindOutput, source, "git", "Rolled back to %s on %s", shortSHA(snap.Commit), snap.Branch)
+	m.logger.Printf("Rolled %s back to snapshot %s for task %s", snap.Workspace, shortSHA(snap.Commit), taskID)
+	if err := m.registry.SetTaskMeta(taskID, map[string]string{MetaRolledBackAt: now.UTC().Format(time.RFC3339)}); err != nil {
+		m.logger.Printf("ERROR: Failed to record the rollback of task %s: %v", taskID, err)
+	}
+	return snap, nil
+}
+
+// Run checkpoints the workspace of every task assigned to a coder agent,
+// when the configuration asks for it, until ctx is done or events is
+// closed
+func (m *Manager) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type != agents.EventTaskAssigned || !m.cfg.Auto {
+				continue
+			}
+			task, ok := e.Data["task"].(agents.Task)
+			if !ok || !m.toCoder(&task) {
+				continue
+			}
+			_, err := m.Take(ctx, task.ID)
+			if err != nil && !errors.Is(err, ErrNoWorkspace) {
+				m.logger.Printf("ERROR: Failed to snapshot the workspace of task %s: %v", task.ID, err)
+			}
+		}
+	}
+}
+
+// toCoder reports whether the task is assigned to a coder agent
+func (m *Manager) toCoder(task *agents.Task) bool {
+	if task.AssignedTo == "" {
+		return false
+	}
+	agent, ok := m.registry.GetAgent(task.AssignedTo)
+	return ok && agent.Type == agents.AgentTypeCoder
+}
+
+// fromMeta reads the snapshot recorded on a task, or returns nil
+func fromMeta(task *agents.Task) *Snapshot {
+	commit := task.Meta[MetaCommit]
+	if commit == "" {
+		return nil
+	}
+	snap := &Snapshot{
+		TaskID:    task.ID,
+		Workspace: task.Meta[MetaWorkspace],
+		Checkpoint: tools.Checkpoint{
+			Ref:    RefPrefix + task.ID,
+			Commit: commit,
+			Head:   task.Meta[MetaHead],
+			Branch: task.Meta[MetaBranch],
+		},
+	}
+	snap.TakenAt, _ = time.Parse(time.RFC3339, task.Meta[MetaTakenAt])
+	if at, err := time.Parse(time.RFC3339, task.Meta[MetaRolledBackAt]); err == nil {
+		snap.RolledBackAt = &at
+	}
+	return snap
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/pullrequest"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestTakeAndRollback(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "skagent")
+	t.Setenv("GIT_AUTHOR_EMAIL", "skagent@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "skagent")
+	t.Setenv("GIT_COMMITTER_EMAIL", "skagent@example.com")
+
+	work := t.TempDir()
+	git(t, work, "init", "-b", "main")
+	os.WriteFile(filepath.Join(work, ".gitignore"), []byte("*.log\n"), 0o644)
+	os.WriteFile(filepath.Join(work, "main.go"), []byte("package main\n"), 0o644)
+	git(t, work, "add", "-A")
+	git(t, work, "commit", "-m", "init")
+	head := git(t, work, "rev-parse", "HEAD")
+
+	// Work in progress the agent must not lose: an edit, a staged file,
+	// a new file and an ignored one
+	os.WriteFile(filepath.Join(work, "main.go"), []byte("package main // wip\n"), 0o644)
+	os.WriteFile(filepath.Join(work, "staged.go"), []byte("package main\n"), 0o644)
+	git(t, work, "add", "staged.go")
+	os.WriteFile(filepath.Join(work, "notes.txt"), []byte("todo\n"), 0o644)
+	os.WriteFile(filepath.Join(work, "debug.log"), []byte("log\n"), 0o644)
+
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	task := registry.CreateTask(&agents.Task{Title: "Refactor main", Meta: map[string]string{pullrequest.MetaWorkspace: work}})
+	m := New(config.SnapshotConfig{Enabled: true}, registry)
+
+	if _, err := m.Rollback(ctx, task.ID); err != ErrNoSnapshot {
+		t.Fatalf("rollback before a snapshot: %v", err)
+	}
+	snap, err := m.Take(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Head != head || snap.Branch != "main" || snap.Ref != RefPrefix+task.ID ||
+		strings.Join(snap.Files, ",") != "main.go,staged.go,notes.txt" {
+		t.Fatalf("snapshot: %+v", snap)
+	}
+	if got := git(t, work, "rev-parse", snap.Ref); got != snap.Commit {
+		t.Fatalf("ref points at %s, want %s", got, snap.Commit)
+	}
+	// Taking it changed nothing in the workspace
+	if status := git(t, work, "status", "--porcelain"); !strings.Contains(status, "A  staged.go") || !strings.Contains(status, "M main.go") {
+		t.Fatalf("status after the snapshot:\n%s", status)
+	}
+	if again, err := m.Take(ctx, task.ID); err != nil || again.Commit != snap.Commit {
+		t.Fatalf("second snapshot: %+v %v", again, err)
+	}
+
+	// The agent commits on a branch of its own, edits and creates files
+	git(t, work, "checkout", "-b", "agent")
+	os.WriteFile(filepath.Join(work, "main.go"), []byte("package broken\n"), 0o644)
+	git(t, work, "commit", "-am", "agent work")
+	os.WriteFile(filepath.Join(work, "notes.txt"), []byte("overwritten\n"), 0o644)
+	os.WriteFile(filepath.Join(work, "generated.go"), []byte("package main\n"), 0o644)
+
+	back, err := m.Rollback(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.RolledBackAt == nil {
+		t.Fatal("rollback not recorded")
+	}
+	if branch := git(t, work, "rev-parse", "--abbrev-ref", "HEAD"); branch != "main" {
+		t.Fatalf("branch after rollback: %s", branch)
+	}
+	if got := git(t, work, "rev-parse", "HEAD"); got != head {
+		t.Fatalf("HEAD after rollback: %s", got)
+	}
+	for name, want := range map[string]string{
+		"main.go":   "package main // wip\n",
+		"staged.go": "package main\n",
+		"notes.txt": "todo\n",
+		"debug.log": "log\n",
+	} {
+		if got := read(t, filepath.Join(work, name)); got != want {
+			t.Fatalf("%s after rollback: %q", name, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(work, "generated.go")); !os.IsNotExist(err) {
+		t.Fatalf("generated.go survived the rollback: %v", err)
+	}
+
+	got, err := m.Get(task.ID)
+	if err != nil || got.Commit != snap.Commit || got.Workspace != work || got.RolledBackAt == nil {
+		t.Fatalf("recorded snapshot: %+v %v", got, err)
+	}
+	// A rolled back task takes a fresh snapshot
+	os.WriteFile(filepath.Join(work, "main.go"), []byte("package main // v2\n"), 0o644)
+	fresh, err := m.Take(ctx, task.ID)
+	if err != nil || fresh.Commit == snap.Commit || fresh.RolledBackAt != nil {
+		t.Fatalf("fresh snapshot: %+v %v", fresh, err)
+	}
+}
+
+func TestTakeNoWorkspace(t *testing.T) {
+	registry := agents.NewRegistry(context.Background())
+	task := registry.CreateTask(&agents.Task{Title: "Anything"})
+	m := New(config.SnapshotConfig{Enabled: true}, registry)
+	if _, err := m.Take(context.Background(), task.ID); err != ErrNoWorkspace {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := m.Take(context.Background(), "missing"); err != agents.ErrTaskNotFound {
+		t.Fatalf("missing task: %v", err)
+	}
+}
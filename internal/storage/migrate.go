@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migrations upgrade a schema one version at a time; migrations[i] takes
+// it from version i to i+1. Append new ones, never edit old ones:
+// databases in the field have already run them.
+
+// sqliteMigrations are the migrations of SQLite databases
+var sqliteMigrations = []string{
+	// 1: agents, tasks and workspaces as JSON
+	`CREATE TABLE agents (
+		id         TEXT PRIMARY KEY,
+		workspace  TEXT NOT NULL,
+		status     TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	);
+	CREATE TABLE tasks (
+		id          TEXT PRIMARY KEY,
+		workspace   TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		assigned_to TEXT NOT NULL DEFAULT '',
+		data        TEXT NOT NULL,
+		created_at  TEXT NOT NULL,
+		updated_at  TEXT NOT NULL
+	);
+	CREATE INDEX tasks_status ON tasks (status);
+	CREATE TABLE workspaces (
+		name TEXT PRIMARY KEY,
+		data TEXT NOT NULL
+	);`,
+	// 2: archive of finished tasks
+	`CREATE TABLE archived_tasks (
+		id          TEXT PRIMARY KEY,
+		workspace   TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		data        TEXT NOT NULL,
+		finished_at TEXT NOT NULL,
+		archived_at TEXT NOT NULL
+	);
+	CREATE INDEX archived_tasks_workspace ON archived_tasks (workspace, finished_at);`,
+}
+
+// postgresMigrations are the migrations of PostgreSQL databases
+var postgresMigrations = []string{
+	// 1: agents, tasks and workspaces as JSON
+	`CREATE TABLE agents (
+		id         TEXT PRIMARY KEY,
+		workspace  TEXT NOT NULL,
+		status     TEXT NOT NULL,
+		data       JSONB NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE TABLE tasks (
+		id          TEXT PRIMARY KEY,
+		workspace   TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		assigned_to TEXT NOT NULL DEFAULT '',
+		data        JSONB NOT NULL,
+		created_at  TIMESTAMPTZ NOT NULL,
+		updated_at  TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX tasks_status ON tasks (status);
+	CREATE TABLE workspaces (
+		name TEXT PRIMARY KEY,
+		data JSONB NOT NULL
+	);`,
+	// 2: archive of finished tasks
+	`CREATE TABLE archived_tasks (
+		id          TEXT PRIMARY KEY,
+		workspace   TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		data        JSONB NOT NULL,
+		finished_at TIMESTAMPTZ NOT NULL,
+		archived_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX archived_tasks_workspace ON archived_tasks (workspace, finished_at);`,
+}
+
+// migrate runs the migrations the database has not run yet, each in its
+// own transaction with the version it reaches. lock, when set, is run at
+// the start of each transaction to keep other processes from migrating at
+// the same time.
+func migrate(db *sql.DB, migrations []string, bind func(string) string, lock string) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return err
+	}
+	for {
+		done, err := migrateOne(db, migrations, bind, lock)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// migrateOne runs the next migration, or reports that there is none
+func migrateOne(db *sql.DB, migrations []string, bind func(string) string, lock string) (done bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if lock != "" {
+		if _, err := tx.Exec(lock); err != nil {
+			return false, err
+		}
+	}
+	// Read under the lock, as another process may have just migrated
+	version, err := schemaVersion(tx)
+	if err != nil {
+		return false, err
+	}
+	if version > len(migrations) {
+		return false, fmt.Errorf("schema version %d is newer than this skagent, which knows %d", version, len(migrations))
+	}
+	if version == len(migrations) {
+		return true, tx.Commit()
+	}
+	if _, err := tx.Exec(migrations[version]); err != nil {
+		return false, fmt.Errorf("migration %d: %w", version+1, err)
+	}
+	if _, err := tx.Exec(bind("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)"),
+		version+1, textTime(time.Now())); err != nil {
+		return false, err
+	}
+	return false, tx.Commit()
+}
+
+// querier is a database or a transaction
+type querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// schemaVersion is the last migration the database ran, 0 for a new one
+func schemaVersion(q querier) (int, error) {
+	var version sql.NullInt64
+	if err := q.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// migrationLock is the advisory lock key that serializes the migrations of
+// instances starting together against one database
+const migrationLock = 0x736b6167656e74
+
+// Postgres is a store in a PostgreSQL database, which several headless
+// instances can share: assignments are claimed under row locks, so that
+// no task goes to two agents and no agent gets two tasks.
+type Postgres struct {
+	sqlStore
+}
+
+// OpenPostgres connects to the database at dsn and brings its schema up to
+// date
+func OpenPostgres(dsn string) (*Postgres, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to postgres: %w", err)
+	}
+	lock := fmt.Sprintf("SELECT pg_advisory_xact_lock(%d)", migrationLock)
+	if err := migrate(db, postgresMigrations, dollarBind, lock); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating postgres: %w", err)
+	}
+	return &Postgres{sqlStore{db: db, bind: dollarBind, stamp: utcTime}}, nil
+}
+
+// Version returns the schema version of the database
+func (p *Postgres) Version() (int, error) {
+	return schemaVersion(p.db)
+}
+
+// Claim assigns task to agentID in the database, moving it to status and
+// into a slot or the queue of the agent, and returns both rows as stored.
+// The task and agent rows stay locked until the claim commits, so of two
+// instances claiming either one, the second sees what the first did and
+// gets agents.ErrClaimed.
+func (p *Postgres) Claim(task *agents.Task, agentID string, status agents.TaskStatus) (*agents.Task, *agents.Agent, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var stored agents.Task
+	if err := lockRow(tx, "SELECT data FROM tasks WHERE id = $1 FOR UPDATE", task.ID, &stored); err != nil {
+		return nil, nil, err
+	}
+	if stored.Status != task.Status || stored.AssignedTo != task.AssignedTo {
+		return nil, nil, agents.ErrClaimed
+	}
+	var agent agents.Agent
+	if err := lockRow(tx, "SELECT data FROM agents WHERE id = $1 FOR UPDATE", agentID, &agent); err != nil {
+		return nil, nil, err
+	}
+	if !agent.Accepts(status) {
+		return nil, nil, agents.ErrClaimed
+	}
+
+	now := time.Now()
+	stored.Status = status
+	stored.AssignedTo = agentID
+	stored.UpdatedAt = now
+	agent.Hold(&stored)
+	agent.UpdatedAt = now
+	agent.Version++
+	if err := p.putTask(tx, &stored); err != nil {
+		return nil, nil, err
+	}
+	if err := p.putAgent(tx, &agent); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return &stored, &agent, nil
+}
+
+// Begin starts a transaction in which the agents and tasks read stay locked
+// until it commits or rolls back, so that a registry applies its changes to
+// the stored rows instead of overwriting what other instances stored
+func (p *Postgres) Begin() (agents.StoreTx, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &postgresTx{store: p, tx: tx}, nil
+}
+
+// postgresTx is a transaction on a Postgres store
+type postgresTx struct {
+	store *Postgres
+	tx    *sql.Tx
+}
+
+// Agent returns the stored agent with id, locked, or nil if there is none
+func (t *postgresTx) Agent(id string) (*agents.Agent, error) {
+	var agent agents.Agent
+	if found, err := t.lock("SELECT data FROM agents WHERE id = $1 FOR UPDATE", id, &agent); !found {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// Task returns the stored task with id, locked, or nil if there is none
+func (t *postgresTx) Task(id string) (*agents.Task, error) {
+	var task agents.Task
+	if found, err := t.lock("SELECT data FROM tasks WHERE id = $1 FOR UPDATE", id, &task); !found {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// lock is lockRow reporting whether the row was found
+func (t *postgresTx) lock(query, id string, out interface{}) (bool, error) {
+	err := lockRow(t.tx, query, id, out)
+	if errors.Is(err, agents.ErrClaimed) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Save writes the changes in the transaction
+func (t *postgresTx) Save(c agents.Changes) error {
+	return t.store.save(t.tx, c)
+}
+
+// Commit commits the transaction
+func (t *postgresTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback rolls the transaction back
+func (t *postgresTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// lockRow decodes the JSON of the row query selects for id into out; a
+// missing row was deleted by another instance
+func lockRow(tx *sql.Tx, query, id string, out interface{}) error {
+	var data []byte
+	err := tx.QueryRow(query, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return agents.ErrClaimed
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// utcTime stores times as they are, in UTC
+func utcTime(t time.Time) interface{} {
+	return t.UTC()
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestDollarBind(t *testing.T) {
+	got := dollarBind("INSERT INTO t (a, b) VALUES (?, ?) ON CONFLICT (a) DO UPDATE SET b = excluded.b")
+	if want := "INSERT INTO t (a, b) VALUES ($1, $2) ON CONFLICT (a) DO UPDATE SET b = excluded.b"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestPostgresClaims runs against the database in SKAGENT_TEST_POSTGRES,
+// which it empties: point it at a throwaway one
+func TestPostgresClaims(t *testing.T) {
+	dsn := os.Getenv("SKAGENT_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("SKAGENT_TEST_POSTGRES is not set")
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DROP TABLE IF EXISTS agents, tasks, archived_tasks, workspaces, schema_migrations"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	ctx := context.Background()
+	open := func() *agents.Registry {
+		store, err := OpenPostgres(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		r := agents.NewRegistry(ctx)
+		if err := r.UseStore(store); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	first, second := open(), open()
+
+	agent, err := first.CreateAgent("coder-1", "coder", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := first.CreateTask(&agents.Task{Title: "shared"})
+	if err := second.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := second.GetTask(task.ID); !ok {
+		t.Fatal("the second instance does not see the task")
+	}
+
+	// Both instances try to assign the task; only the first gets it
+	if err := first.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.AssignTask(task.ID, agent.ID); !errors.Is(err, agents.ErrClaimed) {
+		t.Fatalf("second claim err = %v", err)
+	}
+	if got, _ := second.GetTask(task.ID); got.Status != agents.TaskStatusInProgress || got.AssignedTo != agent.ID {
+		t.Fatalf("task in the second instance = %+v", got)
+	}
+
+	// The first completes it while the second, unaware, edits it: the edit
+	// goes on the stored row and leaves the task completed
+	if err := first.CompleteTask(task.ID, &agents.TaskResult{Success: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.SetTaskMeta(task.ID, map[string]string{"pr": "42"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := first.GetTask(task.ID); got.Status != agents.TaskStatusCompleted || got.Meta["pr"] != "42" {
+		t.Fatalf("task after both edits = %+v", got)
+	}
+	if got, _ := first.GetAgent(agent.ID); len(got.Running) != 0 || got.Stats.TasksCompleted != 1 {
+		t.Fatalf("agent after the completion = %+v", got)
+	}
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// sqlStore is the part of a store common to the SQL databases: queries are
+// written with ? placeholders, which bind rewrites for the driver
+type sqlStore struct {
+	db *sql.DB
+	// bind rewrites the placeholders of a query
+	bind func(query string) string
+	// stamp is the value a time is stored as
+	stamp func(time.Time) interface{}
+}
+
+// Load returns every stored agent, task and workspace
+func (s *sqlStore) Load() (*agents.State, error) {
+	state := &agents.State{}
+	if err := loadRows(s.db, "SELECT data FROM workspaces ORDER BY name", &state.Workspaces); err != nil {
+		return nil, fmt.Errorf("workspaces: %w", err)
+	}
+	if err := loadRows(s.db, "SELECT data FROM tasks ORDER BY created_at, id", &state.Tasks); err != nil {
+		return nil, fmt.Errorf("tasks: %w", err)
+	}
+	if err := loadRows(s.db, "SELECT data FROM agents ORDER BY id", &state.Agents); err != nil {
+		return nil, fmt.Errorf("agents: %w", err)
+	}
+	return state, nil
+}
+
+// loadRows decodes the JSON of each row into a new element of out
+func loadRows[T any](db *sql.DB, query string, out *[]*T, args ...interface{}) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		v := new(T)
+		if err := json.Unmarshal(data, v); err != nil {
+			return err
+		}
+		*out = append(*out, v)
+	}
+	return rows.Err()
+}
+
+// Save writes the changes in one transaction
+func (s *sqlStore) Save(c agents.Changes) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.save(tx, c); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// save writes the changes in tx
+func (s *sqlStore) save(tx *sql.Tx, c agents.Changes) error {
+	for _, a := range c.Agents {
+		if err := s.putAgent(tx, a); err != nil {
+			return fmt.Errorf("agent %s: %w", a.ID, err)
+		}
+	}
+	for _, t := range c.Tasks {
+		if err := s.putTask(tx, t); err != nil {
+			return fmt.Errorf("task %s: %w", t.ID, err)
+		}
+	}
+	for _, ws := range c.Workspaces {
+		data, err := json.Marshal(ws)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(s.bind(`INSERT INTO workspaces (name, data) VALUES (?, ?)
+			ON CONFLICT (name) DO UPDATE SET data = excluded.data`), ws.Name, data); err != nil {
+			return fmt.Errorf("workspace %s: %w", ws.Name, err)
+		}
+	}
+	for _, del := range []struct {
+		query string
+		ids   []string
+	}{
+		{"DELETE FROM agents WHERE id = ?", c.DeletedAgents},
+		{"DELETE FROM tasks WHERE id = ?", c.DeletedTasks},
+		{"DELETE FROM workspaces WHERE name = ?", c.DeletedWorkspaces},
+	} {
+		for _, id := range del.ids {
+			if _, err := tx.Exec(s.bind(del.query), id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// putAgent inserts or replaces an agent
+func (s *sqlStore) putAgent(tx *sql.Tx, a *agents.Agent) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.bind(`INSERT INTO agents (id, workspace, status, data, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET workspace = excluded.workspace, status = excluded.status,
+		data = excluded.data, updated_at = excluded.updated_at`),
+		a.ID, a.Workspace, string(a.Status), data, s.stamp(a.UpdatedAt))
+	return err
+}
+
+// putTask inserts or replaces a task
+func (s *sqlStore) putTask(tx *sql.Tx, t *agents.Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.bind(`INSERT INTO tasks (id, workspace, status, assigned_to, data, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET workspace = excluded.workspace, status = excluded.status,
+		assigned_to = excluded.assigned_to, data = excluded.data, updated_at = excluded.updated_at`),
+		t.ID, t.Workspace, string(t.Status), t.AssignedTo, data, s.stamp(t.CreatedAt), s.stamp(t.UpdatedAt))
+	return err
+}
+
+// ArchiveTasks moves tasks from the tasks table to archived_tasks in one
+// transaction
+func (s *sqlStore) ArchiveTasks(tasks []*agents.Task) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := s.stamp(time.Now())
+	for _, t := range tasks {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(s.bind(`INSERT INTO archived_tasks (id, workspace, status, data, finished_at, archived_at) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET workspace = excluded.workspace, status = excluded.status,
+			data = excluded.data, finished_at = excluded.finished_at, archived_at = excluded.archived_at`),
+			t.ID, t.Workspace, string(t.Status), data, s.stamp(t.FinishedAt()), now); err != nil {
+			return fmt.Errorf("task %s: %w", t.ID, err)
+		}
+		if _, err := tx.Exec(s.bind("DELETE FROM tasks WHERE id = ?"), t.ID); err != nil {
+			return fmt.Errorf("task %s: %w", t.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ArchivedTasks returns a page of the archived tasks of a workspace, the
+// most recently finished first
+func (s *sqlStore) ArchivedTasks(workspace string, limit, offset int) ([]*agents.Task, error) {
+	tasks := []*agents.Task{}
+	err := loadRows(s.db, s.bind(`SELECT data FROM archived_tasks WHERE workspace = ?
+		ORDER BY finished_at DESC, id LIMIT ? OFFSET ?`), &tasks, workspace, limit, offset)
+	return tasks, err
+}
+
+// ArchivedTask returns an archived task, or nil when there is none
+func (s *sqlStore) ArchivedTask(id string) (*agents.Task, error) {
+	var tasks []*agents.Task
+	if err := loadRows(s.db, s.bind("SELECT data FROM archived_tasks WHERE id = ?"), &tasks, id); err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	return tasks[0], nil
+}
+
+// Close closes the database
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// noBind leaves the ? placeholders, which SQLite understands
+func noBind(query string) string {
+	return query
+}
+
+// dollarBind numbers the placeholders $1, $2... as PostgreSQL wants them.
+// Queries hold no ? in literals.
+func dollarBind(query string) string {
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// textTime stores times as text in UTC with a fixed number of fractional
+// digits, so that they sort as strings
+func textTime(t time.Time) interface{} {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
@@ -0,0 +1,57 @@
+// Package storage keeps the registry's agents, tasks and workspaces in a
+// database, so that they survive a restart. Each entry is stored as JSON,
+// next to the columns that queries filter on; the schema is created and
+// upgraded by numbered migrations when the database is opened.
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLite is a store in a SQLite database. The database runs in WAL mode,
+// so that a backup or a shell can read it while the registry writes.
+type SQLite struct {
+	sqlStore
+	path string
+}
+
+// OpenSQLite opens the database at path, creating it and its directory if
+// needed, and brings its schema up to date
+func OpenSQLite(path string) (*SQLite, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	dsn := "file:" + path + "?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=5000&_txlock=immediate"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// One connection serializes the writes, which SQLite does anyway
+	db.SetMaxOpenConns(1)
+	if err := migrate(db, sqliteMigrations, noBind, ""); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating %s: %w", path, err)
+	}
+	return &SQLite{sqlStore: sqlStore{db: db, bind: noBind, stamp: textTime}, path: path}, nil
+}
+
+// Path returns the database file
+func (s *SQLite) Path() string {
+	return s.path
+}
+
+// Version returns the schema version of the database
+func (s *SQLite) Version() (int, error) {
+	return schemaVersion(s.db)
+}
+
+// Close checkpoints the WAL into the database and closes it
+func (s *SQLite) Close() error {
+	s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	return s.db.Close()
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestSQLiteSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data", "registry.db")
+
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := agents.NewRegistry(ctx)
+	if err := r.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CreateWorkspace("team-a", "first team"); err != nil {
+		t.Fatal(err)
+	}
+	agent, err := r.CreateAgentIn("team-a", "coder-1", "coder", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := r.CreateTask(&agents.Task{Title: "done", Workspace: "team-a", Labels: []string{"go"}})
+	if err := r.AssignTask(done.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CompleteTask(done.ID, &agents.TaskResult{Success: true, Output: "ok", Duration: 1200}); err != nil {
+		t.Fatal(err)
+	}
+	running := r.CreateTask(&agents.Task{Title: "running", Workspace: "team-a"})
+	if err := r.AssignTask(running.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	gone := r.CreateTask(&agents.Task{Title: "gone"})
+	if _, err := r.ApplyTaskOps([]agents.TaskOp{{Op: agents.BulkDelete, ID: gone.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CloseStore(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new process loads what the first one left
+	store, err = OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if v, err := store.Version(); err != nil || v != len(sqliteMigrations) {
+		t.Fatalf("schema version %d, %v", v, err)
+	}
+	r = agents.NewRegistry(ctx)
+	if err := r.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+	if ws, ok := r.GetWorkspace("team-a"); !ok || ws.Description != "first team" || ws.Agents != 1 || ws.Tasks != 2 {
+		t.Fatalf("workspace = %+v", ws)
+	}
+	a, ok := r.GetAgent(agent.ID)
+	if !ok || a.Status != agents.StatusIdle || a.CurrentTask != nil || a.Stats.TasksCompleted != 1 {
+		t.Fatalf("agent = %+v", a)
+	}
+	if task, _ := r.GetTask(done.ID); task.Status != agents.TaskStatusCompleted || task.Result.Output != "ok" || task.Labels[0] != "go" {
+		t.Fatalf("completed task = %+v", task)
+	}
+	// The task running at the restart is queued again
+	if task, _ := r.GetTask(running.ID); task.Status != agents.TaskStatusPending || task.AssignedTo != "" || task.StartedAt != nil {
+		t.Fatalf("running task = %+v", task)
+	}
+	if _, ok := r.GetTask(gone.ID); ok {
+		t.Fatal("deleted task came back")
+	}
+
+	// The requeue was written through too
+	var status string
+	if err := store.db.QueryRow("SELECT status FROM tasks WHERE id = ?", running.ID).Scan(&status); err != nil || status != "pending" {
+		t.Fatalf("stored status %q, %v", status, err)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.db")
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Opening again runs nothing
+	if err := migrate(store.db, sqliteMigrations, noBind, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec("INSERT INTO schema_migrations (version, applied_at) VALUES (99, '')"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if _, err := OpenSQLite(path); err == nil || !strings.Contains(err.Error(), "newer than this skagent") {
+		t.Fatalf("err = %v", err)
+	}
+	db, _ := sql.Open("sqlite3", path)
+	defer db.Close()
+	var mode string
+	db.QueryRow("PRAGMA journal_mode").Scan(&mode)
+	if mode != "wal" {
+		t.Errorf("journal mode %q", mode)
+	}
+}
+
+func TestSQLiteArchive(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "registry.db")
+	store, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := agents.NewRegistry(ctx)
+	if err := r.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, title := range []string{"first", "second"} {
+		task := r.CreateTask(&agents.Task{Title: title})
+		if err := r.CancelTask(task.ID, agents.Cause{}); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, task.ID)
+	}
+	open := r.CreateTask(&agents.Task{Title: "open"})
+	if n, err := r.Compact(time.Now().Add(time.Second)); err != nil || n != 2 {
+		t.Fatalf("archived %d, %v", n, err)
+	}
+	if err := r.CloseStore(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart loads only the live task, and the archive keeps the others
+	store, err = OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	r = agents.NewRegistry(ctx)
+	if err := r.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+	if tasks := r.ListTasks(); len(tasks) != 1 || tasks[0].ID != open.ID {
+		t.Fatalf("tasks = %+v", tasks)
+	}
+	archived, err := r.ArchivedTasks(agents.DefaultWorkspace, 10, 0)
+	if err != nil || len(archived) != 2 || archived[0].ID != ids[1] || archived[1].Title != "first" {
+		t.Fatalf("archived = %+v, %v", archived, err)
+	}
+	if page, _ := r.ArchivedTasks(agents.DefaultWorkspace, 1, 1); len(page) != 1 || page[0].ID != ids[0] {
+		t.Fatalf("second page = %+v", page)
+	}
+	if other, _ := r.ArchivedTasks("team-a", 10, 0); len(other) != 0 {
+		t.Fatalf("other workspace = %+v", other)
+	}
+	if task, err := r.ArchivedTask(ids[0]); err != nil || task.Status != agents.TaskStatusCancelled {
+		t.Fatalf("archived task = %+v, %v", task, err)
+	}
+}
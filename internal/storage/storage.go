@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// Open opens the store the configuration selects, or returns nil when the
+// registry stays in memory
+func Open(cfg config.StorageConfig) (agents.Store, error) {
+	switch cfg.Driver {
+	case "", config.StorageMemory:
+		return nil, nil
+	case config.StorageSQLite:
+		path := cfg.Path
+		if path == "" {
+			dataDir, err := config.DataDir()
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.Join(dataDir, "registry.db")
+		}
+		return OpenSQLite(path)
+	case config.StoragePostgres:
+		return OpenPostgres(cfg.DSN)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
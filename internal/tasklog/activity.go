@@ -0,0 +1,129 @@
+package tasklog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+// Types of activities besides registry events
+const (
+	ActivityAgent = "agent" // the agent as it was when the tail started
+	ActivityStep  = "step"  // a log entry
+)
+
+// Activity is one event of an agent's live tail: a change of the agent or
+// of one of its tasks, or a step its tasks logged
+type Activity struct {
+	// Type is the registry event type, ActivityAgent or ActivityStep
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	TaskID string    `json:"task_id,omitempty"`
+	// Agent is the agent after an agent event, or at the start
+	Agent *agents.AgentView `json:"agent,omitempty"`
+	// Task is the task after a task event
+	Task *agents.Task `json:"task,omitempty"`
+	Step *Entry       `json:"step,omitempty"`
+}
+
+// Format renders an activity on one line, as the TUI follow view shows it
+func (a Activity) Format() string {
+	if a.Step != nil {
+		return Format(*a.Step)
+	}
+	head := a.Time.Local().Format("15:04:05") + " " + a.Type
+	switch {
+	case a.Task != nil:
+		message := StatusMessage(agents.EventType(a.Type), a.Task)
+		if message == "" {
+			message = string(a.Task.Status)
+		}
+		return fmt.Sprintf("%s %s: %s", head, a.Task.ID, message)
+	case a.Agent != nil:
+		return fmt.Sprintf("%s: %s is %s, %d running, %d queued",
+			head, a.Agent.Name, a.Agent.Status, len(a.Agent.Running), a.Agent.QueueDepth)
+	}
+	return head
+}
+
+// Summary returns e with a prompt, response or tool output cut to its first
+// line and the number of lines left out
+func (e Entry) Summary() Entry {
+	switch e.Kind {
+	case KindPrompt, KindResponse, KindOutput:
+	default:
+		return e
+	}
+	switch more := strings.Count(strings.TrimSpace(e.Message), "\n"); more {
+	case 0:
+		e.Message = firstLine(e.Message)
+	case 1:
+		e.Message = firstLine(e.Message) + " (+1 line)"
+	default:
+		e.Message = fmt.Sprintf("%s (+%d lines)", firstLine(e.Message), more)
+	}
+	return e
+}
+
+// Tail picks the activity of one agent out of the registry's events and
+// the log entries of every task: the agent's own changes, those of the
+// tasks assigned to it and the steps they log
+type Tail struct {
+	agentID string
+	// full keeps whole prompts, responses and outputs in steps
+	full bool
+	// tasks are those assigned to the agent since the tail started, or
+	// before when they are still running or queued
+	tasks map[string]bool
+}
+
+// NewTail follows agent; full keeps whole prompts, responses and outputs
+// rather than their summaries
+func NewTail(agent agents.AgentView, full bool) *Tail {
+	t := &Tail{agentID: agent.ID, full: full, tasks: make(map[string]bool)}
+	for _, id := range agent.Running {
+		t.tasks[id] = true
+	}
+	for _, q := range agent.Queue {
+		t.tasks[q.ID] = true
+	}
+	return t
+}
+
+// Event returns the activity a registry event shows, if it concerns the
+// agent. Agent activities carry no Agent, which the caller fills in with
+// the agent as it is now.
+func (t *Tail) Event(ev agents.Event) (Activity, bool) {
+	a := Activity{Type: string(ev.Type), Time: ev.Time, TaskID: ev.TaskID}
+	if ev.TaskID == "" {
+		return a, ev.AgentID == t.agentID
+	}
+	mine := ev.AgentID == t.agentID
+	if !mine && !t.tasks[ev.TaskID] {
+		return a, false
+	}
+	// A task given to another agent is no longer followed
+	if mine {
+		t.tasks[ev.TaskID] = true
+	} else if ev.AgentID != "" {
+		delete(t.tasks, ev.TaskID)
+	}
+	if task, ok := ev.Data["task"].(agents.Task); ok {
+		a.Task = &task
+	}
+	return a, true
+}
+
+// Step returns the activity a log entry shows, if one of the agent's tasks
+// logged it. Status entries repeat the registry's events and are left out.
+func (t *Tail) Step(e Entry) (Activity, bool) {
+	if e.Kind == KindStatus || (e.Source != t.agentID && !t.tasks[e.TaskID]) {
+		return Activity{}, false
+	}
+	if !t.full {
+		e = e.Summary()
+	}
+	return Activity{Type: ActivityStep, Time: e.Time, TaskID: e.TaskID, Step: &e}, true
+}
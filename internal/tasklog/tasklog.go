@@ -0,0 +1,463 @@
+// Package tasklog keeps the execution log of each task: the prompts sent
+// for it, the tools called and their outputs, retries, errors and the
+// changes of its status. The registry's history says what happened to a
+// task; its log says why, such as what a failed task tried before
+// failing.
+package tasklog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/redact"
+)
+
+// DefaultLimit is how many entries are kept for each task when no limit
+// is given; older ones are dropped
+const DefaultLimit = 1000
+
+// maxMessage bounds the message of an entry, in bytes
+const maxMessage = 16 << 10
+
+// ErrInvalidTaskID is returned for IDs that cannot name a log file
+var ErrInvalidTaskID = errors.New("invalid task ID")
+
+// Kind says what an entry records
+type Kind string
+
+const (
+	KindStatus   Kind = "status"    // the task changed status
+	KindPrompt   Kind = "prompt"    // a prompt sent to a model
+	KindResponse Kind = "response"  // what the model answered
+	KindToolCall Kind = "tool_call" // a tool was called
+	KindOutput   Kind = "output"    // what a tool returned
+	KindRetry    Kind = "retry"     // a step is tried again
+	KindError    Kind = "error"     // a step failed
+	KindNote     Kind = "note"      // anything else worth knowing
+)
+
+// Kinds lists every kind of entry
+var Kinds = []Kind{KindStatus, KindPrompt, KindResponse, KindToolCall, KindOutput, KindRetry, KindError, KindNote}
+
+// Valid reports whether k is a known kind
+func (k Kind) Valid() bool {
+	for _, known := range Kinds {
+		if k == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Entry is one step of a task's execution
+type Entry struct {
+	// Seq orders the entries of a task; it starts at 1
+	Seq    int64     `json:"seq"`
+	TaskID string    `json:"task_id"`
+	Time   time.Time `json:"time"`
+	Kind   Kind      `json:"kind"`
+	// Source is who wrote the entry: an agent, "registry", "evaluator",
+	// "review" or "pull-request"
+	Source string `json:"source,omitempty"`
+	// Tool names the tool of tool_call and output entries
+	Tool    string `json:"tool,omitempty"`
+	Message string `json:"message"`
+}
+
+// Filter selects entries of a task
+type Filter struct {
+	// Kind keeps entries of one kind; empty keeps all
+	Kind Kind
+	// AfterSeq keeps entries after this one, to catch up from the last
+	// entry seen
+	AfterSeq int64
+	// Limit keeps the most recent entries; 0 keeps all
+	Limit int
+}
+
+// taskLog is the log of one task
+type taskLog struct {
+	entries []Entry
+	seq     int64
+	// written counts the lines of the task's file, which is rewritten
+	// with the kept entries once it holds twice the limit
+	written int
+}
+
+// Store keeps the logs of every task, in memory and, when it has a
+// directory, in one JSON lines file per task so that they outlive a
+// restart. It is safe for concurrent use.
+type Store struct {
+	dir    string
+	limit  int
+	logger *log.Logger
+
+	mu    sync.Mutex
+	tasks map[string]*taskLog
+	// subs receive the entries appended from now on
+	subs    map[int]chan Entry
+	nextSub int
+}
+
+// New returns a store keeping up to limit entries of each task, 0 for
+// DefaultLimit, in dir. An empty dir keeps logs in memory only.
+func New(dir string, limit int) (*Store, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+	}
+	return &Store{
+		dir:    dir,
+		limit:  limit,
+		logger: logging.New("tasklog", "[TASKLOG] ", log.Writer()),
+		tasks:  make(map[string]*taskLog),
+		subs:   make(map[int]chan Entry),
+	}, nil
+}
+
+// validID rejects IDs that would name a file outside the store
+func validID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+// path is the file of a task's log
+func (s *Store) path(taskID string) string {
+	return filepath.Join(s.dir, taskID+".jsonl")
+}
+
+// load returns the log of a task, reading its file the first time; the
+// caller holds s.mu
+func (s *Store) load(taskID string) *taskLog {
+	if l, ok := s.tasks[taskID]; ok {
+		return l
+	}
+	l := &taskLog{}
+	s.tasks[taskID] = l
+	if s.dir == "" {
+		return l
+	}
+	data, err := os.ReadFile(s.path(taskID))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.logger.Printf("ERROR: Failed to read the log of task %s: %v", taskID, err)
+		}
+		return l
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), 4*maxMessage)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		l.written++
+		l.entries = append(l.entries, e)
+		if e.Seq > l.seq {
+			l.seq = e.Seq
+		}
+	}
+	if len(l.entries) > s.limit {
+		l.entries = l.entries[len(l.entries)-s.limit:]
+	}
+	return l
+}
+
+// Append adds an entry to the log of e.TaskID and returns it as kept,
+// numbered, timed and with secrets redacted
+func (s *Store) Append(e Entry) (Entry, error) {
+	if !validID(e.TaskID) {
+		return Entry{}, ErrInvalidTaskID
+	}
+	if !e.Kind.Valid() {
+		return Entry{}, fmt.Errorf("unknown log entry kind %q", e.Kind)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Message = clip(redact.String(strings.TrimRight(e.Message, "\n")))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.load(e.TaskID)
+	l.seq++
+	e.Seq = l.seq
+	l.entries = append(l.entries, e)
+	if len(l.entries) > s.limit {
+		l.entries = append(l.entries[:0], l.entries[len(l.entries)-s.limit:]...)
+	}
+	if err := s.write(l, e); err != nil {
+		s.logger.Printf("ERROR: Failed to save the log of task %s: %v", e.TaskID, err)
+	}
+	for _, ch := range s.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return e, nil
+}
+
+// Subscribe registers for the entries appended from now on, to any task.
+// Entries are dropped for a subscriber whose buffer is full. The returned
+// cancel function must be called to release the subscription.
+func (s *Store) Subscribe(buffer int) (<-chan Entry, func()) {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	ch := make(chan Entry, buffer)
+
+	s.mu.Lock()
+	id := s.nextSub
+	s.nextSub++
+	s.subs[id] = ch
+	s.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subs, id)
+			s.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// write saves an entry to its task's file, rewriting the file with the
+// kept entries when it has grown to twice the limit; the caller holds
+// s.mu
+func (s *Store) write(l *taskLog, e Entry) error {
+	if s.dir == "" {
+		return nil
+	}
+	path := s.path(e.TaskID)
+	if l.written >= 2*s.limit {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, kept := range l.entries {
+			if err := enc.Encode(kept); err != nil {
+				return err
+			}
+		}
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return err
+		}
+		l.written = len(l.entries)
+		return nil
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		l.written++
+	}
+	return err
+}
+
+// Entries returns the entries of a task that f selects, oldest first; ok
+// is false when the task has no log
+func (s *Store) Entries(taskID string, f Filter) (entries []Entry, ok bool) {
+	if !validID(taskID) {
+		return nil, false
+	}
+	s.mu.Lock()
+	l := s.load(taskID)
+	ok = l.seq > 0
+	entries = make([]Entry, 0, len(l.entries))
+	for _, e := range l.entries {
+		if e.Seq > f.AfterSeq && (f.Kind == "" || e.Kind == f.Kind) {
+			entries = append(entries, e)
+		}
+	}
+	if !ok {
+		delete(s.tasks, taskID)
+	}
+	s.mu.Unlock()
+
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[len(entries)-f.Limit:]
+	}
+	return entries, ok
+}
+
+// Run records the status changes of the tasks in events, such as a
+// registry subscription, until the channel is closed or ctx is done
+func (s *Store) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			task, ok := ev.Data["task"].(agents.Task)
+			if !ok {
+				continue
+			}
+			message := StatusMessage(ev.Type, &task)
+			if message == "" {
+				continue
+			}
+			kind := KindStatus
+			if ev.Type == agents.EventTaskFailed {
+				kind = KindError
+			} else if ev.Type == agents.EventTaskRevised || ev.Type == agents.EventTaskRetried {
+				kind = KindRetry
+			}
+			if _, err := s.Append(Entry{TaskID: task.ID, Time: ev.Time, Kind: kind, Source: "registry", Message: message}); err != nil {
+				s.logger.Printf("ERROR: Failed to log %s of task %s: %v", ev.Type, task.ID, err)
+			}
+		}
+	}
+}
+
+// StatusMessage describes the change an event made to a task, or returns
+// "" for events the log leaves out
+func StatusMessage(t agents.EventType, task *agents.Task) string {
+	switch t {
+	case agents.EventTaskCreated:
+		return fmt.Sprintf("Created %q", task.Title)
+	case agents.EventTaskAssigned:
+		return "Assigned to agent " + task.AssignedTo
+	case agents.EventTaskCompleted:
+		message := "Completed"
+		if r := task.Result; r != nil {
+			if r.Model != "" {
+				message += " by " + r.Model
+			}
+			message += fmt.Sprintf(" in %s", time.Duration(r.Duration)*time.Millisecond)
+			if out := firstLine(r.Output); out != "" {
+				message += ": " + out
+			}
+		}
+		return message
+	case agents.EventTaskFailed:
+		if task.Result != nil && task.Result.Error != "" {
+			return "Failed: " + task.Result.Error
+		}
+		return "Failed"
+	case agents.EventTaskCancelled:
+		return "Cancelled"
+	case agents.EventTaskEvaluated:
+		if task.Result == nil || task.Result.Evaluation == nil {
+			return ""
+		}
+		e := task.Result.Evaluation
+		verdict := "passed"
+		if !e.Passed {
+			verdict = "fell short"
+		}
+		return fmt.Sprintf("Evaluated: score %d of %d, %s", e.Score, e.Threshold, verdict)
+	case agents.EventTaskRevised:
+		return fmt.Sprintf("Sent back for revision %d: %s", task.Revision, task.Feedback)
+	case agents.EventTaskRetried:
+		if len(task.Attempts) == 0 || task.RetryAt == nil {
+			return ""
+		}
+		a := task.Attempts[len(task.Attempts)-1]
+		return fmt.Sprintf("Attempt %d failed (%s); retrying at %s",
+			a.Number, a.Class, task.RetryAt.Local().Format("15:04:05"))
+	}
+	return ""
+}
+
+// Format renders an entry on one line, its message's further lines
+// indented under it, as the CLI and the TUI show logs
+func Format(e Entry) string {
+	head := e.Time.Local().Format("15:04:05") + " " + string(e.Kind)
+	if e.Source != "" {
+		head += " [" + e.Source + "]"
+	}
+	if e.Tool != "" {
+		head += " " + e.Tool
+	}
+	return head + ": " + strings.ReplaceAll(e.Message, "\n", "\n    ")
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if len(s) > 200 {
+		cut := 200
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = s[:cut] + "…"
+	}
+	return s
+}
+
+// clip shortens a message to maxMessage bytes on a rune boundary
+func clip(s string) string {
+	if len(s) <= maxMessage {
+		return s
+	}
+	cut := maxMessage
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "\n[truncated]"
+}
+
+var defaultStore atomic.Pointer[Store]
+
+// SetDefault makes s the store Record writes to; nil stops recording
+func SetDefault(s *Store) {
+	defaultStore.Store(s)
+}
+
+// Record adds an entry to a task's log in the default store, if there is
+// one. Components running tasks call it for each step they take.
+func Record(taskID string, kind Kind, source, format string, args ...interface{}) {
+	RecordTool(taskID, kind, source, "", format, args...)
+}
+
+// RecordTool is Record for the call of a tool, or its output
+func RecordTool(taskID string, kind Kind, source, tool, format string, args ...interface{}) {
+	s := defaultStore.Load()
+	if s == nil {
+		return
+	}
+	message := format
+	if len(args) > 0 {
+		message = fmt.Sprintf(format, args...)
+	}
+	if _, err := s.Append(Entry{TaskID: taskID, Kind: kind, Source: source, Tool: tool, Message: message}); err != nil {
+		s.logger.Printf("ERROR: Failed to log a step of task %s: %v", taskID, err)
+	}
+}
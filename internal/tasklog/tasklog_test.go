@@ -0,0 +1,140 @@
+package tasklog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestAppendAndReload(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append(Entry{TaskID: "../x", Kind: KindNote, Message: "m"}); err != ErrInvalidTaskID {
+		t.Errorf("path in task ID: %v", err)
+	}
+	if _, err := s.Append(Entry{TaskID: "t1", Kind: "bogus", Message: "m"}); err == nil {
+		t.Error("unknown kind accepted")
+	}
+	for i := 0; i < 8; i++ {
+		kind := KindToolCall
+		if i%2 == 1 {
+			kind = KindOutput
+		}
+		if _, err := s.Append(Entry{TaskID: "t1", Kind: kind, Tool: "git", Message: strings.Repeat("x", i+1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, ok := s.Entries("t1", Filter{})
+	if !ok || len(entries) != 3 || entries[0].Seq != 6 || entries[2].Seq != 8 {
+		t.Fatalf("kept entries: %v %+v", ok, entries)
+	}
+	if got, _ := s.Entries("t1", Filter{Kind: KindOutput}); len(got) != 2 {
+		t.Errorf("by kind: %+v", got)
+	}
+	if got, _ := s.Entries("t1", Filter{AfterSeq: 7}); len(got) != 1 || got[0].Seq != 8 {
+		t.Errorf("after seq: %+v", got)
+	}
+	if _, ok := s.Entries("missing", Filter{}); ok {
+		t.Error("log of an unknown task")
+	}
+
+	// A new store reads the files, rewritten once they held twice the
+	// limit, and numbers on from the last entry
+	reloaded, err := New(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, ok = reloaded.Entries("t1", Filter{})
+	if !ok || len(entries) != 3 || entries[2].Seq != 8 {
+		t.Fatalf("reloaded entries: %v %+v", ok, entries)
+	}
+	e, _ := reloaded.Append(Entry{TaskID: "t1", Kind: KindRetry, Message: "again"})
+	if e.Seq != 9 {
+		t.Errorf("seq after reload: %d", e.Seq)
+	}
+}
+
+func TestRunRecordsStatus(t *testing.T) {
+	s, _ := New("", 0)
+	SetDefault(s)
+	defer SetDefault(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := agents.NewRegistry(ctx)
+	events, unsubscribe := registry.Subscribe(16)
+	defer unsubscribe()
+	go s.Run(ctx, events)
+
+	agent, _ := registry.CreateAgent("a", "coder", nil)
+	task := registry.CreateTask(&agents.Task{Title: "build"})
+	registry.AssignTask(task.ID, agent.ID)
+	Record(task.ID, KindPrompt, agent.ID, "build it with token=%s", "sk-abcdefghijklmnopqrstuvwxyz")
+	registry.CompleteTask(task.ID, &agents.TaskResult{Success: false, Error: "tests failed"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	var entries []Entry
+	for time.Now().Before(deadline) {
+		if entries, _ = s.Entries(task.ID, Filter{}); len(entries) == 4 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("entries: %+v", entries)
+	}
+	var kinds []string
+	for _, e := range entries {
+		kinds = append(kinds, string(e.Kind))
+	}
+	// The prompt is recorded directly while status entries go through
+	// the subscription, so only the ones from events are ordered
+	joined := strings.Join(kinds, ",")
+	if !strings.Contains(joined, "prompt") || !strings.HasSuffix(joined, "error") {
+		t.Errorf("kinds: %s", joined)
+	}
+	for _, e := range entries {
+		if e.Kind == KindPrompt && strings.Contains(e.Message, "abcdefghij") {
+			t.Errorf("secret not redacted: %s", e.Message)
+		}
+		if e.Kind == KindError && e.Message != "Failed: tests failed" {
+			t.Errorf("failure: %q", e.Message)
+		}
+	}
+}
+
+func TestTailFollowsTheAgentsTasks(t *testing.T) {
+	s, _ := New("", 0)
+	steps, cancel := s.Subscribe(16)
+	defer cancel()
+	tail := NewTail(agents.AgentView{ID: "a1", Running: []string{"t1"}}, false)
+
+	// Tasks running when the tail starts are followed
+	s.Append(Entry{TaskID: "t1", Kind: KindOutput, Source: "runner", Message: "line 1\nline 2"})
+	if a, ok := tail.Step(<-steps); !ok || a.Step.Message != "line 1 (+1 line)" {
+		t.Fatalf("step = %+v, %v", a, ok)
+	}
+	s.Append(Entry{TaskID: "t1", Kind: KindStatus, Source: "registry", Message: "Completed"})
+	if _, ok := tail.Step(<-steps); ok {
+		t.Error("status entries repeat the registry's events")
+	}
+
+	// A task given to another agent is no longer followed
+	task := agents.Task{ID: "t1", AssignedTo: "a2"}
+	if _, ok := tail.Event(agents.Event{Type: agents.EventTaskAssigned, TaskID: "t1", AgentID: "a2", Data: map[string]interface{}{"task": task}}); !ok {
+		t.Error("the reassignment of a followed task was left out")
+	}
+	if _, ok := tail.Event(agents.Event{Type: agents.EventTaskCompleted, TaskID: "t1", AgentID: "a2"}); ok {
+		t.Error("the task of another agent was followed")
+	}
+	if _, ok := tail.Event(agents.Event{Type: agents.EventAgentStopped, AgentID: "a1"}); !ok {
+		t.Error("the agent's own event was left out")
+	}
+}
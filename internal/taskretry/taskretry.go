@@ -0,0 +1,170 @@
+// Package taskretry retries failed tasks. When a run fails with an error
+// of a class the task's retry policy retries, and the task has runs left,
+// it goes back to pending with the failed run among its attempts, and is
+// auto-assigned again after an exponential backoff. The policy is the
+// task's own, else that of the failed agent's type, else the default one.
+package taskretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/biodoia/skagent/internal/retry"
+	"github.com/biodoia/skagent/internal/tasklog"
+)
+
+// Defaults of the zero fields of a policy
+const (
+	defaultMaxAttempts = 3
+	defaultBackoff     = 30 * time.Second
+	defaultMaxBackoff  = 10 * time.Minute
+	defaultMultiplier  = 2.0
+)
+
+// defaultRetryOn are the classes retried by a policy that names none
+var defaultRetryOn = []string{string(retry.ClassTimeout), string(retry.ClassRateLimit), string(retry.ClassTransient)}
+
+// cause is recorded on the retries
+var cause = agents.Cause{Actor: "retry"}
+
+// source names the retrier in task logs
+const source = "retry"
+
+// Retrier sends failed tasks back for another attempt
+type Retrier struct {
+	cfg      config.RetryConfig
+	registry *agents.Registry
+	logger   *log.Logger
+}
+
+// New returns a retrier applying the policies of cfg. The registry's
+// task.failed events tell, by their Final, whether the retrier will retry.
+func New(cfg config.RetryConfig, registry *agents.Registry) *Retrier {
+	r := &Retrier{
+		cfg:      cfg,
+		registry: registry,
+		logger:   logging.New("retry", "[RETRY] ", log.Writer()),
+	}
+	if registry != nil {
+		registry.SetRetryDecider(func(task *agents.Task, agentType agents.AgentType) bool {
+			return r.Decide(task, agentType).Retry
+		})
+	}
+	return r
+}
+
+// Decision is what a retry policy makes of a failed run
+type Decision struct {
+	Class retry.Class
+	// Attempt is the number of the failed run, starting at 1
+	Attempt int
+	Retry   bool
+	// Delay is the wait before the next run, when retried
+	Delay time.Duration
+}
+
+// Policy returns the retry policy of task when an agent of agentType
+// failed it, with the defaults of its zero fields filled in
+func (r *Retrier) Policy(task *agents.Task, agentType agents.AgentType) config.RetryPolicy {
+	p := r.cfg.RetryPolicy
+	if typed, ok := r.cfg.AgentTypes[string(agentType)]; ok {
+		p = typed
+	}
+	if task.Retry != nil {
+		p = *task.Retry
+	}
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	if p.Backoff == 0 {
+		p.Backoff = int(defaultBackoff / time.Second)
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = int(defaultMaxBackoff / time.Second)
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = defaultMultiplier
+	}
+	if len(p.RetryOn) == 0 {
+		p.RetryOn = defaultRetryOn
+	}
+	return p
+}
+
+// Decide tells whether task, whose last run an agent of agentType failed,
+// is retried, and after how long
+func (r *Retrier) Decide(task *agents.Task, agentType agents.AgentType) Decision {
+	d := Decision{Attempt: len(task.Attempts) + 1}
+	if task.Result == nil || task.Result.Success {
+		return d
+	}
+	d.Class = retry.Classify(errors.New(task.Result.Error))
+	p := r.Policy(task, agentType)
+	if d.Attempt >= p.MaxAttempts || !slices.Contains(p.RetryOn, string(d.Class)) {
+		return d
+	}
+	d.Retry = true
+	d.Delay = retry.ExponentialBackoff(len(task.Attempts),
+		time.Duration(p.Backoff)*time.Second, time.Duration(p.MaxBackoff)*time.Second, p.Multiplier)
+	return d
+}
+
+// Run retries the tasks that fail while their policies allow, until ctx is
+// done or events is closed
+func (r *Retrier) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.Type != agents.EventTaskFailed {
+				continue
+			}
+			if task, ok := ev.Data["task"].(agents.Task); ok {
+				r.retry(ctx, &task)
+			}
+		}
+	}
+}
+
+// retry sends a failed task back to pending when its policy allows, and
+// assigns it again once the backoff has passed
+func (r *Retrier) retry(ctx context.Context, task *agents.Task) {
+	var agentType agents.AgentType
+	if agent, ok := r.registry.GetAgent(task.AssignedTo); ok {
+		agentType = agent.Type
+	}
+	d := r.Decide(task, agentType)
+	if !d.Retry {
+		if d.Class != "" {
+			tasklog.Record(task.ID, tasklog.KindNote, source, "Attempt %d failed (%s); not retried", d.Attempt, d.Class)
+		}
+		return
+	}
+
+	c := cause
+	c.Reason = fmt.Sprintf("attempt %d failed (%s); retrying in %s", d.Attempt, d.Class, d.Delay)
+	err := r.registry.RetryTask(task.ID, time.Now().Add(d.Delay), string(d.Class), c)
+	switch {
+	case errors.Is(err, agents.ErrTaskNotFailed), errors.Is(err, agents.ErrTaskNotFound), errors.Is(err, agents.ErrDraining):
+		return
+	case err != nil:
+		r.logger.Printf("ERROR: Failed to retry task %s: %v", task.ID, err)
+		return
+	}
+	time.AfterFunc(d.Delay, func() {
+		if ctx.Err() == nil {
+			r.registry.AutoAssign(ctx)
+		}
+	})
+}
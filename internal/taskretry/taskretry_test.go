@@ -0,0 +1,121 @@
+package taskretry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/retry"
+)
+
+func failedTask(err string, attempts int) *agents.Task {
+	return &agents.Task{
+		Attempts: make([]agents.Attempt, attempts),
+		Result:   &agents.TaskResult{Error: err},
+	}
+}
+
+func TestDecideFollowsThePolicy(t *testing.T) {
+	r := New(config.RetryConfig{
+		RetryPolicy: config.RetryPolicy{Backoff: 10, MaxBackoff: 25},
+		AgentTypes:  map[string]config.RetryPolicy{"reviewer": {MaxAttempts: 1}},
+	}, nil)
+
+	tests := []struct {
+		name      string
+		task      *agents.Task
+		agentType agents.AgentType
+		class     retry.Class
+		retry     bool
+		delay     time.Duration
+	}{
+		{"transient first failure", failedTask("read: connection reset by peer", 0), "coder", retry.ClassTransient, true, 10 * time.Second},
+		{"backoff grows", failedTask("the run timed out after 5m0s", 1), "coder", retry.ClassTimeout, true, 20 * time.Second},
+		{"attempts used up", failedTask("HTTP 429", 2), "coder", retry.ClassRateLimit, false, 0},
+		{"permanent error", failedTask("provider \"x\" is not configured", 0), "coder", retry.ClassPermanent, false, 0},
+		{"agent type policy", failedTask("HTTP 503", 0), "reviewer", retry.ClassTransient, false, 0},
+	}
+	for _, tt := range tests {
+		d := r.Decide(tt.task, tt.agentType)
+		if d.Class != tt.class || d.Retry != tt.retry || d.Delay != tt.delay {
+			t.Errorf("%s: decision = %+v, want class %s, retry %v after %s", tt.name, d, tt.class, tt.retry, tt.delay)
+		}
+	}
+
+	// A task's own policy beats its agent type's
+	task := failedTask("not found", 0)
+	task.Retry = &config.RetryPolicy{MaxAttempts: 5, Backoff: 100, RetryOn: []string{"permanent"}}
+	if d := r.Decide(task, "reviewer"); !d.Retry || d.Delay != 100*time.Second {
+		t.Errorf("task policy: decision = %+v", d)
+	}
+}
+
+func TestRunRetriesFailedTasks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := agents.NewRegistry(ctx)
+	events, unsubscribe := registry.Subscribe(16)
+	defer unsubscribe()
+	go New(config.RetryConfig{RetryPolicy: config.RetryPolicy{Backoff: 60}}, registry).Run(ctx, events)
+
+	agent, _ := registry.CreateAgent("coder", "coder", nil)
+	task := registry.CreateTask(&agents.Task{Title: "Fetch the feed"})
+	if err := registry.AssignTask(task.ID, agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.CompleteTask(task.ID, &agents.TaskResult{Error: "dial tcp: connection refused"}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got, _ := registry.GetTask(task.ID)
+		if got.Status == agents.TaskStatusPending {
+			if len(got.Attempts) != 1 || got.Attempts[0].AgentID != agent.ID || got.Attempts[0].Class != "transient" || got.Result != nil {
+				t.Fatalf("retried task = %+v", got)
+			}
+			if got.RetryAt == nil || time.Until(*got.RetryAt) < 50*time.Second {
+				t.Fatalf("retry at %v, want a minute from now", got.RetryAt)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task not retried: status %s", got.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := registry.AutoAssign(ctx); n != 0 {
+		t.Fatalf("assigned %d tasks before their backoff passed", n)
+	}
+}
+
+func TestFailuresToRetryAreNotFinal(t *testing.T) {
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	New(config.RetryConfig{RetryPolicy: config.RetryPolicy{MaxAttempts: 2}}, registry)
+	events, unsubscribe := registry.Subscribe(16)
+	defer unsubscribe()
+
+	agent, _ := registry.CreateAgent("coder", "coder", nil)
+	fail := func(err string) agents.Event {
+		t.Helper()
+		task := registry.CreateTask(&agents.Task{Title: "Fetch the feed"})
+		registry.AssignTask(task.ID, agent.ID)
+		registry.CompleteTask(task.ID, &agents.TaskResult{Error: err})
+		for ev := range events {
+			if ev.Type == agents.EventTaskFailed && ev.TaskID == task.ID {
+				return ev
+			}
+		}
+		t.Fatal("no task.failed event")
+		return agents.Event{}
+	}
+	if ev := fail("HTTP 503"); ev.Final() {
+		t.Error("a failure the policy retries is final")
+	}
+	if ev := fail("provider \"x\" is not configured"); !ev.Final() {
+		t.Error("a permanent failure is not final")
+	}
+}
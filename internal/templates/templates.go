@@ -0,0 +1,175 @@
+// Package templates holds the built-in task templates: common kinds of work,
+// such as fixing a bug or documenting a feature, written once with
+// {{param}} placeholders and the labels and agent types the work needs, so
+// a task starts from a filled-in description rather than a blank one.
+package templates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/validate"
+)
+
+// Param is a placeholder of a template
+type Param struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+	// Default fills an optional parameter left out
+	Default string `json:"default,omitempty"`
+}
+
+// Template is a kind of task. Title, Description and AcceptanceCriteria
+// may hold {{name}} placeholders for the parameters.
+type Template struct {
+	Name               string              `json:"name"`
+	Summary            string              `json:"summary"`
+	Params             []Param             `json:"params"`
+	Title              string              `json:"title"`
+	Description        string              `json:"description"`
+	Labels             []string            `json:"labels,omitempty"`
+	AgentTypes         []string            `json:"agent_types,omitempty"`
+	Priority           agents.TaskPriority `json:"priority"`
+	AcceptanceCriteria []string            `json:"acceptance_criteria,omitempty"`
+}
+
+// builtin are the templates shipped with skagent
+var builtin = []Template{
+	{
+		Name:    "bug-fix",
+		Summary: "Find and fix a bug, with a test that reproduces it",
+		Params: []Param{
+			{Name: "summary", Description: "what goes wrong", Required: true},
+			{Name: "component", Description: "where it happens", Default: "the affected code"},
+			{Name: "steps", Description: "how to reproduce it", Default: "not known yet"},
+		},
+		Title:       "Fix: {{summary}}",
+		Description: "Bug in {{component}}: {{summary}}.\n\nSteps to reproduce: {{steps}}.\n\nFind the cause, fix it with the smallest change that does, and add a test that fails without the fix.",
+		Labels:      []string{"bug", "code"},
+		AgentTypes:  []string{string(agents.AgentTypeCoder)},
+		Priority:    agents.PriorityHigh,
+		AcceptanceCriteria: []string{
+			"a test reproduces {{summary}} and passes with the fix",
+			"the existing tests still pass",
+		},
+	},
+	{
+		Name:    "add-endpoint",
+		Summary: "Add an HTTP endpoint with validation and tests",
+		Params: []Param{
+			{Name: "method", Description: "HTTP method, such as GET or POST", Required: true},
+			{Name: "path", Description: "route of the endpoint", Required: true},
+			{Name: "purpose", Description: "what the endpoint does", Required: true},
+		},
+		Title:       "Add {{method}} {{path}}",
+		Description: "Add the endpoint {{method}} {{path}} to {{purpose}}.\n\nFollow the conventions of the existing routes for authentication, validation of the input and the shape of responses and errors, and document the endpoint.",
+		Labels:      []string{"api", "code"},
+		AgentTypes:  []string{string(agents.AgentTypeCoder)},
+		Priority:    agents.PriorityMedium,
+		AcceptanceCriteria: []string{
+			"{{method}} {{path}} answers as described, with errors for invalid input",
+			"tests cover the endpoint",
+		},
+	},
+	{
+		Name:    "write-tests",
+		Summary: "Add tests for code that lacks them",
+		Params: []Param{
+			{Name: "target", Description: "package, file or function to test", Required: true},
+			{Name: "focus", Description: "cases that matter most", Default: "the main paths and the error cases"},
+		},
+		Title:       "Write tests for {{target}}",
+		Description: "Write tests for {{target}}, covering {{focus}}.\n\nUse the test framework and layout the project already has; do not change the code under test unless a test finds a bug, and report any such bug.",
+		Labels:      []string{"tests"},
+		AgentTypes:  []string{string(agents.AgentTypeTester), string(agents.AgentTypeCoder)},
+		Priority:    agents.PriorityMedium,
+		AcceptanceCriteria: []string{
+			"the new tests pass and cover {{focus}}",
+		},
+	},
+	{
+		Name:    "update-docs",
+		Summary: "Bring documentation up to date with the code",
+		Params: []Param{
+			{Name: "topic", Description: "feature or change to document", Required: true},
+			{Name: "files", Description: "documents to update", Default: "README.md"},
+		},
+		Title:       "Document {{topic}}",
+		Description: "Update {{files}} to describe {{topic}} as the code now behaves.\n\nKeep the style and language of the existing documents, and fix any example that no longer works.",
+		Labels:      []string{"docs"},
+		AgentTypes:  []string{string(agents.AgentTypeDocumenter)},
+		Priority:    agents.PriorityLow,
+		AcceptanceCriteria: []string{
+			"{{files}} describes {{topic}} accurately",
+		},
+	},
+}
+
+// Builtin returns the built-in templates, sorted by name
+func Builtin() []Template {
+	out := make([]Template, len(builtin))
+	copy(out, builtin)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Get returns the built-in template called name
+func Get(name string) (Template, bool) {
+	for _, t := range builtin {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// Render fills the template's placeholders with params and returns the task
+// it describes, not yet created. Missing required parameters and unknown
+// ones are returned as validate.Errors on the fields "params.<name>".
+func (t Template) Render(params map[string]string) (*agents.Task, error) {
+	var errs validate.Errors
+	values := make(map[string]string, len(t.Params))
+	known := make(map[string]bool, len(t.Params))
+	for _, p := range t.Params {
+		known[p.Name] = true
+		v := strings.TrimSpace(params[p.Name])
+		switch {
+		case v != "":
+			values[p.Name] = v
+		case p.Required:
+			errs = append(errs, validate.FieldError{Field: "params." + p.Name, Message: "is required"})
+		default:
+			values[p.Name] = p.Default
+		}
+	}
+	for name := range params {
+		if !known[name] {
+			errs = append(errs, validate.FieldError{Field: "params." + name, Message: fmt.Sprintf("is not a parameter of template %s", t.Name)})
+		}
+	}
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+		return nil, errs
+	}
+
+	pairs := make([]string, 0, 2*len(values))
+	for name, v := range values {
+		pairs = append(pairs, "{{"+name+"}}", v)
+	}
+	fill := strings.NewReplacer(pairs...).Replace
+	task := &agents.Task{
+		Title:       fill(t.Title),
+		Description: fill(t.Description),
+		Priority:    t.Priority,
+		Labels:      append([]string(nil), t.Labels...),
+		AgentTypes:  append([]string(nil), t.AgentTypes...),
+		Meta:        map[string]string{"template": t.Name},
+	}
+	for _, c := range t.AcceptanceCriteria {
+		task.AcceptanceCriteria = append(task.AcceptanceCriteria, fill(c))
+	}
+	return task, nil
+}
@@ -0,0 +1,73 @@
+package templates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/validate"
+)
+
+func TestBuiltin(t *testing.T) {
+	var names []string
+	for _, tmpl := range Builtin() {
+		names = append(names, tmpl.Name)
+		// Every placeholder must be a parameter
+		text := tmpl.Title + tmpl.Description + strings.Join(tmpl.AcceptanceCriteria, "")
+		for _, p := range tmpl.Params {
+			text = strings.ReplaceAll(text, "{{"+p.Name+"}}", "")
+		}
+		if strings.Contains(text, "{{") {
+			t.Errorf("%s has an unknown placeholder: %s", tmpl.Name, text)
+		}
+	}
+	if got := strings.Join(names, " "); got != "add-endpoint bug-fix update-docs write-tests" {
+		t.Errorf("templates = %s", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpl, ok := Get("bug-fix")
+	if !ok {
+		t.Fatal("no bug-fix template")
+	}
+	task, err := tmpl.Render(map[string]string{"summary": "login fails with a plus in the email", "component": "auth"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Title != "Fix: login fails with a plus in the email" {
+		t.Errorf("title = %q", task.Title)
+	}
+	if !strings.HasPrefix(task.Description, "Bug in auth: login fails") || !strings.Contains(task.Description, "Steps to reproduce: not known yet.") {
+		t.Errorf("description = %q", task.Description)
+	}
+	if task.Priority != agents.PriorityHigh || strings.Join(task.Labels, ",") != "bug,code" ||
+		strings.Join(task.AgentTypes, ",") != "coder" || task.Meta["template"] != "bug-fix" {
+		t.Errorf("task = %+v", task)
+	}
+	if task.AcceptanceCriteria[0] != "a test reproduces login fails with a plus in the email and passes with the fix" {
+		t.Errorf("criteria = %v", task.AcceptanceCriteria)
+	}
+
+	// The template is not changed by rendering
+	task.Labels[0] = "changed"
+	if again, _ := Get("bug-fix"); again.Labels[0] != "bug" {
+		t.Error("Render shares the template's labels")
+	}
+}
+
+func TestRenderInvalid(t *testing.T) {
+	tmpl, _ := Get("add-endpoint")
+	_, err := tmpl.Render(map[string]string{"method": "GET", "path": " ", "colour": "red"})
+	var errs validate.Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("err = %v", err)
+	}
+	if errs[0].Field != "params.colour" || errs[1].Field != "params.path" || errs[2].Field != "params.purpose" {
+		t.Errorf("errors = %v", errs)
+	}
+	if _, ok := Get("nope"); ok {
+		t.Error("Get found an unknown template")
+	}
+}
@@ -0,0 +1,241 @@
+// Package testutil fakes the external services skagent talks to and boots
+// the headless stack in-process for integration tests. Completions come
+// from ai.MockProvider.
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/project"
+	"github.com/go-chi/chi/v5"
+)
+
+// FakePMAPIKey is the API key a FakePM accepts
+const FakePMAPIKey = "test-pm-key"
+
+// FakePM is an in-memory project manager speaking the API that
+// project.Client uses. It is closed when the test ends.
+type FakePM struct {
+	server *httptest.Server
+
+	mu          sync.Mutex
+	tasks       map[string]*project.Task
+	order       []string
+	assignments []project.TaskAssignment
+	webhooks    []string
+	projects    []project.ProjectRegistration
+	agents      []project.AgentInfo
+	polls       int
+}
+
+// NewFakePM starts a fake project manager
+func NewFakePM(t testing.TB) *FakePM {
+	t.Helper()
+	pm := &FakePM{tasks: make(map[string]*project.Task)}
+
+	r := chi.NewRouter()
+	r.Use(pm.checkKey)
+	r.Get("/api/v1/tasks", pm.handleListTasks)
+	r.Get("/api/v1/tasks/{taskID}", pm.handleGetTask)
+	r.Patch("/api/v1/tasks/{taskID}", pm.handleUpdateTask)
+	r.Post("/api/v1/task-assignments", pm.handleAssign)
+	r.Get("/api/v1/agents", pm.handleListAgents)
+	r.Post("/api/v1/webhooks", pm.handleWebhook)
+	r.Post("/api/v1/projects", pm.handleRegisterProject)
+
+	pm.server = httptest.NewServer(r)
+	t.Cleanup(pm.server.Close)
+	return pm
+}
+
+// URL is the base URL to configure as project.base_url
+func (pm *FakePM) URL() string { return pm.server.URL }
+
+// AddTask adds a task; missing IDs, statuses and timestamps are filled in
+func (pm *FakePM) AddTask(task project.Task) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if task.ID == "" {
+		task.ID = fmt.Sprintf("pm-%d", len(pm.order)+1)
+	}
+	if task.Status == "" {
+		task.Status = "todo"
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+		task.UpdatedAt = task.CreatedAt
+	}
+	if _, ok := pm.tasks[task.ID]; !ok {
+		pm.order = append(pm.order, task.ID)
+	}
+	pm.tasks[task.ID] = &task
+}
+
+// AddAgent adds an agent to GET /api/v1/agents
+func (pm *FakePM) AddAgent(agent project.AgentInfo) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.agents = append(pm.agents, agent)
+}
+
+// Task returns the current state of a task
+func (pm *FakePM) Task(id string) (project.Task, bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	task, ok := pm.tasks[id]
+	if !ok {
+		return project.Task{}, false
+	}
+	return *task, true
+}
+
+// Polls counts the requests for the task list
+func (pm *FakePM) Polls() int {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.polls
+}
+
+// Assignments returns the assignments received so far
+func (pm *FakePM) Assignments() []project.TaskAssignment {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return append([]project.TaskAssignment(nil), pm.assignments...)
+}
+
+// Webhooks returns the callback URLs registered so far
+func (pm *FakePM) Webhooks() []string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return append([]string(nil), pm.webhooks...)
+}
+
+// Projects returns the repositories registered so far
+func (pm *FakePM) Projects() []project.ProjectRegistration {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return append([]project.ProjectRegistration(nil), pm.projects...)
+}
+
+func (pm *FakePM) checkKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+FakePMAPIKey {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (pm *FakePM) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	pm.mu.Lock()
+	pm.polls++
+	tasks := make([]project.Task, 0, len(pm.order))
+	for _, id := range pm.order {
+		if task := pm.tasks[id]; status == "" || task.Status == status {
+			tasks = append(tasks, *task)
+		}
+	}
+	pm.mu.Unlock()
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func (pm *FakePM) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := pm.Task(chi.URLParam(r, "taskID"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (pm *FakePM) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
+	var update struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	task, ok := pm.tasks[chi.URLParam(r, "taskID")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	task.Status = update.Status
+	task.UpdatedAt = time.Now()
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (pm *FakePM) handleAssign(w http.ResponseWriter, r *http.Request) {
+	var assignment project.TaskAssignment
+	if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	task, ok := pm.tasks[assignment.TaskID]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	task.Assignee = assignment.AgentID
+	pm.assignments = append(pm.assignments, assignment)
+	writeJSON(w, http.StatusCreated, assignment)
+}
+
+func (pm *FakePM) handleListAgents(w http.ResponseWriter, r *http.Request) {
+	pm.mu.Lock()
+	agents := append([]project.AgentInfo{}, pm.agents...)
+	pm.mu.Unlock()
+	writeJSON(w, http.StatusOK, agents)
+}
+
+func (pm *FakePM) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhook struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pm.mu.Lock()
+	pm.webhooks = append(pm.webhooks, webhook.URL)
+	pm.mu.Unlock()
+	writeJSON(w, http.StatusCreated, webhook)
+}
+
+func (pm *FakePM) handleRegisterProject(w http.ResponseWriter, r *http.Request) {
+	var reg project.ProjectRegistration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pm.mu.Lock()
+	pm.projects = append(pm.projects, reg)
+	id := len(pm.projects)
+	pm.mu.Unlock()
+	writeJSON(w, http.StatusCreated, project.RegisteredProject{
+		ID:      fmt.Sprintf("proj-%d", id),
+		Name:    reg.Name,
+		RepoURL: reg.RepoURL,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
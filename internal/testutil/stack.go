@@ -0,0 +1,147 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/core"
+	"github.com/biodoia/skagent/internal/headless"
+)
+
+// StackOptions configures StartHeadless
+type StackOptions struct {
+	// Config defaults to config.DefaultConfig()
+	Config *config.Config
+	// Provider defaults to an ai.MockProvider without scripted replies
+	Provider ai.Provider
+	// PM, when set, enables the project manager integration against it
+	PM *FakePM
+	// Agents are registered before the engine starts, so that the project
+	// manager's first poll can assign work to them
+	Agents []*agents.Agent
+}
+
+// Stack is a headless skagent running in-process. Its REST API is served
+// by an httptest server; the configured ports are never bound.
+type Stack struct {
+	Headless *headless.HeadlessMode
+	Engine   *core.Engine
+	Registry *agents.Registry
+	Provider ai.Provider
+	Server   *httptest.Server
+
+	t testing.TB
+}
+
+// StartHeadless boots the headless stack and stops it when the test ends.
+// Runtime state goes to a temporary data directory.
+func StartHeadless(t testing.TB, opts StackOptions) *Stack {
+	t.Helper()
+	t.Setenv("SKAGENT_DATA_DIR", t.TempDir())
+
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	if opts.Provider == nil {
+		opts.Provider = ai.NewMockProvider()
+	}
+	if opts.PM != nil {
+		cfg.Project.Enabled = true
+		cfg.Project.BaseURL = opts.PM.URL()
+		cfg.Project.APIKey = FakePMAPIKey
+		if cfg.Project.WebhookPort == 0 {
+			cfg.Project.WebhookPort = -1
+		}
+	}
+
+	h, err := headless.New(cfg, opts.Provider)
+	if err != nil {
+		t.Fatalf("starting headless stack: %v", err)
+	}
+	for _, agent := range opts.Agents {
+		h.Registry().RegisterAgent(agent)
+	}
+	if err := h.Engine().Start(); err != nil {
+		t.Fatalf("starting engine: %v", err)
+	}
+
+	s := &Stack{
+		Headless: h,
+		Engine:   h.Engine(),
+		Registry: h.Registry(),
+		Provider: opts.Provider,
+		Server:   httptest.NewServer(h.RESTHandler()),
+		t:        t,
+	}
+	t.Cleanup(func() {
+		s.Server.Close()
+		h.Stop()
+	})
+	return s
+}
+
+// Response is a decoded REST API response
+type Response struct {
+	Status int
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// Data returns the "data" object of the response envelope
+func (r *Response) Data() map[string]interface{} {
+	data, _ := r.Body["data"].(map[string]interface{})
+	return data
+}
+
+// Do sends a request to the REST API. A non-nil body is encoded as JSON;
+// the test fails if the request cannot be sent.
+func (s *Stack) Do(method, path string, body interface{}) *Response {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("encoding request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.Server.URL+path, reader)
+	if err != nil {
+		s.t.Fatalf("building request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.Server.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	result := &Response{Status: resp.StatusCode, Header: resp.Header}
+	json.NewDecoder(resp.Body).Decode(&result.Body)
+	return result
+}
+
+// Eventually polls cond until it holds, failing the test after timeout
+func Eventually(t testing.TB, timeout time.Duration, cond func() bool, format string, args ...interface{}) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out: "+format, args...)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
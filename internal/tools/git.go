@@ -0,0 +1,337 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/dryrun"
+)
+
+// ErrNoChanges is returned by CommitBranch when the working tree is clean
+var ErrNoChanges = errors.New("no changes to commit")
+
+// GitTool runs git in a working tree
+type GitTool struct {
+	dir     string
+	timeout time.Duration
+}
+
+// NewGitTool creates a git tool working in dir; an empty dir uses the
+// current directory
+func NewGitTool(dir string) *GitTool {
+	return &GitTool{
+		dir:     dir,
+		timeout: DefaultTimeout,
+	}
+}
+
+// Name returns the tool identifier
+func (g *GitTool) Name() string {
+	return "git"
+}
+
+// Description returns tool description
+func (g *GitTool) Description() string {
+	return "Git operations: status, diff, log, branches and commits in the working tree"
+}
+
+// CanHandle checks if this tool can handle the intent
+func (g *GitTool) CanHandle(intent string) bool {
+	lower := strings.ToLower(intent)
+	keywords := []string{"git ", "commit", "branch", "diff", "staged"}
+	for _, kw := range keywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs the git command the input asks for. Commits take their
+// message from the quoted part of the input.
+func (g *GitTool) Execute(ctx context.Context, input string) (string, error) {
+	lower := strings.ToLower(input)
+
+	switch {
+	case strings.Contains(lower, "commit"):
+		message := extractQuotedArg(input)
+		if message == "" {
+			return "", fmt.Errorf("commit message not found in input; quote it")
+		}
+		if out, ok := simulate("git", "git add -A && "+dryrun.Command("git", "commit", "-m", message)); ok {
+			return out, nil
+		}
+		if _, err := g.run(ctx, g.dir, "add", "-A"); err != nil {
+			return "", err
+		}
+		return g.run(ctx, g.dir, "commit", "-m", message)
+	case strings.Contains(lower, "diff"):
+		return g.run(ctx, g.dir, "diff", "--stat")
+	case strings.Contains(lower, "log"):
+		return g.run(ctx, g.dir, "log", "--oneline", "-n", "20")
+	case strings.Contains(lower, "branch"):
+		return g.run(ctx, g.dir, "branch", "--list")
+	case strings.Contains(lower, "status"):
+		return g.run(ctx, g.dir, "status", "--short", "--branch")
+	default:
+		return "", fmt.Errorf("unknown git command in input: %s", input)
+	}
+}
+
+// Commit describes a commit made by CommitBranch
+type Commit struct {
+	Branch string   `json:"branch"`
+	Base   string   `json:"base"`
+	SHA    string   `json:"sha"`
+	Files  []string `json:"files"`
+}
+
+// CommitBranch creates branch from the checked-out one in dir and commits
+// every change in the working tree to it. The new branch stays checked
+// out. It returns ErrNoChanges when there is nothing to commit.
+func (g *GitTool) CommitBranch(ctx context.Context, dir, branch, message string) (*Commit, error) {
+	status, err := g.run(ctx, dir, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(status) == "" {
+		return nil, ErrNoChanges
+	}
+	base, err := g.CurrentBranch(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if dryrun.Skip("git", "run %s && git add -A && %s in %s", dryrun.Command("git", "checkout", "-b", branch), dryrun.Command("git", "commit", "-m", message), dir) {
+		return &Commit{Branch: branch, Base: base, Files: statusFiles(status)}, nil
+	}
+
+	if _, err := g.run(ctx, dir, "checkout", "-b", branch); err != nil {
+		return nil, err
+	}
+	if _, err := g.run(ctx, dir, "add", "-A"); err != nil {
+		return nil, err
+	}
+	files, err := g.run(ctx, dir, "diff", "--cached", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := g.run(ctx, dir, "commit", "-m", message); err != nil {
+		return nil, err
+	}
+	sha, err := g.run(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Commit{
+		Branch: branch,
+		Base:   base,
+		SHA:    strings.TrimSpace(sha),
+		Files:  strings.Fields(files),
+	}, nil
+}
+
+// CurrentBranch returns the branch checked out in dir
+func (g *GitTool) CurrentBranch(ctx context.Context, dir string) (string, error) {
+	out, err := g.run(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+	return strings.TrimSpace(out), err
+}
+
+// statusFiles lists the paths in the output of git status --porcelain
+func statusFiles(status string) []string {
+	var files []string
+	for _, line := range strings.Split(status, "\n") {
+		if len(line) > 3 {
+			path := line[3:]
+			if i := strings.Index(path, " -> "); i >= 0 {
+				path = path[i+4:]
+			}
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// Checkout switches dir to branch
+func (g *GitTool) Checkout(ctx context.Context, dir, branch string) error {
+	if dryrun.Skip("git", "run %s in %s", dryrun.Command("git", "checkout", branch), dir) {
+		return nil
+	}
+	_, err := g.run(ctx, dir, "checkout", branch)
+	return err
+}
+
+// Push pushes branch to remote and sets it as the upstream
+func (g *GitTool) Push(ctx context.Context, dir, remote, branch string) error {
+	if dryrun.Skip("git", "run %s in %s", dryrun.Command("git", "push", "--set-upstream", remote, branch), dir) {
+		return nil
+	}
+	_, err := g.run(ctx, dir, "push", "--set-upstream", remote, branch)
+	return err
+}
+
+// Checkpoint is a snapshot of a working tree taken by Snapshot: a commit
+// of every file that is not ignored, tracked or not, whose parent is what
+// was checked out
+type Checkpoint struct {
+	// Ref keeps the commit from garbage collection
+	Ref    string `json:"ref"`
+	Commit string `json:"commit"`
+	// Head and Branch are what was checked out; Branch is "HEAD" when
+	// detached
+	Head   string `json:"head"`
+	Branch string `json:"branch"`
+	// Files are the uncommitted changes the checkpoint holds besides Head
+	Files []string `json:"files,omitempty"`
+}
+
+// Snapshot records the working tree of dir as a commit stored under ref,
+// without touching the index, the working tree or any branch. Untracked
+// files are included, ignored ones are not.
+func (g *GitTool) Snapshot(ctx context.Context, dir, ref, message string) (*Checkpoint, error) {
+	head, err := g.run(ctx, dir, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("the workspace has no commit to snapshot: %w", err)
+	}
+	branch, err := g.CurrentBranch(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	status, err := g.run(ctx, dir, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{Ref: ref, Head: strings.TrimSpace(head), Branch: branch, Files: statusFiles(status)}
+	if dryrun.Skip("git", "snapshot the working tree of %s as %s", dir, ref) {
+		return cp, nil
+	}
+
+	// The working tree is staged in an index of its own, so the real one
+	// keeps what the user staged
+	index, err := os.CreateTemp("", "skagent-snapshot-*.index")
+	if err != nil {
+		return nil, err
+	}
+	index.Close()
+	os.Remove(index.Name())
+	defer os.Remove(index.Name())
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+	for _, args := range [][]string{{"read-tree", cp.Head}, {"add", "-A"}} {
+		if _, err := runCommandEnv(ctx, dir, g.timeout, env, "git", args...); err != nil {
+			return nil, err
+		}
+	}
+	tree, err := runCommandEnv(ctx, dir, g.timeout, env, "git", "write-tree")
+	if err != nil {
+		return nil, err
+	}
+	commit, err := g.run(ctx, dir, "commit-tree", strings.TrimSpace(tree), "-p", cp.Head, "-m", message)
+	if err != nil {
+		return nil, err
+	}
+	cp.Commit = strings.TrimSpace(commit)
+	if _, err := g.run(ctx, dir, "update-ref", ref, cp.Commit); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Restore puts dir back as it was when cp was taken: the branch is checked
+// out and reset to its head, files created since are removed and the
+// files of the checkpoint are written back. Changes that were staged come
+// back unstaged. Ignored files are left alone.
+func (g *GitTool) Restore(ctx context.Context, dir string, cp *Checkpoint) error {
+	if dryrun.Skip("git", "restore %s to the snapshot %s", dir, cp.Ref) {
+		return nil
+	}
+	checkout := []string{"checkout", "-f", cp.Branch}
+	if cp.Branch == "" || cp.Branch == "HEAD" {
+		checkout = []string{"checkout", "-f", "--detach", cp.Head}
+	}
+	for _, args := range [][]string{
+		checkout,
+		{"reset", "--hard", cp.Head},
+		{"clean", "-fd"},
+		{"checkout", cp.Commit, "--", "."},
+		{"reset", "-q"},
+	} {
+		if _, err := g.run(ctx, dir, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulate records a command in dry-run mode and returns what a tool
+// reports instead of running it
+func simulate(tool, command string) (string, bool) {
+	if !dryrun.Skip(tool, "run %s", command) {
+		return "", false
+	}
+	return "[dry-run] would run: " + command + "\n", true
+}
+
+func (g *GitTool) run(ctx context.Context, dir string, args ...string) (string, error) {
+	return runCommand(ctx, dir, g.timeout, "git", args...)
+}
+
+// runCommand runs a CLI in dir, bounding it by timeout unless ctx already
+// has a deadline, and returns its combined output
+func runCommand(ctx context.Context, dir string, timeout time.Duration, name string, args ...string) (string, error) {
+	return runCommandEnv(ctx, dir, timeout, nil, name, args...)
+}
+
+// runCommandEnv is runCommand with env added to the environment
+func runCommandEnv(ctx context.Context, dir string, timeout time.Duration, env []string, name string, args ...string) (string, error) {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s %s timed out after %v", name, args[0], timeout)
+		}
+		return "", fmt.Errorf("%s %s failed: %w\n%s", name, args[0], err, output)
+	}
+	return string(output), nil
+}
+
+// runCommandIO is runCommand for output that is parsed: it feeds stdin to
+// the command and returns its standard output alone, keeping standard
+// error for the error message
+func runCommandIO(ctx context.Context, dir string, timeout time.Duration, stdin io.Reader, name string, args ...string) (string, error) {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s %s timed out after %v", name, args[0], timeout)
+		}
+		return "", fmt.Errorf("%s %s failed: %w\n%s%s", name, args[0], err, stderr.Bytes(), stdout.Bytes())
+	}
+	return stdout.String(), nil
+}
@@ -1,11 +1,16 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/biodoia/skagent/internal/dryrun"
 )
 
 // GitHubTool provides GitHub operations via gh CLI
@@ -87,6 +92,9 @@ func (g *GitHubTool) createRepo(ctx context.Context, input string) (string, erro
 		visibility = "--public"
 	}
 
+	if out, ok := simulate("github", dryrun.Command("gh", "repo", "create", repoName, visibility, "--confirm")); ok {
+		return out, nil
+	}
 	cmd := exec.CommandContext(ctx, "gh", "repo", "create", repoName, visibility, "--confirm")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -105,6 +113,9 @@ func (g *GitHubTool) cloneRepo(ctx context.Context, input string) (string, error
 		return "", fmt.Errorf("repo URL not found in input")
 	}
 
+	if out, ok := simulate("github", dryrun.Command("gh", "repo", "clone", repoURL)); ok {
+		return out, nil
+	}
 	cmd := exec.CommandContext(ctx, "gh", "repo", "clone", repoURL)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -125,6 +136,9 @@ func (g *GitHubTool) manageIssue(ctx context.Context, input string) (string, err
 		if title == "" {
 			title = "New Issue"
 		}
+		if out, ok := simulate("github", dryrun.Command("gh", "issue", "create", "--title", title)); ok {
+			return out, nil
+		}
 		cmd := exec.CommandContext(ctx, "gh", "issue", "create", "--title", title)
 		output, err := cmd.CombinedOutput()
 		if err != nil {
@@ -149,6 +163,9 @@ func (g *GitHubTool) managePR(ctx context.Context, input string) (string, error)
 	lower := strings.ToLower(input)
 
 	if strings.Contains(lower, "create") || strings.Contains(lower, "new") {
+		if out, ok := simulate("github", dryrun.Command("gh", "pr", "create", "--fill")); ok {
+			return out, nil
+		}
 		cmd := exec.CommandContext(ctx, "gh", "pr", "create", "--fill")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
@@ -169,6 +186,93 @@ func (g *GitHubTool) managePR(ctx context.Context, input string) (string, error)
 	return "", fmt.Errorf("unknown PR command")
 }
 
+// PullRequestSpec describes a pull request to open
+type PullRequestSpec struct {
+	Base  string
+	Head  string
+	Title string
+	Body  string
+	Draft bool
+}
+
+// CreatePullRequest opens a pull request for the repository checked out in
+// dir and returns its URL, which is empty in dry-run mode
+func (g *GitHubTool) CreatePullRequest(ctx context.Context, dir string, spec PullRequestSpec) (string, error) {
+	args := []string{"pr", "create", "--base", spec.Base, "--head", spec.Head, "--title", spec.Title, "--body", spec.Body}
+	if spec.Draft {
+		args = append(args, "--draft")
+	}
+	if dryrun.Skip("github", "run %s in %s", dryrun.Command("gh", "pr", "create", "--base", spec.Base, "--head", spec.Head, "--title", spec.Title), dir) {
+		return "", nil
+	}
+	output, err := runCommand(ctx, dir, g.timeout, "gh", args...)
+	if err != nil {
+		return "", err
+	}
+	// gh prints the URL of the new pull request last
+	lines := strings.Fields(output)
+	if len(lines) == 0 {
+		return "", fmt.Errorf("gh pr create printed no URL")
+	}
+	return lines[len(lines)-1], nil
+}
+
+// PullRequestDiff returns the unified diff of pull request number in repo,
+// given as owner/name
+func (g *GitHubTool) PullRequestDiff(ctx context.Context, repo string, number int) (string, error) {
+	return runCommandIO(ctx, "", g.timeout, nil, "gh", "pr", "diff", strconv.Itoa(number), "--repo", repo)
+}
+
+// reviewRequest is the body of GitHub's create-review endpoint
+type reviewRequest struct {
+	CommitID string          `json:"commit_id,omitempty"`
+	Body     string          `json:"body"`
+	Event    string          `json:"event"`
+	Comments []reviewComment `json:"comments,omitempty"`
+}
+
+type reviewComment struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Side string `json:"side"`
+	Body string `json:"body"`
+}
+
+// PostReview submits a review of pull request number in repo at commit
+// sha, with body as its text, event as APPROVE, REQUEST_CHANGES or
+// COMMENT, and a comment on its line for each of comments. It returns the
+// URL of the review, which is empty in dry-run mode.
+func (g *GitHubTool) PostReview(ctx context.Context, repo string, number int, sha, body, event string, comments []ReviewComment) (string, error) {
+	if dryrun.Skip("github", "post a %s review with %d comments on %s#%d", event, len(comments), repo, number) {
+		return "", nil
+	}
+	req := reviewRequest{CommitID: sha, Body: body, Event: event}
+	for _, c := range comments {
+		req.Comments = append(req.Comments, reviewComment{
+			Path: c.Path,
+			Line: c.Line,
+			Side: "RIGHT",
+			Body: fmt.Sprintf("**%s**: %s", c.Severity, c.Body),
+		})
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	endpoint := fmt.Sprintf("repos/%s/pulls/%d/reviews", repo, number)
+	output, err := runCommandIO(ctx, "", g.timeout, bytes.NewReader(input), "gh", "api", "--method", "POST", endpoint, "--input", "-")
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal([]byte(output), &created); err != nil {
+		return "", fmt.Errorf("gh api returned an unexpected review: %w", err)
+	}
+	return created.HTMLURL, nil
+}
+
 func (g *GitHubTool) listRepos(ctx context.Context) (string, error) {
 	cmd := exec.CommandContext(ctx, "gh", "repo", "list", "--limit", "20")
 	output, err := cmd.CombinedOutput()
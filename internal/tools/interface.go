@@ -3,6 +3,10 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sync"
+
+	"github.com/biodoia/skagent/internal/chaos"
+	"github.com/biodoia/skagent/internal/redact"
 )
 
 // Tool interface for all tool implementations
@@ -19,7 +23,12 @@ type Tool interface {
 
 // ToolManager manages a collection of tools
 type ToolManager struct {
-	tools []Tool
+	mu       sync.RWMutex
+	tools    []Tool
+	watchers []func(Tool)
+	removed  []func(name string)
+	// faults, when set, injects chaos faults into tool runs
+	faults *chaos.Injector
 }
 
 // NewToolManager creates a new tool manager
@@ -31,11 +40,94 @@ func NewToolManager() *ToolManager {
 
 // AddTool registers a new tool
 func (tm *ToolManager) AddTool(tool Tool) {
+	tm.mu.Lock()
 	tm.tools = append(tm.tools, tool)
+	watchers := append([]func(Tool){}, tm.watchers...)
+	tm.mu.Unlock()
+	for _, fn := range watchers {
+		fn(tool)
+	}
+}
+
+// Watch calls fn with every registered tool, then with each tool added
+// later
+func (tm *ToolManager) Watch(fn func(Tool)) {
+	tm.mu.Lock()
+	tm.watchers = append(tm.watchers, fn)
+	existing := append([]Tool{}, tm.tools...)
+	tm.mu.Unlock()
+	for _, tool := range existing {
+		fn(tool)
+	}
+}
+
+// RemoveTool unregisters a tool by name and reports whether there was one
+func (tm *ToolManager) RemoveTool(name string) bool {
+	tm.mu.Lock()
+	found := false
+	for i, tool := range tm.tools {
+		if tool.Name() == name {
+			tm.tools = append(tm.tools[:i:i], tm.tools[i+1:]...)
+			found = true
+			break
+		}
+	}
+	removed := append([]func(string){}, tm.removed...)
+	tm.mu.Unlock()
+	if found {
+		for _, fn := range removed {
+			fn(name)
+		}
+	}
+	return found
+}
+
+// WatchRemoved calls fn with the name of each tool removed from now on
+func (tm *ToolManager) WatchRemoved(fn func(name string)) {
+	tm.mu.Lock()
+	tm.removed = append(tm.removed, fn)
+	tm.mu.Unlock()
+}
+
+// Scoped returns a manager with the scoped tools, made for one workspace,
+// that shares the other tools of tm: those no scoped tool replaces by
+// name, including the tools added to or removed from tm later. Tool runs
+// get the faults of tm.
+func (tm *ToolManager) Scoped(scoped ...Tool) *ToolManager {
+	s := NewToolManager()
+	replaced := make(map[string]bool, len(scoped))
+	for _, tool := range scoped {
+		s.AddTool(tool)
+		replaced[tool.Name()] = true
+	}
+	tm.mu.RLock()
+	s.faults = tm.faults
+	tm.mu.RUnlock()
+	tm.Watch(func(tool Tool) {
+		if !replaced[tool.Name()] {
+			s.AddTool(tool)
+		}
+	})
+	tm.WatchRemoved(func(name string) {
+		if !replaced[name] {
+			s.RemoveTool(name)
+		}
+	})
+	return s
+}
+
+// SetFaults injects the faults of inj into every tool run: errors,
+// latency and truncated output. nil stops injecting.
+func (tm *ToolManager) SetFaults(inj *chaos.Injector) {
+	tm.mu.Lock()
+	tm.faults = inj
+	tm.mu.Unlock()
 }
 
 // GetTool returns a tool by name
 func (tm *ToolManager) GetTool(name string) Tool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	for _, tool := range tm.tools {
 		if tool.Name() == name {
 			return tool
@@ -46,11 +138,15 @@ func (tm *ToolManager) GetTool(name string) Tool {
 
 // ListTools returns all registered tools
 func (tm *ToolManager) ListTools() []Tool {
-	return tm.tools
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return append([]Tool{}, tm.tools...)
 }
 
 // CanHandle checks if any tool can handle the given intent
 func (tm *ToolManager) CanHandle(intent string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	for _, tool := range tm.tools {
 		if tool.CanHandle(intent) {
 			return true
@@ -61,6 +157,8 @@ func (tm *ToolManager) CanHandle(intent string) bool {
 
 // FindTool returns the first tool that can handle the intent
 func (tm *ToolManager) FindTool(intent string) Tool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	for _, tool := range tm.tools {
 		if tool.CanHandle(intent) {
 			return tool
@@ -75,7 +173,7 @@ func (tm *ToolManager) Execute(ctx context.Context, intent string, input string)
 	if tool == nil {
 		return "", fmt.Errorf("no tool can handle intent: %s", intent)
 	}
-	return tool.Execute(ctx, input)
+	return tm.run(ctx, tool, input)
 }
 
 // ExecuteByName runs a specific tool by name
@@ -84,12 +182,40 @@ func (tm *ToolManager) ExecuteByName(ctx context.Context, name string, input str
 	if tool == nil {
 		return "", fmt.Errorf("tool not found: %s", name)
 	}
-	return tool.Execute(ctx, input)
+	return tm.run(ctx, tool, input)
+}
+
+// run executes a tool, with the faults of chaos mode if any, and scrubs
+// secrets from its output and progress
+func (tm *ToolManager) run(ctx context.Context, tool Tool, input string) (string, error) {
+	ctx = scrubProgress(ctx)
+	tm.mu.RLock()
+	faults := tm.faults
+	tm.mu.RUnlock()
+	if faults == nil {
+		output, err := tool.Execute(ctx, input)
+		return redact.String(output), err
+	}
+
+	plan := faults.Plan(tool.Name())
+	if err := plan.Wait(ctx); err != nil {
+		return "", err
+	}
+	if plan.Fail == chaos.FaultError {
+		return "", faults.Fail(tool.Name())
+	}
+	output, err := tool.Execute(ctx, input)
+	if err == nil && plan.Truncate {
+		output = chaos.Truncate(output)
+	}
+	return redact.String(output), err
 }
 
 // GetToolDescriptions returns a map of tool names to descriptions
 func (tm *ToolManager) GetToolDescriptions() map[string]string {
 	descriptions := make(map[string]string)
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	for _, tool := range tm.tools {
 		descriptions[tool.Name()] = tool.Description()
 	}
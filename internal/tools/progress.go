@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/biodoia/skagent/internal/redact"
+)
+
+// Progress is an update a tool gives while it runs
+type Progress struct {
+	// Progress is the work done so far and grows with each update; Total
+	// is the work there is, 0 when unknown
+	Progress float64
+	Total    float64
+	Message  string
+	// Partial is output ready before the result, such as a search result
+	// found while the others are fetched
+	Partial string
+}
+
+type progressKey struct{}
+
+// WithProgress has the tools run under ctx report their progress to fn,
+// such as an MCP host that shows it to its user. fn may be called from
+// several goroutines.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress reports p to the function attached to ctx with
+// WithProgress; without one it does nothing
+func ReportProgress(ctx context.Context, p Progress) {
+	if fn, ok := ctx.Value(progressKey{}).(func(Progress)); ok && fn != nil {
+		fn(p)
+	}
+}
+
+// scrubProgress has the updates reported under ctx scrubbed of secrets,
+// like the output of a tool
+func scrubProgress(ctx context.Context) context.Context {
+	fn, ok := ctx.Value(progressKey{}).(func(Progress))
+	if !ok || fn == nil {
+		return ctx
+	}
+	return WithProgress(ctx, func(p Progress) {
+		p.Message, p.Partial = redact.String(p.Message), redact.String(p.Partial)
+		fn(p)
+	})
+}
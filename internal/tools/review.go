@@ -0,0 +1,358 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/redact"
+)
+
+// DefaultMaxReviewDiff is the largest diff sent to the model, in bytes,
+// when none is configured: 200 KiB
+const DefaultMaxReviewDiff = 200 << 10
+
+// Review verdicts
+const (
+	VerdictApprove        = "approve"
+	VerdictComment        = "comment"
+	VerdictRequestChanges = "request_changes"
+)
+
+// Comment severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// ReviewComment is a finding on one line of the new side of a diff
+type ReviewComment struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Body     string `json:"body"`
+}
+
+// Review is the structured result of reviewing a diff
+type Review struct {
+	Summary string `json:"summary"`
+	Verdict string `json:"verdict"`
+	// Comments are anchored to lines of the diff; Notes are the findings
+	// the model made about lines the diff does not show
+	Comments []ReviewComment `json:"comments"`
+	Notes    []string        `json:"notes,omitempty"`
+	// Model is the provider that reviewed the diff; empty when only the
+	// static checks ran
+	Model string `json:"model,omitempty"`
+}
+
+// Event is the GitHub review event for the verdict: APPROVE,
+// REQUEST_CHANGES or COMMENT
+func (r *Review) Event() string {
+	switch r.Verdict {
+	case VerdictApprove:
+		return "APPROVE"
+	case VerdictRequestChanges:
+		return "REQUEST_CHANGES"
+	default:
+		return "COMMENT"
+	}
+}
+
+// DiffFile is the new side of one file in a unified diff
+type DiffFile struct {
+	Path string
+	// Added are the added lines by line number
+	Added map[int]string
+	// Lines are the line numbers a review comment may be anchored to:
+	// the added and context lines of the file's hunks
+	Lines map[int]bool
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// ParseDiff reads the files of a unified diff, as printed by git diff or
+// gh pr diff. Deleted files are left out.
+func ParseDiff(diff string) []*DiffFile {
+	var files []*DiffFile
+	var file *DiffFile
+	line, prev := 0, ""
+	for _, l := range strings.Split(diff, "\n") {
+		// An added line may start with "++ " too; a header follows "--- "
+		header := strings.HasPrefix(prev, "--- ")
+		prev = l
+		switch {
+		case strings.HasPrefix(l, "diff --git "):
+			file = nil
+		case header && strings.HasPrefix(l, "+++ "):
+			path := strings.TrimPrefix(l, "+++ ")
+			if path == "/dev/null" {
+				file = nil
+				continue
+			}
+			file = &DiffFile{Path: strings.TrimPrefix(path, "b/"), Added: make(map[int]string), Lines: make(map[int]bool)}
+			files = append(files, file)
+		case file == nil:
+		case strings.HasPrefix(l, "@@"):
+			m := hunkHeader.FindStringSubmatch(l)
+			if m == nil {
+				continue
+			}
+			line, _ = strconv.Atoi(m[1])
+		case strings.HasPrefix(l, "+"):
+			file.Added[line] = l[1:]
+			file.Lines[line] = true
+			line++
+		case strings.HasPrefix(l, " "):
+			file.Lines[line] = true
+			line++
+		}
+	}
+	return files
+}
+
+// DiffReviewTool reviews unified diffs: static checks flag conflict
+// markers, credentials and leftover debugging in the added lines, and the
+// model, when there is one, reviews the change as a whole
+type DiffReviewTool struct {
+	provider func() ai.Provider
+	maxDiff  int
+}
+
+// NewDiffReviewTool creates a diff review tool asking the model provider
+// returns, or running the static checks alone when it returns nil.
+// maxDiff bounds the diff sent to the model; 0 uses DefaultMaxReviewDiff.
+func NewDiffReviewTool(provider func() ai.Provider, maxDiff int) *DiffReviewTool {
+	if maxDiff <= 0 {
+		maxDiff = DefaultMaxReviewDiff
+	}
+	return &DiffReviewTool{provider: provider, maxDiff: maxDiff}
+}
+
+// Name returns the tool identifier
+func (t *DiffReviewTool) Name() string {
+	return "diff_review"
+}
+
+// Description returns tool description
+func (t *DiffReviewTool) Description() string {
+	return "Review a unified diff and return structured comments with a verdict"
+}
+
+// CanHandle checks if this tool can handle the intent
+func (t *DiffReviewTool) CanHandle(intent string) bool {
+	lower := strings.ToLower(intent)
+	return strings.Contains(lower, "review") && (strings.Contains(lower, "diff") || strings.Contains(lower, "patch"))
+}
+
+// Execute reviews the diff given as input and returns the review as JSON
+func (t *DiffReviewTool) Execute(ctx context.Context, input string) (string, error) {
+	review, err := t.Review(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.MarshalIndent(review, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// Review reviews a unified diff. A model that fails or answers with
+// something other than a review leaves the static findings, with the
+// failure noted in the summary.
+func (t *DiffReviewTool) Review(ctx context.Context, diff string) (*Review, error) {
+	files := ParseDiff(diff)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("input is not a unified diff with changes to review")
+	}
+
+	review := &Review{Comments: staticReview(files)}
+	var summary string
+	var provider ai.Provider
+	if t.provider != nil {
+		provider = t.provider()
+	}
+	// The caller may offer its own model, as an MCP host granting sampling
+	provider = ai.ProviderFor(ctx, provider)
+	switch {
+	case provider == nil:
+		summary = "Static checks only; no model is configured."
+	case len(diff) > t.maxDiff:
+		summary = fmt.Sprintf("Static checks only; the diff is %d bytes, over the %d the model reviews.", len(diff), t.maxDiff)
+	default:
+		answer, err := t.ask(ctx, provider, diff)
+		if err != nil {
+			summary = "Static checks only; the model review failed: " + err.Error()
+			break
+		}
+		review.Model = provider.Name()
+		summary = strings.TrimSpace(answer.Summary)
+		review.Verdict = answer.Verdict
+		review.Comments = append(review.Comments, anchor(files, answer.Comments, &review.Notes)...)
+	}
+
+	review.Comments = dedupe(review.Comments)
+	review.Summary = summary
+	review.Verdict = verdict(review)
+	return review, nil
+}
+
+// reviewPrompt asks the model for a review in the shape of Review
+const reviewPrompt = `You are a senior code reviewer. Review the unified diff the user sends.
+Report only real problems: bugs, security issues, missing error handling, race conditions, unclear code that will cause mistakes. Do not praise and do not restate the change.
+Answer with JSON only, no prose around it:
+{"summary": "<two or three sentences on the change and its main risks>",
+ "verdict": "approve" | "comment" | "request_changes",
+ "comments": [{"path": "<file path as in the diff>", "line": <line number on the new side>, "severity": "error" | "warning" | "info", "body": "<the problem and how to fix it>"}]}
+Use request_changes only for problems that must be fixed before merging.`
+
+func (t *DiffReviewTool) ask(ctx context.Context, provider ai.Provider, diff string) (*Review, error) {
+	reply, err := provider.Complete(ctx, []ai.Message{{Role: "user", Content: diff}}, reviewPrompt)
+	if err != nil {
+		return nil, err
+	}
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("the model did not answer with a JSON review")
+	}
+	var answer Review
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &answer); err != nil {
+		return nil, fmt.Errorf("the model's review is not valid JSON: %w", err)
+	}
+	return &answer, nil
+}
+
+// anchor keeps the comments on lines the diff shows, normalizing their
+// severity; the others are added to notes, since GitHub refuses review
+// comments outside the diff
+func anchor(files []*DiffFile, comments []ReviewComment, notes *[]string) []ReviewComment {
+	byPath := make(map[string]*DiffFile, len(files))
+	for _, f := range files {
+		byPath[f.Path] = f
+	}
+	var out []ReviewComment
+	for _, c := range comments {
+		c.Body = strings.TrimSpace(c.Body)
+		if c.Body == "" {
+			continue
+		}
+		switch c.Severity {
+		case SeverityError, SeverityWarning, SeverityInfo:
+		default:
+			c.Severity = SeverityWarning
+		}
+		if f := byPath[c.Path]; f != nil && f.Lines[c.Line] {
+			out = append(out, c)
+			continue
+		}
+		where := c.Path
+		if c.Line > 0 {
+			where += ":" + strconv.Itoa(c.Line)
+		}
+		if where == "" {
+			*notes = append(*notes, c.Body)
+		} else {
+			*notes = append(*notes, where+": "+c.Body)
+		}
+	}
+	return out
+}
+
+// A static check flags an added line
+type staticCheck struct {
+	match    func(line string) bool
+	severity string
+	body     string
+}
+
+var (
+	debugStatement = regexp.MustCompile(`\bconsole\.log\(|^\s*debugger;|\bpdb\.set_trace\(|\bbreakpoint\(\)|\bbinding\.pry\b`)
+	todoMarker     = regexp.MustCompile(`\b(?:TODO|FIXME|XXX)\b`)
+)
+
+var staticChecks = []staticCheck{
+	{
+		match: func(l string) bool {
+			return strings.HasPrefix(l, "<<<<<<< ") || strings.HasPrefix(l, ">>>>>>> ") || l == "======="
+		},
+		severity: SeverityError,
+		body:     "Merge conflict marker left in the file.",
+	},
+	{
+		match:    redact.HasCredential,
+		severity: SeverityError,
+		body:     "This line looks like it contains a credential. Load it from the environment or a secret store and rotate it.",
+	},
+	{
+		match:    debugStatement.MatchString,
+		severity: SeverityWarning,
+		body:     "Debugging statement left in the change.",
+	},
+	{
+		match:    todoMarker.MatchString,
+		severity: SeverityInfo,
+		body:     "New TODO; consider tracking it in an issue.",
+	},
+}
+
+// staticReview runs the static checks on every added line, in file and
+// line order
+func staticReview(files []*DiffFile) []ReviewComment {
+	var out []ReviewComment
+	for _, f := range files {
+		lines := make([]int, 0, len(f.Added))
+		for n := range f.Added {
+			lines = append(lines, n)
+		}
+		sort.Ints(lines)
+		for _, n := range lines {
+			for _, check := range staticChecks {
+				if check.match(f.Added[n]) {
+					out = append(out, ReviewComment{Path: f.Path, Line: n, Severity: check.severity, Body: check.body})
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// dedupe drops repeated comments on the same line
+func dedupe(comments []ReviewComment) []ReviewComment {
+	seen := make(map[string]bool, len(comments))
+	out := comments[:0]
+	for _, c := range comments {
+		key := c.Path + "\x00" + strconv.Itoa(c.Line) + "\x00" + c.Body
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// verdict settles the verdict: errors request changes whatever the model
+// said, and only a model may approve, and only a change with no comments
+func verdict(r *Review) string {
+	for _, c := range r.Comments {
+		if c.Severity == SeverityError {
+			return VerdictRequestChanges
+		}
+	}
+	switch r.Verdict {
+	case VerdictRequestChanges:
+		return VerdictRequestChanges
+	case VerdictApprove:
+		if len(r.Comments) == 0 && r.Model != "" {
+			return VerdictApprove
+		}
+	}
+	return VerdictComment
+}
@@ -6,6 +6,8 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/biodoia/skagent/internal/dryrun"
 )
 
 // DefaultTimeout for CLI commands
@@ -83,6 +85,9 @@ func (s *SpecKitTool) executeInit(ctx context.Context, input string) (string, er
 		return "", fmt.Errorf("project name not found in input")
 	}
 
+	if out, ok := simulate("speckit", dryrun.Command("specify", "init", projectName)); ok {
+		return out, nil
+	}
 	cmd := exec.CommandContext(ctx, "specify", "init", projectName)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -96,6 +101,9 @@ func (s *SpecKitTool) executeInit(ctx context.Context, input string) (string, er
 }
 
 func (s *SpecKitTool) executeCommand(ctx context.Context, command string) (string, error) {
+	if out, ok := simulate("speckit", dryrun.Command("specify", command)); ok {
+		return out, nil
+	}
 	cmd := exec.CommandContext(ctx, "specify", command)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
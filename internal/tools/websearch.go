@@ -77,6 +77,7 @@ func (w *WebSearchTool) searchGitHub(ctx context.Context, query string) (string,
 
 	searchQuery := url.QueryEscape(strings.Join(terms, " "))
 	apiURL := fmt.Sprintf("https://api.github.com/search/repositories?q=%s&sort=stars&per_page=5", searchQuery)
+	ReportProgress(ctx, Progress{Message: fmt.Sprintf("Searching GitHub for '%s'", strings.Join(terms, " "))})
 
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
@@ -126,6 +127,7 @@ func (w *WebSearchTool) searchGitHub(ctx context.Context, query string) (string,
 	sb.WriteString(fmt.Sprintf("Found %d repositories for '%s':\n\n", result.TotalCount, strings.Join(terms, " ")))
 
 	for i, repo := range result.Items {
+		start := sb.Len()
 		sb.WriteString(fmt.Sprintf("%d. **%s** ⭐ %d\n", i+1, repo.FullName, repo.Stars))
 		if repo.Description != "" {
 			desc := repo.Description
@@ -138,6 +140,8 @@ func (w *WebSearchTool) searchGitHub(ctx context.Context, query string) (string,
 			sb.WriteString(fmt.Sprintf("   Language: %s\n", repo.Language))
 		}
 		sb.WriteString(fmt.Sprintf("   %s\n\n", repo.HTMLURL))
+		// Each repository is shown as soon as it is formatted
+		ReportProgress(ctx, Progress{Progress: float64(i + 1), Total: float64(len(result.Items)), Partial: sb.String()[start:]})
 	}
 
 	return sb.String(), nil
@@ -150,6 +154,7 @@ func (w *WebSearchTool) searchDuckDuckGo(ctx context.Context, query string) (str
 		return "", fmt.Errorf("no search terms found")
 	}
 
+	ReportProgress(ctx, Progress{Message: fmt.Sprintf("Searching DuckDuckGo for '%s'", strings.Join(terms, " "))})
 	searchQuery := url.QueryEscape(strings.Join(terms, " "))
 	apiURL := fmt.Sprintf("https://api.duckduckgo.com/?q=%s&format=json&no_html=1&skip_disambig=1", searchQuery)
 
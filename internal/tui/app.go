@@ -2,6 +2,7 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -13,13 +14,16 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/biodoia/skagent/internal/ai"
 	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/docs"
+	"github.com/biodoia/skagent/internal/i18n"
 	"github.com/biodoia/skagent/internal/tools"
+	"github.com/biodoia/skagent/internal/tui/themes"
 )
 
 // RequestTimeout for AI and tool operations
 const RequestTimeout = 60 * time.Second
 
-// Styles
+// Styles, rebuilt from the active theme by applyStyles
 var (
 	titleStyle = lipgloss.NewStyle().
 			Bold(true).
@@ -54,6 +58,13 @@ var (
 	providerStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#CBA6F7")).
 			Bold(true)
+
+	modeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#A6E3A1")).
+			Bold(true)
+
+	spinnerStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#89B4FA"))
 )
 
 // Message types for tea.Msg
@@ -83,8 +94,13 @@ type Model struct {
 	provider    ai.Provider
 	config      *config.Config
 	tools       *tools.ToolManager
+	docSections []docs.Section
+	themes      *themes.ThemeManager
+	themeEvents <-chan themes.ReloadResult
 	autonomous  bool
 	loading     bool
+	rateLimit   *ai.RateLimit // set while the provider is refusing requests
+	follow      *followState  // set while an agent of the daemon is followed
 	width       int
 	height      int
 	ready       bool
@@ -99,24 +115,34 @@ func InitialModel() Model {
 // InitialModelWithConfig creates the initial application state with custom config
 func initialModelWithConfig(cfg *config.Config) Model {
 	ti := textinput.New()
-	ti.Placeholder = "Describe your project idea... (type /help for commands)"
+	ti.Placeholder = i18n.T("tui.placeholder")
 	ti.Focus()
 	ti.CharLimit = 1000
 	ti.Width = 70
 
+	// Themes: built-in and custom ones from the themes directory
+	themeManager, themeErrors := newThemeManager(cfg)
+
 	sp := spinner.New()
 	sp.Spinner = spinner.Dot
-	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#89B4FA"))
+	sp.Style = spinnerStyle
 
 	// Initialize tool manager with all tools
 	tm := tools.NewToolManager()
 	tm.AddTool(tools.NewSpecKitTool(""))
 	tm.AddTool(tools.NewGitHubTool(""))
+	tm.AddTool(tools.NewGitTool(""))
 	tm.AddTool(tools.NewWebSearchTool())
 
+	// SpecKit docs: the embedded copy, overridden by the remote docs cache
+	// and SpecKitPath
+	docSections, err := docs.ForConfig(cfg).Sections()
+	if err != nil {
+		docSections, _ = docs.NewDocLoader("").Sections()
+	}
+
 	// Create AI provider
 	var provider ai.Provider
-	var err error
 	if cfg != nil {
 		provider, err = ai.CreateProvider(cfg)
 		if err != nil {
@@ -124,15 +150,18 @@ func initialModelWithConfig(cfg *config.Config) Model {
 			provider = nil
 		}
 	}
+	tm.AddTool(tools.NewDiffReviewTool(func() ai.Provider { return provider }, 0))
 
 	return Model{
-		messages:   []Message{},
+		messages:   themeErrors,
 		history:    []ai.Message{},
 		input:      ti,
 		spinner:    sp,
 		provider:   provider,
 		config:     cfg,
 		tools:      tm,
+		docSections: docSections,
+		themes:     themeManager,
 		autonomous: false,
 		loading:    false,
 		ready:      false,
@@ -143,6 +172,7 @@ func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		textinput.Blink,
 		m.spinner.Tick,
+		waitForThemeReload(m.themeEvents),
 	)
 }
 
@@ -159,6 +189,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.loading = false
 				return m, nil
 			}
+			if m.follow != nil {
+				m = m.stopFollow()
+				m.viewport.SetContent(m.renderMessages())
+				m.viewport.GotoBottom()
+				return m, nil
+			}
 			return m, tea.Quit
 		case "enter":
 			if m.input.Value() != "" && !m.loading {
@@ -209,10 +245,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case aiResponseMsg:
 		m.loading = false
+		m.rateLimit = nil
+		if rl, ok := ai.AsRateLimit(msg.err); ok {
+			m.rateLimit = &rl.Limit
+		}
 		if msg.err != nil {
 			m.messages = append(m.messages, Message{
 				Role:    "error",
-				Content: fmt.Sprintf("Error: %v", msg.err),
+				Content: i18n.T("tui.error", msg.err),
 			})
 		} else {
 			m.messages = append(m.messages, Message{
@@ -231,7 +271,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err != nil {
 			m.messages = append(m.messages, Message{
 				Role:    "error",
-				Content: fmt.Sprintf("Tool %s error: %v", msg.tool, msg.err),
+				Content: i18n.T("tui.tool_error", msg.tool, msg.err),
 			})
 		} else {
 			m.messages = append(m.messages, Message{
@@ -241,6 +281,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.viewport.SetContent(m.renderMessages())
 
+	case themeReloadMsg:
+		return m.handleThemeReload(msg)
+
+	case taskDetailMsg:
+		return m.handleTaskDetail(msg)
+
+	case delegateMsg:
+		return m.handleDelegate(msg)
+
+	case rollbackMsg:
+		return m.handleRollback(msg)
+
+	case templateMsg:
+		return m.handleTemplate(msg)
+
+	case followMsg:
+		return m.handleFollow(msg)
+
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -263,17 +321,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) handleCommand(cmd string) (tea.Model, tea.Cmd) {
 	parts := strings.Fields(cmd)
 	command := strings.ToLower(parts[0])
+	var next tea.Cmd
 
 	switch command {
 	case "/auto", "/autonomous":
 		m.autonomous = !m.autonomous
-		status := "disabled"
+		status := i18n.T("tui.auto.disabled")
 		if m.autonomous {
-			status = "enabled"
+			status = i18n.T("tui.auto.enabled")
 		}
 		m.messages = append(m.messages, Message{
 			Role:    "system",
-			Content: fmt.Sprintf("Autonomous mode %s", status),
+			Content: status,
 		})
 
 	case "/clear":
@@ -282,20 +341,20 @@ func (m Model) handleCommand(cmd string) (tea.Model, tea.Cmd) {
 
 	case "/provider":
 		if m.config != nil {
-			providerName := "unknown"
+			providerName := i18n.T("tui.provider.unknown")
 			if m.provider != nil {
 				providerName = m.provider.Name()
 			}
 			model := m.config.GetActiveProvider().Model
 			m.messages = append(m.messages, Message{
 				Role:    "system",
-				Content: fmt.Sprintf("Current provider: %s\nModel: %s", providerName, model),
+				Content: i18n.T("tui.provider.current", providerName, model),
 			})
 		}
 
 	case "/models":
 		var sb strings.Builder
-		sb.WriteString("Available free models on OpenRouter:\n\n")
+		sb.WriteString(i18n.T("tui.models.title"))
 		for i, model := range config.OpenRouterFreeModels {
 			if model.Recommended {
 				sb.WriteString("⭐ ")
@@ -309,6 +368,24 @@ func (m Model) handleCommand(cmd string) (tea.Model, tea.Cmd) {
 			Content: sb.String(),
 		})
 
+	case "/theme":
+		m = m.themeCommand(parts[1:])
+
+	case "/task":
+		m, next = m.taskCommand(parts[1:])
+
+	case "/delegate":
+		m, next = m.delegateCommand(parts[1:])
+
+	case "/rollback":
+		m, next = m.rollbackCommand(parts[1:])
+
+	case "/template":
+		m, next = m.templateCommand(parts[1:])
+
+	case "/follow":
+		m, next = m.followCommand(parts[1:])
+
 	case "/help":
 		m.messages = append(m.messages, Message{
 			Role:    "system",
@@ -321,53 +398,21 @@ func (m Model) handleCommand(cmd string) (tea.Model, tea.Cmd) {
 	default:
 		m.messages = append(m.messages, Message{
 			Role:    "error",
-			Content: fmt.Sprintf("Unknown command: %s\nType /help for available commands", cmd),
+			Content: i18n.T("tui.command.unknown", cmd),
 		})
 	}
 	m.viewport.SetContent(m.renderMessages())
 	m.viewport.GotoBottom()
-	return m, nil
+	return m, next
 }
 
 func helpText() string {
-	return `
-╭─────────────────────────────────────────────╮
-│           SkAgent Commands                   │
-╰─────────────────────────────────────────────╯
-
-  /auto      Toggle autonomous mode
-  /provider  Show current AI provider
-  /models    List available free models
-  /clear     Clear conversation
-  /help      Show this help
-  /quit      Exit application
-
-╭─────────────────────────────────────────────╮
-│           SpecKit Workflow                   │
-╰─────────────────────────────────────────────╯
-
-In autonomous mode, the agent will:
-1. Analyze your project idea
-2. Search for best practices
-3. Generate SpecKit specifications
-4. Create technical plans
-5. Break down into tasks
-
-Just describe your project idea to get started!
-
-╭─────────────────────────────────────────────╮
-│           Keyboard Shortcuts                 │
-╰─────────────────────────────────────────────╯
-
-  Enter      Send message
-  Ctrl+C     Exit
-  Esc        Cancel/Exit
-  ↑/↓        Scroll messages`
+	return i18n.T("tui.help")
 }
 
 func (m Model) View() string {
 	if !m.ready {
-		return "Initializing..."
+		return i18n.T("tui.initializing")
 	}
 
 	// Header with provider info
@@ -380,18 +425,16 @@ func (m Model) View() string {
 
 	modeIndicator := ""
 	if m.autonomous {
-		modeIndicator = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#A6E3A1")).
-			Bold(true).
-			Render(" AUTO")
+		modeIndicator = modeStyle.Render(" AUTO")
 	}
 	header += providerInfo + modeIndicator
 
 	// Error if no provider
 	if m.provider == nil {
-		return fmt.Sprintf("%s\n\n%s\n\nRun 'skagent setup' to configure an AI provider.",
+		return fmt.Sprintf("%s\n\n%s\n\n%s",
 			header,
-			errorStyle.Render("⚠ No AI provider configured"))
+			errorStyle.Render(i18n.T("tui.no_provider")),
+			i18n.T("tui.no_provider.hint"))
 	}
 
 	// Status line
@@ -409,12 +452,15 @@ func (m Model) View() string {
 			}
 		}
 	}
-	status := statusStyle.Render(fmt.Sprintf("Model: %s | Messages: %d | /help for commands", model, len(m.messages)))
+	status := statusStyle.Render(i18n.T("tui.status", model, len(m.messages)))
+	if limited := m.rateLimitStatus(); limited != "" {
+		status += errorStyle.Render(" | " + limited)
+	}
 
 	// Loading indicator
 	loadingIndicator := ""
 	if m.loading {
-		loadingIndicator = fmt.Sprintf(" %s Thinking...", m.spinner.View())
+		loadingIndicator = fmt.Sprintf(" %s %s", m.spinner.View(), i18n.T("tui.thinking"))
 	}
 
 	// Input box
@@ -428,24 +474,39 @@ func (m Model) View() string {
 	)
 }
 
+// rateLimitStatus describes the provider's quota after it refused a
+// request, until the quota resets
+func (m Model) rateLimitStatus() string {
+	if m.rateLimit == nil {
+		return ""
+	}
+	if m.rateLimit.Reset != nil && m.rateLimit.RetryAfter(time.Now()) == 0 {
+		return ""
+	}
+	if s := m.rateLimit.String(); s != "" {
+		return i18n.T("tui.rate_limited.detail", s)
+	}
+	return i18n.T("tui.rate_limited")
+}
+
 func (m Model) renderMessages() string {
 	var sb strings.Builder
 
 	if len(m.messages) == 0 {
-		sb.WriteString(systemStyle.Render("Welcome! Describe your project idea or type /help for commands.\n"))
+		sb.WriteString(systemStyle.Render(i18n.T("tui.welcome")))
 	}
 
 	for _, msg := range m.messages {
 		var styled string
 		switch msg.Role {
 		case "user":
-			styled = userStyle.Render("You: ") + msg.Content
+			styled = userStyle.Render(i18n.T("tui.role.user")) + msg.Content
 		case "assistant":
-			styled = assistantStyle.Render("Agent: ") + msg.Content
+			styled = assistantStyle.Render(i18n.T("tui.role.assistant")) + msg.Content
 		case "system":
-			styled = systemStyle.Render("System: ") + msg.Content
+			styled = systemStyle.Render(i18n.T("tui.role.system")) + msg.Content
 		case "error":
-			styled = errorStyle.Render("Error: ") + msg.Content
+			styled = errorStyle.Render(i18n.T("tui.role.error")) + msg.Content
 		default:
 			styled = msg.Content
 		}
@@ -458,10 +519,10 @@ func (m Model) renderMessages() string {
 func (m Model) processInteractive(input string) tea.Cmd {
 	return func() tea.Msg {
 		if m.provider == nil {
-			return aiResponseMsg{err: fmt.Errorf("no AI provider configured")}
+			return aiResponseMsg{err: errors.New(i18n.T("tui.no_provider.error"))}
 		}
 
-		systemPrompt := ai.SystemPrompt + "\n\n" + ai.SpecKitDocs
+		systemPrompt := ai.SystemPrompt + "\n\n" + m.selectDocs(input)
 
 		response, err := m.provider.Complete(context.Background(), m.history, systemPrompt)
 		return aiResponseMsg{response: response, err: err}
@@ -471,7 +532,7 @@ func (m Model) processInteractive(input string) tea.Cmd {
 func (m Model) processAutonomous(input string) tea.Cmd {
 	return func() tea.Msg {
 		if m.provider == nil {
-			return aiResponseMsg{err: fmt.Errorf("no AI provider configured")}
+			return aiResponseMsg{err: errors.New(i18n.T("tui.no_provider.error"))}
 		}
 
 		// In autonomous mode, we add extra context
@@ -496,13 +557,23 @@ Be proactive and thorough. Start generating specifications immediately.`, input)
 		copy(history, m.history[:len(m.history)-1])
 		history = append(history, ai.Message{Role: "user", Content: prompt})
 
-		systemPrompt := ai.SystemPrompt + "\n\n" + ai.SpecKitDocs
+		systemPrompt := ai.SystemPrompt + "\n\n" + m.selectDocs(input)
 
 		response, err := m.provider.Complete(context.Background(), history, systemPrompt)
 		return aiResponseMsg{response: response, err: err}
 	}
 }
 
+// selectDocs returns the SpecKit docs relevant to input, trimmed to an
+// eighth of the active model's context window when it is known
+func (m Model) selectDocs(input string) string {
+	budget := 0
+	if m.config != nil {
+		budget = config.ContextLength(m.config.GetActiveProvider().Model) / 8
+	}
+	return docs.Select(m.docSections, input, budget)
+}
+
 // Run starts the TUI application with default config
 func Run() error {
 	return RunWithConfig(nil)
@@ -517,6 +588,13 @@ func RunWithConfig(cfg *config.Config) error {
 		m = InitialModel()
 	}
 
+	// Hot-reload custom themes while the TUI runs
+	if dir, err := themes.ThemesDir(); err == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		m.themeEvents = m.themes.Watch(ctx, dir, time.Second)
+	}
+
 	p := tea.NewProgram(
 		m,
 		tea.WithAltScreen(),
@@ -20,6 +20,10 @@ type AgentInfo struct {
 	LastActive  time.Time
 	TasksDone   int
 	SuccessRate float64
+	// Notes are the agent's scratchpad: blockers, environment quirks...
+	Notes       string
+	NotesBy     string
+	NotesAt     time.Time
 }
 
 type DashboardModel struct {
@@ -177,9 +181,39 @@ func (d *DashboardModel) Render() string {
 		search,
 		"",
 		table,
+		"",
+		d.renderDetail(),
 	)
 }
 
+// renderDetail shows the selected agent with its notes
+func (d *DashboardModel) renderDetail() string {
+	agent := d.GetSelectedAgent()
+	if agent == nil {
+		return ""
+	}
+	
+	lines := []string{
+		"🔎 " + agent.Name + " (" + agent.ID + ")",
+		"📝 Notes:",
+	}
+	if strings.TrimSpace(agent.Notes) == "" {
+		lines = append(lines, "  No notes")
+		return strings.Join(lines, "\n")
+	}
+	for _, line := range strings.Split(strings.TrimRight(agent.Notes, "\n"), "\n") {
+		lines = append(lines, "  "+line)
+	}
+	if !agent.NotesAt.IsZero() {
+		updated := "  Updated " + formatRelativeTime(agent.NotesAt)
+		if agent.NotesBy != "" {
+			updated += " by " + agent.NotesBy
+		}
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render(updated))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (d *DashboardModel) renderStats() string {
 	active := 0
 	idle := 0
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/biodoia/skagent/internal/config"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
@@ -248,8 +249,8 @@ func (s *SettingsModel) Render() string {
 }
 
 func getConfigDir() string {
-	if home, err := os.UserHomeDir(); err == nil {
-		return filepath.Join(home, ".config", "skagent")
+	if dir, err := config.ConfigDir(); err == nil {
+		return dir
 	}
 	return ".config/skagent"
 }
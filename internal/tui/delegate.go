@@ -0,0 +1,75 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/constitution"
+	"github.com/biodoia/skagent/internal/core"
+	"github.com/biodoia/skagent/internal/i18n"
+	"github.com/biodoia/skagent/pkg/client"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// delegateMsg carries the tasks created on the daemon from the
+// conversation's plan
+type delegateMsg struct {
+	results []client.BulkResult
+	err     error
+}
+
+// delegateCommand creates a task on the running daemon for each step of
+// the plan last proposed in the conversation. The arguments are labels
+// added to every task, so that they are routed to the right agents.
+func (m Model) delegateCommand(labels []string) (Model, tea.Cmd) {
+	plan := latestPlan(m.history)
+	if len(plan.Tasks) == 0 {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.delegate.no_plan")})
+		return m, nil
+	}
+	ops := core.PlanTaskOps(plan, core.DelegateOptions{Labels: labels})
+	c := daemonClient(m.config)
+
+	m.loading = true
+	return m, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+		defer cancel()
+		results, err := c.ApplyTaskOps(ctx, ops)
+		return delegateMsg{results: results, err: err}
+	}
+}
+
+// latestPlan is the checklist of the last reply that has one
+func latestPlan(history []ai.Message) constitution.Plan {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role != "assistant" {
+			continue
+		}
+		if plan := constitution.ParseTasks(history[i].Content); len(plan.Tasks) > 0 {
+			return plan
+		}
+	}
+	return constitution.Plan{}
+}
+
+// handleDelegate lists the tasks created
+func (m Model) handleDelegate(msg delegateMsg) (tea.Model, tea.Cmd) {
+	m.loading = false
+	if msg.err != nil {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.delegate.error", msg.err)})
+	} else {
+		var sb strings.Builder
+		sb.WriteString(i18n.T("tui.delegate.created", len(msg.results)))
+		for _, r := range msg.results {
+			if r.Task != nil {
+				fmt.Fprintf(&sb, "\n  %s  %s", r.ID, r.Task.Title)
+			}
+		}
+		m.messages = append(m.messages, Message{Role: "system", Content: sb.String()})
+	}
+	m.viewport.SetContent(m.renderMessages())
+	m.viewport.GotoBottom()
+	return m, nil
+}
@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"context"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/i18n"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/pkg/client"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// followState is the live tail of an agent of the daemon, shown in the
+// conversation as it streams
+type followState struct {
+	agentID string
+	events  <-chan followMsg
+	cancel  context.CancelFunc
+}
+
+// followMsg carries the next activity of a followed agent, or the end of
+// its stream with the error that ended it
+type followMsg struct {
+	agentID  string
+	activity tasklog.Activity
+	done     bool
+	err      error
+	// events is the stream the message came from
+	events <-chan followMsg
+}
+
+// followCommand streams the activity of an agent from the daemon's REST
+// API into the conversation; without arguments it stops following
+func (m Model) followCommand(args []string) (Model, tea.Cmd) {
+	if len(args) > 1 || (len(args) == 0 && m.follow == nil) {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.follow.usage")})
+		return m, nil
+	}
+	if m.follow != nil {
+		m = m.stopFollow()
+		if len(args) == 0 {
+			return m, nil
+		}
+	}
+	c := daemonClient(m.config)
+	id := args[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan followMsg, 64)
+	go func() {
+		defer close(events)
+		err := c.FollowAgent(ctx, id, false, func(a client.AgentActivity) error {
+			select {
+			case events <- followMsg{agentID: id, activity: a}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case events <- followMsg{agentID: id, done: true, err: err}:
+		case <-ctx.Done():
+		}
+	}()
+
+	m.follow = &followState{agentID: id, events: events, cancel: cancel}
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("tui.follow.started", id)})
+	return m, waitForActivity(events)
+}
+
+// stopFollow ends the live tail, if any
+func (m Model) stopFollow() Model {
+	if m.follow == nil {
+		return m
+	}
+	m.follow.cancel()
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("tui.follow.stopped", m.follow.agentID)})
+	m.follow = nil
+	return m
+}
+
+// waitForActivity delivers the next activity of a followed agent; it
+// delivers nothing once the tail was stopped
+func waitForActivity(events <-chan followMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		msg.events = events
+		return msg
+	}
+}
+
+// handleFollow adds an activity to the tail, which grows as one block of
+// the conversation, and waits for the next
+func (m Model) handleFollow(msg followMsg) (tea.Model, tea.Cmd) {
+	// Activities of a tail stopped since are dropped
+	if m.follow == nil || msg.events != m.follow.events {
+		return m, nil
+	}
+	if msg.done {
+		m.follow.cancel()
+		m.follow = nil
+		if msg.err != nil {
+			m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.follow.error", msg.agentID, msg.err)})
+		} else {
+			m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("tui.follow.ended", msg.agentID)})
+		}
+	} else {
+		line := msg.activity.Format()
+		step := msg.activity.Step
+		if msg.activity.Type == string(agents.EventTaskFailed) || (step != nil && step.Kind == tasklog.KindError) {
+			line = errorStyle.Render(line)
+		}
+		if last := len(m.messages) - 1; last >= 0 && m.messages[last].Role == "follow" {
+			m.messages[last].Content += "\n" + line
+		} else {
+			m.messages = append(m.messages, Message{Role: "follow", Content: line})
+		}
+	}
+	m.viewport.SetContent(m.renderMessages())
+	m.viewport.GotoBottom()
+	if m.follow == nil {
+		return m, nil
+	}
+	return m, waitForActivity(msg.events)
+}
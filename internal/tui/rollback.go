@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"context"
+
+	"github.com/biodoia/skagent/internal/i18n"
+	"github.com/biodoia/skagent/pkg/client"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// rollbackMsg carries the snapshot a task's workspace was restored to
+type rollbackMsg struct {
+	snapshot *client.Snapshot
+	err      error
+}
+
+// rollbackCommand restores the workspace of a task on the running daemon
+// to the snapshot taken before its agent changed it
+func (m Model) rollbackCommand(args []string) (Model, tea.Cmd) {
+	if len(args) != 1 {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.rollback.usage")})
+		return m, nil
+	}
+	c := daemonClient(m.config)
+	id := args[0]
+
+	m.loading = true
+	return m, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+		defer cancel()
+		snap, err := c.RollbackTask(ctx, id)
+		return rollbackMsg{snapshot: snap, err: err}
+	}
+}
+
+// handleRollback reports the restored snapshot
+func (m Model) handleRollback(msg rollbackMsg) (tea.Model, tea.Cmd) {
+	m.loading = false
+	if msg.err != nil {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.rollback.error", msg.err)})
+	} else {
+		snap := msg.snapshot
+		content := i18n.T("tui.rollback.done", snap.TaskID, shortCommit(snap.Head), snap.Branch, snap.Workspace)
+		m.messages = append(m.messages, Message{Role: "system", Content: content})
+	}
+	m.viewport.SetContent(m.renderMessages())
+	m.viewport.GotoBottom()
+	return m, nil
+}
+
+// shortCommit abbreviates a commit hash as git does
+func shortCommit(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,138 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/i18n"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/pkg/client"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// taskDetailMsg carries a task fetched from the running daemon, rendered
+// for the conversation
+type taskDetailMsg struct {
+	detail string
+	err    error
+}
+
+// taskCommand fetches a task with its execution log from the daemon's
+// REST API, at $SKAGENT_URL or the configured API address
+func (m Model) taskCommand(args []string) (Model, tea.Cmd) {
+	if len(args) != 1 {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.task.usage")})
+		return m, nil
+	}
+	c := daemonClient(m.config)
+	id := args[0]
+
+	m.loading = true
+	return m, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+		defer cancel()
+		task, err := c.GetTask(ctx, id)
+		if err != nil {
+			return taskDetailMsg{err: err}
+		}
+		// A daemon without task logs answers 503; show the task without
+		entries, err := c.TaskLog(ctx, id)
+		var apiErr *client.Error
+		if err != nil && !(errors.As(err, &apiErr) && apiErr.Status == http.StatusServiceUnavailable) {
+			return taskDetailMsg{err: err}
+		}
+		return taskDetailMsg{detail: renderTaskDetail(task, entries)}
+	}
+}
+
+// handleTaskDetail shows a fetched task
+func (m Model) handleTaskDetail(msg taskDetailMsg) (tea.Model, tea.Cmd) {
+	m.loading = false
+	if msg.err != nil {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.task.fetch_error", msg.err)})
+	} else {
+		m.messages = append(m.messages, Message{Role: "system", Content: msg.detail})
+	}
+	m.viewport.SetContent(m.renderMessages())
+	m.viewport.GotoBottom()
+	return m, nil
+}
+
+// renderTaskDetail is the task detail pane: the task, its outcome and
+// its execution log
+func renderTaskDetail(task *client.Task, entries []client.TaskLogEntry) string {
+	var sb strings.Builder
+	field := func(label string, value interface{}) {
+		fmt.Fprintf(&sb, "  %-10s %v\n", i18n.T(label), value)
+	}
+	sb.WriteString(i18n.T("tui.task.heading", task.ID) + "\n\n")
+	field("tui.task.title", task.Title)
+	field("tui.task.status", task.Status)
+	if task.AssignedTo != "" {
+		field("tui.task.agent", task.AssignedTo)
+	}
+	if task.Revision > 0 {
+		field("tui.task.revision", task.Revision)
+	}
+	if len(task.Attempts) > 0 {
+		field("tui.task.attempt", len(task.Attempts)+1)
+	}
+	if r := task.Result; r != nil {
+		if r.Error != "" {
+			field("tui.task.error", r.Error)
+		}
+		if r.Evaluation != nil {
+			field("tui.task.score", i18n.T("tui.task.score.value", r.Evaluation.Score, r.Evaluation.Threshold))
+		}
+	}
+
+	sb.WriteString("\n" + i18n.T("tui.task.log") + "\n")
+	if len(entries) == 0 {
+		sb.WriteString("  " + i18n.T("tui.task.log.empty") + "\n")
+	}
+	for _, e := range entries {
+		line := tasklog.Format(e)
+		if e.Kind == tasklog.KindError {
+			line = errorStyle.Render(line)
+		}
+		sb.WriteString("  " + line + "\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// daemonClient is a client of the daemon's REST API, with the key in
+// $SKAGENT_API_KEY if set
+func daemonClient(cfg *config.Config) *client.Client {
+	var opts []client.Option
+	if key := os.Getenv("SKAGENT_API_KEY"); key != "" {
+		opts = append(opts, client.WithAPIKey(key))
+	}
+	return client.New(apiURL(cfg), opts...)
+}
+
+// apiURL is the address of the daemon's REST API
+func apiURL(cfg *config.Config) string {
+	if u := os.Getenv("SKAGENT_URL"); u != "" {
+		return u
+	}
+	host, port, scheme := "localhost", 8080, "http"
+	if cfg != nil {
+		if cfg.API.Host != "" && cfg.API.Host != "0.0.0.0" && cfg.API.Host != "::" {
+			host = cfg.API.Host
+		}
+		if cfg.API.Port > 0 {
+			port = cfg.API.Port
+		}
+		if cfg.API.TLS.CertFile != "" {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/i18n"
+	"github.com/biodoia/skagent/internal/templates"
+	"github.com/biodoia/skagent/pkg/client"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// templateMsg carries the task created on the daemon from a template
+type templateMsg struct {
+	task *client.Task
+	err  error
+}
+
+// templateCommand lists the built-in task templates or, given a name and
+// name=value parameters, creates a task from one on the running daemon
+func (m Model) templateCommand(args []string) (Model, tea.Cmd) {
+	if len(args) == 0 {
+		m.messages = append(m.messages, Message{Role: "system", Content: templateList()})
+		return m, nil
+	}
+	tmpl, ok := templates.Get(args[0])
+	if !ok {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.template.unknown", args[0])})
+		return m, nil
+	}
+	params, err := templateParams(tmpl, args[1:])
+	if err != nil {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.template.error", err)})
+		return m, nil
+	}
+	c := daemonClient(m.config)
+
+	m.loading = true
+	return m, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+		defer cancel()
+		task, err := c.CreateTaskFromTemplate(ctx, client.TemplateTaskRequest{Template: tmpl.Name, Params: params})
+		return templateMsg{task: task, err: err}
+	}
+}
+
+// templateParams reads name=value arguments. A value runs until the next
+// argument that starts with a parameter of the template and "=", so values
+// may have spaces: summary=login fails component=auth.
+func templateParams(tmpl templates.Template, args []string) (map[string]string, error) {
+	params := make(map[string]string)
+	current := ""
+	for _, arg := range args {
+		if name, value, ok := strings.Cut(arg, "="); ok && isParam(tmpl, name) {
+			current = name
+			params[name] = value
+			continue
+		}
+		if current == "" {
+			return nil, fmt.Errorf("%q is not name=value", arg)
+		}
+		params[current] += " " + arg
+	}
+	return params, nil
+}
+
+func isParam(tmpl templates.Template, name string) bool {
+	for _, p := range tmpl.Params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// templateList describes the templates and their parameters; required
+// ones are marked with *
+func templateList() string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T("tui.template.list"))
+	for _, t := range templates.Builtin() {
+		fmt.Fprintf(&sb, "\n\n  %s  %s", t.Name, t.Summary)
+		for _, p := range t.Params {
+			mark := " "
+			if p.Required {
+				mark = "*"
+			}
+			fmt.Fprintf(&sb, "\n    %s%s  %s", mark, p.Name, p.Description)
+			if p.Default != "" {
+				fmt.Fprintf(&sb, " (%s)", i18n.T("tui.template.default", p.Default))
+			}
+		}
+	}
+	sb.WriteString("\n\n" + i18n.T("tui.template.usage"))
+	return sb.String()
+}
+
+// handleTemplate reports the task created
+func (m Model) handleTemplate(msg templateMsg) (tea.Model, tea.Cmd) {
+	m.loading = false
+	if msg.err != nil {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.template.error", msg.err)})
+	} else {
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("tui.template.created", msg.task.ID, msg.task.Title)})
+	}
+	m.viewport.SetContent(m.renderMessages())
+	m.viewport.GotoBottom()
+	return m, nil
+}
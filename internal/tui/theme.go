@@ -0,0 +1,145 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/i18n"
+	"github.com/biodoia/skagent/internal/tui/themes"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// themeReloadMsg carries a change in the themes directory
+type themeReloadMsg struct {
+	result themes.ReloadResult
+	ok     bool // false once the watcher has stopped
+}
+
+// newThemeManager loads the custom themes and selects the configured
+// theme; it returns messages describing themes that failed to load
+func newThemeManager(cfg *config.Config) (*themes.ThemeManager, []Message) {
+	tm := themes.NewThemeManager()
+	tm.SetProfile(themes.DetectProfile())
+
+	var msgs []Message
+	if dir, err := themes.ThemesDir(); err == nil {
+		for _, err := range tm.LoadThemesDir(dir).Errors {
+			msgs = append(msgs, Message{Role: "error", Content: i18n.T("tui.theme.error", err)})
+		}
+	}
+
+	name := ""
+	if cfg != nil {
+		name = cfg.ThemeName
+	}
+	switch {
+	case name == "" || name == "catppuccin":
+		// The default family follows the terminal background
+		tm.SetTheme(themes.DefaultThemeFor(themes.HasDarkBackground()))
+	case tm.SetTheme(name) != nil:
+		msgs = append(msgs, Message{Role: "error", Content: i18n.T("tui.theme.unknown", name, themes.DefaultTheme)})
+	}
+	applyStyles(tm.Styles())
+	return tm, msgs
+}
+
+// applyStyles rebuilds the TUI styles from a theme
+func applyStyles(s *themes.Styles) {
+	titleStyle = s.Header
+	userStyle = s.UserMessage
+	assistantStyle = s.AssistantMessage
+	systemStyle = s.SystemMessage
+	errorStyle = s.ErrorMessage
+	inputStyle = s.InputFocused
+	statusStyle = s.Muted.Italic(true)
+	providerStyle = s.Subtitle.Bold(true)
+	modeStyle = s.StatusOnline.Bold(true)
+	spinnerStyle = s.Progress
+}
+
+// waitForThemeReload delivers the next change from the themes watcher
+func waitForThemeReload(events <-chan themes.ReloadResult) tea.Cmd {
+	if events == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		res, ok := <-events
+		return themeReloadMsg{result: res, ok: ok}
+	}
+}
+
+// handleThemeReload restyles the TUI when the active theme changed and
+// reports the themes that were added, removed or rejected
+func (m Model) handleThemeReload(msg themeReloadMsg) (Model, tea.Cmd) {
+	if !msg.ok {
+		return m, nil
+	}
+	res := msg.result
+	if res.CurrentChanged {
+		applyStyles(m.themes.Styles())
+		m.spinner.Style = spinnerStyle
+	}
+
+	var parts []string
+	if len(res.Loaded) > 0 {
+		parts = append(parts, i18n.T("tui.theme.loaded", strings.Join(res.Loaded, ", ")))
+	}
+	if len(res.Removed) > 0 {
+		parts = append(parts, i18n.T("tui.theme.removed", strings.Join(res.Removed, ", ")))
+	}
+	if len(parts) > 0 {
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("tui.theme.changes", strings.Join(parts, "; "))})
+	}
+	for _, err := range res.Errors {
+		m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.theme.error", err)})
+	}
+	m.viewport.SetContent(m.renderMessages())
+	return m, waitForThemeReload(m.themeEvents)
+}
+
+// themeCommand lists the available themes, previews one or switches to one
+func (m Model) themeCommand(args []string) Model {
+	if len(args) == 0 {
+		var sb strings.Builder
+		sb.WriteString(i18n.T("tui.theme.list"))
+		current := m.themes.CurrentTheme().Name
+		for _, name := range m.themes.ListThemes() {
+			marker := "   "
+			if name == current {
+				marker = "➜  "
+			}
+			sb.WriteString(marker + name)
+			if m.themes.IsCustom(name) {
+				sb.WriteString(i18n.T("tui.theme.custom"))
+			}
+			sb.WriteString("\n")
+		}
+		if dir, err := themes.ThemesDir(); err == nil {
+			sb.WriteString(i18n.T("tui.theme.dir", dir))
+		}
+		m.messages = append(m.messages, Message{Role: "system", Content: sb.String()})
+		return m
+	}
+
+	if args[0] == "preview" {
+		theme := m.themes.CurrentTheme()
+		if len(args) > 1 {
+			var ok bool
+			if theme, ok = m.themes.GetTheme(args[1]); !ok {
+				m.messages = append(m.messages, Message{Role: "error", Content: i18n.T("tui.theme.not_found", args[1])})
+				return m
+			}
+		}
+		m.messages = append(m.messages, Message{Role: "system", Content: "\n" + m.themes.PreviewTheme(theme)})
+		return m
+	}
+
+	if err := m.themes.SetTheme(args[0]); err != nil {
+		m.messages = append(m.messages, Message{Role: "error", Content: err.Error()})
+		return m
+	}
+	applyStyles(m.themes.Styles())
+	m.spinner.Style = spinnerStyle
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("tui.theme.set", args[0])})
+	return m
+}
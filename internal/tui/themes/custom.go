@@ -0,0 +1,295 @@
+package themes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+// DefaultTheme is selected when no theme, or an unknown one, is configured
+const DefaultTheme = "catppuccin-mocha"
+
+// ThemesDir returns the directory scanned for custom themes
+func ThemesDir() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "themes"), nil
+}
+
+// Validate checks every color of the theme. Colors are "#RGB" or "#RRGGBB"
+// hex values or ANSI color numbers from 0 to 255; empty colors are allowed
+// and are filled from the default theme when the theme is loaded.
+func (t *Theme) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("theme has no name")
+	}
+
+	var problems []string
+	if t.Extends != "" && !IsBuiltin(t.Extends) {
+		problems = append(problems, fmt.Sprintf("extends: unknown built-in theme %q", t.Extends))
+	}
+	for _, e := range t.InvalidColors() {
+		problems = append(problems, e.Error())
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("theme %s: %s", t.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ColorError names a color of a theme that is not valid, or a style
+// override for a style that does not exist
+type ColorError struct {
+	Field   string // JSON path of the color
+	Value   string
+	Unknown bool // Field names no style
+}
+
+func (e ColorError) Error() string {
+	if e.Unknown {
+		return fmt.Sprintf("%s: unknown style", e.Field)
+	}
+	return fmt.Sprintf("%s: invalid color %q", e.Field, e.Value)
+}
+
+// Message describes the problem without naming the field
+func (e ColorError) Message() string {
+	if e.Unknown {
+		return "unknown style; valid names are " + strings.Join(StyleNames(), ", ")
+	}
+	return fmt.Sprintf("invalid color %q", e.Value)
+}
+
+// InvalidColors returns every color of the theme, including those of its
+// style overrides, that is set but invalid, and every override of an
+// unknown style
+func (t *Theme) InvalidColors() []ColorError {
+	var errs []ColorError
+	v := reflect.ValueOf(t.Colors)
+	typ := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		color := v.Field(i).String()
+		if color != "" && !validColor(color) {
+			field := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			errs = append(errs, ColorError{Field: "colors." + field, Value: color})
+		}
+	}
+	return append(errs, t.overrideErrors()...)
+}
+
+func validColor(c string) bool {
+	if strings.HasPrefix(c, "#") {
+		hex := c[1:]
+		if len(hex) != 3 && len(hex) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+	n, err := strconv.Atoi(c)
+	return err == nil && n >= 0 && n <= 255
+}
+
+// FillDefaults copies the colors t leaves empty from the built-in theme it
+// extends, or from the default theme
+func (t *Theme) FillDefaults() {
+	base, ok := builtinThemes[t.Extends]
+	if !ok {
+		base = builtinThemes[DefaultTheme]
+	}
+	v := reflect.ValueOf(&t.Colors).Elem()
+	b := reflect.ValueOf(base().Colors)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).String() == "" {
+			v.Field(i).SetString(b.Field(i).String())
+		}
+	}
+}
+
+// readTheme parses and validates a theme file; the file name, without
+// its extension, names a theme that does not name itself
+func readTheme(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme file: %w", err)
+	}
+
+	theme, err := ParseTheme(data, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return theme, nil
+}
+
+// ParseTheme decodes, validates and completes a theme given as JSON, as
+// LoadCustomTheme does for files; name is used when the JSON has none
+func ParseTheme(data []byte, name string) (*Theme, error) {
+	var theme Theme
+	if err := json.Unmarshal(data, &theme); err != nil {
+		return nil, fmt.Errorf("failed to parse theme: %w", err)
+	}
+	if theme.Name == "" {
+		theme.Name = name
+	}
+	if err := theme.Validate(); err != nil {
+		return nil, err
+	}
+	theme.FillDefaults()
+	return &theme, nil
+}
+
+// ReloadResult describes what a scan of the themes directory changed
+type ReloadResult struct {
+	Loaded  []string // themes added or updated
+	Removed []string // themes whose file went away
+	Errors  []error  // files that could not be loaded
+	// CurrentChanged is set when the active theme was updated or removed
+	CurrentChanged bool
+}
+
+// LoadThemesDir registers every *.json theme in dir, replacing the themes
+// previously loaded from it and dropping those whose file is gone. A
+// missing directory holds no themes. Scanning the directory again only
+// reports the themes that changed.
+func (tm *ThemeManager) LoadThemesDir(dir string) ReloadResult {
+	var res ReloadResult
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		res.Errors = append(res.Errors, fmt.Errorf("failed to read themes directory: %w", err))
+		return res
+	}
+
+	found := make(map[string]*Theme)
+	paths := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		theme, err := readTheme(path)
+		if err != nil {
+			res.Errors = append(res.Errors, err)
+			continue
+		}
+		if IsBuiltin(theme.Name) {
+			res.Errors = append(res.Errors, fmt.Errorf("%s: theme name %q is taken by a built-in theme", entry.Name(), theme.Name))
+			continue
+		}
+		if _, dup := found[theme.Name]; dup {
+			res.Errors = append(res.Errors, fmt.Errorf("%s: theme %q is defined twice", entry.Name(), theme.Name))
+			continue
+		}
+		found[theme.Name] = theme
+		paths[theme.Name] = path
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	current := ""
+	if tm.current != nil {
+		current = tm.current.Name
+	}
+	for name, theme := range found {
+		if old, ok := tm.themes[name]; ok && tm.custom[name] == paths[name] && reflect.DeepEqual(old, theme) {
+			continue
+		}
+		tm.themes[name] = theme
+		tm.custom[name] = paths[name]
+		res.Loaded = append(res.Loaded, name)
+		if name == current {
+			tm.apply(theme)
+			res.CurrentChanged = true
+		}
+	}
+	for name, path := range tm.custom {
+		if _, ok := found[name]; ok || filepath.Dir(path) != filepath.Clean(dir) {
+			continue
+		}
+		delete(tm.themes, name)
+		delete(tm.custom, name)
+		res.Removed = append(res.Removed, name)
+		if name == current {
+			tm.apply(tm.themes[DefaultTheme])
+			res.CurrentChanged = true
+		}
+	}
+
+	sort.Strings(res.Loaded)
+	sort.Strings(res.Removed)
+	return res
+}
+
+// Changed reports whether the scan loaded or removed anything
+func (r ReloadResult) Changed() bool {
+	return len(r.Loaded) > 0 || len(r.Removed) > 0
+}
+
+// Watch rescans dir every interval and sends a result whenever a theme
+// file was added, changed or removed. The channel is closed when ctx is
+// done.
+func (tm *ThemeManager) Watch(ctx context.Context, dir string, interval time.Duration) <-chan ReloadResult {
+	ch := make(chan ReloadResult)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := snapshot(dir)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			cur := snapshot(dir)
+			if cur == last {
+				continue
+			}
+			last = cur
+			res := tm.LoadThemesDir(dir)
+			if !res.Changed() && len(res.Errors) == 0 {
+				continue
+			}
+			select {
+			case ch <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// snapshot summarizes the names, sizes and modification times of the
+// theme files in dir; any change to a theme file changes the summary
+func snapshot(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s\x00%d\x00%d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
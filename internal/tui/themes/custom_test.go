@@ -0,0 +1,74 @@
+package themes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTheme(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	theme := &Theme{Name: "ok", Colors: ThemeColors{Primary: "#abc", Error: "#FF0000", Muted: "244"}}
+	if err := theme.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	for _, color := range []string{"red", "#12345", "#GGGGGG", "256", "-1"} {
+		theme := &Theme{Name: "bad", Colors: ThemeColors{Primary: color}}
+		if err := theme.Validate(); err == nil {
+			t.Errorf("color %q: expected an error", color)
+		}
+	}
+}
+
+func TestLoadThemesDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTheme(t, dir, "sunset.json", `{"colors": {"primary": "#FF6B6B"}}`)
+	writeTheme(t, dir, "broken.json", `{"name": "broken", "colors": {"primary": "orange"}}`)
+	writeTheme(t, dir, "clash.json", `{"name": "dracula"}`)
+	writeTheme(t, dir, "notes.txt", `not a theme`)
+
+	tm := NewThemeManager()
+	res := tm.LoadThemesDir(dir)
+	if len(res.Loaded) != 1 || res.Loaded[0] != "sunset" {
+		t.Fatalf("Loaded = %v, want [sunset]", res.Loaded)
+	}
+	if len(res.Errors) != 2 {
+		t.Fatalf("Errors = %v, want 2", res.Errors)
+	}
+
+	theme, ok := tm.GetTheme("sunset")
+	if !ok || !tm.IsCustom("sunset") {
+		t.Fatal("sunset not registered as a custom theme")
+	}
+	if theme.Colors.Primary != "#FF6B6B" || theme.Colors.Background != CatppuccinMocha().Colors.Background {
+		t.Errorf("colors = %+v, want primary from file and the rest from the default", theme.Colors)
+	}
+
+	// Unchanged files are not reported again
+	if res := tm.LoadThemesDir(dir); res.Changed() {
+		t.Errorf("second scan changed %v %v", res.Loaded, res.Removed)
+	}
+
+	// Editing the active theme restyles it; deleting it falls back
+	if err := tm.SetTheme("sunset"); err != nil {
+		t.Fatal(err)
+	}
+	writeTheme(t, dir, "sunset.json", `{"colors": {"primary": "#00FF00"}}`)
+	res = tm.LoadThemesDir(dir)
+	if !res.CurrentChanged || tm.CurrentTheme().Colors.Primary != "#00FF00" {
+		t.Errorf("edit: CurrentChanged=%v primary=%s", res.CurrentChanged, tm.CurrentTheme().Colors.Primary)
+	}
+
+	os.Remove(filepath.Join(dir, "sunset.json"))
+	res = tm.LoadThemesDir(dir)
+	if len(res.Removed) != 1 || !res.CurrentChanged || tm.CurrentTheme().Name != DefaultTheme {
+		t.Errorf("remove: Removed=%v current=%s", res.Removed, tm.CurrentTheme().Name)
+	}
+}
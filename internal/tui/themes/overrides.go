@@ -0,0 +1,129 @@
+package themes
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// StyleOverride changes part of one entry of Styles. Colors follow the
+// same rules as ThemeColors; unset fields keep the theme's value.
+type StyleOverride struct {
+	Foreground       string `json:"foreground,omitempty"`
+	Background       string `json:"background,omitempty"`
+	BorderForeground string `json:"border_foreground,omitempty"`
+	Bold             *bool  `json:"bold,omitempty"`
+	Italic           *bool  `json:"italic,omitempty"`
+	Underline        *bool  `json:"underline,omitempty"`
+}
+
+// colors returns the override's colors by JSON name
+func (o StyleOverride) colors() map[string]string {
+	return map[string]string{
+		"foreground":        o.Foreground,
+		"background":        o.Background,
+		"border_foreground": o.BorderForeground,
+	}
+}
+
+// apply layers the override on top of a style
+func (o StyleOverride) apply(s lipgloss.Style) lipgloss.Style {
+	if o.Foreground != "" {
+		s = s.Foreground(lipgloss.Color(o.Foreground))
+	}
+	if o.Background != "" {
+		s = s.Background(lipgloss.Color(o.Background))
+	}
+	if o.BorderForeground != "" {
+		s = s.BorderForeground(lipgloss.Color(o.BorderForeground))
+	}
+	if o.Bold != nil {
+		s = s.Bold(*o.Bold)
+	}
+	if o.Italic != nil {
+		s = s.Italic(*o.Italic)
+	}
+	if o.Underline != nil {
+		s = s.Underline(*o.Underline)
+	}
+	return s
+}
+
+// StyleNames returns the keys a theme's "styles" section accepts: the
+// Styles fields in snake case, such as "input_focused" or "badge"
+func StyleNames() []string {
+	typ := reflect.TypeOf(Styles{})
+	names := make([]string, typ.NumField())
+	for i := range names {
+		names[i] = snakeCase(typ.Field(i).Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// styleField returns the Styles field for a snake case name
+func styleField(styles *Styles, name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(styles).Elem()
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		if snakeCase(typ.Field(i).Name) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// overrideErrors returns the style overrides that name no style or set an
+// invalid color
+func (t *Theme) overrideErrors() []ColorError {
+	var errs []ColorError
+	known := make(map[string]bool)
+	for _, name := range StyleNames() {
+		known[name] = true
+	}
+
+	names := make([]string, 0, len(t.Styles))
+	for name := range t.Styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			errs = append(errs, ColorError{Field: "styles." + name, Value: name, Unknown: true})
+			continue
+		}
+		colors := t.Styles[name].colors()
+		for _, key := range []string{"foreground", "background", "border_foreground"} {
+			if c := colors[key]; c != "" && !validColor(c) {
+				errs = append(errs, ColorError{Field: "styles." + name + "." + key, Value: c})
+			}
+		}
+	}
+	return errs
+}
+
+// applyOverrides layers the theme's style overrides onto styles
+func (t *Theme) applyOverrides(styles *Styles) {
+	for name, o := range t.Styles {
+		if field, ok := styleField(styles, name); ok {
+			field.Set(reflect.ValueOf(o.apply(field.Interface().(lipgloss.Style))))
+		}
+	}
+}
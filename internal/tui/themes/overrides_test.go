@@ -0,0 +1,46 @@
+package themes
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestStyleOverrides(t *testing.T) {
+	theme, err := ParseTheme([]byte(`{
+		"extends": "dracula",
+		"styles": {
+			"input_focused": {"border_foreground": "#FF00FF"},
+			"badge": {"background": "#00FF00", "bold": true}
+		}
+	}`), "tweaked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.Colors.Primary != Dracula().Colors.Primary {
+		t.Errorf("primary = %s, want the dracula color", theme.Colors.Primary)
+	}
+
+	styles := NewThemeManager().buildStyles(theme)
+	if got := styles.InputFocused.GetBorderTopForeground(); got != lipgloss.Color("#FF00FF") {
+		t.Errorf("input border = %v", got)
+	}
+	if got := styles.Badge.GetBackground(); got != lipgloss.Color("#00FF00") || !styles.Badge.GetBold() {
+		t.Errorf("badge = %v bold=%v", got, styles.Badge.GetBold())
+	}
+	if got := styles.Input.GetBorderTopForeground(); got != lipgloss.Color(theme.Colors.Border) {
+		t.Errorf("unrelated style changed: %v", got)
+	}
+
+	bad := &Theme{Name: "bad", Styles: map[string]StyleOverride{
+		"nonexistent": {},
+		"header":      {Foreground: "pink"},
+	}}
+	errs := bad.InvalidColors()
+	if len(errs) != 2 || errs[0].Field != "styles.header.foreground" || !errs[1].Unknown {
+		t.Errorf("InvalidColors() = %+v", errs)
+	}
+	if _, err := ParseTheme([]byte(`{"extends": "nope"}`), "x"); err == nil {
+		t.Error("unknown base theme accepted")
+	}
+}
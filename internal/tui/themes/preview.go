@@ -0,0 +1,180 @@
+package themes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Minimum contrast ratios, after WCAG 2.x: body text needs 4.5:1, while
+// bold headings, borders and de-emphasized text get by with 3:1
+const (
+	ContrastText = 4.5
+	ContrastUI   = 3.0
+)
+
+// ContrastCheck is the contrast of one foreground/background pair
+type ContrastCheck struct {
+	Name       string  `json:"name"`
+	Foreground string  `json:"foreground"`
+	Background string  `json:"background"`
+	Ratio      float64 `json:"ratio"`
+	Minimum    float64 `json:"minimum"`
+	Pass       bool    `json:"pass"`
+}
+
+// ContrastRatio returns the WCAG contrast ratio of two hex colors, from 1
+// (identical) to 21 (black on white)
+func ContrastRatio(fg, bg string) (float64, error) {
+	l1, err := luminance(fg)
+	if err != nil {
+		return 0, err
+	}
+	l2, err := luminance(bg)
+	if err != nil {
+		return 0, err
+	}
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05), nil
+}
+
+// luminance is the relative luminance of a color; ANSI indices are
+// measured against the xterm palette
+func luminance(c string) (float64, error) {
+	r, g, b, ok := parseHex(c)
+	if !ok {
+		n, err := strconv.Atoi(c)
+		if err != nil || n < 0 || n > 255 {
+			return 0, fmt.Errorf("invalid color %q", c)
+		}
+		r, g, b = ansi256RGB(n)
+	}
+	channel := func(v int) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(r) + 0.7152*channel(g) + 0.0722*channel(b), nil
+}
+
+// CheckContrast measures every foreground/background pair the TUI draws
+// with this theme
+func (t *Theme) CheckContrast() []ContrastCheck {
+	c := t.Colors
+	pairs := []struct {
+		name    string
+		fg, bg  string
+		minimum float64
+	}{
+		{"foreground", c.Foreground, c.Background, ContrastText},
+		{"user_message", c.UserMessage, c.Background, ContrastText},
+		{"assistant_message", c.AssistantMessage, c.Background, ContrastText},
+		{"system_message", c.SystemMessage, c.Background, ContrastText},
+		{"error", c.Error, c.Background, ContrastText},
+		{"warning", c.Warning, c.Background, ContrastText},
+		{"success", c.Success, c.Background, ContrastText},
+		{"info", c.Info, c.Background, ContrastText},
+		{"selection", c.Foreground, c.Selection, ContrastText},
+		{"header", c.Background, c.Primary, ContrastText},
+		{"button", c.Foreground, c.Secondary, ContrastUI},
+		{"badge", c.Background, c.Accent, ContrastUI},
+		{"primary", c.Primary, c.Background, ContrastUI},
+		{"muted", c.Muted, c.Background, ContrastUI},
+		{"border_focused", c.BorderFocused, c.Background, ContrastUI},
+		{"comment", c.Comment, c.Background, ContrastUI},
+		{"keyword", c.Keyword, c.Background, ContrastText},
+		{"string", c.String, c.Background, ContrastText},
+		{"number", c.Number, c.Background, ContrastText},
+		{"function", c.Function, c.Background, ContrastText},
+	}
+
+	checks := make([]ContrastCheck, 0, len(pairs))
+	for _, p := range pairs {
+		ratio, err := ContrastRatio(p.fg, p.bg)
+		if err != nil {
+			continue
+		}
+		checks = append(checks, ContrastCheck{
+			Name:       p.name,
+			Foreground: p.fg,
+			Background: p.bg,
+			Ratio:      math.Round(ratio*100) / 100,
+			Minimum:    p.minimum,
+			Pass:       ratio >= p.minimum,
+		})
+	}
+	return checks
+}
+
+// ContrastFailures returns the checks that fall below their minimum
+func ContrastFailures(checks []ContrastCheck) []ContrastCheck {
+	var failed []ContrastCheck
+	for _, c := range checks {
+		if !c.Pass {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Preview renders a sample screen with every style of the current theme
+func (tm *ThemeManager) Preview() string {
+	tm.mu.RLock()
+	theme := tm.current
+	tm.mu.RUnlock()
+	return tm.PreviewTheme(theme)
+}
+
+// PreviewTheme renders a sample screen for any theme, in the colors the
+// terminal profile allows, followed by its contrast problems
+func (tm *ThemeManager) PreviewTheme(theme *Theme) string {
+	degraded := Degrade(theme, tm.Profile())
+	s := tm.buildStyles(degraded)
+	fg := func(color string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+	}
+	c := degraded.Colors
+
+	var b strings.Builder
+	b.WriteString(s.Header.Render(theme.Name) + " " + s.Subtitle.Render(theme.Description) + "\n\n")
+	b.WriteString(s.Tab.Render("Chat") + s.TabActive.Render("Preview") + s.Tab.Render("Settings") + "\n\n")
+
+	chat := strings.Join([]string{
+		s.PanelTitle.Render("Conversation"),
+		s.UserMessage.Render("You: ") + s.Body.Render("Plan a REST API for a todo app"),
+		s.AssistantMessage.Render("Agent: ") + s.Body.Render("Here is a SpecKit plan with three phases."),
+		s.SystemMessage.Render("System: ") + s.Muted.Render("[speckit] spec.md written"),
+		s.ErrorMessage.Render("Error: ") + s.Body.Render("provider timed out"),
+	}, "\n")
+	b.WriteString(s.PanelFocused.Render(chat) + "\n")
+	b.WriteString(s.Panel.Render(s.Title.Render("Unfocused panel")+"\n"+s.Muted.Render("Secondary content")) + "\n")
+
+	b.WriteString(s.InputFocused.Render("Describe your project idea...") + "\n")
+	b.WriteString(s.Input.Render(s.Placeholder.Render("Inactive input")) + "\n\n")
+
+	b.WriteString(s.ButtonActive.Render("Run") + " " + s.Button.Render("Cancel") + " " + s.Badge.Render("3 tasks") + "\n")
+	b.WriteString(s.StatusOnline.Render("● online") + "  " + s.StatusBusy.Render("● busy") + "  " +
+		s.StatusOffline.Render("● offline") + "  " + s.Progress.Render("━━━━━━━╸   70%") + "\n\n")
+
+	b.WriteString(s.Code.Render("go run ./cmd/skagent") + "\n")
+	b.WriteString(fg(c.Keyword).Render("func ") + fg(c.Function).Render("main") + s.Body.Render("() { ") +
+		fg(c.Function).Render("fmt.Println") + s.Body.Render("(") + fg(c.String).Render(`"hi"`) + s.Body.Render(", ") +
+		fg(c.Number).Render("42") + s.Body.Render(") } ") + fg(c.Comment).Render("// syntax colors") + "\n")
+
+	if failed := ContrastFailures(theme.CheckContrast()); len(failed) > 0 {
+		b.WriteString("\n" + s.StatusBusy.Render("Low contrast:") + "\n")
+		for _, f := range failed {
+			b.WriteString(fmt.Sprintf("  %-18s %5.2f:1 (needs %.1f:1)\n", f.Name, f.Ratio, f.Minimum))
+		}
+	} else {
+		b.WriteString("\n" + s.StatusOnline.Render("All contrast checks pass") + "\n")
+	}
+	return b.String()
+}
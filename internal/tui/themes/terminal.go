@@ -0,0 +1,235 @@
+package themes
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ColorProfile is the range of colors a terminal can display
+type ColorProfile int
+
+const (
+	// Monochrome terminals get no colors, only bold, italics and borders
+	Monochrome ColorProfile = iota
+	// ANSI16 is the basic 16-color palette
+	ANSI16
+	// ANSI256 is the xterm 256-color palette
+	ANSI256
+	// TrueColor is 24-bit color
+	TrueColor
+)
+
+// ColorsEnv overrides the detected color profile: "truecolor", "256",
+// "16" or "none"
+const ColorsEnv = "SKAGENT_COLORS"
+
+func (p ColorProfile) String() string {
+	switch p {
+	case TrueColor:
+		return "truecolor"
+	case ANSI256:
+		return "256"
+	case ANSI16:
+		return "16"
+	default:
+		return "none"
+	}
+}
+
+// ParseColorProfile parses the values accepted by SKAGENT_COLORS
+func ParseColorProfile(s string) (ColorProfile, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "truecolor", "24bit":
+		return TrueColor, true
+	case "256", "ansi256":
+		return ANSI256, true
+	case "16", "ansi", "ansi16":
+		return ANSI16, true
+	case "none", "mono", "monochrome":
+		return Monochrome, true
+	}
+	return Monochrome, false
+}
+
+// DetectProfile works out the color profile from the environment. SSH
+// sessions rarely forward COLORTERM, so they fall back to what TERM
+// advertises rather than assuming 24-bit color.
+func DetectProfile() ColorProfile {
+	return detectProfile(os.Getenv)
+}
+
+func detectProfile(getenv func(string) string) ColorProfile {
+	if p, ok := ParseColorProfile(getenv(ColorsEnv)); ok {
+		return p
+	}
+	if getenv("NO_COLOR") != "" {
+		return Monochrome
+	}
+
+	term := strings.ToLower(getenv("TERM"))
+	switch colorterm := strings.ToLower(getenv("COLORTERM")); {
+	case term == "dumb":
+		return Monochrome
+	case colorterm == "truecolor" || colorterm == "24bit":
+		return TrueColor
+	case strings.Contains(term, "truecolor") || strings.Contains(term, "direct"):
+		return TrueColor
+	case strings.Contains(term, "256color"):
+		return ANSI256
+	case term == "" && getenv("WT_SESSION") != "":
+		// Windows Terminal does not set TERM
+		return TrueColor
+	case term == "":
+		return Monochrome
+	}
+	return ANSI16
+}
+
+// HasDarkBackground reports whether the terminal background is dark. The
+// COLORFGBG variable set by many terminals is trusted first; otherwise the
+// terminal is queried, which assumes dark when it cannot tell.
+func HasDarkBackground() bool {
+	if dark, ok := backgroundFromEnv(os.Getenv("COLORFGBG")); ok {
+		return dark
+	}
+	return lipgloss.HasDarkBackground()
+}
+
+// backgroundFromEnv parses COLORFGBG ("fg;bg" or "fg;extra;bg"); ANSI
+// colors 7 and 9-15 are light backgrounds
+func backgroundFromEnv(colorfgbg string) (dark, ok bool) {
+	parts := strings.Split(colorfgbg, ";")
+	if len(parts) < 2 {
+		return false, false
+	}
+	bg, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || bg < 0 || bg > 15 {
+		return false, false
+	}
+	return !(bg == 7 || bg >= 9), true
+}
+
+// DefaultThemeFor returns the built-in theme suited to the background
+func DefaultThemeFor(dark bool) string {
+	if dark {
+		return DefaultTheme
+	}
+	return "catppuccin-latte"
+}
+
+// Degrade returns a copy of t with every color mapped to the nearest one
+// the profile can show
+func Degrade(t *Theme, p ColorProfile) *Theme {
+	out := *t
+	if p == TrueColor {
+		return &out
+	}
+	v := reflect.ValueOf(&out.Colors).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		v.Field(i).SetString(degradeColor(v.Field(i).String(), p))
+	}
+	if t.Styles != nil {
+		out.Styles = make(map[string]StyleOverride, len(t.Styles))
+		for name, o := range t.Styles {
+			o.Foreground = degradeColor(o.Foreground, p)
+			o.Background = degradeColor(o.Background, p)
+			o.BorderForeground = degradeColor(o.BorderForeground, p)
+			out.Styles[name] = o
+		}
+	}
+	return &out
+}
+
+// degradeColor maps one theme color to the profile: an xterm-256 index, a
+// basic ANSI index, or no color at all
+func degradeColor(c string, p ColorProfile) string {
+	if c == "" || p == TrueColor {
+		return c
+	}
+	if p == Monochrome {
+		return ""
+	}
+
+	r, g, b, ok := parseHex(c)
+	if !ok {
+		// Already an ANSI index
+		n, err := strconv.Atoi(c)
+		if err != nil {
+			return c
+		}
+		if p == ANSI16 && n > 15 {
+			r, g, b = ansi256RGB(n)
+			return strconv.Itoa(nearest(r, g, b, 0, 16))
+		}
+		return c
+	}
+	if p == ANSI16 {
+		return strconv.Itoa(nearest(r, g, b, 0, 16))
+	}
+	// The first 16 entries vary between terminals; only the cube and the
+	// gray ramp are predictable
+	return strconv.Itoa(nearest(r, g, b, 16, 256))
+}
+
+func parseHex(c string) (r, g, b int, ok bool) {
+	if !strings.HasPrefix(c, "#") {
+		return 0, 0, 0, false
+	}
+	hex := c[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, false
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(n >> 16), int(n >> 8 & 0xFF), int(n & 0xFF), true
+}
+
+// ansi16 is the xterm default palette
+var ansi16 = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// ansi256RGB returns the color of an xterm-256 palette entry
+func ansi256RGB(n int) (r, g, b int) {
+	switch {
+	case n < 16:
+		c := ansi16[n]
+		return c[0], c[1], c[2]
+	case n < 232:
+		n -= 16
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	default:
+		gray := 8 + (n-232)*10
+		return gray, gray, gray
+	}
+}
+
+// nearest returns the palette index in [from, to) closest to r, g, b,
+// using the "redmean" approximation of perceived distance
+func nearest(r, g, b, from, to int) int {
+	best, bestDist := from, -1
+	for i := from; i < to; i++ {
+		pr, pg, pb := ansi256RGB(i)
+		rm := (r + pr) / 2
+		dr, dg, db := r-pr, g-pg, b-pb
+		dist := (512+rm)*dr*dr>>8 + 4*dg*dg + (767-rm)*db*db>>8
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
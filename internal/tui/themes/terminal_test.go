@@ -0,0 +1,81 @@
+package themes
+
+import "testing"
+
+func TestDetectProfile(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want ColorProfile
+	}{
+		{map[string]string{"TERM": "xterm-256color", "COLORTERM": "truecolor"}, TrueColor},
+		{map[string]string{"TERM": "xterm-256color"}, ANSI256},
+		{map[string]string{"TERM": "xterm"}, ANSI16},
+		{map[string]string{"TERM": "dumb", "COLORTERM": "truecolor"}, Monochrome},
+		{map[string]string{"TERM": "xterm-256color", "NO_COLOR": "1"}, Monochrome},
+		{map[string]string{"TERM": "xterm", ColorsEnv: "256"}, ANSI256},
+		{map[string]string{}, Monochrome},
+	}
+	for _, tt := range tests {
+		got := detectProfile(func(k string) string { return tt.env[k] })
+		if got != tt.want {
+			t.Errorf("detectProfile(%v) = %s, want %s", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestBackgroundFromEnv(t *testing.T) {
+	for value, want := range map[string]bool{"15;0": true, "0;15": false, "0;default;7": false, "7;8": true} {
+		dark, ok := backgroundFromEnv(value)
+		if !ok || dark != want {
+			t.Errorf("backgroundFromEnv(%q) = %v, %v; want %v", value, dark, ok, want)
+		}
+	}
+	if _, ok := backgroundFromEnv("garbage"); ok {
+		t.Error("backgroundFromEnv accepted garbage")
+	}
+}
+
+func TestDegrade(t *testing.T) {
+	theme := &Theme{Name: "t", Colors: ThemeColors{Primary: "#FF0000", Muted: "#808080", Accent: "#fff", Info: "33"}}
+
+	c := Degrade(theme, ANSI256).Colors
+	if c.Primary != "196" || c.Muted != "244" || c.Accent != "231" || c.Info != "33" {
+		t.Errorf("256 colors = %+v", c)
+	}
+	c = Degrade(theme, ANSI16).Colors
+	if c.Primary != "9" || c.Accent != "15" || c.Info != "12" {
+		t.Errorf("16 colors = %+v", c)
+	}
+	if c := Degrade(theme, Monochrome).Colors; c.Primary != "" || c.Info != "" {
+		t.Errorf("monochrome colors = %+v", c)
+	}
+	if theme.Colors.Primary != "#FF0000" {
+		t.Error("Degrade modified the original theme")
+	}
+}
+
+func TestContrast(t *testing.T) {
+	ratio, err := ContrastRatio("#000", "#FFFFFF")
+	if err != nil || ratio < 20.9 || ratio > 21 {
+		t.Fatalf("black on white = %v, %v; want 21", ratio, err)
+	}
+	if ratio, _ := ContrastRatio("#777777", "#777777"); ratio != 1 {
+		t.Errorf("identical colors = %v, want 1", ratio)
+	}
+
+	failing := func(theme *Theme) map[string]bool {
+		names := make(map[string]bool)
+		for _, c := range ContrastFailures(theme.CheckContrast()) {
+			names[c.Name] = true
+		}
+		return names
+	}
+	theme := CatppuccinMocha()
+	if failing(theme)["muted"] {
+		t.Fatal("stock muted color fails contrast")
+	}
+	theme.Colors.Muted = "#2A2A3A"
+	if !failing(theme)["muted"] {
+		t.Error("dark muted color on a dark background passes contrast")
+	}
+}
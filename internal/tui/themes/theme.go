@@ -1,10 +1,9 @@
 package themes
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
+	"sync"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -15,6 +14,10 @@ type Theme struct {
 	Author      string      `json:"author,omitempty"`
 	Description string      `json:"description,omitempty"`
 	Colors      ThemeColors `json:"colors"`
+	// Extends names the built-in theme that supplies the colors left out
+	Extends string `json:"extends,omitempty"`
+	// Styles overrides individual entries of Styles by snake case name
+	Styles map[string]StyleOverride `json:"styles,omitempty"`
 }
 
 // ThemeColors contains all color definitions
@@ -99,9 +102,12 @@ type Styles struct {
 
 // ThemeManager handles theme loading and switching
 type ThemeManager struct {
+	mu         sync.RWMutex
 	current    *Theme
 	styles     *Styles
 	themes     map[string]*Theme
+	custom     map[string]string // custom theme name to its file
+	profile    ColorProfile
 	themesPath string
 }
 
@@ -109,86 +115,146 @@ type ThemeManager struct {
 func NewThemeManager() *ThemeManager {
 	tm := &ThemeManager{
 		themes: make(map[string]*Theme),
+		custom:  make(map[string]string),
+		profile: TrueColor,
 	}
 	
 	// Register built-in themes
 	tm.registerBuiltinThemes()
 	
 	// Set default theme
-	tm.SetTheme("catppuccin-mocha")
+	tm.SetTheme(DefaultTheme)
 	
 	return tm
 }
 
+// builtinThemes maps the built-in theme names to their constructors
+var builtinThemes = map[string]func() *Theme{
+	"catppuccin-mocha": CatppuccinMocha,
+	"catppuccin-latte": CatppuccinLatte,
+	"dracula":          Dracula,
+	"nord":             Nord,
+	"tokyo-night":      TokyoNight,
+	"gruvbox-dark":     GruvboxDark,
+	"one-dark":         OneDark,
+	"solarized-dark":   SolarizedDark,
+	"monokai":          Monokai,
+	"github-dark":      GitHubDark,
+}
+
+// IsBuiltin reports whether name is a built-in theme
+func IsBuiltin(name string) bool {
+	_, ok := builtinThemes[name]
+	return ok
+}
+
 // registerBuiltinThemes adds all built-in themes
 func (tm *ThemeManager) registerBuiltinThemes() {
-	tm.themes["catppuccin-mocha"] = CatppuccinMocha()
-	tm.themes["catppuccin-latte"] = CatppuccinLatte()
-	tm.themes["dracula"] = Dracula()
-	tm.themes["nord"] = Nord()
-	tm.themes["tokyo-night"] = TokyoNight()
-	tm.themes["gruvbox-dark"] = GruvboxDark()
-	tm.themes["one-dark"] = OneDark()
-	tm.themes["solarized-dark"] = SolarizedDark()
-	tm.themes["monokai"] = Monokai()
-	tm.themes["github-dark"] = GitHubDark()
+	for name, theme := range builtinThemes {
+		tm.themes[name] = theme()
+	}
 }
 
-// ListThemes returns all available theme names
+// ListThemes returns all available theme names, sorted
 func (tm *ThemeManager) ListThemes() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	names := make([]string, 0, len(tm.themes))
 	for name := range tm.themes {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
 
+// IsCustom reports whether a theme was loaded from a file
+func (tm *ThemeManager) IsCustom(name string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	_, ok := tm.custom[name]
+	return ok
+}
+
 // GetTheme returns a theme by name
 func (tm *ThemeManager) GetTheme(name string) (*Theme, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	theme, ok := tm.themes[name]
 	return theme, ok
 }
 
 // CurrentTheme returns the current theme
 func (tm *ThemeManager) CurrentTheme() *Theme {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.current
 }
 
 // Styles returns the current styles
 func (tm *ThemeManager) Styles() *Styles {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.styles
 }
 
 // SetTheme switches to a new theme
 func (tm *ThemeManager) SetTheme(name string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	theme, ok := tm.themes[name]
 	if !ok {
 		return fmt.Errorf("theme not found: %s", name)
 	}
 	
-	tm.current = theme
-	tm.styles = tm.buildStyles(theme)
+	tm.apply(theme)
 	return nil
 }
 
-// LoadCustomTheme loads a theme from a JSON file
+// apply makes theme current; the caller holds tm.mu
+func (tm *ThemeManager) apply(theme *Theme) {
+	tm.current = theme
+	tm.styles = tm.buildStyles(Degrade(theme, tm.profile))
+}
+
+// SetProfile sets the colors the terminal can show and rebuilds the
+// styles; themes are mapped down to the nearest colors of the profile
+func (tm *ThemeManager) SetProfile(p ColorProfile) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.profile = p
+	if tm.current != nil {
+		tm.apply(tm.current)
+	}
+}
+
+// Profile returns the color profile styles are built for
+func (tm *ThemeManager) Profile() ColorProfile {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.profile
+}
+
+// LoadCustomTheme loads a theme from a JSON file. Colors are validated and
+// the ones the file leaves out are taken from the default theme.
 func (tm *ThemeManager) LoadCustomTheme(path string) error {
-	data, err := os.ReadFile(path)
+	theme, err := readTheme(path)
 	if err != nil {
-		return fmt.Errorf("failed to read theme file: %w", err)
+		return err
 	}
-	
-	var theme Theme
-	if err := json.Unmarshal(data, &theme); err != nil {
-		return fmt.Errorf("failed to parse theme: %w", err)
+	if IsBuiltin(theme.Name) {
+		return fmt.Errorf("theme name %q is taken by a built-in theme", theme.Name)
 	}
-	
-	// Use filename as theme name if not specified
-	if theme.Name == "" {
-		theme.Name = filepath.Base(path)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.themes[theme.Name] = theme
+	tm.custom[theme.Name] = path
+	if tm.current != nil && tm.current.Name == theme.Name {
+		tm.apply(theme)
 	}
-	
-	tm.themes[theme.Name] = &theme
 	return nil
 }
 
@@ -196,7 +262,7 @@ func (tm *ThemeManager) LoadCustomTheme(path string) error {
 func (tm *ThemeManager) buildStyles(t *Theme) *Styles {
 	c := t.Colors
 	
-	return &Styles{
+	styles := &Styles{
 		// App
 		App: lipgloss.NewStyle().
 			Background(lipgloss.Color(c.Background)).
@@ -323,4 +389,7 @@ func (tm *ThemeManager) buildStyles(t *Theme) *Styles {
 			Foreground(lipgloss.Color(c.Accent)).
 			Padding(0, 1),
 	}
+
+	t.applyOverrides(styles)
+	return styles
 }
@@ -0,0 +1,232 @@
+package validate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema checks the arguments of a call against a JSON Schema object, such
+// as the inputSchema of an MCP tool, and returns the invalid ones sorted by
+// name, or nil. It understands the keywords tools use: type, properties,
+// required, additionalProperties, enum, minimum, maximum, minLength,
+// maxLength, items, minItems and maxItems; others are ignored. Nested
+// fields are named by path, as in "options.depth" or "files[2]".
+//
+// Schemas may be written in Go, with []string and int values, or decoded
+// from JSON.
+func Schema(schema map[string]interface{}, args map[string]interface{}) Errors {
+	var errs Errors
+	checkObject(schema, args, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// checkObject checks the properties of an object
+func checkObject(schema map[string]interface{}, obj map[string]interface{}, path string, errs *Errors) {
+	props, _ := schema["properties"].(map[string]interface{})
+	for _, name := range stringList(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, FieldError{Field: join(path, name), Message: "is required"})
+		}
+	}
+	closed := schema["additionalProperties"] == false
+	for name, value := range obj {
+		sub, ok := props[name].(map[string]interface{})
+		if !ok {
+			if closed {
+				*errs = append(*errs, FieldError{Field: join(path, name), Message: "is not a known parameter"})
+			}
+			continue
+		}
+		checkValue(sub, value, join(path, name), errs)
+	}
+}
+
+// checkValue checks one value, reporting the first rule it breaks
+func checkValue(schema map[string]interface{}, value interface{}, path string, errs *Errors) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if types := stringList(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("must be %s", describeTypes(types))
+			return
+		}
+	}
+	if enum, ok := schema["enum"]; ok {
+		options := anyList(enum)
+		found := false
+		for _, o := range options {
+			if fmt.Sprint(o) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			words := make([]string, len(options))
+			for i, o := range options {
+				words[i] = fmt.Sprint(o)
+			}
+			fail("must be one of %s", strings.Join(words, ", "))
+			return
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		n := float64(len([]rune(v)))
+		if min, ok := number(schema["minLength"]); ok && n < min {
+			fail("must be at least %s characters", format(min))
+		} else if max, ok := number(schema["maxLength"]); ok && n > max {
+			fail("must be at most %s characters", format(max))
+		}
+	case []interface{}:
+		n := float64(len(v))
+		if min, ok := number(schema["minItems"]); ok && n < min {
+			fail("must be at least %s items", format(min))
+			return
+		} else if max, ok := number(schema["maxItems"]); ok && n > max {
+			fail("must be at most %s items", format(max))
+			return
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				checkValue(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case map[string]interface{}:
+		checkObject(schema, v, path, errs)
+	default:
+		if n, ok := number(value); ok {
+			if min, ok := number(schema["minimum"]); ok && n < min {
+				fail("must be at least %s", format(min))
+			} else if max, ok := number(schema["maximum"]); ok && n > max {
+				fail("must be at most %s", format(max))
+			}
+		}
+	}
+}
+
+// hasType reports whether value is of the JSON Schema type t
+func hasType(value interface{}, t string) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := number(value)
+		return ok
+	case "integer":
+		n, ok := number(value)
+		return ok && n == math.Trunc(n)
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "null":
+		return value == nil
+	}
+	// Unknown types accept anything
+	return true
+}
+
+// describeTypes names types for a message, as in "a string or null"
+func describeTypes(types []string) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		switch t {
+		case "integer", "object", "array":
+			names[i] = "an " + t
+		case "null":
+			names[i] = "null"
+		default:
+			names[i] = "a " + t
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// number returns v as a float64 if it is a Go or JSON number
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// format writes a limit without a needless fraction
+func format(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// stringList reads a keyword that is a string or a list of them
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case string:
+		return []string{list}
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// anyList reads a keyword that is a list of values
+func anyList(v interface{}) []interface{} {
+	switch list := v.(type) {
+	case []interface{}:
+		return list
+	case []string:
+		out := make([]interface{}, len(list))
+		for i, s := range list {
+			out[i] = s
+		}
+		return out
+	case []int:
+		out := make([]interface{}, len(list))
+		for i, n := range list {
+			out[i] = n
+		}
+		return out
+	}
+	return nil
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,197 @@
+// Package validate checks request structs against the rules in their
+// `validate` struct tags, so that the REST API and headless commands
+// reject the same inputs with the same messages.
+//
+// A tag is a comma-separated list of rules:
+//
+//	required     the field must not be empty (blank strings are empty)
+//	oneof=a b c  the value must be one of the space-separated words
+//	min=N        numbers must be at least N, strings and lists at least N long
+//	max=N        numbers must be at most N, strings and lists at most N long
+//	url          the value must be an absolute http or https URL
+//
+// Rules other than required are skipped for empty values. Fields are
+// reported by their JSON name.
+//
+// Schema checks arguments described by a JSON Schema instead, such as
+// those of MCP tools, and reports them the same way.
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FieldError reports one invalid field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Errors lists every invalid field of a struct
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "invalid " + strings.Join(msgs, "; ")
+}
+
+// rule is one parsed rule of a tag
+type rule struct {
+	name string
+	arg  string
+}
+
+// field is a struct field with rules
+type field struct {
+	index []int
+	name  string
+	rules []rule
+}
+
+// fields caches the parsed tags by struct type
+var fields sync.Map
+
+// Struct checks v, a struct or a pointer to one, and returns its invalid
+// fields in declaration order, or nil. It panics on a malformed tag, which
+// is a programming error.
+func Struct(v interface{}) Errors {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: %T is not a struct", v))
+	}
+	var errs Errors
+	for _, f := range typeFields(rv.Type()) {
+		value := rv.FieldByIndex(f.index)
+		for _, r := range f.rules {
+			if msg := check(r, value); msg != "" {
+				errs = append(errs, FieldError{Field: f.name, Message: msg})
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// typeFields returns the fields of t that have rules
+func typeFields(t reflect.Type) []field {
+	if cached, ok := fields.Load(t); ok {
+		return cached.([]field)
+	}
+	var list []field
+	for _, sf := range reflect.VisibleFields(t) {
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok || tag == "" || !sf.IsExported() {
+			continue
+		}
+		f := field{index: sf.Index, name: jsonName(sf)}
+		for _, part := range strings.Split(tag, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch name {
+			case "required", "url":
+			case "oneof":
+				if arg == "" {
+					panic(fmt.Sprintf("validate: %s.%s: oneof needs values", t, sf.Name))
+				}
+			case "min", "max":
+				if _, err := strconv.ParseFloat(arg, 64); err != nil {
+					panic(fmt.Sprintf("validate: %s.%s: %s needs a number", t, sf.Name, name))
+				}
+			default:
+				panic(fmt.Sprintf("validate: %s.%s: unknown rule %q", t, sf.Name, name))
+			}
+			f.rules = append(f.rules, rule{name: name, arg: arg})
+		}
+		list = append(list, f)
+	}
+	fields.Store(t, list)
+	return list
+}
+
+// jsonName is the name a field has in JSON
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+// check applies r to v and returns why it fails, or ""
+func check(r rule, v reflect.Value) string {
+	if empty(v) {
+		if r.name == "required" {
+			return "is required"
+		}
+		return ""
+	}
+	v = reflect.Indirect(v)
+	switch r.name {
+	case "oneof":
+		words := strings.Fields(r.arg)
+		s := fmt.Sprint(v.Interface())
+		for _, w := range words {
+			if s == w {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(words, ", ")
+	case "min", "max":
+		limit, _ := strconv.ParseFloat(r.arg, 64)
+		n, what := measure(v)
+		if r.name == "min" && n < limit {
+			return fmt.Sprintf("must be at least %s%s", r.arg, what)
+		}
+		if r.name == "max" && n > limit {
+			return fmt.Sprintf("must be at most %s%s", r.arg, what)
+		}
+	case "url":
+		u, err := url.Parse(v.String())
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be an absolute http or https URL"
+		}
+	}
+	return ""
+}
+
+// empty reports whether v is the zero value, counting blank strings
+func empty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// measure returns the number min and max compare, and the unit to name in
+// messages
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	panic("validate: min and max do not apply to " + v.Kind().String())
+}
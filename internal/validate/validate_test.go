@@ -0,0 +1,116 @@
+package validate
+
+import (
+	"testing"
+	"time"
+)
+
+type request struct {
+	Name    string        `json:"name" validate:"required,max=5"`
+	Kind    string        `json:"kind,omitempty" validate:"oneof=a b"`
+	Level   int           `json:"level" validate:"min=0,max=3"`
+	Hook    string        `json:"hook" validate:"url"`
+	Tags    []string      `json:"tags" validate:"max=2"`
+	Timeout time.Duration `json:"timeout" validate:"min=0"`
+	Free    string        `json:"free"`
+}
+
+func TestStruct(t *testing.T) {
+	if errs := Struct(&request{Name: "ok", Kind: "b", Level: 3, Hook: "https://example.com/x"}); errs != nil {
+		t.Fatalf("valid request: %v", errs)
+	}
+
+	errs := Struct(request{Name: "  ", Kind: "c", Level: 4, Hook: "example.com", Tags: []string{"x", "y", "z"}, Timeout: -time.Second})
+	want := []FieldError{
+		{"name", "is required"},
+		{"kind", "must be one of a, b"},
+		{"level", "must be at most 3"},
+		{"hook", "must be an absolute http or https URL"},
+		{"tags", "must be at most 2 items"},
+		{"timeout", "must be at least 0"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("errors: %v", errs)
+	}
+	for i, w := range want {
+		if errs[i] != w {
+			t.Errorf("error %d = %+v, want %+v", i, errs[i], w)
+		}
+	}
+	if got := Struct(request{Name: "toolong"}); len(got) != 1 || got[0].Message != "must be at most 5 characters" {
+		t.Errorf("long name: %v", got)
+	}
+}
+
+func TestStructPanicsOnBadTag(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic for an unknown rule")
+		}
+	}()
+	Struct(struct {
+		X string `validate:"email"`
+	}{})
+}
+
+func TestSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"agent_id": map[string]interface{}{"type": "string", "minLength": 1},
+			"status":   map[string]interface{}{"type": "string", "enum": []string{"active", "idle"}},
+			"priority": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10},
+			"wait":     map[string]interface{}{"type": "boolean"},
+			"files": map[string]interface{}{
+				"type":     "array",
+				"maxItems": 3,
+				"items":    map[string]interface{}{"type": "string"},
+			},
+			"options": map[string]interface{}{
+				"type":                 "object",
+				"properties":           map[string]interface{}{"depth": map[string]interface{}{"type": "number"}},
+				"additionalProperties": false,
+			},
+		},
+		"required": []string{"agent_id"},
+	}
+
+	valid := map[string]interface{}{"agent_id": "a1", "status": "idle", "priority": float64(3), "wait": true,
+		"files": []interface{}{"a.go"}, "options": map[string]interface{}{"depth": 1.5}, "extra": "kept"}
+	if errs := Schema(schema, valid); errs != nil {
+		t.Fatalf("valid arguments: %v", errs)
+	}
+
+	errs := Schema(schema, map[string]interface{}{
+		"status":   "busy",
+		"priority": 2.5,
+		"wait":     "yes",
+		"files":    []interface{}{"a.go", 7},
+		"options":  map[string]interface{}{"depth": 1, "mode": "fast"},
+	})
+	want := map[string]string{
+		"agent_id":     "is required",
+		"status":       "must be one of active, idle",
+		"priority":     "must be an integer",
+		"wait":         "must be a boolean",
+		"files[1]":     "must be a string",
+		"options.mode": "is not a known parameter",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %v", errs)
+	}
+	for _, fe := range errs {
+		if want[fe.Field] != fe.Message {
+			t.Errorf("%s: %q, want %q", fe.Field, fe.Message, want[fe.Field])
+		}
+	}
+
+	// Schemas decoded from JSON work the same
+	decoded := map[string]interface{}{
+		"properties": map[string]interface{}{"priority": map[string]interface{}{"type": "integer", "maximum": float64(10)}},
+		"required":   []interface{}{"priority"},
+	}
+	if errs := Schema(decoded, map[string]interface{}{"priority": float64(11)}); len(errs) != 1 || errs[0].Message != "must be at most 10" {
+		t.Errorf("decoded schema: %v", errs)
+	}
+}
@@ -0,0 +1,232 @@
+package webhooks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/google/uuid"
+)
+
+// CallbackPayload is posted to a task's callback URL when it finishes
+type CallbackPayload struct {
+	ID          string             `json:"id"`
+	Event       string             `json:"event"`
+	TaskID      string             `json:"task_id"`
+	Status      agents.TaskStatus  `json:"status"`
+	AgentID     string             `json:"agent_id,omitempty"`
+	Result      *agents.TaskResult `json:"result,omitempty"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty"`
+}
+
+// DeadLetter is a callback that could not be delivered. It keeps the
+// payload so that the callback can be replayed by hand.
+type DeadLetter struct {
+	TaskID     string          `json:"task_id"`
+	URL        string          `json:"url"`
+	Attempts   int             `json:"attempts"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Error      string          `json:"error"`
+	Payload    json.RawMessage `json:"payload"`
+	Time       time.Time       `json:"time"`
+}
+
+// CallbackDispatcher posts the result of every finished task that has a
+// callback URL. Callbacks that still fail after the sender's retries are
+// appended to a dead-letter log.
+type CallbackDispatcher struct {
+	secretMu   sync.RWMutex
+	secret     string
+	deadLetter string
+	sender     *Sender
+	logger     *log.Logger
+
+	mu       sync.Mutex // serializes dead-letter writes
+	inflight sync.WaitGroup
+	unsigned sync.Once
+}
+
+// NewCallbackDispatcher signs callbacks with secret, unless it is empty,
+// and writes dead letters as JSON lines to deadLetterPath, unless it is
+// empty. A nil sender uses NewSender.
+func NewCallbackDispatcher(secret, deadLetterPath string, sender *Sender) *CallbackDispatcher {
+	if sender == nil {
+		sender = NewSender()
+	}
+	return &CallbackDispatcher{
+		secret:     secret,
+		deadLetter: deadLetterPath,
+		sender:     sender,
+		logger:     logging.New("callbacks", "[CALLBACKS] ", log.Writer()),
+	}
+}
+
+// SetSecret changes the key that signs callbacks from now on
+func (d *CallbackDispatcher) SetSecret(secret string) {
+	d.secretMu.Lock()
+	defer d.secretMu.Unlock()
+	d.secret = secret
+}
+
+// Run dispatches callbacks for the task events read from events until the
+// channel is closed or ctx is done
+func (d *CallbackDispatcher) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			d.Handle(ctx, e)
+		}
+	}
+}
+
+// Handle starts the callback of a task.completed or final task.failed
+// event whose task has a callback URL; other events, and the failures that
+// will be retried, are ignored
+func (d *CallbackDispatcher) Handle(ctx context.Context, e agents.Event) {
+	if e.Type != agents.EventTaskCompleted && e.Type != agents.EventTaskFailed || !e.Final() {
+		return
+	}
+	task, ok := e.Data["task"].(agents.Task)
+	if !ok || task.CallbackURL == "" {
+		return
+	}
+
+	d.inflight.Add(1)
+	go func() {
+		defer d.inflight.Done()
+		d.deliver(ctx, string(e.Type), &task)
+	}()
+}
+
+// Wait blocks until in-flight callbacks finish or ctx is done
+func (d *CallbackDispatcher) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *CallbackDispatcher) deliver(ctx context.Context, event string, task *agents.Task) {
+	payload := CallbackPayload{
+		ID:          uuid.New().String(),
+		Event:       event,
+		TaskID:      task.ID,
+		Status:      task.Status,
+		AgentID:     task.AssignedTo,
+		Result:      task.Result,
+		CompletedAt: task.CompletedAt,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.logger.Printf("ERROR: Encoding callback for task %s: %v", task.ID, err)
+		return
+	}
+	d.secretMu.RLock()
+	secret := d.secret
+	d.secretMu.RUnlock()
+	if secret == "" {
+		d.unsigned.Do(func() {
+			d.logger.Printf("WARN: api.callback_secret is not set, task callbacks are sent unsigned")
+		})
+	}
+
+	res := d.sender.Send(ctx, Request{
+		URL:        task.CallbackURL,
+		Secret:     secret,
+		Event:      event,
+		DeliveryID: payload.ID,
+		Body:       body,
+	})
+	if res.Err == nil {
+		return
+	}
+
+	d.logger.Printf("ERROR: Callback for task %s to %s failed after %d attempts: %v", task.ID, task.CallbackURL, res.Attempts, res.Err)
+	if err := d.writeDeadLetter(DeadLetter{
+		TaskID:     task.ID,
+		URL:        task.CallbackURL,
+		Attempts:   res.Attempts,
+		StatusCode: res.StatusCode,
+		Error:      res.Err.Error(),
+		Payload:    body,
+		Time:       time.Now(),
+	}); err != nil {
+		d.logger.Printf("ERROR: Writing dead letter for task %s: %v", task.ID, err)
+	}
+}
+
+func (d *CallbackDispatcher) writeDeadLetter(dl DeadLetter) error {
+	if d.deadLetter == "" {
+		return nil
+	}
+	line, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(d.deadLetter), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(d.deadLetter, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DeadLetters returns the callbacks that could not be delivered, oldest
+// first
+func (d *CallbackDispatcher) DeadLetters() ([]DeadLetter, error) {
+	list := []DeadLetter{}
+	if d.deadLetter == "" {
+		return list, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, err := os.Open(d.deadLetter)
+	if errors.Is(err, os.ErrNotExist) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var dl DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+			continue // a line cut short by a crash
+		}
+		list = append(list, dl)
+	}
+	return list, scanner.Err()
+}
@@ -0,0 +1,121 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+)
+
+func TestTaskCallbacks(t *testing.T) {
+	received := make(chan CallbackPayload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify("cb-secret", r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, time.Minute); err != nil {
+			t.Errorf("verify: %v", err)
+		}
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var p CallbackPayload
+		json.Unmarshal(body, &p)
+		received <- p
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	events, unsubscribe := registry.Subscribe(16)
+	defer unsubscribe()
+
+	deadLetters := filepath.Join(t.TempDir(), "dead.jsonl")
+	d := NewCallbackDispatcher("cb-secret", deadLetters, testSender())
+	go d.Run(ctx, events)
+
+	ok := registry.CreateTask(&agents.Task{Title: "ok", CallbackURL: srv.URL + "/done"})
+	down := registry.CreateTask(&agents.Task{Title: "down", CallbackURL: srv.URL + "/down"})
+	registry.CreateTask(&agents.Task{Title: "no callback"})
+	registry.CompleteTask(ok.ID, &agents.TaskResult{Success: true, Output: "42"})
+	registry.CompleteTask(down.ID, &agents.TaskResult{Success: false, Error: "boom"})
+
+	select {
+	case p := <-received:
+		if p.TaskID != ok.ID || p.Event != "task.completed" || p.Result == nil || p.Result.Output != "42" {
+			t.Fatalf("payload: %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback received")
+	}
+
+	var letters []DeadLetter
+	deadline := time.Now().Add(5 * time.Second)
+	for len(letters) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		letters, _ = d.DeadLetters()
+	}
+	if len(letters) != 1 {
+		t.Fatalf("dead letters: %+v", letters)
+	}
+	if dl := letters[0]; dl.TaskID != down.ID || dl.Attempts != 3 || dl.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("dead letter: %+v", dl)
+	}
+	var p CallbackPayload
+	if err := json.Unmarshal(letters[0].Payload, &p); err != nil || p.Event != "task.failed" || p.Result.Error != "boom" {
+		t.Fatalf("dead letter payload: %s", letters[0].Payload)
+	}
+}
+
+func TestCallbackWaitsForTheFinalFailure(t *testing.T) {
+	received := make(chan CallbackPayload, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p CallbackPayload
+		json.NewDecoder(r.Body).Decode(&p)
+		received <- p
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	registry := agents.NewRegistry(ctx)
+	// The first run is retried, the second is not
+	registry.SetRetryDecider(func(task *agents.Task, _ agents.AgentType) bool { return len(task.Attempts) == 0 })
+	events, unsubscribe := registry.Subscribe(16)
+	defer unsubscribe()
+	d := NewCallbackDispatcher("cb-secret", filepath.Join(t.TempDir(), "dead.jsonl"), testSender())
+	go d.Run(ctx, events)
+
+	agent, _ := registry.CreateAgent("coder", "coder", nil)
+	task := registry.CreateTask(&agents.Task{Title: "flaky", CallbackURL: srv.URL})
+	for run := range 2 {
+		if err := registry.AssignTask(task.ID, agent.ID); err != nil {
+			t.Fatal(err)
+		}
+		registry.CompleteTask(task.ID, &agents.TaskResult{Error: "connection reset"})
+		if run == 0 {
+			if err := registry.RetryTask(task.ID, time.Now(), "transient", agents.Cause{Actor: "retry"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	select {
+	case p := <-received:
+		if p.Event != "task.failed" || p.Status != agents.TaskStatusCompleted {
+			t.Fatalf("payload: %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback received")
+	}
+	// Events are handled in order, so a callback of the retried run would
+	// be in flight by now
+	d.Wait(ctx)
+	if len(received) != 0 {
+		t.Fatalf("the retried run was called back too: %+v", <-received)
+	}
+}
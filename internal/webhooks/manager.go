@@ -0,0 +1,469 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/digest"
+	"github.com/biodoia/skagent/internal/logging"
+	"github.com/google/uuid"
+)
+
+// maxDeliveries is how many deliveries are kept per subscription
+const maxDeliveries = 100
+
+// EventPing is sent by Ping to test a subscription
+const EventPing = "ping"
+
+// ErrNotFound is returned for an unknown subscription ID
+var ErrNotFound = errors.New("webhook not found")
+
+// ValidationError reports an invalid subscription field
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Subscription registers a URL for the events matching its filters
+type Subscription struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Events are event types ("task.completed"), prefix wildcards
+	// ("task.*") or "*"; an empty list matches every event
+	Events      []string `json:"events"`
+	Description string   `json:"description,omitempty"`
+	// Secret keys the delivery signatures. It is only returned when the
+	// subscription is created.
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Redacted returns a copy without the secret
+func (s Subscription) Redacted() Subscription {
+	s.Secret = ""
+	s.Events = append([]string(nil), s.Events...)
+	return s
+}
+
+// Matches reports whether the subscription wants an event type
+func (s *Subscription) Matches(event string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, pattern := range s.Events {
+		if pattern == "*" || pattern == event {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(event, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery records one event sent to a subscription, including retries
+type Delivery struct {
+	ID             string    `json:"id"`
+	SubscriptionID string    `json:"subscription_id"`
+	Event          string    `json:"event"`
+	Success        bool      `json:"success"`
+	Attempts       int       `json:"attempts"`
+	StatusCode     int       `json:"status_code,omitempty"`
+	Response       string    `json:"response,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	DurationMs     int64     `json:"duration_ms"`
+	Time           time.Time `json:"time"`
+}
+
+// Manager keeps the subscriptions, delivers events to them and logs the
+// deliveries
+type Manager struct {
+	path   string
+	sender *Sender
+	logger *log.Logger
+
+	mu         sync.RWMutex
+	subs       map[string]*Subscription
+	deliveries map[string][]Delivery
+
+	inflight sync.WaitGroup
+}
+
+// NewManager loads the subscriptions saved at path, which may not exist
+// yet. An empty path keeps subscriptions in memory only; a nil sender uses
+// NewSender.
+func NewManager(path string, sender *Sender) (*Manager, error) {
+	if sender == nil {
+		sender = NewSender()
+	}
+	m := &Manager{
+		path:       path,
+		sender:     sender,
+		logger:     logging.New("webhooks", "[WEBHOOKS] ", log.Writer()),
+		subs:       make(map[string]*Subscription),
+		deliveries: make(map[string][]Delivery),
+	}
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var subs []*Subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	for _, s := range subs {
+		m.subs[s.ID] = s
+	}
+	return m, nil
+}
+
+// save writes the subscriptions; the caller holds m.mu
+func (m *Manager) save() error {
+	if m.path == "" {
+		return nil
+	}
+	subs := make([]*Subscription, 0, len(m.subs))
+	for _, s := range m.subs {
+		subs = append(subs, s)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.Before(subs[j].CreatedAt) })
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o700); err != nil {
+		return err
+	}
+	// The file holds the secrets
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// ValidURL reports whether raw is an absolute http or https URL, the only
+// kind deliveries are sent to
+func ValidURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return raw != "" && err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// extraEvents are the events delivered besides the registry's
+var extraEvents = []agents.EventType{digest.EventDaily}
+
+// validate checks a subscription's URL and event filters
+func validate(s *Subscription) error {
+	if !ValidURL(s.URL) {
+		return &ValidationError{Field: "url", Message: "must be an absolute http or https URL"}
+	}
+
+	known := make(map[string]bool)
+	for _, t := range append(agents.EventTypes, extraEvents...) {
+		known[string(t)] = true
+		prefix, _, _ := strings.Cut(string(t), ".")
+		known[prefix+".*"] = true
+	}
+	for _, e := range s.Events {
+		if e != "*" && !known[e] {
+			return &ValidationError{Field: "events", Message: fmt.Sprintf("unknown event %q", e)}
+		}
+	}
+	return nil
+}
+
+func newSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Create validates and stores a subscription, generating its ID and, when
+// none is given, its secret. It returns the subscription with its secret.
+func (m *Manager) Create(s Subscription) (*Subscription, error) {
+	if err := validate(&s); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s.ID = uuid.New().String()
+	s.CreatedAt, s.UpdatedAt = now, now
+	if s.Secret == "" {
+		s.Secret = newSecret()
+	}
+	s.Events = append([]string(nil), s.Events...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs[s.ID] = &s
+	if err := m.save(); err != nil {
+		delete(m.subs, s.ID)
+		return nil, err
+	}
+	created := s
+	return &created, nil
+}
+
+// Get returns a subscription without its secret
+func (m *Manager) Get(id string) (*Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.subs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	r := s.Redacted()
+	return &r, nil
+}
+
+// List returns every subscription without secrets, oldest first
+func (m *Manager) List() []Subscription {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]Subscription, 0, len(m.subs))
+	for _, s := range m.subs {
+		list = append(list, s.Redacted())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// SubscriptionUpdate changes the fields that are set
+type SubscriptionUpdate struct {
+	URL         *string   `json:"url,omitempty"`
+	Events      *[]string `json:"events,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Secret      *string   `json:"secret,omitempty"`
+	Active      *bool     `json:"active,omitempty"`
+}
+
+// Update applies u to a subscription and returns it without its secret
+func (m *Manager) Update(id string, u SubscriptionUpdate) (*Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.subs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	updated := *s
+	if u.URL != nil {
+		updated.URL = *u.URL
+	}
+	if u.Events != nil {
+		updated.Events = append([]string(nil), (*u.Events)...)
+	}
+	if u.Description != nil {
+		updated.Description = *u.Description
+	}
+	if u.Secret != nil {
+		if *u.Secret == "" {
+			return nil, &ValidationError{Field: "secret", Message: "must not be empty"}
+		}
+		updated.Secret = *u.Secret
+	}
+	if u.Active != nil {
+		updated.Active = *u.Active
+	}
+	if err := validate(&updated); err != nil {
+		return nil, err
+	}
+	updated.UpdatedAt = time.Now()
+
+	m.subs[id] = &updated
+	if err := m.save(); err != nil {
+		m.subs[id] = s
+		return nil, err
+	}
+	r := updated.Redacted()
+	return &r, nil
+}
+
+// Delete removes a subscription and its delivery log
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.subs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.subs, id)
+	if err := m.save(); err != nil {
+		m.subs[id] = s
+		return err
+	}
+	delete(m.deliveries, id)
+	return nil
+}
+
+// Deliveries returns a subscription's recent deliveries, newest first
+func (m *Manager) Deliveries(id string) ([]Delivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.subs[id]; !ok {
+		return nil, ErrNotFound
+	}
+	log := m.deliveries[id]
+	list := make([]Delivery, len(log))
+	for i, d := range log {
+		list[len(log)-1-i] = d
+	}
+	return list, nil
+}
+
+// Run delivers the events read from events, such as a registry
+// subscription, until the channel is closed or ctx is done
+func (m *Manager) Run(ctx context.Context, events <-chan agents.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			m.Publish(ctx, e)
+		}
+	}
+}
+
+// Publish starts delivering an event to every active subscription that
+// matches it. Deliveries run in the background; Wait blocks until they end.
+func (m *Manager) Publish(ctx context.Context, e agents.Event) {
+	m.mu.RLock()
+	var targets []Subscription
+	for _, s := range m.subs {
+		if s.Active && s.Matches(string(e.Type)) {
+			targets = append(targets, *s)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, s := range targets {
+		m.inflight.Add(1)
+		go func(s Subscription) {
+			defer m.inflight.Done()
+			m.deliver(ctx, m.sender, &s, string(e.Type), e)
+		}(s)
+	}
+}
+
+// Ping sends a test event to a subscription, even an inactive one, and
+// returns the delivery once it has finished. Pings are not retried, so
+// that the caller gets an answer promptly.
+func (m *Manager) Ping(ctx context.Context, id string) (*Delivery, error) {
+	m.mu.RLock()
+	s, ok := m.subs[id]
+	var sub Subscription
+	if ok {
+		sub = *s
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	once := *m.sender
+	once.Retry.MaxRetries = 0
+	d := m.deliver(ctx, &once, &sub, EventPing, agents.Event{
+		Type: EventPing,
+		Time: time.Now(),
+		Data: map[string]interface{}{"webhook_id": sub.ID},
+	})
+	return &d, nil
+}
+
+// Wait blocks until in-flight deliveries finish or ctx is done
+func (m *Manager) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// payload is the body of a delivery
+type payload struct {
+	ID string `json:"id"`
+	agents.Event
+}
+
+// deliver sends one event to a subscription and logs the outcome
+func (m *Manager) deliver(ctx context.Context, sender *Sender, s *Subscription, event string, e agents.Event) Delivery {
+	d := Delivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: s.ID,
+		Event:          event,
+		Time:           time.Now(),
+	}
+
+	body, err := json.Marshal(payload{ID: d.ID, Event: e})
+	if err != nil {
+		d.Error = err.Error()
+		m.record(d)
+		return d
+	}
+	res := sender.Send(ctx, Request{
+		URL:        s.URL,
+		Secret:     s.Secret,
+		Event:      event,
+		DeliveryID: d.ID,
+		Body:       body,
+	})
+	d.Attempts = res.Attempts
+	d.StatusCode = res.StatusCode
+	d.Response = res.Response
+	d.DurationMs = res.Duration.Milliseconds()
+	d.Success = res.Err == nil
+	if res.Err != nil {
+		d.Error = res.Err.Error()
+		m.logger.Printf("ERROR: Delivery of %s to %s failed after %d attempts: %v", event, s.URL, res.Attempts, res.Err)
+	}
+	m.record(d)
+	return d
+}
+
+// record appends to a subscription's delivery log, dropping the oldest
+// entries past maxDeliveries
+func (m *Manager) record(d Delivery) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.subs[d.SubscriptionID]; !ok {
+		return
+	}
+	log := append(m.deliveries[d.SubscriptionID], d)
+	if len(log) > maxDeliveries {
+		log = log[len(log)-maxDeliveries:]
+	}
+	m.deliveries[d.SubscriptionID] = log
+}
@@ -0,0 +1,138 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/retry"
+)
+
+func testSender() *Sender {
+	return &Sender{
+		Client: &http.Client{Timeout: time.Second},
+		Retry:  retry.Config{MaxRetries: 2, InitialWait: time.Millisecond, MaxWait: time.Millisecond, Multiplier: 2},
+	}
+}
+
+func TestSignedDeliveryWithRetry(t *testing.T) {
+	var calls int32
+	received := make(chan map[string]interface{}, 1)
+	var secret string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, time.Minute); err != nil {
+			t.Errorf("verify: %v", err)
+		}
+		// The first attempt fails and is retried
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var payload map[string]interface{}
+		json.Unmarshal(body, &payload)
+		if payload["id"] != r.Header.Get(DeliveryHeader) {
+			t.Errorf("payload id %v, header %q", payload["id"], r.Header.Get(DeliveryHeader))
+		}
+		received <- payload
+	}))
+	defer srv.Close()
+
+	m, err := NewManager("", testSender())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := m.Create(Subscription{URL: srv.URL, Events: []string{"task.*"}, Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret = sub.Secret
+
+	ctx := context.Background()
+	m.Publish(ctx, agents.Event{Type: agents.EventAgentCreated})
+	m.Publish(ctx, agents.Event{Type: agents.EventTaskCompleted, TaskID: "t1"})
+	if err := m.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case payload := <-received:
+		if payload["type"] != "task.completed" || payload["task_id"] != "t1" {
+			t.Fatalf("payload: %v", payload)
+		}
+	default:
+		t.Fatal("no delivery received")
+	}
+
+	deliveries, _ := m.Deliveries(sub.ID)
+	if len(deliveries) != 1 {
+		t.Fatalf("want only the task event delivered, got %+v", deliveries)
+	}
+	if d := deliveries[0]; !d.Success || d.Attempts != 2 || d.StatusCode != http.StatusOK {
+		t.Fatalf("delivery: %+v", d)
+	}
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "unknown hook", http.StatusGone)
+	}))
+	defer srv.Close()
+
+	m, _ := NewManager("", testSender())
+	sub, _ := m.Create(Subscription{URL: srv.URL, Active: true})
+	m.Publish(context.Background(), agents.Event{Type: agents.EventAgentError})
+	m.Wait(context.Background())
+
+	deliveries, _ := m.Deliveries(sub.ID)
+	if calls != 1 || len(deliveries) != 1 {
+		t.Fatalf("calls %d, deliveries %+v", calls, deliveries)
+	}
+	if d := deliveries[0]; d.Success || d.StatusCode != http.StatusGone || d.Response != "unknown hook" {
+		t.Fatalf("delivery: %+v", d)
+	}
+}
+
+func TestSubscriptionsPersistAndValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	m, err := NewManager(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Create(Subscription{URL: "ftp://example.com"}); err == nil {
+		t.Fatal("want an error for a non-HTTP URL")
+	}
+	if _, err := m.Create(Subscription{URL: "https://example.com", Events: []string{"task.exploded"}}); err == nil {
+		t.Fatal("want an error for an unknown event")
+	}
+	if _, err := m.Create(Subscription{URL: "https://example.com/digest", Events: []string{"report.daily"}}); err != nil {
+		t.Fatalf("the daily digest event: %v", err)
+	}
+
+	sub, err := m.Create(Subscription{URL: "https://example.com/hook", Events: []string{"agent.error"}, Secret: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.Get(sub.ID); got.Secret != "" {
+		t.Fatal("Get must not return the secret")
+	}
+
+	reloaded, err := NewManager(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reloaded.subs[sub.ID]
+	if got == nil || got.Secret != "s3cret" || !got.Matches("agent.error") || got.Matches("task.completed") {
+		t.Fatalf("reloaded: %+v", got)
+	}
+}
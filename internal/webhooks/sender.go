@@ -0,0 +1,166 @@
+// Package webhooks delivers registry events to URLs registered by
+// external systems, and task results to task callback URLs, signing each
+// request so receivers can verify it.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/retry"
+)
+
+// Headers set on every delivery
+const (
+	SignatureHeader = "X-Skagent-Signature"
+	TimestampHeader = "X-Skagent-Timestamp"
+	EventHeader     = "X-Skagent-Event"
+	DeliveryHeader  = "X-Skagent-Delivery"
+)
+
+// maxResponseSnippet bounds the part of a receiver's response kept for
+// debugging
+const maxResponseSnippet = 512
+
+// Sign returns the signature of a delivery: "sha256=" followed by the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature and rejects timestamps further than
+// tolerance from now, which limits replays
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s header", TimestampHeader)
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("timestamp outside the %s tolerance", tolerance)
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// StatusError is a delivery answered with a non-2xx status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("receiver returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// isRetryable retries network failures, timeouts, 5xx and 429 responses;
+// other 4xx responses mean the receiver rejected the delivery for good
+func isRetryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode >= 500 || status.StatusCode == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// Request is one signed POST
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+// Result is the outcome of a Request after retries
+type Result struct {
+	Attempts   int
+	StatusCode int
+	Response   string
+	Duration   time.Duration
+	Err        error
+}
+
+// Sender posts signed requests, retrying with exponential backoff
+type Sender struct {
+	Client *http.Client
+	Retry  retry.Config
+}
+
+// NewSender returns a sender with a 10 second request timeout that tries
+// each request up to five times over roughly 15 seconds
+func NewSender() *Sender {
+	return &Sender{
+		Client: &http.Client{Timeout: 10 * time.Second},
+		Retry: retry.Config{
+			MaxRetries:  4,
+			InitialWait: time.Second,
+			MaxWait:     30 * time.Second,
+			Multiplier:  2.0,
+		},
+	}
+}
+
+// Send delivers req. Every attempt is signed with a fresh timestamp but
+// carries the same delivery ID, so that receivers can deduplicate.
+func (s *Sender) Send(ctx context.Context, req Request) Result {
+	var res Result
+	start := time.Now()
+	res.Err = retry.Do(ctx, s.Retry, isRetryable, func() error {
+		res.Attempts++
+		code, body, err := s.post(ctx, req)
+		res.StatusCode, res.Response = code, body
+		return err
+	})
+	res.Duration = time.Since(start)
+	return res
+}
+
+func (s *Sender) post(ctx context.Context, req Request) (int, string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return 0, "", err
+	}
+	ts := time.Now().Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "skagent-webhooks")
+	httpReq.Header.Set(EventHeader, req.Event)
+	httpReq.Header.Set(DeliveryHeader, req.DeliveryID)
+	httpReq.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	if req.Secret != "" {
+		httpReq.Header.Set(SignatureHeader, Sign(req.Secret, ts, req.Body))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+	// Drain a little more so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	body := strings.TrimSpace(string(snippet))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, body, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return resp.StatusCode, body, nil
+}
@@ -0,0 +1,195 @@
+// Package workspace scaffolds the on-disk layout skagent expects in a
+// project: the .skagent directory with its local config overlay and the
+// specs/ tree used by the SpecKit workflow.
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/config"
+	"github.com/biodoia/skagent/internal/dryrun"
+)
+
+// SpecsDirName is the directory holding specifications, plans and tasks
+const SpecsDirName = "specs"
+
+// Options controls Init
+type Options struct {
+	Name  string // project name; defaults to the directory name
+	Force bool   // overwrite files that already exist
+}
+
+// Result lists what Init did, with paths relative to the project root
+type Result struct {
+	Root    string
+	Name    string
+	Created []string
+	Skipped []string
+}
+
+// ProjectFileName is the file in .skagent describing the project itself;
+// config.json next to it stays a plain config overlay
+const ProjectFileName = "project.json"
+
+// Project identifies a skagent workspace
+type Project struct {
+	Name     string `json:"name"`
+	RepoURL  string `json:"repo_url,omitempty"`
+	RemoteID string `json:"remote_id,omitempty"`
+}
+
+// Init creates the skagent layout under dir. Existing files are left
+// untouched unless opts.Force is set, so running it twice is safe.
+func Init(dir string, opts Options) (*Result, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	name := strings.TrimSpace(opts.Name)
+	if name == "" {
+		name = filepath.Base(root)
+	}
+
+	project, err := json.MarshalIndent(Project{Name: name, RepoURL: RepoURL(root)}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := json.MarshalIndent(map[string]string{"speckit_path": SpecsDirName}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		path    string
+		content []byte
+		mode    os.FileMode
+	}{
+		{filepath.Join(config.ProjectDirName, "config.json"), append(overlay, '\n'), 0o600},
+		{filepath.Join(config.ProjectDirName, ProjectFileName), append(project, '\n'), 0o644},
+		{filepath.Join(SpecsDirName, "README.md"), []byte(fmt.Sprintf(specsReadme, name)), 0o644},
+		{filepath.Join(SpecsDirName, "constitution.md"), []byte(constitution), 0o644},
+		{filepath.Join(SpecsDirName, "templates", "spec.md"), []byte(specTemplate), 0o644},
+		{filepath.Join(SpecsDirName, "templates", "plan.md"), []byte(planTemplate), 0o644},
+		{filepath.Join(SpecsDirName, "templates", "tasks.md"), []byte(tasksTemplate), 0o644},
+	}
+
+	res := &Result{Root: root, Name: name}
+	for _, f := range files {
+		target := filepath.Join(root, f.path)
+		if _, err := os.Stat(target); err == nil && !opts.Force {
+			res.Skipped = append(res.Skipped, f.path)
+			continue
+		}
+		if dryrun.Skip("workspace", "write %s", target) {
+			res.Created = append(res.Created, f.path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return res, err
+		}
+		if err := os.WriteFile(target, f.content, f.mode); err != nil {
+			return res, err
+		}
+		res.Created = append(res.Created, f.path)
+	}
+	return res, nil
+}
+
+// Load reads the project description of the workspace at root
+func Load(root string) (*Project, error) {
+	path := filepath.Join(root, config.ProjectDirName, ProjectFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Project
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Save writes the project description of the workspace at root
+func Save(root string, p *Project) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root, config.ProjectDirName, ProjectFileName)
+	if dryrun.Skip("workspace", "write %s", path) {
+		return nil
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// RepoURL returns the origin remote of the git repository at dir, or ""
+// when there is none or git is not installed
+func RepoURL(dir string) string {
+	cmd := exec.Command("git", "-C", dir, "config", "--get", "remote.origin.url")
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+const specsReadme = `# %s specifications
+
+This directory is the home of the spec-driven workflow.
+
+- constitution.md: project principles (/speckit.constitution)
+- templates/: starting points for new features
+- NNN-feature-name/: one directory per feature, holding
+  spec.md (/speckit.specify), plan.md (/speckit.plan) and
+  tasks.md (/speckit.tasks)
+`
+
+const constitution = `# Constitution
+
+1. Library-First: features start as standalone libraries
+2. CLI Mandate: all functionality is reachable from the CLI
+3. Test-First: no implementation without tests
+4. Simplicity: prefer the smallest design that works
+5. Anti-Abstraction: use frameworks directly
+6. Integration-First: test in realistic environments
+`
+
+const specTemplate = `# Feature: <name>
+
+## What and why
+
+## User stories
+
+## Requirements
+
+## Out of scope
+
+## Open questions
+`
+
+const planTemplate = `# Plan: <name>
+
+## Architecture
+
+## Technical decisions
+
+## Risks
+`
+
+const tasksTemplate = `# Tasks: <name>
+
+- [ ] T001
+`
@@ -0,0 +1,70 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/biodoia/skagent/internal/config"
+)
+
+func TestInitIsIdempotent(t *testing.T) {
+	dir := t.TempDir()
+
+	res, err := Init(dir, Options{Name: "demo"})
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if len(res.Created) == 0 || len(res.Skipped) != 0 {
+		t.Fatalf("first run: created %v, skipped %v", res.Created, res.Skipped)
+	}
+	if config.FindProjectConfig(dir) == "" {
+		t.Fatal("project config overlay not found after Init")
+	}
+
+	spec := filepath.Join(dir, SpecsDirName, "templates", "spec.md")
+	if err := os.WriteFile(spec, []byte("custom"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = Init(dir, Options{Name: "demo"})
+	if err != nil {
+		t.Fatalf("second Init: %v", err)
+	}
+	if len(res.Created) != 0 {
+		t.Fatalf("second run created %v", res.Created)
+	}
+	if data, _ := os.ReadFile(spec); string(data) != "custom" {
+		t.Fatalf("existing file was overwritten: %q", data)
+	}
+
+	if _, err := Init(dir, Options{Name: "demo", Force: true}); err != nil {
+		t.Fatalf("forced Init: %v", err)
+	}
+	if data, _ := os.ReadFile(spec); string(data) == "custom" {
+		t.Fatal("--force did not overwrite the existing file")
+	}
+}
+
+func TestSaveAndLoadProject(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Init(dir, Options{}); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if p.Name != filepath.Base(dir) {
+		t.Fatalf("default name = %q, want %q", p.Name, filepath.Base(dir))
+	}
+
+	p.RemoteID = "proj-1"
+	if err := Save(dir, p); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got, _ := Load(dir); got.RemoteID != "proj-1" {
+		t.Fatalf("RemoteID = %q after save", got.RemoteID)
+	}
+}
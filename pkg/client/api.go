@@ -0,0 +1,339 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/snapshot"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/templates"
+)
+
+// The API's resources, as the server encodes them
+type (
+	Agent      = agents.AgentView
+	Task       = agents.Task
+	TaskResult = agents.TaskResult
+	TaskStatus = agents.TaskStatus
+	Transition = agents.Transition
+	Routing    = agents.RoutingDecision
+	TaskOp     = agents.TaskOp
+	BulkResult = agents.BulkResult
+	AgentNotes = agents.Notes
+	Snapshot   = snapshot.Snapshot
+	Template   = templates.Template
+
+	TaskLogEntry  = tasklog.Entry
+	TaskLogKind   = tasklog.Kind
+	AgentActivity = tasklog.Activity
+)
+
+// Task statuses
+const (
+	TaskPending    = agents.TaskStatusPending
+	TaskInProgress = agents.TaskStatusInProgress
+	TaskCompleted  = agents.TaskStatusCompleted
+	TaskFailed     = agents.TaskStatusFailed
+	TaskCancelled  = agents.TaskStatusCancelled
+)
+
+// Finished reports whether a task has reached a final status
+func Finished(task *Task) bool {
+	switch task.Status {
+	case TaskCompleted, TaskFailed, TaskCancelled:
+		return true
+	}
+	return false
+}
+
+// CreateAgentRequest is the body of POST /agents
+type CreateAgentRequest struct {
+	Name   string                 `json:"name"`
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// CreateAgent registers an agent
+func (c *Client) CreateAgent(ctx context.Context, req CreateAgentRequest) (*Agent, error) {
+	var out struct {
+		Agent Agent `json:"agent"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/agents", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out.Agent, nil
+}
+
+// GetAgent returns an agent
+func (c *Client) GetAgent(ctx context.Context, id string) (*Agent, error) {
+	var out struct {
+		Agent Agent `json:"agent"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/agents/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Agent, nil
+}
+
+// ListAgents returns every agent
+func (c *Client) ListAgents(ctx context.Context) ([]Agent, error) {
+	var out struct {
+		Agents []Agent `json:"agents"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/agents", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Agents, nil
+}
+
+// DeleteAgent removes an agent
+func (c *Client) DeleteAgent(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/agents/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// GetAgentNotes returns an agent's notes with their history
+func (c *Client) GetAgentNotes(ctx context.Context, id string) (*AgentNotes, error) {
+	var out struct {
+		Notes AgentNotes `json:"notes"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/agents/"+url.PathEscape(id)+"/notes", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Notes, nil
+}
+
+// SetAgentNotes replaces an agent's notes, signed by author when it is
+// not empty; an empty text clears them
+func (c *Client) SetAgentNotes(ctx context.Context, id, text, author string) (*AgentNotes, error) {
+	var out struct {
+		Notes AgentNotes `json:"notes"`
+	}
+	body := map[string]string{"text": text, "author": author}
+	if err := c.do(ctx, http.MethodPut, "/agents/"+url.PathEscape(id)+"/notes", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Notes, nil
+}
+
+// SubmitTaskRequest is the body of POST /tasks
+type SubmitTaskRequest struct {
+	Task string `json:"task"`
+	// Priority runs from 0 (low) to 3 (urgent)
+	Priority int `json:"priority"`
+	// AgentID assigns the task straight away; otherwise it waits for
+	// auto-assignment
+	AgentID string `json:"agent_id,omitempty"`
+	// CallbackURL receives the result when the task finishes
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// SubmitTask creates a task
+func (c *Client) SubmitTask(ctx context.Context, req SubmitTaskRequest) (*Task, error) {
+	var out struct {
+		Task Task `json:"task"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/tasks", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out.Task, nil
+}
+
+// TaskTemplates lists the built-in task templates
+func (c *Client) TaskTemplates(ctx context.Context) ([]Template, error) {
+	var out struct {
+		Templates []Template `json:"templates"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tasks/templates", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Templates, nil
+}
+
+// TemplateTaskRequest is the body of POST /tasks/from-template
+type TemplateTaskRequest struct {
+	Template string            `json:"template"`
+	Params   map[string]string `json:"params,omitempty"`
+	// AgentID assigns the task straight away; otherwise it waits for
+	// auto-assignment by the template's agent types
+	AgentID string `json:"agent_id,omitempty"`
+	// Priority overrides the template's, from 0 (low) to 3 (urgent)
+	Priority    *int   `json:"priority,omitempty"`
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// CreateTaskFromTemplate creates a task from a built-in template, filling
+// its placeholders with req.Params
+func (c *Client) CreateTaskFromTemplate(ctx context.Context, req TemplateTaskRequest) (*Task, error) {
+	var out struct {
+		Task Task `json:"task"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/tasks/from-template", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out.Task, nil
+}
+
+// GetTask returns a task
+func (c *Client) GetTask(ctx context.Context, id string) (*Task, error) {
+	var out struct {
+		Task Task `json:"task"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Task, nil
+}
+
+// CancelTask cancels a task that has not finished; reason is recorded in
+// its history
+func (c *Client) CancelTask(ctx context.Context, id, reason string) error {
+	var query url.Values
+	if reason != "" {
+		query = url.Values{"reason": {reason}}
+	}
+	return c.do(ctx, http.MethodDelete, "/tasks/"+url.PathEscape(id), query, nil, nil)
+}
+
+// TaskHistory returns a task's state transitions, oldest first
+func (c *Client) TaskHistory(ctx context.Context, id string) ([]Transition, error) {
+	var out struct {
+		Transitions []Transition `json:"transitions"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id)+"/history", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Transitions, nil
+}
+
+// ApplyTaskOps creates, updates and deletes tasks in one batch, all or
+// none
+func (c *Client) ApplyTaskOps(ctx context.Context, ops []TaskOp) ([]BulkResult, error) {
+	var out struct {
+		Results []BulkResult `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/tasks/bulk", nil, map[string]interface{}{"operations": ops}, &out); err != nil {
+		return nil, err
+	}
+	return out.Results, nil
+}
+
+// TaskRouting explains why a task was assigned to its agent, or how
+// auto-assign would route it now if it is pending
+func (c *Client) TaskRouting(ctx context.Context, id string) (*Routing, error) {
+	var out struct {
+		Routing Routing `json:"routing"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id)+"/routing", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Routing, nil
+}
+
+// TaskLog returns a task's execution log, oldest first: the prompts,
+// tool calls, outputs, retries and errors of its run
+func (c *Client) TaskLog(ctx context.Context, id string) ([]TaskLogEntry, error) {
+	var out struct {
+		Entries []TaskLogEntry `json:"entries"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id)+"/log", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Entries, nil
+}
+
+// AppendTaskLog records a step of a task's run in its execution log
+func (c *Client) AppendTaskLog(ctx context.Context, id string, kind TaskLogKind, tool, message string) error {
+	body := map[string]string{"kind": string(kind), "tool": tool, "message": message}
+	return c.do(ctx, http.MethodPost, "/tasks/"+url.PathEscape(id)+"/log", nil, body, nil)
+}
+
+// TaskSnapshot returns the checkpoint taken of a task's workspace before
+// its agent changed it
+func (c *Client) TaskSnapshot(ctx context.Context, id string) (*Snapshot, error) {
+	return c.snapshot(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id)+"/snapshot")
+}
+
+// RollbackTask restores a task's workspace to its checkpoint, discarding
+// every change made since
+func (c *Client) RollbackTask(ctx context.Context, id string) (*Snapshot, error) {
+	return c.snapshot(ctx, http.MethodPost, "/tasks/"+url.PathEscape(id)+"/rollback")
+}
+
+func (c *Client) snapshot(ctx context.Context, method, path string) (*Snapshot, error) {
+	var out struct {
+		Snapshot Snapshot `json:"snapshot"`
+	}
+	if err := c.do(ctx, method, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Snapshot, nil
+}
+
+// DefaultPollInterval is how often StreamEvents polls
+const DefaultPollInterval = time.Second
+
+// waitTimeout is how long each long poll of WaitForTask is held by the
+// server, well under the default HTTP client timeout
+const waitTimeout = 30 * time.Second
+
+// WaitForTask waits for a task to finish, or for ctx to be done, and
+// returns it in its final state. It long-polls GET /tasks/{id}/wait, so the
+// task is returned as soon as it finishes. A failed or cancelled task is
+// not an error; check its Status and Result.
+func (c *Client) WaitForTask(ctx context.Context, id string) (*Task, error) {
+	query := url.Values{"timeout": {waitTimeout.String()}}
+	for {
+		var out struct {
+			Task     Task `json:"task"`
+			Finished bool `json:"finished"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id)+"/wait", query, nil, &out); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if out.Finished {
+			return &out.Task, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return &out.Task, err
+		}
+	}
+}
+
+// StreamEvents calls handle with every task transition after since, in
+// order, as they happen, until ctx is done or handle returns an error.
+// Pass 0 to start from the oldest transition the server keeps, or the Seq
+// of the last transition seen to resume.
+func (c *Client) StreamEvents(ctx context.Context, since int64, handle func(Transition) error) error {
+	for {
+		var out struct {
+			Transitions []Transition `json:"transitions"`
+			NextSince   int64        `json:"next_since"`
+		}
+		query := url.Values{"since": {strconv.FormatInt(since, 10)}}
+		if err := c.do(ctx, http.MethodGet, "/tasks/transitions", query, nil, &out); err != nil {
+			return err
+		}
+		for _, t := range out.Transitions {
+			if err := handle(t); err != nil {
+				return err
+			}
+		}
+		since = out.NextSince
+		if len(out.Transitions) > 0 {
+			// Catch up on anything that arrived meanwhile before waiting
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(DefaultPollInterval):
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package client is a typed Go client for the skagent REST API and the
+// HTTP tools endpoint of its MCP server.
+//
+//	c := client.New("http://localhost:8080", client.WithAPIKey(key))
+//	task, err := c.SubmitTask(ctx, client.SubmitTaskRequest{Task: "Write the README"})
+//	...
+//	task, err = c.WaitForTask(ctx, task.ID)
+//
+// Requests that fail with a network error, 429 or a 502/503/504 are
+// retried with exponential backoff. POSTs carry an Idempotency-Key, so a
+// retried request is applied once.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/retry"
+	"github.com/google/uuid"
+)
+
+// RetryConfig controls how failed requests are retried; MaxRetries 0
+// disables retries
+type RetryConfig = retry.Config
+
+// DefaultRetry retries three times, waiting 500ms, 1s and 2s
+var DefaultRetry = RetryConfig{
+	MaxRetries:  3,
+	InitialWait: 500 * time.Millisecond,
+	MaxWait:     10 * time.Second,
+	Multiplier:  2,
+}
+
+// Client calls the REST API. It is safe for concurrent use.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+	retry   RetryConfig
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with an API key from the auth.keys
+// configuration
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient sends requests through hc, e.g. one configured for mTLS
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithRetry replaces DefaultRetry
+func WithRetry(cfg RetryConfig) Option {
+	return func(c *Client) { c.retry = cfg }
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://localhost:8080", or "unix:///run/skagent/api.sock" for the Unix
+// domain socket of api.socket. WithHTTPClient replaces the socket
+// transport.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: 60 * time.Second},
+		retry:   DefaultRetry,
+	}
+	if path, ok := strings.CutPrefix(baseURL, "unix://"); ok {
+		c.baseURL = "http://localhost"
+		c.http.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// FieldError points at a single invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error answered by the server
+type Error struct {
+	// Status is the HTTP status code
+	Status int `json:"-"`
+	// Code is the machine-readable code, e.g. "TASK_NOT_FOUND"
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	// RetryAfter is the wait the server asked for on 429 and 503
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("skagent: %d %s: %s", e.Status, e.Code, e.Message)
+	for _, d := range e.Details {
+		msg += fmt.Sprintf("; %s %s", d.Field, d.Message)
+	}
+	return msg
+}
+
+// IsNotFound reports whether err is a 404 from the server
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
+}
+
+// temporary reports whether a request that failed with err may succeed
+// when sent again
+func temporary(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		switch e.Status {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// The request never got an answer
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || retry.DefaultIsRetryable(err)
+}
+
+// envelope is the REST API's response wrapper
+type envelope struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   *Error          `json:"error"`
+	Message string          `json:"message"`
+}
+
+// newRequest builds a request to the REST API with the client's
+// credentials
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, payload []byte) (*http.Request, error) {
+	target := c.baseURL + "/api/v1" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return req, nil
+}
+
+// do sends a request to the REST API and decodes the response's data into
+// out, when out is not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	// One key for every attempt, so that the server applies the request once
+	idempotencyKey := ""
+	if method == http.MethodPost {
+		idempotencyKey = uuid.New().String()
+	}
+
+	return retry.Do(ctx, c.retry, temporary, func() error {
+		req, err := c.newRequest(ctx, method, path, query, payload)
+		if err != nil {
+			return err
+		}
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			if resp.StatusCode >= 300 {
+				return &Error{Status: resp.StatusCode, Code: http.StatusText(resp.StatusCode), Message: strings.TrimSpace(string(data))}
+			}
+			return fmt.Errorf("skagent: decoding response: %w", err)
+		}
+		if resp.StatusCode >= 300 || env.Error != nil {
+			return responseError(resp, env)
+		}
+		if out == nil || len(env.Data) == 0 {
+			return nil
+		}
+		return json.Unmarshal(env.Data, out)
+	})
+}
+
+// responseError returns the error of a response that failed
+func responseError(resp *http.Response, env envelope) *Error {
+	e := env.Error
+	if e == nil {
+		e = &Error{Code: http.StatusText(resp.StatusCode), Message: env.Message}
+	}
+	e.Status = resp.StatusCode
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return e
+}
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/biodoia/skagent/internal/agents"
+	"github.com/biodoia/skagent/internal/ai"
+	"github.com/biodoia/skagent/internal/tasklog"
+	"github.com/biodoia/skagent/internal/testutil"
+)
+
+func TestClientAgainstHeadless(t *testing.T) {
+	stack := testutil.StartHeadless(t, testutil.StackOptions{})
+	c := New(stack.Server.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	agent, err := c.CreateAgent(ctx, CreateAgentRequest{Name: "writer", Type: "coder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := c.SubmitTask(ctx, SubmitTaskRequest{Task: "Write the README", AgentID: agent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != TaskInProgress || task.AssignedTo != agent.ID {
+		t.Fatalf("submitted task = %+v", task)
+	}
+	routing, err := c.TaskRouting(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if routing.AgentID != agent.ID || routing.Preview || len(routing.Candidates) != 1 || !routing.Candidates[0].Chosen {
+		t.Fatalf("routing = %+v", routing)
+	}
+
+	stack.Registry.CompleteTask(task.ID, &agents.TaskResult{Success: true, Output: "done"})
+	task, err = c.WaitForTask(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != TaskCompleted || task.Result == nil || task.Result.Output != "done" {
+		t.Fatalf("finished task = %+v", task)
+	}
+
+	var events []Transition
+	errStop := errors.New("stop")
+	err = c.StreamEvents(ctx, 0, func(tr Transition) error {
+		events = append(events, tr)
+		if tr.Event == agents.EventTaskCompleted {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || len(events) != 3 {
+		t.Fatalf("streamed %d events, err %v", len(events), err)
+	}
+
+	if _, err := c.GetTask(ctx, "missing"); !IsNotFound(err) {
+		t.Errorf("missing task: err = %v", err)
+	}
+	var apiErr *Error
+	if _, err := c.SubmitTask(ctx, SubmitTaskRequest{}); !errors.As(err, &apiErr) || apiErr.Code != "VALIDATION_FAILED" || len(apiErr.Details) == 0 {
+		t.Errorf("invalid task: err = %v", err)
+	}
+}
+
+func TestClientRetriesWithOneIdempotencyKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		attempt := len(keys)
+		mu.Unlock()
+		if attempt == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"success":false,"error":{"code":"SHUTTING_DOWN","message":"draining"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"success":true,"data":{"task":{"id":"t1","status":"pending"}}}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetry(RetryConfig{MaxRetries: 2, InitialWait: time.Millisecond, MaxWait: time.Millisecond, Multiplier: 1}))
+	task, err := c.SubmitTask(context.Background(), SubmitTaskRequest{Task: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.ID != "t1" || len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("task %+v after attempts with keys %q", task, keys)
+	}
+}
+
+func TestClientChatAndLogs(t *testing.T) {
+	stack := testutil.StartHeadless(t, testutil.StackOptions{Provider: ai.NewMockProvider("Hello from the mock")})
+	c := New(stack.Server.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session, err := c.CreateSession(ctx, "remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed strings.Builder
+	reply, err := c.Chat(ctx, session.ID, "hi", func(delta string) error {
+		streamed.WriteString(delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "Hello from the mock" || streamed.String() != reply {
+		t.Fatalf("reply %q, streamed %q", reply, streamed.String())
+	}
+	if _, err := c.Chat(ctx, "missing", "hi", nil); !IsNotFound(err) {
+		t.Errorf("missing session: err = %v", err)
+	}
+
+	entries, err := c.Logs(ctx, LogQuery{Limit: 1})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("logs = %v, err %v", entries, err)
+	}
+	errStop := errors.New("stop")
+	var followed []LogEntry
+	err = c.FollowLogs(ctx, LogQuery{Limit: 1}, func(e LogEntry) error {
+		followed = append(followed, e)
+		return errStop
+	})
+	if !errors.Is(err, errStop) || followed[0].Seq < entries[0].Seq {
+		t.Fatalf("followed %v, err %v", followed, err)
+	}
+}
+
+func TestClientFollowAgent(t *testing.T) {
+	stack := testutil.StartHeadless(t, testutil.StackOptions{})
+	c := New(stack.Server.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	agent, err := c.CreateAgent(ctx, CreateAgentRequest{Name: "coder", Type: "coder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := stack.Registry.CreateTask(&agents.Task{Title: "elsewhere"})
+	activities := make(chan AgentActivity, 16)
+	go c.FollowAgent(ctx, agent.ID, false, func(a AgentActivity) error {
+		activities <- a
+		return nil
+	})
+	next := func() AgentActivity {
+		t.Helper()
+		select {
+		case a := <-activities:
+			return a
+		case <-ctx.Done():
+			t.Fatal("no activity")
+			return AgentActivity{}
+		}
+	}
+	if a := next(); a.Type != tasklog.ActivityAgent || a.Agent == nil || a.Agent.ID != agent.ID {
+		t.Fatalf("first activity = %+v", a)
+	}
+
+	task, err := c.SubmitTask(ctx, SubmitTaskRequest{Task: "Write the README", AgentID: agent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := next(); a.Type != string(agents.EventTaskAssigned) || a.Task == nil || a.Task.ID != task.ID {
+		t.Fatalf("assignment = %+v", a)
+	}
+	// Steps of other tasks are left out, and prompts are summarized
+	tasklog.Record(other.ID, tasklog.KindNote, "someone", "not this one")
+	tasklog.Record(task.ID, tasklog.KindPrompt, agent.ID, "Write the README\nin English\nwith examples")
+	if a := next(); a.Step == nil || a.Step.Message != "Write the README (+2 lines)" {
+		t.Fatalf("step = %+v", a)
+	}
+	stack.Registry.CompleteTask(task.ID, &agents.TaskResult{Success: true, Output: "done"})
+	if a := next(); a.Type != string(agents.EventTaskCompleted) || !strings.Contains(a.Format(), "done") {
+		t.Fatalf("completion = %+v", a)
+	}
+
+	if err := c.FollowAgent(ctx, "missing", false, nil); !IsNotFound(err) {
+		t.Errorf("missing agent: err = %v", err)
+	}
+}
+
+func TestClientOverUnixSocket(t *testing.T) {
+	stack := testutil.StartHeadless(t, testutil.StackOptions{})
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "api.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: stack.Server.Config.Handler}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	c := New("unix://" + path)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := c.CreateAgent(ctx, CreateAgentRequest{Name: "writer", Type: "coder"}); err != nil {
+		t.Fatal(err)
+	}
+	agents, err := c.ListAgents(ctx)
+	if err != nil || len(agents) != 1 {
+		t.Fatalf("agents over the socket: %v %v", agents, err)
+	}
+}
+
+func TestTasksSinceIncludesArchivedTasks(t *testing.T) {
+	now := time.Now().UTC()
+	live := []Task{{ID: "live-1", Status: TaskPending}, {ID: "moved", Status: TaskCompleted}}
+	// One more than a page, finishing an hour apart, the latest first
+	var archived []Task
+	for i := range archivePage + 1 {
+		done := now.Add(-time.Duration(i) * time.Hour)
+		archived = append(archived, Task{ID: fmt.Sprintf("old-%d", i), Status: TaskCompleted, CompletedAt: &done})
+	}
+	archived[0].ID = "moved"
+
+	var mu sync.Mutex
+	var pages []string
+	noArchive := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		tasks := live
+		if q.Get("archived") == "true" {
+			mu.Lock()
+			pages = append(pages, q.Get("offset"))
+			mu.Unlock()
+			if noArchive {
+				w.WriteHeader(http.StatusNotImplemented)
+				w.Write([]byte(`{"success":false,"error":{"code":"NO_ARCHIVE","message":"no archive"}}`))
+				return
+			}
+			limit, _ := strconv.Atoi(q.Get("limit"))
+			offset, _ := strconv.Atoi(q.Get("offset"))
+			tasks = archived[min(offset, len(archived)):min(offset+limit, len(archived))]
+		}
+		body, _ := json.Marshal(map[string]any{"success": true, "data": map[string]any{"tasks": tasks}})
+		w.Write(body)
+	}))
+	defer srv.Close()
+	c := New(srv.URL)
+	ctx := context.Background()
+
+	tasks, err := c.TasksSince(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != len(live)+len(archived)-1 || len(pages) != 2 {
+		t.Fatalf("got %d tasks over archive pages %q, want %d over two", len(tasks), pages, len(live)+len(archived)-1)
+	}
+
+	// Pages stop once they reach tasks older than since
+	pages = nil
+	tasks, err = c.TasksSince(ctx, now.Add(-time.Duration(archivePage+10)*time.Hour))
+	if err != nil || len(pages) != 2 {
+		t.Fatalf("since before the first page's end: %d tasks, pages %q, %v", len(tasks), pages, err)
+	}
+	pages = nil
+	if _, err := c.TasksSince(ctx, now.Add(-48*time.Hour)); err != nil || len(pages) != 1 {
+		t.Fatalf("since within the first page: pages %q, %v", pages, err)
+	}
+
+	// Without an archive only the live tasks are listed, at once
+	noArchive, pages = true, nil
+	tasks, err = c.TasksSince(ctx, time.Time{})
+	if err != nil || len(tasks) != len(live) || len(pages) != 1 {
+		t.Fatalf("without an archive: %d tasks over pages %q, %v", len(tasks), pages, err)
+	}
+}
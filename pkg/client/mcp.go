@@ -0,0 +1,131 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/biodoia/skagent/internal/retry"
+)
+
+// Tool describes a tool the MCP server exposes
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// MCPClient calls the HTTP tools endpoint of the MCP server, which
+// listens on its own port and answers calls in JSON-RPC envelopes. It shares Client's options.
+type MCPClient struct {
+	c *Client
+}
+
+// NewMCP returns a client for the MCP server at baseURL, e.g.
+// "http://localhost:8081"
+func NewMCP(baseURL string, opts ...Option) *MCPClient {
+	return &MCPClient{c: New(baseURL, opts...)}
+}
+
+// ListTools returns the tools the server exposes, by name
+func (m *MCPClient) ListTools(ctx context.Context) (map[string]Tool, error) {
+	var out struct {
+		Tools map[string]Tool `json:"tools"`
+	}
+	if err := m.do(ctx, http.MethodGet, "/tools", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Tools, nil
+}
+
+// CallTool runs a tool and decodes its result into out, when out is not
+// nil
+func (m *MCPClient) CallTool(ctx context.Context, name string, args map[string]interface{}, out interface{}) error {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var resp struct {
+		Result struct {
+			Result json.RawMessage `json:"result"`
+		} `json:"result"`
+	}
+	if err := m.do(ctx, http.MethodPost, "/tools/"+url.PathEscape(name)+"/call", args, &resp); err != nil {
+		return err
+	}
+	if out == nil || len(resp.Result.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Result.Result, out)
+}
+
+// do sends a request to the MCP server, whose responses are not wrapped in
+// the REST envelope and whose errors carry the HTTP status as their code
+func (m *MCPClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	c := m.c
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	return retry.Do(ctx, c.retry, temporary, func() error {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode >= 300 {
+			// JSON-RPC errors keep the request ID in their data
+			var failure struct {
+				Error struct {
+					Message   string `json:"message"`
+					RequestID string `json:"request_id"`
+					Data      struct {
+						RequestID string `json:"request_id"`
+					} `json:"data"`
+				} `json:"error"`
+			}
+			e := &Error{Status: resp.StatusCode, Code: http.StatusText(resp.StatusCode)}
+			if json.Unmarshal(data, &failure) == nil && failure.Error.Message != "" {
+				e.Message, e.RequestID = failure.Error.Message, failure.Error.RequestID
+				if e.RequestID == "" {
+					e.RequestID = failure.Error.Data.RequestID
+				}
+			} else {
+				e.Message = strings.TrimSpace(string(data))
+			}
+			return e
+		}
+		if out == nil {
+			return nil
+		}
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("skagent: decoding response: %w", err)
+		}
+		return nil
+	})
+}
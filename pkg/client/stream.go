@@ -0,0 +1,302 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/skagent/internal/core"
+	"github.com/biodoia/skagent/internal/logging"
+)
+
+// Conversations and log entries, as the server encodes them
+type (
+	Session         = core.Session
+	Message         = core.Message
+	LogEntry        = logging.Entry
+	DelegateOptions = core.DelegateOptions
+	Delegation      = core.Delegation
+)
+
+// ListTasks returns every task
+func (c *Client) ListTasks(ctx context.Context) ([]Task, error) {
+	var out struct {
+		Tasks []Task `json:"tasks"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tasks", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Tasks, nil
+}
+
+// archivePage is the most archived tasks the server returns at once
+const archivePage = 1000
+
+// ListArchivedTasks returns a page of the archived tasks, the most
+// recently finished first. A server whose storage keeps no archive answers
+// with a 501 NO_ARCHIVE error.
+func (c *Client) ListArchivedTasks(ctx context.Context, limit, offset int) ([]Task, error) {
+	query := url.Values{
+		"archived": {"true"},
+		"limit":    {strconv.Itoa(limit)},
+		"offset":   {strconv.Itoa(offset)},
+	}
+	var out struct {
+		Tasks []Task `json:"tasks"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/tasks", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Tasks, nil
+}
+
+// TasksSince returns the live tasks and the archived ones that finished
+// since since, or every archived task when since is zero. A server without
+// an archive has only live tasks.
+func (c *Client) TasksSince(ctx context.Context, since time.Time) ([]Task, error) {
+	tasks, err := c.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		seen[t.ID] = true
+	}
+	for offset := 0; ; offset += archivePage {
+		page, err := c.ListArchivedTasks(ctx, archivePage, offset)
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusNotImplemented {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range page {
+			// A task archived between the two listings shows up in both
+			if !seen[t.ID] {
+				seen[t.ID] = true
+				tasks = append(tasks, t)
+			}
+		}
+		if len(page) < archivePage || page[len(page)-1].FinishedAt().Before(since) {
+			return tasks, nil
+		}
+	}
+}
+
+// LogQuery selects server log entries
+type LogQuery struct {
+	// Level is the lowest level returned: debug, info, warn or error
+	Level     string
+	Component string
+	// Since is an RFC 3339 time or a duration back from now, e.g. "15m"
+	Since string
+	// Limit keeps the most recent entries; 0 returns all that match
+	Limit int
+}
+
+func (q LogQuery) values() url.Values {
+	v := url.Values{}
+	if q.Level != "" {
+		v.Set("level", q.Level)
+	}
+	if q.Component != "" {
+		v.Set("component", q.Component)
+	}
+	if q.Since != "" {
+		v.Set("since", q.Since)
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return v
+}
+
+// Logs returns the buffered server log entries matching q, oldest first
+func (c *Client) Logs(ctx context.Context, q LogQuery) ([]LogEntry, error) {
+	var out struct {
+		Logs []LogEntry `json:"logs"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/system/logs", q.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Logs, nil
+}
+
+// FollowLogs calls handle with the entries matching q and then with new
+// ones as the server logs them, until ctx is done, the server closes the
+// stream or handle returns an error
+func (c *Client) FollowLogs(ctx context.Context, q LogQuery, handle func(LogEntry) error) error {
+	query := q.values()
+	query.Set("follow", "1")
+	return c.stream(ctx, http.MethodGet, "/system/logs", query, nil, func(event, data string) error {
+		if event != "log" {
+			return nil
+		}
+		var e LogEntry
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return fmt.Errorf("skagent: decoding log entry: %w", err)
+		}
+		return handle(e)
+	})
+}
+
+// FollowAgent calls handle with an agent as it is, then with its changes,
+// those of its tasks and the steps they log as they happen, until ctx is
+// done, the server closes the stream, the agent is deleted or handle
+// returns an error. full asks for whole prompts, responses and tool
+// outputs rather than their first line.
+func (c *Client) FollowAgent(ctx context.Context, id string, full bool, handle func(AgentActivity) error) error {
+	query := url.Values{}
+	if full {
+		query.Set("full", "true")
+	}
+	return c.stream(ctx, http.MethodGet, "/agents/"+url.PathEscape(id)+"/stream", query, nil, func(event, data string) error {
+		var a AgentActivity
+		if err := json.Unmarshal([]byte(data), &a); err != nil {
+			return fmt.Errorf("skagent: decoding agent activity: %w", err)
+		}
+		return handle(a)
+	})
+}
+
+// CreateSession starts a conversation
+func (c *Client) CreateSession(ctx context.Context, title string) (*Session, error) {
+	var body interface{}
+	if title != "" {
+		body = map[string]string{"title": title}
+	}
+	var out struct {
+		Session Session `json:"session"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/sessions", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Session, nil
+}
+
+// Chat sends a message to a session and calls onDelta with each piece of
+// the reply as the model produces it. It returns the whole reply.
+func (c *Client) Chat(ctx context.Context, sessionID, content string, onDelta func(string) error) (string, error) {
+	payload, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return "", err
+	}
+	var response string
+	done := false
+	err = c.stream(ctx, http.MethodPost, "/sessions/"+url.PathEscape(sessionID)+"/chat/stream", nil, payload, func(event, data string) error {
+		switch event {
+		case "delta":
+			var d struct {
+				Content string `json:"content"`
+			}
+			if err := json.Unmarshal([]byte(data), &d); err != nil {
+				return err
+			}
+			if onDelta != nil {
+				return onDelta(d.Content)
+			}
+		case "done":
+			var d struct {
+				Response string `json:"response"`
+			}
+			if err := json.Unmarshal([]byte(data), &d); err != nil {
+				return err
+			}
+			response, done = d.Response, true
+			return io.EOF
+		case "error":
+			e := &Error{Status: http.StatusBadGateway}
+			if err := json.Unmarshal([]byte(data), e); err != nil {
+				return err
+			}
+			return e
+		}
+		return nil
+	})
+	if err == io.EOF && done {
+		err = nil
+	}
+	if err == nil && !done {
+		err = fmt.Errorf("skagent: stream ended before the reply was complete")
+	}
+	return response, err
+}
+
+// TasksFromSession creates a task for each step of the plan agreed in a
+// session: the markdown checklist of its last reply that has one
+func (c *Client) TasksFromSession(ctx context.Context, sessionID string, opts DelegateOptions) (*Delegation, error) {
+	var out struct {
+		Delegation Delegation `json:"delegation"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/sessions/"+url.PathEscape(sessionID)+"/task-from-session", nil, opts, &out); err != nil {
+		return nil, err
+	}
+	return &out.Delegation, nil
+}
+
+// stream sends a request for server-sent events and calls handle with
+// each event until the stream ends or handle returns an error. Streams are
+// not retried and are not subject to the client's timeout.
+func (c *Client) stream(ctx context.Context, method, path string, query url.Values, payload []byte, handle func(event, data string) error) error {
+	req, err := c.newRequest(ctx, method, path, query, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	hc := *c.http
+	hc.Timeout = 0
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var env envelope
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if json.Unmarshal(data, &env) != nil {
+			return &Error{Status: resp.StatusCode, Code: http.StatusText(resp.StatusCode), Message: strings.TrimSpace(string(data))}
+		}
+		return responseError(resp, env)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	var event string
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				if err := handle(event, data.String()); err != nil {
+					return err
+				}
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// Comment, such as a keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}